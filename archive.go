@@ -0,0 +1,476 @@
+package trid
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	// ErrEntryTooLarge is returned for archive entries larger than
+	// ArchiveOptions.MaxEntrySize, which are not extracted.
+	ErrEntryTooLarge = errors.New("archive entry too large")
+
+	// ErrExtractLimit is returned when a budget of ArchiveOptions is
+	// exhausted: for the entry exceeding MaxExtractedSize and the entries
+	// following it, for archives with entries beyond MaxEntries, and for
+	// archives nested deeper than MaxDepth.
+	ErrExtractLimit = errors.New("archive extraction limit exceeded")
+)
+
+// Default budgets of ArchiveOptions.
+const (
+	DefaultMaxExtractedSize = 1 << 30
+	DefaultMaxEntries       = 10000
+	DefaultMaxDepth         = 8
+)
+
+// archiveFormat is a container format ScanArchive looks into.
+type archiveFormat int
+
+const (
+	formatNone archiveFormat = iota
+	formatZip
+	formatTar
+	formatGzip
+	formatBzip2
+	formatXz
+)
+
+// Signatures of the container formats.
+var (
+	zipMagic   = [][]byte{[]byte("PK\x03\x04"), []byte("PK\x05\x06")} // Local file header, or end of an empty archive.
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	tarMagic   = []byte("ustar") // At offset 257.
+)
+
+// compressedExts maps the extensions of compressed files to the extension of
+// their payload.
+var compressedExts = map[string]string{
+	".gz": "", ".bz2": "", ".xz": "",
+	".tgz": ".tar", ".tbz": ".tar", ".tbz2": ".tar", ".txz": ".tar",
+}
+
+// ArchiveOptions configures ScanArchive.
+type ArchiveOptions struct {
+	TempDir          string // Directory where entries are extracted to be identified (default: os.TempDir()).
+	MaxEntrySize     int64  // Size in bytes above which entries are not extracted; 0 means no limit.
+	MaxExtractedSize int64  // Total size in bytes of the entries extracted (default: DefaultMaxExtractedSize); negative means no limit.
+	MaxEntries       int    // Total number of entries identified (default: DefaultMaxEntries); negative means no limit.
+	MaxDepth         int    // Levels of nested archives looked into (default: DefaultMaxDepth); 1 only looks into the file scanned.
+}
+
+// ArchiveEntry is the identification result of an archive or of one of its
+// entries, forming a tree.
+type ArchiveEntry struct {
+	Name      string          // Name of the entry in its archive, or the path of the file scanned.
+	Path      string          // Chain of names from the file scanned, separated by "!" (e.g., "outer.zip!inner.tar.gz!file.bin").
+	Size      int64           // Size of the entry or file in bytes; for compressed payloads, the size extracted.
+	FileTypes []FileType      // Identified file types.
+	Err       error           // Error if the entry could not be identified or, for archives, read.
+	Entries   []*ArchiveEntry // Entries of the archive, in archive order; nil if it is not one.
+}
+
+// archiveScan is the state of a ScanArchive call.
+type archiveScan struct {
+	t               *Trid
+	numberOfMatches int
+	opts            ArchiveOptions
+	dir             string // Temporary directory for extracted entries, once created.
+	extracted       int64  // Bytes extracted so far.
+	entries         int    // Entries found so far.
+	exhausted       bool   // Whether MaxExtractedSize or MaxEntries was reached.
+}
+
+// ScanArchive identifies the file at filePath and, if it is an archive, each
+// of its entries, recursively, so that uploads cannot hide content behind an
+// archive label. Zip and tar archives are supported, as well as files
+// compressed with gzip, bzip2 or xz, whose payload is their single entry.
+// Entries are extracted one by one to a temporary file, identified, looked
+// into if they are archives themselves and removed, so at most one entry per
+// level of nesting is on disk at a time.
+//
+// The payload of a compressed file has the same Path as the file, so that
+// the entries of a compressed tar archive appear directly below it, but it
+// counts as a level of nesting.
+//
+// To prevent resource exhaustion by decompression bombs, extraction is
+// limited by the budgets of opts, enforced on the bytes actually extracted
+// whatever sizes entries declare. Once MaxExtractedSize bytes have been
+// extracted or MaxEntries entries found, the entries following fail with
+// ErrExtractLimit; archives nested beyond MaxDepth are identified but not
+// looked into, with ErrExtractLimit as their error.
+//
+// Errors concerning the archive or a single entry, such as a corrupt archive
+// or an unsupported compression method, are reported in the ArchiveEntry
+// concerned. The returned error is reserved for invalid arguments, a missing
+// file and failures affecting every file, as for ScanBatch.
+func (t *Trid) ScanArchive(ctx context.Context, filePath string, numberOfMatches int, opts ArchiveOptions) (*ArchiveEntry, error) {
+	root := &ArchiveEntry{Name: filePath, Path: filePath}
+	if root.FileTypes, root.Err = t.ScanContext(ctx, filePath, numberOfMatches); isFatalScanError(ctx, root.Err) {
+		return nil, root.Err
+	}
+
+	if fi, err := os.Stat(filePath); err == nil {
+		root.Size = fi.Size()
+	}
+
+	if opts.MaxExtractedSize == 0 {
+		opts.MaxExtractedSize = DefaultMaxExtractedSize
+	}
+
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+
+	if opts.MaxDepth < 1 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+
+	s := &archiveScan{t: t, numberOfMatches: numberOfMatches, opts: opts}
+	defer s.close()
+
+	if err := s.scanEntries(ctx, root, filePath, 1); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// close removes the temporary directory, if created.
+func (s *archiveScan) close() {
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// scanEntries identifies the entries of the archive at filePath, if it is
+// one, adding them to parent. depth is the level of nesting of the entries.
+func (s *archiveScan) scanEntries(ctx context.Context, parent *ArchiveEntry, filePath string, depth int) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		parent.Err = err
+		return nil
+	}
+	defer f.Close()
+
+	format, err := detectArchive(f)
+	if err != nil {
+		parent.Err = err
+		return nil
+	}
+
+	if format != formatNone && depth > s.opts.MaxDepth {
+		parent.Err = fmt.Errorf("%w: archives nested deeper than %d levels", ErrExtractLimit, s.opts.MaxDepth)
+		return nil
+	}
+
+	switch format {
+	case formatZip:
+		return s.scanZip(ctx, parent, f, depth)
+	case formatTar:
+		return s.scanTar(ctx, parent, f, depth)
+	case formatGzip, formatBzip2, formatXz:
+		return s.scanCompressed(ctx, parent, f, format, depth)
+	default:
+		return nil
+	}
+}
+
+// detectArchive returns the container format of f from its signature.
+func detectArchive(f io.ReaderAt) (archiveFormat, error) {
+	head := make([]byte, 257+len(tarMagic))
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return formatNone, err
+	}
+	head = head[:n]
+
+	switch {
+	case hasPrefix(head, zipMagic):
+		return formatZip, nil
+	case bytes.HasPrefix(head, gzipMagic):
+		return formatGzip, nil
+	case bytes.HasPrefix(head, bzip2Magic):
+		return formatBzip2, nil
+	case bytes.HasPrefix(head, xzMagic):
+		return formatXz, nil
+	case bytes.HasSuffix(head, tarMagic) && len(head) == 257+len(tarMagic):
+		return formatTar, nil
+	default:
+		return formatNone, nil
+	}
+}
+
+// scanZip identifies the entries of the zip archive f.
+func (s *archiveScan) scanZip(ctx context.Context, parent *ArchiveEntry, f *os.File, depth int) error {
+	fi, err := f.Stat()
+	if err != nil {
+		parent.Err = err
+		return nil
+	}
+
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		parent.Err = fmt.Errorf("reading archive: %w", err)
+		return nil
+	}
+
+	parent.Entries = make([]*ArchiveEntry, 0, len(zr.File))
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		entry := s.addEntry(parent, zf.Name, int64(zf.UncompressedSize64))
+		if entry == nil {
+			return nil
+		}
+
+		if err := s.scanEntry(ctx, entry, zf.Open, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanTar identifies the regular files of the tar archive r.
+func (s *archiveScan) scanTar(ctx context.Context, parent *ArchiveEntry, r io.Reader, depth int) error {
+	parent.Entries = make([]*ArchiveEntry, 0)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			parent.Err = fmt.Errorf("reading archive: %w", err)
+			return nil
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entry := s.addEntry(parent, hdr.Name, hdr.Size)
+		if entry == nil {
+			return nil
+		}
+
+		if err := s.scanEntry(ctx, entry, func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// scanCompressed identifies the payload of the compressed file f and, if it
+// is an archive, its entries.
+func (s *archiveScan) scanCompressed(ctx context.Context, parent *ArchiveEntry, f *os.File, format archiveFormat, depth int) error {
+	name := payloadName(parent.Name)
+
+	var r io.Reader
+	var err error
+	switch format {
+	case formatGzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(f); err == nil {
+			if zr.Name != "" {
+				name = path.Base(strings.ReplaceAll(zr.Name, "\\", "/"))
+			}
+			r = zr
+		}
+	case formatBzip2:
+		r = bzip2.NewReader(f)
+	case formatXz:
+		r, err = xz.NewReader(f)
+	}
+
+	if err != nil {
+		parent.Err = fmt.Errorf("reading archive: %w", err)
+		return nil
+	}
+
+	payload := s.addEntry(parent, name, 0)
+	if payload == nil {
+		return nil
+	}
+	payload.Path = parent.Path
+
+	return s.scanEntry(ctx, payload, func() (io.ReadCloser, error) { return io.NopCloser(r), nil }, depth)
+}
+
+// payloadName returns the name of the payload of the compressed file at
+// filePath, from its name.
+func payloadName(filePath string) string {
+	base := filepath.Base(filePath)
+	ext := filepath.Ext(base)
+	if payloadExt, ok := compressedExts[strings.ToLower(ext)]; ok {
+		return strings.TrimSuffix(base, ext) + payloadExt
+	}
+
+	return base
+}
+
+// addEntry adds an entry to parent and returns it, or returns nil if
+// MaxEntries is exhausted.
+func (s *archiveScan) addEntry(parent *ArchiveEntry, name string, size int64) *ArchiveEntry {
+	if s.opts.MaxEntries > 0 && s.entries >= s.opts.MaxEntries {
+		s.exhausted = true
+		parent.Err = fmt.Errorf("%w: more than %d entries", ErrExtractLimit, s.opts.MaxEntries)
+		return nil
+	}
+	s.entries++
+
+	entry := &ArchiveEntry{Name: name, Path: parent.Path + "!" + name, Size: size}
+	parent.Entries = append(parent.Entries, entry)
+
+	return entry
+}
+
+// scanEntry extracts an archive entry opened by open, identifies it and, if
+// it is an archive, its entries. depth is the level of nesting of the entry.
+func (s *archiveScan) scanEntry(ctx context.Context, entry *ArchiveEntry, open func() (io.ReadCloser, error), depth int) error {
+	if s.opts.MaxEntrySize > 0 && entry.Size > s.opts.MaxEntrySize {
+		entry.Err = fmt.Errorf("%w: %d bytes, limit is %d", ErrEntryTooLarge, entry.Size, s.opts.MaxEntrySize)
+		return nil
+	}
+
+	rc, err := open()
+	if err != nil {
+		entry.Err = err
+		return nil
+	}
+	defer rc.Close()
+
+	tmp, err := s.extract(entry, rc)
+	if err != nil || tmp == "" {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if err := s.identify(ctx, entry, tmp); err != nil {
+		return err
+	}
+
+	return s.scanEntries(ctx, entry, tmp, depth+1)
+}
+
+// identify identifies the extracted entry at tmp.
+func (s *archiveScan) identify(ctx context.Context, entry *ArchiveEntry, tmp string) error {
+	results, err := s.t.ScanBatch(ctx, []string{tmp}, s.numberOfMatches)
+	if err != nil {
+		return err
+	}
+
+	entry.FileTypes, entry.Err = results[0].FileTypes, results[0].Err
+
+	return nil
+}
+
+// extract copies r, the contents of entry, to a new temporary file keeping
+// the extension of the entry, and returns its path. It enforces the size
+// limits whatever size the entry declares, recording failures in entry.Err
+// and returning an empty path then. The returned error is reserved for
+// failures to create the temporary file.
+func (s *archiveScan) extract(entry *ArchiveEntry, r io.Reader) (string, error) {
+	if s.exhausted {
+		entry.Err = ErrExtractLimit
+		return "", nil
+	}
+
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.opts.TempDir, "trid-archive-*")
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "entry-*"+plainExt(entry.Name))
+	if err != nil {
+		return "", err
+	}
+
+	// Read one byte more than allowed to detect entries exceeding a limit
+	limit, budget := s.opts.MaxEntrySize, int64(-1)
+	if s.opts.MaxExtractedSize > 0 {
+		budget = s.opts.MaxExtractedSize - s.extracted
+		if limit <= 0 || budget < limit {
+			limit = budget
+		}
+	}
+
+	if limit > 0 || budget == 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	s.extracted += n
+	entry.Size = max(entry.Size, n)
+
+	if err == nil && (limit > 0 || budget == 0) && n > limit {
+		if limit == budget {
+			s.exhausted = true
+			err = fmt.Errorf("%w: %d bytes extracted", ErrExtractLimit, s.opts.MaxExtractedSize)
+		} else {
+			err = fmt.Errorf("%w: more than %d bytes", ErrEntryTooLarge, limit)
+		}
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		entry.Err = err
+		return "", nil
+	}
+
+	return tmp.Name(), nil
+}
+
+// plainExt returns the extension of the entry named name if it is short and
+// alphanumeric, to keep it for Options.Fallback, and "" otherwise.
+func plainExt(name string) string {
+	ext := path.Ext(strings.ReplaceAll(name, "\\", "/"))
+	if len(ext) < 2 || len(ext) > 16 {
+		return ""
+	}
+
+	for _, r := range ext[1:] {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return ""
+		}
+	}
+
+	return ext
+}
+
+// hasPrefix reports whether b starts with any of the prefixes.
+func hasPrefix(b []byte, prefixes [][]byte) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(b, p) {
+			return true
+		}
+	}
+
+	return false
+}
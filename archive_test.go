@@ -0,0 +1,363 @@
+package trid
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// writeZip creates a zip archive with the given entries, by name, and returns
+// its path. Names ending with a slash are directories.
+func writeZip(t *testing.T, entries map[string][]byte, order ...string) string {
+	t.Helper()
+
+	name := filepath.Join(t.TempDir(), "archive.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, n := range order {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write(entries[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return name
+}
+
+// readFile returns the contents of the file at path.
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestScanArchive(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	archive := writeZip(t, map[string][]byte{
+		"doc.pdf":       pdf,
+		"dir/":          nil,
+		"dir/sample.7z": readFile(t, "testdata/sample.7z"),
+		"notes.txt":     []byte("plain text"),
+		"empty":         nil,
+	}, "doc.pdf", "dir/", "dir/sample.7z", "notes.txt", "empty")
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	root, err := tr.ScanArchive(context.Background(), archive, 1, ArchiveOptions{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	// The test definitions do not describe zip archives
+	if root.Name != archive || !errors.Is(root.Err, ErrUnknownFileType) || root.Size == 0 {
+		t.Errorf("ScanArchive() = %+v", root)
+	}
+
+	expected := []struct {
+		name string
+		size int64
+		ext  string
+		err  error
+	}{
+		{"doc.pdf", int64(len(pdf)), ".pdf", nil},
+		{"dir/sample.7z", 0, ".7z", nil},
+		{"notes.txt", 10, "", ErrUnknownFileType},
+		{"empty", 0, "", ErrEmptyFile},
+	}
+
+	if len(root.Entries) != len(expected) {
+		t.Fatalf("ScanArchive() entries = %d, want %d", len(root.Entries), len(expected))
+	}
+
+	for i, e := range expected {
+		entry := root.Entries[i]
+		if entry.Name != e.name || !errors.Is(entry.Err, e.err) || e.size != 0 && entry.Size != e.size {
+			t.Errorf("entry %d = %+v, want %s", i, entry, e.name)
+		}
+
+		if e.ext != "" && (len(entry.FileTypes) != 1 || entry.FileTypes[0].Extension != e.ext) {
+			t.Errorf("entry %s = %+v, want %s", e.name, entry.FileTypes, e.ext)
+		}
+	}
+}
+
+func TestScanArchiveLimits(t *testing.T) {
+	archive := writeZip(t, map[string][]byte{"big.pdf": readFile(t, "testdata/sample.pdf")}, "big.pdf")
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+	tmp := t.TempDir()
+
+	root, err := tr.ScanArchive(context.Background(), archive, 1, ArchiveOptions{TempDir: tmp, MaxEntrySize: 16})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	if !errors.Is(root.Entries[0].Err, ErrEntryTooLarge) {
+		t.Errorf("entry error = %v, want %v", root.Entries[0].Err, ErrEntryTooLarge)
+	}
+
+	// Extracted entries are removed
+	if files, _ := os.ReadDir(tmp); len(files) != 0 {
+		t.Errorf("temporary directory holds %d files", len(files))
+	}
+}
+
+func TestScanArchiveNotArchive(t *testing.T) {
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	root, err := tr.ScanArchive(context.Background(), "testdata/sample.pdf", 1, ArchiveOptions{})
+	if err != nil || root.Entries != nil || len(root.FileTypes) != 1 {
+		t.Errorf("ScanArchive() = %+v, %v, want the PDF without entries", root, err)
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.zip")
+	os.WriteFile(corrupt, []byte("PK\x03\x04 truncated"), 0o644)
+
+	if root, err := tr.ScanArchive(context.Background(), corrupt, 1, ArchiveOptions{}); err != nil || !errors.Is(root.Err, zip.ErrFormat) {
+		t.Errorf("ScanArchive() = %+v, %v, want %v", root, err, zip.ErrFormat)
+	}
+
+	if _, err := tr.ScanArchive(context.Background(), "missing.zip", 1, ArchiveOptions{}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("ScanArchive() error = %v, want %v", err, ErrFileNotFound)
+	}
+}
+
+// tarball returns a tar archive of the given entries, in order.
+func tarball(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o644, Size: int64(len(e.data))}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// tarEntry is an entry of a tarball.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	data     []byte
+}
+
+// gzipped returns data compressed with gzip, with name in the header.
+func gzipped(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// xzipped returns data compressed with xz.
+func xzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// entryNames returns the names of the entries below e, depth first, with
+// their extension or error.
+func entryNames(e *ArchiveEntry) []string {
+	var names []string
+	for _, c := range e.Entries {
+		desc := c.Name
+		if c.Err != nil {
+			desc += " error"
+		} else if len(c.FileTypes) > 0 {
+			desc += " " + c.FileTypes[0].Extension
+		}
+
+		names = append(names, desc)
+		names = append(names, entryNames(c)...)
+	}
+
+	return names
+}
+
+func TestScanArchiveFormats(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	tarData := tarball(t,
+		tarEntry{"docs/", tar.TypeDir, nil},
+		tarEntry{"docs/a.pdf", tar.TypeReg, pdf},
+		tarEntry{"link", tar.TypeSymlink, nil},
+		tarEntry{"b.7z", tar.TypeReg, readFile(t, "testdata/sample.7z")},
+	)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"plain.tar":     tarData,
+		"docs.tgz":      gzipped(t, "", tarData),
+		"named.gz":      gzipped(t, "report.pdf", pdf),
+		"docs.tar.xz":   xzipped(t, tarData),
+		"sample.pdf.xz": xzipped(t, pdf),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{filepath.Join(dir, "plain.tar"), []string{"docs/a.pdf .pdf", "b.7z .7z"}},
+		{filepath.Join(dir, "docs.tgz"), []string{"docs.tar error", "docs/a.pdf .pdf", "b.7z .7z"}},
+		{filepath.Join(dir, "named.gz"), []string{"report.pdf .pdf"}},
+		{filepath.Join(dir, "docs.tar.xz"), []string{"docs.tar error", "docs/a.pdf .pdf", "b.7z .7z"}},
+		{filepath.Join(dir, "sample.pdf.xz"), []string{"sample.pdf .pdf"}},
+		{"testdata/archives/sample.pdf.bz2", []string{"sample.pdf .pdf"}},
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			root, err := tr.ScanArchive(context.Background(), tt.path, 1, ArchiveOptions{TempDir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("ScanArchive() error = %v", err)
+			}
+
+			// The test definitions do not describe tar archives
+			if names := entryNames(root); !slices.Equal(names, tt.expected) {
+				t.Errorf("ScanArchive() entries = %q, want %q", names, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanArchiveBomb(t *testing.T) {
+	bomb := filepath.Join(t.TempDir(), "zeros.tar.gz")
+	zeros := make([]byte, 4<<20)
+	if err := os.WriteFile(bomb, gzipped(t, "", tarball(t, tarEntry{"a", tar.TypeReg, zeros}, tarEntry{"b", tar.TypeReg, zeros})), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	root, err := tr.ScanArchive(context.Background(), bomb, 1, ArchiveOptions{MaxExtractedSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	payload := root.Entries[0]
+	if !errors.Is(payload.Err, ErrExtractLimit) || payload.Entries != nil || payload.Size != 1<<20+1 {
+		t.Errorf("ScanArchive() payload = %+v, want %v", payload, ErrExtractLimit)
+	}
+
+	root, err = tr.ScanArchive(context.Background(), bomb, 1, ArchiveOptions{MaxExtractedSize: 12<<20 + 16<<10})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	// The payload fits, but not its entries on top of it
+	entries := root.Entries[0].Entries
+	if len(entries) != 2 || !errors.Is(entries[0].Err, ErrUnknownFileType) || !errors.Is(entries[1].Err, ErrExtractLimit) {
+		t.Errorf("ScanArchive() entries = %q, want the second over the limit", entryNames(root.Entries[0]))
+	}
+}
+
+func TestScanArchiveNested(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	inner := gzipped(t, "", tarball(t, tarEntry{"file.pdf", tar.TypeReg, pdf}))
+	outer := writeZip(t, map[string][]byte{"inner.tar.gz": inner, "b.pdf": pdf}, "inner.tar.gz", "b.pdf")
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	root, err := tr.ScanArchive(context.Background(), outer, 1, ArchiveOptions{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	expected := []string{"inner.tar.gz error", "inner.tar error", "file.pdf .pdf", "b.pdf .pdf"}
+	if names := entryNames(root); !slices.Equal(names, expected) {
+		t.Fatalf("ScanArchive() entries = %q, want %q", names, expected)
+	}
+
+	// Compressed payloads are transparent in paths
+	payload := root.Entries[0].Entries[0]
+	if path := payload.Entries[0].Path; path != outer+"!inner.tar.gz!file.pdf" {
+		t.Errorf("Path = %q, want %q", path, outer+"!inner.tar.gz!file.pdf")
+	}
+
+	if payload.Path != root.Entries[0].Path || root.Entries[1].Path != outer+"!b.pdf" {
+		t.Errorf("Path = %q and %q", payload.Path, root.Entries[1].Path)
+	}
+
+	// The payload is identified, but not looked into
+	root, err = tr.ScanArchive(context.Background(), outer, 1, ArchiveOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	payload = root.Entries[0].Entries[0]
+	if !errors.Is(payload.Err, ErrExtractLimit) || payload.Entries != nil {
+		t.Errorf("ScanArchive() payload = %+v, want %v", payload, ErrExtractLimit)
+	}
+
+	root, err = tr.ScanArchive(context.Background(), outer, 1, ArchiveOptions{MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("ScanArchive() error = %v", err)
+	}
+
+	expected = []string{"inner.tar.gz error", "inner.tar error"}
+	payload = root.Entries[0].Entries[0]
+	if names := entryNames(root); !slices.Equal(names, expected) || !errors.Is(root.Err, ErrExtractLimit) || !errors.Is(payload.Err, ErrExtractLimit) {
+		t.Errorf("ScanArchive() entries = %q, want %q", names, expected)
+	}
+}
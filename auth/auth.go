@@ -0,0 +1,243 @@
+// Package auth authenticates the clients of the identification servers with
+// API keys and enforces per-tenant quotas, for servers shared by several
+// internal tenants.
+//
+// Keys are checked by an Authenticator: StaticKeys for a fixed set of keys, or
+// AuthenticatorFunc to validate tokens with an external service. A Guard
+// combines an Authenticator with the request rate of each tenant; it is set as
+// server.Options.Auth or tridgrpc.ServerOptions.Auth.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnauthenticated is returned when a request has no API key or an
+	// invalid one.
+	ErrUnauthenticated = errors.New("missing or invalid API key")
+
+	// ErrRateLimited is matched by the QuotaError returned when a tenant
+	// exceeds its request rate.
+	ErrRateLimited = errors.New("request rate quota exceeded")
+
+	// ErrUploadTooLarge is returned when an upload exceeds the size quota of
+	// its tenant.
+	ErrUploadTooLarge = errors.New("upload exceeds the size quota")
+)
+
+// Quota limits the use of a server by a tenant. Zero fields place no limit.
+type Quota struct {
+	RequestsPerSecond float64 // Sustained request rate.
+	Burst             int     // Requests allowed at once above the rate (default: RequestsPerSecond, at least 1).
+	MaxUploadSize     int64   // Maximum size of an uploaded file in bytes, below the limit of the server.
+}
+
+// Tenant is the client a key belongs to. Quotas apply to tenants, so several
+// keys of a tenant share its quota.
+type Tenant struct {
+	Name  string // Name of the tenant, unique among tenants.
+	Quota Quota  // Limits of the tenant.
+}
+
+// Authenticator returns the tenant an API key belongs to.
+type Authenticator interface {
+	// Authenticate returns the tenant of key, or an error if the key is not
+	// valid. Errors other than ErrUnauthenticated are treated as failures of
+	// the authenticator and reported to the client as such.
+	Authenticate(ctx context.Context, key string) (*Tenant, error)
+}
+
+// AuthenticatorFunc is an Authenticator implemented by a function, such as a
+// call to a token validation service.
+type AuthenticatorFunc func(ctx context.Context, key string) (*Tenant, error)
+
+// Authenticate calls f.
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, key string) (*Tenant, error) {
+	return f(ctx, key)
+}
+
+// StaticKeys is an Authenticator with a fixed set of keys, mapped to their
+// tenant. Keys are compared in constant time.
+type StaticKeys map[string]*Tenant
+
+// Authenticate returns the tenant of key.
+func (s StaticKeys) Authenticate(ctx context.Context, key string) (*Tenant, error) {
+	var tenant *Tenant
+	for k, t := range s {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			tenant = t
+		}
+	}
+
+	if tenant == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return tenant, nil
+}
+
+// QuotaError is returned when a tenant exceeds its request rate.
+type QuotaError struct {
+	Tenant     string        // Name of the tenant.
+	RetryAfter time.Duration // Time until a request would be allowed.
+}
+
+// Error implements the error interface.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v for %s, retry after %s", ErrRateLimited, e.Tenant, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Unwrap returns ErrRateLimited.
+func (e *QuotaError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Guard authenticates requests and enforces the request rate of tenants. It
+// is safe for concurrent use.
+type Guard struct {
+	auth    Authenticator
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewGuard returns a guard authenticating keys with a.
+func NewGuard(a Authenticator) *Guard {
+	return &Guard{auth: a, now: time.Now, buckets: make(map[string]*Bucket)}
+}
+
+// Authenticate returns the tenant of key, or ErrUnauthenticated if there is
+// none.
+func (g *Guard) Authenticate(ctx context.Context, key string) (*Tenant, error) {
+	if key == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	t, err := g.auth.Authenticate(ctx, key)
+	if err == nil && t == nil {
+		err = ErrUnauthenticated
+	}
+
+	return t, err
+}
+
+// Allow counts a request of t against its rate quota, returning a
+// *QuotaError if the quota is exceeded.
+func (g *Guard) Allow(t *Tenant) error {
+	if t.Quota.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	b, ok := g.buckets[t.Name]
+	if !ok || b.rate != t.Quota.RequestsPerSecond {
+		burst := t.Quota.Burst
+		if burst < 1 {
+			burst = max(1, int(math.Ceil(t.Quota.RequestsPerSecond)))
+		}
+
+		b = NewBucket(t.Quota.RequestsPerSecond, burst)
+		g.buckets[t.Name] = b
+	}
+	g.mu.Unlock()
+
+	if wait := b.take(g.now()); wait > 0 {
+		return &QuotaError{Tenant: t.Name, RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// Admit authenticates key and counts the request against the quota of its
+// tenant.
+func (g *Guard) Admit(ctx context.Context, key string) (*Tenant, error) {
+	t, err := g.Authenticate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.Allow(t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Bucket is a token bucket: it allows a number of events per second, with
+// bursts of up to its capacity. It is safe for concurrent use.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket allowing rate events per second, with
+// bursts of up to burst events.
+func NewBucket(rate float64, burst int) *Bucket {
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Take takes a token from the bucket. It returns 0 if one was available, and
+// the time until one will be otherwise.
+func (b *Bucket) Take() time.Duration {
+	return b.take(time.Now())
+}
+
+// take takes a token at time now.
+func (b *Bucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// KeyFromRequest returns the API key of r, sent as a bearer token in the
+// Authorization header or in the X-API-Key header.
+func KeyFromRequest(r *http.Request) string {
+	return KeyFromHeaders(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+}
+
+// KeyFromHeaders returns the API key from the values of the Authorization and
+// X-API-Key headers, or of the equivalent gRPC metadata.
+func KeyFromHeaders(authorization, apiKey string) string {
+	if scheme, token, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return strings.TrimSpace(apiKey)
+}
+
+// tenantKey is the context key of the tenant of a request.
+type tenantKey struct{}
+
+// NewContext returns a copy of ctx carrying t.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// FromContext returns the tenant carried by ctx, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(*Tenant)
+	return t, ok
+}
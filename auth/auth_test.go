@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStaticKeys(t *testing.T) {
+	keys := StaticKeys{"k1": {Name: "a"}, "k2": {Name: "b"}}
+
+	if tenant, err := keys.Authenticate(context.Background(), "k2"); err != nil || tenant.Name != "b" {
+		t.Errorf("Authenticate(k2) = %v, %v, want tenant b", tenant, err)
+	}
+
+	if _, err := keys.Authenticate(context.Background(), "k3"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate(k3) error = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	failure := errors.New("validation service down")
+	g := NewGuard(AuthenticatorFunc(func(ctx context.Context, key string) (*Tenant, error) {
+		switch key {
+		case "valid":
+			return &Tenant{Name: "a", Quota: Quota{RequestsPerSecond: 2}}, nil
+		case "broken":
+			return nil, failure
+		default:
+			return nil, nil
+		}
+	}))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"", "other"} {
+		if _, err := g.Admit(ctx, key); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("Admit(%q) error = %v, want ErrUnauthenticated", key, err)
+		}
+	}
+
+	if _, err := g.Admit(ctx, "broken"); !errors.Is(err, failure) {
+		t.Errorf("Admit(broken) error = %v, want %v", err, failure)
+	}
+
+	// The burst defaults to the rate
+	for i := 0; i < 2; i++ {
+		if _, err := g.Admit(ctx, "valid"); err != nil {
+			t.Fatalf("Admit() #%d error = %v", i, err)
+		}
+	}
+
+	_, err := g.Admit(ctx, "valid")
+	var quotaErr *QuotaError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &quotaErr) || quotaErr.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Admit() above the quota error = %v, want a retry after 500ms", err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if _, err := g.Admit(ctx, "valid"); err != nil {
+		t.Errorf("Admit() once refilled error = %v", err)
+	}
+}
+
+func TestKeyFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected string
+	}{
+		{"Bearer token", "Authorization", "Bearer abc", "abc"},
+		{"Case-insensitive scheme", "Authorization", "bearer abc", "abc"},
+		{"Other scheme", "Authorization", "Basic abc", ""},
+		{"API key header", "X-API-Key", "abc", "abc"},
+		{"None", "Accept", "*/*", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set(tt.header, tt.value)
+
+			if got := KeyFromRequest(r); got != tt.expected {
+				t.Errorf("KeyFromRequest() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// reFileHeader matches the line introducing the results of each file in TrID
+// output.
+var reFileHeader = regexp.MustCompile(`(?m)^[ \t]*(?:Collecting data from file|File):[ \t]*(.*?)[ \t]*\r?$`)
+
+// maxArgsLength is the total length of file path arguments above which
+// FileListAuto passes paths on standard input. It stays well below the
+// smallest common command line limit (32 KiB on Windows).
+const maxArgsLength = 24 << 10
+
+// FileListMode selects how ScanBatch passes file paths to TrID.
+type FileListMode int
+
+const (
+	// FileListAuto passes paths as arguments, switching to standard input
+	// when the command line would become too long.
+	FileListAuto FileListMode = iota
+
+	// FileListArgs always passes paths as command line arguments.
+	FileListArgs
+
+	// FileListStdin always passes paths on standard input, one per line,
+	// using TrID's -@ option.
+	FileListStdin
+)
+
+// BatchResult is the identification result of a single file of a batch.
+type BatchResult struct {
+	Path        string         // Path of the file, as passed to ScanBatch.
+	FileTypes   []FileType     // Identified file types.
+	Warnings    []string       // Non-fatal warnings printed by TrID for the file or the whole batch.
+	Err         error          // Error if the file could not be identified.
+	Charset     string         // Character encoding of textual files, with Options.Charset.
+	Executable  *Executable    // Header of executables, with Options.Executables.
+	PDF         *PDF           // Properties of PDF documents, with Options.PDF.
+	Office      *Office        // Container and macros of Office documents, with Options.Office.
+	Image       *Image         // Header of images, with Options.Images.
+	Enrichments map[string]any // Details added by Options.Enrichers, by name.
+	Suspicion   *Suspicion     // Why the file name is deceptive, with Options.SuspiciousNames; nil if it is not.
+}
+
+// outputSection is the part of TrID output describing a single file.
+type outputSection struct {
+	path string
+	body string
+}
+
+// ScanBatch identifies several files, passing up to Options.BatchSize files to
+// each TrID process to amortize its startup cost. Results are returned in the
+// order of filePaths.
+//
+// Errors concerning a single file, such as ErrFileNotFound or
+// ErrUnknownFileType, are reported in its BatchResult. If a TrID process fails
+// as a whole, its files are retried one by one so that a single problematic
+// file cannot fail the others. The returned error is reserved for failures
+// affecting the entire batch, such as ErrNumberOfMatches, missing definitions,
+// an open circuit breaker or a cancelled context.
+//
+// When a Limiter is configured, chunks are identified concurrently within its
+// limit; otherwise they are identified one after another.
+func (t *Trid) ScanBatch(ctx context.Context, filePaths []string, numberOfMatches int) ([]BatchResult, error) {
+	return t.scanTracked(ctx, filePaths, numberOfMatches, nil, 0)
+}
+
+// scanTracked implements ScanBatch, reporting progress to tracker, which holds
+// the files of filePaths from index base on, or to a tracker of its own if
+// tracker is nil.
+func (t *Trid) scanTracked(ctx context.Context, filePaths []string, numberOfMatches int, tracker *progress, base int) ([]BatchResult, error) {
+	ctx, span := t.startSpan(ctx, "trid.ScanBatch", attribute.Int("trid.batch.files", len(filePaths)),
+		attribute.Int("trid.matches.requested", numberOfMatches))
+
+	results, err := t.scanBatch(ctx, filePaths, numberOfMatches, tracker, base)
+	if err == nil {
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+
+			t.stats.scanDone(r.Err)
+			t.options.Hooks.scanComplete(r.Path, r.FileTypes, r.Err)
+		}
+
+		span.SetAttributes(attribute.Int("trid.batch.failed", failed))
+	} else if numberOfMatches >= 1 {
+		// Every file was started; report the batch failure for each of them
+		for _, path := range filePaths {
+			t.options.Hooks.scanComplete(path, nil, err)
+		}
+	}
+	endSpan(span, err)
+
+	return results, err
+}
+
+// scanBatch implements ScanBatch. Files are handled Options.BatchSize at a
+// time, each window from its checks to its post-processing, so the temporary
+// copies made for Options.LocalCopy and OversizeTruncate are removed as soon
+// as their window is done instead of piling up until the whole batch is.
+func (t *Trid) scanBatch(ctx context.Context, filePaths []string, numberOfMatches int, tracker *progress, base int) ([]BatchResult, error) {
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	t.checkDefsAge(ctx)
+
+	if tracker == nil {
+		tracker, base = t.newProgress(filePaths), 0
+	}
+
+	b := &batchState{
+		paths:        filePaths,
+		results:      make([]BatchResult, len(filePaths)),
+		targets:      make([]string, len(filePaths)),
+		keys:         make([]cacheKeys, len(filePaths)),
+		matches:      numberOfMatches,
+		progress:     tracker,
+		progressBase: base,
+	}
+
+	for _, path := range filePaths {
+		t.options.Hooks.scanStart(path)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		batchErr error
+	)
+
+	// Windows are identified one after another, or concurrently within the
+	// limit of the Limiter
+	slots := make(chan struct{}, 1)
+	if t.options.Limiter != nil {
+		slots = make(chan struct{}, t.options.Limiter.Limit())
+	}
+
+	for start := 0; start < len(filePaths) && ctx.Err() == nil; start += t.options.BatchSize {
+		end := min(start+t.options.BatchSize, len(filePaths))
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := t.scanWindow(ctx, b, start, end)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil && batchErr == nil {
+				batchErr = err
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if batchErr != nil {
+		return nil, batchErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.results, nil
+}
+
+// batchState holds the files of a ScanBatch call and their results, shared by
+// its windows, each working on its own range of indexes.
+type batchState struct {
+	paths        []string
+	results      []BatchResult
+	targets      []string // Paths passed to TrID, which may be temporary copies.
+	keys         []cacheKeys
+	matches      int
+	progress     *progress
+	progressBase int // Index of the first file of the batch in progress.
+}
+
+// done reports the files of b at the given indexes as identified to its
+// progress tracker.
+func (b *batchState) done(indexes ...int) {
+	if b.progress == nil || len(indexes) == 0 {
+		return
+	}
+
+	shifted := make([]int, len(indexes))
+	for j, i := range indexes {
+		shifted[j] = b.progressBase + i
+	}
+
+	b.progress.done(shifted...)
+}
+
+// scanWindow identifies the files of b from index start to end, exclusive,
+// with a single TrID process, removing their temporary copies when done. It
+// returns an error only if it affects every file of the batch.
+func (t *Trid) scanWindow(ctx context.Context, b *batchState, start, end int) error {
+	var (
+		pending []int
+		settled []int // Files done without running TrID.
+	)
+
+	for i := start; i < end; i++ {
+		path := b.paths[i]
+		b.results[i].Path = path
+
+		if path == "" {
+			b.results[i].Err = ErrNoFileSpecified
+			settled = append(settled, i)
+			continue
+		}
+
+		target, release, err := t.checkFile(path)
+		if err != nil {
+			b.results[i].Err = err
+			settled = append(settled, i)
+			continue
+		}
+		defer release()
+
+		b.targets[i] = target
+
+		if b.results[i].FileTypes = t.precheck(target); b.results[i].FileTypes != nil {
+			settled = append(settled, i)
+			continue
+		}
+
+		if t.options.Cache != nil {
+			var hit bool
+			b.keys[i], b.results[i].FileTypes, hit, b.results[i].Err = t.cacheLookup(ctx, target, b.matches)
+			t.stats.cacheLookup(hit)
+			if hit {
+				settled = append(settled, i)
+				continue
+			}
+		}
+
+		pending = append(pending, i)
+	}
+
+	b.done(settled...)
+
+	if len(pending) > 0 {
+		if err := t.scanChunk(ctx, b.targets, pending, b.matches, b.results); err != nil {
+			if !t.options.Fallback || !canFallback(err) {
+				return err
+			}
+
+			// Identify the files TrID did not get to without it below
+			for _, i := range pending {
+				if b.results[i].FileTypes == nil && b.results[i].Err == nil {
+					b.results[i].Err = err
+				}
+			}
+		}
+	}
+
+	if t.options.Cache != nil {
+		for _, i := range pending {
+			t.cacheStore(ctx, b.keys[i], b.results[i].FileTypes, b.results[i].Err)
+		}
+	}
+
+	for i := start; i < end; i++ {
+		r, target := &b.results[i], b.targets[i]
+
+		if r.Err != nil && target != "" {
+			if fr, err := t.fallback(ctx, target, r.Err); err == nil {
+				r.FileTypes, r.Warnings, r.Err = fr.FileTypes, nil, nil
+			}
+		}
+
+		if r.Err == nil || target != "" {
+			r.FileTypes, r.Charset, r.Err = t.detectCharset(target, r.FileTypes, r.Err)
+		}
+
+		if r.Err == nil {
+			r.FileTypes = t.refineContainer(ctx, target, r.FileTypes)
+			r.FileTypes, r.Err = t.refine(ctx, r.FileTypes)
+			r.Executable = t.inspectExecutable(target, r.FileTypes)
+			r.PDF = t.inspectPDF(target, r.FileTypes)
+			r.Office = t.inspectOffice(target, r.FileTypes)
+			r.Image = t.inspectImage(target, r.FileTypes)
+			r.Enrichments = t.enrich(ctx, target, r.FileTypes)
+			r.Suspicion = t.checkName(r.Path, r.FileTypes)
+		}
+	}
+
+	// The files TrID was run on are done, whether it or the fallback
+	// identified them
+	b.done(pending...)
+
+	return nil
+}
+
+// scanChunk identifies the files at the given indexes with a single TrID
+// process, storing the outcome in results. If the process fails, the files are
+// identified individually.
+func (t *Trid) scanChunk(ctx context.Context, filePaths []string, chunk []int, numberOfMatches int, results []BatchResult) error {
+	paths := make([]string, len(chunk))
+	for i, idx := range chunk {
+		paths[i] = filePaths[idx]
+	}
+
+	chunkResults, err := t.runBatch(ctx, paths, numberOfMatches)
+	if err == nil {
+		for i, idx := range chunk {
+			results[idx].FileTypes, results[idx].Warnings, results[idx].Err =
+				chunkResults[i].FileTypes, chunkResults[i].Warnings, chunkResults[i].Err
+		}
+
+		return nil
+	}
+
+	if isBatchError(ctx, err) {
+		return err
+	}
+
+	// Isolate the failure by identifying each file on its own
+	for _, idx := range chunk {
+		r, err := t.run(ctx, filePaths[idx], numberOfMatches)
+		if isBatchError(ctx, err) {
+			return err
+		}
+
+		if results[idx].Err = err; err == nil {
+			results[idx].FileTypes, results[idx].Warnings = r.FileTypes, r.Warnings
+		}
+	}
+
+	return nil
+}
+
+// isFatalScanError reports whether err, returned by a scan, concerns the
+// arguments or affects every file, rather than the identification of the file.
+func isFatalScanError(ctx context.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrNoFileSpecified), errors.Is(err, ErrNumberOfMatches), errors.Is(err, ErrFileNotFound):
+		return true
+	default:
+		return isBatchError(ctx, err)
+	}
+}
+
+// isBatchError reports whether err affects every file rather than a single
+// one.
+func isBatchError(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, ErrNoDefinitions) || errors.Is(err, ErrEmptyDefPackage) ||
+		errors.Is(err, ErrDefinitionsMismatch) || errors.Is(err, ErrUnavailable)
+}
+
+// runBatch executes TrID on several files and splits its output per file.
+func (t *Trid) runBatch(ctx context.Context, paths []string, numberOfMatches int) ([]BatchResult, error) {
+	if t.options.Engine == EngineNative {
+		return t.runNativeBatch(ctx, paths, numberOfMatches)
+	}
+
+	var (
+		p   *process
+		err error
+	)
+	args := make([]string, len(paths))
+	for i, path := range paths {
+		args[i] = argPath(path)
+	}
+
+	timeout := t.timeout(paths...)
+	if t.useStdin(paths) {
+		p, err = t.execRetry(ctx, timeout, strings.Join(args, "\n")+"\n", append(t.args(numberOfMatches), "-@")...)
+	} else {
+		p, err = t.execRetry(ctx, timeout, "", append(t.args(numberOfMatches), args...)...)
+	}
+
+	pr := detectProfile(p.stdout)
+	header, sections := splitOutput(p.stdout)
+	if tridErr := p.check(header); tridErr != nil {
+		return nil, p.error(tridErr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.log(ctx, slog.LevelDebug, "parsed TrID batch output", "profile", pr.name, "files", len(paths), "sections", len(sections))
+
+	// Warnings outside the sections concern every file
+	_, batchWarnings, _ := pr.parse(header)
+	batchWarnings = append(stderrWarnings(p.stderr), batchWarnings...)
+
+	bySection := make(map[string]string, len(sections))
+	for _, s := range sections {
+		bySection[s.path] = s.body
+	}
+
+	results := make([]BatchResult, len(paths))
+	for i, path := range paths {
+		results[i].Path = path
+
+		body, ok := bySection[args[i]]
+		if !ok {
+			body, ok = bySection[path]
+		}
+
+		if !ok && len(sections) == len(paths) {
+			// TrID may print paths differently than given; fall back to order
+			body, ok = sections[i].body, true
+		}
+
+		if !ok {
+			results[i].Err = p.error(fmt.Errorf("no TrID output for %s", path))
+			continue
+		}
+
+		if tridErr := checkTridError(body); tridErr != nil {
+			results[i].Err = p.sectionError(tridErr, body)
+			continue
+		}
+
+		fileTypes, warnings, err := pr.parse(body)
+		if err != nil {
+			results[i].Err = p.sectionError(err, body)
+			continue
+		}
+
+		results[i].FileTypes, results[i].Warnings = fileTypes, append(slices.Clip(batchWarnings), warnings...)
+	}
+
+	return results, nil
+}
+
+// useStdin reports whether paths should be passed on standard input.
+// Paths containing line breaks cannot be listed on standard input, so chunks
+// including them are always passed as arguments.
+func (t *Trid) useStdin(paths []string) bool {
+	if t.options.FileList == FileListArgs {
+		return false
+	}
+
+	length := 0
+	for _, p := range paths {
+		if strings.ContainsAny(p, "\r\n") {
+			return false
+		}
+
+		length += len(p) + 1
+	}
+
+	return t.options.FileList == FileListStdin || length > maxArgsLength
+}
+
+// splitOutput splits TrID output into the header preceding the first file and
+// a section per file.
+func splitOutput(out string) (string, []outputSection) {
+	locs := reFileHeader.FindAllStringSubmatchIndex(out, -1)
+	if len(locs) == 0 {
+		return out, nil
+	}
+
+	sections := make([]outputSection, len(locs))
+	for i, loc := range locs {
+		end := len(out)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+
+		sections[i] = outputSection{path: out[loc[2]:loc[3]], body: out[loc[1]:end]}
+	}
+
+	return out[:locs[0][0]], sections
+}
@@ -0,0 +1,269 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newBatchStub creates a stub TrID executable that identifies files starting
+// with "%PDF" and reports other files as unknown, in multi-file output format.
+// Files named "crash" make the whole process fail. It returns the command and
+// a function reporting the number of files passed to each invocation, negated
+// if they were read from standard input.
+func newBatchStub(t *testing.T) (string, func() []int) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls.txt")
+	cmd := filepath.Join(dir, "trid")
+	script := `#!/bin/sh
+stdin=0
+for f in "$@"; do
+  [ "$f" = "-@" ] && stdin=1
+done
+if [ $stdin = 1 ]; then
+  files=$(cat)
+  echo "stdin" >> '` + calls + `'
+else
+  files=$(for f in "$@"; do case "$f" in -*) ;; *) echo "$f" ;; esac; done)
+fi
+n=$(echo "$files" | grep -c .)
+echo $n >> '` + calls + `'
+echo "TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello"
+echo "Definitions found:  18452"
+echo "Analyzing..."
+echo "$files" | while IFS= read -r f; do
+  case "$f" in *crash) [ $n -gt 1 ] && exit 3 ;; esac
+  echo
+  echo "File: $f"
+  if head -c 4 "$f" | grep -q '%PDF'; then
+    echo " 100.0% (.PDF) Adobe Portable Document Format (5000/1)"
+    echo "        Mime type       : application/pdf"
+  else
+    echo "Unknown!"
+  fi
+done
+`
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "triddefs.trd"), []byte("definitions"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, func() []int {
+		b, _ := os.ReadFile(calls)
+		var sizes []int
+		for _, line := range strings.Fields(string(b)) {
+			if line == "stdin" {
+				// Paths read from standard input are recorded as negative sizes
+				sizes = append(sizes, 0)
+				continue
+			}
+
+			n, _ := strconv.Atoi(line)
+			if len(sizes) > 0 && sizes[len(sizes)-1] == 0 {
+				sizes[len(sizes)-1] = -n
+				continue
+			}
+			sizes = append(sizes, n)
+		}
+		return sizes
+	}
+}
+
+// writeFiles creates files with the given contents and returns their paths.
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, c := range contents {
+		paths[i] = filepath.Join(dir, "file"+strconv.Itoa(i))
+		if err := os.WriteFile(paths[i], []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return paths
+}
+
+func TestScanBatch(t *testing.T) {
+	cmd, calls := newBatchStub(t)
+
+	paths := writeFiles(t, "%PDF-1", "binary", "%PDF-2", "%PDF-3", "%PDF-4")
+	paths = append(paths, "", filepath.Join(t.TempDir(), "missing"))
+
+	trid := NewTrid(Options{Cmd: cmd, BatchSize: 2})
+	results, err := trid.ScanBatch(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	expectedErrs := []error{nil, ErrUnknownFileType, nil, nil, nil, ErrNoFileSpecified, ErrFileNotFound}
+	for i, r := range results {
+		if r.Path != paths[i] {
+			t.Errorf("result %d has path %s, want %s", i, r.Path, paths[i])
+		}
+
+		if !errors.Is(r.Err, expectedErrs[i]) || (expectedErrs[i] == nil && r.Err != nil) {
+			t.Errorf("result %d error = %v, want %v", i, r.Err, expectedErrs[i])
+		}
+
+		if r.Err == nil && (len(r.FileTypes) != 1 || r.FileTypes[0].MimeType != "application/pdf") {
+			t.Errorf("result %d = %+v", i, r.FileTypes)
+		}
+	}
+
+	if sizes := calls(); len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("TrID invoked with %v files, want [2 2 1]", sizes)
+	}
+}
+
+func TestScanBatchFailureIsolation(t *testing.T) {
+	cmd, calls := newBatchStub(t)
+
+	paths := writeFiles(t, "%PDF-1", "%PDF-2")
+	crash := filepath.Join(filepath.Dir(paths[0]), "crash")
+	os.WriteFile(crash, []byte("%PDF-3"), 0o644)
+	paths = append(paths, crash)
+
+	trid := NewTrid(Options{Cmd: cmd, MaxConcurrent: 2})
+	results, err := trid.ScanBatch(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	for i, r := range results {
+		if r.Err != nil || len(r.FileTypes) != 1 {
+			t.Errorf("result %d = %+v, %v", i, r.FileTypes, r.Err)
+		}
+	}
+
+	// One failed batch, then one process per file
+	if sizes := calls(); len(sizes) != 4 || sizes[0] != 3 {
+		t.Errorf("TrID invoked with %v files, want [3 1 1 1]", sizes)
+	}
+}
+
+func TestScanBatchCache(t *testing.T) {
+	cmd, calls := newBatchStub(t)
+
+	paths := writeFiles(t, "%PDF-1", "%PDF-1", "binary")
+	trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}})
+
+	trid.ScanBatch(context.Background(), paths[:1], 1)
+	results, err := trid.ScanBatch(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	if results[1].Err != nil || len(results[1].FileTypes) != 1 {
+		t.Errorf("cached result = %+v, %v", results[1].FileTypes, results[1].Err)
+	}
+
+	// The second batch only needs to identify the binary file
+	if sizes := calls(); len(sizes) != 2 || sizes[1] != 1 {
+		t.Errorf("TrID invoked with %v files, want [1 1]", sizes)
+	}
+}
+
+func TestScanBatchErrors(t *testing.T) {
+	trid := NewTrid(Options{})
+	if _, err := trid.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 0); !errors.Is(err, ErrNumberOfMatches) {
+		t.Errorf("ScanBatch() error = %v, want ErrNumberOfMatches", err)
+	}
+}
+
+func TestScanBatchFileList(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          FileListMode
+		expectedSizes []int
+	}{
+		{"Arguments", FileListArgs, []int{3}},
+		{"Standard input", FileListStdin, []int{-3}},
+		{"Auto", FileListAuto, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, calls := newBatchStub(t)
+
+			trid := NewTrid(Options{Cmd: cmd, FileList: tt.mode})
+			results, err := trid.ScanBatch(context.Background(), writeFiles(t, "%PDF-1", "%PDF-2", "%PDF-3"), 1)
+			if err != nil {
+				t.Fatalf("ScanBatch() error = %v", err)
+			}
+
+			for i, r := range results {
+				if r.Err != nil || len(r.FileTypes) != 1 {
+					t.Errorf("result %d = %+v, %v", i, r.FileTypes, r.Err)
+				}
+			}
+
+			if sizes := calls(); !slices.Equal(sizes, tt.expectedSizes) {
+				t.Errorf("TrID invoked with %v files, want %v", sizes, tt.expectedSizes)
+			}
+		})
+	}
+}
+
+func TestUseStdin(t *testing.T) {
+	long := make([]string, 200)
+	for i := range long {
+		long[i] = "/" + strings.Repeat("x", 200)
+	}
+
+	tests := []struct {
+		name     string
+		mode     FileListMode
+		paths    []string
+		expected bool
+	}{
+		{"Auto with short list", FileListAuto, []string{"a", "b"}, false},
+		{"Auto with long list", FileListAuto, long, true},
+		{"Arguments with long list", FileListArgs, long, false},
+		{"Standard input", FileListStdin, []string{"a", "b"}, true},
+		{"Line break in path", FileListStdin, []string{"a", "line\nbreak"}, false},
+		{"Auto with line break in path", FileListAuto, append([]string{"a\r"}, long...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(Options{FileList: tt.mode})
+			if got := trid.useStdin(tt.paths); got != tt.expected {
+				t.Errorf("useStdin() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	out := "TrID/32 - File Identifier v2.24\r\nAnalyzing...\r\n\r\nFile: a.pdf\r\n 100.0% (.PDF) PDF (1/1)\r\n\r\nFile: b c.bin\r\nUnknown!\r\n"
+
+	header, sections := splitOutput(out)
+	if !strings.Contains(header, "Analyzing") {
+		t.Errorf("header = %q", header)
+	}
+
+	if len(sections) != 2 || sections[0].path != "a.pdf" || sections[1].path != "b c.bin" {
+		t.Fatalf("sections = %+v", sections)
+	}
+
+	if !strings.Contains(sections[1].body, "Unknown!") || strings.Contains(sections[0].body, "Unknown!") {
+		t.Errorf("sections = %+v", sections)
+	}
+}
@@ -0,0 +1,113 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned without running TrID while the circuit breaker
+// is open.
+var ErrUnavailable = errors.New("TrID is unavailable")
+
+// Breaker configures a circuit breaker that stops running TrID after repeated
+// failures indicating a broken installation, such as a missing or crashing
+// executable, processes timing out or missing definitions. While the circuit
+// is open, scans fail immediately with ErrUnavailable. Once the cooldown has
+// elapsed, a single scan is let through as a probe: if it succeeds the
+// circuit closes, otherwise it stays open for another cooldown.
+type Breaker struct {
+	Threshold int           // Number of consecutive failed processes opening the circuit; 0 disables the breaker.
+	Cooldown  time.Duration // Time the circuit stays open before a probe is let through (default: 30s).
+}
+
+// circuit holds the state of the circuit breaker.
+type circuit struct {
+	mu        sync.Mutex
+	failures  int       // Number of consecutive failed processes.
+	openUntil time.Time // End of the current cooldown, if the circuit is open.
+	probing   bool      // Whether a probe process is running.
+}
+
+// breakerAllow reports whether a TrID process may be started, returning
+// ErrUnavailable if not. probe is true if the process is the probe of an open
+// circuit.
+func (t *Trid) breakerAllow() (probe bool, err error) {
+	if t.options.Breaker.Threshold < 1 {
+		return false, nil
+	}
+
+	c := &t.circuit
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failures < t.options.Breaker.Threshold {
+		return false, nil
+	}
+
+	if c.probing || time.Now().Before(c.openUntil) {
+		return false, ErrUnavailable
+	}
+
+	c.probing = true
+
+	return true, nil
+}
+
+// breakerRecord updates the circuit breaker with the outcome of a TrID
+// process. p is nil if no process was started, which leaves the count of
+// failures unchanged.
+func (t *Trid) breakerRecord(ctx context.Context, probe bool, p *process, err error) {
+	if t.options.Breaker.Threshold < 1 {
+		return
+	}
+
+	c := &t.circuit
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if probe {
+		c.probing = false
+	}
+
+	if p == nil || ctx.Err() != nil {
+		// No process ran, or the caller gave up or ran out of time; the
+		// outcome says nothing about TrID
+		return
+	}
+
+	if !isInstallationFailure(p, err) {
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.failures >= t.options.Breaker.Threshold {
+		c.openUntil = time.Now().Add(t.options.Breaker.Cooldown)
+		t.log(ctx, slog.LevelWarn, "circuit breaker open", "failures", c.failures, "until", c.openUntil, "error", err)
+	}
+}
+
+// isInstallationFailure reports whether the outcome of a TrID process points
+// to a problem with the installation rather than with the scanned files.
+func isInstallationFailure(p *process, err error) bool {
+	if tridErr := p.check(p.stdout); errors.Is(tridErr, ErrNoDefinitions) || errors.Is(tridErr, ErrEmptyDefPackage) {
+		return true
+	}
+
+	if err == nil {
+		return false
+	}
+
+	// Processes exiting with a status code ran; only those killed by a
+	// signal count as failures
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode() == -1
+	}
+
+	return true
+}
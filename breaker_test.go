@@ -0,0 +1,127 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	cmd, calls := newStub(t, "No definitions available!\n")
+	setOutput := func(output string) {
+		if err := os.WriteFile(filepath.Join(filepath.Dir(cmd), "output.txt"), []byte(output), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trid := NewTrid(Options{Cmd: cmd, Breaker: Breaker{Threshold: 2, Cooldown: 50 * time.Millisecond}})
+
+	for i := 0; i < 2; i++ {
+		if _, err := trid.Scan(cmd, 1); !errors.Is(err, ErrNoDefinitions) {
+			t.Fatalf("Scan() error = %v, want %v", err, ErrNoDefinitions)
+		}
+	}
+
+	// The circuit is open: scans fail without running TrID
+	if _, err := trid.Scan(cmd, 1); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrUnavailable)
+	}
+
+	if _, err := trid.ScanBatch(context.Background(), []string{cmd}, 1); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("ScanBatch() error = %v, want %v", err, ErrUnavailable)
+	}
+
+	if got := calls(); got != 2 {
+		t.Fatalf("TrID invoked %d times, want 2", got)
+	}
+
+	// A failed probe keeps the circuit open
+	time.Sleep(60 * time.Millisecond)
+	if _, err := trid.Scan(cmd, 1); !errors.Is(err, ErrNoDefinitions) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrNoDefinitions)
+	}
+
+	if _, err := trid.Scan(cmd, 1); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrUnavailable)
+	}
+
+	// A successful probe closes it
+	setOutput(pdfOutput)
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := trid.Scan(cmd, 1); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+	}
+
+	if got := calls(); got != 5 {
+		t.Errorf("TrID invoked %d times, want 5", got)
+	}
+}
+
+func TestBreakerIgnoresFileErrors(t *testing.T) {
+	cmd, calls := newStub(t, unknownOutput)
+
+	trid := NewTrid(Options{Cmd: cmd, Breaker: Breaker{Threshold: 1}})
+
+	for i := 0; i < 3; i++ {
+		if _, err := trid.Scan(cmd, 1); !errors.Is(err, ErrUnknownFileType) {
+			t.Fatalf("Scan() error = %v, want %v", err, ErrUnknownFileType)
+		}
+	}
+
+	if got := calls(); got != 3 {
+		t.Errorf("TrID invoked %d times, want 3", got)
+	}
+}
+
+func TestBreakerMissingExecutable(t *testing.T) {
+	trid := NewTrid(Options{
+		Cmd:     filepath.Join(t.TempDir(), "missing"),
+		Breaker: Breaker{Threshold: 1, Cooldown: time.Hour},
+	})
+
+	if _, err := trid.Scan("testdata/sample.pdf", 1); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Scan() error = %v, want an execution error", err)
+	}
+
+	if _, err := trid.Scan("testdata/sample.pdf", 1); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrUnavailable)
+	}
+}
+
+func TestBreakerIgnoresCallerErrors(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+	limiter := NewLimiter(1)
+
+	trid := NewTrid(Options{Cmd: cmd, Limiter: limiter, Breaker: Breaker{Threshold: 2, Cooldown: time.Hour}})
+
+	// Scans waiting for a busy slot until their context expires start no
+	// process, so they say nothing about TrID
+	if err := limiter.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := trid.ScanContext(ctx, cmd, 1)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("ScanContext() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+
+	limiter.Release()
+
+	if _, err := trid.Scan(cmd, 1); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if got := calls(); got != 1 {
+		t.Errorf("TrID invoked %d times, want 1", got)
+	}
+}
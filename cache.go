@@ -0,0 +1,143 @@
+package trid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Cache stores scan results. Keys are derived from the file contents, the
+// definitions fingerprint, the engine, Options.ExtraArgs and the number of
+// matches requested, so identical files are only identified once and updating
+// the definitions or changing the options affecting results invalidates
+// earlier results. Implementations must be safe for concurrent use;
+// see the cache subpackage for ready-made implementations.
+type Cache interface {
+	// Get returns the results stored under key, if any.
+	Get(ctx context.Context, key string) ([]FileType, bool, error)
+
+	// Set stores results under key. A ttl of 0 means the entry does not
+	// expire.
+	Set(ctx context.Context, key string, fileTypes []FileType, ttl time.Duration) error
+
+	// Delete removes the entry stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// cacheKeys holds the keys a scan is cached under.
+type cacheKeys struct {
+	result  string // Key of successful results, including the number of matches.
+	unknown string // Key of ErrUnknownFileType results.
+}
+
+// scanCached scans a file through the configured cache. Cache failures are
+// treated as misses, so an unavailable cache never fails a scan.
+func (t *Trid) scanCached(ctx context.Context, filePath string, numberOfMatches int) (*Report, error) {
+	keys, fileTypes, hit, err := t.cacheLookup(ctx, filePath, numberOfMatches)
+	t.stats.cacheLookup(hit)
+	if hit {
+		t.log(ctx, slog.LevelDebug, "cache hit", "path", filePath, "matches", len(fileTypes), "error", err)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Report{FileTypes: fileTypes}, nil
+	}
+
+	r, err := t.run(ctx, filePath, numberOfMatches)
+	if err != nil {
+		t.cacheStore(ctx, keys, nil, err)
+		return nil, err
+	}
+
+	t.cacheStore(ctx, keys, r.FileTypes, nil)
+
+	return r, nil
+}
+
+// cacheLookup returns the cached result of scanning a file, and whether it was
+// found. A cached unidentified file is returned as ErrUnknownFileType. The
+// returned keys are empty if the file could not be hashed.
+func (t *Trid) cacheLookup(ctx context.Context, filePath string, numberOfMatches int) (cacheKeys, []FileType, bool, error) {
+	contentKey, err := t.contentKey(ctx, filePath)
+	if err != nil {
+		return cacheKeys{}, nil, false, nil
+	}
+
+	keys := cacheKeys{
+		result:  contentKey + ":" + strconv.Itoa(numberOfMatches),
+		unknown: contentKey + ":unknown",
+	}
+
+	if fileTypes, ok, err := t.options.Cache.Get(ctx, keys.result); err == nil && ok && len(fileTypes) > 0 {
+		return keys, slices.Clone(fileTypes), true, nil
+	}
+
+	if t.options.NegativeCacheTTL > 0 {
+		if fileTypes, ok, err := t.options.Cache.Get(ctx, keys.unknown); err == nil && ok && len(fileTypes) == 0 {
+			return keys, nil, true, ErrUnknownFileType
+		}
+	}
+
+	return keys, nil, false, nil
+}
+
+// cacheStore caches the result of a scan. Successful results are stored under
+// a key that includes the number of matches. If NegativeCacheTTL is set, files
+// TrID cannot identify are stored as an empty result under a separate key,
+// independent of the number of matches.
+func (t *Trid) cacheStore(ctx context.Context, keys cacheKeys, fileTypes []FileType, err error) {
+	if keys.result == "" {
+		return
+	}
+
+	switch {
+	case err == nil && len(fileTypes) > 0:
+		t.options.Cache.Set(ctx, keys.result, slices.Clone(fileTypes), t.options.CacheTTL)
+	case errors.Is(err, ErrUnknownFileType) && t.options.NegativeCacheTTL > 0:
+		t.options.Cache.Set(ctx, keys.unknown, []FileType{}, t.options.NegativeCacheTTL)
+	}
+}
+
+// contentKey returns the part of the cache keys identifying a file: the
+// SHA-256 checksums of its contents and of the definitions package, followed
+// by the options affecting results.
+func (t *Trid) contentKey(ctx context.Context, filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	defsSum, err := t.definitionsFingerprint(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) + ":" + defsSum + ":" + t.optionsKey(), nil
+}
+
+// optionsKey returns the part of the cache keys identifying the options that
+// affect results: the engine and a checksum of the extra TrID arguments.
+func (t *Trid) optionsKey() string {
+	h := sha256.New()
+	for _, arg := range t.options.ExtraArgs {
+		// Length-prefixed, so arguments cannot run into each other
+		h.Write([]byte(strconv.Itoa(len(arg)) + ":" + arg))
+	}
+
+	return t.options.Engine.String() + ":" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// Compile-time checks that Disk implements the cache interfaces.
+var (
+	_ trid.Cache  = (*Disk)(nil)
+	_ trid.Purger = (*Disk)(nil)
+)
+
+// Disk is a persistent cache storing each entry as a file below a directory,
+// so results survive process restarts. When the total size of the entries
+// exceeds the limit, the least recently used entries are evicted.
+type Disk struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	total    int64
+	entries  map[string]*diskEntry
+}
+
+// diskEntry tracks the size and last use of a cache file.
+type diskEntry struct {
+	size    int64
+	lastUse time.Time
+}
+
+// diskRecord is the content of a cache file.
+type diskRecord struct {
+	Key       string          `json:"key"`
+	FileTypes []trid.FileType `json:"file_types"`
+	Expires   time.Time       `json:"expires,omitempty"`
+}
+
+// NewDisk opens the cache stored in dir, creating the directory if needed.
+// The total size of the cache files is kept below maxBytes; a limit of 0 or
+// less disables eviction.
+func NewDisk(dir string, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &Disk{dir: dir, maxBytes: maxBytes, entries: make(map[string]*diskEntry)}
+
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		d.entries[path] = &diskEntry{size: fi.Size(), lastUse: fi.ModTime()}
+		d.total += fi.Size()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.evict()
+
+	return d, nil
+}
+
+// Get implements trid.Cache.
+func (d *Disk) Get(ctx context.Context, key string) ([]trid.FileType, bool, error) {
+	path := d.path(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[path]
+	if !ok {
+		return nil, false, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		d.remove(path)
+		return nil, false, err
+	}
+
+	var rec diskRecord
+	if err := json.Unmarshal(b, &rec); err != nil || rec.Key != key {
+		return nil, false, err
+	}
+
+	if !rec.Expires.IsZero() && time.Now().After(rec.Expires) {
+		d.remove(path)
+		return nil, false, nil
+	}
+
+	// Record the use in the file's modification time so it survives restarts
+	e.lastUse = time.Now()
+	os.Chtimes(path, e.lastUse, e.lastUse)
+
+	return rec.FileTypes, true, nil
+}
+
+// Set implements trid.Cache.
+func (d *Disk) Set(ctx context.Context, key string, fileTypes []trid.FileType, ttl time.Duration) error {
+	rec := diskRecord{Key: key, FileTypes: fileTypes}
+	if ttl > 0 {
+		rec.Expires = time.Now().Add(ttl)
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	path := d.path(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if e, ok := d.entries[path]; ok {
+		d.total -= e.size
+	}
+
+	d.entries[path] = &diskEntry{size: int64(len(b)), lastUse: time.Now()}
+	d.total += int64(len(b))
+
+	d.evict()
+
+	return nil
+}
+
+// Delete implements trid.Cache.
+func (d *Disk) Delete(ctx context.Context, key string) error {
+	path := d.path(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.remove(path)
+
+	return nil
+}
+
+// Purge implements trid.Purger.
+func (d *Disk) Purge(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for path := range d.entries {
+		d.remove(path)
+	}
+
+	return nil
+}
+
+// Size returns the total size of the cache files in bytes.
+func (d *Disk) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.total
+}
+
+// path returns the file storing key. Files are spread over subdirectories to
+// keep directories small.
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(d.dir, name[:2], name+".json")
+}
+
+// evict removes the least recently used entries until the cache is within
+// its size limit. The caller must hold d.mu.
+func (d *Disk) evict() {
+	if d.maxBytes <= 0 || d.total <= d.maxBytes {
+		return
+	}
+
+	paths := make([]string, 0, len(d.entries))
+	for path := range d.entries {
+		paths = append(paths, path)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return d.entries[paths[i]].lastUse.Before(d.entries[paths[j]].lastUse)
+	})
+
+	for _, path := range paths {
+		if d.total <= d.maxBytes {
+			break
+		}
+
+		d.remove(path)
+	}
+}
+
+// remove deletes an entry. The caller must hold d.mu.
+func (d *Disk) remove(path string) {
+	if e, ok := d.entries[path]; ok {
+		d.total -= e.size
+		delete(d.entries, path)
+	}
+
+	os.Remove(path)
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestDisk(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	d, err := NewDisk(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Set(ctx, "a", []trid.FileType{{Extension: ".a", Probability: 50}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	d.Set(ctx, "short", []trid.FileType{{Extension: ".s"}}, 20*time.Millisecond)
+	d.Set(ctx, "deleted", []trid.FileType{{Extension: ".d"}}, 0)
+
+	if _, ok, _ := d.Get(ctx, "missing"); ok {
+		t.Error("Get(missing) found an entry")
+	}
+
+	if err := d.Delete(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the cache to check that entries persist
+	d, err = NewDisk(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft, ok, err := d.Get(ctx, "a")
+	if err != nil || !ok || len(ft) != 1 || ft[0].Extension != ".a" || ft[0].Probability != 50 {
+		t.Errorf("Get(a) = %v, %v, %v after reopening", ft, ok, err)
+	}
+
+	if _, ok, _ := d.Get(ctx, "deleted"); ok {
+		t.Error("Get(deleted) found a deleted entry")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := d.Get(ctx, "short"); ok {
+		t.Error("Get(short) returned an expired entry")
+	}
+}
+
+func TestDiskEviction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	d, err := NewDisk(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+	entrySize := d.Size()
+
+	d, err = NewDisk(dir, 2*entrySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	d.Set(ctx, "b", []trid.FileType{{Extension: ".b"}}, 0)
+	time.Sleep(10 * time.Millisecond)
+
+	// Use "a" so that "b" becomes the least recently used entry
+	d.Get(ctx, "a")
+	time.Sleep(10 * time.Millisecond)
+	d.Set(ctx, "c", []trid.FileType{{Extension: ".c"}}, 0)
+
+	if _, ok, _ := d.Get(ctx, "b"); ok {
+		t.Error("Get(b) found evicted entry")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := d.Get(ctx, key); !ok {
+			t.Errorf("Get(%s) missing", key)
+		}
+	}
+
+	if d.Size() > 2*entrySize {
+		t.Errorf("Size() = %d, exceeds limit %d", d.Size(), 2*entrySize)
+	}
+
+	// Reopening with a smaller limit evicts immediately
+	d, err = NewDisk(dir, entrySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Size() > entrySize {
+		t.Errorf("Size() = %d after reopening, exceeds limit %d", d.Size(), entrySize)
+	}
+}
+
+func TestDiskPurge(t *testing.T) {
+	ctx := context.Background()
+
+	d, err := NewDisk(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+	d.Set(ctx, "b", []trid.FileType{{Extension: ".b"}}, 0)
+	d.Purge(ctx)
+
+	if _, ok, _ := d.Get(ctx, "a"); ok || d.Size() != 0 {
+		t.Error("Purge() did not remove all entries")
+	}
+}
@@ -0,0 +1,132 @@
+// Package cache provides implementations of trid.Cache.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// Compile-time checks that LRU implements the cache interfaces.
+var (
+	_ trid.Cache  = (*LRU)(nil)
+	_ trid.Purger = (*LRU)(nil)
+)
+
+// LRU is an in-memory cache holding a fixed number of entries, evicting the
+// least recently used entry when full.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// lruEntry is an element of the LRU list.
+type lruEntry struct {
+	key       string
+	fileTypes []trid.FileType
+	expires   time.Time
+}
+
+// NewLRU returns an LRU cache holding up to capacity entries. A capacity below
+// 1 is treated as 1.
+func NewLRU(capacity int) *LRU {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements trid.Cache.
+func (c *LRU) Get(ctx context.Context, key string) ([]trid.FileType, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.removeElement(el)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+
+	return e.fileTypes, true, nil
+}
+
+// Set implements trid.Cache.
+func (c *LRU) Set(ctx context.Context, key string, fileTypes []trid.FileType, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.fileTypes = fileTypes
+		e.expires = expires
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, fileTypes: fileTypes, expires: expires})
+
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+
+	return nil
+}
+
+// Delete implements trid.Cache.
+func (c *LRU) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+
+	return nil
+}
+
+// Purge implements trid.Purger.
+func (c *LRU) Purge(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// removeElement removes an entry. The caller must hold c.mu.
+func (c *LRU) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+	c.Set(ctx, "b", []trid.FileType{{Extension: ".b"}}, 0)
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	if ft, ok, _ := c.Get(ctx, "a"); !ok || ft[0].Extension != ".a" {
+		t.Fatalf("Get(a) = %v, %v", ft, ok)
+	}
+
+	c.Set(ctx, "c", []trid.FileType{{Extension: ".c"}}, 0)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("Get(b) found evicted entry")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("Get(%s) missing", key)
+		}
+	}
+
+	c.Set(ctx, "c", []trid.FileType{{Extension: ".updated"}}, 0)
+	if ft, _, _ := c.Get(ctx, "c"); ft[0].Extension != ".updated" {
+		t.Errorf("Get(c) = %v, want updated entry", ft)
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	c.Delete(ctx, "c")
+	if _, ok, _ := c.Get(ctx, "c"); ok || c.Len() != 1 {
+		t.Error("Delete(c) did not remove the entry")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "short", []trid.FileType{{Extension: ".a"}}, 20*time.Millisecond)
+	c.Set(ctx, "forever", []trid.FileType{{Extension: ".b"}}, 0)
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("Get(short) returned an expired entry")
+	}
+
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("Get(forever) missing")
+	}
+}
+
+func TestLRUPurge(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+	c.Set(ctx, "b", []trid.FileType{{Extension: ".b"}}, 0)
+	c.Purge(ctx)
+
+	if _, ok, _ := c.Get(ctx, "a"); ok || c.Len() != 0 {
+		t.Error("Purge() did not remove all entries")
+	}
+}
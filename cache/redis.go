@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrRedisClosed is returned when using a Redis cache that has been closed.
+var ErrRedisClosed = errors.New("redis cache closed")
+
+// RedisOptions configures a Redis cache.
+type RedisOptions struct {
+	Addr        string        // Address of the Redis server (default: "localhost:6379").
+	Username    string        // Username for ACL authentication.
+	Password    string        // Password; AUTH is skipped if empty.
+	DB          int           // Database to select.
+	Prefix      string        // Prefix added to every key (default: "trid:").
+	PoolSize    int           // Maximum number of idle connections kept open (default: 10).
+	DialTimeout time.Duration // Timeout for establishing connections (default: 5s).
+	TLSConfig   *tls.Config   // TLS configuration; plain TCP is used if nil.
+}
+
+// Compile-time checks that Redis implements the cache interfaces.
+var (
+	_ trid.Cache  = (*Redis)(nil)
+	_ trid.Purger = (*Redis)(nil)
+)
+
+// Redis is a cache shared between processes through a Redis server. It speaks
+// the Redis protocol directly, so no client library is required.
+type Redis struct {
+	opts RedisOptions
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+// redisConn is a connection to the Redis server.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedis returns a cache backed by the Redis server described by opts.
+// Connections are established lazily.
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = "trid:"
+	}
+
+	if opts.PoolSize < 1 {
+		opts.PoolSize = 10
+	}
+
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+
+	return &Redis{opts: opts}
+}
+
+// Get implements trid.Cache.
+func (r *Redis) Get(ctx context.Context, key string) ([]trid.FileType, bool, error) {
+	reply, err := r.do(ctx, "GET", r.opts.Prefix+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %T", reply)
+	}
+
+	var fileTypes []trid.FileType
+	if err := json.Unmarshal(b, &fileTypes); err != nil {
+		return nil, false, err
+	}
+
+	return fileTypes, true, nil
+}
+
+// Set implements trid.Cache.
+func (r *Redis) Set(ctx context.Context, key string, fileTypes []trid.FileType, ttl time.Duration) error {
+	b, err := json.Marshal(fileTypes)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"SET", r.opts.Prefix + key, string(b)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+
+	_, err = r.do(ctx, args...)
+
+	return err
+}
+
+// Delete implements trid.Cache.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", r.opts.Prefix+key)
+	return err
+}
+
+// Purge implements trid.Purger by deleting every key with the configured
+// prefix. Glob characters of the prefix match literally.
+func (r *Redis) Purge(ctx context.Context) error {
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", globEscape(r.opts.Prefix)+"*", "COUNT", "1000")
+		if err != nil {
+			return err
+		}
+
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return fmt.Errorf("redis: unexpected SCAN reply %v", reply)
+		}
+
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]any)
+
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, k := range keys {
+				if b, ok := k.([]byte); ok {
+					args = append(args, string(b))
+				}
+			}
+
+			if _, err := r.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// globEscape escapes the characters of s that have a special meaning in the
+// glob-style patterns of SCAN MATCH.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Close closes all idle connections. Subsequent operations fail with
+// ErrRedisClosed.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	for _, c := range r.idle {
+		c.conn.Close()
+	}
+	r.idle = nil
+
+	return nil
+}
+
+// do sends a command and returns its reply. Error replies are returned as
+// errors; the connection is reused unless an I/O error occurred.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	c, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			c.conn.Close()
+			return nil, err
+		}
+	}
+
+	r.put(c)
+
+	return reply, err
+}
+
+// get returns an idle connection or dials a new one.
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrRedisClosed
+	}
+
+	if n := len(r.idle); n > 0 {
+		c := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return c, nil
+	}
+	r.mu.Unlock()
+
+	return r.dial(ctx)
+}
+
+// put returns a connection to the pool, closing it if the pool is full.
+func (r *Redis) put(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || len(r.idle) >= r.opts.PoolSize {
+		c.conn.Close()
+		return
+	}
+
+	r.idle = append(r.idle, c)
+}
+
+// dial opens and initializes a new connection.
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: r.opts.DialTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if r.opts.TLSConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: r.opts.TLSConfig}).DialContext(ctx, "tcp", r.opts.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.opts.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if r.opts.Password != "" {
+		args := []string{"AUTH", r.opts.Password}
+		if r.opts.Username != "" {
+			args = []string{"AUTH", r.opts.Username, r.opts.Password}
+		}
+
+		if _, err := c.do(ctx, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if r.opts.DB != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(r.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// do writes a command and reads its reply, honouring the context deadline.
+// If the context is cancelled before do returns, the error of the context is
+// returned, so the connection is not reused: its deadline may have been moved
+// to the past after the reply was read.
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+
+	// Abort blocking I/O when the context is cancelled
+	stop := context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Unix(1, 0)) })
+
+	reply, err := c.roundTrip(args...)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+
+	return reply, err
+}
+
+// roundTrip writes a command and reads its reply.
+func (c *redisConn) roundTrip(args ...string) (any, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readReply reads a single RESP reply.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
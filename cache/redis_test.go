@@ -0,0 +1,294 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// fakeRedis is a minimal in-process Redis server supporting the commands used
+// by the cache.
+type fakeRedis struct {
+	mu       sync.Mutex
+	data     map[string]string
+	expires  map[string]time.Time
+	password string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{data: make(map[string]string), expires: make(map[string]time.Time), password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			b := make([]byte, size+2)
+			io.ReadFull(r, b)
+			args[i] = string(b[:size])
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == f.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "GET":
+			v, ok := f.data[args[1]]
+			if exp, has := f.expires[args[1]]; has && time.Now().After(exp) {
+				ok = false
+			}
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			}
+		case args[0] == "SET":
+			f.data[args[1]] = args[2]
+			delete(f.expires, args[1])
+			if len(args) == 5 && args[3] == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			reply = "+OK\r\n"
+		case args[0] == "DEL":
+			for _, k := range args[1:] {
+				delete(f.data, k)
+			}
+			reply = fmt.Sprintf(":%d\r\n", len(args)-1)
+		case args[0] == "SCAN":
+			// Return all matching keys in a single iteration
+			var keys strings.Builder
+			count := 0
+			for k := range f.data {
+				if ok, _ := path.Match(args[3], k); ok {
+					fmt.Fprintf(&keys, "$%d\r\n%s\r\n", len(k), k)
+					count++
+				}
+			}
+			reply = fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", count, keys.String())
+		case args[0] == "SELECT":
+			reply = "+OK\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		conn.Write([]byte(reply))
+	}
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	f, addr := startFakeRedis(t, "secret")
+
+	r := NewRedis(RedisOptions{Addr: addr, Password: "secret", DB: 2})
+	defer r.Close()
+
+	if _, ok, err := r.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(a) = %v, %v on empty cache", ok, err)
+	}
+
+	if err := r.Set(ctx, "a", []trid.FileType{{Extension: ".a", Probability: 12.5}}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ft, ok, err := r.Get(ctx, "a")
+	if err != nil || !ok || len(ft) != 1 || ft[0].Extension != ".a" || ft[0].Probability != 12.5 {
+		t.Errorf("Get(a) = %v, %v, %v", ft, ok, err)
+	}
+
+	r.Set(ctx, "short", []trid.FileType{{Extension: ".s"}}, 20*time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := r.Get(ctx, "short"); ok {
+		t.Error("Get(short) returned an expired entry")
+	}
+
+	if err := r.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := r.Get(ctx, "a"); ok {
+		t.Error("Get(a) found a deleted entry")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.data["trid:short"]; !ok {
+		t.Error("keys are not prefixed")
+	}
+
+	// The connection is reused, so AUTH and SELECT are only sent once
+	if cmds := strings.Join(f.commands, " "); strings.Count(cmds, "AUTH") != 1 || strings.Count(cmds, "SELECT") != 1 {
+		t.Errorf("unexpected commands: %s", cmds)
+	}
+}
+
+func TestRedisErrors(t *testing.T) {
+	ctx := context.Background()
+	_, addr := startFakeRedis(t, "secret")
+
+	r := NewRedis(RedisOptions{Addr: addr, Password: "wrong"})
+	if _, _, err := r.Get(ctx, "a"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Get() error = %v, want WRONGPASS", err)
+	}
+
+	r.Close()
+	if _, _, err := r.Get(ctx, "a"); err != ErrRedisClosed {
+		t.Errorf("Get() error = %v, want ErrRedisClosed", err)
+	}
+}
+
+func TestRedisPurge(t *testing.T) {
+	ctx := context.Background()
+	f, addr := startFakeRedis(t, "")
+
+	f.data["other:key"] = "[]"
+
+	r := NewRedis(RedisOptions{Addr: addr})
+	defer r.Close()
+
+	r.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+	r.Set(ctx, "b", []trid.FileType{{Extension: ".b"}}, 0)
+
+	if err := r.Purge(ctx); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.data) != 1 || f.data["other:key"] != "[]" {
+		t.Errorf("Purge() left %v, want only other:key", f.data)
+	}
+}
+
+func TestRedisPurgeGlobPrefix(t *testing.T) {
+	ctx := context.Background()
+	f, addr := startFakeRedis(t, "")
+
+	// Unescaped, the prefix would match these keys as a pattern
+	f.data["ab:key"] = "[]"
+	f.data["x:key"] = "[]"
+
+	r := NewRedis(RedisOptions{Addr: addr, Prefix: "a*:"})
+	defer r.Close()
+
+	r.Set(ctx, "a", []trid.FileType{{Extension: ".a"}}, 0)
+
+	if err := r.Purge(ctx); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.data) != 2 || f.data["ab:key"] != "[]" || f.data["x:key"] != "[]" {
+		t.Errorf("Purge() left %v, want only ab:key and x:key", f.data)
+	}
+
+	if got := globEscape(`a*b?c[d]e\f`); got != `a\*b\?c\[d\]e\\f` {
+		t.Errorf("globEscape() = %s", got)
+	}
+}
+
+// cancelConn cancels a context once the reply has been read, and waits for
+// the cancellation to move the deadline of the connection to the past.
+type cancelConn struct {
+	net.Conn
+	cancel context.CancelFunc
+	moved  chan struct{}
+	once   sync.Once
+}
+
+func (c *cancelConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.cancel()
+
+	select {
+	case <-c.moved:
+	case <-time.After(time.Second):
+	}
+
+	return n, err
+}
+
+func (c *cancelConn) SetDeadline(t time.Time) error {
+	if !t.IsZero() && t.Before(time.Now()) {
+		c.once.Do(func() { close(c.moved) })
+	}
+
+	return c.Conn.SetDeadline(t)
+}
+
+func TestRedisCancelled(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		io.ReadFull(server, make([]byte, len("*2\r\n$3\r\nGET\r\n$1\r\na\r\n")))
+		server.Write([]byte("$-1\r\n"))
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &cancelConn{Conn: client, cancel: cancel, moved: make(chan struct{})}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	// A connection whose deadline was moved after the reply was read must not
+	// be reused, so the command fails
+	if _, err := c.do(ctx, "GET", "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,304 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const pdfOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: sample.pdf
+100.0% (.PDF) Adobe Portable Document Format (5000/1)
+        Mime type       : application/pdf
+        Definition      : pdf-generic.trid.xml
+`
+
+// newStub creates a stub TrID executable, next to a definitions package, that
+// prints output and records each invocation. It returns the command and a function reporting the number of
+// invocations so far.
+func newStub(t *testing.T, output string) (string, func() int) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	outFile := filepath.Join(dir, "output.txt")
+	callsFile := filepath.Join(dir, "calls.txt")
+	if err := os.WriteFile(outFile, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\necho x >> '" + callsFile + "'\ncat '" + outFile + "'\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "triddefs.trd"), []byte("definitions"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, func() int {
+		b, _ := os.ReadFile(callsFile)
+		return strings.Count(string(b), "x")
+	}
+}
+
+// mapCache is a minimal Cache for tests.
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string][]FileType
+	purges  int
+}
+
+func (m *mapCache) Purge(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[string][]FileType)
+	m.purges++
+	return nil
+}
+
+func (m *mapCache) Get(ctx context.Context, key string) ([]FileType, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ft, ok := m.entries[key]
+	return ft, ok, nil
+}
+
+func (m *mapCache) Set(ctx context.Context, key string, fileTypes []FileType, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = fileTypes
+	return nil
+}
+
+func (m *mapCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+func TestScanCache(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.pdf")
+	second := filepath.Join(dir, "second.pdf")
+	other := filepath.Join(dir, "other.pdf")
+	os.WriteFile(first, []byte("%PDF-1.4"), 0o644)
+	os.WriteFile(second, []byte("%PDF-1.4"), 0o644)
+	os.WriteFile(other, []byte("%PDF-1.7"), 0o644)
+
+	trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}})
+
+	for _, path := range []string{first, second, first} {
+		results, err := trid.Scan(path, 1)
+		if err != nil {
+			t.Fatalf("Scan(%s) error = %v", path, err)
+		}
+
+		if len(results) != 1 || results[0].Extension != ".pdf" {
+			t.Errorf("Scan(%s) = %+v", path, results)
+		}
+	}
+
+	if calls() != 1 {
+		t.Errorf("identical files invoked TrID %d times, want 1", calls())
+	}
+
+	trid.Scan(other, 1)
+	trid.Scan(first, 2)
+	if calls() != 3 {
+		t.Errorf("TrID invoked %d times, want 3 after different content and match count", calls())
+	}
+
+	// Modifying a returned slice must not affect the cache
+	results, _ := trid.Scan(first, 1)
+	results[0].Extension = ".modified"
+	if results, _ := trid.Scan(first, 1); results[0].Extension != ".pdf" {
+		t.Errorf("cached result was modified: %+v", results)
+	}
+}
+
+const unknownOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: sample.unknown
+Unknown!
+`
+
+func TestScanNegativeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.unknown")
+	os.WriteFile(path, []byte{0x00, 0x01, 0x02}, 0o644)
+
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		expectedCalls int
+	}{
+		{"Disabled", 0, 3},
+		{"Enabled", time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, calls := newStub(t, unknownOutput)
+			trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}, NegativeCacheTTL: tt.ttl})
+
+			// The negative entry applies regardless of the number of matches
+			for _, n := range []int{1, 1, 5} {
+				if _, err := trid.Scan(path, n); !errors.Is(err, ErrUnknownFileType) {
+					t.Fatalf("Scan() error = %v, want ErrUnknownFileType", err)
+				}
+			}
+
+			if calls() != tt.expectedCalls {
+				t.Errorf("TrID invoked %d times, want %d", calls(), tt.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestScanCacheDefinitionsChange(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+	defsPath := filepath.Join(filepath.Dir(cmd), "triddefs.trd")
+
+	path := filepath.Join(t.TempDir(), "sample.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0o644)
+
+	c := &mapCache{entries: make(map[string][]FileType)}
+	trid := NewTrid(Options{Cmd: cmd, Cache: c})
+
+	before, err := trid.DefinitionsFingerprint()
+	if err != nil {
+		t.Fatalf("DefinitionsFingerprint() error = %v", err)
+	}
+
+	trid.Scan(path, 1)
+	trid.Scan(path, 1)
+
+	// Replace the definitions with a different package
+	if err := os.WriteFile(defsPath, []byte("updated definitions"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(defsPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+
+	trid.Scan(path, 1)
+
+	if calls() != 2 {
+		t.Errorf("TrID invoked %d times, want 2", calls())
+	}
+
+	after, _ := trid.DefinitionsFingerprint()
+	if before == after {
+		t.Error("DefinitionsFingerprint() did not change after updating definitions")
+	}
+
+	if c.purges != 1 {
+		t.Errorf("cache purged %d times, want 1", c.purges)
+	}
+}
+
+func TestScanCacheOptions(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+
+	path := filepath.Join(t.TempDir(), "sample.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0o644)
+
+	// Instances sharing a cache only reuse results of identical options
+	c := &mapCache{entries: make(map[string][]FileType)}
+	for _, extra := range [][]string{nil, {"-w"}, {"-w"}, {"-w", "-r:3"}, {"-w-r:3"}} {
+		if _, err := NewTrid(Options{Cmd: cmd, Cache: c, ExtraArgs: extra}).Scan(path, 1); err != nil {
+			t.Fatalf("Scan() with %q error = %v", extra, err)
+		}
+	}
+
+	if calls() != 4 {
+		t.Errorf("TrID invoked %d times, want 4", calls())
+	}
+
+	cli, native := &Trid{options: Options{Engine: EngineCLI}}, &Trid{options: Options{Engine: EngineNative}}
+	if cli.optionsKey() == native.optionsKey() {
+		t.Error("optionsKey() does not depend on the engine")
+	}
+}
+
+func TestScanCacheWithoutDefinitions(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+	os.Remove(filepath.Join(filepath.Dir(cmd), "triddefs.trd"))
+
+	path := filepath.Join(t.TempDir(), "sample.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4"), 0o644)
+
+	trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}})
+
+	if _, err := trid.DefinitionsFingerprint(); !errors.Is(err, ErrNoDefinitions) {
+		t.Errorf("DefinitionsFingerprint() error = %v, want ErrNoDefinitions", err)
+	}
+
+	// Scans still succeed, bypassing the cache
+	for i := 0; i < 2; i++ {
+		if _, err := trid.Scan(path, 1); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+	}
+
+	if calls() != 2 {
+		t.Errorf("TrID invoked %d times, want 2", calls())
+	}
+}
+
+func TestScanRawBypassesCache(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+
+	trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}})
+
+	for i := 0; i < 2; i++ {
+		if _, err := trid.ScanReport(context.Background(), cmd, 1); err != nil {
+			t.Fatalf("ScanReport() error = %v", err)
+		}
+	}
+
+	// The second report is cached and has no raw output
+	r, err := trid.ScanReport(context.Background(), cmd, 1)
+	if err != nil {
+		t.Fatalf("ScanReport() error = %v", err)
+	}
+
+	if r.Raw != "" || calls() != 1 {
+		t.Errorf("ScanReport() = %+v after %d invocations, want a cached report", r, calls())
+	}
+
+	r, err = trid.ScanRaw(context.Background(), cmd, 1)
+	if err != nil {
+		t.Fatalf("ScanRaw() error = %v", err)
+	}
+
+	if r.Raw != pdfOutput || len(r.FileTypes) != 1 {
+		t.Errorf("ScanRaw() = %+v, want the parsed and raw output", r)
+	}
+
+	if got := calls(); got != 2 {
+		t.Errorf("TrID invoked %d times, want 2", got)
+	}
+}
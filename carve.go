@@ -0,0 +1,130 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrCarveEngine is returned by Carve unless the native engine is used, as
+// carving needs the definitions in memory.
+var ErrCarveEngine = errors.New("carving requires the native engine")
+
+// DefaultCarveWindow is the default CarveOptions.Window.
+const DefaultCarveWindow = 64 << 10
+
+// carveChunkSize is the size of the parts of a file read at once by Carve,
+// on top of the window.
+const carveChunkSize = 16 << 20
+
+// CarveOptions configures Carve.
+type CarveOptions struct {
+	// Window is the number of bytes identified from each offset at which a
+	// file may start (default: DefaultCarveWindow). Definitions looking for
+	// strings only find them within the window.
+	Window int64
+}
+
+// CarvedRegion is a region of a file matching a definition.
+type CarvedRegion struct {
+	Offset    int64      // Offset of the region.
+	Length    int64      // Length of the region in bytes, estimated unless Exact.
+	Exact     bool       // Whether Length was determined from the structure of the format, as by ScanOverlay.
+	FileTypes []FileType // Identified file types of the region.
+}
+
+// Carve reports every region of the file at filePath matching a definition,
+// such as the files embedded in firmware images or memory dumps, like binwalk
+// powered by TrID definitions. It requires the native engine and returns
+// ErrCarveEngine otherwise.
+//
+// The regions start at the offsets from which the longest pattern of a
+// definition is found at its offset, and are identified from the
+// opts.Window bytes following. The length of zip archives, PNG images, PE
+// executables and PDF documents is determined from their structure; that of
+// other regions is estimated as the distance to the next region or the end of
+// the file. Regions are reported in order and may overlap, such as the files
+// stored in an archive that is stored itself.
+func (t *Trid) Carve(ctx context.Context, filePath string, numberOfMatches int, opts CarveOptions) ([]CarvedRegion, error) {
+	if filePath == "" {
+		return nil, ErrNoFileSpecified
+	}
+
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	if t.optsErr != nil {
+		return nil, t.optsErr
+	}
+
+	if t.options.Engine != EngineNative {
+		return nil, ErrCarveEngine
+	}
+
+	if opts.Window <= 0 {
+		opts.Window = DefaultCarveWindow
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := fileSize(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []CarvedRegion
+	buf := make([]byte, carveChunkSize+opts.Window)
+	for base := int64(0); base < size; base += carveChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, err := f.ReadAt(buf, base)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		data := buf[:n]
+
+		// Offsets past the chunk are found again with the next one
+		for _, c := range t.native.Candidates(data) {
+			if c >= carveChunkSize {
+				break
+			}
+
+			r, err := t.identifyNative(ctx, filePath, data[c:min(int64(c)+opts.Window, int64(n))], numberOfMatches)
+			if errors.Is(err, ErrUnknownFileType) {
+				continue
+			}
+
+			fileTypes, err := t.refine(ctx, r.FileTypes)
+			if errors.Is(err, ErrUnknownFileType) {
+				continue
+			}
+
+			regions = append(regions, CarvedRegion{Offset: base + int64(c), FileTypes: fileTypes})
+		}
+	}
+
+	for i := range regions {
+		r := &regions[i]
+		if _, end := formatEnd(io.NewSectionReader(f, r.Offset, size-r.Offset), size-r.Offset); end > 0 {
+			r.Length, r.Exact = end, true
+		} else if i+1 < len(regions) {
+			r.Length = regions[i+1].Offset - r.Offset
+		} else {
+			r.Length = size - r.Offset
+		}
+	}
+
+	return regions, nil
+}
@@ -0,0 +1,49 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCarve(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	sevenZip := readFile(t, "testdata/sample.7z")
+
+	padding := bytes.Repeat([]byte{0xaa}, 1000)
+	blob := filepath.Join(t.TempDir(), "firmware.bin")
+	if err := os.WriteFile(blob, bytes.Join([][]byte{padding, pdf, padding, sevenZip, padding}, nil), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	regions, err := tr.Carve(context.Background(), blob, 1, CarveOptions{})
+	if err != nil {
+		t.Fatalf("Carve() error = %v", err)
+	}
+
+	sevenZipAt := int64(2000 + len(pdf))
+	expected := []CarvedRegion{
+		{Offset: 1000, Length: int64(len(pdf)), Exact: true},
+		{Offset: sevenZipAt, Length: int64(len(sevenZip)) + 1000},
+	}
+	exts := []string{".pdf", ".7z"}
+
+	if len(regions) != len(expected) {
+		t.Fatalf("Carve() = %+v, want %d regions", regions, len(expected))
+	}
+
+	for i, r := range regions {
+		if r.Offset != expected[i].Offset || r.Length != expected[i].Length || r.Exact != expected[i].Exact || r.FileTypes[0].Extension != exts[i] {
+			t.Errorf("region %d = %+v, want %+v of %s", i, r, expected[i], exts[i])
+		}
+	}
+
+	if _, err := NewTrid(Options{Cmd: "trid", Runner: RunnerFunc(nil)}).Carve(context.Background(), blob, 1, CarveOptions{}); !errors.Is(err, ErrCarveEngine) {
+		t.Errorf("Carve() with the CLI engine error = %v, want %v", err, ErrCarveEngine)
+	}
+}
@@ -0,0 +1,87 @@
+package trid
+
+import "strings"
+
+// Category is a broad classification of a file type, derived from its MIME type
+// and extension.
+type Category string
+
+// Known file type categories.
+const (
+	CategoryArchive    Category = "archive"
+	CategoryAudio      Category = "audio"
+	CategoryDocument   Category = "document"
+	CategoryExecutable Category = "executable"
+	CategoryFont       Category = "font"
+	CategoryImage      Category = "image"
+	CategoryText       Category = "text"
+	CategoryVideo      Category = "video"
+	CategoryOther      Category = "other"
+)
+
+// extCategories maps well-known extensions to their category.
+var extCategories = map[string]Category{
+	".7z": CategoryArchive, ".arj": CategoryArchive, ".bz2": CategoryArchive, ".cab": CategoryArchive,
+	".gz": CategoryArchive, ".iso": CategoryArchive, ".lz": CategoryArchive, ".lzh": CategoryArchive,
+	".rar": CategoryArchive, ".tar": CategoryArchive, ".xz": CategoryArchive, ".z": CategoryArchive,
+	".zip": CategoryArchive, ".zst": CategoryArchive,
+
+	".aac": CategoryAudio, ".aif": CategoryAudio, ".flac": CategoryAudio, ".m4a": CategoryAudio,
+	".mid": CategoryAudio, ".mp3": CategoryAudio, ".ogg": CategoryAudio, ".opus": CategoryAudio,
+	".wav": CategoryAudio, ".wma": CategoryAudio,
+
+	".doc": CategoryDocument, ".docx": CategoryDocument, ".epub": CategoryDocument, ".odp": CategoryDocument,
+	".ods": CategoryDocument, ".odt": CategoryDocument, ".pdf": CategoryDocument, ".ppt": CategoryDocument,
+	".pptx": CategoryDocument, ".ps": CategoryDocument, ".rtf": CategoryDocument, ".xls": CategoryDocument,
+	".xlsx": CategoryDocument,
+
+	".apk": CategoryExecutable, ".bat": CategoryExecutable, ".class": CategoryExecutable, ".com": CategoryExecutable,
+	".dll": CategoryExecutable, ".dylib": CategoryExecutable, ".elf": CategoryExecutable, ".exe": CategoryExecutable,
+	".jar": CategoryExecutable, ".msi": CategoryExecutable, ".o": CategoryExecutable, ".scr": CategoryExecutable,
+	".so": CategoryExecutable, ".sys": CategoryExecutable,
+
+	".otf": CategoryFont, ".ttf": CategoryFont, ".woff": CategoryFont, ".woff2": CategoryFont,
+
+	".bmp": CategoryImage, ".gif": CategoryImage, ".ico": CategoryImage, ".jpg": CategoryImage,
+	".jpeg": CategoryImage, ".png": CategoryImage, ".psd": CategoryImage, ".svg": CategoryImage,
+	".tif": CategoryImage, ".tiff": CategoryImage, ".webp": CategoryImage,
+
+	".csv": CategoryText, ".htm": CategoryText, ".html": CategoryText, ".json": CategoryText,
+	".md": CategoryText, ".txt": CategoryText, ".xml": CategoryText, ".yaml": CategoryText,
+
+	".avi": CategoryVideo, ".flv": CategoryVideo, ".m4v": CategoryVideo, ".mkv": CategoryVideo,
+	".mov": CategoryVideo, ".mp4": CategoryVideo, ".mpg": CategoryVideo, ".webm": CategoryVideo,
+	".wmv": CategoryVideo,
+}
+
+// Category returns the broad category of the file type. The extension is
+// consulted first, then the MIME type; CategoryOther is returned if neither is
+// recognized.
+func (f FileType) Category() Category {
+	// TrID may report several extensions separated by slashes (e.g., ".jpg/.jpeg")
+	for _, ext := range strings.Split(f.Extension, "/") {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+
+		if c, ok := extCategories[strings.ToLower(ext)]; ok {
+			return c
+		}
+	}
+
+	mime := strings.ToLower(f.MimeType)
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return CategoryImage
+	case strings.HasPrefix(mime, "audio/"):
+		return CategoryAudio
+	case strings.HasPrefix(mime, "video/"):
+		return CategoryVideo
+	case strings.HasPrefix(mime, "font/"):
+		return CategoryFont
+	case strings.HasPrefix(mime, "text/"):
+		return CategoryText
+	}
+
+	return CategoryOther
+}
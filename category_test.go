@@ -0,0 +1,25 @@
+package trid
+
+import "testing"
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType FileType
+		expected Category
+	}{
+		{"PDF by extension", FileType{Extension: ".pdf"}, CategoryDocument},
+		{"Upper case extension", FileType{Extension: ".ZIP"}, CategoryArchive},
+		{"Multiple extensions", FileType{Extension: ".foo/.jpg"}, CategoryImage},
+		{"MIME type fallback", FileType{Extension: ".xyz", MimeType: "video/x-custom"}, CategoryVideo},
+		{"Unknown", FileType{Extension: ".xyz"}, CategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fileType.Category(); got != tt.expected {
+				t.Errorf("Category() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package trid
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceCharset is the FileType.Source of matches of files TrID cannot
+// identify but whose encoding is detected as text.
+const SourceCharset = "charset"
+
+// charsetSampleSize is the number of leading bytes of a file the encoding is
+// detected from.
+const charsetSampleSize = 64 << 10
+
+// maxTextEntropy is the entropy, in bits per byte, above which unidentified
+// files are not considered text.
+const maxTextEntropy = 6
+
+// textMimeTypes are the MIME types of textual formats other than text/*.
+var textMimeTypes = map[string]bool{
+	"application/json": true, "application/xml": true, "application/javascript": true,
+	"application/x-sh": true, "application/x-yaml": true, "application/rtf": true,
+}
+
+// textExts are the extensions of textual formats, for matches without a MIME
+// type.
+var textExts = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".xml": true, ".html": true, ".htm": true,
+	".json": true, ".js": true, ".css": true, ".md": true, ".ini": true, ".log": true,
+	".srt": true, ".yaml": true, ".yml": true, ".sql": true, ".eml": true,
+}
+
+// BOMs of the Unicode encodings, longest first.
+var boms = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0x00, 0x00, 0xfe, 0xff}, "UTF-32BE"},
+	{[]byte{0xff, 0xfe, 0x00, 0x00}, "UTF-32LE"},
+	{[]byte{0xef, 0xbb, 0xbf}, "UTF-8"},
+	{[]byte{0xfe, 0xff}, "UTF-16BE"},
+	{[]byte{0xff, 0xfe}, "UTF-16LE"},
+}
+
+// DetectCharset returns the IANA name of the character encoding of the text
+// data, or "" if data does not look like text. UTF-8, UTF-16 and UTF-32 with
+// or without a byte order mark, US-ASCII, ISO-2022-JP, Shift_JIS, EUC-JP,
+// windows-1252 and ISO-8859-1 are recognized; single-byte encodings other
+// than windows-1252 are reported as ISO-8859-1.
+func DetectCharset(data []byte) string {
+	for _, b := range boms {
+		if bytes.HasPrefix(data, b.bom) {
+			return b.charset
+		}
+	}
+
+	if charset := utf16Charset(data); charset != "" {
+		return charset
+	}
+
+	ascii := true
+	for _, c := range data {
+		switch {
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' && c != 0x1b:
+			return ""
+		case c >= 0x80:
+			ascii = false
+		}
+	}
+
+	switch {
+	case ascii && bytes.Contains(data, []byte("\x1b$B")):
+		return "ISO-2022-JP"
+	case ascii:
+		return "US-ASCII"
+	case utf8.Valid(trimIncompleteRune(data)):
+		return "UTF-8"
+	case isJapanese(data, sjisPair, 0x82, 0x83):
+		return "Shift_JIS"
+	case isJapanese(data, eucJPPair, 0xa4, 0xa5):
+		return "EUC-JP"
+	}
+
+	for _, c := range data {
+		if c >= 0x80 && c < 0xa0 {
+			return "windows-1252"
+		}
+	}
+
+	return "ISO-8859-1"
+}
+
+// utf16Charset detects UTF-16 text without a byte order mark from the zero
+// bytes of ASCII characters, returning "" if data is not UTF-16.
+func utf16Charset(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	var even, odd int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 && data[i+1] != 0 {
+			even++
+		}
+		if data[i+1] == 0 && data[i] != 0 {
+			odd++
+		}
+	}
+
+	pairs := len(data) / 2
+	switch {
+	case odd*10 >= pairs*7 && even == 0:
+		return "UTF-16LE"
+	case even*10 >= pairs*7 && odd == 0:
+		return "UTF-16BE"
+	default:
+		return ""
+	}
+}
+
+// trimIncompleteRune removes a UTF-8 sequence cut at the end of data.
+func trimIncompleteRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if c := data[len(data)-i]; utf8.RuneStart(c) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+
+	return data
+}
+
+// isJapanese reports whether data is valid in a double-byte Japanese
+// encoding, whose pairs are recognized by pair, and whether enough of them
+// start with the lead bytes of hiragana and katakana, to tell it from other
+// encodings data may be valid in.
+func isJapanese(data []byte, pair func(data []byte) (int, bool), kana ...byte) bool {
+	var pairs, kanaPairs int
+	for i := 0; i < len(data); {
+		if data[i] < 0x80 {
+			i++
+			continue
+		}
+
+		n, ok := pair(data[i:])
+		if !ok {
+			// A pair cut at the end of the sample
+			return i == len(data)-1 && pairs > 0 && kanaPairs*5 >= pairs
+		}
+
+		if n == 2 {
+			pairs++
+			if bytes.IndexByte(kana, data[i]) >= 0 {
+				kanaPairs++
+			}
+		}
+		i += n
+	}
+
+	return pairs > 0 && kanaPairs*5 >= pairs
+}
+
+// sjisPair returns the length of the Shift_JIS character at the start of
+// data, which starts with a byte above 0x7f.
+func sjisPair(data []byte) (int, bool) {
+	c := data[0]
+	switch {
+	case c >= 0xa1 && c <= 0xdf:
+		return 1, true
+	case (c >= 0x81 && c <= 0x9f || c >= 0xe0 && c <= 0xfc) && len(data) > 1:
+		t := data[1]
+		return 2, t >= 0x40 && t <= 0xfc && t != 0x7f
+	default:
+		return 0, false
+	}
+}
+
+// eucJPPair returns the length of the EUC-JP character at the start of data,
+// which starts with a byte above 0x7f. Supplementary characters are not
+// recognized.
+func eucJPPair(data []byte) (int, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+
+	c, t := data[0], data[1]
+	switch {
+	case c == 0x8e:
+		return 2, t >= 0xa1 && t <= 0xdf
+	case c >= 0xa1 && c <= 0xfe:
+		return 2, t >= 0xa1 && t <= 0xfe
+	default:
+		return 0, false
+	}
+}
+
+// entropy returns the Shannon entropy of data in bits per byte.
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, c := range data {
+		counts[c]++
+	}
+
+	var h float64
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(data))
+			h -= p * math.Log2(p)
+		}
+	}
+
+	return h
+}
+
+// isTextual reports whether ft is a textual format.
+func isTextual(ft FileType) bool {
+	mimeType := mediaType(ft.MimeType)
+	return strings.HasPrefix(mimeType, "text/") || textMimeTypes[mimeType] ||
+		strings.HasSuffix(mimeType, "+xml") || strings.HasSuffix(mimeType, "+json") ||
+		ft.MimeType == "" && textExts[strings.ToLower(ft.Extension)]
+}
+
+// reportCharset is detectCharset for the report of a scan.
+func (t *Trid) reportCharset(path string, r *Report, err error) (*Report, error) {
+	var fileTypes []FileType
+	if err == nil {
+		fileTypes = r.FileTypes
+	}
+
+	fileTypes, charset, err := t.detectCharset(path, fileTypes, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		r = &Report{}
+	}
+	r.FileTypes, r.Charset = fileTypes, charset
+
+	return r, nil
+}
+
+// detectCharset detects the encoding of the file at path, if Options.Charset
+// is set, given the outcome of its identification. It returns the charset of
+// textual files and, for files TrID failed to identify with
+// ErrUnknownFileType, a low-probability text match if they look like text.
+func (t *Trid) detectCharset(path string, fileTypes []FileType, err error) ([]FileType, string, error) {
+	if !t.options.Charset || err != nil && !errors.Is(err, ErrUnknownFileType) || err == nil && !isTextual(fileTypes[0]) {
+		return fileTypes, "", err
+	}
+
+	sample, readErr := readHead(path, charsetSampleSize)
+	if readErr != nil || len(sample) == 0 {
+		return fileTypes, "", err
+	}
+
+	charset := DetectCharset(sample)
+	if err == nil || charset == "" {
+		return fileTypes, charset, err
+	}
+
+	if entropy(sample) > maxTextEntropy {
+		return fileTypes, "", err
+	}
+
+	return []FileType{{
+		Extension:   ".txt",
+		Probability: sniffProbability,
+		Name:        "Text (" + charset + ")",
+		MimeType:    "text/plain; charset=" + charset,
+		Source:      SourceCharset,
+	}}, charset, nil
+}
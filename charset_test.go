@@ -0,0 +1,101 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"ASCII", "plain text\r\n", "US-ASCII"},
+		{"UTF-8", "naïve café", "UTF-8"},
+		{"UTF-8 cut", "naïve caf\xc3", "UTF-8"},
+		{"UTF-8 BOM", "\xef\xbb\xbfhello", "UTF-8"},
+		{"UTF-16LE BOM", "\xff\xfeh\x00i\x00", "UTF-16LE"},
+		{"UTF-16LE", "h\x00e\x00l\x00l\x00o\x00", "UTF-16LE"},
+		{"UTF-16BE", "\x00h\x00e\x00l\x00l\x00o", "UTF-16BE"},
+		{"UTF-32BE BOM", "\x00\x00\xfe\xff\x00\x00\x00h", "UTF-32BE"},
+		{"Shift_JIS", "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x90\xa2\x8a\x45", "Shift_JIS"},
+		{"EUC-JP", "\xa4\xb3\xa4\xf3\xa4\xcb\xa4\xc1\xa4\xcf\xc0\xa4\xb3\xa6", "EUC-JP"},
+		{"ISO-2022-JP", "\x1b$B$3$s$K$A$O\x1b(B", "ISO-2022-JP"},
+		{"ISO-8859-1", "caf\xe9 cr\xe8me br\xfbl\xe9e", "ISO-8859-1"},
+		{"windows-1252", "\x93quoted\x94 caf\xe9", "windows-1252"},
+		{"Binary", "\x00\x01\x02\x03binary", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if charset := DetectCharset([]byte(tt.data)); charset != tt.expected {
+				t.Errorf("DetectCharset(%q) = %q, want %q", tt.data, charset, tt.expected)
+			}
+		})
+	}
+}
+
+const textOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: notes.txt
+100.0% (.TXT) Text - UTF-8 encoded (5000/1)
+        Mime type       : text/plain
+`
+
+func TestScanCharset(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notes, []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd"), 0o644)
+
+	noise := filepath.Join(dir, "noise.bin")
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i*7 + i/256)
+	}
+	os.WriteFile(noise, data, 0o644)
+
+	output := func(out string) Runner {
+		return RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+			return CommandResult{Stdout: out}, nil
+		})
+	}
+
+	ctx := context.Background()
+
+	tr := NewTrid(Options{Cmd: "trid", Runner: output(textOutput), Charset: true})
+	if r, err := tr.ScanReport(ctx, notes, 1); err != nil || r.Charset != "Shift_JIS" || r.FileTypes[0].Extension != ".txt" {
+		t.Errorf("ScanReport() = %+v, %v, want Shift_JIS text", r, err)
+	}
+
+	results, err := tr.ScanBatch(ctx, []string{notes}, 1)
+	if err != nil || results[0].Charset != "Shift_JIS" {
+		t.Errorf("ScanBatch() = %+v, %v, want Shift_JIS", results, err)
+	}
+
+	// Files that are not text have no charset
+	if r, err := NewTrid(Options{Cmd: "trid", Runner: output(pdfOutput), Charset: true}).ScanReport(ctx, notes, 1); err != nil || r.Charset != "" {
+		t.Errorf("ScanReport() of a PDF = %+v, %v, want no charset", r, err)
+	}
+
+	// Unknown files are identified as text by their encoding
+	tr = NewTrid(Options{Cmd: "trid", Runner: output(unknownOutput), Charset: true})
+	if r, err := tr.ScanReport(ctx, notes, 1); err != nil || r.Charset != "Shift_JIS" || r.FileTypes[0].Source != SourceCharset || !strings.Contains(r.FileTypes[0].MimeType, "Shift_JIS") {
+		t.Errorf("ScanReport() of unknown text = %+v, %v", r, err)
+	}
+
+	if _, err := tr.ScanReport(ctx, noise, 1); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("ScanReport() of noise error = %v, want %v", err, ErrUnknownFileType)
+	}
+
+	if r, err := NewTrid(Options{Cmd: "trid", Runner: output(textOutput)}).ScanReport(ctx, notes, 1); err != nil || r.Charset != "" {
+		t.Errorf("ScanReport() without Charset = %+v, %v", r, err)
+	}
+}
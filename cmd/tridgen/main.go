@@ -0,0 +1,153 @@
+// Command tridgen compiles a selection of TrID XML definitions into Go source
+// identifying just those file types, with no definitions to load at run time.
+//
+// Usage:
+//
+//	tridgen -xml DIR [-pkg NAME] [-o FILE] [-ext .pdf,.png] [DEFINITION...]
+//
+// Definitions are selected by file name, with the patterns of path.Match
+// (e.g., "pdf*.trid.xml"), and by extension; all of them are compiled if
+// neither is given. The package name defaults to $GOPACKAGE, so tridgen can be
+// run by go generate:
+//
+//	//go:generate go run github.com/attilabuti/trid/cmd/tridgen -xml ../triddefs_xml -o formats_gen.go -ext .pdf,.png,.zip
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/attilabuti/trid/native"
+)
+
+// errUsage is returned when the command line is invalid.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	switch err := generate(args, stdout, stderr); {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "tridgen: %v\n", err)
+		return 1
+	}
+}
+
+// generate implements the command.
+func generate(args []string, stdout, stderr io.Writer) error {
+	fset := flag.NewFlagSet("tridgen", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	xmlDefs := fset.String("xml", "", "directory of TrID XML definitions")
+	pkg := fset.String("pkg", os.Getenv("GOPACKAGE"), "name of the generated package (default: $GOPACKAGE)")
+	output := fset.String("o", "", "file the source is written to (default: standard output)")
+	exts := fset.String("ext", "", "comma-separated extensions of the definitions compiled (e.g., .pdf,.png)")
+	fset.Usage = func() {
+		fmt.Fprint(stderr, "Usage: tridgen -xml DIR [flags] [DEFINITION...]\n\nFlags:\n")
+		fset.PrintDefaults()
+	}
+
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	if *xmlDefs == "" || *pkg == "" {
+		fset.Usage()
+		return errUsage
+	}
+
+	set, err := native.Load(os.DirFS(*xmlDefs))
+	if err != nil {
+		return err
+	}
+
+	defs, err := selectDefinitions(set.Definitions(), fset.Args(), *exts)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := native.Generate(&b, *pkg, defs...); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err := stdout.Write(b.Bytes())
+		return err
+	}
+
+	return os.WriteFile(*output, b.Bytes(), 0o644)
+}
+
+// selectDefinitions returns the definitions whose file name matches one of
+// patterns or whose extension is one of exts, or all of them if neither is
+// given.
+func selectDefinitions(defs []*native.Definition, patterns []string, exts string) ([]*native.Definition, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%v: %s", err, pattern)
+		}
+	}
+
+	var wanted []string
+	for _, ext := range strings.Split(exts, ",") {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			wanted = append(wanted, ext)
+		}
+	}
+
+	if len(patterns) == 0 && len(wanted) == 0 {
+		return defs, nil
+	}
+
+	var selected []*native.Definition
+	for _, d := range defs {
+		if matchesAny(d, patterns, wanted) {
+			selected = append(selected, d)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, errors.New("no definitions selected")
+	}
+
+	return selected, nil
+}
+
+// matchesAny reports whether d matches one of the file name patterns or has
+// one of the extensions, given without dot. Definitions with several
+// extensions, such as ".jpg/jpeg", match each of them.
+func matchesAny(d *native.Definition, patterns, exts []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, d.File); ok {
+			return true
+		}
+	}
+
+	for _, ext := range strings.Split(strings.TrimPrefix(d.Extension, "."), "/") {
+		for _, wanted := range exts {
+			if ext == wanted {
+				return true
+			}
+		}
+	}
+
+	return false
+}
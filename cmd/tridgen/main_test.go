@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("GOPACKAGE", "formats")
+	out := filepath.Join(t.TempDir(), "formats_gen.go")
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{"All definitions", []string{"-xml", "../../testdata/defs"}, 0, "from 3 TrID definitions"},
+		{"By extension", []string{"-xml", "../../testdata/defs", "-ext", "7Z"}, 0, "from 1 TrID definitions"},
+		{"By file name", []string{"-xml", "../../testdata/defs", "pdf*.trid.xml"}, 0, "from 2 TrID definitions"},
+		{"Package name", []string{"-xml", "../../testdata/defs", "-pkg", "magic"}, 0, "package magic"},
+		{"Nothing selected", []string{"-xml", "../../testdata/defs", "-ext", ".png"}, 1, ""},
+		{"Bad pattern", []string{"-xml", "../../testdata/defs", "["}, 1, ""},
+		{"Without definitions", nil, 2, ""},
+		{"To a file", []string{"-xml", "../../testdata/defs", "-o", out}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("run() output %q does not contain %q", stdout.String(), tt.expectedOut)
+			}
+		})
+	}
+
+	if b, err := os.ReadFile(out); err != nil || !bytes.Contains(b, []byte("package formats")) {
+		t.Errorf("run() -o wrote %q, %v", b, err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/defs"
+	"github.com/attilabuti/trid/native"
+)
+
+// runDefs implements the defs command.
+func runDefs(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, "Usage: tridgo defs <info|update|diff|yara> [flags]\n")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "info":
+		return runDefsInfo(args[1:], stdout, stderr)
+	case "update":
+		return runDefsUpdate(args[1:], stdout, stderr)
+	case "diff":
+		return runDefsDiff(args[1:], stdout, stderr)
+	case "yara":
+		return runDefsYARA(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "tridgo: unknown defs command %q\n", args[0])
+		return errUsage
+	}
+}
+
+// runDefsInfo implements the defs info command.
+func runDefsInfo(args []string, stdout, stderr io.Writer) error {
+	var f tridFlags
+	fset := newFlagSet("defs info", "", stderr)
+	f.register(fset)
+	format := fset.String("format", "text", "output format: text or json")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	path, err := f.locateDefs()
+	if err != nil {
+		return err
+	}
+
+	info, err := defs.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return writeInfo(stdout, *format, info)
+}
+
+// runDefsUpdate implements the defs update command.
+func runDefsUpdate(args []string, stdout, stderr io.Writer) error {
+	var f tridFlags
+	fset := newFlagSet("defs update", "", stderr)
+	f.register(fset)
+	location := fset.String("url", defs.DefaultURL, "download location of the definitions package, such as an internal mirror")
+	proxy := fset.String("proxy", "", "URL of the HTTP proxy (default: from HTTP_PROXY and HTTPS_PROXY)")
+	caCert := fset.String("ca-cert", "", "PEM file of the certificate authorities trusted for the download (default: the system's)")
+	format := fset.String("format", "text", "output format: text or json")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	opts := defs.UpdateOptions{URL: *location}
+	if *proxy != "" {
+		var err error
+		if opts.Proxy, err = url.Parse(*proxy); err != nil {
+			return fmt.Errorf("%w: invalid proxy: %v", errUsage, err)
+		}
+	}
+
+	if *caCert != "" {
+		pem, err := os.ReadFile(*caCert)
+		if err != nil {
+			return err
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", *caCert)
+		}
+		opts.TLSConfig = &tls.Config{RootCAs: roots}
+	}
+
+	path, err := f.locateDefs()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	info, err := defs.UpdateWithOptions(ctx, path, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeInfo(stdout, *format, info)
+}
+
+// runDefsDiff implements the defs diff command.
+func runDefsDiff(args []string, stdout, stderr io.Writer) error {
+	fset := newFlagSet("defs diff", "OLD NEW", stderr)
+	format := fset.String("format", "text", "output format: text or json")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if fset.NArg() != 2 {
+		fset.Usage()
+		return errUsage
+	}
+
+	changes, err := defs.Diff(fset.Arg(0), fset.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	return writeChanges(stdout, *format, changes)
+}
+
+// writeChanges writes the changes between two definitions packages.
+func writeChanges(w io.Writer, format string, c *defs.Changes) error {
+	switch format {
+	case "text":
+		for _, group := range []struct {
+			mark    string
+			changes []defs.Change
+		}{{"+", c.Added}, {"-", c.Removed}, {"~", c.Modified}} {
+			for _, ch := range group.changes {
+				line := fmt.Sprintf("%s %s: %s (%s)", group.mark, ch.File, ch.Name, ch.Extension)
+				if len(ch.Fields) > 0 {
+					line += " [" + strings.Join(ch.Fields, ", ") + "]"
+				}
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%d added, %d removed, %d modified\n", len(c.Added), len(c.Removed), len(c.Modified))
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	default:
+		return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+// runDefsYARA implements the defs yara command.
+func runDefsYARA(args []string, stdout, stderr io.Writer) error {
+	fset := newFlagSet("defs yara", "", stderr)
+	xmlDefs := fset.String("xml", "", "directory of TrID XML definitions to convert")
+	output := fset.String("o", "", "file the rules are written to (default: standard output)")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if *xmlDefs == "" {
+		fset.Usage()
+		return errUsage
+	}
+
+	set, err := native.Load(os.DirFS(*xmlDefs))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return set.WriteYARA(stdout)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err := set.WriteYARA(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeInfo writes information about a definitions package.
+func writeInfo(w io.Writer, format string, info *defs.Info) error {
+	switch format {
+	case "text":
+		_, err := fmt.Fprintf(w, "Path:     %s\nSize:     %d\nModified: %s\nSHA-256:  %s\n",
+			info.Path, info.Size, info.ModTime.Format("2006-01-02 15:04:05"), info.SHA256)
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	default:
+		return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+// locateDefs returns the path of the definitions package selected by the
+// options, looking for the TrID executable if neither the executable nor the
+// definitions are set.
+func (f *tridFlags) locateDefs() (string, error) {
+	opts, err := f.options()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := opts.Cmd
+	if cmd == "" && opts.Definitions == "" {
+		if cmd, err = trid.FindBinary(); err != nil {
+			return "", err
+		}
+	}
+
+	return defs.Locate(cmd, opts.Definitions)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/attilabuti/trid"
+)
+
+// runFix implements the fix command.
+func runFix(args []string, stdout, stderr io.Writer) error {
+	var f tridFlags
+	fset := newFlagSet("fix", "DIR...", stderr)
+	f.register(fset)
+	replace := fset.Bool("replace", false, "replace the current extension instead of appending to it")
+	dryRun := fset.Bool("dry-run", false, "print the renames without performing them")
+	undoLog := fset.String("undo-log", "trid-undo.json", "file recording the renames so they can be reverted")
+	undo := fset.Bool("undo", false, "revert the renames recorded in the undo log")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if *undo {
+		return trid.UndoRenames(*undoLog)
+	}
+
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return errUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	t := trid.NewTrid(opts)
+
+	var plan []trid.Rename
+	for _, root := range fset.Args() {
+		renames, err := t.PlanFixes(ctx, root, trid.FixOptions{Replace: *replace})
+		if err != nil {
+			return err
+		}
+
+		plan = append(plan, renames...)
+	}
+
+	for _, r := range plan {
+		if _, err := fmt.Fprintf(stdout, "%s -> %s\n", r.OldPath, r.NewPath); err != nil {
+			return err
+		}
+	}
+
+	if *dryRun || len(plan) == 0 {
+		return nil
+	}
+
+	return trid.ApplyRenames(plan, *undoLog)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/enrich/ffprobe"
+)
+
+// enrichers are the enrichers selected by -enrich, by name.
+var enrichers = map[string]func() trid.Enricher{
+	ffprobe.Name: func() trid.Enricher { return ffprobe.New(ffprobe.Options{}) },
+}
+
+// tridFlags holds the flags shared by all commands that run TrID. The library
+// options are loaded with trid.LoadOptions from the -config file, or the one
+// named by TRID_CONFIG, and the environment; the flags given override them.
+type tridFlags struct {
+	config string
+	set    []func(o *trid.Options) // Store the values of the flags given, in command line order.
+}
+
+// register adds the shared flags to fset.
+func (f *tridFlags) register(fset *flag.FlagSet) {
+	fset.StringVar(&f.config, "config", "", "configuration `FILE` of the library options, overridden by the environment and the flags (default: $TRID_CONFIG)")
+
+	optionFlag(f, fset, "cmd", "`COMMAND` invoking the TrID file identifier (default: found in TRID_CMD, PATH or the usual install locations)", parseString, func(o *trid.Options) *string { return &o.Cmd })
+	optionFlag(f, fset, "defs", "`PATH` of the TrID definitions package", parseString, func(o *trid.Options) *string { return &o.Definitions })
+	optionFlag(f, fset, "defs-sha256", "expected `SHA256` checksum of the definitions package, or \"sidecar\"", parseString, func(o *trid.Options) *string { return &o.DefsSHA256 })
+	optionFlag(f, fset, "max-defs-age", "`AGE` of the definitions above which scans warn they are stale", time.ParseDuration, func(o *trid.Options) *time.Duration { return &o.MaxDefsAge })
+	optionFlag(f, fset, "engine", "identification `ENGINE`: cli, native (XML definitions given by -defs) or auto (default cli)",
+		parseEnum(map[string]trid.EngineMode{"cli": trid.EngineCLI, "native": trid.EngineNative, "auto": trid.EngineAuto}), func(o *trid.Options) *trid.EngineMode { return &o.Engine })
+	optionFlag(f, fset, "timeout", "maximum `DURATION` to wait for each TrID execution (default 30s)", time.ParseDuration, func(o *trid.Options) *time.Duration { return &o.Timeout })
+	optionFlag(f, fset, "timeout-per-mb", "`DURATION` added to -timeout for each MiB of the files identified", time.ParseDuration, func(o *trid.Options) *time.Duration { return &o.TimeoutPerMB })
+	optionFlag(f, fset, "file-list", "`MODE` of passing file paths to TrID: auto, args or stdin (default auto)",
+		parseEnum(map[string]trid.FileListMode{"auto": trid.FileListAuto, "args": trid.FileListArgs, "stdin": trid.FileListStdin}), func(o *trid.Options) *trid.FileListMode { return &o.FileList })
+	optionFlag(f, fset, "retry-attempts", "maximum `NUMBER` of attempts of TrID processes failing transiently", strconv.Atoi, func(o *trid.Options) *int { return &o.Retry.Attempts })
+	optionFlag(f, fset, "retry-backoff", "`DELAY` before the first retry, doubled for each further retry (default 100ms)", time.ParseDuration, func(o *trid.Options) *time.Duration { return &o.Retry.Backoff })
+	optionFlag(f, fset, "breaker-threshold", "`NUMBER` of consecutive failed TrID processes after which scans fail immediately", strconv.Atoi, func(o *trid.Options) *int { return &o.Breaker.Threshold })
+	optionFlag(f, fset, "breaker-cooldown", "`DURATION` scans fail immediately before TrID is tried again (default 30s)", time.ParseDuration, func(o *trid.Options) *time.Duration { return &o.Breaker.Cooldown })
+	optionFlag(f, fset, "min-probability", "drop matches below this `PERCENTAGE`", parseFloat, func(o *trid.Options) *float64 { return &o.MinProbability })
+	optionFlag(f, fset, "symlinks", "symbolic link `POLICY`: follow, refuse or target (default follow)",
+		parseEnum(map[string]trid.SymlinkPolicy{"follow": trid.SymlinkFollow, "refuse": trid.SymlinkRefuse, "target": trid.SymlinkTarget}), func(o *trid.Options) *trid.SymlinkPolicy { return &o.Symlinks })
+	optionFlag(f, fset, "max-file-size", "`SIZE` in bytes above which files are handled according to -oversize", parseInt64, func(o *trid.Options) *int64 { return &o.MaxFileSize })
+	optionFlag(f, fset, "oversize", "`POLICY` for files larger than -max-file-size: fail or truncate (default fail)",
+		parseEnum(map[string]trid.OversizePolicy{"fail": trid.OversizeFail, "truncate": trid.OversizeTruncate}), func(o *trid.Options) *trid.OversizePolicy { return &o.Oversize })
+	optionFlag(f, fset, "local-copy-head-size", "copy only the first `SIZE` bytes of files with -local-copy; 0 copies whole files", parseInt64, func(o *trid.Options) *int64 { return &o.LocalCopy.HeadSize })
+	optionFlag(f, fset, "local-copy-dir", "`DIR`ectory of the copies made with -local-copy (default: the temporary directory)", parseString, func(o *trid.Options) *string { return &o.LocalCopy.Dir })
+
+	boolFlag(f, fset, "local-copy", "copy files to local storage before identifying them", func(o *trid.Options) *bool { return &o.LocalCopy.Enabled })
+	boolFlag(f, fset, "fallback", "identify files TrID cannot identify, or cannot be run for, with the standard library", func(o *trid.Options) *bool { return &o.Fallback })
+	boolFlag(f, fset, "containers", "tell OOXML, ODF, JAR and APK files apart by inspecting zip archives", func(o *trid.Options) *bool { return &o.Containers })
+	boolFlag(f, fset, "charset", "detect the character encoding of textual files", func(o *trid.Options) *bool { return &o.Charset })
+	boolFlag(f, fset, "precheck", "classify obvious text, JSON, CSV and XML files without running TrID", func(o *trid.Options) *bool { return &o.Precheck })
+	boolFlag(f, fset, "executables", "parse the headers of PE, ELF and Mach-O files", func(o *trid.Options) *bool { return &o.Executables })
+	boolFlag(f, fset, "pdf", "extract the version, encryption and page count of PDF documents", func(o *trid.Options) *bool { return &o.PDF })
+	boolFlag(f, fset, "office", "tell OLE from OOXML Office documents and detect their macros", func(o *trid.Options) *bool { return &o.Office })
+	boolFlag(f, fset, "images", "decode the dimensions, color depth and frame count of images", func(o *trid.Options) *bool { return &o.Images })
+	boolFlag(f, fset, "suspicious-names", "flag deceptive file names, such as double extensions", func(o *trid.Options) *bool { return &o.SuspiciousNames })
+
+	var extra []string
+	fset.Func("extra-arg", "additional TrID `ARG`ument, replacing those of the configuration (repeatable)", func(v string) error {
+		extra = append(extra, v)
+		args := slices.Clone(extra)
+		f.set = append(f.set, func(o *trid.Options) { o.ExtraArgs = args })
+		return nil
+	})
+
+	names := make([]string, 0, len(enrichers))
+	for name := range enrichers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fset.Func("enrich", "add details with the enricher `NAME`: "+strings.Join(names, ", ")+" (repeatable)", func(v string) error {
+		newEnricher, ok := enrichers[v]
+		if !ok {
+			return fmt.Errorf("unknown enricher %q", v)
+		}
+
+		f.set = append(f.set, func(o *trid.Options) {
+			if o.Enrichers == nil {
+				o.Enrichers = make(map[string]trid.Enricher)
+			}
+			o.Enrichers[v] = newEnricher()
+		})
+		return nil
+	})
+}
+
+// options returns the library options selected by the configuration, the
+// environment and the flags.
+func (f *tridFlags) options() (trid.Options, error) {
+	opts, err := trid.LoadOptions(f.config)
+	if err != nil {
+		return trid.Options{}, err
+	}
+
+	for _, set := range f.set {
+		set(&opts)
+	}
+
+	return opts, nil
+}
+
+// optionFlag adds a flag to fset whose value, parsed by parse, is stored in
+// the field of the options returned by field.
+func optionFlag[T any](f *tridFlags, fset *flag.FlagSet, name, usage string, parse func(string) (T, error), field func(o *trid.Options) *T) {
+	fset.Func(name, usage, func(v string) error {
+		value, err := parse(v)
+		if err != nil {
+			return err
+		}
+
+		f.set = append(f.set, func(o *trid.Options) { *field(o) = value })
+		return nil
+	})
+}
+
+// boolFlag is like optionFlag for a boolean flag, which may be given without
+// a value.
+func boolFlag(f *tridFlags, fset *flag.FlagSet, name, usage string, field func(o *trid.Options) *bool) {
+	fset.BoolFunc(name, usage, func(v string) error {
+		value, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+
+		f.set = append(f.set, func(o *trid.Options) { *field(o) = value })
+		return nil
+	})
+}
+
+// parseString returns v; it is the parser of string flags.
+func parseString(v string) (string, error) {
+	return v, nil
+}
+
+// parseInt64 parses a 64-bit integer.
+func parseInt64(v string) (int64, error) {
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// parseFloat parses a floating-point number.
+func parseFloat(v string) (float64, error) {
+	return strconv.ParseFloat(v, 64)
+}
+
+// parseEnum returns a parser of the values named in values.
+func parseEnum[T any](values map[string]T) func(string) (T, error) {
+	return func(v string) (T, error) {
+		value, ok := values[v]
+		if !ok {
+			var zero T
+			return zero, fmt.Errorf("unknown value %q", v)
+		}
+
+		return value, nil
+	}
+}
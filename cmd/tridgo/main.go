@@ -0,0 +1,110 @@
+// Command tridgo identifies file types with TrID.
+//
+// Usage:
+//
+//	tridgo scan [flags] FILE...
+//	tridgo walk [flags] DIR...
+//	tridgo fix [flags] DIR...
+//	tridgo verify [flags] -xml DIR PATH...
+//	tridgo defs info [flags]
+//	tridgo defs update [flags]
+//	tridgo defs diff [flags] OLD NEW
+//	tridgo defs yara -xml DIR [flags]
+//	tridgo serve [flags]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// usage is printed when no or an unknown subcommand is given.
+const usage = `Usage: tridgo <command> [flags] [arguments]
+
+Commands:
+  scan    identify one or more files
+  walk    recursively identify the files in directories
+  fix     rename the files in directories to their identified extension
+  verify  compare the native engine with TrID on files or directories
+  defs    show information about, update, compare or convert the definitions
+  serve   run the HTTP identification server
+
+Run "tridgo <command> -h" for the flags of a command.
+`
+
+// errUsage is returned when the command line is invalid.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "scan":
+		err = runScan(args[1:], stdout, stderr)
+	case "walk":
+		err = runWalk(args[1:], stdout, stderr)
+	case "fix":
+		err = runFix(args[1:], stdout, stderr)
+	case "verify":
+		err = runVerify(args[1:], stdout, stderr)
+	case "defs":
+		err = runDefs(args[1:], stdout, stderr)
+	case "serve":
+		err = runServe(args[1:], stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "tridgo: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "tridgo: %v\n", err)
+		return 1
+	}
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting.
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	fset.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tridgo %s [flags] %s\n\nFlags:\n", name, args)
+		fset.PrintDefaults()
+	}
+
+	return fset
+}
+
+// parseFlags parses args, mapping parse failures to errUsage.
+func parseFlags(fset *flag.FlagSet, args []string) error {
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return errUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/attilabuti/trid/export"
+)
+
+// newStub creates a stub TrID executable identifying every file as a PDF,
+// next to a definitions package, and a directory containing two sample files.
+func newStub(t *testing.T) (cmd, dir string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	bin := t.TempDir()
+	cmd = filepath.Join(bin, "trid")
+	script := `#!/bin/sh
+echo "TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello"
+for f in "$@"; do
+  case "$f" in -*) continue ;; esac
+  echo
+  echo "File: $f"
+  echo " 100.0% (.PDF) Adobe Portable Document Format (5000/1)"
+done
+`
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bin, "triddefs.trd"), []byte("defs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.pdf", filepath.Join("sub", "b.pdf")} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cmd, dir
+}
+
+func TestRun(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{"No command", nil, 2, ""},
+		{"Unknown command", []string{"bogus"}, 2, ""},
+		{"Scan", []string{"scan", "-cmd", cmd, filepath.Join(dir, "a.pdf")}, 0, "Adobe Portable Document Format"},
+		{"Scan missing file", []string{"scan", "-cmd", cmd, filepath.Join(dir, "missing.pdf")}, 1, "file not found"},
+		{"Scan without files", []string{"scan", "-cmd", cmd}, 2, ""},
+		{"Scan unknown format", []string{"scan", "-cmd", cmd, "-format", "xml", filepath.Join(dir, "a.pdf")}, 2, ""},
+		{"Walk", []string{"walk", "-cmd", cmd, "-j", "2", dir}, 0, filepath.Join("sub", "b.pdf")},
+		{"Walk with progress", []string{"walk", "-cmd", cmd, "-progress", dir}, 0, filepath.Join("sub", "b.pdf")},
+		{"Walk HTML report", []string{"walk", "-cmd", cmd, "-format", "html", dir}, 0, "<td>100.0% (.pdf) Adobe Portable Document Format</td>"},
+		{"Walk Markdown report", []string{"walk", "-cmd", cmd, "-format", "markdown", dir}, 0, "| Adobe Portable Document Format | .pdf | 2 |"},
+		{"Walk Parquet export", []string{"walk", "-cmd", cmd, "-format", "parquet", dir}, 0, "PAR1"},
+		{"Walk NDJSON export", []string{"walk", "-cmd", cmd, "-format", "ndjson", dir}, 0, `"extension":".pdf"`},
+		{"Walk CSV export", []string{"walk", "-cmd", cmd, "-format", "csv", dir}, 0, "path,rank,probability,extension,name,mime_type,definition,error\n"},
+		{"Walk table", []string{"walk", "-cmd", cmd, "-format", "table", dir}, 0, ".pdf       100.0%       Adobe Portable Document Format"},
+		{"Scan quiet with format", []string{"scan", "-cmd", cmd, "-quiet", "-format", "json", filepath.Join(dir, "a.pdf")}, 2, ""},
+		{"Walk with checkpoint", []string{"walk", "-cmd", cmd, "-checkpoint", filepath.Join(t.TempDir(), "walk.cp"), dir}, 0, filepath.Join("sub", "b.pdf")},
+		{"Defs info", []string{"defs", "info", "-cmd", cmd}, 0, "triddefs.trd"},
+		{"Defs diff", []string{"defs", "diff", "../../testdata/defs", "../../testdata/defs"}, 0, "0 added, 0 removed, 0 modified"},
+		{"Defs diff of binary packages", []string{"defs", "diff", "old.trd", "new.trd"}, 1, ""},
+		{"Defs yara", []string{"defs", "yara", "-xml", "../../testdata/defs"}, 0, "rule trid_7z"},
+		{"Defs yara without definitions", []string{"defs", "yara"}, 2, ""},
+		{"Defs unknown command", []string{"defs", "bogus"}, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("run() output %q does not contain %q", stdout.String(), tt.expectedOut)
+			}
+		})
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	cmd, dir := newStub(t)
+	pdf := filepath.Join(dir, "a.pdf")
+
+	text := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(text, []byte("plain text notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := filepath.Join(t.TempDir(), "trid.toml")
+	if err := os.WriteFile(config, []byte("cmd = \""+cmd+"\"\nmin_probability = 50\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.toml")
+	if err := os.WriteFile(invalid, []byte("timeout = soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{"Configuration", []string{"scan", "-config", config, pdf}, 0, "Adobe Portable Document Format"},
+		{"Flag overriding the configuration", []string{"scan", "-config", config, "-min-probability", "100.5", pdf}, 1, ""},
+		{"Invalid configuration", []string{"scan", "-config", invalid, "-cmd", cmd, pdf}, 1, ""},
+		{"Boolean flag", []string{"scan", "-cmd", cmd, "-precheck", text}, 0, ".txt"},
+		{"Boolean flag with value", []string{"scan", "-cmd", cmd, "-precheck=false", text}, 0, ".pdf"},
+		{"Repeated flags", []string{"scan", "-cmd", cmd, "-extra-arg", "-w", "-extra-arg", "-r:3", pdf}, 0, ".pdf"},
+		{"Reserved extra argument", []string{"scan", "-cmd", cmd, "-extra-arg", "-n:3", pdf}, 1, ""},
+		{"Unknown enumeration value", []string{"walk", "-cmd", cmd, "-oversize", "bogus", dir}, 2, ""},
+		{"Unknown enricher", []string{"scan", "-cmd", cmd, "-enrich", "bogus", pdf}, 2, ""},
+		{"Unknown symlink policy", []string{"walk", "-cmd", cmd, "-symlinks", "bogus", dir}, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("run() output %q does not contain %q", stdout.String(), tt.expectedOut)
+			}
+		})
+	}
+
+	// The environment applies without a configuration file
+	t.Setenv("TRID_CMD", cmd)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"scan", pdf}, &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), ".pdf") {
+		t.Errorf("run() with TRID_CMD = %d, %q (stderr: %s)", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"walk", "-cmd", cmd, "-format", "json", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	var results []export.Record
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].FileTypes[0].Extension != ".pdf" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestRunQuiet(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	tests := []struct {
+		name        string
+		args        []string
+		expectedOut string
+	}{
+		{"Single file", []string{"scan", "-cmd", cmd, "-quiet", filepath.Join(dir, "a.pdf")}, ".pdf\n"},
+		{"Several files", []string{"walk", "-cmd", cmd, "-quiet", dir}, filepath.Join(dir, "a.pdf") + "\t.pdf\n" + filepath.Join(dir, "sub", "b.pdf") + "\t.pdf\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != 0 {
+				t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+			}
+
+			if stdout.String() != tt.expectedOut {
+				t.Errorf("run() output = %q, want %q", stdout.String(), tt.expectedOut)
+			}
+		})
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	cmd, dir := newStub(t)
+	output := filepath.Join(t.TempDir(), "results.csv")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"walk", "-cmd", cmd, "-format", "csv", "-output", output, dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("run() wrote %q to standard output", stdout.String())
+	}
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 3 || !strings.HasSuffix(lines[1], ",1,100,.pdf,Adobe Portable Document Format,,,") {
+		t.Errorf("output file = %q, want a header and a row per file", b)
+	}
+
+	// An invalid format leaves no output file behind
+	os.Remove(output)
+	if code := run([]string{"walk", "-cmd", cmd, "-format", "xml", "-output", output, dir}, &stdout, &stderr); code != 2 {
+		t.Errorf("run() with an invalid format = %d, want 2", code)
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("output file exists after an invalid format: %v", err)
+	}
+
+	// Invalid flags leave an existing output file alone
+	if err := os.WriteFile(output, []byte("previous results"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"walk", "-cmd", cmd, "-format", "csv", "-symlinks", "bogus", "-output", output, dir},
+		{"walk", "-cmd", cmd, "-format", "csv", "-checkpoint", filepath.Join(t.TempDir(), "checkpoint"), "-symlinks", "bogus", "-output", output, dir},
+		{"scan", "-cmd", cmd, "-format", "csv", "-quiet", "-output", output, filepath.Join(dir, "a.pdf")},
+	} {
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Errorf("run(%q) = %d, want 2", args, code)
+		}
+
+		if b, _ := os.ReadFile(output); string(b) != "previous results" {
+			t.Errorf("run(%q) changed the output file to %q", args, b)
+		}
+	}
+}
+
+func TestRunCheckpoint(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	// A TrID losing its definitions while sub/b.pdf is identified interrupts
+	// the walk until the marker is removed.
+	marker := filepath.Join(t.TempDir(), "broken")
+	flaky := filepath.Join(filepath.Dir(cmd), "trid-flaky")
+	script := `#!/bin/sh
+for f in "$@"; do
+  case "$f" in */b.pdf) if [ -e "` + marker + `" ]; then echo "No definitions available!"; exit 0; fi ;; esac
+done
+exec "` + cmd + `" "$@"
+`
+	if err := os.WriteFile(flaky, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(t.TempDir(), "results.ndjson")
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	args := []string{"walk", "-cmd", flaky, "-format", "ndjson", "-checkpoint", checkpoint, "-checkpoint-every", "1", "-output", output, "-summary", dir}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != 1 {
+		t.Fatalf("interrupted run() = %d, want 1 (stderr: %s)", code, stderr.String())
+	}
+
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("checkpoint missing after an interrupted walk: %v", err)
+	}
+
+	os.Remove(marker)
+	stderr.Reset()
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("resumed run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	// The summary covers the files of the resumed run only
+	if !strings.Contains(stderr.String(), "1 files") {
+		t.Errorf("summary %q does not cover the resumed file only", stderr.String())
+	}
+
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint left behind after a completed walk: %v", err)
+	}
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "a.pdf") || !strings.Contains(lines[1], "b.pdf") {
+		t.Errorf("output file = %q, want the results of both runs", b)
+	}
+
+	// Formats that cannot be appended to are refused
+	csv := filepath.Join(t.TempDir(), "results.csv")
+	if code := run([]string{"walk", "-cmd", cmd, "-format", "csv", "-checkpoint", checkpoint, "-output", csv, dir}, &stdout, &stderr); code != 2 {
+		t.Errorf("run() with -format csv = %d, want 2", code)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"walk", "-cmd", cmd, "-summary", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	for _, expected := range []string{"2 files", "0 unknown (0.0%)", "Adobe Portable Document Format (.pdf)"} {
+		if !strings.Contains(stderr.String(), expected) {
+			t.Errorf("summary %q does not contain %q", stderr.String(), expected)
+		}
+	}
+}
+
+func TestRunFix(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	bin := filepath.Join(dir, "c.bin")
+	if err := os.WriteFile(bin, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	undoLog := filepath.Join(t.TempDir(), "undo.json")
+	fixed := filepath.Join(dir, "c.pdf")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"fix", "-cmd", cmd, "-replace", "-dry-run", "-undo-log", undoLog, dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	if expected := bin + " -> " + fixed + "\n"; stdout.String() != expected {
+		t.Errorf("run() output = %q, want %q", stdout.String(), expected)
+	}
+
+	if code := run([]string{"fix", "-cmd", cmd, "-replace", "-undo-log", undoLog, dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	if _, err := os.Stat(fixed); err != nil {
+		t.Errorf("fix did not rename %s: %v", bin, err)
+	}
+
+	if code := run([]string{"fix", "-undo", "-undo-log", undoLog}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d (stderr: %s)", code, stderr.String())
+	}
+
+	if _, err := os.Stat(bin); err != nil {
+		t.Errorf("fix -undo did not restore %s: %v", bin, err)
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	cmd, dir := newStub(t)
+
+	matching := filepath.Join(t.TempDir(), "objects.pdf")
+	if err := os.WriteFile(matching, []byte("%PDF-1.4 1 0 obj endobj"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{"Agreement", []string{"-max-delta", "30", matching}, 0, matching + ": ok\n"},
+		{"Probability", []string{matching}, 1, "probability: native 71.4% (.pdf) Adobe Portable Document Format, trid 100.0% (.pdf)"},
+		{"Top match", []string{dir}, 1, "top_match: native 100.0% (.) Generic PDF document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"verify", "-cmd", cmd, "-xml", "../../testdata/defs"}, tt.args...), &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("run() = %d, want %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("run() output %q does not contain %q", stdout.String(), tt.expectedOut)
+			}
+		})
+	}
+}
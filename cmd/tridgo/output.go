@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/export"
+	"github.com/attilabuti/trid/parquet"
+	"github.com/attilabuti/trid/report"
+)
+
+// checkFormat checks that format is a known output format that can be
+// combined with quiet, which only applies to text.
+func checkFormat(format string, quiet bool) error {
+	if quiet && format != "text" {
+		return fmt.Errorf("%w: -quiet cannot be combined with -format %s", errUsage, format)
+	}
+
+	switch format {
+	case "text", "html", "markdown", "parquet":
+		return nil
+	}
+
+	if !slices.Contains(export.Formats, format) {
+		return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+
+	return nil
+}
+
+// newWriter returns a writer for the named output format. With quiet, only
+// the extension of the most likely match of each file is written, after its
+// path if prefix is set.
+func newWriter(format string, quiet, prefix bool, w io.Writer) (export.Writer, error) {
+	if err := checkFormat(format, quiet); err != nil {
+		return nil, err
+	}
+
+	if quiet {
+		return &quietWriter{w: w, prefix: prefix}, nil
+	}
+
+	switch format {
+	case "text":
+		return &textWriter{w: w}, nil
+	case "html", "markdown":
+		return &reportWriter{w: w, format: format}, nil
+	case "parquet":
+		return parquet.NewWriter(w, parquet.Options{}), nil
+	}
+
+	return export.NewWriter(format, w)
+}
+
+// textWriter writes human-readable results.
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Write(r trid.BatchResult) error {
+	if r.Err != nil {
+		_, err := fmt.Fprintf(t.w, "%s\n  error: %v\n", r.Path, r.Err)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(t.w, "%s\n", r.Path); err != nil {
+		return err
+	}
+
+	for _, ft := range r.FileTypes {
+		if _, err := fmt.Fprintf(t.w, "  %5.1f%% (%s) %s\n", ft.Probability, ft.Extension, ft.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, warning := range r.Warnings {
+		if _, err := fmt.Fprintf(t.w, "  warning: %s\n", warning); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *textWriter) Close() error {
+	return nil
+}
+
+// reportWriter writes all results as an HTML or Markdown report.
+type reportWriter struct {
+	w       io.Writer
+	format  string
+	results []trid.BatchResult
+}
+
+func (r *reportWriter) Write(br trid.BatchResult) error {
+	r.results = append(r.results, br)
+	return nil
+}
+
+func (r *reportWriter) Close() error {
+	rep := report.New("TrID scan report", r.results)
+	if r.format == "html" {
+		return rep.WriteHTML(r.w)
+	}
+
+	return rep.WriteMarkdown(r.w)
+}
+
+// quietWriter writes the extension of the most likely match of each file, so
+// the output can be used in shell pipelines. Files without matches are left
+// out.
+type quietWriter struct {
+	w      io.Writer
+	prefix bool
+}
+
+func (q *quietWriter) Write(r trid.BatchResult) error {
+	best, ok := trid.Results(r.FileTypes).Best()
+	if !ok {
+		return nil
+	}
+
+	if q.prefix {
+		_, err := fmt.Fprintf(q.w, "%s\t%s\n", r.Path, best.Extension)
+		return err
+	}
+
+	_, err := fmt.Fprintln(q.w, best.Extension)
+	return err
+}
+
+func (q *quietWriter) Close() error {
+	return nil
+}
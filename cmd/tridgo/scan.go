@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/export"
+)
+
+// scanFlags holds the flags of the scan and walk commands.
+type scanFlags struct {
+	tridFlags
+	matches  int
+	format   string
+	output   string
+	quiet    bool
+	progress bool
+	summary  bool
+}
+
+// register adds the scan flags to fset.
+func (f *scanFlags) register(fset *flag.FlagSet) {
+	f.tridFlags.register(fset)
+	fset.IntVar(&f.matches, "n", 5, "maximum number of matches per file")
+	optionFlag(&f.tridFlags, fset, "j", "maximum `NUMBER` of concurrent TrID processes (default 1)", strconv.Atoi, func(o *trid.Options) *int { return &o.MaxConcurrent })
+	optionFlag(&f.tridFlags, fset, "batch-size", "maximum `NUMBER` of files passed to a single TrID process (default 200)", strconv.Atoi, func(o *trid.Options) *int { return &o.BatchSize })
+	fset.StringVar(&f.format, "format", "text", "output format: text, json, ndjson, csv, table, html, markdown or parquet")
+	fset.StringVar(&f.output, "output", "", "write the results to `FILE` instead of standard output")
+	fset.BoolVar(&f.quiet, "quiet", false, "write only the extension of the most likely match of each file; requires -format text")
+	fset.BoolVar(&f.progress, "progress", false, "report progress on standard error")
+	fset.BoolVar(&f.summary, "summary", false, "report the number of files of each type on standard error")
+}
+
+// runScan implements the scan command.
+func runScan(args []string, stdout, stderr io.Writer) error {
+	var f scanFlags
+	fset := newFlagSet("scan", "FILE...", stderr)
+	f.register(fset)
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return errUsage
+	}
+
+	opts, err := f.scanOptions(stderr)
+	if err != nil {
+		return err
+	}
+
+	return scanPaths(fset.Args(), fset.NArg() > 1, opts, f, stdout, stderr)
+}
+
+// runWalk implements the walk command.
+func runWalk(args []string, stdout, stderr io.Writer) error {
+	var f scanFlags
+	fset := newFlagSet("walk", "DIR...", stderr)
+	f.register(fset)
+	checkpoint := fset.String("checkpoint", "", "file recording the files done, so an interrupted walk resumes where it stopped; -summary and the exit status then cover the files of this run only")
+	checkpointEvery := fset.Int("checkpoint-every", 1000, "number of files identified between checkpoints")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if fset.NArg() == 0 {
+		fset.Usage()
+		return errUsage
+	}
+
+	opts, err := f.scanOptions(stderr)
+	if err != nil {
+		return err
+	}
+
+	if *checkpoint != "" {
+		return walkCheckpointed(fset.Args(), trid.WalkOptions{Symlinks: opts.Symlinks, Checkpoint: *checkpoint, CheckpointEvery: *checkpointEvery}, opts, f, stdout, stderr)
+	}
+
+	var paths []string
+	for _, root := range fset.Args() {
+		files, err := trid.Walk(root, opts.Symlinks)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, files...)
+	}
+
+	return scanPaths(paths, true, opts, f, stdout, stderr)
+}
+
+// walkCheckpointed identifies the files below roots like scanPaths, writing
+// results as each chunk of files is identified and recording the files done in
+// the checkpoint of walkOpts, so an interrupted walk resumes where it stopped.
+// The results of a resumed walk are appended to the -output file, which must
+// have a format that can be appended to; the summary only covers the files
+// identified by this run.
+func walkCheckpointed(roots []string, walkOpts trid.WalkOptions, opts trid.Options, f scanFlags, stdout, stderr io.Writer) (err error) {
+	resume := false
+	if f.output != "" {
+		if f.format != "text" && f.format != "ndjson" {
+			return fmt.Errorf("%w: -output with -checkpoint requires -format text or ndjson, which can be appended to", errUsage)
+		}
+
+		if _, err := os.Stat(walkOpts.Checkpoint); err == nil {
+			resume = true
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	w, err := f.writer(true, resume, stdout)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(w, &err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var summary trid.Summary
+	err = trid.NewTrid(opts).ScanWalk(ctx, roots, f.matches, walkOpts, func(r trid.BatchResult) error {
+		summary.Add(r)
+		return w.Write(r)
+	})
+	if f.progress {
+		fmt.Fprintln(stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return summarize(&summary, f, stderr)
+}
+
+// writer returns the writer of the results selected by the flags, writing to
+// the -output file, created or truncated unless appending, or to stdout. The
+// format is checked before the file is touched.
+func (f *scanFlags) writer(prefix, appending bool, stdout io.Writer) (export.Writer, error) {
+	if err := checkFormat(f.format, f.quiet); err != nil {
+		return nil, err
+	}
+
+	if f.output == "" {
+		return newWriter(f.format, f.quiet, prefix, stdout)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(f.output, flags, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWriter(f.format, f.quiet, prefix, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileWriter{Writer: w, file: file}, nil
+}
+
+// closeWriter closes w, setting *err to the error of Close unless it is
+// already set. It is meant to be deferred.
+func closeWriter(w export.Writer, err *error) {
+	if closeErr := w.Close(); *err == nil {
+		*err = closeErr
+	}
+}
+
+// fileWriter is a writer closing the file it writes to.
+type fileWriter struct {
+	export.Writer
+	file *os.File
+}
+
+func (w *fileWriter) Close() error {
+	err := w.Writer.Close()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// scanOptions returns the library options selected by the flags, with at
+// least one concurrent TrID process, reporting progress on stderr with
+// -progress.
+func (f *scanFlags) scanOptions(stderr io.Writer) (trid.Options, error) {
+	opts, err := f.options()
+	if err != nil {
+		return trid.Options{}, err
+	}
+
+	opts.MaxConcurrent = max(opts.MaxConcurrent, 1)
+
+	if f.progress {
+		opts.Progress = func(p trid.Progress) {
+			fmt.Fprintf(stderr, "\r%d/%d files, %d/%d bytes, ETA %s ", p.FilesDone, p.FilesTotal, p.BytesDone, p.BytesTotal, p.ETA.Round(time.Second))
+		}
+	}
+
+	return opts, nil
+}
+
+// scanPaths identifies paths in batches with opts, and writes the results in
+// input order, with their path if prefix is set and -quiet. With -progress,
+// the progress is reported on stderr. The output is only opened once the files
+// are identified, so a failed scan leaves an existing -output file alone.
+func scanPaths(paths []string, prefix bool, opts trid.Options, f scanFlags, stdout, stderr io.Writer) (err error) {
+	if err := checkFormat(f.format, f.quiet); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results, err := trid.NewTrid(opts).ScanBatch(ctx, paths, f.matches)
+	if f.progress {
+		fmt.Fprintln(stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w, err := f.writer(prefix, false, stdout)
+	if err != nil {
+		return err
+	}
+	defer closeWriter(w, &err)
+
+	for _, r := range results {
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+
+	return summarize(trid.Summarize(results), f, stderr)
+}
+
+// summarize writes summary to stderr with -summary, and returns an error if
+// some files could not be identified.
+func summarize(summary *trid.Summary, f scanFlags, stderr io.Writer) error {
+	if f.summary {
+		writeSummary(stderr, summary)
+	}
+
+	if failed := summary.Unknown + summary.Errors; failed > 0 {
+		return fmt.Errorf("%d of %d files could not be identified", failed, summary.Files)
+	}
+
+	return nil
+}
+
+// writeSummary writes summary to w as text, file types first by decreasing
+// number of files.
+func writeSummary(w io.Writer, summary *trid.Summary) {
+	fmt.Fprintf(w, "%d files, %d bytes: %d unknown (%.1f%%), %d failed (%.1f%%)\n",
+		summary.Files, summary.Bytes, summary.Unknown, summary.UnknownRate()*100, summary.Errors, summary.ErrorRate()*100)
+
+	names := make([]string, 0, len(summary.Types))
+	for name := range summary.Types {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, b := summary.Types[names[i]], summary.Types[names[j]]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		tc := summary.Types[name]
+		fmt.Fprintf(w, "%8d %12d bytes  %s (%s)\n", tc.Files, tc.Bytes, name, tc.Extension)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/server"
+)
+
+// stringsFlag is a flag that may be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// runServe implements the serve command.
+func runServe(args []string, stderr io.Writer) error {
+	var (
+		f         tridFlags
+		pathRoots stringsFlag
+	)
+
+	fset := newFlagSet("serve", "", stderr)
+	f.register(fset)
+	addr := fset.String("addr", ":8080", "address to listen on")
+	maxConcurrent := fset.Int("max-concurrent", 4, "maximum number of concurrent identifications")
+	maxUploadSize := fset.Int64("max-upload-size", 100<<20, "maximum size of an uploaded file in bytes")
+	maxQueue := fset.Int("max-queue", 0, "maximum number of requests waiting for identification (0: unlimited)")
+	rateLimit := fset.Float64("rate-limit", 0, "maximum requests per second of each client (0: unlimited)")
+	rateBurst := fset.Int("rate-burst", 0, "requests a client may send at once above -rate-limit (default: the rate)")
+	matches := fset.Int("n", 5, "default maximum number of matches per file")
+	fset.Var(&pathRoots, "path-root", "directory /identify/path and /scans may read from (repeatable)")
+	apiKeys := fset.String("api-keys", "", "file of API keys required by the server, one \"KEY [TENANT]\" per line")
+	keyRate := fset.Float64("key-rate", 0, "maximum requests per second of each tenant with -api-keys (0: unlimited)")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	var guard *auth.Guard
+	if *apiKeys != "" {
+		keys, err := readAPIKeys(*apiKeys, auth.Quota{RequestsPerSecond: *keyRate})
+		if err != nil {
+			return err
+		}
+
+		guard = auth.NewGuard(keys)
+	}
+
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	handler := server.New(trid.NewTrid(opts), server.Options{
+		MaxConcurrent:   *maxConcurrent,
+		MaxUploadSize:   *maxUploadSize,
+		MaxQueue:        *maxQueue,
+		RateLimit:       *rateLimit,
+		RateBurst:       *rateBurst,
+		NumberOfMatches: *matches,
+		PathRoots:       pathRoots,
+		Auth:            guard,
+	})
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		// Cancelled scans end their event streams, which Shutdown waits for
+		handler.Close()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stderr, "tridgo: listening on %s\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// readAPIKeys reads the API keys of the file at path, one per line followed by
+// the name of its tenant, which defaults to the line number. Blank lines and
+// lines starting with # are ignored. Every tenant gets quota.
+func readAPIKeys(path string, quota auth.Quota) (auth.StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(auth.StaticKeys)
+	tenants := make(map[string]*auth.Tenant)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		name := "line " + strconv.Itoa(i+1)
+		if len(fields) > 1 {
+			name = fields[1]
+		}
+
+		if tenants[name] == nil {
+			tenants[name] = &auth.Tenant{Name: name, Quota: quota}
+		}
+		keys[fields[0]] = tenants[name]
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no API keys", path)
+	}
+
+	return keys, nil
+}
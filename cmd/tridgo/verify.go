@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/attilabuti/trid"
+)
+
+// errDiverged is returned by the verify command when the engines disagree on
+// some files.
+var errDiverged = errors.New("the native engine and TrID diverged")
+
+// runVerify implements the verify command.
+func runVerify(args []string, stdout, stderr io.Writer) error {
+	var f tridFlags
+	fset := newFlagSet("verify", "PATH...", stderr)
+	f.register(fset)
+	xmlDefs := fset.String("xml", "", "directory of TrID XML definitions used by the native engine")
+	matches := fset.Int("n", 5, "maximum number of matches compared per file")
+	maxDelta := fset.Float64("max-delta", trid.DefaultMaxDelta, "probability difference, in percentage points, reported as a divergence")
+
+	if err := parseFlags(fset, args); err != nil {
+		return err
+	}
+
+	if fset.NArg() == 0 || *xmlDefs == "" {
+		fset.Usage()
+		return errUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	opts.Engine = trid.EngineCLI
+	cli := trid.NewTrid(opts)
+
+	opts.Definitions, opts.Engine = *xmlDefs, trid.EngineNative
+	native := trid.NewTrid(opts)
+
+	var total, diverged int
+	for _, root := range fset.Args() {
+		paths, err := trid.Walk(root, trid.SymlinkFollow)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			v := trid.Verify(ctx, native, cli, path, *matches, *maxDelta)
+			if err := writeVerification(stdout, v); err != nil {
+				return err
+			}
+
+			total++
+			if v.Diverged() {
+				diverged++
+			}
+		}
+	}
+
+	if diverged > 0 {
+		return fmt.Errorf("%w on %d of %d files", errDiverged, diverged, total)
+	}
+
+	return nil
+}
+
+// writeVerification writes the outcome of a verification in text form.
+func writeVerification(w io.Writer, v *trid.Verification) error {
+	if !v.Diverged() {
+		_, err := fmt.Fprintf(w, "%s: ok\n", v.Path)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%s: diverged\n", v.Path); err != nil {
+		return err
+	}
+
+	for _, d := range v.Divergences {
+		if _, err := fmt.Fprintf(w, "  %s: native %s, trid %s\n", d.Kind, outcome(d.Native, v.NativeErr), outcome(d.CLI, v.CLIErr)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// outcome describes the match or error of an engine.
+func outcome(ft trid.FileType, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+
+	return fmt.Sprintf("%.1f%% (%s) %s", ft.Probability, ft.Extension, ft.Name)
+}
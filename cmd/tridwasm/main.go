@@ -0,0 +1,130 @@
+//go:build js && wasm
+
+// Command tridwasm exposes the native engine to JavaScript, so browsers and
+// edge runtimes can identify files client-side with TrID definitions.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o trid.wasm ./cmd/tridwasm
+//
+// and load it with wasm_exec.js from the Go distribution. It defines a global
+// trid object with two functions:
+//
+//	trid.load(definitions) // Array of *.trid.xml files; returns the number loaded
+//	trid.identify(data, n) // Uint8Array of the whole file; returns up to n matches (default 5)
+//
+// Definitions are given as strings, Uint8Arrays, or {name, data} objects to
+// report their file names in matches.
+//
+// Matches are objects with the fields of trid.FileType in their JSON form.
+// Failures return an object with an error field instead.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/attilabuti/trid/native"
+)
+
+// set holds the definitions loaded with trid.load.
+var set = native.NewSet()
+
+func main() {
+	js.Global().Set("trid", js.ValueOf(map[string]any{
+		"load":     js.FuncOf(load),
+		"identify": js.FuncOf(identify),
+	}))
+
+	select {}
+}
+
+// load implements trid.load.
+func load(this js.Value, args []js.Value) any {
+	if len(args) != 1 || !args[0].InstanceOf(js.Global().Get("Array")) {
+		return jsError(errors.New("load expects an array of definitions"))
+	}
+
+	var defs []*native.Definition
+	for i := range args[0].Length() {
+		v, name := args[0].Index(i), ""
+		if v.Type() == js.TypeObject && v.Get("data").Truthy() {
+			v, name = v.Get("data"), v.Get("name").String()
+		}
+
+		data, err := jsBytes(v)
+		if err != nil {
+			return jsError(fmt.Errorf("definition %d: %w", i, err))
+		}
+
+		def, err := native.Parse(bytes.NewReader(data))
+		if err != nil {
+			return jsError(fmt.Errorf("definition %d: %w", i, err))
+		}
+
+		def.File = name
+		defs = append(defs, def)
+	}
+
+	set = native.NewSet(defs...)
+
+	return len(defs)
+}
+
+// identify implements trid.identify.
+func identify(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return jsError(errors.New("identify expects the file contents"))
+	}
+
+	n := 5
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		n = args[1].Int()
+	}
+
+	data, err := jsBytes(args[0])
+	if err != nil {
+		return jsError(err)
+	}
+
+	matches := set.Identify(data)
+
+	result := make([]any, 0, min(len(matches), n))
+	for _, m := range matches[:min(len(matches), n)] {
+		d := m.Definition
+		result = append(result, map[string]any{
+			"extension":   d.Extension,
+			"probability": m.Probability,
+			"name":        d.Name,
+			"mime_type":   d.MimeType,
+			"related_url": d.RelatedURL,
+			"remarks":     d.Remarks,
+			"definition":  d.File,
+		})
+	}
+
+	return result
+}
+
+// jsBytes returns the contents of a string or Uint8Array.
+func jsBytes(v js.Value) ([]byte, error) {
+	if v.Type() == js.TypeString {
+		return []byte(v.String()), nil
+	}
+
+	if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, fmt.Errorf("expected a string or Uint8Array, got %s", v.Type())
+	}
+
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+
+	return b, nil
+}
+
+// jsError returns an object describing err.
+func jsError(err error) any {
+	return map[string]any{"error": err.Error()}
+}
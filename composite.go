@@ -0,0 +1,268 @@
+package trid
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SourceComposite is the FileType.Source of the matches of a Composite.
+const SourceComposite = "composite"
+
+// Engine is a Scanner taking part in a Composite.
+type Engine struct {
+	Name    string  // Name of the engine, reported in the evidence (e.g., "trid", "libmagic").
+	Scanner Scanner // Scanner identifying files.
+	Weight  float64 // Weight of the answers of the engine (default: 1).
+}
+
+// CompositeOptions configures a Composite.
+type CompositeOptions struct {
+	// MinAgreement is the number of engines that must report a file type
+	// for it to be a match (default: 1).
+	MinAgreement int
+
+	// EngineMatches is the number of matches requested from each engine
+	// (default: 5).
+	EngineMatches int
+
+	// Key returns the key under which matches of different engines are
+	// reconciled (default: the lowercase extension, or the MIME type or name
+	// of matches without one).
+	Key func(FileType) string
+}
+
+// Evidence is the match of a single engine supporting a fused match.
+type Evidence struct {
+	Engine   string   // Name of the engine.
+	FileType FileType // Match of the engine.
+}
+
+// FusedMatch is a file type reconciled from the answers of several engines.
+type FusedMatch struct {
+	FileType             // Fused match, with SourceComposite as Source.
+	Agreement int        // Number of engines reporting the file type.
+	Evidence  []Evidence // Matches of the engines reporting the file type, by weight.
+}
+
+// Fusion is the result of identifying a file with a Composite.
+type Fusion struct {
+	Matches []FusedMatch     // Fused matches, most likely first.
+	Errors  map[string]error // Errors of the engines that failed, by name; ErrUnknownFileType is not an error.
+}
+
+// Composite is a Scanner running several engines on each file and fusing
+// their answers, so that the misidentification of a single engine is
+// outvoted by the others. It is safe for concurrent use.
+//
+// The probability of a fused match is the weighted average of the
+// probabilities each engine reports for it, engines that do not report it
+// counting as 0%. Engines that fail are left out of the average; engines
+// that find no match are not. Its other fields are taken from the evidence
+// of the highest weighted probability, completed by the others.
+type Composite struct {
+	ScannerFunc
+
+	engines []Engine
+	options CompositeOptions
+}
+
+var _ Scanner = (*Composite)(nil)
+
+// NewComposite returns a Composite of the given engines.
+func NewComposite(engines []Engine, opts CompositeOptions) *Composite {
+	engines = slices.Clone(engines)
+	for i := range engines {
+		if engines[i].Weight == 0 {
+			engines[i].Weight = 1
+		}
+	}
+
+	if opts.MinAgreement < 1 {
+		opts.MinAgreement = 1
+	}
+
+	if opts.EngineMatches < 1 {
+		opts.EngineMatches = 5
+	}
+
+	if opts.Key == nil {
+		opts.Key = matchKey
+	}
+
+	c := &Composite{engines: engines, options: opts}
+	c.ScannerFunc = func(ctx context.Context, filePath string) ([]FileType, error) {
+		f, err := c.fuse(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		fileTypes := make([]FileType, len(f.Matches))
+		for i, m := range f.Matches {
+			fileTypes[i] = m.FileType
+		}
+
+		return fileTypes, nil
+	}
+
+	return c
+}
+
+// ScanFused identifies the file at filePath with every engine, returning up
+// to numberOfMatches fused matches along with the evidence of each engine.
+// It returns ErrUnknownFileType if no file type is reported by enough
+// engines, and the error of the first engine if all of them failed.
+func (c *Composite) ScanFused(ctx context.Context, filePath string, numberOfMatches int) (*Fusion, error) {
+	if filePath == "" {
+		return nil, ErrNoFileSpecified
+	}
+
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	f, err := c.fuse(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Matches) == 0 {
+		return nil, ErrUnknownFileType
+	}
+
+	f.Matches = f.Matches[:min(len(f.Matches), numberOfMatches)]
+
+	return f, nil
+}
+
+// fuse runs the engines concurrently on the file at filePath and reconciles
+// their answers.
+func (c *Composite) fuse(ctx context.Context, filePath string) (*Fusion, error) {
+	answers := make([][]FileType, len(c.engines))
+	errs := make([]error, len(c.engines))
+
+	var wg sync.WaitGroup
+	for i, e := range c.engines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i], errs[i] = e.Scanner.ScanContext(ctx, filePath, c.options.EngineMatches)
+		}()
+	}
+	wg.Wait()
+
+	f := &Fusion{}
+	byKey := make(map[string]*FusedMatch)
+	var keys []string
+	var totalWeight float64
+	var firstErr error
+
+	for i, e := range c.engines {
+		if err := errs[i]; err != nil && !errors.Is(err, ErrUnknownFileType) {
+			if f.Errors == nil {
+				f.Errors = make(map[string]error)
+			}
+			f.Errors[e.Name] = err
+
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		totalWeight += e.Weight
+
+		// Keep the most likely match of the engine for each key
+		best := make(map[string]FileType)
+		var engineKeys []string
+		for _, ft := range answers[i] {
+			key := c.options.Key(ft)
+			if prev, ok := best[key]; !ok || ft.Probability > prev.Probability {
+				if !ok {
+					engineKeys = append(engineKeys, key)
+				}
+				best[key] = ft
+			}
+		}
+
+		for _, key := range engineKeys {
+			ft := best[key]
+			m := byKey[key]
+			if m == nil {
+				m = &FusedMatch{}
+				byKey[key] = m
+				keys = append(keys, key)
+			}
+
+			m.Agreement++
+			m.Probability += e.Weight * ft.Probability
+			m.Evidence = append(m.Evidence, Evidence{Engine: e.Name, FileType: ft})
+		}
+	}
+
+	if totalWeight == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+
+		return f, nil
+	}
+
+	weight := make(map[string]float64, len(c.engines))
+	for _, e := range c.engines {
+		weight[e.Name] = e.Weight
+	}
+
+	for _, key := range keys {
+		m := byKey[key]
+		if m.Agreement < c.options.MinAgreement {
+			continue
+		}
+
+		slices.SortStableFunc(m.Evidence, func(a, b Evidence) int {
+			return cmp.Compare(weight[b.Engine]*b.FileType.Probability, weight[a.Engine]*a.FileType.Probability)
+		})
+
+		m.FileType = fusedFileType(m.Evidence, m.Probability/totalWeight)
+		f.Matches = append(f.Matches, *m)
+	}
+
+	slices.SortStableFunc(f.Matches, func(a, b FusedMatch) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+
+	return f, nil
+}
+
+// fusedFileType returns the match described by evidence, ordered by weight,
+// with the given probability.
+func fusedFileType(evidence []Evidence, probability float64) FileType {
+	ft := evidence[0].FileType
+	for _, e := range evidence[1:] {
+		ft.Extension = cmp.Or(ft.Extension, e.FileType.Extension)
+		ft.Name = cmp.Or(ft.Name, e.FileType.Name)
+		ft.MimeType = cmp.Or(ft.MimeType, e.FileType.MimeType)
+		ft.RelatedURL = cmp.Or(ft.RelatedURL, e.FileType.RelatedURL)
+	}
+
+	ft.Extension = strings.ToLower(ft.Extension)
+	ft.Probability = probability
+	ft.Source = SourceComposite
+
+	return ft
+}
+
+// matchKey is the default CompositeOptions.Key.
+func matchKey(ft FileType) string {
+	switch {
+	case ft.Extension != "" && ft.Extension != ".":
+		return strings.ToLower(ft.Extension)
+	case ft.MimeType != "":
+		return ft.MimeType
+	default:
+		return ft.Name
+	}
+}
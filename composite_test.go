@@ -0,0 +1,93 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// engineFunc returns a Scanner answering every file with fileTypes or err.
+func engineFunc(fileTypes []FileType, err error) Scanner {
+	return ScannerFunc(func(ctx context.Context, path string) ([]FileType, error) {
+		return fileTypes, err
+	})
+}
+
+func TestComposite(t *testing.T) {
+	c := NewComposite([]Engine{
+		{Name: "trid", Weight: 2, Scanner: engineFunc([]FileType{
+			{Extension: ".jar", Probability: 60, Name: "Java Archive"},
+			{Extension: ".zip", Probability: 40, Name: "ZIP compressed archive"},
+		}, nil)},
+		{Name: "magic", Scanner: engineFunc([]FileType{
+			{Extension: ".ZIP", Probability: 100, Name: "Zip archive data", MimeType: "application/zip"},
+		}, nil)},
+		{Name: "filetype", Scanner: engineFunc([]FileType{
+			{Extension: ".zip", Probability: 100, Name: "application/zip", MimeType: "application/zip"},
+		}, nil)},
+		{Name: "broken", Scanner: engineFunc(nil, ErrUnavailable)},
+	}, CompositeOptions{})
+
+	f, err := c.ScanFused(context.Background(), "testdata/sample.pdf", 5)
+	if err != nil {
+		t.Fatalf("ScanFused() error = %v", err)
+	}
+
+	if len(f.Matches) != 2 {
+		t.Fatalf("ScanFused() = %+v, want 2 matches", f.Matches)
+	}
+
+	// (2*40 + 100 + 100) / 4 and 2*60 / 4
+	zip, jar := f.Matches[0], f.Matches[1]
+	if zip.Extension != ".zip" || zip.Probability != 70 || zip.Agreement != 3 || zip.Source != SourceComposite {
+		t.Errorf("ScanFused()[0] = %+v, want .zip at 70%% by 3 engines", zip)
+	}
+
+	if zip.Name != "Zip archive data" || zip.MimeType != "application/zip" {
+		t.Errorf("ScanFused()[0] = %+v, want the fields of the heaviest evidence", zip.FileType)
+	}
+
+	if zip.Evidence[0].Engine != "magic" || zip.Evidence[2].Engine != "trid" {
+		t.Errorf("ScanFused()[0] evidence = %+v, want by weighted probability", zip.Evidence)
+	}
+
+	if jar.Extension != ".jar" || jar.Probability != 30 || jar.Agreement != 1 {
+		t.Errorf("ScanFused()[1] = %+v, want .jar at 30%% by 1 engine", jar)
+	}
+
+	if !errors.Is(f.Errors["broken"], ErrUnavailable) || len(f.Errors) != 1 {
+		t.Errorf("ScanFused() errors = %v, want broken", f.Errors)
+	}
+
+	fileTypes, err := c.Scan("testdata/sample.pdf", 1)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0] != zip.FileType {
+		t.Errorf("Scan() = %+v, %v, want %+v", fileTypes, err, zip.FileType)
+	}
+}
+
+func TestCompositeAgreement(t *testing.T) {
+	c := NewComposite([]Engine{
+		{Name: "a", Scanner: engineFunc([]FileType{{Extension: ".pdf", Probability: 100}}, nil)},
+		{Name: "b", Scanner: engineFunc([]FileType{{Extension: ".ai", Probability: 100}}, nil)},
+		{Name: "c", Scanner: engineFunc(nil, ErrUnknownFileType)},
+	}, CompositeOptions{MinAgreement: 2})
+
+	if _, err := c.ScanFused(context.Background(), "testdata/sample.pdf", 1); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("ScanFused() error = %v, want %v", err, ErrUnknownFileType)
+	}
+}
+
+func TestCompositeErrors(t *testing.T) {
+	c := NewComposite([]Engine{
+		{Name: "a", Scanner: engineFunc(nil, ErrUnavailable)},
+		{Name: "b", Scanner: engineFunc(nil, ErrTimeout)},
+	}, CompositeOptions{})
+
+	if _, err := c.ScanFused(context.Background(), "testdata/sample.pdf", 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("ScanFused() error = %v, want %v", err, ErrUnavailable)
+	}
+
+	if _, err := c.ScanFused(context.Background(), "", 1); !errors.Is(err, ErrNoFileSpecified) {
+		t.Errorf("ScanFused() error = %v, want %v", err, ErrNoFileSpecified)
+	}
+}
@@ -0,0 +1,254 @@
+package trid
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigEnv is the environment variable naming the configuration file read by
+// LoadOptions when no path is given.
+const ConfigEnv = "TRID_CONFIG"
+
+// ErrInvalidConfig is returned by LoadOptions for malformed configuration.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// configKey describes a configuration setting.
+type configKey struct {
+	name string                           // Key in configuration files.
+	env  string                           // Environment variable.
+	set  func(o *Options, v string) error // Stores the value in the options.
+}
+
+// configKeys lists the settings read by LoadOptions.
+var configKeys = []configKey{
+	{"cmd", "TRID_CMD", func(o *Options, v string) error { o.Cmd = v; return nil }},
+	{"definitions", "TRID_DEFS", func(o *Options, v string) error { o.Definitions = v; return nil }},
+	{"defs_sha256", "TRID_DEFS_SHA256", func(o *Options, v string) error { o.DefsSHA256 = v; return nil }},
+	{"max_defs_age", "TRID_MAX_DEFS_AGE", durationSetter(func(o *Options) *time.Duration { return &o.MaxDefsAge })},
+	{"timeout", "TRID_TIMEOUT", durationSetter(func(o *Options) *time.Duration { return &o.Timeout })},
+	{"timeout_per_mb", "TRID_TIMEOUT_PER_MB", durationSetter(func(o *Options) *time.Duration { return &o.TimeoutPerMB })},
+	{"cache_ttl", "TRID_CACHE_TTL", durationSetter(func(o *Options) *time.Duration { return &o.CacheTTL })},
+	{"negative_cache_ttl", "TRID_NEGATIVE_CACHE_TTL", durationSetter(func(o *Options) *time.Duration { return &o.NegativeCacheTTL })},
+	{"max_concurrent", "TRID_MAX_CONCURRENT", intSetter(func(o *Options) *int { return &o.MaxConcurrent })},
+	{"batch_size", "TRID_BATCH_SIZE", intSetter(func(o *Options) *int { return &o.BatchSize })},
+	{"file_list", "TRID_FILE_LIST", enumSetter(map[string]FileListMode{"auto": FileListAuto, "args": FileListArgs, "stdin": FileListStdin}, func(o *Options) *FileListMode { return &o.FileList })},
+	{"retry_attempts", "TRID_RETRY_ATTEMPTS", intSetter(func(o *Options) *int { return &o.Retry.Attempts })},
+	{"retry_backoff", "TRID_RETRY_BACKOFF", durationSetter(func(o *Options) *time.Duration { return &o.Retry.Backoff })},
+	{"breaker_threshold", "TRID_BREAKER_THRESHOLD", intSetter(func(o *Options) *int { return &o.Breaker.Threshold })},
+	{"breaker_cooldown", "TRID_BREAKER_COOLDOWN", durationSetter(func(o *Options) *time.Duration { return &o.Breaker.Cooldown })},
+	{"min_probability", "TRID_MIN_PROBABILITY", func(o *Options, v string) (err error) { o.MinProbability, err = strconv.ParseFloat(v, 64); return err }},
+	{"fallback", "TRID_FALLBACK", boolSetter(func(o *Options) *bool { return &o.Fallback })},
+	{"containers", "TRID_CONTAINERS", boolSetter(func(o *Options) *bool { return &o.Containers })},
+	{"charset", "TRID_CHARSET", boolSetter(func(o *Options) *bool { return &o.Charset })},
+	{"precheck", "TRID_PRECHECK", boolSetter(func(o *Options) *bool { return &o.Precheck })},
+	{"executables", "TRID_EXECUTABLES", boolSetter(func(o *Options) *bool { return &o.Executables })},
+	{"pdf", "TRID_PDF", boolSetter(func(o *Options) *bool { return &o.PDF })},
+	{"office", "TRID_OFFICE", boolSetter(func(o *Options) *bool { return &o.Office })},
+	{"images", "TRID_IMAGES", boolSetter(func(o *Options) *bool { return &o.Images })},
+	{"suspicious_names", "TRID_SUSPICIOUS_NAMES", boolSetter(func(o *Options) *bool { return &o.SuspiciousNames })},
+	{"engine", "TRID_ENGINE", enumSetter(map[string]EngineMode{"cli": EngineCLI, "native": EngineNative, "auto": EngineAuto}, func(o *Options) *EngineMode { return &o.Engine })},
+	{"extra_args", "TRID_EXTRA_ARGS", func(o *Options, v string) error { o.ExtraArgs = parseList(v); return nil }},
+	{"symlinks", "TRID_SYMLINKS", enumSetter(map[string]SymlinkPolicy{"follow": SymlinkFollow, "refuse": SymlinkRefuse, "target": SymlinkTarget}, func(o *Options) *SymlinkPolicy { return &o.Symlinks })},
+	{"max_file_size", "TRID_MAX_FILE_SIZE", int64Setter(func(o *Options) *int64 { return &o.MaxFileSize })},
+	{"oversize", "TRID_OVERSIZE", enumSetter(map[string]OversizePolicy{"fail": OversizeFail, "truncate": OversizeTruncate}, func(o *Options) *OversizePolicy { return &o.Oversize })},
+	{"local_copy", "TRID_LOCAL_COPY", boolSetter(func(o *Options) *bool { return &o.LocalCopy.Enabled })},
+	{"local_copy_head_size", "TRID_LOCAL_COPY_HEAD_SIZE", int64Setter(func(o *Options) *int64 { return &o.LocalCopy.HeadSize })},
+	{"local_copy_dir", "TRID_LOCAL_COPY_DIR", func(o *Options, v string) error { o.LocalCopy.Dir = v; return nil }},
+}
+
+// LoadOptions returns options read from a configuration file and the
+// environment, for deployments that are configured without code changes.
+// Environment variables take precedence over the file, whose settings take
+// precedence over the defaults applied by NewTrid.
+//
+// The file at path, or named by TRID_CONFIG if path is empty, is optional. It
+// holds one "key = value" (TOML) or "key: value" (YAML) setting per line, with
+// "#" starting comments; strings may be quoted and extra_args may be a list
+// such as ["-w", "-r:3"]. Keys are the lowercase names of the environment
+// variables without the TRID_ prefix, except "definitions" for TRID_DEFS.
+// Durations use time.ParseDuration syntax, such as "45s".
+func LoadOptions(path string) (Options, error) {
+	var opts Options
+
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+
+	if path != "" {
+		if err := loadConfigFile(&opts, path); err != nil {
+			return Options{}, err
+		}
+	}
+
+	for _, key := range configKeys {
+		v, ok := os.LookupEnv(key.env)
+		if !ok {
+			continue
+		}
+
+		if err := key.set(&opts, strings.TrimSpace(v)); err != nil {
+			return Options{}, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, key.env, err)
+		}
+	}
+
+	return opts, nil
+}
+
+// loadConfigFile stores the settings of the configuration file at path in
+// opts.
+func loadConfigFile(opts *Options, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	keys := make(map[string]configKey, len(configKeys))
+	for _, key := range configKeys {
+		keys[key.name] = key
+	}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return fmt.Errorf("%w: %s:%d: expected key and value", ErrInvalidConfig, path, n)
+		}
+
+		name := strings.TrimSpace(line[:i])
+		key, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("%w: %s:%d: unknown key %q", ErrInvalidConfig, path, n, name)
+		}
+
+		if err := key.set(opts, unquote(strings.TrimSpace(line[i+1:]))); err != nil {
+			return fmt.Errorf("%w: %s:%d: %s: %v", ErrInvalidConfig, path, n, name, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// stripComment removes a "#" comment outside of quotes from line.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// unquote removes matching single or double quotes around v.
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+
+	return v
+}
+
+// parseList parses a list of strings, either as a bracketed, comma-separated
+// list or as space-separated words.
+func parseList(v string) []string {
+	if !strings.HasPrefix(v, "[") || !strings.HasSuffix(v, "]") {
+		return strings.Fields(v)
+	}
+
+	var list []string
+	for _, item := range strings.Split(v[1:len(v)-1], ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// durationSetter returns a setter parsing a duration into the field returned
+// by field.
+func durationSetter(field func(o *Options) *time.Duration) func(*Options, string) error {
+	return func(o *Options, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+
+		*field(o) = d
+		return nil
+	}
+}
+
+// intSetter returns a setter parsing an integer into the field returned by
+// field.
+func intSetter(field func(o *Options) *int) func(*Options, string) error {
+	return func(o *Options, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+
+		*field(o) = n
+		return nil
+	}
+}
+
+// int64Setter is like intSetter for 64-bit integers.
+func int64Setter(field func(o *Options) *int64) func(*Options, string) error {
+	return func(o *Options, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		*field(o) = n
+		return nil
+	}
+}
+
+// boolSetter returns a setter parsing a boolean into the field returned by
+// field.
+func boolSetter(field func(o *Options) *bool) func(*Options, string) error {
+	return func(o *Options, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+
+		*field(o) = b
+		return nil
+	}
+}
+
+// enumSetter returns a setter storing the value named v in values into the
+// field returned by field.
+func enumSetter[T any](values map[string]T, field func(o *Options) *T) func(*Options, string) error {
+	return func(o *Options, v string) error {
+		value, ok := values[strings.ToLower(v)]
+		if !ok {
+			return fmt.Errorf("unknown value %q", v)
+		}
+
+		*field(o) = value
+		return nil
+	}
+}
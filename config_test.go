@@ -0,0 +1,118 @@
+package trid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadOptions(t *testing.T) {
+	for _, key := range configKeys {
+		t.Setenv(key.env, "")
+		os.Unsetenv(key.env)
+	}
+	t.Setenv(ConfigEnv, "")
+
+	dir := t.TempDir()
+	toml := filepath.Join(dir, "trid.toml")
+	yaml := filepath.Join(dir, "trid.yaml")
+
+	if err := os.WriteFile(toml, []byte(`# TrID settings
+cmd = "/opt/trid/trid"
+timeout = "45s"
+max_concurrent = 4
+extra_args = ["-w", "-r:3"] # passed through
+symlinks = "refuse"
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(yaml, []byte(`---
+cmd: 'C:\TrID\trid.exe'
+definitions: /srv/triddefs.trd
+local_copy: true
+oversize: truncate
+engine: auto
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := LoadOptions(toml)
+	if err != nil {
+		t.Fatalf("LoadOptions() error = %v", err)
+	}
+
+	expected := Options{
+		Cmd:           "/opt/trid/trid",
+		Timeout:       45 * time.Second,
+		MaxConcurrent: 4,
+		ExtraArgs:     []string{"-w", "-r:3"},
+		Symlinks:      SymlinkRefuse,
+	}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("LoadOptions() = %+v, want %+v", opts, expected)
+	}
+
+	// Environment variables take precedence over the file
+	t.Setenv(ConfigEnv, yaml)
+	t.Setenv("TRID_DEFS", "/etc/triddefs.trd")
+	t.Setenv("TRID_BATCH_SIZE", "50")
+
+	opts, err = LoadOptions("")
+	if err != nil {
+		t.Fatalf("LoadOptions() error = %v", err)
+	}
+
+	expected = Options{
+		Cmd:         `C:\TrID\trid.exe`,
+		Definitions: "/etc/triddefs.trd",
+		BatchSize:   50,
+		Oversize:    OversizeTruncate,
+		LocalCopy:   LocalCopy{Enabled: true},
+		Engine:      EngineAuto,
+	}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("LoadOptions() = %+v, want %+v", opts, expected)
+	}
+}
+
+func TestLoadOptionsErrors(t *testing.T) {
+	t.Setenv(ConfigEnv, "")
+
+	tests := []struct {
+		name    string
+		content string
+		env     string
+	}{
+		{"Unknown key", "timout = 1s\n", ""},
+		{"Missing value separator", "cmd\n", ""},
+		{"Invalid duration", "timeout = soon\n", ""},
+		{"Invalid enum", "symlinks: sometimes\n", ""},
+		{"Invalid environment", "", "many"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "trid.toml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Setenv("TRID_MAX_CONCURRENT", "1")
+			if tt.env != "" {
+				t.Setenv("TRID_MAX_CONCURRENT", tt.env)
+			}
+
+			if _, err := LoadOptions(path); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("LoadOptions() error = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+
+	if _, err := LoadOptions(filepath.Join(t.TempDir(), "missing.toml")); !os.IsNotExist(err) {
+		t.Errorf("LoadOptions() error = %v, want not exist", err)
+	}
+}
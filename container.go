@@ -0,0 +1,193 @@
+package trid
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// SourceContainer is the FileType.Source of matches refined by inspecting the
+// structure of zip-based containers.
+const SourceContainer = "container"
+
+// maxContainerManifest is the size above which the manifests of a container
+// are not read.
+const maxContainerManifest = 1 << 20
+
+// containerType is a file type recognized from the structure of a zip-based
+// container.
+type containerType struct {
+	Extension string
+	Name      string
+	MimeType  string
+}
+
+// zipExts are the extensions of the file types built on zip archives, whose
+// matches are refined.
+var zipExts = map[string]bool{
+	".zip": true, ".jar": true, ".apk": true, ".epub": true,
+	".docx": true, ".docm": true, ".xlsx": true, ".xlsm": true, ".pptx": true, ".pptm": true,
+	".odt": true, ".ods": true, ".odp": true, ".odg": true,
+}
+
+// odfTypes maps the contents of the mimetype entry of OpenDocument and EPUB
+// files to their file type.
+var odfTypes = map[string]containerType{
+	"application/vnd.oasis.opendocument.text":         {".odt", "OpenDocument Text", "application/vnd.oasis.opendocument.text"},
+	"application/vnd.oasis.opendocument.spreadsheet":  {".ods", "OpenDocument Spreadsheet", "application/vnd.oasis.opendocument.spreadsheet"},
+	"application/vnd.oasis.opendocument.presentation": {".odp", "OpenDocument Presentation", "application/vnd.oasis.opendocument.presentation"},
+	"application/vnd.oasis.opendocument.graphics":     {".odg", "OpenDocument Drawing", "application/vnd.oasis.opendocument.graphics"},
+	"application/epub+zip":                            {".epub", "EPUB electronic publication", "application/epub+zip"},
+}
+
+// ooxmlTypes maps the content types of the main part of Office Open XML files,
+// declared in [Content_Types].xml, to their file type.
+var ooxmlTypes = map[string]containerType{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml":   {".docx", "Word Microsoft Office Open XML Format document", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	"application/vnd.ms-word.document.macroenabled.main+xml":                             {".docm", "Word Microsoft Office Open XML Format document (with Macro)", "application/vnd.ms-word.document.macroEnabled.12"},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml":         {".xlsx", "Excel Microsoft Office Open XML Format document", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	"application/vnd.ms-excel.sheet.macroenabled.main+xml":                               {".xlsm", "Excel Microsoft Office Open XML Format document (with Macro)", "application/vnd.ms-excel.sheet.macroEnabled.12"},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml": {".pptx", "PowerPoint Microsoft Office Open XML Format document", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	"application/vnd.ms-powerpoint.presentation.macroenabled.main+xml":                   {".pptm", "PowerPoint Microsoft Office Open XML Format document (with Macro)", "application/vnd.ms-powerpoint.presentation.macroEnabled.12"},
+}
+
+// File types recognized from the entries of a zip archive.
+var (
+	apkType = containerType{".apk", "Android Package", "application/vnd.android.package-archive"}
+	jarType = containerType{".jar", "Java Archive", "application/java-archive"}
+)
+
+// refineContainer refines the matches of the file at path, if
+// Options.Containers is set and the most likely one is a zip-based container,
+// by inspecting its structure. The file type found takes the lead, merged
+// with the match of the same extension, if any.
+func (t *Trid) refineContainer(ctx context.Context, path string, fileTypes []FileType) []FileType {
+	if !t.options.Containers || len(fileTypes) == 0 || !zipExts[strings.ToLower(fileTypes[0].Extension)] {
+		return fileTypes
+	}
+
+	ct, evidence := inspectContainer(path)
+	if evidence == "" {
+		return fileTypes
+	}
+
+	t.log(ctx, slog.LevelDebug, "refined container", "path", path, "extension", ct.Extension, "evidence", evidence)
+
+	// Merge the match of the same extension, if TrID found one
+	refined := FileType{Name: ct.Name}
+	rest := make([]FileType, 0, len(fileTypes))
+	merged := -1
+	for i, ft := range fileTypes {
+		if merged < 0 && strings.EqualFold(ft.Extension, ct.Extension) {
+			refined, merged = ft, i
+			continue
+		}
+
+		rest = append(rest, ft)
+	}
+
+	// The container is at least as likely as the generic match it refines
+	if merged != 0 {
+		refined.Probability += fileTypes[0].Probability
+	}
+	refined.Extension = ct.Extension
+	refined.MimeType = ct.MimeType
+	refined.Source = SourceContainer
+	refined.Remarks = strings.TrimSpace(refined.Remarks + " " + evidence)
+
+	refinedTypes := append([]FileType{refined}, rest...)
+	if len(refinedTypes) > len(fileTypes) {
+		refinedTypes = refinedTypes[:len(fileTypes)]
+	}
+
+	return refinedTypes
+}
+
+// inspectContainer recognizes the zip-based container at path from its
+// entries. It returns the file type found and a description of the evidence,
+// or an empty evidence if the file is not a container it recognizes.
+func inspectContainer(path string) (containerType, string) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return containerType{}, ""
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	if f := entries["mimetype"]; f != nil {
+		if b, err := readEntry(f); err == nil {
+			if ct, ok := odfTypes[string(bytes.TrimSpace(b))]; ok {
+				return ct, "Identified by the mimetype entry."
+			}
+		}
+	}
+
+	if f := entries["[Content_Types].xml"]; f != nil {
+		if b, err := readEntry(f); err == nil {
+			if ct, ok := ooxmlType(b); ok {
+				return ct, "Identified by [Content_Types].xml."
+			}
+		}
+	}
+
+	if entries["AndroidManifest.xml"] != nil && entries["classes.dex"] != nil {
+		return apkType, "Identified by AndroidManifest.xml and classes.dex."
+	}
+
+	if entries["META-INF/MANIFEST.MF"] != nil {
+		return jarType, "Identified by META-INF/MANIFEST.MF."
+	}
+
+	return containerType{}, ""
+}
+
+// ooxmlType returns the file type of an Office Open XML file from its
+// [Content_Types].xml.
+func ooxmlType(contentTypes []byte) (containerType, bool) {
+	var types struct {
+		Overrides []struct {
+			ContentType string `xml:"ContentType,attr"`
+		} `xml:"Override"`
+	}
+
+	if err := xml.Unmarshal(contentTypes, &types); err != nil {
+		return containerType{}, false
+	}
+
+	for _, o := range types.Overrides {
+		if ct, ok := ooxmlTypes[strings.ToLower(o.ContentType)]; ok {
+			return ct, true
+		}
+	}
+
+	return containerType{}, false
+}
+
+// readEntry returns the contents of the zip entry f, if not larger than
+// maxContainerManifest.
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxContainerManifest+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > maxContainerManifest {
+		return nil, zip.ErrFormat
+	}
+
+	return b, nil
+}
@@ -0,0 +1,79 @@
+package trid
+
+import (
+	"context"
+	"testing"
+)
+
+const zipOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: archive.zip
+ 60.0% (.ZIP) ZIP compressed archive (4000/1)
+ 25.0% (.DOCX) Word Microsoft Office Open XML Format document (1600/1)
+ 15.0% (.JAR) Java Archive (1000/1)
+`
+
+func TestRefineContainer(t *testing.T) {
+	contentTypes := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+</Types>`)
+
+	tests := []struct {
+		name        string
+		entries     map[string][]byte
+		extension   string
+		probability float64
+	}{
+		{"docx", map[string][]byte{"[Content_Types].xml": []byte(`<Types><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`)}, ".docx", 85},
+		{"xlsx", map[string][]byte{"[Content_Types].xml": contentTypes}, ".xlsx", 60},
+		{"odt", map[string][]byte{"mimetype": []byte("application/vnd.oasis.opendocument.text")}, ".odt", 60},
+		{"jar", map[string][]byte{"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n")}, ".jar", 75},
+		{"apk", map[string][]byte{"AndroidManifest.xml": nil, "classes.dex": nil, "META-INF/MANIFEST.MF": nil}, ".apk", 60},
+		{"zip", map[string][]byte{"notes.txt": []byte("plain text")}, ".zip", 60},
+	}
+
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: zipOutput}, nil
+	})
+	trid := NewTrid(Options{Cmd: "trid", Runner: runner, Containers: true})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for name := range tt.entries {
+				names = append(names, name)
+			}
+			archive := writeZip(t, tt.entries, names...)
+
+			fileTypes, err := trid.Scan(archive, 3)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			top := fileTypes[0]
+			if top.Extension != tt.extension || top.Probability != tt.probability || len(fileTypes) != 3 {
+				t.Errorf("Scan() = %+v, want %s at %v%% first", fileTypes, tt.extension, tt.probability)
+			}
+
+			if refined := tt.extension != ".zip"; refined != (top.Source == SourceContainer) || refined && top.Remarks == "" {
+				t.Errorf("Scan() top match = %+v", top)
+			}
+
+			results, err := trid.ScanBatch(context.Background(), []string{archive}, 3)
+			if err != nil || results[0].Err != nil || results[0].FileTypes[0] != top {
+				t.Errorf("ScanBatch() = %+v, %v, want %+v first", results, err, top)
+			}
+		})
+	}
+
+	// Matches are left alone by default
+	archive := writeZip(t, map[string][]byte{"mimetype": []byte("application/epub+zip")}, "mimetype")
+	if fileTypes, err := NewTrid(Options{Cmd: "trid", Runner: runner}).Scan(archive, 1); err != nil || fileTypes[0].Extension != ".zip" {
+		t.Errorf("Scan() without Containers = %+v, %v", fileTypes, err)
+	}
+}
@@ -0,0 +1,259 @@
+// Package defs provides information about TrID definition packages.
+package defs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmptyPackage is returned when a downloaded definitions package is empty.
+	ErrEmptyPackage = errors.New("definitions package is empty")
+
+	// ErrNoPackageInArchive is returned when a downloaded archive contains no
+	// .trd file.
+	ErrNoPackageInArchive = errors.New("no definitions package found in archive")
+
+	// ErrInvalidChecksum is returned by ReadChecksum when the sidecar file
+	// does not hold a SHA-256 checksum.
+	ErrInvalidChecksum = errors.New("invalid definitions checksum")
+)
+
+// DefaultFile is the file name of the definitions package TrID loads when no
+// package is specified.
+const DefaultFile = "triddefs.trd"
+
+// Info describes a TrID definitions package on disk.
+type Info struct {
+	Path    string    `json:"path"`     // Absolute path of the package.
+	Size    int64     `json:"size"`     // Size of the package in bytes.
+	ModTime time.Time `json:"mod_time"` // Last modification time of the package.
+	SHA256  string    `json:"sha256"`   // Hex-encoded SHA-256 checksum of the package.
+}
+
+// Locate returns the path of the definitions package used by TrID. If path is
+// empty, the default package next to the TrID executable named by cmd is
+// returned.
+func Locate(cmd, path string) (string, error) {
+	if path != "" {
+		return filepath.Abs(path)
+	}
+
+	bin, err := exec.LookPath(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(bin); err == nil {
+		bin = resolved
+	}
+
+	return filepath.Abs(filepath.Join(filepath.Dir(bin), DefaultFile))
+}
+
+// Stat returns information about the definitions package at path.
+func Stat(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Path:    abs,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// ChecksumSuffix is appended to the path of a definitions package to name its
+// sidecar checksum file.
+const ChecksumSuffix = ".sha256"
+
+// ReadChecksum reads the expected SHA-256 checksum of the package at path from
+// its sidecar file, path followed by ChecksumSuffix, as written by sha256sum:
+// the hex-encoded checksum, optionally followed by the file name.
+func ReadChecksum(path string) (string, error) {
+	b, err := os.ReadFile(path + ChecksumSuffix)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%w: %s is empty", ErrInvalidChecksum, path+ChecksumSuffix)
+	}
+
+	sum := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("%w: %q", ErrInvalidChecksum, fields[0])
+	}
+
+	return sum, nil
+}
+
+// DefaultURL is the official download location of the TrID definitions
+// package.
+const DefaultURL = "https://mark0.net/download/triddefs.zip"
+
+// UpdateOptions configures how UpdateWithOptions downloads the definitions
+// package, for fleets that cannot reach the official location directly.
+type UpdateOptions struct {
+	URL       string       // Download location, such as an internal mirror (default: DefaultURL).
+	Proxy     *url.URL     // HTTP proxy (default: from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables).
+	TLSConfig *tls.Config  // TLS configuration, such as the root CAs of a mirror (default: the system's).
+	Client    *http.Client // HTTP client making the request; Proxy and TLSConfig are ignored if set.
+}
+
+// client returns the HTTP client selected by the options.
+func (o UpdateOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+
+	if o.Proxy == nil && o.TLSConfig == nil {
+		return http.DefaultClient
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if o.Proxy != nil {
+		tr.Proxy = http.ProxyURL(o.Proxy)
+	}
+	if o.TLSConfig != nil {
+		tr.TLSClientConfig = o.TLSConfig
+	}
+
+	return &http.Client{Transport: tr}
+}
+
+// Update downloads the definitions package from url (DefaultURL if empty) and
+// atomically replaces the package at path, like UpdateWithOptions.
+func Update(ctx context.Context, url, path string) (*Info, error) {
+	return UpdateWithOptions(ctx, path, UpdateOptions{URL: url})
+}
+
+// UpdateWithOptions downloads the definitions package as configured by opts
+// and atomically replaces the package at path. The download may be the
+// package itself or a zip archive containing it, in which case the
+// modification time of the package is set to that recorded in the archive,
+// its build date.
+func UpdateWithOptions(ctx context.Context, path string, opts UpdateOptions) (*Info, error) {
+	url := opts.URL
+	if url == "" {
+		url = DefaultURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading definitions: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, built, err := extract(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyPackage
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".triddefs-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !built.IsZero() {
+		if err := os.Chtimes(tmp.Name(), time.Time{}, built); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+
+	return Stat(path)
+}
+
+// extract returns the definitions package from a downloaded file, unpacking
+// it if it is a zip archive, and its modification time in the archive, if
+// any.
+func extract(body []byte) ([]byte, time.Time, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		// Not a zip archive; assume the package itself was downloaded
+		return body, time.Time{}, nil
+	}
+
+	for _, f := range zr.File {
+		if !strings.EqualFold(filepath.Ext(f.Name), ".trd") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		return data, f.Modified, err
+	}
+
+	return nil, time.Time{}, ErrNoPackageInArchive
+}
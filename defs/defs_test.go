@@ -0,0 +1,224 @@
+package defs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	if info.Size != 3 {
+		t.Errorf("Stat() size = %d, want 3", info.Size)
+	}
+
+	if expected := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; info.SHA256 != expected {
+		t.Errorf("Stat() sha256 = %s, want %s", info.SHA256, expected)
+	}
+
+	if _, err := Stat(filepath.Join(t.TempDir(), "missing.trd")); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want not exist", err)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "trid")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Locate(bin, "")
+	if err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+
+	if expected := filepath.Join(dir, DefaultFile); path != expected {
+		t.Errorf("Locate() = %s, want %s", path, expected)
+	}
+
+	path, err = Locate(bin, "custom.trd")
+	if err != nil || filepath.Base(path) != "custom.trd" {
+		t.Errorf("Locate() = %s, %v, want custom.trd", path, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	built := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fw, _ := zw.CreateHeader(&zip.FileHeader{Name: "triddefs.trd", Modified: built})
+	fw.Write([]byte("new definitions"))
+	zw.Close()
+
+	emptyArchive := bytes.Buffer{}
+	zip.NewWriter(&emptyArchive).Close()
+
+	files := map[string][]byte{
+		"/triddefs.zip":  archive.Bytes(),
+		"/triddefs.trd":  []byte("raw definitions"),
+		"/empty.zip":     emptyArchive.Bytes(),
+		"/triddefs.none": {},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedData string
+		expectedErr  error
+	}{
+		{"Zip archive", "/triddefs.zip", "new definitions", nil},
+		{"Raw package", "/triddefs.trd", "raw definitions", nil},
+		{"Archive without package", "/empty.zip", "", ErrNoPackageInArchive},
+		{"Empty download", "/triddefs.none", "", ErrEmptyPackage},
+		{"Missing", "/missing", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), DefaultFile)
+			if err := os.WriteFile(dest, []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := Update(context.Background(), srv.URL+tt.path, dest)
+			if tt.expectedData == "" {
+				if err == nil || (tt.expectedErr != nil && !errors.Is(err, tt.expectedErr)) {
+					t.Errorf("Update() error = %v, expectedErr %v", err, tt.expectedErr)
+				}
+
+				if b, _ := os.ReadFile(dest); string(b) != "old" {
+					t.Errorf("Update() replaced package on failure: %q", b)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			if b, _ := os.ReadFile(dest); string(b) != tt.expectedData {
+				t.Errorf("Update() wrote %q, want %q", b, tt.expectedData)
+			}
+
+			if info.Size != int64(len(tt.expectedData)) {
+				t.Errorf("Update() info size = %d, want %d", info.Size, len(tt.expectedData))
+			}
+
+			if archived := tt.path == "/triddefs.zip"; archived != info.ModTime.Equal(built) {
+				t.Errorf("Update() info mod time = %v, want the build date %v: %t", info.ModTime, built, archived)
+			}
+		})
+	}
+}
+
+func TestReadChecksum(t *testing.T) {
+	dir := t.TempDir()
+	sum := "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		expectedErr error
+	}{
+		{"Checksum", sum + "\n", strings.ToLower(sum), nil},
+		{"Checksum with file name", sum + "  triddefs.trd\n", strings.ToLower(sum), nil},
+		{"Empty", "", "", ErrInvalidChecksum},
+		{"Not a SHA-256 checksum", "abc triddefs.trd", "", ErrInvalidChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, DefaultFile)
+			if err := os.WriteFile(path+ChecksumSuffix, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := ReadChecksum(path)
+			if actual != tt.expected || !errors.Is(err, tt.expectedErr) {
+				t.Errorf("ReadChecksum() = %q, %v, want %q, %v", actual, err, tt.expected, tt.expectedErr)
+			}
+		})
+	}
+
+	if _, err := ReadChecksum(filepath.Join(dir, "missing.trd")); !os.IsNotExist(err) {
+		t.Errorf("ReadChecksum() error = %v, want not exist", err)
+	}
+}
+
+func TestUpdateWithOptions(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	fw, _ := zw.Create("triddefs.trd")
+	fw.Write([]byte("mirrored definitions"))
+	zw.Close()
+
+	mirror := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	}))
+	defer mirror.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(mirror.Certificate())
+
+	dest := filepath.Join(t.TempDir(), DefaultFile)
+	if _, err := UpdateWithOptions(context.Background(), dest, UpdateOptions{URL: mirror.URL}); err == nil {
+		t.Error("UpdateWithOptions() trusted the certificate of the mirror without TLSConfig")
+	}
+
+	if _, err := UpdateWithOptions(context.Background(), dest, UpdateOptions{URL: mirror.URL, TLSConfig: &tls.Config{RootCAs: roots}}); err != nil {
+		t.Fatalf("UpdateWithOptions() error = %v", err)
+	}
+
+	if b, _ := os.ReadFile(dest); string(b) != "mirrored definitions" {
+		t.Errorf("UpdateWithOptions() wrote %q, want %q", b, "mirrored definitions")
+	}
+
+	// The proxy receives requests for plain HTTP locations
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Write(archive.Bytes())
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	if _, err := UpdateWithOptions(context.Background(), dest, UpdateOptions{URL: "http://mirror.invalid/triddefs.zip", Proxy: proxyURL}); err != nil {
+		t.Fatalf("UpdateWithOptions() through a proxy error = %v", err)
+	}
+
+	if requested != "http://mirror.invalid/triddefs.zip" {
+		t.Errorf("proxy received %q, want http://mirror.invalid/triddefs.zip", requested)
+	}
+}
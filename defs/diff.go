@@ -0,0 +1,148 @@
+package defs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/attilabuti/trid/native"
+)
+
+// ErrBinaryPackage is returned by Diff when given a .trd package, whose
+// format is not documented; the XML definitions of the same release are
+// compared instead.
+var ErrBinaryPackage = errors.New("binary definitions packages cannot be compared, use the XML definitions")
+
+// Changes lists the definitions added, removed and modified between two
+// definitions packages, each sorted by definition file name.
+type Changes struct {
+	Added    []Change `json:"added"`
+	Removed  []Change `json:"removed"`
+	Modified []Change `json:"modified"`
+}
+
+// Change describes a definition that changed between two packages.
+type Change struct {
+	File      string   `json:"file"`             // Name of the definition file (e.g., "pdf.trid.xml").
+	Name      string   `json:"name"`             // File type, in the newer package if modified.
+	Extension string   `json:"extension"`        // Extension, in the newer package if modified.
+	Fields    []string `json:"fields,omitempty"` // Properties that changed, for modified definitions: "name", "extension", "mime_type", "url", "remarks", "patterns" or "strings".
+}
+
+// Empty reports whether no definition changed.
+func (c *Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Diff compares the TrID XML definitions of two packages, each a directory of
+// *.trid.xml files or a zip archive of them. Definitions are matched by file
+// name.
+func Diff(oldPkg, newPkg string) (*Changes, error) {
+	oldDefs, err := loadXML(oldPkg)
+	if err != nil {
+		return nil, err
+	}
+
+	newDefs, err := loadXML(newPkg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Changes{}
+	for file, d := range newDefs {
+		old, ok := oldDefs[file]
+		if !ok {
+			c.Added = append(c.Added, change(d, nil))
+			continue
+		}
+
+		if fields := changedFields(old, d); len(fields) > 0 {
+			c.Modified = append(c.Modified, change(d, fields))
+		}
+	}
+
+	for file, d := range oldDefs {
+		if _, ok := newDefs[file]; !ok {
+			c.Removed = append(c.Removed, change(d, nil))
+		}
+	}
+
+	for _, changes := range [][]Change{c.Added, c.Removed, c.Modified} {
+		slices.SortFunc(changes, func(a, b Change) int {
+			return strings.Compare(a.File, b.File)
+		})
+	}
+
+	return c, nil
+}
+
+// loadXML loads the XML definitions of the package at path, by file name.
+func loadXML(path string) (map[string]*native.Definition, error) {
+	if strings.EqualFold(filepath.Ext(path), ".trd") {
+		return nil, ErrBinaryPackage
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set *native.Set
+	if fi.IsDir() {
+		set, err = native.Load(os.DirFS(path))
+	} else {
+		var zr *zip.ReadCloser
+		if zr, err = zip.OpenReader(path); err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		set, err = native.Load(zr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[string]*native.Definition, set.Len())
+	for _, d := range set.Definitions() {
+		defs[d.File] = d
+	}
+
+	return defs, nil
+}
+
+// change returns the change of definition d.
+func change(d *native.Definition, fields []string) Change {
+	return Change{File: d.File, Name: d.Name, Extension: d.Extension, Fields: fields}
+}
+
+// changedFields returns the properties that differ between two versions of a
+// definition.
+func changedFields(old, d *native.Definition) []string {
+	var fields []string
+	for _, f := range []struct {
+		name    string
+		changed bool
+	}{
+		{"name", old.Name != d.Name},
+		{"extension", old.Extension != d.Extension},
+		{"mime_type", old.MimeType != d.MimeType},
+		{"url", old.RelatedURL != d.RelatedURL},
+		{"remarks", old.Remarks != d.Remarks},
+		{"patterns", !slices.EqualFunc(old.Patterns, d.Patterns, func(a, b native.Pattern) bool {
+			return a.Offset == b.Offset && bytes.Equal(a.Bytes, b.Bytes)
+		})},
+		{"strings", !slices.EqualFunc(old.Strings, d.Strings, bytes.Equal)},
+	} {
+		if f.changed {
+			fields = append(fields, f.name)
+		}
+	}
+
+	return fields
+}
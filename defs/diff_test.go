@@ -0,0 +1,84 @@
+package defs
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// definition returns a TrID XML definition of the given file type, extension
+// and pattern.
+func definition(name, ext, pattern string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<TrID ver="2.00">
+	<Info><FileType>` + name + `</FileType><Ext>` + ext + `</Ext></Info>
+	<General><CheckStrings>False</CheckStrings></General>
+	<FrontBlock><Pattern><Bytes>` + pattern + `</Bytes><Pos>0</Pos></Pattern></FrontBlock>
+</TrID>`
+}
+
+func TestDiff(t *testing.T) {
+	oldDir := t.TempDir()
+	for file, def := range map[string]string{
+		"a.trid.xml": definition("Kept", "A", "41"),
+		"b.trid.xml": definition("Removed", "B", "42"),
+		"c.trid.xml": definition("Modified", "C", "43"),
+	} {
+		if err := os.WriteFile(filepath.Join(oldDir, file), []byte(def), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The newer package is a zip archive, with definitions in subdirectories
+	newPkg := filepath.Join(t.TempDir(), "triddefs_xml.zip")
+	f, err := os.Create(newPkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(f)
+	for file, def := range map[string]string{
+		"defs/a/a.trid.xml": definition("Kept", "A", "41"),
+		"defs/c/c.trid.xml": definition("Modified type", "C", "4344"),
+		"defs/d/d.trid.xml": definition("Added", "D", "44"),
+	} {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(def)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Diff(oldDir, newPkg)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	expected := &Changes{
+		Added:    []Change{{File: "d.trid.xml", Name: "Added", Extension: ".d"}},
+		Removed:  []Change{{File: "b.trid.xml", Name: "Removed", Extension: ".b"}},
+		Modified: []Change{{File: "c.trid.xml", Name: "Modified type", Extension: ".c", Fields: []string{"name", "patterns"}}},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Diff() = %+v, want %+v", c, expected)
+	}
+
+	if c, err := Diff(oldDir, oldDir); err != nil || !c.Empty() {
+		t.Errorf("Diff() of a package with itself = %+v, %v, want no changes", c, err)
+	}
+
+	if _, err := Diff(filepath.Join(oldDir, DefaultFile), oldDir); !errors.Is(err, ErrBinaryPackage) {
+		t.Errorf("Diff() error = %v, want %v", err, ErrBinaryPackage)
+	}
+}
@@ -0,0 +1,76 @@
+package trid
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid/defs"
+)
+
+// defsAgeInterval is how often scans check the age of the definitions, with
+// Options.MaxDefsAge.
+const defsAgeInterval = time.Hour
+
+// defsAgeCheck records when scans last checked the age of the definitions.
+type defsAgeCheck struct {
+	mu      sync.Mutex
+	checked time.Time
+}
+
+// DefsAge returns the age of the definitions package, or of the directory of
+// XML definitions of the native engine, from its modification time.
+// defs.Update sets the modification time of packages downloaded in a zip
+// archive to their build date; other packages are as old as their copy.
+func (t *Trid) DefsAge() (time.Duration, error) {
+	_, age, err := t.definitionsAge()
+	return age, err
+}
+
+// definitionsAge returns the path and age of the definitions.
+func (t *Trid) definitionsAge() (string, time.Duration, error) {
+	path, err := defs.Locate(t.options.Cmd, t.options.Definitions)
+	if err != nil {
+		return "", 0, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", 0, ErrNoDefinitions
+		}
+
+		return "", 0, err
+	}
+
+	return path, time.Since(fi.ModTime()), nil
+}
+
+// checkDefsAge warns, by logging and Hooks.OnStaleDefinitions, if the
+// definitions are older than Options.MaxDefsAge. The age is checked at most
+// once per defsAgeInterval; missing definitions are left for the scan to
+// report.
+func (t *Trid) checkDefsAge(ctx context.Context) {
+	if t.options.MaxDefsAge <= 0 {
+		return
+	}
+
+	c := &t.defsAge
+	c.mu.Lock()
+	if !c.checked.IsZero() && time.Since(c.checked) < defsAgeInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.checked = time.Now()
+	c.mu.Unlock()
+
+	path, age, err := t.definitionsAge()
+	if err != nil || age <= t.options.MaxDefsAge {
+		return
+	}
+
+	t.log(ctx, slog.LevelWarn, "definitions are stale", "path", path, "age", age.Round(time.Hour), "max_age", t.options.MaxDefsAge)
+	t.options.Hooks.staleDefinitions(path, age)
+}
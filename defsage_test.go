@@ -0,0 +1,72 @@
+package trid
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefsAge(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+	path := filepath.Join(filepath.Dir(cmd), "triddefs.trd")
+
+	built := time.Now().Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(path, time.Time{}, built); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		stale []string
+		log   bytes.Buffer
+	)
+
+	trid := NewTrid(Options{
+		Cmd:        cmd,
+		MaxDefsAge: 30 * 24 * time.Hour,
+		Logger:     slog.New(slog.NewTextHandler(&log, nil)),
+		Hooks: Hooks{OnStaleDefinitions: func(path string, age time.Duration) {
+			stale = append(stale, filepath.Base(path))
+		}},
+	})
+
+	age, err := trid.DefsAge()
+	if err != nil {
+		t.Fatalf("DefsAge() error = %v", err)
+	}
+
+	if age < 59*24*time.Hour || age > 61*24*time.Hour {
+		t.Errorf("DefsAge() = %v, want about 60 days", age)
+	}
+
+	// The age is checked once per hour
+	for range 2 {
+		if _, err := trid.Scan("testdata/sample.pdf", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(stale) != 1 || stale[0] != "triddefs.trd" {
+		t.Errorf("OnStaleDefinitions called with %q, want triddefs.trd once", stale)
+	}
+
+	if !strings.Contains(log.String(), "definitions are stale") {
+		t.Errorf("log does not warn of stale definitions: %s", log.String())
+	}
+
+	fresh := NewTrid(Options{Cmd: cmd, MaxDefsAge: 90 * 24 * time.Hour, Hooks: Hooks{
+		OnStaleDefinitions: func(string, time.Duration) { t.Error("OnStaleDefinitions called for fresh definitions") },
+	}})
+	if _, err := fresh.Scan("testdata/sample.pdf", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := NewTrid(Options{Cmd: cmd, Definitions: filepath.Join(t.TempDir(), "missing.trd")})
+	if _, err := missing.DefsAge(); !errors.Is(err, ErrNoDefinitions) {
+		t.Errorf("DefsAge() error = %v, want %v", err, ErrNoDefinitions)
+	}
+}
@@ -0,0 +1,81 @@
+package trid
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// CmdEnv is the environment variable FindBinary checks first for the TrID
+// executable.
+const CmdEnv = "TRID_CMD"
+
+// binaryNames lists the names of the TrID executable, preferred first.
+var binaryNames = []string{"trid64", "trid"}
+
+// FindBinary locates the TrID executable. It checks, in order, the TRID_CMD
+// environment variable, the directories in PATH and the usual install
+// locations of the current operating system, preferring trid64 over trid in
+// each place. If none is found, the error matches ErrBinaryNotFound and lists
+// the locations tried.
+func FindBinary() (string, error) {
+	return findBinary(os.Getenv(CmdEnv), installDirs(runtime.GOOS, os.Getenv))
+}
+
+// findBinary implements FindBinary for the given TRID_CMD value and install
+// directories.
+func findBinary(envCmd string, dirs []string) (string, error) {
+	var tried []string
+
+	if envCmd != "" {
+		if path, err := exec.LookPath(envCmd); err == nil {
+			return path, nil
+		}
+
+		tried = append(tried, CmdEnv+"="+envCmd)
+	}
+
+	for _, name := range binaryNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	tried = append(tried, "PATH")
+
+	for _, dir := range dirs {
+		for _, name := range binaryNames {
+			if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+				return path, nil
+			}
+		}
+
+		tried = append(tried, dir)
+	}
+
+	return "", fmt.Errorf("%w (tried %s)", ErrBinaryNotFound, strings.Join(tried, ", "))
+}
+
+// installDirs returns the directories TrID is commonly installed in on goos.
+func installDirs(goos string, getenv func(string) string) []string {
+	if goos == "windows" {
+		var dirs []string
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "LOCALAPPDATA"} {
+			if base := getenv(env); base != "" {
+				dirs = append(dirs, filepath.Join(base, "TrID"))
+			}
+		}
+
+		return dirs
+	}
+
+	dirs := []string{"/usr/local/bin", "/usr/bin", "/opt/trid", "/usr/local/trid"}
+	if home := getenv("HOME"); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".local", "bin"), filepath.Join(home, "trid"))
+	}
+
+	return dirs
+}
@@ -0,0 +1,93 @@
+package trid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeExecutable creates an executable file named name in dir.
+func writeExecutable(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestFindBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executables require a POSIX shell")
+	}
+
+	pathDir, installDir, otherDir := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("PATH", pathDir)
+
+	if _, err := findBinary("", []string{installDir}); !errors.Is(err, ErrBinaryNotFound) {
+		t.Fatalf("findBinary() error = %v, want ErrBinaryNotFound", err)
+	} else if msg := err.Error(); !strings.Contains(msg, "PATH") || !strings.Contains(msg, installDir) {
+		t.Errorf("findBinary() error = %q, want the locations tried", msg)
+	}
+
+	installed := writeExecutable(t, installDir, "trid")
+	if got, err := findBinary("", []string{otherDir, installDir}); err != nil || got != installed {
+		t.Errorf("findBinary() = %q, %v, want %q", got, err, installed)
+	}
+
+	writeExecutable(t, pathDir, "trid")
+	preferred := writeExecutable(t, pathDir, "trid64")
+	if got, err := findBinary("", []string{installDir}); err != nil || got != preferred {
+		t.Errorf("findBinary() = %q, %v, want %q", got, err, preferred)
+	}
+
+	env := writeExecutable(t, otherDir, "custom-trid")
+	if got, err := findBinary(env, []string{installDir}); err != nil || got != env {
+		t.Errorf("findBinary(%q) = %q, %v, want %q", env, got, err, env)
+	}
+
+	// An unusable TRID_CMD falls back to the other locations
+	if got, err := findBinary(filepath.Join(otherDir, "missing"), nil); err != nil || got != preferred {
+		t.Errorf("findBinary() = %q, %v, want %q", got, err, preferred)
+	}
+}
+
+func TestInstallDirs(t *testing.T) {
+	env := map[string]string{"ProgramFiles": `C:\Program Files`, "HOME": "/home/user"}
+	getenv := func(key string) string { return env[key] }
+
+	if dirs := installDirs("windows", getenv); len(dirs) != 1 || dirs[0] != filepath.Join(`C:\Program Files`, "TrID") {
+		t.Errorf("installDirs(windows) = %q", dirs)
+	}
+
+	dirs := installDirs("linux", getenv)
+	for _, want := range []string{"/usr/local/bin", "/opt/trid"} {
+		if !strings.Contains(strings.Join(dirs, "\n"), want) {
+			t.Errorf("installDirs(linux) = %q, want %q included", dirs, want)
+		}
+	}
+}
+
+func TestNewTridDiscoveryError(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv(CmdEnv, "")
+
+	tr := NewTrid(Options{})
+	if tr.optsErr == nil {
+		t.Skip("TrID is installed in a well-known location")
+	}
+
+	file := filepath.Join(t.TempDir(), "sample.pdf")
+	if err := os.WriteFile(file, []byte(probeContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.Scan(file, 1); !errors.Is(err, ErrBinaryNotFound) {
+		t.Errorf("Scan() error = %v, want ErrBinaryNotFound", err)
+	}
+}
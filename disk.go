@@ -0,0 +1,321 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// Partitioning schemes reported in DiskReport.Scheme.
+const (
+	SchemeMBR = "mbr"
+	SchemeGPT = "gpt"
+)
+
+// Defaults of DiskOptions.
+const (
+	DefaultSectorSize    = 512
+	DefaultDiskChunkSize = 1 << 20
+)
+
+// maxLogicalPartitions bounds the chain of extended boot records followed,
+// which may loop in corrupt images.
+const maxLogicalPartitions = 128
+
+// DiskOptions configures ScanDisk.
+type DiskOptions struct {
+	SectorSize int64 // Size of the sectors partition tables refer to (default: DefaultSectorSize).
+	ChunkSize  int64 // Size of the chunks volumes are identified by (default: DefaultDiskChunkSize).
+}
+
+// DiskChunk is the identification result of a chunk of a volume.
+type DiskChunk struct {
+	Offset    int64      // Offset of the chunk in the image.
+	Size      int64      // Size of the chunk in bytes.
+	FileTypes []FileType // Identified file types.
+	Err       error      // Error if the chunk could not be identified, other than ErrUnknownFileType.
+}
+
+// Volume is a partition of a disk image, or the whole image if it has no
+// partition table.
+type Volume struct {
+	Index  int         // Number of the partition, from 1, or 0 for the whole image.
+	Offset int64       // Offset of the volume in the image.
+	Size   int64       // Size of the volume in bytes.
+	Type   string      // Partition type: the type byte of MBR partitions, such as "0x83", or the type GUID of GPT ones.
+	Name   string      // Name of GPT partitions.
+	Chunks []DiskChunk // Chunks identified, in order; chunks holding only zeros or of an unknown type are left out.
+}
+
+// DiskReport is the result of ScanDisk.
+type DiskReport struct {
+	Path    string   // Path of the image or device.
+	Size    int64    // Size of the image or device in bytes.
+	Scheme  string   // Partitioning scheme, SchemeMBR or SchemeGPT; empty if the image has no partition table.
+	Volumes []Volume // Partitions, in table order, or the whole image.
+}
+
+// ScanDisk identifies the content of the raw disk image or block device at
+// filePath, such as /dev/sdb, volume by volume. MBR partition tables,
+// including logical partitions, and GPT partition tables are read to report
+// each partition separately; an image without one is a single volume.
+//
+// Each volume is split into chunks of opts.ChunkSize bytes, identified as by
+// ScanAt, so that file systems are identified by their first chunk and files
+// stored contiguously by the chunk they start in. Chunks holding only zeros
+// are skipped. Reading block devices usually requires elevated privileges.
+//
+// The returned error is reserved for invalid arguments, unreadable images and
+// failures affecting every file, as for ScanArchive.
+func (t *Trid) ScanDisk(ctx context.Context, filePath string, numberOfMatches int, opts DiskOptions) (*DiskReport, error) {
+	if filePath == "" {
+		return nil, ErrNoFileSpecified
+	}
+
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	if opts.SectorSize <= 0 {
+		opts.SectorSize = DefaultSectorSize
+	}
+
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultDiskChunkSize
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := fileSize(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &DiskReport{Path: filePath, Size: size}
+	if r.Scheme, r.Volumes = partitions(f, size, opts.SectorSize); len(r.Volumes) == 0 {
+		r.Volumes = []Volume{{Size: size}}
+	}
+
+	buf := make([]byte, opts.ChunkSize)
+	for i := range r.Volumes {
+		v := &r.Volumes[i]
+		for off := v.Offset; off < v.Offset+v.Size; off += opts.ChunkSize {
+			n := min(opts.ChunkSize, v.Offset+v.Size-off)
+			if read, err := f.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+				return nil, err
+			} else if isZero(buf[:read]) {
+				continue
+			}
+
+			c := DiskChunk{Offset: off, Size: n}
+			c.FileTypes, c.Err = t.ScanAt(ctx, filePath, off, n, numberOfMatches)
+
+			switch {
+			case isFatalScanError(ctx, c.Err):
+				return nil, c.Err
+			case errors.Is(c.Err, ErrUnknownFileType):
+				continue
+			}
+
+			v.Chunks = append(v.Chunks, c)
+		}
+	}
+
+	return r, nil
+}
+
+// fileSize returns the size of f, which may be a block device, whose size is
+// not reported by Stat.
+func fileSize(f *os.File) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if fi.Mode().IsRegular() {
+		return fi.Size(), nil
+	}
+
+	return f.Seek(0, io.SeekEnd)
+}
+
+// isZero reports whether b holds only zeros.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// partitions returns the partitioning scheme of the disk image f, of the
+// given size, and its partitions, if it has a partition table.
+func partitions(f io.ReaderAt, size, sectorSize int64) (string, []Volume) {
+	mbr := make([]byte, 512)
+	if _, err := f.ReadAt(mbr, 0); err != nil || mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return "", nil
+	}
+
+	entries := mbrEntries(mbr)
+	if len(entries) > 0 && entries[0].Type == "0xee" {
+		if volumes, err := gptPartitions(f, size, sectorSize); err == nil {
+			return SchemeGPT, volumes
+		}
+	}
+
+	var volumes []Volume
+	for _, e := range entries {
+		switch e.Type {
+		case "0x05", "0x0f", "0x85":
+			volumes = append(volumes, logicalPartitions(f, e.Offset, sectorSize)...)
+		default:
+			volumes = append(volumes, Volume{Offset: e.Offset * sectorSize, Size: e.Size * sectorSize, Type: e.Type})
+		}
+	}
+
+	// Tables pointing outside the image are not partition tables, such as
+	// boot sectors of unpartitioned media
+	for _, v := range volumes {
+		if v.Offset+v.Size > size || v.Size == 0 {
+			return "", nil
+		}
+	}
+
+	for i := range volumes {
+		volumes[i].Index = i + 1
+	}
+
+	return SchemeMBR, volumes
+}
+
+// mbrEntries returns the used entries of the partition table of the sector
+// mbr, with offsets and sizes in sectors.
+func mbrEntries(mbr []byte) []Volume {
+	var entries []Volume
+	for i := range 4 {
+		e := mbr[446+16*i : 446+16*(i+1)]
+		if e[4] == 0 {
+			continue
+		}
+
+		entries = append(entries, Volume{
+			Offset: int64(binary.LittleEndian.Uint32(e[8:])),
+			Size:   int64(binary.LittleEndian.Uint32(e[12:])),
+			Type:   fmt.Sprintf("0x%02x", e[4]),
+		})
+	}
+
+	return entries
+}
+
+// logicalPartitions returns the logical partitions of the extended partition
+// starting at sector start, following its chain of extended boot records.
+func logicalPartitions(f io.ReaderAt, start, sectorSize int64) []Volume {
+	var volumes []Volume
+
+	ebr := make([]byte, 512)
+	for next := start; len(volumes) < maxLogicalPartitions; {
+		if _, err := f.ReadAt(ebr, next*sectorSize); err != nil || ebr[510] != 0x55 || ebr[511] != 0xaa {
+			break
+		}
+
+		entries := mbrEntries(ebr)
+		if len(entries) == 0 {
+			break
+		}
+
+		// The logical partition is relative to its record, the next record
+		// to the extended partition
+		e := entries[0]
+		volumes = append(volumes, Volume{Offset: (next + e.Offset) * sectorSize, Size: e.Size * sectorSize, Type: e.Type})
+
+		if len(entries) < 2 || entries[1].Offset == 0 {
+			break
+		}
+		next = start + entries[1].Offset
+	}
+
+	return volumes
+}
+
+// gptPartitions returns the partitions of the GPT of the disk image f.
+func gptPartitions(f io.ReaderAt, size, sectorSize int64) ([]Volume, error) {
+	hdr := make([]byte, 92)
+	if _, err := f.ReadAt(hdr, sectorSize); err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(hdr, []byte("EFI PART")) {
+		return nil, errors.New("no GPT header")
+	}
+
+	entriesLBA := int64(binary.LittleEndian.Uint64(hdr[72:]))
+	count := int64(binary.LittleEndian.Uint32(hdr[80:]))
+	entrySize := int64(binary.LittleEndian.Uint32(hdr[84:]))
+	if entrySize < 128 || count > 1024 || entriesLBA*sectorSize+count*entrySize > size {
+		return nil, errors.New("invalid GPT header")
+	}
+
+	table := make([]byte, count*entrySize)
+	if _, err := f.ReadAt(table, entriesLBA*sectorSize); err != nil {
+		return nil, err
+	}
+
+	var volumes []Volume
+	for i := range count {
+		e := table[i*entrySize : (i+1)*entrySize]
+		if isZero(e[:16]) {
+			continue
+		}
+
+		first := int64(binary.LittleEndian.Uint64(e[32:]))
+		last := int64(binary.LittleEndian.Uint64(e[40:]))
+		if last < first || (last+1)*sectorSize > size {
+			return nil, errors.New("invalid GPT partition")
+		}
+
+		volumes = append(volumes, Volume{
+			Index:  int(i) + 1,
+			Offset: first * sectorSize,
+			Size:   (last - first + 1) * sectorSize,
+			Type:   guidString(e[:16]),
+			Name:   utf16String(e[56:128]),
+		})
+	}
+
+	return volumes, nil
+}
+
+// guidString formats a GUID stored in mixed-endian form.
+func guidString(b []byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(b[0:]), binary.LittleEndian.Uint16(b[4:]), binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// utf16String decodes a NUL-terminated UTF-16LE string.
+func utf16String(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	s, _, _ := strings.Cut(string(utf16.Decode(u)), "\x00")
+
+	return s
+}
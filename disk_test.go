@@ -0,0 +1,133 @@
+package trid
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// partitionEntry writes an MBR partition entry to slot i of the sector b.
+func partitionEntry(b []byte, i int, typ byte, start, sectors uint32) {
+	e := b[446+16*i:]
+	e[4] = typ
+	binary.LittleEndian.PutUint32(e[8:], start)
+	binary.LittleEndian.PutUint32(e[12:], sectors)
+	b[510], b[511] = 0x55, 0xaa
+}
+
+// writeImage writes a disk image of the given number of sectors, prepared by
+// fill, and returns its path.
+func writeImage(t *testing.T, sectors int, fill func(img []byte)) string {
+	t.Helper()
+
+	img := make([]byte, sectors*512)
+	fill(img)
+
+	path := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(path, img, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// chunkExts returns the offsets of the chunks of v with the extension of
+// their most likely match.
+func chunkExts(v Volume) map[int64]string {
+	exts := make(map[int64]string)
+	for _, c := range v.Chunks {
+		if len(c.FileTypes) > 0 {
+			exts[c.Offset] = c.FileTypes[0].Extension
+		}
+	}
+
+	return exts
+}
+
+func TestScanDisk(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	sevenZip := readFile(t, "testdata/sample.7z")
+
+	mbr := writeImage(t, 64, func(img []byte) {
+		partitionEntry(img, 0, 0x83, 8, 16)
+		partitionEntry(img, 1, 0x05, 24, 40)
+		copy(img[8*512:], pdf)
+
+		// Extended boot record with a single logical partition
+		partitionEntry(img[24*512:], 0, 0x07, 1, 16)
+		copy(img[25*512:], sevenZip)
+	})
+
+	gpt := writeImage(t, 64, func(img []byte) {
+		partitionEntry(img, 0, 0xee, 1, 63)
+		copy(img[512:], "EFI PART")
+		binary.LittleEndian.PutUint64(img[512+72:], 2)
+		binary.LittleEndian.PutUint32(img[512+80:], 4)
+		binary.LittleEndian.PutUint32(img[512+84:], 128)
+
+		e := img[2*512:]
+		copy(e, []byte{0xaf, 0x3d, 0xc6, 0x0f, 0x83, 0x84, 0x72, 0x47, 0x8e, 0x79, 0x3d, 0x69, 0xd8, 0x47, 0x7d, 0xe4})
+		binary.LittleEndian.PutUint64(e[32:], 16)
+		binary.LittleEndian.PutUint64(e[40:], 47)
+		for i, c := range utf16.Encode([]rune("data")) {
+			binary.LittleEndian.PutUint16(e[56+2*i:], c)
+		}
+		copy(img[16*512:], pdf)
+	})
+
+	raw := writeImage(t, 32, func(img []byte) { copy(img[8192:], pdf) })
+
+	tests := []struct {
+		name    string
+		path    string
+		scheme  string
+		volumes []Volume
+		exts    []map[int64]string
+	}{
+		{"MBR", mbr, SchemeMBR, []Volume{
+			{Index: 1, Offset: 8 * 512, Size: 16 * 512, Type: "0x83"},
+			{Index: 2, Offset: 25 * 512, Size: 16 * 512, Type: "0x07"},
+		}, []map[int64]string{{8 * 512: ".pdf"}, {25 * 512: ".7z"}}},
+		{"GPT", gpt, SchemeGPT, []Volume{
+			{Index: 1, Offset: 16 * 512, Size: 32 * 512, Type: "0FC63DAF-8483-4772-8E79-3D69D8477DE4", Name: "data"},
+		}, []map[int64]string{{16 * 512: ".pdf"}}},
+		{"Raw", raw, "", []Volume{
+			{Size: 32 * 512},
+		}, []map[int64]string{{8192: ".pdf"}}},
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tr.ScanDisk(context.Background(), tt.path, 1, DiskOptions{ChunkSize: 4096})
+			if err != nil {
+				t.Fatalf("ScanDisk() error = %v", err)
+			}
+
+			if r.Scheme != tt.scheme || len(r.Volumes) != len(tt.volumes) {
+				t.Fatalf("ScanDisk() = %s with %+v, want %s with %d volumes", r.Scheme, r.Volumes, tt.scheme, len(tt.volumes))
+			}
+
+			for i, v := range r.Volumes {
+				want := tt.volumes[i]
+				if v.Index != want.Index || v.Offset != want.Offset || v.Size != want.Size || v.Type != want.Type || v.Name != want.Name {
+					t.Errorf("volume %d = %+v, want %+v", i, v, want)
+				}
+
+				if exts := chunkExts(v); !maps.Equal(exts, tt.exts[i]) {
+					t.Errorf("volume %d chunks = %v, want %v", i, exts, tt.exts[i])
+				}
+			}
+		})
+	}
+
+	if _, err := tr.ScanDisk(context.Background(), "missing.img", 1, DiskOptions{}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("ScanDisk() error = %v, want %v", err, ErrFileNotFound)
+	}
+}
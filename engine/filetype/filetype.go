@@ -0,0 +1,72 @@
+// Package filetype identifies files with the pure-Go h2non/filetype and
+// gabriel-vasile/mimetype libraries, as an alternative to TrID where it cannot
+// be installed. Files are identified from their first bytes.
+package filetype
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	h2non "github.com/h2non/filetype"
+
+	"github.com/attilabuti/trid"
+)
+
+// Source is the FileType.Source of the matches of this package.
+const Source = "filetype"
+
+// headSize is the number of bytes read from each file, enough for both
+// libraries.
+const headSize = 3072
+
+// New returns a Scanner identifying files with Identify.
+func New() trid.Scanner {
+	return trid.ScannerFunc(Identify)
+}
+
+// Identify identifies the file at path with both libraries. Libraries do not
+// report probabilities: the match of each library has a probability of 50%,
+// and a match both agree on 100%. Their names are the MIME types, as the
+// libraries do not describe file types.
+func Identify(ctx context.Context, path string) ([]trid.FileType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	var fileTypes []trid.FileType
+	add := func(mimeType, ext string) {
+		for i := range fileTypes {
+			if fileTypes[i].MimeType == mimeType {
+				fileTypes[i].Probability += 50
+				return
+			}
+		}
+
+		fileTypes = append(fileTypes, trid.FileType{Extension: ext, Probability: 50, Name: mimeType, MimeType: mimeType, Source: Source})
+	}
+
+	if kind, err := h2non.Match(head); err == nil && kind != h2non.Unknown {
+		add(kind.MIME.Value, "."+kind.Extension)
+	}
+
+	// mimetype reports data it cannot identify as application/octet-stream
+	if m := mimetype.Detect(head); m.String() != "application/octet-stream" {
+		mimeType, _, _ := strings.Cut(m.String(), ";")
+		add(mimeType, m.Extension())
+	}
+
+	return fileTypes, nil
+}
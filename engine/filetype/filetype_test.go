@@ -0,0 +1,50 @@
+package filetype
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestScan(t *testing.T) {
+	opaque := filepath.Join(t.TempDir(), "opaque.bin")
+	if err := os.WriteFile(opaque, []byte{0x00, 0x01, 0x02, 0xff}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedMime string
+		expectedExt  string
+		expectedErr  error
+	}{
+		{"PDF", "../../testdata/sample.pdf", "application/pdf", ".pdf", nil},
+		{"7z", "../../testdata/sample.7z", "application/x-7z-compressed", ".7z", nil},
+		{"Unknown", opaque, "", "", trid.ErrUnknownFileType},
+		{"Missing", "missing.pdf", "", "", trid.ErrFileNotFound},
+	}
+
+	scanner := New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileTypes, err := scanner.Scan(tt.path, 5)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr != nil {
+				return
+			}
+
+			best := fileTypes[0]
+			if best.MimeType != tt.expectedMime || best.Extension != tt.expectedExt || best.Probability != 100 || best.Source != Source {
+				t.Errorf("Scan() = %+v, want %s (%s) from both libraries", fileTypes, tt.expectedMime, tt.expectedExt)
+			}
+		})
+	}
+}
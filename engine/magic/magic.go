@@ -0,0 +1,20 @@
+// Package magic identifies files with libmagic, the library behind the file
+// command, as a drop-in alternative to TrID on systems without it.
+//
+// The package uses cgo and must be built with the libmagic tag, with the
+// libmagic development files installed (libmagic-dev on Debian, file-devel on
+// Fedora, libmagic from Homebrew):
+//
+//	go build -tags libmagic
+//
+// Without the tag, New fails with ErrUnsupported.
+package magic
+
+import "errors"
+
+// Source is the FileType.Source of the matches of this package.
+const Source = "libmagic"
+
+// ErrUnsupported is returned by New when the package was built without
+// libmagic support.
+var ErrUnsupported = errors.New("libmagic support not built in (build with -tags libmagic)")
@@ -0,0 +1,119 @@
+//go:build cgo && libmagic
+
+package magic
+
+/*
+#cgo LDFLAGS: -lmagic
+#include <stdlib.h>
+#include <magic.h>
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/attilabuti/trid"
+)
+
+// Scanner is a trid.Scanner backed by libmagic. It is safe for concurrent
+// use, although files are identified one at a time.
+type Scanner struct {
+	trid.ScannerFunc
+
+	mu     sync.Mutex
+	cookie C.magic_t
+}
+
+// New returns a Scanner using the default magic database. It must be closed
+// when no longer needed.
+func New() (*Scanner, error) {
+	cookie := C.magic_open(C.MAGIC_NONE)
+	if cookie == nil {
+		return nil, errors.New("magic_open failed")
+	}
+
+	if C.magic_load(cookie, nil) != 0 {
+		err := magicError(cookie)
+		C.magic_close(cookie)
+		return nil, err
+	}
+
+	s := &Scanner{cookie: cookie}
+	s.ScannerFunc = s.identify
+
+	return s, nil
+}
+
+// Close releases the magic database.
+func (s *Scanner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cookie != nil {
+		C.magic_close(s.cookie)
+		s.cookie = nil
+	}
+
+	return nil
+}
+
+// identify returns the single match libmagic reports for the file at path,
+// with a probability of 100%, or none if libmagic only recognizes data.
+func (s *Scanner) identify(ctx context.Context, path string) ([]trid.FileType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cookie == nil {
+		return nil, errors.New("magic: scanner is closed")
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	describe := func(flags C.int) (string, error) {
+		C.magic_setflags(s.cookie, flags)
+
+		desc := C.magic_file(s.cookie, cpath)
+		if desc == nil {
+			return "", magicError(s.cookie)
+		}
+
+		return C.GoString(desc), nil
+	}
+
+	mimeType, err := describe(C.MAGIC_MIME_TYPE)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := describe(C.MAGIC_NONE)
+	if err != nil {
+		return nil, err
+	}
+
+	if mimeType == "application/octet-stream" && name == "data" {
+		return nil, nil
+	}
+
+	// Extensions are reported as "jpeg/jpg/jpe/jfif", or "???" if unknown
+	var ext string
+	if exts, err := describe(C.MAGIC_EXTENSION); err == nil && exts != "???" {
+		first, _, _ := strings.Cut(exts, "/")
+		ext = "." + first
+	}
+
+	return []trid.FileType{{Extension: ext, Probability: 100, Name: name, MimeType: mimeType, Source: Source}}, nil
+}
+
+// magicError returns the last error of cookie.
+func magicError(cookie C.magic_t) error {
+	if msg := C.magic_error(cookie); msg != nil {
+		return errors.New("magic: " + C.GoString(msg))
+	}
+
+	return errors.New("magic: unknown error")
+}
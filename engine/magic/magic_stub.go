@@ -0,0 +1,30 @@
+//go:build !(cgo && libmagic)
+
+package magic
+
+import (
+	"context"
+
+	"github.com/attilabuti/trid"
+)
+
+// Scanner is a trid.Scanner backed by libmagic.
+type Scanner struct {
+	trid.ScannerFunc
+}
+
+// New fails with ErrUnsupported, as the package was built without libmagic
+// support.
+func New() (*Scanner, error) {
+	return nil, ErrUnsupported
+}
+
+// Close releases the magic database.
+func (s *Scanner) Close() error {
+	return nil
+}
+
+// identify is not available without libmagic support.
+func (s *Scanner) identify(ctx context.Context, path string) ([]trid.FileType, error) {
+	return nil, ErrUnsupported
+}
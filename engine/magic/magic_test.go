@@ -0,0 +1,96 @@
+//go:build cgo && libmagic
+
+package magic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestScan(t *testing.T) {
+	opaque := filepath.Join(t.TempDir(), "opaque.bin")
+	if err := os.WriteFile(opaque, []byte{0x00, 0x01, 0x02, 0xff, 0x7f, 0x80}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedMime string
+		expectedErr  error
+	}{
+		{"PDF", "../../testdata/sample.pdf", "application/pdf", nil},
+		{"7z", "../../testdata/sample.7z", "application/x-7z-compressed", nil},
+		{"Unknown", opaque, "", trid.ErrUnknownFileType},
+		{"Missing", "missing.pdf", "", trid.ErrFileNotFound},
+	}
+
+	scanner, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer scanner.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileTypes, err := scanner.Scan(tt.path, 5)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr != nil {
+				return
+			}
+
+			if len(fileTypes) != 1 {
+				t.Fatalf("Scan() = %+v, want a single match", fileTypes)
+			}
+
+			best := fileTypes[0]
+			if best.MimeType != tt.expectedMime || best.Name == "" || best.Probability != 100 || best.Source != Source {
+				t.Errorf("Scan() = %+v, want %s", best, tt.expectedMime)
+			}
+		})
+	}
+}
+
+func TestConcurrentScan(t *testing.T) {
+	scanner, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer scanner.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := scanner.Scan("../../testdata/sample.pdf", 1); err != nil {
+				t.Errorf("Scan() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClosed(t *testing.T) {
+	scanner, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := scanner.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := scanner.Scan("../../testdata/sample.pdf", 1); err == nil {
+		t.Error("Scan() after Close() succeeded")
+	}
+}
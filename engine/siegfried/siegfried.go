@@ -0,0 +1,131 @@
+// Package siegfried identifies files with siegfried (sf), matching them
+// against the PRONOM registry of file formats, to compare or combine TrID and
+// PRONOM identification through the trid.Scanner interface.
+//
+// siegfried must be installed, with its signature file; see
+// https://github.com/richardlehane/siegfried.
+package siegfried
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/attilabuti/trid"
+)
+
+// Source is the FileType.Source of the matches of this package.
+const Source = "siegfried"
+
+// pronomURL is the address of the PRONOM registry entries, followed by their
+// identifier.
+const pronomURL = "https://www.nationalarchives.gov.uk/PRONOM/"
+
+// reExtensionMatch extracts the extension from the basis of a match.
+var reExtensionMatch = regexp.MustCompile(`extension match ([^;\s]+)`)
+
+// Options are the options of the scanner returned by New.
+type Options struct {
+	Cmd       string      // Command to run siegfried (default: "sf").
+	Signature string      // Signature file passed with -sig (default: siegfried's default).
+	Runner    trid.Runner // Runs siegfried (default: trid.ExecRunner).
+}
+
+// output is the JSON output of sf -json.
+type output struct {
+	Files []struct {
+		Filename string `json:"filename"`
+		Errors   string `json:"errors"`
+		Matches  []struct {
+			Namespace string `json:"ns"`
+			ID        string `json:"id"`
+			Format    string `json:"format"`
+			Version   string `json:"version"`
+			MimeType  string `json:"mime"`
+			Basis     string `json:"basis"`
+			Warning   string `json:"warning"`
+		} `json:"matches"`
+	} `json:"files"`
+}
+
+// New returns a Scanner running siegfried on each file. Matches are named
+// after their PRONOM format, with the PRONOM identifier (e.g., "fmt/276") as
+// Definition, the basis of the match as Remarks and a link to the PRONOM
+// registry as RelatedURL. siegfried does not report probabilities: each
+// identifier configured in the signature file reports a single match, and
+// their probabilities are split evenly.
+func New(opts Options) trid.Scanner {
+	if opts.Cmd == "" {
+		opts.Cmd = "sf"
+	}
+
+	if opts.Runner == nil {
+		opts.Runner = trid.ExecRunner{}
+	}
+
+	return trid.ScannerFunc(func(ctx context.Context, path string) ([]trid.FileType, error) {
+		args := []string{"-json"}
+		if opts.Signature != "" {
+			args = append(args, "-sig", opts.Signature)
+		}
+
+		res, err := opts.Runner.Run(ctx, trid.Command{Name: opts.Cmd, Args: append(args, path)})
+		if err != nil {
+			if stderr := strings.TrimSpace(res.Stderr); stderr != "" {
+				return nil, fmt.Errorf("siegfried: %w: %s", err, stderr)
+			}
+
+			return nil, fmt.Errorf("siegfried: %w", err)
+		}
+
+		return parseOutput(res.Stdout)
+	})
+}
+
+// parseOutput converts the JSON output of siegfried for a single file.
+func parseOutput(out string) ([]trid.FileType, error) {
+	var o output
+	if err := json.Unmarshal([]byte(out), &o); err != nil {
+		return nil, fmt.Errorf("siegfried: invalid output: %w", err)
+	}
+
+	if len(o.Files) != 1 {
+		return nil, fmt.Errorf("siegfried: output describes %d files, want 1", len(o.Files))
+	}
+
+	file := o.Files[0]
+	if file.Errors != "" {
+		return nil, errors.New("siegfried: " + file.Errors)
+	}
+
+	var fileTypes []trid.FileType
+	for _, m := range file.Matches {
+		if m.ID == "" || m.ID == "UNKNOWN" {
+			continue
+		}
+
+		ft := trid.FileType{Name: m.Format, MimeType: m.MimeType, Remarks: m.Basis, Definition: m.ID, Source: Source}
+		if m.Version != "" && !strings.Contains(m.Format, m.Version) {
+			ft.Name += " " + m.Version
+		}
+
+		if ext := reExtensionMatch.FindStringSubmatch(m.Basis); ext != nil {
+			ft.Extension = "." + strings.ToLower(ext[1])
+		}
+
+		if m.Namespace == "pronom" {
+			ft.RelatedURL = pronomURL + m.ID
+		}
+
+		fileTypes = append(fileTypes, ft)
+	}
+
+	for i := range fileTypes {
+		fileTypes[i].Probability = 100 / float64(len(fileTypes))
+	}
+
+	return fileTypes, nil
+}
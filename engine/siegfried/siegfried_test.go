@@ -0,0 +1,111 @@
+package siegfried
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+const pdfOutput = `{"siegfried":"1.11.0","scandate":"2024-05-01T10:00:00Z","signature":"default.sig",
+"identifiers":[{"name":"pronom","details":"DROID_SignatureFile_V116.xml"}],
+"files":[{"filename":"sample.pdf","filesize":1024,"modified":"2024-05-01T10:00:00Z","errors":"",
+"matches":[{"ns":"pronom","id":"fmt/276","format":"Acrobat PDF 1.7 - Portable Document Format","version":"1.7",
+"mime":"application/pdf","class":"Page Description","basis":"extension match pdf; byte match at [[0 8] [1016 5]]","warning":""}]}]}`
+
+const unknownOutput = `{"siegfried":"1.11.0","files":[{"filename":"opaque.bin","errors":"",
+"matches":[{"ns":"pronom","id":"UNKNOWN","format":"","version":"","mime":"","basis":"","warning":"no match"}]}]}`
+
+func TestScan(t *testing.T) {
+	var args []string
+	runner := trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		args = append([]string{c.Name}, c.Args...)
+		return trid.CommandResult{Stdout: pdfOutput}, nil
+	})
+
+	fileTypes, err := New(Options{Signature: "deluxe.sig", Runner: runner}).Scan("../../testdata/sample.pdf", 5)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if expected := []string{"sf", "-json", "-sig", "deluxe.sig", "../../testdata/sample.pdf"}; !slices.Equal(args, expected) {
+		t.Errorf("Scan() ran %q, want %q", args, expected)
+	}
+
+	expected := trid.FileType{
+		Extension:   ".pdf",
+		Probability: 100,
+		Name:        "Acrobat PDF 1.7 - Portable Document Format",
+		MimeType:    "application/pdf",
+		RelatedURL:  "https://www.nationalarchives.gov.uk/PRONOM/fmt/276",
+		Remarks:     "extension match pdf; byte match at [[0 8] [1016 5]]",
+		Definition:  "fmt/276",
+		Source:      Source,
+	}
+	if len(fileTypes) != 1 || fileTypes[0] != expected {
+		t.Errorf("Scan() = %+v, want %+v", fileTypes, expected)
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name          string
+		out           string
+		expectedNames []string
+		expectedErr   bool
+	}{
+		{"Unknown", unknownOutput, nil, false},
+		{"Several identifiers", `{"files":[{"matches":[
+			{"ns":"pronom","id":"fmt/11","format":"Portable Network Graphics","version":"1.0","mime":"image/png"},
+			{"ns":"tika","id":"image/png","format":"Portable Network Graphics","mime":"image/png"}]}]}`,
+			[]string{"Portable Network Graphics 1.0", "Portable Network Graphics"}, false},
+		{"File error", `{"files":[{"errors":"open x: permission denied","matches":[]}]}`, nil, true},
+		{"Several files", `{"files":[{},{}]}`, nil, true},
+		{"Invalid JSON", `sf: no such file`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileTypes, err := parseOutput(tt.out)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("parseOutput() error = %v, want error %v", err, tt.expectedErr)
+			}
+
+			var names []string
+			for _, f := range fileTypes {
+				names = append(names, f.Name)
+
+				if f.Probability != 100/float64(len(fileTypes)) {
+					t.Errorf("parseOutput() probability = %v", f.Probability)
+				}
+			}
+
+			if !slices.Equal(names, tt.expectedNames) {
+				t.Errorf("parseOutput() names = %q, want %q", names, tt.expectedNames)
+			}
+		})
+	}
+}
+
+func TestScanUnknown(t *testing.T) {
+	runner := trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		return trid.CommandResult{Stdout: unknownOutput}, nil
+	})
+
+	if _, err := New(Options{Runner: runner}).Scan("../../testdata/sample.pdf", 1); !errors.Is(err, trid.ErrUnknownFileType) {
+		t.Errorf("Scan() error = %v, want %v", err, trid.ErrUnknownFileType)
+	}
+}
+
+func TestScanFailure(t *testing.T) {
+	runner := trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		return trid.CommandResult{Stderr: "sf: signature file not found\n", ExitCode: 1}, errors.New("exit status 1")
+	})
+
+	_, err := New(Options{Runner: runner}).Scan("../../testdata/sample.pdf", 1)
+	if err == nil || err.Error() != "siegfried: exit status 1: sf: signature file not found" {
+		t.Errorf("Scan() error = %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package tika identifies files with the /detect endpoint of an Apache Tika
+// server, for environments already running Tika to cross-check TrID results
+// against it.
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/attilabuti/trid"
+)
+
+// Source is the FileType.Source of the matches of this package.
+const Source = "tika"
+
+// DefaultURL is the address Tika servers listen on by default.
+const DefaultURL = "http://localhost:9998"
+
+// Options are the options of the scanner returned by New.
+type Options struct {
+	URL        string       // Base URL of the Tika server (default: DefaultURL).
+	HTTPClient *http.Client // HTTP client used to reach Tika (default: http.DefaultClient).
+}
+
+// New returns a Scanner uploading each file to the /detect/stream endpoint of
+// a Tika server. Tika reports a single MIME type, which is used as the name of
+// the match, with a probability of 100%. The extension is the first one
+// registered for the MIME type in the mime package, if any. Files Tika only
+// recognizes as application/octet-stream have no match.
+func New(opts Options) trid.Scanner {
+	if opts.URL == "" {
+		opts.URL = DefaultURL
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return trid.ScannerFunc(func(ctx context.Context, path string) ([]trid.FileType, error) {
+		mimeType, err := detect(ctx, opts, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			return nil, nil
+		}
+
+		var ext string
+		if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+			ext = exts[0]
+		}
+
+		return []trid.FileType{{Extension: ext, Probability: 100, Name: mimeType, MimeType: mimeType, Source: Source}}, nil
+	})
+}
+
+// detect uploads the file at path to Tika and returns the detected MIME type.
+// The file name is sent along, as Tika takes it into account.
+func detect(ctx context.Context, opts Options, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(opts.URL, "/")+"/detect/stream", f)
+	if err != nil {
+		return "", err
+	}
+
+	if fi, err := f.Stat(); err == nil {
+		req.ContentLength = fi.Size()
+	}
+
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("tika: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if err != nil {
+		return "", fmt.Errorf("tika: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("tika: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	mimeType, _, _ := strings.Cut(strings.TrimSpace(string(body)), ";")
+
+	return mimeType, nil
+}
@@ -0,0 +1,73 @@
+package tika
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestScan(t *testing.T) {
+	var filename string
+	var size int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/detect/stream" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		filename = params["filename"]
+
+		body, _ := io.ReadAll(r.Body)
+		size = len(body)
+
+		io.WriteString(w, "application/pdf")
+	}))
+	defer srv.Close()
+
+	fileTypes, err := New(Options{URL: srv.URL + "/"}).Scan("../../testdata/sample.pdf", 5)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	expected := trid.FileType{Extension: ".pdf", Probability: 100, Name: "application/pdf", MimeType: "application/pdf", Source: Source}
+	if len(fileTypes) != 1 || fileTypes[0] != expected {
+		t.Errorf("Scan() = %+v, want %+v", fileTypes, expected)
+	}
+
+	if filename != "sample.pdf" || size == 0 {
+		t.Errorf("Tika received %q (%d bytes), want sample.pdf", filename, size)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		expectedErr error
+	}{
+		{"Unknown", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "application/octet-stream\n") }, trid.ErrUnknownFileType},
+		{"Server error", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "boom", http.StatusInternalServerError) }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			_, err := New(Options{URL: srv.URL}).Scan("../../testdata/sample.pdf", 1)
+			if err == nil {
+				t.Fatal("Scan() succeeded")
+			}
+
+			if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Scan() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
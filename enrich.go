@@ -0,0 +1,60 @@
+package trid
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Enricher adds details to the identification of files, like
+// Options.Executables and Options.PDF do, from other libraries or tools. It is
+// set in Options.Enrichers, and its details are reported in
+// Report.Enrichments under its name.
+type Enricher interface {
+	// Enrich returns details about the file at path, given its matches, most
+	// likely first, or nil if it does not apply to the file.
+	Enrich(ctx context.Context, path string, fileTypes []FileType) (any, error)
+}
+
+// EnricherFunc adapts a function to the Enricher interface.
+type EnricherFunc func(ctx context.Context, path string, fileTypes []FileType) (any, error)
+
+// Enrich implements Enricher.
+func (f EnricherFunc) Enrich(ctx context.Context, path string, fileTypes []FileType) (any, error) {
+	return f(ctx, path, fileTypes)
+}
+
+// enrich runs Options.Enrichers, in the order of their names, on the file at
+// path. Failing enrichers are logged and left out, as they do not make the
+// identification fail.
+func (t *Trid) enrich(ctx context.Context, path string, fileTypes []FileType) map[string]any {
+	if len(t.options.Enrichers) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(t.options.Enrichers))
+	for name := range t.options.Enrichers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var enrichments map[string]any
+	for _, name := range names {
+		details, err := t.options.Enrichers[name].Enrich(ctx, path, fileTypes)
+		if err != nil {
+			t.log(ctx, slog.LevelWarn, "enrichment failed", "path", path, "enricher", name, "error", err)
+			continue
+		}
+
+		if details == nil {
+			continue
+		}
+
+		if enrichments == nil {
+			enrichments = make(map[string]any)
+		}
+		enrichments[name] = details
+	}
+
+	return enrichments
+}
@@ -0,0 +1,178 @@
+// Package ffprobe describes audio and video files with ffprobe, as a
+// trid.Enricher adding their container, duration and streams to the
+// identification of files TrID reports as media.
+//
+// ffprobe must be installed; it is part of FFmpeg, see https://ffmpeg.org.
+package ffprobe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// Name is the name the enricher is conventionally set under in
+// trid.Options.Enrichers.
+const Name = "ffprobe"
+
+// mediaExts are the extensions of media matches without an audio or video MIME
+// type.
+var mediaExts = map[string]bool{
+	".mp4": true, ".m4a": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true,
+	".wmv": true, ".wma": true, ".asf": true, ".flv": true, ".mpg": true, ".mpeg": true, ".ts": true,
+	".m2ts": true, ".3gp": true, ".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".oga": true,
+	".ogv": true, ".opus": true, ".aac": true, ".aiff": true, ".aif": true, ".amr": true, ".mka": true,
+}
+
+// Options are the options of the enricher returned by New.
+type Options struct {
+	Cmd    string      // Command to run ffprobe (default: "ffprobe").
+	Runner trid.Runner // Runs ffprobe (default: trid.ExecRunner).
+}
+
+// Media describes an audio or video file.
+type Media struct {
+	Container string        `json:"container"`          // Short names of the container format, as reported by ffprobe (e.g., "mov,mp4,m4a,3gp,3g2,mj2").
+	Format    string        `json:"format"`             // Descriptive name of the container format.
+	Duration  time.Duration `json:"duration"`           // Duration, or 0 if unknown.
+	BitRate   int64         `json:"bit_rate,omitempty"` // Overall bit rate in bits per second.
+	Streams   []Stream      `json:"streams"`            // Streams, in container order.
+}
+
+// Stream is a stream of a media file.
+type Stream struct {
+	Index      int           `json:"index"`                 // Index of the stream in the container.
+	Type       string        `json:"type"`                  // Kind of stream: "video", "audio", "subtitle", "data" or "attachment".
+	Codec      string        `json:"codec"`                 // Short name of the codec (e.g., "h264", "aac").
+	CodecName  string        `json:"codec_name"`            // Descriptive name of the codec.
+	Duration   time.Duration `json:"duration,omitempty"`    // Duration of the stream, if known.
+	Width      int           `json:"width,omitempty"`       // Width of video streams in pixels.
+	Height     int           `json:"height,omitempty"`      // Height of video streams in pixels.
+	FrameRate  string        `json:"frame_rate,omitempty"`  // Average frame rate of video streams, as a fraction (e.g., "30000/1001").
+	SampleRate int           `json:"sample_rate,omitempty"` // Sample rate of audio streams in hertz.
+	Channels   int           `json:"channels,omitempty"`    // Number of channels of audio streams.
+	Language   string        `json:"language,omitempty"`    // Language tag of the stream, if any.
+}
+
+// output is the JSON output of ffprobe -show_format -show_streams.
+type output struct {
+	Format struct {
+		FormatName     string `json:"format_name"`
+		FormatLongName string `json:"format_long_name"`
+		Duration       string `json:"duration"`
+		BitRate        string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		CodecType     string            `json:"codec_type"`
+		CodecName     string            `json:"codec_name"`
+		CodecLongName string            `json:"codec_long_name"`
+		Duration      string            `json:"duration"`
+		Width         int               `json:"width"`
+		Height        int               `json:"height"`
+		AvgFrameRate  string            `json:"avg_frame_rate"`
+		SampleRate    string            `json:"sample_rate"`
+		Channels      int               `json:"channels"`
+		Tags          map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+// New returns an Enricher running ffprobe on files whose most likely match is
+// an audio or video format, recognized by its MIME type or extension. Its
+// details are a *Media.
+func New(opts Options) trid.Enricher {
+	if opts.Cmd == "" {
+		opts.Cmd = "ffprobe"
+	}
+
+	if opts.Runner == nil {
+		opts.Runner = trid.ExecRunner{}
+	}
+
+	return trid.EnricherFunc(func(ctx context.Context, path string, fileTypes []trid.FileType) (any, error) {
+		if len(fileTypes) == 0 || !IsMedia(fileTypes[0]) {
+			return nil, nil
+		}
+
+		args := []string{"-v", "error", "-print_format", "json", "-show_format", "-show_streams", path}
+		res, err := opts.Runner.Run(ctx, trid.Command{Name: opts.Cmd, Args: args})
+		if err != nil {
+			if stderr := strings.TrimSpace(res.Stderr); stderr != "" {
+				return nil, fmt.Errorf("ffprobe: %w: %s", err, stderr)
+			}
+
+			return nil, fmt.Errorf("ffprobe: %w", err)
+		}
+
+		return parseOutput(res.Stdout)
+	})
+}
+
+// IsMedia reports whether ft is an audio or video format.
+func IsMedia(ft trid.FileType) bool {
+	mimeType, _, _ := strings.Cut(ft.MimeType, ";")
+	mimeType = strings.TrimSpace(mimeType)
+
+	return strings.HasPrefix(mimeType, "audio/") || strings.HasPrefix(mimeType, "video/") ||
+		mediaExts[strings.ToLower(ft.Extension)]
+}
+
+// parseOutput converts the JSON output of ffprobe.
+func parseOutput(out string) (*Media, error) {
+	var o output
+	if err := json.Unmarshal([]byte(out), &o); err != nil {
+		return nil, fmt.Errorf("ffprobe: invalid output: %w", err)
+	}
+
+	if o.Format.FormatName == "" {
+		return nil, fmt.Errorf("ffprobe: output describes no format")
+	}
+
+	m := &Media{
+		Container: o.Format.FormatName,
+		Format:    o.Format.FormatLongName,
+		Duration:  parseDuration(o.Format.Duration),
+		Streams:   make([]Stream, 0, len(o.Streams)),
+	}
+	m.BitRate, _ = strconv.ParseInt(o.Format.BitRate, 10, 64)
+
+	for _, s := range o.Streams {
+		st := Stream{
+			Index:     s.Index,
+			Type:      s.CodecType,
+			Codec:     s.CodecName,
+			CodecName: s.CodecLongName,
+			Duration:  parseDuration(s.Duration),
+			Width:     s.Width,
+			Height:    s.Height,
+			Channels:  s.Channels,
+			Language:  s.Tags["language"],
+		}
+
+		// Streams other than video report 0/0
+		if s.CodecType == "video" && s.AvgFrameRate != "0/0" {
+			st.FrameRate = s.AvgFrameRate
+		}
+		st.SampleRate, _ = strconv.Atoi(s.SampleRate)
+
+		m.Streams = append(m.Streams, st)
+	}
+
+	return m, nil
+}
+
+// parseDuration converts a duration in seconds reported by ffprobe, or
+// returns 0 if it is missing or "N/A".
+func parseDuration(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
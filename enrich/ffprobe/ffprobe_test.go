@@ -0,0 +1,102 @@
+package ffprobe
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+const mp4Output = `{
+    "streams": [
+        {"index": 0, "codec_name": "h264", "codec_long_name": "H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10",
+         "codec_type": "video", "width": 1920, "height": 1080, "avg_frame_rate": "30000/1001", "duration": "10.010000",
+         "tags": {"language": "und"}},
+        {"index": 1, "codec_name": "aac", "codec_long_name": "AAC (Advanced Audio Coding)", "codec_type": "audio",
+         "sample_rate": "48000", "channels": 2, "avg_frame_rate": "0/0", "duration": "10.005333",
+         "tags": {"language": "eng"}}
+    ],
+    "format": {"filename": "clip.mp4", "nb_streams": 2, "format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+        "format_long_name": "QuickTime / MOV", "duration": "10.010000", "size": "1048576", "bit_rate": "838022"}
+}`
+
+func TestEnrich(t *testing.T) {
+	var args []string
+	runner := trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		args = append([]string{c.Name}, c.Args...)
+		return trid.CommandResult{Stdout: mp4Output}, nil
+	})
+
+	e := New(Options{Runner: runner})
+	mp4 := []trid.FileType{{Extension: ".mp4", Name: "MP4 Video", MimeType: "video/mp4"}}
+
+	details, err := e.Enrich(context.Background(), "clip.mp4", mp4)
+	if err != nil {
+		t.Fatalf("Enrich() error = %v", err)
+	}
+
+	if expected := []string{"ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "clip.mp4"}; !slices.Equal(args, expected) {
+		t.Errorf("Enrich() ran %q, want %q", args, expected)
+	}
+
+	m, ok := details.(*Media)
+	if !ok {
+		t.Fatalf("Enrich() = %T, want *Media", details)
+	}
+
+	if m.Container != "mov,mp4,m4a,3gp,3g2,mj2" || m.Format != "QuickTime / MOV" || m.Duration != 10010*time.Millisecond || m.BitRate != 838022 {
+		t.Errorf("Enrich() = %+v", m)
+	}
+
+	expected := []Stream{
+		{Index: 0, Type: "video", Codec: "h264", CodecName: "H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10",
+			Duration: 10010 * time.Millisecond, Width: 1920, Height: 1080, FrameRate: "30000/1001", Language: "und"},
+		{Index: 1, Type: "audio", Codec: "aac", CodecName: "AAC (Advanced Audio Coding)",
+			Duration: 10005333 * time.Microsecond, SampleRate: 48000, Channels: 2, Language: "eng"},
+	}
+	if !slices.Equal(m.Streams, expected) {
+		t.Errorf("Streams = %+v, want %+v", m.Streams, expected)
+	}
+
+	// Other files are left alone
+	args = nil
+	if details, err := e.Enrich(context.Background(), "sample.pdf", []trid.FileType{{Extension: ".pdf", MimeType: "application/pdf"}}); details != nil || err != nil || args != nil {
+		t.Errorf("Enrich() of a PDF = %v, %v, ran %q, want nothing", details, err, args)
+	}
+}
+
+func TestEnrichErr(t *testing.T) {
+	runner := trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		return trid.CommandResult{Stderr: "clip.mp4: Invalid data found when processing input\n"}, errors.New("exit status 1")
+	})
+
+	_, err := New(Options{Runner: runner}).Enrich(context.Background(), "clip.mp4", []trid.FileType{{Extension: ".mp4"}})
+	if err == nil || err.Error() != "ffprobe: exit status 1: clip.mp4: Invalid data found when processing input" {
+		t.Errorf("Enrich() error = %v", err)
+	}
+
+	if _, err := parseOutput("{}"); err == nil {
+		t.Error("parseOutput() of empty output succeeded, want error")
+	}
+}
+
+func TestIsMedia(t *testing.T) {
+	tests := []struct {
+		ft       trid.FileType
+		expected bool
+	}{
+		{trid.FileType{Extension: ".mkv"}, true},
+		{trid.FileType{Extension: ".MP3"}, true},
+		{trid.FileType{Extension: ".bin", MimeType: "audio/x-wav; codecs=1"}, true},
+		{trid.FileType{Extension: ".png", MimeType: "image/png"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsMedia(tt.ft); got != tt.expected {
+			t.Errorf("IsMedia(%+v) = %v, want %v", tt.ft, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEnrichers(t *testing.T) {
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: pdfOutput}, nil
+	})
+
+	var order []string
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, Enrichers: map[string]Enricher{
+		"pages": EnricherFunc(func(ctx context.Context, path string, fileTypes []FileType) (any, error) {
+			order = append(order, "pages")
+			return map[string]int{"count": 1}, nil
+		}),
+		"broken": EnricherFunc(func(ctx context.Context, path string, fileTypes []FileType) (any, error) {
+			order = append(order, "broken")
+			return nil, errors.New("tool missing")
+		}),
+		"images": EnricherFunc(func(ctx context.Context, path string, fileTypes []FileType) (any, error) {
+			order = append(order, "images")
+			if fileTypes[0].Extension != ".png" {
+				return nil, nil
+			}
+			return "image", nil
+		}),
+	}})
+
+	r, err := tr.ScanReport(context.Background(), "testdata/sample.pdf", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Enrichments) != 1 || r.Enrichments["pages"] == nil {
+		t.Errorf("Enrichments = %v, want pages only", r.Enrichments)
+	}
+
+	if expected := "broken,images,pages"; strings.Join(order, ",") != expected {
+		t.Errorf("enrichers ran in order %v, want %s", order, expected)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 2)
+	if err != nil || results[0].Enrichments["pages"] == nil {
+		t.Errorf("ScanBatch() = %+v, %v, want pages enrichment", results, err)
+	}
+}
@@ -0,0 +1,72 @@
+package trid
+
+import "strings"
+
+// TridError describes a failed TrID invocation. It wraps one of the package's
+// sentinel errors or the error returned by the process, so errors.Is and
+// errors.As work as usual, and carries the details needed to debug the
+// failure.
+type TridError struct {
+	Err      error    // Underlying error.
+	ExitCode int      // Exit code of the TrID process, or -1 if it did not exit normally.
+	Stderr   string   // Standard error of the TrID process.
+	Output   string   // Raw standard output of the TrID process concerning the failed file.
+	Command  []string // Command line executed, starting with the command.
+
+	// Partial holds the results parsed from the output produced before an
+	// ErrTimeout, if any. Matches may be missing or incomplete.
+	Partial []FileType
+}
+
+// Error returns the message of the underlying error.
+func (e *TridError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *TridError) Unwrap() error {
+	return e.Err
+}
+
+// CommandLine returns the executed command line as a single string.
+func (e *TridError) CommandLine() string {
+	return strings.Join(e.Command, " ")
+}
+
+// process is the outcome of running TrID.
+type process struct {
+	command  []string
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// error wraps err in a TridError describing the process.
+func (p *process) error(err error) error {
+	return &TridError{
+		Err:      err,
+		ExitCode: p.exitCode,
+		Stderr:   p.stderr,
+		Output:   p.stdout,
+		Command:  p.command,
+	}
+}
+
+// sectionError is like error but reports output as the raw output, for
+// failures concerning a single file of a batch.
+func (p *process) sectionError(err error, output string) error {
+	section := *p
+	section.stdout = output
+
+	return section.error(err)
+}
+
+// check returns the error reported by TrID on standard error or, failing
+// that, in stdout, which is the part of standard output to inspect.
+func (p *process) check(stdout string) error {
+	if err := checkTridError(p.stderr); err != nil {
+		return err
+	}
+
+	return checkTridError(stdout)
+}
@@ -0,0 +1,195 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTridError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\necho 'No definitions available!'\necho 'cannot read defs' >&2\nexit 2\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	trid := NewTrid(Options{Cmd: cmd, Definitions: "/missing.trd"})
+	_, err := trid.Scan(cmd, 3)
+
+	if !errors.Is(err, ErrNoDefinitions) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrNoDefinitions)
+	}
+
+	var tridErr *TridError
+	if !errors.As(err, &tridErr) {
+		t.Fatalf("Scan() error = %T, want *TridError", err)
+	}
+
+	if tridErr.ExitCode != 2 {
+		t.Errorf("ExitCode = %d, want 2", tridErr.ExitCode)
+	}
+
+	if tridErr.Stderr != "cannot read defs\n" {
+		t.Errorf("Stderr = %q, want %q", tridErr.Stderr, "cannot read defs\n")
+	}
+
+	if tridErr.Output != "No definitions available!\n" {
+		t.Errorf("Output = %q, want %q", tridErr.Output, "No definitions available!\n")
+	}
+
+	expectedCmd := cmd + " -v -n:3 -d:/missing.trd " + cmd
+	if got := tridErr.CommandLine(); got != expectedCmd {
+		t.Errorf("CommandLine() = %q, want %q", got, expectedCmd)
+	}
+
+	if err.Error() != ErrNoDefinitions.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), ErrNoDefinitions.Error())
+	}
+}
+
+func TestTridErrorExecution(t *testing.T) {
+	trid := NewTrid(Options{Cmd: filepath.Join(t.TempDir(), "missing")})
+	_, err := trid.Scan("testdata/sample.pdf", 1)
+
+	var tridErr *TridError
+	if !errors.As(err, &tridErr) {
+		t.Fatalf("Scan() error = %T, want *TridError", err)
+	}
+
+	if tridErr.ExitCode != -1 {
+		t.Errorf("ExitCode = %d, want -1", tridErr.ExitCode)
+	}
+}
+
+func TestTridErrorBatch(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	paths := writeFiles(t, "%PDF-1", "plain text")
+
+	results, err := NewTrid(Options{Cmd: cmd}).ScanBatch(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	var tridErr *TridError
+	if !errors.As(results[1].Err, &tridErr) || !errors.Is(results[1].Err, ErrUnknownFileType) {
+		t.Fatalf("result error = %v, want a *TridError wrapping %v", results[1].Err, ErrUnknownFileType)
+	}
+
+	// The raw output is limited to the section of the failed file
+	if strings.Contains(tridErr.Output, "PDF") || !strings.Contains(tridErr.Output, "Unknown!") {
+		t.Errorf("Output = %q, want the output of %s only", tridErr.Output, paths[1])
+	}
+}
+
+func TestScanStderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	tests := []struct {
+		name        string
+		script      string
+		expectedErr error
+	}{
+		{
+			name: "Warning between results",
+			script: "echo 'Collecting data from file: sample.pdf'\n" +
+				"echo ' 100.0% (.PDF) Adobe Portable Document Format (5000/1)'\n" +
+				"echo 'Warning: slow disk' >&2\n" +
+				"echo '        Mime type       : application/pdf'\n",
+		},
+		{
+			name:        "Error on standard error",
+			script:      "echo 'No definitions available!' >&2\n",
+			expectedErr: ErrNoDefinitions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := filepath.Join(t.TempDir(), "trid")
+			if err := os.WriteFile(cmd, []byte("#!/bin/sh\n"+tt.script), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			fileTypes, err := NewTrid(Options{Cmd: cmd}).Scan(cmd, 1)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if tt.expectedErr == nil && (len(fileTypes) != 1 || fileTypes[0].MimeType != "application/pdf") {
+				t.Errorf("Scan() = %+v, want a PDF with its mime type", fileTypes)
+			}
+		})
+	}
+}
+
+func TestScanTimeoutPartial(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	cmd := filepath.Join(t.TempDir(), "trid")
+	script := "#!/bin/sh\n" +
+		"echo 'Collecting data from file: sample.pdf'\n" +
+		"echo ' 100.0% (.PDF) Adobe Portable Document Format (5000/1)'\n" +
+		"exec sleep 5\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewTrid(Options{Cmd: cmd, Timeout: 200 * time.Millisecond}).Scan(cmd, 1)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrTimeout)
+	}
+
+	var tridErr *TridError
+	if !errors.As(err, &tridErr) {
+		t.Fatalf("Scan() error = %T, want *TridError", err)
+	}
+
+	if len(tridErr.Partial) != 1 || tridErr.Partial[0].Extension != ".pdf" {
+		t.Errorf("Partial = %+v, want the PDF match", tridErr.Partial)
+	}
+}
+
+func TestScanReportWarnings(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	cmd := filepath.Join(t.TempDir(), "trid")
+	script := "#!/bin/sh\n" +
+		"echo 'Warning: slow disk' >&2\n" +
+		"echo 'Collecting data from file: sample.pdf'\n" +
+		"echo 'Read error, file truncated'\n" +
+		"echo ' 100.0% (.PDF) Adobe Portable Document Format (5000/1)'\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewTrid(Options{Cmd: cmd}).ScanReport(context.Background(), cmd, 1)
+	if err != nil {
+		t.Fatalf("ScanReport() error = %v", err)
+	}
+
+	if len(r.FileTypes) != 1 {
+		t.Errorf("FileTypes = %+v, want one match", r.FileTypes)
+	}
+
+	expected := []string{"Warning: slow disk", "Read error, file truncated"}
+	if !slices.Equal(r.Warnings, expected) {
+		t.Errorf("Warnings = %q, want %q", r.Warnings, expected)
+	}
+}
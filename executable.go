@@ -0,0 +1,384 @@
+package trid
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Executable formats reported in Executable.Format.
+const (
+	ExecutablePE    = "pe"
+	ExecutableELF   = "elf"
+	ExecutableMachO = "macho"
+)
+
+// Executable describes the header of an executable, shared library or object
+// file, with Options.Executables.
+type Executable struct {
+	Format    string     `json:"format"`              // ExecutablePE, ExecutableELF or ExecutableMachO.
+	Arch      string     `json:"arch"`                // Architecture, named as by GOARCH where possible (e.g., "amd64", "arm64"); comma-separated for universal Mach-O files.
+	Bits      int        `json:"bits"`                // 32 or 64.
+	Type      string     `json:"type"`                // Kind of file: "executable", "library", "driver", "object" or "core".
+	Subsystem string     `json:"subsystem,omitempty"` // PE subsystem (e.g., "windows-gui", "windows-cui", "efi-application") or ELF OS ABI.
+	Linker    string     `json:"linker,omitempty"`    // Linker version of PE files (e.g., "14.29").
+	Timestamp *time.Time `json:"timestamp,omitempty"` // Link time of PE files, if set; often forged or a build hash.
+	Hints     []string   `json:"hints,omitempty"`     // Toolchains and packers the file shows traces of (e.g., "go", "msvc", "gcc", "upx", ".net").
+}
+
+// exeExts are the extensions of executable matches.
+var exeExts = map[string]bool{
+	".exe": true, ".dll": true, ".sys": true, ".ocx": true, ".cpl": true, ".scr": true, ".drv": true,
+	".efi": true, ".mui": true, ".elf": true, ".so": true, ".o": true, ".ko": true, ".dylib": true,
+	".bundle": true, ".axf": true, ".bin": true,
+}
+
+// isExecutable reports whether ft is an executable format.
+func isExecutable(ft FileType) bool {
+	name := strings.ToLower(ft.Name)
+	return exeExts[strings.ToLower(ft.Extension)] || strings.Contains(name, "executable") ||
+		strings.Contains(name, "elf") || strings.Contains(name, "mach-o")
+}
+
+// inspectExecutable parses the header of the file at path, if Options.
+// Executables is set and its most likely match is an executable format. It
+// returns nil if the file is not a PE, ELF or Mach-O file.
+func (t *Trid) inspectExecutable(path string, fileTypes []FileType) *Executable {
+	if !t.options.Executables || len(fileTypes) == 0 || !isExecutable(fileTypes[0]) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return inspectPE(f)
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		return inspectELF(f)
+	default:
+		return inspectMachO(f)
+	}
+}
+
+// PE machine types, by GOARCH name.
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_ARM:   "arm",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+	pe.IMAGE_FILE_MACHINE_IA64:  "ia64",
+}
+
+// PE subsystems.
+var peSubsystems = map[uint16]string{
+	pe.IMAGE_SUBSYSTEM_NATIVE:                   "native",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_GUI:              "windows-gui",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_CUI:              "windows-cui",
+	pe.IMAGE_SUBSYSTEM_POSIX_CUI:                "posix-cui",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_CE_GUI:           "windows-ce-gui",
+	pe.IMAGE_SUBSYSTEM_EFI_APPLICATION:          "efi-application",
+	pe.IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER:  "efi-boot-service-driver",
+	pe.IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER:       "efi-runtime-driver",
+	pe.IMAGE_SUBSYSTEM_EFI_ROM:                  "efi-rom",
+	pe.IMAGE_SUBSYSTEM_XBOX:                     "xbox",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_BOOT_APPLICATION: "windows-boot-application",
+}
+
+// inspectPE parses the header of a PE file.
+func inspectPE(f *os.File) *Executable {
+	pf, err := pe.NewFile(f)
+	if err != nil {
+		return nil
+	}
+	defer pf.Close()
+
+	x := &Executable{
+		Format: ExecutablePE,
+		Arch:   peMachines[pf.Machine],
+		Bits:   32,
+		Type:   "executable",
+	}
+
+	if pf.TimeDateStamp != 0 {
+		ts := time.Unix(int64(pf.TimeDateStamp), 0).UTC()
+		x.Timestamp = &ts
+	}
+
+	if x.Arch == "" {
+		x.Arch = fmt.Sprintf("0x%04x", pf.Machine)
+	}
+
+	if pf.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		x.Type = "library"
+	}
+
+	var (
+		subsystem uint16
+		dirs      []pe.DataDirectory
+	)
+	switch oh := pf.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		subsystem, dirs = oh.Subsystem, oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+		x.Linker = fmt.Sprintf("%d.%02d", oh.MajorLinkerVersion, oh.MinorLinkerVersion)
+	case *pe.OptionalHeader64:
+		subsystem, dirs = oh.Subsystem, oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+		x.Linker = fmt.Sprintf("%d.%02d", oh.MajorLinkerVersion, oh.MinorLinkerVersion)
+		x.Bits = 64
+	}
+
+	if x.Subsystem = peSubsystems[subsystem]; subsystem == pe.IMAGE_SUBSYSTEM_NATIVE && x.Type == "executable" {
+		x.Type = "driver"
+	}
+
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR && dirs[pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR].Size > 0 {
+		x.hint(".net")
+	}
+
+	// The undocumented Rich header between the DOS stub and the PE header is
+	// written by the Microsoft linker
+	if hasRichHeader(f) {
+		x.hint("msvc")
+	}
+
+	for _, s := range pf.Sections {
+		switch name := s.Name; {
+		case strings.HasPrefix(name, "UPX"):
+			x.hint("upx")
+		case name == "CODE":
+			x.hint("delphi")
+		case name == ".aspack" || name == ".adata":
+			x.hint("aspack")
+		case name == ".themida" || name == ".winlice":
+			x.hint("themida")
+		case name == ".vmp0" || name == ".vmp1":
+			x.hint("vmprotect")
+		}
+	}
+
+	if hasString(f, goBuildInfoMagic) {
+		x.hint("go")
+	}
+
+	return x
+}
+
+// hasRichHeader reports whether the DOS stub of the PE file f holds a Rich
+// header.
+func hasRichHeader(f io.ReaderAt) bool {
+	buf := make([]byte, 0x400)
+	n, _ := f.ReadAt(buf, 0)
+	if n < 0x40 {
+		return false
+	}
+
+	peOffset := int(buf[0x3c]) | int(buf[0x3d])<<8 | int(buf[0x3e])<<16 | int(buf[0x3f])<<24
+	if peOffset <= 0x40 || peOffset > n {
+		return false
+	}
+
+	return bytes.Contains(buf[0x40:peOffset], []byte("Rich"))
+}
+
+// ELF machines, by GOARCH name.
+var elfMachines = map[elf.Machine]string{
+	elf.EM_386:       "386",
+	elf.EM_X86_64:    "amd64",
+	elf.EM_ARM:       "arm",
+	elf.EM_AARCH64:   "arm64",
+	elf.EM_MIPS:      "mips",
+	elf.EM_PPC:       "ppc",
+	elf.EM_PPC64:     "ppc64",
+	elf.EM_RISCV:     "riscv",
+	elf.EM_S390:      "s390x",
+	elf.EM_SPARCV9:   "sparc64",
+	elf.EM_LOONGARCH: "loong64",
+}
+
+// inspectELF parses the header of an ELF file.
+func inspectELF(f *os.File) *Executable {
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return nil
+	}
+	defer ef.Close()
+
+	x := &Executable{Format: ExecutableELF, Arch: elfMachines[ef.Machine], Bits: 32}
+	if x.Arch == "" {
+		x.Arch = strings.ToLower(strings.TrimPrefix(ef.Machine.String(), "EM_"))
+	}
+
+	if ef.Class == elf.ELFCLASS64 {
+		x.Bits = 64
+		if x.Arch == "riscv" {
+			x.Arch = "riscv64"
+		}
+	}
+
+	if ef.OSABI != elf.ELFOSABI_NONE {
+		x.Subsystem = strings.ToLower(strings.TrimPrefix(ef.OSABI.String(), "ELFOSABI_"))
+	}
+
+	switch ef.Type {
+	case elf.ET_EXEC:
+		x.Type = "executable"
+	case elf.ET_DYN:
+		// Position-independent executables are shared objects with an
+		// interpreter
+		x.Type = "library"
+		if slices.ContainsFunc(ef.Progs, func(p *elf.Prog) bool { return p.Type == elf.PT_INTERP }) {
+			x.Type = "executable"
+		}
+	case elf.ET_REL:
+		x.Type = "object"
+		if ef.Section(".modinfo") != nil {
+			x.Type = "driver"
+		}
+	case elf.ET_CORE:
+		x.Type = "core"
+	}
+
+	if s := ef.Section(".comment"); s != nil {
+		if data, err := s.Data(); err == nil {
+			switch {
+			case bytes.Contains(data, []byte("GCC:")):
+				x.hint("gcc")
+			case bytes.Contains(data, []byte("clang")):
+				x.hint("clang")
+			}
+
+			if bytes.Contains(data, []byte("rustc")) {
+				x.hint("rust")
+			}
+		}
+	}
+
+	if hasString(f, goBuildInfoMagic) {
+		x.hint("go")
+	}
+
+	if hasString(f, upxMagic) {
+		x.hint("upx")
+	}
+
+	return x
+}
+
+// Mach-O CPU types, by GOARCH name.
+var machoCPUs = map[macho.Cpu]string{
+	macho.Cpu386:   "386",
+	macho.CpuAmd64: "amd64",
+	macho.CpuArm:   "arm",
+	macho.CpuArm64: "arm64",
+	macho.CpuPpc:   "ppc",
+	macho.CpuPpc64: "ppc64",
+}
+
+// inspectMachO parses the header of a Mach-O or universal Mach-O file.
+func inspectMachO(f *os.File) *Executable {
+	if ff, err := macho.NewFatFile(f); err == nil {
+		defer ff.Close()
+
+		var x *Executable
+		for _, arch := range ff.Arches {
+			ax := machoExecutable(arch.File)
+			if x == nil {
+				x = ax
+				continue
+			}
+
+			x.Arch += "," + ax.Arch
+			x.Bits = max(x.Bits, ax.Bits)
+			for _, h := range ax.Hints {
+				x.hint(h)
+			}
+		}
+
+		return x
+	}
+
+	mf, err := macho.NewFile(f)
+	if err != nil {
+		return nil
+	}
+	defer mf.Close()
+
+	return machoExecutable(mf)
+}
+
+// machoExecutable describes a Mach-O file.
+func machoExecutable(mf *macho.File) *Executable {
+	x := &Executable{Format: ExecutableMachO, Arch: machoCPUs[mf.Cpu], Bits: 32}
+	if x.Arch == "" {
+		x.Arch = strings.ToLower(mf.Cpu.String())
+	}
+
+	if mf.Magic == macho.Magic64 {
+		x.Bits = 64
+	}
+
+	switch mf.Type {
+	case macho.TypeExec:
+		x.Type = "executable"
+	case macho.TypeDylib, macho.TypeBundle:
+		x.Type = "library"
+	case macho.TypeObj:
+		x.Type = "object"
+	case 4: // MH_CORE
+		x.Type = "core"
+	default:
+		x.Type = "executable"
+	}
+
+	if mf.Section("__go_buildinfo") != nil {
+		x.hint("go")
+	}
+
+	return x
+}
+
+// Strings left in binaries by toolchains and packers, found wherever they are
+// as section names may be stripped.
+var (
+	goBuildInfoMagic = []byte("\xff Go buildinf:")
+	upxMagic         = []byte("UPX!")
+)
+
+// hasString reports whether s is found in the file f.
+func hasString(f *os.File, s []byte) bool {
+	size, err := fileSize(f)
+	if err != nil {
+		return false
+	}
+
+	found := false
+	scanSignature(f, size, s, func(int64) bool {
+		found = true
+		return false
+	})
+
+	return found
+}
+
+// hint adds a toolchain hint, once.
+func (x *Executable) hint(h string) {
+	if !slices.Contains(x.Hints, h) {
+		x.Hints = append(x.Hints, h)
+	}
+}
@@ -0,0 +1,161 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// minimalPE returns the headers of a 64-bit PE file linked by the Microsoft
+// linker, with a single UPX section.
+func minimalPE(t *testing.T, characteristics, subsystem uint16, dotnet bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	dos := make([]byte, 0x80)
+	copy(dos, "MZ")
+	binary.LittleEndian.PutUint32(dos[0x3c:], 0x80)
+	copy(dos[0x60:], "Rich")
+	buf.Write(dos)
+	buf.WriteString("PE\x00\x00")
+
+	oh := pe.OptionalHeader64{
+		Magic:               0x20b,
+		MajorLinkerVersion:  14,
+		MinorLinkerVersion:  29,
+		Subsystem:           subsystem,
+		NumberOfRvaAndSizes: 16,
+	}
+	if dotnet {
+		oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR] = pe.DataDirectory{VirtualAddress: 0x2000, Size: 0x48}
+	}
+
+	binary.Write(&buf, binary.LittleEndian, pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections:     1,
+		TimeDateStamp:        1700000000,
+		SizeOfOptionalHeader: uint16(binary.Size(oh)),
+		Characteristics:      characteristics,
+	})
+	binary.Write(&buf, binary.LittleEndian, oh)
+	binary.Write(&buf, binary.LittleEndian, pe.SectionHeader32{Name: [8]uint8{'U', 'P', 'X', '0'}})
+
+	return buf.Bytes()
+}
+
+// minimalELF returns the header of a 64-bit ELF file without sections.
+func minimalELF(t *testing.T, typ elf.Type, machine elf.Machine) []byte {
+	t.Helper()
+
+	hdr := elf.Header64{
+		Type:    uint16(typ),
+		Machine: uint16(machine),
+		Version: uint32(elf.EV_CURRENT),
+		Ehsize:  64,
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, hdr)
+
+	return buf.Bytes()
+}
+
+func TestInspectExecutable(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tr := NewTrid(Options{Cmd: "trid", Executables: true})
+	exe := []FileType{{Extension: ".exe", Name: "Win64 Executable (generic)"}}
+
+	x := tr.inspectExecutable(write("app.exe", minimalPE(t, pe.IMAGE_FILE_EXECUTABLE_IMAGE, pe.IMAGE_SUBSYSTEM_WINDOWS_GUI, false)), exe)
+	if x == nil || x.Format != ExecutablePE || x.Arch != "amd64" || x.Bits != 64 || x.Type != "executable" ||
+		x.Subsystem != "windows-gui" || x.Linker != "14.29" || x.Timestamp == nil || x.Timestamp.Unix() != 1700000000 {
+		t.Fatalf("inspectExecutable(app.exe) = %+v", x)
+	}
+
+	if !slices.Contains(x.Hints, "msvc") || !slices.Contains(x.Hints, "upx") {
+		t.Errorf("Hints = %v, want msvc and upx", x.Hints)
+	}
+
+	x = tr.inspectExecutable(write("lib.dll", minimalPE(t, pe.IMAGE_FILE_DLL, pe.IMAGE_SUBSYSTEM_WINDOWS_CUI, true)), exe)
+	if x == nil || x.Type != "library" || !slices.Contains(x.Hints, ".net") {
+		t.Errorf("inspectExecutable(lib.dll) = %+v, want .NET library", x)
+	}
+
+	x = tr.inspectExecutable(write("driver.sys", minimalPE(t, pe.IMAGE_FILE_EXECUTABLE_IMAGE, pe.IMAGE_SUBSYSTEM_NATIVE, false)), exe)
+	if x == nil || x.Type != "driver" {
+		t.Errorf("inspectExecutable(driver.sys) = %+v, want driver", x)
+	}
+
+	elfType := []FileType{{Extension: "", Name: "ELF Executable and Linkable format (generic)"}}
+	x = tr.inspectExecutable(write("libz.so", minimalELF(t, elf.ET_DYN, elf.EM_AARCH64)), elfType)
+	if x == nil || x.Format != ExecutableELF || x.Arch != "arm64" || x.Bits != 64 || x.Type != "library" {
+		t.Errorf("inspectExecutable(libz.so) = %+v, want arm64 library", x)
+	}
+
+	x = tr.inspectExecutable(write("a.out", minimalELF(t, elf.ET_EXEC, elf.EM_X86_64)), elfType)
+	if x == nil || x.Arch != "amd64" || x.Type != "executable" {
+		t.Errorf("inspectExecutable(a.out) = %+v, want amd64 executable", x)
+	}
+
+	// Neither other matches nor other files are parsed
+	if x := tr.inspectExecutable(filepath.Join(dir, "app.exe"), []FileType{{Extension: ".pdf", Name: "Adobe Portable Document Format"}}); x != nil {
+		t.Errorf("inspectExecutable() of a PDF match = %+v, want nil", x)
+	}
+
+	if x := tr.inspectExecutable("testdata/sample.pdf", exe); x != nil {
+		t.Errorf("inspectExecutable(sample.pdf) = %+v, want nil", x)
+	}
+
+	if x := NewTrid(Options{Cmd: "trid"}).inspectExecutable(filepath.Join(dir, "app.exe"), exe); x != nil {
+		t.Errorf("inspectExecutable() without Executables = %+v, want nil", x)
+	}
+}
+
+const exeOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: app.exe
+ 62.1% (.EXE) Win64 Executable (generic) (10523/12/4)
+ 22.9% (.DLL) Win32 Dynamic Link Library (generic) (3881/0/0)
+`
+
+func TestScanExecutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.exe")
+	if err := os.WriteFile(path, minimalPE(t, pe.IMAGE_FILE_EXECUTABLE_IMAGE, pe.IMAGE_SUBSYSTEM_WINDOWS_CUI, false), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: exeOutput}, nil
+	})
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, Executables: true})
+
+	r, err := tr.ScanReport(context.Background(), path, 2)
+	if err != nil || r.Executable == nil || r.Executable.Subsystem != "windows-cui" {
+		t.Errorf("ScanReport() = %+v, %v, want executable header", r, err)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{path}, 2)
+	if err != nil || results[0].Executable == nil || results[0].Executable.Arch != "amd64" {
+		t.Errorf("ScanBatch() = %+v, %v, want executable header", results, err)
+	}
+}
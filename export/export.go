@@ -0,0 +1,219 @@
+// Package export writes scan results in line- and table-oriented formats for
+// other programs and shell pipelines: JSON, newline-delimited JSON, CSV and
+// aligned text tables.
+//
+// Every format is written by a Writer, like parquet.Writer, so callers can
+// choose the format at run time:
+//
+//	w, err := export.NewWriter("csv", os.Stdout)
+//	for _, r := range results {
+//		w.Write(r)
+//	}
+//	w.Close()
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrUnknownFormat is returned by NewWriter for an unsupported format.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Formats lists the formats supported by NewWriter.
+var Formats = []string{"json", "ndjson", "csv", "table"}
+
+// Writer writes scan results in a format. Results are written in the order
+// of the calls to Write; Close completes the output, but does not close the
+// underlying writer.
+type Writer interface {
+	Write(r trid.BatchResult) error
+	Close() error
+}
+
+// Record is the JSON representation of a result, as written by the JSON and
+// NDJSON writers.
+type Record struct {
+	Path      string          `json:"path"`
+	FileTypes []trid.FileType `json:"file_types,omitempty"`
+	Warnings  []string        `json:"warnings,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// NewRecord returns the JSON representation of r.
+func NewRecord(r trid.BatchResult) Record {
+	rec := Record{Path: r.Path, FileTypes: r.FileTypes, Warnings: r.Warnings}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+
+	return rec
+}
+
+// NewWriter returns a Writer of the named format, one of Formats.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "json":
+		return NewJSON(w), nil
+	case "ndjson":
+		return NewNDJSON(w), nil
+	case "csv":
+		return NewCSV(w), nil
+	case "table":
+		return NewTable(w), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// jsonWriter writes results as a single indented JSON array of Records.
+type jsonWriter struct {
+	w       io.Writer
+	records []Record
+}
+
+// NewJSON returns a Writer of a JSON array of Records, written by Close.
+func NewJSON(w io.Writer) Writer {
+	return &jsonWriter{w: w, records: make([]Record, 0)}
+}
+
+func (j *jsonWriter) Write(r trid.BatchResult) error {
+	j.records = append(j.records, NewRecord(r))
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(j.records)
+}
+
+// ndjsonWriter writes a Record per line.
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+// NewNDJSON returns a Writer of newline-delimited JSON: a Record per line,
+// written as soon as it is passed to Write.
+func NewNDJSON(w io.Writer) Writer {
+	return &ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonWriter) Write(r trid.BatchResult) error {
+	return n.enc.Encode(NewRecord(r))
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// csvHeader is the header row of CSV output.
+var csvHeader = []string{"path", "rank", "probability", "extension", "name", "mime_type", "definition", "error"}
+
+// csvWriter writes a row per match.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSV returns a Writer of CSV with a header row and a row per match, ranked
+// from 1 by decreasing probability. Files without matches have a single row
+// with a rank of 0 and their error, if any.
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// writeHeader writes the header row, unless it is written already.
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+
+	c.header = true
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(r trid.BatchResult) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	var errMsg string
+	if r.Err != nil {
+		errMsg = r.Err.Error()
+	}
+
+	if len(r.FileTypes) == 0 {
+		return c.w.Write([]string{r.Path, "0", "", "", "", "", "", errMsg})
+	}
+
+	for i, ft := range r.FileTypes {
+		row := []string{r.Path, strconv.Itoa(i + 1), strconv.FormatFloat(ft.Probability, 'f', -1, 64), ft.Extension, ft.Name, ft.MimeType, ft.Definition, errMsg}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tableWriter writes an aligned row per file.
+type tableWriter struct {
+	w      *tabwriter.Writer
+	header bool
+}
+
+// NewTable returns a Writer of a text table aligned in columns, with a row
+// per file showing its most likely match, or its error. The table is written
+// by Close, once the width of the columns is known.
+func NewTable(w io.Writer) Writer {
+	return &tableWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+}
+
+// writeHeader writes the header row, unless it is written already.
+func (t *tableWriter) writeHeader() {
+	if !t.header {
+		t.header = true
+		fmt.Fprintln(t.w, "PATH\tEXTENSION\tPROBABILITY\tNAME")
+	}
+}
+
+func (t *tableWriter) Write(r trid.BatchResult) error {
+	t.writeHeader()
+
+	best, ok := trid.Results(r.FileTypes).Best()
+	switch {
+	case r.Err != nil:
+		_, err := fmt.Fprintf(t.w, "%s\t-\t-\terror: %v\n", r.Path, r.Err)
+		return err
+	case !ok:
+		_, err := fmt.Fprintf(t.w, "%s\t-\t-\tunknown\n", r.Path)
+		return err
+	default:
+		_, err := fmt.Fprintf(t.w, "%s\t%s\t%.1f%%\t%s\n", r.Path, best.Extension, best.Probability, best.Name)
+		return err
+	}
+}
+
+func (t *tableWriter) Close() error {
+	t.writeHeader()
+
+	return t.w.Flush()
+}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+// results returns a result with two matches, an unknown file and a failure.
+func results() []trid.BatchResult {
+	return []trid.BatchResult{
+		{Path: "a.pdf", FileTypes: []trid.FileType{
+			{Extension: ".pdf", Probability: 90.5, Name: "Adobe Portable Document Format", MimeType: "application/pdf", Definition: "pdf.trid.xml"},
+			{Extension: ".ai", Probability: 9.5, Name: "Adobe Illustrator"},
+		}},
+		{Path: "b.bin"},
+		{Path: "c.bin", Err: trid.ErrFileNotFound},
+	}
+}
+
+// write writes rs with the writer of format and returns the output.
+func write(t *testing.T, format string, rs []trid.BatchResult) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range rs {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestJSON(t *testing.T) {
+	var records []Record
+	if err := json.Unmarshal([]byte(write(t, "json", results())), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Record{
+		{Path: "a.pdf", FileTypes: results()[0].FileTypes},
+		{Path: "b.bin"},
+		{Path: "c.bin", Error: trid.ErrFileNotFound.Error()},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %+v, want %+v", records, expected)
+	}
+
+	if got := write(t, "json", nil); got != "[]\n" {
+		t.Errorf("JSON of no results = %q, want an empty array", got)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(write(t, "ndjson", results()), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+
+	for i, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+
+		if r.Path != results()[i].Path {
+			t.Errorf("line %d has path %q, want %q", i, r.Path, results()[i].Path)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(write(t, "csv", results()))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		csvHeader,
+		{"a.pdf", "1", "90.5", ".pdf", "Adobe Portable Document Format", "application/pdf", "pdf.trid.xml", ""},
+		{"a.pdf", "2", "9.5", ".ai", "Adobe Illustrator", "", "", ""},
+		{"b.bin", "0", "", "", "", "", "", ""},
+		{"c.bin", "0", "", "", "", "", "", trid.ErrFileNotFound.Error()},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("rows = %q, want %q", rows, expected)
+	}
+
+	if got := write(t, "csv", nil); got != strings.Join(csvHeader, ",")+"\n" {
+		t.Errorf("CSV of no results = %q, want the header", got)
+	}
+}
+
+func TestTable(t *testing.T) {
+	expected := `PATH   EXTENSION  PROBABILITY  NAME
+a.pdf  .pdf       90.5%        Adobe Portable Document Format
+b.bin  -          -            unknown
+c.bin  -          -            error: file not found
+`
+	if got := write(t, "table", results()); got != expected {
+		t.Errorf("table = %q, want %q", got, expected)
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("xml", &bytes.Buffer{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewWriter() error = %v, want ErrUnknownFormat", err)
+	}
+}
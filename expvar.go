@@ -0,0 +1,29 @@
+package trid
+
+import "expvar"
+
+// expvarStats is the value published under Options.ExpvarName.
+type expvarStats struct {
+	Scans           uint64  `json:"scans"`
+	Errors          uint64  `json:"errors"`
+	Timeouts        uint64  `json:"timeouts"`
+	AverageDuration float64 `json:"average_duration_seconds"` // Average duration of the TrID processes run.
+}
+
+// publishExpvar publishes the basic counters of the instance under name.
+func (t *Trid) publishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		s := t.Stats()
+
+		v := expvarStats{Scans: s.Scans, Timeouts: s.Errors["timeout"]}
+		for _, n := range s.Errors {
+			v.Errors += n
+		}
+
+		if s.Durations.Count > 0 {
+			v.AverageDuration = s.Durations.Sum.Seconds() / float64(s.Durations.Count)
+		}
+
+		return v
+	}))
+}
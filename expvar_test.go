@@ -0,0 +1,29 @@
+package trid
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	trid := NewTrid(Options{Cmd: cmd, ExpvarName: "trid_test"})
+	trid.Scan("testdata/sample.pdf", 1)
+	trid.Scan("testdata/missing.pdf", 1)
+
+	v := expvar.Get("trid_test")
+	if v == nil {
+		t.Fatal("expvar trid_test not published")
+	}
+
+	var got expvarStats
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Scans != 2 || got.Errors != 1 || got.Timeouts != 0 || got.AverageDuration <= 0 {
+		t.Errorf("expvar trid_test = %+v", got)
+	}
+}
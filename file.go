@@ -0,0 +1,153 @@
+package trid
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrFileTooLarge is returned for files larger than Options.MaxFileSize when
+// Options.Oversize is OversizeFail.
+var ErrFileTooLarge = errors.New("file is too large")
+
+// OversizePolicy selects how files larger than Options.MaxFileSize are
+// handled.
+type OversizePolicy int
+
+const (
+	// OversizeFail rejects large files with ErrFileTooLarge without running
+	// TrID.
+	OversizeFail OversizePolicy = iota
+
+	// OversizeTruncate identifies large files from a temporary copy of their
+	// first MaxFileSize bytes. TrID definitions mostly match the beginning of
+	// files, so this rarely changes the result.
+	OversizeTruncate
+)
+
+// LocalCopy configures copying files to local storage before identifying
+// them. TrID reads files in many small chunks, which is slow on high-latency
+// network filesystems such as NFS or SMB; a sequential copy is much faster.
+type LocalCopy struct {
+	Enabled  bool                   // Copy files before identifying them.
+	HeadSize int64                  // Copy only the first HeadSize bytes; 0 copies whole files.
+	Dir      string                 // Directory of the copies (default: os.TempDir()).
+	Match    func(path string) bool // Reports whether the file at path is copied (default: every file).
+}
+
+// checkFile checks that the file at path can be identified and returns the
+// path to pass to TrID, which may be a temporary file, and a function
+// releasing it.
+func (t *Trid) checkFile(path string) (string, func(), error) {
+	fi, err := os.Lstat(path)
+	if err == nil && t.options.Symlinks == SymlinkRefuse {
+		var linked bool
+		if linked, err = isLinked(path); linked {
+			return "", nil, fmt.Errorf("%w: %s", ErrIsSymlink, path)
+		}
+	}
+
+	if err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+		if t.options.Symlinks == SymlinkTarget {
+			path, err = filepath.EvalSymlinks(path)
+		}
+
+		if err == nil {
+			fi, err = os.Stat(path)
+		}
+	}
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, ErrFileNotFound
+		}
+
+		return "", nil, err
+	}
+
+	if fi.Mode().IsRegular() && fi.Size() == 0 {
+		return "", nil, ErrEmptyFile
+	}
+
+	if limit := t.options.MaxFileSize; limit > 0 && fi.Mode().IsRegular() && fi.Size() > limit {
+		if t.options.Oversize != OversizeTruncate {
+			return "", nil, fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrFileTooLarge, path, fi.Size(), limit)
+		}
+
+		return copyHead(path, t.options.LocalCopy.Dir, limit)
+	}
+
+	if lc := t.options.LocalCopy; lc.Enabled && (lc.Match == nil || lc.Match(path)) {
+		n := fi.Size()
+		if lc.HeadSize > 0 {
+			n = min(n, lc.HeadSize)
+		}
+
+		return copyHead(path, lc.Dir, n)
+	}
+
+	return path, func() {}, nil
+}
+
+// timeout returns the time TrID is given to identify the files at paths:
+// Options.Timeout plus Options.TimeoutPerMB for each MiB they contain, so
+// small files fail fast while large ones are not killed prematurely.
+func (t *Trid) timeout(paths ...string) time.Duration {
+	if t.options.TimeoutPerMB <= 0 {
+		return t.options.Timeout
+	}
+
+	var size int64
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			size += fi.Size()
+		}
+	}
+
+	return t.options.Timeout + time.Duration(float64(t.options.TimeoutPerMB)*float64(size)/(1<<20))
+}
+
+// copyHead copies up to the first n bytes of the file at path to a temporary
+// file in dir and returns its path and a function removing it.
+func copyHead(path, dir string, n int64) (string, func(), error) {
+	return copyRange(path, dir, "trid-copy-*"+filepath.Ext(path), 0, n)
+}
+
+// copyRange copies up to n bytes from offset of the file at path to a
+// temporary file in dir, named after pattern as for os.CreateTemp, and
+// returns its path and a function removing it.
+func copyRange(path, dir, pattern string, offset, n int64) (string, func(), error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", nil, err
+	}
+
+	remove := func() { os.Remove(dst.Name()) }
+
+	// The file may have shrunk since it was checked
+	_, err = io.Copy(dst, io.NewSectionReader(src, offset, n))
+	if err == io.EOF {
+		err = nil
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+
+	return dst.Name(), remove, nil
+}
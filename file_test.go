@@ -0,0 +1,234 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRecordingStub creates a stub TrID executable that prints pdfOutput and
+// records the path and size of the file it identified in the returned file.
+func newRecordingStub(t *testing.T) (cmd, seen string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	seen = filepath.Join(dir, "seen.txt")
+	cmd = filepath.Join(dir, "trid")
+	script := "#!/bin/sh\n" +
+		"for f; do :; done\n" +
+		"echo \"$f $(wc -c < \"$f\")\" > '" + seen + "'\n" +
+		"cat <<'EOF'\n" + pdfOutput + "EOF\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, seen
+}
+
+// readSeen returns the path and size recorded by a recording stub.
+func readSeen(t *testing.T, seen string) (string, string) {
+	t.Helper()
+
+	b, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, size, _ := strings.Cut(strings.TrimSpace(string(b)), " ")
+	return path, strings.TrimSpace(size)
+}
+
+func TestMaxFileSize(t *testing.T) {
+	cmd, seen := newRecordingStub(t)
+	dir := t.TempDir()
+
+	small := filepath.Join(dir, "small.pdf")
+	large := filepath.Join(dir, "large.pdf")
+	if err := os.WriteFile(small, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, []byte("%PDF-1.4"+strings.Repeat("x", 1000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Fail", func(t *testing.T) {
+		trid := NewTrid(Options{Cmd: cmd, MaxFileSize: 100})
+
+		if _, err := trid.Scan(small, 1); err != nil {
+			t.Errorf("Scan() error = %v", err)
+		}
+
+		os.Remove(seen)
+		if _, err := trid.Scan(large, 1); !errors.Is(err, ErrFileTooLarge) {
+			t.Errorf("Scan() error = %v, want ErrFileTooLarge", err)
+		}
+
+		if _, err := os.Stat(seen); !os.IsNotExist(err) {
+			t.Errorf("Scan() ran TrID on a file above the limit")
+		}
+
+		results, err := trid.ScanBatch(context.Background(), []string{large}, 1)
+		if err != nil || !errors.Is(results[0].Err, ErrFileTooLarge) {
+			t.Errorf("ScanBatch() = %+v, %v, want ErrFileTooLarge", results, err)
+		}
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		trid := NewTrid(Options{Cmd: cmd, MaxFileSize: 100, Oversize: OversizeTruncate})
+
+		if _, err := trid.Scan(large, 1); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		path, size := readSeen(t, seen)
+		if path == large || size != "100" {
+			t.Errorf("TrID identified %s of %s bytes, want a 100 byte copy", path, size)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("temporary copy %s was not removed", path)
+		}
+	})
+}
+
+func TestScanEmptyFile(t *testing.T) {
+	cmd, calls := newStub(t, unknownOutput)
+	trid := NewTrid(Options{Cmd: cmd})
+
+	path := filepath.Join(t.TempDir(), "empty.bin")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trid.Scan(path, 1); !errors.Is(err, ErrEmptyFile) {
+		t.Errorf("Scan() error = %v, want ErrEmptyFile", err)
+	}
+
+	results, err := trid.ScanBatch(context.Background(), []string{path}, 1)
+	if err != nil || !errors.Is(results[0].Err, ErrEmptyFile) {
+		t.Errorf("ScanBatch() = %+v, %v, want ErrEmptyFile", results, err)
+	}
+
+	if n := calls(); n != 0 {
+		t.Errorf("TrID was run %d times for an empty file", n)
+	}
+}
+
+func TestLocalCopy(t *testing.T) {
+	cmd, seen := newRecordingStub(t)
+	dir := t.TempDir()
+	copies := t.TempDir()
+
+	local := filepath.Join(dir, "local.pdf")
+	remote := filepath.Join(dir, "remote.pdf")
+	for _, path := range []string{local, remote} {
+		if err := os.WriteFile(path, []byte("%PDF-1.4"+strings.Repeat("x", 100)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		copy         LocalCopy
+		path         string
+		expectedCopy bool
+		expectedSize string
+	}{
+		{"Disabled", LocalCopy{}, remote, false, "108"},
+		{"Whole file", LocalCopy{Enabled: true, Dir: copies}, remote, true, "108"},
+		{"Head", LocalCopy{Enabled: true, Dir: copies, HeadSize: 16}, remote, true, "16"},
+		{"Not matched", LocalCopy{Enabled: true, Dir: copies, Match: func(p string) bool { return p == remote }}, local, false, "108"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTrid(Options{Cmd: cmd, LocalCopy: tt.copy}).Scan(tt.path, 1); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			path, size := readSeen(t, seen)
+			if (filepath.Dir(path) == copies) != tt.expectedCopy || size != tt.expectedSize {
+				t.Errorf("TrID identified %s of %s bytes, want copy %v of %s bytes", path, size, tt.expectedCopy, tt.expectedSize)
+			}
+
+			if entries, _ := os.ReadDir(copies); len(entries) != 0 {
+				t.Errorf("local copies were not removed: %v", entries)
+			}
+		})
+	}
+}
+
+func TestLocalCopyBatch(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	copies := t.TempDir()
+	paths := writeFiles(t, "%PDF-1.4", "%PDF-1.5", "%PDF-1.6", "%PDF-1.7", "%PDF-2.0")
+
+	// Only the copies of the current window exist while it is identified
+	most := 0
+	trid := NewTrid(Options{Cmd: cmd, BatchSize: 2, LocalCopy: LocalCopy{Enabled: true, Dir: copies}, Progress: func(Progress) {
+		entries, _ := os.ReadDir(copies)
+		most = max(most, len(entries))
+	}})
+
+	results, err := trid.ScanBatch(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	for _, r := range results {
+		if r.Err != nil || len(r.FileTypes) != 1 {
+			t.Errorf("ScanBatch() result = %+v", r)
+		}
+	}
+
+	if most != 2 {
+		t.Errorf("%d local copies existed at once, want 2", most)
+	}
+
+	if entries, _ := os.ReadDir(copies); len(entries) != 0 {
+		t.Errorf("local copies were not removed: %v", entries)
+	}
+}
+
+func TestTimeoutPerMB(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	large := filepath.Join(dir, "large")
+	if err := os.WriteFile(small, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, make([]byte, 3<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		perMB    time.Duration
+		paths    []string
+		expected time.Duration
+	}{
+		{"Flat", 0, []string{large}, time.Second},
+		{"Small file", time.Second, []string{small}, time.Second + time.Second/(1<<20)},
+		{"Large file", time.Second, []string{large}, 4 * time.Second},
+		{"Batch", time.Second, []string{large, large}, 7 * time.Second},
+		{"Missing file", time.Second, []string{filepath.Join(dir, "missing")}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(Options{Cmd: "trid", Timeout: time.Second, TimeoutPerMB: tt.perMB})
+			if got := trid.timeout(tt.paths...); got != tt.expected {
+				t.Errorf("timeout() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
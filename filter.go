@@ -0,0 +1,43 @@
+package trid
+
+import "context"
+
+// minProbabilityKey is the context key of WithMinProbability.
+type minProbabilityKey struct{}
+
+// WithMinProbability returns a copy of ctx that overrides
+// Options.MinProbability for scans using it.
+func WithMinProbability(ctx context.Context, p float64) context.Context {
+	return context.WithValue(ctx, minProbabilityKey{}, p)
+}
+
+// minProbability returns the minimum probability of matches for scans using
+// ctx.
+func (t *Trid) minProbability(ctx context.Context) float64 {
+	if p, ok := ctx.Value(minProbabilityKey{}).(float64); ok {
+		return p
+	}
+
+	return t.options.MinProbability
+}
+
+// refine drops the matches of a scan below the minimum probability or
+// rejected by Options.ResultFilter. It returns ErrUnknownFileType if no match
+// remains. Filtering is applied to results after caching, so that the cache
+// can serve scans with any minimum.
+func (t *Trid) refine(ctx context.Context, fileTypes []FileType) ([]FileType, error) {
+	minimum := t.minProbability(ctx)
+	if minimum <= 0 && t.options.ResultFilter == nil {
+		return fileTypes, nil
+	}
+
+	kept := Results(fileTypes).filter(func(f FileType) bool {
+		return f.Probability >= minimum && (t.options.ResultFilter == nil || t.options.ResultFilter(f))
+	})
+
+	if len(kept) == 0 {
+		return nil, ErrUnknownFileType
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,80 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const mixedOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: sample.bin
+ 62.5% (.ZIP) ZIP compressed archive (5000/1)
+ 25.0% (.JAR) Java Archive (2000/1)
+ 12.5% (.BIN) Generic binary (1000/1)
+`
+
+func TestMinProbability(t *testing.T) {
+	cmd, _ := newStub(t, mixedOutput)
+
+	tests := []struct {
+		name     string
+		options  float64
+		override float64
+		expected int
+		err      error
+	}{
+		{"Disabled", 0, -1, 3, nil},
+		{"Option", 20, -1, 2, nil},
+		{"Override", 20, 60, 1, nil},
+		{"Override disabling", 20, 0, 3, nil},
+		{"Nothing left", 70, -1, 0, ErrUnknownFileType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(Options{Cmd: cmd, MinProbability: tt.options})
+
+			ctx := context.Background()
+			if tt.override >= 0 {
+				ctx = WithMinProbability(ctx, tt.override)
+			}
+
+			fileTypes, err := trid.ScanContext(ctx, "testdata/sample.pdf", 5)
+			if !errors.Is(err, tt.err) || len(fileTypes) != tt.expected {
+				t.Errorf("ScanContext() = %d matches, %v, want %d, %v", len(fileTypes), err, tt.expected, tt.err)
+			}
+
+			results, err := trid.ScanBatch(ctx, []string{"testdata/sample.pdf"}, 5)
+			if err != nil || !errors.Is(results[0].Err, tt.err) || len(results[0].FileTypes) != tt.expected {
+				t.Errorf("ScanBatch() = %+v, %v, want %d matches, %v", results, err, tt.expected, tt.err)
+			}
+		})
+	}
+}
+
+func TestResultFilter(t *testing.T) {
+	cmd, _ := newStub(t, mixedOutput)
+
+	notGeneric := func(f FileType) bool { return !strings.HasPrefix(f.Name, "Generic") }
+
+	trid := NewTrid(Options{Cmd: cmd, ResultFilter: notGeneric})
+	fileTypes, err := trid.Scan("testdata/sample.pdf", 5)
+	if err != nil || len(fileTypes) != 2 || fileTypes[1].Extension != ".jar" {
+		t.Errorf("Scan() = %+v, %v, want the ZIP and JAR matches", fileTypes, err)
+	}
+
+	trid = NewTrid(Options{Cmd: cmd, ResultFilter: notGeneric, MinProbability: 50})
+	if fileTypes, err := trid.Scan("testdata/sample.pdf", 5); err != nil || len(fileTypes) != 1 {
+		t.Errorf("Scan() = %+v, %v, want the ZIP match", fileTypes, err)
+	}
+
+	trid = NewTrid(Options{Cmd: cmd, ResultFilter: func(FileType) bool { return false }})
+	if _, err := trid.Scan("testdata/sample.pdf", 5); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("Scan() error = %v, want ErrUnknownFileType", err)
+	}
+}
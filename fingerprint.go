@@ -0,0 +1,86 @@
+package trid
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid/defs"
+)
+
+// Purger is implemented by caches that can remove all of their entries. When
+// the definitions fingerprint changes, Trid purges a cache implementing it,
+// since entries computed with the old definitions can no longer be reached.
+type Purger interface {
+	Purge(ctx context.Context) error
+}
+
+// fingerprint memoizes the checksum of the definitions package, recomputing it
+// only when the file is replaced or its size or modification time changes.
+type fingerprint struct {
+	mu      sync.Mutex
+	path    string
+	file    os.FileInfo // Identifies the file, to notice it being replaced.
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// DefinitionsFingerprint returns the SHA-256 checksum of the definitions
+// package used by TrID, or with the native engine, of the XML definitions it
+// loaded. Cached results are keyed by this fingerprint, so updating the
+// definitions invalidates them.
+func (t *Trid) DefinitionsFingerprint() (string, error) {
+	return t.definitionsFingerprint(context.Background())
+}
+
+// definitionsFingerprint returns the checksum of the definitions package,
+// purging the cache if it changed since the previous call.
+func (t *Trid) definitionsFingerprint(ctx context.Context) (string, error) {
+	if t.options.Engine == EngineNative {
+		// The XML definitions are loaded once, by NewTrid
+		if t.native == nil {
+			return "", t.optsErr
+		}
+
+		return t.native.Fingerprint(), nil
+	}
+
+	path, err := defs.Locate(t.options.Cmd, t.options.Definitions)
+	if err != nil {
+		return "", err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNoDefinitions
+		}
+
+		return "", err
+	}
+
+	fp := &t.fingerprint
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	if fp.path == path && os.SameFile(fp.file, fi) && fp.size == fi.Size() && fp.modTime.Equal(fi.ModTime()) {
+		return fp.sum, nil
+	}
+
+	info, err := defs.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if fp.sum != "" && fp.sum != info.SHA256 {
+		if p, ok := t.options.Cache.(Purger); ok {
+			p.Purge(ctx)
+		}
+	}
+
+	fp.path, fp.file, fp.size, fp.modTime, fp.sum = path, fi, fi.Size(), fi.ModTime(), info.SHA256
+
+	return fp.sum, nil
+}
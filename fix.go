@@ -0,0 +1,118 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoExtension is returned by FixExtension when the best matching file type
+// has no extension.
+var ErrNoExtension = errors.New("identified file type has no extension")
+
+// FixOptions configures FixExtension.
+type FixOptions struct {
+	Replace bool // Replace the current extension instead of appending to it (TrID's -ce rather than -ae).
+	UseTrid bool // Let TrID rename the file instead of renaming it in Go.
+	DryRun  bool // Compute the new path without renaming the file.
+}
+
+// Rename describes the renaming of a file to its identified extension.
+type Rename struct {
+	OldPath  string   `json:"old_path"`  // Path of the file before renaming.
+	NewPath  string   `json:"new_path"`  // Path of the file after renaming; equal to OldPath if it already had the extension.
+	FileType FileType `json:"file_type"` // Best matching file type, which the extension was taken from.
+}
+
+// Changed reports whether the file is renamed.
+func (r Rename) Changed() bool {
+	return r.OldPath != r.NewPath
+}
+
+// FixExtension renames the file at path to carry the extension of its best
+// matching file type, like TrID's -ae and -ce options. Files that already have
+// the extension are left alone, and existing files are never overwritten. With
+// DryRun, the file is identified but not renamed.
+func (t *Trid) FixExtension(path string, opts FixOptions) (Rename, error) {
+	return t.FixExtensionContext(context.Background(), path, opts)
+}
+
+// FixExtensionContext is like FixExtension but stops the TrID process when
+// the context is cancelled.
+func (t *Trid) FixExtensionContext(ctx context.Context, path string, opts FixOptions) (Rename, error) {
+	_, release, err := t.checkScanArgs(path, 1)
+	if err != nil {
+		return Rename{}, err
+	}
+	release()
+
+	var r *Report
+	if opts.UseTrid && !opts.DryRun {
+		flag := "-ae"
+		if opts.Replace {
+			flag = "-ce"
+		}
+
+		r, err = t.run(ctx, path, 1, flag)
+	} else {
+		r, err = t.ScanReport(ctx, path, 1)
+	}
+
+	if err != nil {
+		return Rename{}, err
+	}
+
+	if len(r.FileTypes) == 0 {
+		return Rename{}, ErrUnknownFileType
+	}
+
+	rename, err := proposeRename(path, r.FileTypes[0], opts.Replace)
+	if err != nil || !rename.Changed() || opts.DryRun {
+		return rename, err
+	}
+
+	if opts.UseTrid {
+		if _, err := os.Stat(rename.NewPath); err != nil {
+			return rename, fmt.Errorf("TrID did not rename %s: %w", path, err)
+		}
+
+		return rename, nil
+	}
+
+	if _, err := os.Lstat(rename.NewPath); err == nil {
+		return rename, fmt.Errorf("renaming %s: %w", path, os.ErrExist)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return rename, err
+	}
+
+	return rename, os.Rename(rename.OldPath, rename.NewPath)
+}
+
+// proposeRename returns the renaming of path to the extension of ft. With
+// replace, the current extension of path is replaced; otherwise the new
+// extension is appended unless path already has it.
+func proposeRename(path string, ft FileType, replace bool) (Rename, error) {
+	rename := Rename{OldPath: path, NewPath: path, FileType: ft}
+
+	// Alternative extensions are listed as ".JPG/JPEG"; the first is preferred
+	ext, _, _ := strings.Cut(ft.Extension, "/")
+	if ext == "" || ext == "." {
+		return rename, fmt.Errorf("%w: %s", ErrNoExtension, ft.Name)
+	}
+
+	current := filepath.Ext(path)
+	if strings.EqualFold(current, ext) {
+		return rename, nil
+	}
+
+	if replace {
+		rename.NewPath = strings.TrimSuffix(path, current) + ext
+	} else {
+		rename.NewPath = path + ext
+	}
+
+	return rename, nil
+}
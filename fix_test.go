@@ -0,0 +1,138 @@
+package trid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFixExtension(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+	trid := NewTrid(Options{Cmd: cmd})
+
+	tests := []struct {
+		name     string
+		file     string
+		opts     FixOptions
+		expected string
+		renamed  bool
+	}{
+		{"Append", "report.bin", FixOptions{}, "report.bin.pdf", true},
+		{"Replace", "report.bin", FixOptions{Replace: true}, "report.pdf", true},
+		{"Missing extension", "report", FixOptions{Replace: true}, "report.pdf", true},
+		{"Already correct", "report.PDF", FixOptions{}, "report.PDF", false},
+		{"Dry run", "report.bin", FixOptions{DryRun: true}, "report.bin.pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := trid.FixExtension(path, tt.opts)
+			if err != nil {
+				t.Fatalf("FixExtension() error = %v", err)
+			}
+
+			if expected := filepath.Join(dir, tt.expected); r.OldPath != path || r.NewPath != expected {
+				t.Errorf("FixExtension() = %s -> %s, want %s -> %s", r.OldPath, r.NewPath, path, expected)
+			}
+
+			if _, err := os.Stat(path); (err == nil) == tt.renamed {
+				t.Errorf("FixExtension() renamed = %v, want %v", err != nil, tt.renamed)
+			}
+		})
+	}
+}
+
+func TestFixExtensionExisting(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+	trid := NewTrid(Options{Cmd: cmd})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.bin")
+	for _, p := range []string{path, path + ".pdf"} {
+		if err := os.WriteFile(p, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := trid.FixExtension(path, FixOptions{}); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("FixExtension() error = %v, want os.ErrExist", err)
+	}
+
+	if b, _ := os.ReadFile(path + ".pdf"); string(b) != path+".pdf" {
+		t.Errorf("FixExtension() overwrote the existing file")
+	}
+}
+
+func TestFixExtensionNoMatches(t *testing.T) {
+	cmd, _ := newStub(t, `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: report.bin
+`)
+	trid := NewTrid(Options{Cmd: cmd})
+
+	path := filepath.Join(t.TempDir(), "report.bin")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trid.FixExtension(path, FixOptions{}); !errors.Is(err, ErrUnknownFileType) {
+		t.Fatalf("FixExtension() error = %v, want %v", err, ErrUnknownFileType)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("FixExtension() renamed the file: %v", err)
+	}
+}
+
+func TestFixExtensionUseTrid(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	// The stub renames its last argument when asked to with -ae
+	dir := t.TempDir()
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\n" +
+		"for f; do :; done\n" +
+		"case \" $* \" in *\" -ae \"*) mv \"$f\" \"$f.pdf\";; esac\n" +
+		"cat <<'EOF'\n" + pdfOutput + "EOF\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "report.bin")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewTrid(Options{Cmd: cmd}).FixExtension(path, FixOptions{UseTrid: true})
+	if err != nil {
+		t.Fatalf("FixExtension() error = %v", err)
+	}
+
+	if _, err := os.Stat(r.NewPath); err != nil || r.NewPath != path+".pdf" {
+		t.Errorf("FixExtension() = %s, %v, want %s", r.NewPath, err, path+".pdf")
+	}
+}
+
+func TestProposeRename(t *testing.T) {
+	r, err := proposeRename("photo", FileType{Extension: ".jpg/jpeg", Name: "JPEG"}, false)
+	if err != nil || r.NewPath != "photo.jpg" {
+		t.Errorf("proposeRename() = %s, %v, want photo.jpg", r.NewPath, err)
+	}
+
+	if _, err := proposeRename("data", FileType{Extension: ".", Name: "Text"}, false); !errors.Is(err, ErrNoExtension) {
+		t.Errorf("proposeRename() error = %v, want ErrNoExtension", err)
+	}
+}
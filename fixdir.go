@@ -0,0 +1,145 @@
+package trid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrRenameConflict is returned by ApplyRenames when a file cannot be renamed
+// without overwriting another file.
+var ErrRenameConflict = errors.New("rename conflict")
+
+// PlanFixes identifies the files Walk finds in the tree rooted at root and
+// returns the renames needed to give each of them the extension of its best
+// match, as FixExtension would. Only opts.Replace is used. Files that cannot be
+// identified, already have the right extension, or whose new path is taken are
+// left out of the plan. Nothing is renamed; pass the plan to ApplyRenames.
+func (t *Trid) PlanFixes(ctx context.Context, root string, opts FixOptions) ([]Rename, error) {
+	paths, err := Walk(root, t.options.Symlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := t.ScanBatch(ctx, paths, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]Rename, 0)
+	taken := make(map[string]bool)
+	for _, r := range results {
+		if r.Err != nil || len(r.FileTypes) == 0 {
+			continue
+		}
+
+		rename, err := proposeRename(r.Path, r.FileTypes[0], opts.Replace)
+		if err != nil || !rename.Changed() || taken[rename.NewPath] {
+			continue
+		}
+
+		if _, err := os.Lstat(rename.NewPath); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		taken[rename.NewPath] = true
+		plan = append(plan, rename)
+	}
+
+	return plan, nil
+}
+
+// ApplyRenames performs the renames of a plan as a unit. Before renaming
+// anything, it checks that no file would be overwritten and writes the plan to
+// undoLog, so it can be reverted with UndoRenames even if the process is
+// interrupted. If a rename fails, those already performed are reverted.
+func ApplyRenames(renames []Rename, undoLog string) error {
+	targets := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		if targets[r.NewPath] {
+			return fmt.Errorf("%w: %s is the target of several renames", ErrRenameConflict, r.NewPath)
+		}
+		targets[r.NewPath] = true
+
+		if _, err := os.Lstat(r.NewPath); !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s already exists", ErrRenameConflict, r.NewPath)
+		}
+	}
+
+	if err := writeUndoLog(undoLog, renames); err != nil {
+		return err
+	}
+
+	for i, r := range renames {
+		if err := os.Rename(r.OldPath, r.NewPath); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				os.Rename(renames[j].NewPath, renames[j].OldPath)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UndoRenames reverts the renames recorded in undoLog by ApplyRenames, in
+// reverse order. Renames that were not performed are skipped.
+func UndoRenames(undoLog string) error {
+	b, err := os.ReadFile(undoLog)
+	if err != nil {
+		return err
+	}
+
+	var renames []Rename
+	if err := json.Unmarshal(b, &renames); err != nil {
+		return fmt.Errorf("reading undo log %s: %w", undoLog, err)
+	}
+
+	var errs []error
+	for i := len(renames) - 1; i >= 0; i-- {
+		r := renames[i]
+		if _, err := os.Lstat(r.NewPath); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if _, err := os.Lstat(r.OldPath); err == nil {
+			errs = append(errs, fmt.Errorf("%w: %s already exists", ErrRenameConflict, r.OldPath))
+			continue
+		}
+
+		if err := os.Rename(r.NewPath, r.OldPath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeUndoLog atomically writes renames to path.
+func writeUndoLog(path string, renames []Rename) error {
+	b, err := json.MarshalIndent(renames, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".trid-undo-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(b, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
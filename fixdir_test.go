@@ -0,0 +1,84 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanApplyUndo(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	trid := NewTrid(Options{Cmd: cmd})
+
+	paths := writeFiles(t, "%PDF-1", "binary", "%PDF-2")
+	dir := filepath.Dir(paths[0])
+
+	// file2 cannot be renamed without overwriting another file
+	if err := os.WriteFile(paths[2]+".pdf", []byte("%PDF-3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := trid.PlanFixes(context.Background(), dir, FixOptions{})
+	if err != nil {
+		t.Fatalf("PlanFixes() error = %v", err)
+	}
+
+	if len(plan) != 1 || plan[0].OldPath != paths[0] || plan[0].NewPath != paths[0]+".pdf" {
+		t.Fatalf("PlanFixes() = %+v, want %s renamed", plan, paths[0])
+	}
+
+	undoLog := filepath.Join(t.TempDir(), "undo.json")
+	if err := ApplyRenames(plan, undoLog); err != nil {
+		t.Fatalf("ApplyRenames() error = %v", err)
+	}
+
+	if _, err := os.Stat(paths[0] + ".pdf"); err != nil {
+		t.Errorf("ApplyRenames() did not rename: %v", err)
+	}
+
+	if err := UndoRenames(undoLog); err != nil {
+		t.Fatalf("UndoRenames() error = %v", err)
+	}
+
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Errorf("UndoRenames() did not restore: %v", err)
+	}
+}
+
+func TestApplyRenamesConflict(t *testing.T) {
+	paths := writeFiles(t, "a", "b")
+	undoLog := filepath.Join(t.TempDir(), "undo.json")
+
+	renames := []Rename{{OldPath: paths[0], NewPath: paths[1]}}
+	if err := ApplyRenames(renames, undoLog); !errors.Is(err, ErrRenameConflict) {
+		t.Fatalf("ApplyRenames() error = %v, want ErrRenameConflict", err)
+	}
+
+	if _, err := os.Stat(undoLog); !os.IsNotExist(err) {
+		t.Errorf("ApplyRenames() wrote an undo log for a rejected plan")
+	}
+}
+
+func TestApplyRenamesRollback(t *testing.T) {
+	paths := writeFiles(t, "a")
+	undoLog := filepath.Join(t.TempDir(), "undo.json")
+
+	renames := []Rename{
+		{OldPath: paths[0], NewPath: paths[0] + ".txt"},
+		{OldPath: paths[0] + ".missing", NewPath: paths[0] + ".bin"},
+	}
+	if err := ApplyRenames(renames, undoLog); err == nil {
+		t.Fatal("ApplyRenames() error = nil, want an error")
+	}
+
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Errorf("ApplyRenames() did not revert the first rename: %v", err)
+	}
+
+	// Undoing an interrupted plan skips the renames that were not performed
+	if err := UndoRenames(undoLog); err != nil {
+		t.Errorf("UndoRenames() error = %v", err)
+	}
+}
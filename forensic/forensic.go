@@ -0,0 +1,249 @@
+// Package forensic identifies the files stored in forensic images, such as
+// EWF (E01) or AFF images, with TrID, reading only the head of large files so
+// that the image does not need to be extracted.
+//
+// The package does not decode image formats itself: an Image is supplied by
+// the caller, typically by combining a reader for the container, such as
+// libewf bindings, with a file system parser. File systems exposed as an
+// fs.FS can be adapted with FSImage.
+package forensic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrStop can be returned by a ScanImage callback to stop scanning without
+// reporting an error.
+var ErrStop = errors.New("stop scanning")
+
+// File describes a file stored in an image.
+type File struct {
+	Path    string    // Path of the file in the image, with slashes.
+	Size    int64     // Size of the file in bytes.
+	ModTime time.Time // Last modification time of the file, if known.
+	ID      string    // Identifier of the file in the image, such as its inode or MFT entry number, if any.
+}
+
+// Image is an opened forensic image. Implementations adapt readers of
+// container formats and file systems.
+type Image interface {
+	// Walk calls fn for every regular file of the image. Walking stops at
+	// the first error returned by fn.
+	Walk(ctx context.Context, fn func(File) error) error
+
+	// Open returns the contents of the file f, as passed to a Walk callback.
+	Open(ctx context.Context, f File) (io.ReadCloser, error)
+}
+
+// Scanner identifies files. *trid.Trid satisfies this interface.
+type Scanner interface {
+	ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error)
+}
+
+// Options configures an Adapter.
+type Options struct {
+	NumberOfMatches int    // Number of matches to request per file (default: 5).
+	HeadSize        int64  // Number of leading bytes read from large files (default: 1 MB).
+	MaxFullSize     int64  // Files up to this size are read completely (default: HeadSize).
+	MaxConcurrent   int    // Maximum number of files identified concurrently by ScanImage (default: 4).
+	TempDir         string // Directory for temporary files (default: os.TempDir()).
+}
+
+// Result is the identification result of a file of an image.
+type Result struct {
+	File      File            // The identified file.
+	Partial   bool            // Whether only the head of the file was read.
+	FileTypes []trid.FileType // Identified file types.
+	Err       error           // Error if the identification failed.
+}
+
+// Adapter identifies the files of a forensic image.
+type Adapter struct {
+	image   Image
+	scanner Scanner
+	opts    Options
+}
+
+// New returns an Adapter reading files from image and identifying them with
+// s.
+func New(image Image, s Scanner, opts Options) *Adapter {
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	if opts.HeadSize <= 0 {
+		opts.HeadSize = 1 << 20
+	}
+
+	if opts.MaxFullSize < opts.HeadSize {
+		opts.MaxFullSize = opts.HeadSize
+	}
+
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 4
+	}
+
+	return &Adapter{image: image, scanner: s, opts: opts}
+}
+
+// ScanFile copies f, or its head if it is larger than MaxFullSize, to a
+// temporary file and identifies it.
+func (a *Adapter) ScanFile(ctx context.Context, f File) Result {
+	r := Result{File: f}
+
+	length := int64(-1)
+	if f.Size > a.opts.MaxFullSize {
+		length = a.opts.HeadSize
+		r.Partial = true
+	}
+
+	tmp, err := a.copy(ctx, f, length)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer os.Remove(tmp)
+
+	r.FileTypes, r.Err = a.scanner.ScanContext(ctx, tmp, a.opts.NumberOfMatches)
+
+	return r
+}
+
+// ScanImage identifies every file of the image, calling fn with each result.
+// Results are delivered in completion order and fn is never called
+// concurrently. Scanning stops at the first error returned by fn; returning
+// ErrStop stops without an error.
+func (a *Adapter) ScanImage(ctx context.Context, fn func(Result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		fnErr error
+		sem   = make(chan struct{}, a.opts.MaxConcurrent)
+	)
+
+	walkErr := a.image.Walk(ctx, func(f File) error {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r := a.ScanFile(ctx, f)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if fnErr != nil {
+				return
+			}
+
+			if err := fn(r); err != nil {
+				fnErr = err
+				cancel()
+			}
+		}()
+
+		return nil
+	})
+
+	wg.Wait()
+
+	if fnErr != nil {
+		if errors.Is(fnErr, ErrStop) {
+			return nil
+		}
+
+		return fnErr
+	}
+
+	return walkErr
+}
+
+// copy copies length bytes of f (all if negative) to a temporary file, keeping
+// its extension, and returns its path.
+func (a *Adapter) copy(ctx context.Context, f File, length int64) (string, error) {
+	body, err := a.image.Open(ctx, f)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(a.opts.TempDir, "trid-forensic-*"+path.Ext(f.Path))
+	if err != nil {
+		return "", err
+	}
+
+	var src io.Reader = body
+	if length >= 0 {
+		src = io.LimitReader(body, length)
+	}
+
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// fsImage is the Image returned by FSImage.
+type fsImage struct {
+	fsys fs.FS
+}
+
+// FSImage returns an Image of the file system fsys, such as a file system of
+// a forensic image exposed as an fs.FS by its parser.
+func FSImage(fsys fs.FS) Image {
+	return fsImage{fsys: fsys}
+}
+
+// Walk implements Image.
+func (i fsImage) Walk(ctx context.Context, fn func(File) error) error {
+	return fs.WalkDir(i.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return fn(File{Path: p, Size: fi.Size(), ModTime: fi.ModTime()})
+	})
+}
+
+// Open implements Image.
+func (i fsImage) Open(ctx context.Context, f File) (io.ReadCloser, error) {
+	return i.fsys.Open(f.Path)
+}
@@ -0,0 +1,96 @@
+package forensic
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/attilabuti/trid"
+)
+
+// sizeScanner reports the size and extension of the scanned file as its
+// match.
+type sizeScanner struct{}
+
+func (sizeScanner) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return []trid.FileType{{Extension: filepath.Ext(filePath), Name: strings.Repeat("x", int(fi.Size()))}}, nil
+}
+
+// image is a test file system.
+var image = fstest.MapFS{
+	"Users/alice/report.pdf": {Data: []byte("12345")},
+	"Windows/big.dll":        {Data: []byte(strings.Repeat("z", 100))},
+	"Users/alice":            {Mode: os.ModeDir},
+}
+
+func TestScanFile(t *testing.T) {
+	a := New(FSImage(image), sizeScanner{}, Options{HeadSize: 10, MaxFullSize: 50})
+
+	tests := []struct {
+		path    string
+		partial bool
+		size    int
+	}{
+		{"Users/alice/report.pdf", false, 5},
+		{"Windows/big.dll", true, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r := a.ScanFile(context.Background(), File{Path: tt.path, Size: int64(len(image[tt.path].Data))})
+			if r.Err != nil {
+				t.Fatalf("ScanFile() error = %v", r.Err)
+			}
+
+			if r.Partial != tt.partial || len(r.FileTypes[0].Name) != tt.size || r.FileTypes[0].Extension != filepath.Ext(tt.path) {
+				t.Errorf("ScanFile() = %+v, want partial %v and %d bytes", r, tt.partial, tt.size)
+			}
+		})
+	}
+
+	if r := a.ScanFile(context.Background(), File{Path: "missing"}); r.Err == nil {
+		t.Error("ScanFile() of a missing file succeeded")
+	}
+}
+
+func TestScanImage(t *testing.T) {
+	a := New(FSImage(image), sizeScanner{}, Options{})
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	err := a.ScanImage(context.Background(), func(r Result) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		paths = append(paths, r.File.Path)
+		return r.Err
+	})
+	if err != nil || len(paths) != 2 {
+		t.Errorf("ScanImage() = %v, %v, want both files", paths, err)
+	}
+
+	err = New(FSImage(image), sizeScanner{}, Options{MaxConcurrent: 1}).ScanImage(context.Background(), func(r Result) error {
+		return ErrStop
+	})
+	if err != nil {
+		t.Errorf("ScanImage() stopped with error = %v", err)
+	}
+
+	failure := errors.New("failure")
+	err = a.ScanImage(context.Background(), func(r Result) error { return failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("ScanImage() error = %v, want %v", err, failure)
+	}
+}
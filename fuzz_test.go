@@ -0,0 +1,111 @@
+package trid
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addOutputSeeds adds the recorded TrID outputs in testdata/output to the
+// seed corpus of f.
+func addOutputSeeds(f *testing.F) {
+	files, err := filepath.Glob("testdata/output/*.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, file := range files {
+		out, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(string(out))
+	}
+}
+
+func FuzzParseOutput(f *testing.F) {
+	addOutputSeeds(f)
+	f.Add(" 50.0% (.PDF PDF document\n")
+	f.Add("Collecting data from file: 1.0% (.X) Y\n 99.9% (.) Z (1/1)\n")
+
+	f.Fuzz(func(t *testing.T, out string) {
+		fileTypes, warnings, err := detectProfile(out).parse(out)
+		if err != nil {
+			return
+		}
+
+		// Every match line must be returned, reported as a warning or fail
+		// the parse, never be dropped
+		matches := 0
+		for _, line := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n") {
+			if classifyLine(line) == lineMatch {
+				matches++
+			}
+		}
+
+		malformed := 0
+		for _, w := range warnings {
+			if strings.HasPrefix(w, "TrID output line ") {
+				malformed++
+			}
+		}
+
+		if len(fileTypes)+malformed != matches {
+			t.Errorf("parse() returned %d file types and %d malformed lines for %d match lines", len(fileTypes), malformed, matches)
+		}
+
+		for i, ft := range fileTypes {
+			if ft.Probability < 0 || ft.Probability > 100 || ft.Name == "" {
+				t.Errorf("parse()[%d] = %+v", i, ft)
+			}
+
+			if i > 0 && ft.Probability > fileTypes[i-1].Probability {
+				t.Errorf("parse() not sorted by probability: %+v", fileTypes)
+			}
+		}
+	})
+}
+
+func FuzzCheckTridError(f *testing.F) {
+	addOutputSeeds(f)
+	f.Add("Error: found no file(s) to analyze!")
+	f.Add("Def package triddefs.trd is empty!")
+
+	f.Fuzz(func(t *testing.T, out string) {
+		err := checkTridError(out)
+
+		// A file type listed in the output must never be mistaken for an
+		// error, unless TrID also reported one
+		fileTypes, parseErr := parseOutput(out)
+		if err != nil && parseErr == nil && len(fileTypes) > 0 && !strings.Contains(out, "!") {
+			t.Errorf("checkTridError() = %v for output with matches %+v", err, fileTypes)
+		}
+	})
+}
+
+func FuzzSplitOutput(f *testing.F) {
+	f.Add("sample.pdf")
+	f.Add("./-n:5")
+	f.Add(" 50.0% (.PDF) name.pdf")
+	f.Add("File: nested")
+
+	f.Fuzz(func(t *testing.T, name string) {
+		// TrID cannot be given names with line breaks or surrounding space
+		if name != strings.TrimSpace(name) || strings.ContainsAny(name, "\r\n") || name == "" {
+			return
+		}
+
+		out := "TrID/32 - File Identifier v2.24\n\nFile: " + name + "\n 100.0% (.PDF) Adobe PDF (1/1)\n"
+
+		_, sections := splitOutput(out)
+		if len(sections) != 1 || sections[0].path != name {
+			t.Fatalf("splitOutput() sections = %+v, want one for %q", sections, name)
+		}
+
+		if fileTypes, err := parseOutput(sections[0].body); err != nil || len(fileTypes) != 1 {
+			t.Errorf("parseOutput() of section = %+v, %v", fileTypes, err)
+		}
+	})
+}
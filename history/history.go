@@ -0,0 +1,228 @@
+// Package history records scans in a SQL database, SQLite in particular, and
+// queries them by content hash, file type or time range, as a durable record of
+// what was identified and when.
+//
+// The package uses database/sql and no driver of its own: open the database
+// with a SQLite driver, such as modernc.org/sqlite or github.com/mattn/go-sqlite3,
+// and pass it to New.
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// schema creates the table of scans and its indexes, if they do not exist.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS trid_scans (
+		id INTEGER PRIMARY KEY,
+		path TEXT NOT NULL,
+		sha256 TEXT NOT NULL,
+		extension TEXT NOT NULL,
+		name TEXT NOT NULL,
+		file_types TEXT NOT NULL,
+		error TEXT NOT NULL,
+		defs TEXT NOT NULL,
+		scanned_at INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS trid_scans_sha256 ON trid_scans (sha256)`,
+	`CREATE INDEX IF NOT EXISTS trid_scans_extension ON trid_scans (extension, scanned_at)`,
+	`CREATE INDEX IF NOT EXISTS trid_scans_scanned_at ON trid_scans (scanned_at)`,
+}
+
+// Statements reading and writing scans.
+const (
+	insertScan = `INSERT INTO trid_scans (path, sha256, extension, name, file_types, error, defs, scanned_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	selectScans = `SELECT id, path, sha256, file_types, error, defs, scanned_at FROM trid_scans`
+)
+
+// Record is a scan recorded in a Store.
+type Record struct {
+	ID        int64           `json:"id"`              // Identifier assigned by the store.
+	Path      string          `json:"path"`            // Path of the file scanned.
+	SHA256    string          `json:"sha256"`          // Hex-encoded SHA-256 checksum of the file, or empty if it could not be read.
+	FileTypes []trid.FileType `json:"file_types"`      // Identified file types, most likely first.
+	Error     string          `json:"error,omitempty"` // Why the scan failed, if it did.
+	Defs      string          `json:"defs,omitempty"`  // Fingerprint of the definitions used (see Trid.DefinitionsFingerprint).
+	Time      time.Time       `json:"time"`            // When the scan completed.
+}
+
+// Query selects records. Zero fields select every record.
+type Query struct {
+	SHA256    string    // Checksum of the file.
+	Extension string    // Extension of the most likely file type (e.g., ".pdf"), regardless of case; any of the alternatives of a type such as ".jpg/jpeg" matches.
+	Since     time.Time // Earliest time of the scan, inclusive.
+	Until     time.Time // Latest time of the scan, exclusive.
+	Limit     int       // Maximum number of records returned, most recent first; 0 means no limit.
+}
+
+// Options configures a Store.
+type Options struct {
+	// OnError is called with the errors of the hook returned by Hook, which
+	// has no caller to return them to; nil ignores them.
+	OnError func(path string, err error)
+
+	// Now returns the time recorded for scans (default: time.Now).
+	Now func() time.Time
+}
+
+// Store records scans in a database.
+type Store struct {
+	db   *sql.DB
+	opts Options
+}
+
+// New returns a store recording scans in db, creating its table if needed.
+func New(ctx context.Context, db *sql.DB, opts Options) (*Store, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Store{db: db, opts: opts}, nil
+}
+
+// Add records r, setting its identifier, and its time if zero.
+func (s *Store) Add(ctx context.Context, r *Record) error {
+	if r.Time.IsZero() {
+		r.Time = s.opts.Now()
+	}
+
+	fileTypes, err := json.Marshal(r.FileTypes)
+	if err != nil {
+		return err
+	}
+
+	var ext, name string
+	if len(r.FileTypes) > 0 {
+		ext, name = strings.ToLower(r.FileTypes[0].Extension), r.FileTypes[0].Name
+	}
+
+	res, err := s.db.ExecContext(ctx, insertScan, r.Path, r.SHA256, ext, name, string(fileTypes), r.Error, r.Defs, r.Time.UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+// Find returns the records selected by q, most recent first.
+func (s *Store) Find(ctx context.Context, q Query) ([]Record, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if q.SHA256 != "" {
+		where, args = append(where, "sha256 = ?"), append(args, strings.ToLower(q.SHA256))
+	}
+
+	// Types may list alternative extensions (e.g., ".jpg/jpeg"), with or
+	// without their dots; match any of them
+	if q.Extension != "" {
+		where = append(where, "instr(replace('/' || extension || '/', '/.', '/'), ?) > 0")
+		args = append(args, "/"+strings.TrimPrefix(strings.ToLower(q.Extension), ".")+"/")
+	}
+
+	if !q.Since.IsZero() {
+		where, args = append(where, "scanned_at >= ?"), append(args, q.Since.UnixMilli())
+	}
+
+	if !q.Until.IsZero() {
+		where, args = append(where, "scanned_at < ?"), append(args, q.Until.UnixMilli())
+	}
+
+	query := selectScans
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY scanned_at DESC, id DESC"
+
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r         Record
+			fileTypes string
+			scannedAt int64
+		)
+
+		if err := rows.Scan(&r.ID, &r.Path, &r.SHA256, &fileTypes, &r.Error, &r.Defs, &scannedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(fileTypes), &r.FileTypes); err != nil {
+			return nil, err
+		}
+
+		r.Time = time.UnixMilli(scannedAt)
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// Hook returns a function recording each scan, for Hooks.OnScanComplete. The
+// file is read to compute its checksum, and defs, if not nil, is called for
+// the fingerprint of the definitions, typically Trid.DefinitionsFingerprint;
+// failures are left out of the record. Since scans wait for their hooks,
+// recording adds the time of a checksum and an insert to each scan.
+func (s *Store) Hook(defs func() (string, error)) func(path string, results []trid.FileType, err error) {
+	return func(path string, results []trid.FileType, scanErr error) {
+		r := &Record{Path: path, FileTypes: results, SHA256: fileSum(path)}
+		if scanErr != nil {
+			r.Error = scanErr.Error()
+		}
+
+		if defs != nil {
+			r.Defs, _ = defs()
+		}
+
+		if err := s.Add(context.Background(), r); err != nil && s.opts.OnError != nil {
+			s.opts.OnError(path, err)
+		}
+	}
+}
+
+// fileSum returns the hex-encoded SHA-256 checksum of the file at path, or an
+// empty string if it cannot be read.
+func fileSum(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,294 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// sqliteDriver is a database/sql driver running each statement through the
+// sqlite3 shell on the database file named by the data source, so that the
+// store is tested against SQLite itself without a Go driver. Arguments are
+// bound by replacing the placeholders with SQL literals.
+type sqliteDriver struct{}
+
+func (sqliteDriver) Open(name string) (driver.Conn, error) { return sqliteConn{name}, nil }
+
+type sqliteConn struct{ db string }
+
+func (c sqliteConn) Prepare(query string) (driver.Stmt, error) { return sqliteStmt{c.db, query}, nil }
+func (c sqliteConn) Close() error                              { return nil }
+func (c sqliteConn) Begin() (driver.Tx, error)                 { return nil, errors.New("transactions not supported") }
+
+type sqliteStmt struct {
+	db    string
+	query string
+}
+
+func (s sqliteStmt) Close() error  { return nil }
+func (s sqliteStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s sqliteStmt) Exec(args []driver.Value) (driver.Result, error) {
+	rows, err := s.run(args, "; SELECT last_insert_rowid()")
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	if len(rows) > 0 {
+		id, _ = rows[0][0].(int64)
+	}
+
+	return sqliteResult(id), nil
+}
+
+func (s sqliteStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.run(args, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqliteRows{rows: rows}, nil
+}
+
+// run executes the statement with args, followed by suffix, and returns the
+// rows output last.
+func (s sqliteStmt) run(args []driver.Value, suffix string) ([][]driver.Value, error) {
+	var query strings.Builder
+	for i, part := range strings.Split(s.query, "?") {
+		if i > 0 {
+			query.WriteString(literal(args[i-1]))
+		}
+		query.WriteString(part)
+	}
+
+	cmd := exec.Command("sqlite3", "-json", s.db)
+	cmd.Stdin = strings.NewReader(query.String() + suffix + ";\n")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil || stderr.Len() > 0 {
+		return nil, fmt.Errorf("sqlite3: %v: %s", err, stderr.String())
+	}
+
+	return decodeRows(out)
+}
+
+// literal returns v as an SQL literal.
+func literal(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	default:
+		panic(fmt.Sprintf("unsupported argument %T", v))
+	}
+}
+
+// decodeRows decodes the last JSON array of objects output by sqlite3 -json,
+// keeping the columns of each row in order.
+func decodeRows(out []byte) ([][]driver.Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+
+	var rows [][]driver.Value
+	for dec.More() {
+		rows = nil
+		if _, err := dec.Token(); err != nil { // [
+			return nil, err
+		}
+
+		for dec.More() {
+			var row []driver.Value
+			dec.Token() // {
+			for dec.More() {
+				dec.Token() // Column name
+				v, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				if n, ok := v.(json.Number); ok {
+					v, err = n.Int64()
+					if err != nil {
+						return nil, err
+					}
+				}
+				row = append(row, v)
+			}
+			dec.Token() // }
+			rows = append(rows, row)
+		}
+		dec.Token() // ]
+	}
+
+	return rows, nil
+}
+
+type sqliteResult int64
+
+func (r sqliteResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r sqliteResult) RowsAffected() (int64, error) { return 1, nil }
+
+type sqliteRows struct {
+	rows [][]driver.Value
+}
+
+func (r *sqliteRows) Columns() []string {
+	return []string{"id", "path", "sha256", "file_types", "error", "defs", "scanned_at"}
+}
+
+func (r *sqliteRows) Close() error { return nil }
+
+func (r *sqliteRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func init() {
+	sql.Register("sqlite3-shell", sqliteDriver{})
+}
+
+// openStore returns a store backed by a new SQLite database, skipping the
+// test if the sqlite3 shell is not installed.
+func openStore(t *testing.T, opts Options) *Store {
+	t.Helper()
+
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 shell not installed")
+	}
+
+	db, err := sql.Open("sqlite3-shell", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := New(context.Background(), db, opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return s
+}
+
+func TestFind(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := openStore(t, Options{})
+
+	records := []*Record{
+		{Path: "a.pdf", SHA256: "aa", FileTypes: []trid.FileType{{Extension: ".PDF", Name: "PDF", Probability: 90}}, Time: base},
+		{Path: "b.zip", SHA256: "bb", FileTypes: []trid.FileType{{Extension: ".zip", Name: "ZIP"}}, Time: base.Add(time.Hour)},
+		{Path: "c.pdf", SHA256: "aa", FileTypes: []trid.FileType{{Extension: ".pdf", Name: "PDF"}}, Time: base.Add(2 * time.Hour)},
+		{Path: "d.bin", SHA256: "dd", Error: "unknown file type", Time: base.Add(3 * time.Hour)},
+		{Path: "e.jpg", SHA256: "ee", FileTypes: []trid.FileType{{Extension: ".JPG/JPEG", Name: "JPEG"}}, Time: base.Add(4 * time.Hour)},
+		{Path: "f.tif", SHA256: "ff", FileTypes: []trid.FileType{{Extension: ".tif/.tiff", Name: "TIFF"}}, Time: base.Add(5 * time.Hour)},
+	}
+
+	for _, r := range records {
+		if err := s.Add(context.Background(), r); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	if records[3].ID != 4 {
+		t.Errorf("Add() id = %d, want 4", records[3].ID)
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{"All", Query{}, []string{"f.tif", "e.jpg", "d.bin", "c.pdf", "b.zip", "a.pdf"}},
+		{"By hash", Query{SHA256: "AA"}, []string{"c.pdf", "a.pdf"}},
+		{"By type", Query{Extension: ".pdf"}, []string{"c.pdf", "a.pdf"}},
+		{"By time range", Query{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"c.pdf", "b.zip"}},
+		{"Limited", Query{Limit: 1}, []string{"f.tif"}},
+		{"First alternative", Query{Extension: ".jpg"}, []string{"e.jpg"}},
+		{"Other alternative", Query{Extension: "JPEG"}, []string{"e.jpg"}},
+		{"Dotted alternative", Query{Extension: ".tiff"}, []string{"f.tif"}},
+		{"Partial extension", Query{Extension: ".jp"}, nil},
+		{"Combined", Query{Extension: ".PDF", Since: base.Add(time.Minute)}, []string{"c.pdf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := s.Find(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			var paths []string
+			for _, r := range found {
+				paths = append(paths, r.Path)
+			}
+
+			if !slices.Equal(paths, tt.expected) {
+				t.Errorf("Find() = %q, want %q", paths, tt.expected)
+			}
+		})
+	}
+
+	found, _ := s.Find(context.Background(), Query{SHA256: "aa", Until: base.Add(time.Minute)})
+	if len(found) != 1 || !found[0].Time.Equal(base) || found[0].FileTypes[0].Probability != 90 {
+		t.Errorf("Find() = %+v, want a.pdf as recorded", found)
+	}
+}
+
+func TestHook(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := openStore(t, Options{Now: func() time.Time { return now }})
+
+	path := filepath.Join(t.TempDir(), "sample.txt")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := s.Hook(func() (string, error) { return "defs-sum", nil })
+	hook(path, []trid.FileType{{Extension: ".txt", Name: "Text"}}, nil)
+	hook(filepath.Join(t.TempDir(), "missing"), nil, trid.ErrFileNotFound)
+
+	found, err := s.Find(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("Find() returned %d records, want 2", len(found))
+	}
+
+	r := found[1]
+	if r.SHA256 != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" || r.Defs != "defs-sum" || !r.Time.Equal(now) || r.FileTypes[0].Name != "Text" {
+		t.Errorf("Hook() recorded %+v", r)
+	}
+
+	if r := found[0]; r.SHA256 != "" || r.Error != trid.ErrFileNotFound.Error() || len(r.FileTypes) != 0 {
+		t.Errorf("Hook() recorded %+v for a missing file", r)
+	}
+}
@@ -0,0 +1,53 @@
+package trid
+
+import "time"
+
+// Hooks are callbacks invoked during scans, for auditing, quota accounting or
+// debugging. Nil callbacks are skipped. Callbacks may be called concurrently
+// and should return quickly, as scans wait for them.
+type Hooks struct {
+	// OnScanStart is called with the path of each file before it is
+	// identified, including files of a batch.
+	OnScanStart func(path string)
+
+	// OnScanComplete is called with the path and result of each file once it
+	// has been identified or has failed.
+	OnScanComplete func(path string, results []FileType, err error)
+
+	// OnProcessSpawn is called with the command line of each TrID process
+	// before it is started.
+	OnProcessSpawn func(cmdline []string)
+
+	// OnStaleDefinitions is called with the path and age of the definitions
+	// when a scan finds them older than Options.MaxDefsAge, at most once per
+	// hour.
+	OnStaleDefinitions func(path string, age time.Duration)
+}
+
+// scanStart calls Hooks.OnScanStart, if set.
+func (h Hooks) scanStart(path string) {
+	if h.OnScanStart != nil {
+		h.OnScanStart(path)
+	}
+}
+
+// scanComplete calls Hooks.OnScanComplete, if set.
+func (h Hooks) scanComplete(path string, results []FileType, err error) {
+	if h.OnScanComplete != nil {
+		h.OnScanComplete(path, results, err)
+	}
+}
+
+// processSpawn calls Hooks.OnProcessSpawn, if set.
+func (h Hooks) processSpawn(cmdline []string) {
+	if h.OnProcessSpawn != nil {
+		h.OnProcessSpawn(append([]string(nil), cmdline...))
+	}
+}
+
+// staleDefinitions calls Hooks.OnStaleDefinitions, if set.
+func (h Hooks) staleDefinitions(path string, age time.Duration) {
+	if h.OnStaleDefinitions != nil {
+		h.OnStaleDefinitions(path, age)
+	}
+}
@@ -0,0 +1,83 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	trid := NewTrid(Options{Cmd: cmd, Hooks: Hooks{
+		OnScanStart: func(path string) { record("start " + path) },
+		OnScanComplete: func(path string, results []FileType, err error) {
+			if err != nil {
+				record("error " + path)
+			} else {
+				record("complete " + path + " " + results[0].Extension)
+			}
+		},
+		OnProcessSpawn: func(cmdline []string) { record("spawn " + cmdline[len(cmdline)-1]) },
+	}})
+
+	trid.Scan("testdata/sample.pdf", 1)
+	trid.Scan("testdata/missing.pdf", 1)
+
+	expected := []string{
+		"start testdata/sample.pdf",
+		"spawn testdata/sample.pdf",
+		"complete testdata/sample.pdf .pdf",
+		"start testdata/missing.pdf",
+		"error testdata/missing.pdf",
+	}
+
+	if !slices.Equal(events, expected) {
+		t.Errorf("events = %q, want %q", events, expected)
+	}
+}
+
+func TestHooksBatchFailure(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	var started, failed []string
+
+	trid := NewTrid(Options{Cmd: cmd, Hooks: Hooks{
+		OnScanStart: func(path string) { started = append(started, path) },
+		OnScanComplete: func(path string, results []FileType, err error) {
+			if errors.Is(err, context.Canceled) {
+				failed = append(failed, path)
+			}
+		},
+	}})
+
+	trid.ScanBatch(context.Background(), []string{"a", "b"}, 0)
+	if len(started) != 0 {
+		t.Errorf("OnScanStart called for invalid batch: %q", started)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	paths := []string{"testdata/sample.pdf", "testdata/sample.pdf"}
+	if _, err := trid.ScanBatch(ctx, paths, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanBatch() error = %v, want context.Canceled", err)
+	}
+
+	if !slices.Equal(started, paths) || !slices.Equal(failed, paths) {
+		t.Errorf("started = %q, failed = %q, want %q", started, failed, paths)
+	}
+}
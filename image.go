@@ -0,0 +1,378 @@
+package trid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Image formats reported in Image.Format.
+const (
+	ImagePNG  = "png"
+	ImageGIF  = "gif"
+	ImageJPEG = "jpeg"
+	ImageBMP  = "bmp"
+	ImageWebP = "webp"
+)
+
+// errImageHeader is returned by the header decoders for malformed images.
+var errImageHeader = errors.New("invalid image header")
+
+// Image describes the header of an image, with Options.Images.
+type Image struct {
+	Format string `json:"format"` // ImagePNG, ImageGIF, ImageJPEG, ImageBMP or ImageWebP.
+	Width  int    `json:"width"`  // Width in pixels.
+	Height int    `json:"height"` // Height in pixels.
+	Depth  int    `json:"depth"`  // Bits per pixel, or 0 if unknown, as for lossy WebP images; for GIF images, that of the color table.
+	Frames int    `json:"frames"` // Number of frames, more than 1 for animations.
+}
+
+// imageExts are the extensions of image matches.
+var imageExts = map[string]bool{
+	".png": true, ".apng": true, ".gif": true, ".jpg": true, ".jpeg": true, ".jpe": true, ".jfif": true,
+	".bmp": true, ".dib": true, ".webp": true,
+}
+
+// isImage reports whether ft is an image format.
+func isImage(ft FileType) bool {
+	return imageExts[strings.ToLower(ft.Extension)] || strings.HasPrefix(mediaType(ft.MimeType), "image/")
+}
+
+// inspectImage decodes the header of the image at path, if Options.Images is
+// set and its most likely match is an image format. It returns nil if the
+// file is not a PNG, GIF, JPEG, BMP or WebP image.
+func (t *Trid) inspectImage(path string, fileTypes []FileType) *Image {
+	if !t.options.Images || len(fileTypes) == 0 || !isImage(fileTypes[0]) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	head, _ := r.Peek(12)
+
+	var img *Image
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		img, err = pngHeader(r)
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		img, err = gifHeader(r)
+	case bytes.HasPrefix(head, []byte("\xff\xd8")):
+		img, err = jpegHeader(r)
+	case bytes.HasPrefix(head, []byte("BM")):
+		img, err = bmpHeader(r)
+	case len(head) == 12 && string(head[:4]) == "RIFF" && string(head[8:]) == "WEBP":
+		img, err = webpHeader(r)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return img
+}
+
+// pngHeader decodes the header of a PNG image, counting the frames of APNG
+// animations.
+func pngHeader(r io.Reader) (*Image, error) {
+	if _, err := io.CopyN(io.Discard, r, 8); err != nil {
+		return nil, err
+	}
+
+	img := &Image{Format: ImagePNG, Frames: 1}
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return nil, err
+		}
+
+		length := int64(binary.BigEndian.Uint32(hdr))
+		switch string(hdr[4:]) {
+		case "IHDR":
+			ihdr := make([]byte, 13)
+			if length < 13 {
+				return nil, errImageHeader
+			}
+			if _, err := io.ReadFull(r, ihdr); err != nil {
+				return nil, err
+			}
+			length -= 13
+
+			img.Width = int(binary.BigEndian.Uint32(ihdr[0:]))
+			img.Height = int(binary.BigEndian.Uint32(ihdr[4:]))
+
+			// Samples per pixel by color type: grayscale, RGB, palette,
+			// grayscale and alpha, RGBA
+			samples := map[byte]int{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}[ihdr[9]]
+			img.Depth = int(ihdr[8]) * samples
+		case "acTL":
+			actl := make([]byte, 8)
+			if length < 8 {
+				return nil, errImageHeader
+			}
+			if _, err := io.ReadFull(r, actl); err != nil {
+				return nil, err
+			}
+			length -= 8
+
+			img.Frames = int(binary.BigEndian.Uint32(actl))
+		case "IDAT", "IEND":
+			// The animation control chunk precedes the image data
+			if img.Width == 0 {
+				return nil, errImageHeader
+			}
+			return img, nil
+		}
+
+		// Chunk data and CRC
+		if _, err := io.CopyN(io.Discard, r, length+4); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// gifHeader decodes the header of a GIF image, walking its blocks to count
+// its frames.
+func gifHeader(r *bufio.Reader) (*Image, error) {
+	hdr := make([]byte, 13)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+
+	img := &Image{
+		Format: ImageGIF,
+		Width:  int(binary.LittleEndian.Uint16(hdr[6:])),
+		Height: int(binary.LittleEndian.Uint16(hdr[8:])),
+	}
+
+	// Global color table, whose size gives the depth
+	if hdr[10]&0x80 != 0 {
+		img.Depth = int(hdr[10]&0x07) + 1
+		if _, err := io.CopyN(io.Discard, r, 3<<(hdr[10]&0x07+1)); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			// Count the frames of truncated animations
+			if img.Frames > 0 {
+				return img, nil
+			}
+			return nil, err
+		}
+
+		switch b {
+		case 0x21: // Extension
+			if _, err := r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case 0x2c: // Image descriptor
+			desc := make([]byte, 9)
+			if _, err := io.ReadFull(r, desc); err != nil {
+				return nil, err
+			}
+			img.Frames++
+
+			// Local color table and LZW minimum code size
+			skip := int64(1)
+			if desc[8]&0x80 != 0 {
+				skip += 3 << (desc[8]&0x07 + 1)
+				if img.Depth == 0 {
+					img.Depth = int(desc[8]&0x07) + 1
+				}
+			}
+			if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+				return nil, err
+			}
+		case 0x3b: // Trailer
+			return img, nil
+		default:
+			return nil, errImageHeader
+		}
+
+		// Data sub-blocks, up to a zero-length block
+		for {
+			n, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				break
+			}
+			if _, err := r.Discard(int(n)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// jpegHeader decodes the start of frame of a JPEG image.
+func jpegHeader(r *bufio.Reader) (*Image, error) {
+	if _, err := r.Discard(2); err != nil {
+		return nil, err
+	}
+
+	for {
+		marker := make([]byte, 4)
+		if _, err := io.ReadFull(r, marker[:2]); err != nil {
+			return nil, err
+		}
+
+		// Fill bytes may precede markers
+		for marker[1] == 0xff {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			marker[1] = b
+		}
+
+		if marker[0] != 0xff {
+			return nil, errImageHeader
+		}
+
+		if _, err := io.ReadFull(r, marker[2:]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return nil, errImageHeader
+		}
+
+		// Start of frame markers, other than DHT, JPG and DAC
+		if m := marker[1]; m >= 0xc0 && m <= 0xcf && m != 0xc4 && m != 0xc8 && m != 0xcc {
+			sof := make([]byte, 6)
+			if length < 6 {
+				return nil, errImageHeader
+			}
+			if _, err := io.ReadFull(r, sof); err != nil {
+				return nil, err
+			}
+
+			return &Image{
+				Format: ImageJPEG,
+				Width:  int(binary.BigEndian.Uint16(sof[3:])),
+				Height: int(binary.BigEndian.Uint16(sof[1:])),
+				Depth:  int(sof[0]) * int(sof[5]),
+				Frames: 1,
+			}, nil
+		}
+
+		if _, err := r.Discard(length); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// bmpHeader decodes the header of a BMP image.
+func bmpHeader(r io.Reader) (*Image, error) {
+	hdr := make([]byte, 30)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+
+	img := &Image{Format: ImageBMP, Frames: 1}
+	if size := binary.LittleEndian.Uint32(hdr[14:]); size == 12 {
+		// OS/2 BITMAPCOREHEADER
+		img.Width = int(binary.LittleEndian.Uint16(hdr[18:]))
+		img.Height = int(binary.LittleEndian.Uint16(hdr[20:]))
+		img.Depth = int(binary.LittleEndian.Uint16(hdr[24:]))
+	} else {
+		// Top-down images have a negative height
+		img.Width = int(int32(binary.LittleEndian.Uint32(hdr[18:])))
+		img.Height = int(int32(binary.LittleEndian.Uint32(hdr[22:])))
+		img.Depth = int(binary.LittleEndian.Uint16(hdr[28:]))
+	}
+
+	img.Height = max(img.Height, -img.Height)
+	if img.Width <= 0 {
+		return nil, errImageHeader
+	}
+
+	return img, nil
+}
+
+// webpHeader decodes the header of a WebP image, counting the frames of
+// animations.
+func webpHeader(r io.Reader) (*Image, error) {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return nil, err
+	}
+
+	img := &Image{Format: ImageWebP}
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if img.Width > 0 && (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) {
+				img.Frames = max(img.Frames, 1)
+				return img, nil
+			}
+			return nil, err
+		}
+
+		length := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		var data []byte
+		switch string(hdr[:4]) {
+		case "VP8X", "VP8 ", "VP8L":
+			data = make([]byte, min(length, 10))
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+		case "ANMF":
+			img.Frames++
+		}
+
+		switch string(hdr[:4]) {
+		case "VP8X":
+			// Canvas size of extended images, 24-bit values minus one
+			if len(data) < 10 {
+				return nil, errImageHeader
+			}
+			img.Width = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+			img.Height = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+			if data[0]&0x10 != 0 {
+				img.Depth = 32
+			}
+		case "VP8 ":
+			// Lossy key frame: dimensions follow the start code
+			if len(data) < 10 || img.Width > 0 {
+				break
+			}
+			img.Width = int(binary.LittleEndian.Uint16(data[6:]) & 0x3fff)
+			img.Height = int(binary.LittleEndian.Uint16(data[8:]) & 0x3fff)
+		case "VP8L":
+			// Lossless: 14-bit dimensions minus one after the signature
+			if len(data) < 5 || img.Width > 0 {
+				break
+			}
+			bits := binary.LittleEndian.Uint32(data[1:])
+			img.Width = int(bits&0x3fff) + 1
+			img.Height = int(bits>>14&0x3fff) + 1
+			img.Depth = 24
+			if bits>>28&1 != 0 {
+				img.Depth = 32
+			}
+		}
+
+		// Chunks are padded to an even size
+		if _, err := io.CopyN(io.Discard, r, length+length%2-int64(len(data))); err != nil {
+			if img.Width > 0 {
+				img.Frames = max(img.Frames, 1)
+				return img, nil
+			}
+			return nil, err
+		}
+	}
+}
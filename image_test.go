@@ -0,0 +1,139 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectImage(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 30)))
+	pngData := bytes.Clone(buf.Bytes())
+
+	// An APNG animation control chunk after the header chunk
+	actl := []byte{0, 0, 0, 8, 'a', 'c', 'T', 'L', 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0}
+	apngData := append(append(bytes.Clone(pngData[:33]), actl...), pngData[33:]...)
+
+	buf.Reset()
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 48)), nil)
+	jpegData := bytes.Clone(buf.Bytes())
+
+	buf.Reset()
+	frame := image.NewPaletted(image.Rect(0, 0, 16, 8), palette.Plan9)
+	gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame, frame, frame, frame}, Delay: []int{1, 1, 1, 1}})
+	gifData := bytes.Clone(buf.Bytes())
+
+	buf.Reset()
+	gray := image.NewGray(image.Rect(0, 0, 5, 7))
+	gray.Set(0, 0, color.White)
+	png.Encode(&buf, gray)
+	grayData := bytes.Clone(buf.Bytes())
+
+	bmp := make([]byte, 54)
+	copy(bmp, "BM")
+	binary.LittleEndian.PutUint32(bmp[14:], 40)
+	binary.LittleEndian.PutUint32(bmp[18:], 320)
+	binary.LittleEndian.PutUint32(bmp[22:], uint32(0xffffffff-200+1)) // -200, top-down
+	binary.LittleEndian.PutUint16(bmp[28:], 24)
+
+	// Lossless WebP: 14-bit width and height minus one, alpha flag
+	vp8l := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(vp8l[1:], 99|149<<14|1<<28)
+	webp := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8L\x05\x00\x00\x00"), append(vp8l, 0)...)
+
+	// Animated WebP: extended header and two frames
+	vp8x := []byte{0x02, 0, 0, 0, 199, 0, 0, 99, 0, 0}
+	anim := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00")
+	anim = append(anim, vp8x...)
+	anim = append(anim, "ANMF\x02\x00\x00\x00\x00\x00ANMF\x02\x00\x00\x00\x00\x00"...)
+
+	tr := NewTrid(Options{Cmd: "trid", Images: true})
+	match := []FileType{{Extension: ".png", MimeType: "image/png"}}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected Image
+	}{
+		{"PNG", pngData, Image{Format: ImagePNG, Width: 40, Height: 30, Depth: 32, Frames: 1}},
+		{"APNG", apngData, Image{Format: ImagePNG, Width: 40, Height: 30, Depth: 32, Frames: 3}},
+		{"Grayscale PNG", grayData, Image{Format: ImagePNG, Width: 5, Height: 7, Depth: 8, Frames: 1}},
+		{"JPEG", jpegData, Image{Format: ImageJPEG, Width: 64, Height: 48, Depth: 24, Frames: 1}},
+		{"GIF", gifData, Image{Format: ImageGIF, Width: 16, Height: 8, Depth: 8, Frames: 4}},
+		{"BMP", bmp, Image{Format: ImageBMP, Width: 320, Height: 200, Depth: 24, Frames: 1}},
+		{"WebP", webp, Image{Format: ImageWebP, Width: 100, Height: 150, Depth: 32, Frames: 1}},
+		{"Animated WebP", anim, Image{Format: ImageWebP, Width: 200, Height: 100, Frames: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if img := tr.inspectImage(write(tt.name, tt.data), match); img == nil || *img != tt.expected {
+				t.Errorf("inspectImage() = %+v, want %+v", img, tt.expected)
+			}
+		})
+	}
+
+	if img := tr.inspectImage("testdata/sample.pdf", match); img != nil {
+		t.Errorf("inspectImage(sample.pdf) = %+v, want nil", img)
+	}
+
+	if img := tr.inspectImage(write("pdf-match.png", pngData), []FileType{{Extension: ".pdf"}}); img != nil {
+		t.Errorf("inspectImage() of a PDF match = %+v, want nil", img)
+	}
+
+	if img := NewTrid(Options{Cmd: "trid"}).inspectImage(write("off.png", pngData), match); img != nil {
+		t.Errorf("inspectImage() without Images = %+v, want nil", img)
+	}
+}
+
+const pngOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: image.png
+100.0% (.PNG) Portable Network Graphics (16000/1)
+`
+
+func TestScanImage(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 12, 34)))
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: pngOutput}, nil
+	})
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, Images: true})
+
+	r, err := tr.ScanReport(context.Background(), path, 1)
+	if err != nil || r.Image == nil || r.Image.Width != 12 || r.Image.Height != 34 {
+		t.Errorf("ScanReport() = %+v, %v, want 12x34 image", r, err)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{path}, 1)
+	if err != nil || results[0].Image == nil || results[0].Image.Format != ImagePNG {
+		t.Errorf("ScanBatch() = %+v, %v, want PNG image", results, err)
+	}
+}
@@ -0,0 +1,14 @@
+package install
+
+// Builds lists the official TrID builds by GOOS/GOARCH. Their checksums are
+// not pinned yet: until go generate records the checksums of the builds you
+// have verified, Install requires Config.SHA256 and fails with
+// ErrChecksumRequired without it. The archives are not versioned upstream, so
+// pins must be renewed when a release replaces them. TrID has no 64-bit Windows
+// build; windows/amd64 runs the 32-bit one.
+var Builds = map[string]Build{
+	"linux/386":     {URL: "https://mark0.net/download/trid_linux.zip", SHA256: "", Binary: "trid"},
+	"linux/amd64":   {URL: "https://mark0.net/download/trid_linux_64.zip", SHA256: "", Binary: "trid"},
+	"windows/386":   {URL: "https://mark0.net/download/trid_w32.zip", SHA256: "", Binary: "trid.exe"},
+	"windows/amd64": {URL: "https://mark0.net/download/trid_w32.zip", SHA256: "", Binary: "trid.exe"},
+}
@@ -0,0 +1,93 @@
+//go:build ignore
+
+// Genbuilds downloads the official TrID builds and writes builds.go, pinning
+// the SHA-256 checksum of each archive. Run it with go generate after
+// verifying a new upstream release.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// builds lists the download location and executable of the official TrID
+// builds by GOOS/GOARCH. TrID has no 64-bit Windows build; windows/amd64 runs
+// the 32-bit one.
+var builds = map[string][2]string{
+	"windows/386":   {"https://mark0.net/download/trid_w32.zip", "trid.exe"},
+	"windows/amd64": {"https://mark0.net/download/trid_w32.zip", "trid.exe"},
+	"linux/386":     {"https://mark0.net/download/trid_linux.zip", "trid"},
+	"linux/amd64":   {"https://mark0.net/download/trid_linux_64.zip", "trid"},
+}
+
+func main() {
+	platforms := make([]string, 0, len(builds))
+	for platform := range builds {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by genbuilds.go; DO NOT EDIT.\n\npackage install\n\n")
+	b.WriteString("// Builds lists the official TrID builds by GOOS/GOARCH, with the checksums of\n")
+	b.WriteString("// the archives verified when they were pinned. The archives are not versioned\n")
+	b.WriteString("// upstream; once a new release replaces them, set Config.SHA256 to the\n")
+	b.WriteString("// checksum of the release you have verified, or run go generate. TrID has no\n")
+	b.WriteString("// 64-bit Windows build; windows/amd64 runs the 32-bit one.\n")
+	b.WriteString("var Builds = map[string]Build{\n")
+
+	sums := make(map[string]string)
+	for _, platform := range platforms {
+		url, binary := builds[platform][0], builds[platform][1]
+
+		sum, ok := sums[url]
+		if !ok {
+			var err error
+			if sum, err = checksum(url); err != nil {
+				log.Fatalf("genbuilds: %s: %v", url, err)
+			}
+			sums[url] = sum
+		}
+
+		fmt.Fprintf(&b, "\t%q: {URL: %q, SHA256: %q, Binary: %q},\n", platform, url, sum, binary)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("genbuilds: %v", err)
+	}
+
+	if err := os.WriteFile("builds.go", src, 0o644); err != nil {
+		log.Fatalf("genbuilds: %v", err)
+	}
+}
+
+// checksum downloads url and returns the hex-encoded SHA-256 checksum of the
+// response body.
+func checksum(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading: %s", resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,235 @@
+// Package install downloads the TrID command-line tool into a user cache
+// directory and configures the trid package to use it.
+package install
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/defs"
+)
+
+//go:generate go run genbuilds.go
+
+var (
+	// ErrUnsupportedPlatform is returned when no TrID build is known for the
+	// current operating system and architecture.
+	ErrUnsupportedPlatform = errors.New("no TrID build available for this platform")
+
+	// ErrChecksumRequired is returned when no checksum is pinned for the build.
+	ErrChecksumRequired = errors.New("no checksum pinned for TrID build")
+
+	// ErrChecksumMismatch is returned when the downloaded archive does not
+	// match the pinned checksum.
+	ErrChecksumMismatch = errors.New("TrID download checksum mismatch")
+
+	// ErrNoBinaryInArchive is returned when the downloaded archive does not
+	// contain the TrID executable.
+	ErrNoBinaryInArchive = errors.New("no TrID executable found in archive")
+)
+
+// Build describes a downloadable TrID build.
+type Build struct {
+	URL    string // Download location of the zip archive.
+	SHA256 string // Hex-encoded SHA-256 checksum of the archive.
+	Binary string // Name of the executable in the archive.
+}
+
+// Config configures the installation.
+type Config struct {
+	Dir            string       // Installation directory (default: "trid" in the user cache directory).
+	Build          *Build       // Build to install (default: the entry of Builds for the current platform).
+	SHA256         string       // Pinned checksum of the archive; overrides Build.SHA256.
+	DefinitionsURL string       // Download location of the definitions package (default: defs.DefaultURL).
+	HTTPClient     *http.Client // HTTP client used to download TrID and its definitions (default: http.DefaultClient).
+}
+
+// Install downloads TrID and its definitions package unless they are already
+// installed, and returns the path of the executable. The archive must match
+// the pinned checksum; each checksum is installed in its own subdirectory of
+// Config.Dir, so changing the pin installs the new build alongside the old.
+// The definitions package is not pinned, as it is updated daily upstream.
+func Install(ctx context.Context, cfg Config) (string, error) {
+	build, err := cfg.build()
+	if err != nil {
+		return "", err
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(cache, "trid")
+	}
+
+	dir = filepath.Join(dir, build.SHA256[:12])
+	bin := filepath.Join(dir, build.Binary)
+
+	if _, err := os.Stat(bin); errors.Is(err, os.ErrNotExist) {
+		if err := cfg.download(ctx, build, dir); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, defs.DefaultFile)); errors.Is(err, os.ErrNotExist) {
+		opts := defs.UpdateOptions{URL: cfg.DefinitionsURL, Client: cfg.HTTPClient}
+		if _, err := defs.UpdateWithOptions(ctx, filepath.Join(dir, defs.DefaultFile), opts); err != nil {
+			return "", fmt.Errorf("installing definitions: %w", err)
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	return bin, nil
+}
+
+// Configure installs TrID like Install and points opts.Cmd at it.
+func Configure(ctx context.Context, cfg Config, opts *trid.Options) error {
+	bin, err := Install(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	opts.Cmd = bin
+
+	return nil
+}
+
+// build returns the build to install, with the pinned checksum applied.
+func (cfg Config) build() (Build, error) {
+	var build Build
+	if cfg.Build != nil {
+		build = *cfg.Build
+	} else {
+		b, ok := Builds[runtime.GOOS+"/"+runtime.GOARCH]
+		if !ok {
+			return Build{}, fmt.Errorf("%w: %s/%s", ErrUnsupportedPlatform, runtime.GOOS, runtime.GOARCH)
+		}
+
+		build = b
+	}
+
+	if cfg.SHA256 != "" {
+		build.SHA256 = cfg.SHA256
+	}
+
+	build.SHA256 = strings.ToLower(build.SHA256)
+	if len(build.SHA256) != sha256.Size*2 {
+		return Build{}, fmt.Errorf("%w: %s", ErrChecksumRequired, build.URL)
+	}
+
+	if build.Binary == "" {
+		build.Binary = "trid"
+		if runtime.GOOS == "windows" {
+			build.Binary += ".exe"
+		}
+	}
+
+	return build, nil
+}
+
+// download fetches and verifies the archive of build and extracts the
+// executable into dir.
+func (cfg Config) download(ctx context.Context, build Build, dir string) error {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, build.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading TrID: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	if got := hex.EncodeToString(sum[:]); got != build.SHA256 {
+		return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, got, build.SHA256)
+	}
+
+	data, err := extract(body, build.Binary)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".trid-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, build.Binary))
+}
+
+// extract returns the executable named binary from a zip archive.
+func extract(body []byte, binary string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Base(f.Name), binary) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	return nil, ErrNoBinaryInArchive
+}
@@ -0,0 +1,139 @@
+package install
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/defs"
+)
+
+func TestInstall(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	fw, _ := zw.Create("TrID/trid")
+	fw.Write([]byte("#!/bin/sh\n"))
+	zw.Close()
+
+	sum := sha256.Sum256(archive.Bytes())
+	checksum := hex.EncodeToString(sum[:])
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/trid.zip":
+			downloads.Add(1)
+			w.Write(archive.Bytes())
+		case "/triddefs.trd":
+			w.Write([]byte("definitions"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := Config{
+		Dir:            dir,
+		Build:          &Build{URL: srv.URL + "/trid.zip", Binary: "trid"},
+		SHA256:         checksum,
+		DefinitionsURL: srv.URL + "/triddefs.trd",
+	}
+
+	var opts trid.Options
+	if err := Configure(context.Background(), cfg, &opts); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	if expected := filepath.Join(dir, checksum[:12], "trid"); opts.Cmd != expected {
+		t.Errorf("Configure() Cmd = %q, want %q", opts.Cmd, expected)
+	}
+
+	if fi, err := os.Stat(opts.Cmd); err != nil || fi.Mode().Perm()&0o100 == 0 {
+		t.Errorf("executable not installed: %v", err)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, checksum[:12], defs.DefaultFile)); string(b) != "definitions" {
+		t.Errorf("definitions = %q, want %q", b, "definitions")
+	}
+
+	if _, err := Install(context.Background(), cfg); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("archive downloaded %d times, want 1", n)
+	}
+}
+
+func TestInstallChecksum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer srv.Close()
+
+	build := &Build{URL: srv.URL, Binary: "trid"}
+
+	tests := []struct {
+		name        string
+		checksum    string
+		expectedErr error
+	}{
+		{"Not pinned", "", ErrChecksumRequired},
+		{"Mismatch", "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := Install(context.Background(), Config{Dir: dir, Build: build, SHA256: tt.checksum})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Install() error = %v, want %v", err, tt.expectedErr)
+			}
+
+			if entries, _ := os.ReadDir(filepath.Join(dir, "0123456789ab")); len(entries) != 0 {
+				t.Errorf("Install() left files behind: %v", entries)
+			}
+		})
+	}
+}
+
+func TestBuilds(t *testing.T) {
+	checksum := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	for platform, b := range Builds {
+		t.Run(platform, func(t *testing.T) {
+			if !strings.HasPrefix(b.URL, "https://") || b.Binary == "" {
+				t.Errorf("Builds[%q] = %+v, want an HTTPS URL and a binary", platform, b)
+			}
+
+			// An entry is usable as is once pinned, and with Config.SHA256 until then
+			_, err := Config{Build: &b}.build()
+			if b.SHA256 != "" && err != nil {
+				t.Errorf("build() of the pinned entry error = %v", err)
+			} else if b.SHA256 == "" && !errors.Is(err, ErrChecksumRequired) {
+				t.Errorf("build() of the unpinned entry error = %v, want %v", err, ErrChecksumRequired)
+			}
+
+			built, err := Config{Build: &b, SHA256: checksum}.build()
+			if err != nil {
+				t.Fatalf("build() with a checksum error = %v", err)
+			}
+
+			if built.SHA256 != checksum || built.Binary != b.Binary {
+				t.Errorf("build() = %+v, want the entry with the checksum applied", built)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package trid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/attilabuti/trid/defs"
+)
+
+// SidecarChecksum, as Options.DefsSHA256, reads the expected checksum
+// of the definitions package from its sidecar file, the package path followed
+// by ".sha256", in the format written by sha256sum.
+const SidecarChecksum = "sidecar"
+
+// verifyDefinitions checks the definitions package against
+// Options.DefsSHA256, if set, before TrID is run, so that a tampered
+// package is not used. The checksum is memoized like the fingerprint, and
+// recomputed whenever the package is replaced or its size or modification time
+// changes. The native engine, whose definitions are a directory of XML files,
+// is not checked.
+func (t *Trid) verifyDefinitions(ctx context.Context) error {
+	expected := t.options.DefsSHA256
+	if expected == "" || t.options.Engine == EngineNative {
+		return nil
+	}
+
+	if expected == SidecarChecksum {
+		path, err := defs.Locate(t.options.Cmd, t.options.Definitions)
+		if err != nil {
+			return err
+		}
+
+		if expected, err = defs.ReadChecksum(path); err != nil {
+			return fmt.Errorf("%w: %v", ErrDefinitionsMismatch, err)
+		}
+	}
+
+	sum, err := t.definitionsFingerprint(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(sum, expected) {
+		t.log(ctx, slog.LevelError, "definitions checksum mismatch", "sha256", sum, "expected", expected)
+		return fmt.Errorf("%w: sha256 %s, expected %s", ErrDefinitionsMismatch, sum, expected)
+	}
+
+	return nil
+}
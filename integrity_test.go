@@ -0,0 +1,58 @@
+package trid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefsSHA256(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+	path := filepath.Join(filepath.Dir(cmd), "triddefs.trd")
+
+	sum := sha256.Sum256([]byte("definitions"))
+	valid := hex.EncodeToString(sum[:])
+
+	if _, err := NewTrid(Options{Cmd: cmd, DefsSHA256: strings.ToUpper(valid)}).Scan("testdata/sample.pdf", 1); err != nil {
+		t.Errorf("Scan() with the checksum of the package error = %v", err)
+	}
+
+	trid := NewTrid(Options{Cmd: cmd, DefsSHA256: SidecarChecksum})
+	if _, err := trid.Scan("testdata/sample.pdf", 1); !errors.Is(err, ErrDefinitionsMismatch) {
+		t.Errorf("Scan() without a sidecar file error = %v, want %v", err, ErrDefinitionsMismatch)
+	}
+
+	if err := os.WriteFile(path+".sha256", []byte(valid+"  triddefs.trd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trid.Scan("testdata/sample.pdf", 1); err != nil {
+		t.Errorf("Scan() with a sidecar file error = %v", err)
+	}
+
+	// Replace the package with a tampered one of the same size
+	before := calls()
+	if err := os.WriteFile(path+".new", []byte("tampered!!!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trid.Scan("testdata/sample.pdf", 1); !errors.Is(err, ErrDefinitionsMismatch) {
+		t.Errorf("Scan() of a tampered package error = %v, want %v", err, ErrDefinitionsMismatch)
+	}
+
+	if _, err := trid.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 1); !errors.Is(err, ErrDefinitionsMismatch) {
+		t.Errorf("ScanBatch() of a tampered package error = %v, want %v", err, ErrDefinitionsMismatch)
+	}
+
+	if calls() != before {
+		t.Errorf("TrID was run %d times with a tampered package", calls()-before)
+	}
+}
@@ -0,0 +1,45 @@
+package trid
+
+import "context"
+
+// Limiter bounds the number of TrID processes running at once. A single
+// Limiter can be shared by several Trid instances to enforce a process-wide
+// limit.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing at most n concurrent processes. A
+// value below 1 is treated as 1.
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is available or ctx is done.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot obtained with Acquire.
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Running returns the number of slots currently in use.
+func (l *Limiter) Running() int {
+	return len(l.slots)
+}
+
+// Limit returns the maximum number of concurrent processes.
+func (l *Limiter) Limit() int {
+	return cap(l.slots)
+}
@@ -0,0 +1,94 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newSlowStub creates a stub TrID executable that records the number of
+// concurrently running instances in dir while sleeping briefly.
+func newSlowStub(t *testing.T) (cmd string, maxRunning func() int) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	cmd = filepath.Join(dir, "trid")
+	script := "#!/bin/sh\n" +
+		"d='" + dir + "'\n" +
+		"touch \"$d/run.$$\"\n" +
+		"ls \"$d\" | grep -c '^run\\.' >> \"$d/counts\"\n" +
+		"sleep 0.1\n" +
+		"rm \"$d/run.$$\"\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, func() int {
+		b, _ := os.ReadFile(filepath.Join(dir, "counts"))
+		highest := 0
+		for _, line := range strings.Fields(string(b)) {
+			if n, _ := strconv.Atoi(line); n > highest {
+				highest = n
+			}
+		}
+		return highest
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	cmd, maxRunning := newSlowStub(t)
+
+	limiter := NewLimiter(2)
+	instances := []*Trid{
+		NewTrid(Options{Cmd: cmd, Limiter: limiter}),
+		NewTrid(Options{Cmd: cmd, Limiter: limiter}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instances[i%2].Scan("testdata/sample.pdf", 1)
+		}()
+	}
+	wg.Wait()
+
+	if n := maxRunning(); n < 1 || n > 2 {
+		t.Errorf("%d TrID processes ran concurrently, want at most 2", n)
+	}
+
+	if limiter.Running() != 0 {
+		t.Errorf("Running() = %d after all scans finished", limiter.Running())
+	}
+}
+
+func TestLimiterAcquireCancelled(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	l.Release()
+	if l.Running() != 0 || l.Limit() != 1 {
+		t.Errorf("Running() = %d, Limit() = %d", l.Running(), l.Limit())
+	}
+}
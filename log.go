@@ -0,0 +1,55 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+)
+
+// log emits a record with the configured logger, if any.
+func (t *Trid) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if t.options.Logger == nil {
+		return
+	}
+
+	t.options.Logger.Log(ctx, level, msg, args...)
+}
+
+// errorClass returns a short, stable name for the kind of err, suitable as a
+// log attribute or metric label.
+func errorClass(err error) string {
+	var (
+		parseErr *ParseError
+		execErr  *exec.Error
+		tridErr  *TridError
+		pathErr  *fs.PathError // Within a TridError, starting an executable given by path failed.
+	)
+
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrUnknownFileType):
+		return "unknown_type"
+	case errors.Is(err, ErrNoDefinitions), errors.Is(err, ErrEmptyDefPackage), errors.Is(err, ErrDefinitionsMismatch):
+		return "definitions"
+	case errors.Is(err, ErrNoFileSpecified), errors.Is(err, ErrNumberOfMatches), errors.Is(err, ErrReservedArg), errors.Is(err, ErrInvalidRange):
+		return "invalid_argument"
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, ErrEmptyFile), errors.Is(err, ErrFileTooLarge), errors.Is(err, ErrIsSymlink):
+		return "file"
+	case errors.Is(err, ErrBinaryNotFound), errors.As(err, &execErr),
+		errors.As(err, &tridErr) && errors.As(tridErr.Err, &pathErr):
+		return "executable"
+	case errors.As(err, &parseErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,76 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	trid := NewTrid(Options{Cmd: cmd, Logger: logger})
+	if _, err := trid.Scan(filepath.Join("testdata", "sample.pdf"), 1); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var messages []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record struct {
+			Msg     string `json:"msg"`
+			Matches int    `json:"matches"`
+		}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, record.Msg)
+		if record.Msg == "parsed TrID output" && record.Matches != 1 {
+			t.Errorf("parsed TrID output: matches = %d, want 1", record.Matches)
+		}
+	}
+
+	expected := []string{"running TrID", "TrID finished", "parsed TrID output"}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("log messages = %q, want %q", messages, expected)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, "none"},
+		{&TridError{Err: fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded)}, "timeout"},
+		{context.Canceled, "canceled"},
+		{ErrUnavailable, "unavailable"},
+		{&TridError{Err: ErrUnknownFileType}, "unknown_type"},
+		{ErrEmptyDefPackage, "definitions"},
+		{ErrNumberOfMatches, "invalid_argument"},
+		{ErrEmptyFile, "file"},
+		{&TridError{Err: &exec.Error{Name: "trid", Err: exec.ErrNotFound}}, "executable"},
+		{&TridError{Err: &fs.PathError{Op: "fork/exec", Path: "/opt/trid/trid", Err: fs.ErrNotExist}}, "executable"},
+		{&fs.PathError{Op: "open", Path: "file", Err: fs.ErrPermission}, "other"},
+		{&ParseError{Line: 1}, "parse"},
+		{errors.New("boom"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := errorClass(tt.err); got != tt.expected {
+			t.Errorf("errorClass(%v) = %q, want %q", tt.err, got, tt.expected)
+		}
+	}
+}
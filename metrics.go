@@ -0,0 +1,140 @@
+package trid
+
+import (
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of
+// Stats.Durations.
+var durationBuckets = [...]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Stats is a snapshot of the activity of a Trid instance since it was created.
+type Stats struct {
+	Scans       uint64            // Files identified, successfully or not, including cache hits.
+	Errors      map[string]uint64 // Failed file identifications by error class (e.g., "timeout", "unknown_type").
+	CacheHits   uint64            // Scans answered from the cache.
+	CacheMisses uint64            // Scans the cache could not answer.
+	Prechecked  uint64            // Scans answered by Options.Precheck without TrID.
+	Processes   int               // TrID processes currently running.
+	Durations   Histogram         // Durations of the TrID processes run.
+}
+
+// Histogram is a cumulative histogram of durations.
+type Histogram struct {
+	Buckets []float64     // Upper bounds of the buckets in seconds.
+	Counts  []uint64      // Counts[i] is the number of observations of at most Buckets[i].
+	Count   uint64        // Total number of observations.
+	Sum     time.Duration // Sum of all observations.
+}
+
+// stats collects the counters reported by Trid.Stats.
+type stats struct {
+	mu          sync.Mutex
+	scans       uint64
+	errors      map[string]uint64
+	cacheHits   uint64
+	cacheMisses uint64
+	prechecked  uint64
+	processes   int
+	buckets     [len(durationBuckets)]uint64 // Non-cumulative counts per bucket.
+	count       uint64
+	sum         time.Duration
+}
+
+// Stats returns a snapshot of the activity of the instance, suitable for
+// exporting as metrics.
+func (t *Trid) Stats() Stats {
+	s := &t.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := Stats{
+		Scans:       s.scans,
+		Errors:      make(map[string]uint64, len(s.errors)),
+		CacheHits:   s.cacheHits,
+		CacheMisses: s.cacheMisses,
+		Prechecked:  s.prechecked,
+		Processes:   s.processes,
+		Durations: Histogram{
+			Buckets: append([]float64(nil), durationBuckets[:]...),
+			Counts:  make([]uint64, len(durationBuckets)),
+			Count:   s.count,
+			Sum:     s.sum,
+		},
+	}
+
+	for class, n := range s.errors {
+		snapshot.Errors[class] = n
+	}
+
+	var cumulative uint64
+	for i := range durationBuckets {
+		cumulative += s.buckets[i]
+		snapshot.Durations.Counts[i] = cumulative
+	}
+
+	return snapshot
+}
+
+// scanDone records the outcome of identifying a file.
+func (s *stats) scanDone(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scans++
+
+	if err != nil {
+		if s.errors == nil {
+			s.errors = make(map[string]uint64)
+		}
+
+		s.errors[errorClass(err)]++
+	}
+}
+
+// cacheLookup records whether a scan was answered from the cache.
+func (s *stats) cacheLookup(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hit {
+		s.cacheHits++
+	} else {
+		s.cacheMisses++
+	}
+}
+
+// precheckHit records a scan answered by Options.Precheck.
+func (s *stats) precheckHit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prechecked++
+}
+
+// processStarted records the start of a TrID process.
+func (s *stats) processStarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processes++
+}
+
+// processDone records the end of a TrID process that ran for d.
+func (s *stats) processDone(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processes--
+
+	for i, bound := range durationBuckets {
+		if d.Seconds() <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+
+	s.count++
+	s.sum += d
+}
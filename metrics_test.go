@@ -0,0 +1,51 @@
+package trid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	dir := t.TempDir()
+	sample := filepath.Join(dir, "sample.pdf")
+	os.WriteFile(sample, []byte("%PDF-1.4"), 0o644)
+
+	trid := NewTrid(Options{Cmd: cmd, Cache: &mapCache{entries: make(map[string][]FileType)}})
+
+	trid.Scan(sample, 1)
+	trid.Scan(sample, 1)
+	trid.Scan(filepath.Join(dir, "missing.pdf"), 1)
+	trid.ScanBatch(context.Background(), []string{sample, ""}, 1)
+
+	s := trid.Stats()
+	if s.Scans != 5 {
+		t.Errorf("Scans = %d, want 5", s.Scans)
+	}
+
+	if s.Errors["file"] != 1 || s.Errors["invalid_argument"] != 1 || len(s.Errors) != 2 {
+		t.Errorf("Errors = %v, want one file and one invalid_argument error", s.Errors)
+	}
+
+	if s.CacheHits != 2 || s.CacheMisses != 1 {
+		t.Errorf("CacheHits, CacheMisses = %d, %d, want 2, 1", s.CacheHits, s.CacheMisses)
+	}
+
+	if s.Processes != 0 {
+		t.Errorf("Processes = %d, want 0", s.Processes)
+	}
+
+	if h := s.Durations; h.Count != 1 || h.Counts[len(h.Counts)-1] != 1 || h.Sum <= 0 {
+		t.Errorf("Durations = %+v, want a single observation", h)
+	}
+
+	// The snapshot must not share state with the instance
+	s.Errors["file"] = 10
+	s.Durations.Buckets[0] = 10
+	if s := trid.Stats(); s.Errors["file"] != 1 || s.Durations.Buckets[0] == 10 {
+		t.Errorf("Stats() snapshot shares state: %+v", s)
+	}
+}
@@ -0,0 +1,192 @@
+// Package middleware provides net/http middleware that identifies multipart
+// file uploads with TrID and enforces an upload policy.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/policy"
+)
+
+// ErrInvalidForm is passed to the ErrorHandler when the multipart form cannot
+// be parsed.
+var ErrInvalidForm = errors.New("invalid multipart form")
+
+// Scanner identifies files. *trid.Trid satisfies this interface.
+type Scanner interface {
+	ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error)
+}
+
+// Options configures the upload middleware.
+type Options struct {
+	Policy          *policy.Policy                                          // Policy to enforce; if nil, uploads are only annotated.
+	NumberOfMatches int                                                     // Number of matches to request per file (default: 5).
+	MaxMemory       int64                                                   // Maximum bytes of a form kept in memory (default: 32 MB).
+	ErrorHandler    func(w http.ResponseWriter, r *http.Request, err error) // Writes the response for rejected requests.
+}
+
+// Upload describes an identified file part of a multipart form.
+type Upload struct {
+	Field     string          // Name of the form field.
+	Filename  string          // File name supplied by the client.
+	Size      int64           // Size of the file in bytes.
+	FileTypes []trid.FileType // File types identified by TrID.
+	Decision  policy.Decision // Policy decision; zero value if no policy is configured.
+}
+
+// RejectedError is returned to the ErrorHandler when an upload is denied by
+// the policy.
+type RejectedError struct {
+	Upload Upload
+}
+
+// Error implements the error interface.
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("upload %q in field %q rejected: %s", e.Upload.Filename, e.Upload.Field, e.Upload.Decision.Reason)
+}
+
+type contextKey struct{}
+
+// Uploads returns the uploads identified by the middleware for the request
+// context, and whether the middleware processed the request.
+func Uploads(ctx context.Context) ([]Upload, bool) {
+	uploads, ok := ctx.Value(contextKey{}).([]Upload)
+	return uploads, ok
+}
+
+// New returns middleware that identifies every file part of multipart form
+// requests. If a policy is configured and any file is denied, the request is
+// rejected through the ErrorHandler; otherwise the identified uploads are
+// stored in the request context, retrievable with Uploads. Requests that are
+// not multipart forms are passed through untouched.
+func New(s Scanner, opts Options) func(http.Handler) http.Handler {
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = 32 << 20
+	}
+
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = defaultErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := r.ParseMultipartForm(opts.MaxMemory); err != nil {
+				opts.ErrorHandler(w, r, fmt.Errorf("%w: %v", ErrInvalidForm, err))
+				return
+			}
+
+			uploads := make([]Upload, 0)
+			for field, headers := range r.MultipartForm.File {
+				for _, fh := range headers {
+					u, err := identify(r.Context(), s, opts, field, fh)
+					if err != nil {
+						opts.ErrorHandler(w, r, err)
+						return
+					}
+
+					if opts.Policy != nil && !u.Decision.Allowed() {
+						opts.ErrorHandler(w, r, &RejectedError{Upload: u})
+						return
+					}
+
+					uploads = append(uploads, u)
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, uploads)))
+		})
+	}
+}
+
+// identify scans a single file part, spooling it to a temporary file if the
+// multipart reader kept it in memory.
+func identify(ctx context.Context, s Scanner, opts Options, field string, fh *multipart.FileHeader) (Upload, error) {
+	u := Upload{Field: field, Filename: fh.Filename, Size: fh.Size}
+
+	f, err := fh.Open()
+	if err != nil {
+		return u, err
+	}
+	defer f.Close()
+
+	path, cleanup, err := localPath(f)
+	if err != nil {
+		return u, err
+	}
+	defer cleanup()
+
+	u.FileTypes, err = s.ScanContext(ctx, path, opts.NumberOfMatches)
+	if err != nil && !errors.Is(err, trid.ErrUnknownFileType) {
+		return u, err
+	}
+
+	if opts.Policy != nil {
+		u.Decision = opts.Policy.Evaluate(u.FileTypes)
+	}
+
+	return u, nil
+}
+
+// localPath returns a path on disk holding the contents of f, and a function
+// that removes any temporary file it created.
+func localPath(f multipart.File) (string, func(), error) {
+	if osFile, ok := f.(*os.File); ok {
+		return osFile.Name(), func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "trid-upload-*")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	_, err = io.Copy(tmp, f)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// defaultErrorHandler responds with 415 for rejected uploads, 400 for
+// malformed forms, 422 for empty files, 413 for files above
+// Options.MaxFileSize of the Trid instance and 500 for other identification
+// failures.
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var rejected *RejectedError
+	switch {
+	case errors.As(err, &rejected):
+		http.Error(w, rejected.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, ErrInvalidForm):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, trid.ErrEmptyFile):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, trid.ErrFileTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, "file identification failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/policy"
+)
+
+// fakeScanner identifies files by their content.
+type fakeScanner map[string][]trid.FileType
+
+func (f fakeScanner) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if results, ok := f[string(b)]; ok {
+		return results, nil
+	}
+
+	return nil, trid.ErrUnknownFileType
+}
+
+var scanner = fakeScanner{
+	"%PDF-1.4": {{Extension: ".pdf", Probability: 100, MimeType: "application/pdf"}},
+	"MZ":       {{Extension: ".exe", Probability: 100}},
+}
+
+func newUploadRequest(t *testing.T, files map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.WriteField("comment", "hello")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	p := &policy.Policy{AllowCategories: []trid.Category{trid.CategoryDocument}}
+
+	tests := []struct {
+		name           string
+		files          map[string]string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"Allowed upload", map[string]string{"doc": "%PDF-1.4"}, http.StatusOK, 1},
+		{"Denied upload", map[string]string{"doc": "%PDF-1.4", "bin": "MZ"}, http.StatusUnsupportedMediaType, 0},
+		{"Unknown upload", map[string]string{"doc": "???"}, http.StatusUnsupportedMediaType, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploads []Upload
+			h := New(scanner, Options{Policy: p})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploads, _ = Uploads(r.Context())
+			}))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newUploadRequest(t, tt.files))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+
+			if len(uploads) != tt.expectedCount {
+				t.Errorf("got %d uploads, want %d", len(uploads), tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePassThrough(t *testing.T) {
+	called := false
+	h := New(scanner, Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := Uploads(r.Context()); ok {
+			t.Error("Uploads() reported processed request for non-multipart body")
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !called {
+		t.Error("next handler was not called")
+	}
+}
+
+func TestMiddlewareScanErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Empty file", trid.ErrEmptyFile, http.StatusUnprocessableEntity},
+		{"File too large", fmt.Errorf("%w: upload is 20 bytes, limit is 10", trid.ErrFileTooLarge), http.StatusRequestEntityTooLarge},
+		{"Other failure", trid.ErrTimeout, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scannerFunc(func(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+				return nil, tt.err
+			})
+			h := New(s, Options{})(http.NotFoundHandler())
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newUploadRequest(t, map[string]string{"doc": "%PDF-1.4"}))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+// scannerFunc adapts a function to the Scanner interface.
+type scannerFunc func(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error)
+
+func (f scannerFunc) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	return f(ctx, filePath, numberOfMatches)
+}
+
+func TestMiddlewareInvalidForm(t *testing.T) {
+	h := New(scanner, Options{})(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("garbage"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
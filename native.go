@@ -0,0 +1,186 @@
+package trid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/attilabuti/trid/native"
+)
+
+// EngineMode selects how files are identified.
+type EngineMode int
+
+const (
+	// EngineCLI runs the TrID executable.
+	EngineCLI EngineMode = iota
+
+	// EngineNative matches files against TrID definitions in XML form
+	// in-process, with the native package. Options.Definitions must name a
+	// directory of *.trid.xml files or a single one; otherwise every scan
+	// fails with ErrNoDefinitions.
+	EngineNative
+
+	// EngineAuto uses the native engine if Options.Definitions can be loaded
+	// by it, and the TrID executable otherwise.
+	EngineAuto
+)
+
+// String returns the name of the engine, as reported in Report.Engine.
+func (m EngineMode) String() string {
+	switch m {
+	case EngineCLI:
+		return "cli"
+	case EngineNative:
+		return "native"
+	case EngineAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("EngineMode(%d)", int(m))
+	}
+}
+
+// Engine returns the engine identifying files, EngineCLI or EngineNative,
+// once EngineAuto has been resolved.
+func (t *Trid) Engine() EngineMode {
+	return t.options.Engine
+}
+
+// resolveEngine loads the native definitions if needed by opts.Engine and
+// returns the engine to use, with the definitions if native.
+func resolveEngine(opts Options) (EngineMode, *native.Set, error) {
+	if opts.Engine == EngineCLI {
+		return EngineCLI, nil, nil
+	}
+
+	set, err := loadNative(opts.Definitions)
+	if err != nil {
+		if opts.Engine == EngineAuto {
+			return EngineCLI, nil, nil
+		}
+
+		return EngineNative, nil, fmt.Errorf("%w: %w", ErrNoDefinitions, err)
+	}
+
+	return EngineNative, set, nil
+}
+
+// loadNative loads the XML definitions at path, a directory or a single
+// definition file.
+func loadNative(path string) (*native.Set, error) {
+	if path == "" {
+		return nil, native.ErrNoDefinitions
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		return native.Load(os.DirFS(path))
+	}
+
+	if !strings.HasSuffix(strings.ToLower(path), ".xml") {
+		return nil, fmt.Errorf("%s is not an XML definition", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	def, err := native.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	def.File = filepath.Base(path)
+
+	return native.NewSet(def), nil
+}
+
+// runNativeBatch identifies several files with the native engine.
+func (t *Trid) runNativeBatch(ctx context.Context, paths []string, numberOfMatches int) ([]BatchResult, error) {
+	results := make([]BatchResult, len(paths))
+	for i, path := range paths {
+		r, err := t.runNative(ctx, path, numberOfMatches)
+		if isBatchError(ctx, err) {
+			return nil, err
+		}
+
+		results[i].Path = path
+		if results[i].Err = err; err == nil {
+			results[i].FileTypes = r.FileTypes
+		}
+	}
+
+	return results, nil
+}
+
+// runNative identifies a single file with the native engine.
+func (t *Trid) runNative(ctx context.Context, filePath string, numberOfMatches int) (*Report, error) {
+	if t.optsErr != nil {
+		return nil, t.optsErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	// Only the bytes the definitions need are read
+	matches, err := t.native.IdentifyReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.nativeReport(ctx, filePath, matches, numberOfMatches)
+}
+
+// identifyNative identifies data, the contents of the file at filePath or of
+// a region of it, with the native engine.
+func (t *Trid) identifyNative(ctx context.Context, filePath string, data []byte, numberOfMatches int) (*Report, error) {
+	return t.nativeReport(ctx, filePath, t.native.Identify(data), numberOfMatches)
+}
+
+// nativeReport returns the report of the native matches of the file at
+// filePath.
+func (t *Trid) nativeReport(ctx context.Context, filePath string, matches []native.Match, numberOfMatches int) (*Report, error) {
+	t.log(ctx, slog.LevelDebug, "identified file natively", "path", filePath, "definitions", t.native.Len(), "matches", len(matches))
+
+	if len(matches) == 0 {
+		return nil, ErrUnknownFileType
+	}
+
+	// Sort all matches before truncating, so the most likely ones are kept
+	fileTypes := make([]FileType, len(matches))
+	for i := range fileTypes {
+		d := matches[i].Definition
+		fileTypes[i] = FileType{
+			Extension:   d.Extension,
+			Probability: matches[i].Probability,
+			Name:        d.Name,
+			MimeType:    d.MimeType,
+			RelatedURL:  d.RelatedURL,
+			Remarks:     d.Remarks,
+			Definition:  d.File,
+		}
+	}
+
+	Results(fileTypes).SortByProbability()
+
+	return &Report{FileTypes: fileTypes[:min(len(fileTypes), numberOfMatches)], Engine: EngineNative.String()}, nil
+}
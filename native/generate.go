@@ -0,0 +1,139 @@
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Generate writes Go source code of package pkg identifying files with just
+// the given definitions, for deployments that only care about a few formats.
+// The code has no dependencies beyond the standard library and no definitions
+// to load: each definition is compiled to a function comparing its patterns
+// and strings with the data, and its points are computed in advance. It
+// defines:
+//
+//	type FileType struct { ... }          // File type identified, like Match.
+//	func Identify(data []byte) []FileType // Like Set.Identify.
+//
+// Probabilities are those Set.Identify computes with the same definitions.
+func Generate(w io.Writer, pkg string, defs ...*Definition) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	if len(defs) == 0 {
+		return ErrNoDefinitions
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by tridgen from %d TrID definitions; DO NOT EDIT.\n\n", len(defs))
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	haveStrings := false
+	for _, d := range defs {
+		haveStrings = haveStrings || len(d.Strings) > 0
+	}
+
+	if haveStrings {
+		b.WriteString("import (\n\t\"bytes\"\n\t\"math\"\n\t\"sort\"\n)\n\n")
+	} else {
+		b.WriteString("import (\n\t\"math\"\n\t\"sort\"\n)\n\n")
+	}
+
+	b.WriteString(`// FileType is a file type identified by Identify.
+type FileType struct {
+	Name        string  // Descriptive name of the file type.
+	Extension   string  // Extension, as reported by TrID (e.g., ".pdf", or "." if none).
+	MimeType    string  // MIME type, if known.
+	Definition  string  // Name of the definition file (e.g., "pdf.trid.xml").
+	Probability float64 // Share of the points of all matches, as a percentage.
+}
+
+// definition is a compiled definition.
+type definition struct {
+	fileType FileType
+	points   int
+	match    func(data, upper []byte) bool
+}
+
+`)
+
+	b.WriteString("var definitions = [...]definition{\n")
+	for i, d := range defs {
+		fmt.Fprintf(&b, "\t{FileType{Name: %q, Extension: %q, MimeType: %q, Definition: %q}, %d, match%d},\n",
+			d.Name, d.Extension, d.MimeType, d.File, d.points(), i)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString(`// Identify returns the file types data matches, most likely first. data
+// should be the whole file, as strings may be anywhere in it.
+func Identify(data []byte) []FileType {
+`)
+	if haveStrings {
+		b.WriteString("\tupper := bytes.ToUpper(data)\n")
+	} else {
+		b.WriteString("\tvar upper []byte\n")
+	}
+	b.WriteString(`
+	var matches []definition
+	total := 0
+	for _, d := range definitions {
+		if d.match(data, upper) {
+			matches = append(matches, d)
+			total += d.points
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].points > matches[j].points
+	})
+
+	fileTypes := make([]FileType, len(matches))
+	for i, m := range matches {
+		fileTypes[i] = m.fileType
+		fileTypes[i].Probability = math.Round(float64(m.points)*1000/float64(total)) / 10
+	}
+
+	return fileTypes
+}
+`)
+
+	for i, d := range defs {
+		fmt.Fprintf(&b, "\n// match%d matches %s.\n", i, strings.Join(strings.Fields(d.Name), " "))
+		fmt.Fprintf(&b, "func match%d(data, upper []byte) bool {\n", i)
+		var conditions []string
+		for _, p := range d.Patterns {
+			end := p.Offset + len(p.Bytes)
+			conditions = append(conditions, fmt.Sprintf("len(data) >= %d && string(data[%d:%d]) == %s", end, p.Offset, end, strconv.Quote(string(p.Bytes))))
+		}
+		for _, s := range d.Strings {
+			conditions = append(conditions, fmt.Sprintf("bytes.Contains(upper, []byte(%s))", strconv.Quote(string(s))))
+		}
+
+		if len(conditions) == 0 {
+			conditions = append(conditions, "true")
+		}
+
+		b.WriteString("\treturn ")
+		for j, c := range conditions {
+			if j > 0 {
+				b.WriteString(" &&\n\t\t")
+			}
+			b.WriteString(c)
+		}
+		b.WriteString("\n}\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated invalid source: %w", err)
+	}
+
+	_, err = w.Write(src)
+	return err
+}
@@ -0,0 +1,95 @@
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var src bytes.Buffer
+	if err := Generate(&src, "formats", set.Definitions()...); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "formats_gen.go", src.Bytes(), 0); err != nil {
+		t.Fatalf("Generate() source does not parse: %v\n%s", err, src.String())
+	}
+
+	if err := Generate(&src, "not a package", set.Definitions()...); err == nil {
+		t.Error("Generate() accepted an invalid package name")
+	}
+
+	if err := Generate(&src, "formats"); err != ErrNoDefinitions {
+		t.Errorf("Generate() error = %v, want %v", err, ErrNoDefinitions)
+	}
+}
+
+// TestGenerateIdentify checks that the generated code identifies files like
+// Set.Identify, by running it.
+func TestGenerateIdentify(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var src bytes.Buffer
+	if err := Generate(&src, "main", set.Definitions()...); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := []string{"%PDF-1.4\n1 0 obj\nendobj\n", "%PDF-1.4\n", "7z\xbc\xaf\x27\x1c\x00\x04", "plain text"}
+	main := "package main\n\nimport \"fmt\"\n\nfunc main() {\n"
+	for _, s := range samples {
+		main += fmt.Sprintf("\tfor _, ft := range Identify([]byte(%q)) {\n\t\tfmt.Printf(\"%%s %%s %%.1f\\n\", ft.Definition, ft.Extension, ft.Probability)\n\t}\n\tfmt.Println(\"--\")\n", s)
+	}
+	main += "}\n"
+
+	files := map[string]string{"go.mod": "module formats\n\ngo 1.22\n", "formats_gen.go": src.String(), "main.go": main}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goCmd, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOTOOLCHAIN=local")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("running the generated code: %v\n%s", err, out)
+	}
+
+	var expected strings.Builder
+	for _, s := range samples {
+		for _, m := range set.Identify([]byte(s)) {
+			fmt.Fprintf(&expected, "%s %s %.1f\n", m.Definition.File, m.Definition.Extension, m.Probability)
+		}
+		expected.WriteString("--\n")
+	}
+
+	if string(out) != expected.String() {
+		t.Errorf("generated Identify() =\n%s\nwant\n%s", out, expected.String())
+	}
+}
@@ -0,0 +1,442 @@
+// Package native identifies files without the TrID executable, by matching
+// them against TrID definitions in XML form (*.trid.xml, as created by
+// TrIDScan and distributed in the TrIDDefs XML package).
+//
+// The package does not start processes nor access the file system other than
+// through the fs.FS passed to Load, so it can be used wherever Go runs.
+package native
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ErrNoDefinitions is returned by Load when no definitions are found.
+var ErrNoDefinitions = errors.New("no TrID XML definitions found")
+
+// Points of each byte of the patterns and strings of a definition. Like in
+// TrID, patterns at the start of the file weigh more than other patterns,
+// which weigh as much as strings found anywhere in the file.
+const (
+	frontPoints   = 1000
+	patternPoints = 500
+	stringPoints  = 500
+)
+
+// Definition is a TrID file type definition.
+type Definition struct {
+	File       string    // Name of the definition file (e.g., "pdf.trid.xml").
+	Name       string    // Descriptive name of the file type.
+	Extension  string    // Extension, as reported by TrID (e.g., ".pdf", ".jpg/jpeg", or "." if none).
+	MimeType   string    // MIME type, if known.
+	RelatedURL string    // URL for additional information about the file type.
+	Remarks    string    // Notes about the file type.
+	Patterns   []Pattern // Byte patterns the file must contain.
+	Strings    [][]byte  // Strings the file must contain anywhere, in upper case.
+}
+
+// Pattern is a sequence of bytes at a given offset from the start of a file.
+type Pattern struct {
+	Offset int
+	Bytes  []byte
+}
+
+// Match is a definition a file matches.
+type Match struct {
+	Definition  *Definition
+	Points      int     // Points of the patterns and strings matched.
+	Probability float64 // Share of the points of all matches, as a percentage rounded like TrID's.
+}
+
+// Set is a set of definitions. It is safe for concurrent use.
+type Set struct {
+	defs        []*Definition
+	haveStrings bool     // Whether any definition has strings.
+	head        int      // Number of bytes from the start of a file the patterns reach.
+	strings     [][]byte // Distinct strings of the definitions.
+	longest     int      // Length of the longest string.
+	sum         string   // SHA-256 checksum of the definitions.
+}
+
+// xmlDefinition is the XML form of a definition.
+type xmlDefinition struct {
+	Info struct {
+		FileType  string `xml:"FileType"`
+		Ext       string `xml:"Ext"`
+		Mime      string `xml:"Mime"`
+		ExtraInfo struct {
+			Rem    string `xml:"Rem"`
+			RefURL string `xml:"RefURL"`
+		} `xml:"ExtraInfo"`
+	} `xml:"Info"`
+	General struct {
+		CheckStrings string `xml:"CheckStrings"`
+	} `xml:"General"`
+	Patterns []struct {
+		Bytes string `xml:"Bytes"`
+		Pos   int    `xml:"Pos"`
+	} `xml:"FrontBlock>Pattern"`
+	Strings []string `xml:"GlobalStrings>String"`
+}
+
+// Parse parses a definition in XML form. Definitions are usually encoded in
+// ISO-8859-1, which is supported along with UTF-8.
+func Parse(r io.Reader) (*Definition, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+
+	var x xmlDefinition
+	if err := dec.Decode(&x); err != nil {
+		return nil, err
+	}
+
+	if x.Info.FileType == "" {
+		return nil, errors.New("definition has no file type")
+	}
+
+	def := &Definition{
+		Name:       strings.TrimSpace(x.Info.FileType),
+		Extension:  "." + strings.ToLower(strings.TrimSpace(x.Info.Ext)),
+		MimeType:   strings.TrimSpace(x.Info.Mime),
+		RelatedURL: strings.TrimSpace(x.Info.ExtraInfo.RefURL),
+		Remarks:    strings.TrimSpace(x.Info.ExtraInfo.Rem),
+	}
+
+	for _, p := range x.Patterns {
+		b, err := hex.DecodeString(strings.Join(strings.Fields(p.Bytes), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern bytes: %w", err)
+		}
+
+		if p.Pos < 0 {
+			return nil, fmt.Errorf("invalid pattern position %d", p.Pos)
+		}
+
+		def.Patterns = append(def.Patterns, Pattern{Offset: p.Pos, Bytes: b})
+	}
+
+	if strings.EqualFold(strings.TrimSpace(x.General.CheckStrings), "true") {
+		for _, s := range x.Strings {
+			if s != "" {
+				def.Strings = append(def.Strings, bytes.ToUpper([]byte(s)))
+			}
+		}
+	}
+
+	if len(def.Patterns) == 0 && len(def.Strings) == 0 {
+		return nil, errors.New("definition has neither patterns nor strings")
+	}
+
+	return def, nil
+}
+
+// charsetReader decodes ISO-8859-1, the encoding of TrID definitions.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	b, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+
+	return strings.NewReader(string(runes)), nil
+}
+
+// NewSet returns a set of the given definitions.
+func NewSet(defs ...*Definition) *Set {
+	s := &Set{defs: defs}
+
+	seen := make(map[string]bool)
+	h := sha256.New()
+	for _, d := range defs {
+		s.haveStrings = s.haveStrings || len(d.Strings) > 0
+
+		for _, p := range d.Patterns {
+			s.head = max(s.head, p.Offset+len(p.Bytes))
+		}
+
+		for _, str := range d.Strings {
+			if !seen[string(str)] {
+				seen[string(str)] = true
+				s.strings = append(s.strings, str)
+				s.longest = max(s.longest, len(str))
+			}
+		}
+
+		d.hash(h)
+	}
+	s.sum = hex.EncodeToString(h.Sum(nil))
+
+	return s
+}
+
+// hash writes the fields of d to h, each prefixed with its length.
+func (d *Definition) hash(h io.Writer) {
+	field := func(b []byte) {
+		binary.Write(h, binary.BigEndian, uint32(len(b)))
+		h.Write(b)
+	}
+
+	for _, f := range []string{d.File, d.Name, d.Extension, d.MimeType, d.RelatedURL, d.Remarks} {
+		field([]byte(f))
+	}
+
+	binary.Write(h, binary.BigEndian, uint32(len(d.Patterns)))
+	for _, p := range d.Patterns {
+		binary.Write(h, binary.BigEndian, uint32(p.Offset))
+		field(p.Bytes)
+	}
+
+	binary.Write(h, binary.BigEndian, uint32(len(d.Strings)))
+	for _, str := range d.Strings {
+		field(str)
+	}
+}
+
+// Load loads the definitions (*.trid.xml) found in fsys, in any directory.
+// It returns ErrNoDefinitions if there are none.
+func Load(fsys fs.FS) (*Set, error) {
+	var defs []*Definition
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(p), ".trid.xml") {
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		def, err := Parse(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		def.File = path.Base(p)
+		defs = append(defs, def)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(defs) == 0 {
+		return nil, ErrNoDefinitions
+	}
+
+	return NewSet(defs...), nil
+}
+
+// Len returns the number of definitions in the set.
+func (s *Set) Len() int {
+	return len(s.defs)
+}
+
+// Definitions returns the definitions in the set, in the order they were
+// loaded.
+func (s *Set) Definitions() []*Definition {
+	return slices.Clone(s.defs)
+}
+
+// Fingerprint returns the SHA-256 checksum of the definitions in the set, in
+// hexadecimal. Sets of the same definitions, loaded in the same order, have
+// the same fingerprint.
+func (s *Set) Fingerprint() string {
+	return s.sum
+}
+
+// Identify returns the definitions data matches, most likely first. data
+// should be the whole file, as strings may be anywhere in it.
+func (s *Set) Identify(data []byte) []Match {
+	var upper []byte
+	if s.haveStrings {
+		upper = bytes.ToUpper(data)
+	}
+
+	return s.identify(data, func(str []byte) bool { return bytes.Contains(upper, str) })
+}
+
+// readChunk is the size of the chunks IdentifyReader searches for strings.
+const readChunk = 64 << 10
+
+// IdentifyReader is like Identify but reads the file from r, keeping only
+// the bytes the patterns of the definitions reach in memory. The rest of the
+// file is read, in chunks, only if a definition has strings.
+func (s *Set) IdentifyReader(r io.Reader) ([]Match, error) {
+	head := make([]byte, s.head)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	found := make(map[string]bool)
+	if len(s.strings) > 0 {
+		if err := s.findStrings(io.MultiReader(bytes.NewReader(head), r), found); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.identify(head, func(str []byte) bool { return found[string(str)] }), nil
+}
+
+// findStrings reads r to its end, adding the strings of the set found in it,
+// regardless of case, to found. Consecutive chunks overlap by the length of
+// the longest string but one, so that strings across chunks are found.
+func (s *Set) findStrings(r io.Reader, found map[string]bool) error {
+	buf := make([]byte, s.longest-1+readChunk)
+	kept := 0
+	for {
+		n, err := io.ReadFull(r, buf[kept:])
+		chunk := bytes.ToUpper(buf[:kept+n])
+
+		for _, str := range s.strings {
+			if !found[string(str)] && bytes.Contains(chunk, str) {
+				found[string(str)] = true
+			}
+		}
+
+		if len(found) == len(s.strings) || err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		kept = min(s.longest-1, kept+n)
+		copy(buf, buf[len(chunk)-kept:len(chunk)])
+	}
+}
+
+// identify returns the definitions matching a file starting with data, most
+// likely first. contains reports whether the file contains a string, in
+// upper case.
+func (s *Set) identify(data []byte, contains func(str []byte) bool) []Match {
+	var matches []Match
+	total := 0
+	for _, d := range s.defs {
+		if points, ok := d.match(data, contains); ok {
+			matches = append(matches, Match{Definition: d, Points: points})
+			total += points
+		}
+	}
+
+	for i := range matches {
+		matches[i].Probability = math.Round(float64(matches[i].Points)*1000/float64(total)) / 10
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return b.Points - a.Points
+	})
+
+	return matches
+}
+
+// minAnchor is the length below which patterns are too common to locate
+// embedded files by.
+const minAnchor = 3
+
+// Candidates returns the offsets in data, in increasing order, at which a file
+// matching a definition may start: those from which the longest pattern of a
+// definition is found at its offset. Definitions whose patterns are all
+// shorter than 3 bytes are left out, as their patterns are found all over.
+// Files found at the offsets can be identified by passing Identify the data
+// from them.
+func (s *Set) Candidates(data []byte) []int {
+	seen := make(map[int]bool)
+	for _, d := range s.defs {
+		var anchor *Pattern
+		for i, p := range d.Patterns {
+			if len(p.Bytes) >= minAnchor && (anchor == nil || len(p.Bytes) > len(anchor.Bytes)) {
+				anchor = &d.Patterns[i]
+			}
+		}
+
+		if anchor == nil {
+			continue
+		}
+
+		for i := 0; ; {
+			j := bytes.Index(data[i:], anchor.Bytes)
+			if j < 0 {
+				break
+			}
+
+			if start := i + j - anchor.Offset; start >= 0 {
+				seen[start] = true
+			}
+			i += j + 1
+		}
+	}
+
+	offsets := make([]int, 0, len(seen))
+	for off := range seen {
+		offsets = append(offsets, off)
+	}
+	slices.Sort(offsets)
+
+	return offsets
+}
+
+// match reports whether a file starting with data matches d, and its points.
+// contains reports whether the file contains a string, in upper case.
+func (d *Definition) match(data []byte, contains func(str []byte) bool) (int, bool) {
+	for _, p := range d.Patterns {
+		if p.Offset+len(p.Bytes) > len(data) || !bytes.Equal(data[p.Offset:p.Offset+len(p.Bytes)], p.Bytes) {
+			return 0, false
+		}
+	}
+
+	for _, s := range d.Strings {
+		if !contains(s) {
+			return 0, false
+		}
+	}
+
+	return d.points(), true
+}
+
+// points returns the points of files matching d.
+func (d *Definition) points() int {
+	points := 0
+	for _, p := range d.Patterns {
+		if p.Offset == 0 {
+			points += len(p.Bytes) * frontPoints
+		} else {
+			points += len(p.Bytes) * patternPoints
+		}
+	}
+
+	for _, s := range d.Strings {
+		points += len(s) * stringPoints
+	}
+
+	return points
+}
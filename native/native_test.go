@@ -0,0 +1,221 @@
+package native
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if set.Len() != 3 {
+		t.Errorf("Load() loaded %d definitions, want 3", set.Len())
+	}
+
+	if _, err := Load(fstest.MapFS{"readme.txt": {Data: []byte("none")}}); !errors.Is(err, ErrNoDefinitions) {
+		t.Errorf("Load() error = %v, want %v", err, ErrNoDefinitions)
+	}
+
+	if _, err := Load(fstest.MapFS{"bad.trid.xml": {Data: []byte("<TrID>")}}); err == nil || !strings.Contains(err.Error(), "bad.trid.xml") {
+		t.Errorf("Load() error = %v, want an error naming bad.trid.xml", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("../testdata/defs/7z.trid.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	def, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if def.Name != "7-Zip compressed archive" || def.Extension != ".7z" || def.Remarks != "Format créé par Igor Pavlov" {
+		t.Errorf("Parse() = %+v", def)
+	}
+
+	if len(def.Patterns) != 1 || def.Patterns[0].Offset != 0 || string(def.Patterns[0].Bytes) != "7z\xbc\xaf\x27\x1c" {
+		t.Errorf("Parse() patterns = %+v", def.Patterns)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile("../testdata/sample.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches := set.Identify(data)
+	if len(matches) != 2 {
+		t.Fatalf("Identify() = %+v, want 2 matches", matches)
+	}
+
+	// 7 front bytes and a 6-byte string, against 4 front bytes
+	pdf, generic := matches[0], matches[1]
+	if pdf.Definition.File != "pdf.trid.xml" || pdf.Points != 10000 || pdf.Probability != 71.4 {
+		t.Errorf("Identify()[0] = %+v %+v", pdf, pdf.Definition)
+	}
+
+	if generic.Definition.Extension != "." || generic.Points != 4000 || generic.Probability != 28.6 {
+		t.Errorf("Identify()[1] = %+v %+v", generic, generic.Definition)
+	}
+
+	if matches := set.Identify([]byte("%PDF-1.4 without objects")); len(matches) != 1 || matches[0].Probability != 100 {
+		t.Errorf("Identify() = %+v, want only the generic definition", matches)
+	}
+
+	if matches := set.Identify(nil); len(matches) != 0 {
+		t.Errorf("Identify(nil) = %+v", matches)
+	}
+}
+
+func TestIdentifyReader(t *testing.T) {
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile("../testdata/sample.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := set.IdentifyReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("IdentifyReader() error = %v", err)
+	}
+
+	if expected := set.Identify(data); !reflect.DeepEqual(matches, expected) {
+		t.Errorf("IdentifyReader() = %+v, want %+v", matches, expected)
+	}
+
+	// Strings are found across chunks, regardless of case
+	set = NewSet(&Definition{Name: "Marked", Extension: ".m", Patterns: []Pattern{{Bytes: []byte("MK")}}, Strings: [][]byte{[]byte("MARKER")}})
+	data = make([]byte, 3*readChunk)
+	copy(data, "MK")
+	copy(data[readChunk-3:], "marker")
+
+	if matches, err := set.IdentifyReader(bytes.NewReader(data)); err != nil || len(matches) != 1 {
+		t.Errorf("IdentifyReader() = %+v, %v, want a match", matches, err)
+	}
+
+	// Without strings, only the bytes the patterns reach are read
+	set = NewSet(&Definition{Name: "Tar archive", Extension: ".tar", Patterns: []Pattern{{Offset: 257, Bytes: []byte("ustar")}}})
+	data = make([]byte, 1000)
+	copy(data[257:], "ustar")
+
+	r := bytes.NewReader(data)
+	if matches, err := set.IdentifyReader(r); err != nil || len(matches) != 1 {
+		t.Errorf("IdentifyReader() = %+v, %v, want a match", matches, err)
+	}
+
+	if read := len(data) - r.Len(); read != 262 {
+		t.Errorf("IdentifyReader() read %d bytes, want 262", read)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a.Fingerprint()) != 64 || a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("Fingerprint() = %q and %q, want the same checksum", a.Fingerprint(), b.Fingerprint())
+	}
+
+	defs := a.Definitions()
+	if c := NewSet(defs[:len(defs)-1]...); c.Fingerprint() == a.Fingerprint() {
+		t.Errorf("Fingerprint() of different definitions = %q, want another checksum", c.Fingerprint())
+	}
+}
+
+func TestPatternOffset(t *testing.T) {
+	set := NewSet(&Definition{Name: "Tar archive", Extension: ".tar", Patterns: []Pattern{{Offset: 257, Bytes: []byte("ustar")}}})
+
+	data := make([]byte, 300)
+	copy(data[257:], "ustar")
+
+	if matches := set.Identify(data); len(matches) != 1 || matches[0].Points != 5*patternPoints {
+		t.Errorf("Identify() = %+v", matches)
+	}
+
+	if matches := set.Identify(data[:260]); len(matches) != 0 {
+		t.Errorf("Identify() of truncated data = %+v", matches)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	set := NewSet(
+		&Definition{Name: "Tar archive", Extension: ".tar", Patterns: []Pattern{{Offset: 257, Bytes: []byte("ustar")}}},
+		&Definition{Name: "GIF image", Extension: ".gif", Patterns: []Pattern{{Bytes: []byte("GIF8")}, {Offset: 5, Bytes: []byte("a")}}},
+		&Definition{Name: "Short", Extension: ".s", Patterns: []Pattern{{Bytes: []byte("a")}}},
+	)
+
+	data := make([]byte, 1000)
+	copy(data[10:], "GIF89a")
+	copy(data[100+257:], "ustar")
+	copy(data[500:], "GIF87a")
+	copy(data[200:], "ustar") // Too close to the start to be a tar archive
+
+	if offsets := set.Candidates(data); !slices.Equal(offsets, []int{10, 100, 500}) {
+		t.Errorf("Candidates() = %v, want [10 100 500]", offsets)
+	}
+
+	if matches := set.Identify(data[100:]); len(matches) != 1 || matches[0].Definition.Extension != ".tar" {
+		t.Errorf("Identify() at a candidate = %+v", matches)
+	}
+}
+
+// TestPortable checks that the package stays free of dependencies preventing
+// its use in WebAssembly.
+func TestPortable(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forbidden := []string{"C", "net", "net/http", "os", "os/exec", "os/signal", "syscall", "unsafe"}
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), name, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, imp := range f.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); slices.Contains(forbidden, path) {
+				t.Errorf("%s imports %s", name, path)
+			}
+		}
+	}
+}
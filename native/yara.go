@@ -0,0 +1,108 @@
+package native
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// YARA returns d as a YARA rule named name: its patterns must be found at
+// their offsets, and its strings anywhere in the file, regardless of the case
+// of ASCII letters. The name must be a valid YARA identifier; RuleName
+// derives one from the definition.
+func (d *Definition) YARA(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rule %s\n{\n", name)
+
+	b.WriteString("    meta:\n")
+	writeMeta(&b, "description", d.Name)
+	writeMeta(&b, "extension", d.Extension)
+	writeMeta(&b, "mime", d.MimeType)
+	writeMeta(&b, "url", d.RelatedURL)
+	writeMeta(&b, "remarks", d.Remarks)
+	writeMeta(&b, "source", d.File)
+
+	b.WriteString("    strings:\n")
+	var conditions []string
+	for i, p := range d.Patterns {
+		fmt.Fprintf(&b, "        $p%d = { % X }\n", i, p.Bytes)
+		conditions = append(conditions, fmt.Sprintf("$p%d at %d", i, p.Offset))
+	}
+	for i, s := range d.Strings {
+		fmt.Fprintf(&b, "        $s%d = \"%s\" nocase\n", i, yaraEscape(string(s)))
+		conditions = append(conditions, fmt.Sprintf("$s%d", i))
+	}
+
+	fmt.Fprintf(&b, "    condition:\n        %s\n}\n", strings.Join(conditions, " and "))
+
+	return b.String()
+}
+
+// RuleName returns the YARA identifier of d: "trid_" followed by the name of
+// its definition file without extension, or by its file type if unknown,
+// with characters YARA does not allow replaced by underscores.
+func (d *Definition) RuleName() string {
+	base := strings.TrimSuffix(d.File, ".trid.xml")
+	if base == "" {
+		base = d.Name
+	}
+
+	id := []byte("trid_")
+	for _, c := range []byte(base) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			id = append(id, c)
+		default:
+			id = append(id, '_')
+		}
+	}
+
+	// YARA limits identifiers to 128 characters
+	return string(id[:min(len(id), 128)])
+}
+
+// WriteYARA writes the definitions of s to w as YARA rules, in the order they
+// were loaded. Rules whose names clash are numbered.
+func (s *Set) WriteYARA(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// %d rules converted from TrID definitions\n", len(s.defs))
+
+	seen := make(map[string]int)
+	for _, d := range s.defs {
+		name := d.RuleName()
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name[:min(len(name), 120)], seen[name])
+		}
+
+		fmt.Fprintf(bw, "\n%s", d.YARA(name))
+	}
+
+	return bw.Flush()
+}
+
+// writeMeta writes a meta field of a rule, if value is not empty.
+func writeMeta(b *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "        %s = \"%s\"\n", key, yaraEscape(value))
+	}
+}
+
+// yaraEscape escapes s for a YARA text string: quotes, backslashes and bytes
+// other than printable ASCII are escaped.
+func yaraEscape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, "\\x%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package native
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestYARA(t *testing.T) {
+	def := &Definition{
+		File:      "pdf.trid.xml",
+		Name:      `Adobe "PDF"`,
+		Extension: ".pdf",
+		MimeType:  "application/pdf",
+		Patterns:  []Pattern{{Offset: 0, Bytes: []byte("%PDF")}, {Offset: 8, Bytes: []byte{0x0a, 0xff}}},
+		Strings:   [][]byte{[]byte("ENDOBJ"), []byte("CAF\xc9")},
+	}
+
+	expected := `rule trid_pdf
+{
+    meta:
+        description = "Adobe \"PDF\""
+        extension = ".pdf"
+        mime = "application/pdf"
+        source = "pdf.trid.xml"
+    strings:
+        $p0 = { 25 50 44 46 }
+        $p1 = { 0A FF }
+        $s0 = "ENDOBJ" nocase
+        $s1 = "CAF\xC9" nocase
+    condition:
+        $p0 at 0 and $p1 at 8 and $s0 and $s1
+}
+`
+	if actual := def.YARA(def.RuleName()); actual != expected {
+		t.Errorf("YARA() = %s, want %s", actual, expected)
+	}
+}
+
+func TestRuleName(t *testing.T) {
+	tests := []struct {
+		def      Definition
+		expected string
+	}{
+		{Definition{File: "7z.trid.xml"}, "trid_7z"},
+		{Definition{File: "bitmap-os2.trid.xml"}, "trid_bitmap_os2"},
+		{Definition{Name: "Generic PDF document"}, "trid_Generic_PDF_document"},
+		{Definition{File: strings.Repeat("a", 200) + ".trid.xml"}, "trid_" + strings.Repeat("a", 123)},
+	}
+
+	for _, tt := range tests {
+		if actual := tt.def.RuleName(); actual != tt.expected {
+			t.Errorf("RuleName() = %q, want %q", actual, tt.expected)
+		}
+	}
+}
+
+func TestWriteYARA(t *testing.T) {
+	set, err := Load(os.DirFS("../testdata/defs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := set.WriteYARA(&buf); err != nil {
+		t.Fatalf("WriteYARA() error = %v", err)
+	}
+
+	for _, s := range []string{"rule trid_7z\n", "rule trid_pdf\n", "rule trid_pdfgeneric\n", `"Format cr\xC3\xA9\xC3\xA9 par Igor Pavlov"`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("WriteYARA() output does not contain %q:\n%s", s, buf.String())
+		}
+	}
+
+	dup := NewSet(&Definition{File: "a.trid.xml", Strings: [][]byte{[]byte("A")}}, &Definition{File: "a.trid.xml", Strings: [][]byte{[]byte("B")}})
+	buf.Reset()
+	if err := dup.WriteYARA(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "rule trid_a\n") || !strings.Contains(buf.String(), "rule trid_a_2\n") {
+		t.Errorf("WriteYARA() does not number clashing rules:\n%s", buf.String())
+	}
+}
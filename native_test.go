@@ -0,0 +1,143 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/attilabuti/trid/native"
+)
+
+func TestEngineNative(t *testing.T) {
+	tr := NewTrid(Options{Cmd: "missing-trid", Definitions: "testdata/defs", Engine: EngineNative})
+
+	if tr.Engine() != EngineNative {
+		t.Fatalf("Engine() = %v, want native", tr.Engine())
+	}
+
+	r, err := tr.ScanReport(context.Background(), "testdata/sample.pdf", 5)
+	if err != nil {
+		t.Fatalf("ScanReport() error = %v", err)
+	}
+
+	if r.Engine != "native" || len(r.FileTypes) != 2 {
+		t.Fatalf("ScanReport() = %+v, want 2 native matches", r)
+	}
+
+	expected := FileType{Extension: ".pdf", Probability: 71.4, Name: "Adobe Portable Document Format", MimeType: "application/pdf",
+		RelatedURL: "http://www.adobe.com/", Definition: "pdf.trid.xml"}
+	if r.FileTypes[0] != expected {
+		t.Errorf("ScanReport()[0] = %+v, want %+v", r.FileTypes[0], expected)
+	}
+
+	if _, err := tr.Scan("testdata/sample.unknown", 1); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("Scan() error = %v, want %v", err, ErrUnknownFileType)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{"testdata/sample.7z", "testdata/sample.unknown", "missing"}, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	if len(results[0].FileTypes) != 1 || results[0].FileTypes[0].Extension != ".7z" {
+		t.Errorf("ScanBatch()[0] = %+v, want .7z", results[0])
+	}
+
+	for i, expected := range []error{nil, ErrUnknownFileType, ErrFileNotFound} {
+		if !errors.Is(results[i].Err, expected) {
+			t.Errorf("ScanBatch()[%d] error = %v, want %v", i, results[i].Err, expected)
+		}
+	}
+}
+
+func TestEngineNativeSingleDefinition(t *testing.T) {
+	tr := NewTrid(Options{Definitions: "testdata/defs/7z.trid.xml", Engine: EngineNative})
+
+	fileTypes, err := tr.Scan("testdata/sample.7z", 1)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0].Probability != 100 || fileTypes[0].Definition != "7z.trid.xml" {
+		t.Errorf("Scan() = %+v, %v, want 7z.trid.xml", fileTypes, err)
+	}
+}
+
+func TestNativeReportTruncate(t *testing.T) {
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	matches := []native.Match{
+		{Definition: &native.Definition{File: "zip.trid.xml", Extension: ".zip"}, Probability: 40},
+		{Definition: &native.Definition{File: "jar.trid.xml", Extension: ".jar"}, Probability: 40},
+		{Definition: &native.Definition{File: "docx.trid.xml", Extension: ".docx"}, Probability: 20},
+	}
+
+	r, err := tr.nativeReport(context.Background(), "sample.jar", matches, 1)
+	if err != nil || len(r.FileTypes) != 1 || r.FileTypes[0].Definition != "jar.trid.xml" {
+		t.Errorf("nativeReport() = %+v, %v, want the first of all matches sorted", r, err)
+	}
+}
+
+func TestEngineNativeNoDefinitions(t *testing.T) {
+	for _, defs := range []string{"", "testdata/empty_def", "missing"} {
+		tr := NewTrid(Options{Definitions: defs, Engine: EngineNative})
+
+		if _, err := tr.Scan("testdata/sample.pdf", 1); !errors.Is(err, ErrNoDefinitions) {
+			t.Errorf("Scan() with %q error = %v, want %v", defs, err, ErrNoDefinitions)
+		}
+
+		results, err := tr.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 1)
+		if !errors.Is(err, ErrNoDefinitions) {
+			t.Errorf("ScanBatch() with %q = %+v, %v, want %v", defs, results, err, ErrNoDefinitions)
+		}
+	}
+}
+
+func TestEngineNativeProbeAndCache(t *testing.T) {
+	c := &mapCache{entries: make(map[string][]FileType)}
+	tr := NewTrid(Options{Cmd: "missing-trid", Definitions: "testdata/defs", Engine: EngineNative, Cache: c})
+
+	if err := tr.Probe(context.Background()); err != nil {
+		t.Errorf("Probe() error = %v", err)
+	}
+
+	if sum, err := tr.DefinitionsFingerprint(); err != nil || len(sum) != 64 {
+		t.Errorf("DefinitionsFingerprint() = %q, %v, want a SHA-256 checksum", sum, err)
+	}
+
+	if _, err := tr.Scan("testdata/sample.pdf", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.entries) != 1 {
+		t.Errorf("cache has %d entries after a scan, want 1", len(c.entries))
+	}
+}
+
+func TestEngineAuto(t *testing.T) {
+	tests := []struct {
+		defs     string
+		expected EngineMode
+	}{
+		{"testdata/defs", EngineNative},
+		{"testdata/defs/pdf.trid.xml", EngineNative},
+		{"testdata/empty_def", EngineCLI},
+		{"", EngineCLI},
+	}
+
+	for _, tt := range tests {
+		if engine := NewTrid(Options{Definitions: tt.defs, Engine: EngineAuto}).Engine(); engine != tt.expected {
+			t.Errorf("Engine() with %q = %v, want %v", tt.defs, engine, tt.expected)
+		}
+	}
+}
+
+func TestEngineCLIReport(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+	tr := NewTrid(Options{Cmd: cmd})
+
+	r, err := tr.ScanReport(context.Background(), "testdata/sample.pdf", 1)
+	if err != nil {
+		t.Fatalf("ScanReport() error = %v", err)
+	}
+
+	if r.Engine != "cli" {
+		t.Errorf("ScanReport() engine = %q, want cli", r.Engine)
+	}
+}
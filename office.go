@@ -0,0 +1,245 @@
+package trid
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Containers of Office documents reported in Office.Container.
+const (
+	OfficeOLE   = "ole"   // Legacy OLE compound file, such as .doc, .xls and .ppt.
+	OfficeOOXML = "ooxml" // Office Open XML, such as .docx, .xlsx and .pptx.
+	OfficeODF   = "odf"   // OpenDocument, such as .odt and .ods.
+)
+
+// oleMagic is the signature of OLE compound files.
+var oleMagic = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+// oleMacroEntries are the storages and streams of OLE compound files holding
+// VBA projects.
+var oleMacroEntries = map[string]bool{
+	"Macros": true, "_VBA_PROJECT_CUR": true, "_VBA_PROJECT": true, "VBA": true,
+}
+
+// Office describes the container of an Office document, with Options.Office.
+type Office struct {
+	Container string `json:"container"`          // OfficeOLE, OfficeOOXML or OfficeODF.
+	Macros    bool   `json:"macros"`             // Whether the document holds VBA, Excel 4 or OpenDocument Basic macros.
+	Encrypted bool   `json:"encrypted"`          // Whether the document is password-protected, for OOXML documents stored encrypted in an OLE file.
+	Evidence  string `json:"evidence,omitempty"` // Entry the macros were found in (e.g., "word/vbaProject.bin", "Macros").
+}
+
+// officeExts are the extensions of Office document matches.
+var officeExts = map[string]bool{
+	".doc": true, ".dot": true, ".xls": true, ".xlt": true, ".ppt": true, ".pot": true, ".pps": true,
+	".docx": true, ".docm": true, ".dotx": true, ".dotm": true, ".xlsx": true, ".xlsm": true, ".xlsb": true,
+	".xltx": true, ".xltm": true, ".xlam": true, ".pptx": true, ".pptm": true, ".potx": true, ".potm": true,
+	".ppsx": true, ".ppsm": true, ".ppam": true, ".odt": true, ".ods": true, ".odp": true, ".odg": true,
+	".ott": true, ".ots": true, ".otp": true,
+}
+
+// isOffice reports whether ft is an Office document format.
+func isOffice(ft FileType) bool {
+	mimeType := mediaType(ft.MimeType)
+	return officeExts[strings.ToLower(ft.Extension)] || mimeType == "application/msword" ||
+		strings.HasPrefix(mimeType, "application/vnd.ms-") ||
+		strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(mimeType, "application/vnd.oasis.opendocument.")
+}
+
+// inspectOffice determines the container of the document at path and whether
+// it holds macros, if Options.Office is set and its most likely match is an
+// Office format. It returns nil if the file is neither an OLE compound file
+// nor an OOXML or OpenDocument file.
+func (t *Trid) inspectOffice(path string, fileTypes []FileType) *Office {
+	if !t.options.Office || len(fileTypes) == 0 || !isOffice(fileTypes[0]) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	size, err := fileSize(f)
+	if err != nil {
+		return nil
+	}
+
+	magic := make([]byte, len(oleMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		return nil
+	}
+
+	if bytes.Equal(magic, oleMagic) {
+		return inspectOLE(f, size)
+	}
+
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil
+	}
+
+	return inspectOfficeZip(zr)
+}
+
+// inspectOLE inspects the entries of an OLE compound file.
+func inspectOLE(f io.ReaderAt, size int64) *Office {
+	names, err := oleEntries(f, size)
+	if err != nil {
+		return nil
+	}
+
+	o := &Office{Container: OfficeOLE}
+	for _, name := range names {
+		switch {
+		case oleMacroEntries[name] && !o.Macros:
+			o.Macros, o.Evidence = true, name
+		case name == "EncryptedPackage":
+			o.Encrypted = true
+		}
+	}
+
+	return o
+}
+
+// inspectOfficeZip inspects the entries of an OOXML or OpenDocument file.
+func inspectOfficeZip(zr *zip.Reader) *Office {
+	var o *Office
+	for _, f := range zr.File {
+		switch name := f.Name; {
+		case name == "[Content_Types].xml":
+			o = &Office{Container: OfficeOOXML}
+		case name == "mimetype":
+			o = &Office{Container: OfficeODF}
+		}
+	}
+
+	if o == nil {
+		return nil
+	}
+
+	for _, f := range zr.File {
+		name := f.Name
+		switch o.Container {
+		case OfficeOOXML:
+			// Excel 4 macros are stored as macro sheets rather than in a VBA
+			// project
+			lower := strings.ToLower(name)
+			o.Macros = strings.HasSuffix(lower, "/vbaproject.bin") || strings.HasPrefix(lower, "xl/macrosheets/")
+		case OfficeODF:
+			// Basic libraries are listed in script-lc.xml even without macros
+			o.Macros = strings.HasPrefix(name, "Basic/") && strings.Count(name, "/") >= 2 && !strings.HasSuffix(name, "/") ||
+				strings.HasPrefix(name, "Scripts/") && !strings.HasSuffix(name, "/")
+		}
+
+		if o.Macros {
+			o.Evidence = name
+			break
+		}
+	}
+
+	return o
+}
+
+// Special sector numbers of OLE compound files.
+const (
+	oleEndOfChain = 0xfffffffe
+	oleMaxRegular = 0xfffffffa
+)
+
+// oleEntries returns the names of the storages and streams of the OLE
+// compound file f, of the given size.
+func oleEntries(f io.ReaderAt, size int64) ([]string, error) {
+	hdr := make([]byte, 512)
+	if _, err := f.ReadAt(hdr, 0); err != nil {
+		return nil, err
+	}
+
+	shift := binary.LittleEndian.Uint16(hdr[0x1e:])
+	if shift != 9 && shift != 12 {
+		return nil, errors.New("invalid sector size")
+	}
+	sectorSize := int64(1) << shift
+	sectors := uint32(size / sectorSize)
+
+	sector := func(n uint32) ([]byte, error) {
+		if n >= sectors {
+			return nil, errors.New("sector out of range")
+		}
+
+		buf := make([]byte, sectorSize)
+		_, err := f.ReadAt(buf, (int64(n)+1)*sectorSize)
+		if err == io.EOF {
+			err = nil
+		}
+
+		return buf, err
+	}
+
+	// The sectors of the allocation table are listed in the header, then in
+	// a chain of DIFAT sectors
+	var fatSectors []uint32
+	for i := range 109 {
+		if n := binary.LittleEndian.Uint32(hdr[0x4c+4*i:]); n <= oleMaxRegular {
+			fatSectors = append(fatSectors, n)
+		}
+	}
+
+	for n, i := binary.LittleEndian.Uint32(hdr[0x44:]), 0; n <= oleMaxRegular && i < int(sectors); i++ {
+		buf, err := sector(n)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := int64(0); j < sectorSize/4-1; j++ {
+			if n := binary.LittleEndian.Uint32(buf[4*j:]); n <= oleMaxRegular {
+				fatSectors = append(fatSectors, n)
+			}
+		}
+		n = binary.LittleEndian.Uint32(buf[sectorSize-4:])
+	}
+
+	var fat []uint32
+	for _, n := range fatSectors {
+		buf, err := sector(n)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := int64(0); j < sectorSize/4; j++ {
+			fat = append(fat, binary.LittleEndian.Uint32(buf[4*j:]))
+		}
+	}
+
+	// Chains longer than the file loop
+	var names []string
+	for n, i := binary.LittleEndian.Uint32(hdr[0x30:]), uint32(0); n != oleEndOfChain; i++ {
+		if n > oleMaxRegular || int(n) >= len(fat) || i >= sectors {
+			return nil, errors.New("invalid directory chain")
+		}
+
+		buf, err := sector(n)
+		if err != nil {
+			return nil, err
+		}
+
+		for e := int64(0); e+128 <= sectorSize; e += 128 {
+			entry := buf[e : e+128]
+			nameLen := int(binary.LittleEndian.Uint16(entry[0x40:]))
+			if typ := entry[0x42]; (typ == 1 || typ == 2) && nameLen > 0 && nameLen <= 64 {
+				names = append(names, utf16String(entry[:nameLen]))
+			}
+		}
+
+		n = fat[n]
+	}
+
+	return names, nil
+}
@@ -0,0 +1,132 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// writeOLE writes an OLE compound file with the given storages and streams
+// at the root, and returns its path.
+func writeOLE(t *testing.T, names ...string) string {
+	t.Helper()
+
+	const free = 0xffffffff
+
+	// Header, then the allocation table in sector 0 and the directory in
+	// sector 1
+	hdr := make([]byte, 512)
+	copy(hdr, oleMagic)
+	binary.LittleEndian.PutUint16(hdr[0x1e:], 9)
+	binary.LittleEndian.PutUint32(hdr[0x2c:], 1)
+	binary.LittleEndian.PutUint32(hdr[0x30:], 1)
+	binary.LittleEndian.PutUint32(hdr[0x44:], oleEndOfChain)
+	for i := range 109 {
+		binary.LittleEndian.PutUint32(hdr[0x4c+4*i:], free)
+	}
+	binary.LittleEndian.PutUint32(hdr[0x4c:], 0)
+
+	fat := bytes.Repeat([]byte{0xff}, 512)
+	binary.LittleEndian.PutUint32(fat[0:], 0xfffffffd)
+	binary.LittleEndian.PutUint32(fat[4:], oleEndOfChain)
+
+	dir := make([]byte, 512)
+	for i, name := range append([]string{"Root Entry"}, names...) {
+		entry := dir[128*i : 128*(i+1)]
+		u := utf16.Encode([]rune(name + "\x00"))
+		for j, c := range u {
+			binary.LittleEndian.PutUint16(entry[2*j:], c)
+		}
+		binary.LittleEndian.PutUint16(entry[0x40:], uint16(2*len(u)))
+
+		entry[0x42] = 2
+		switch {
+		case i == 0:
+			entry[0x42] = 5
+		case name == "Macros" || name == "VBA":
+			entry[0x42] = 1
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "document.doc")
+	if err := os.WriteFile(path, bytes.Join([][]byte{hdr, fat, dir}, nil), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestInspectOffice(t *testing.T) {
+	tr := NewTrid(Options{Cmd: "trid", Office: true})
+	doc := []FileType{{Extension: ".doc", Name: "Microsoft Word document"}}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected Office
+	}{
+		{"OLE", writeOLE(t, "WordDocument", "1Table"), Office{Container: OfficeOLE}},
+		{"OLE macros", writeOLE(t, "WordDocument", "Macros"), Office{Container: OfficeOLE, Macros: true, Evidence: "Macros"}},
+		{"OLE encrypted", writeOLE(t, "EncryptionInfo", "EncryptedPackage"), Office{Container: OfficeOLE, Encrypted: true}},
+		{"OOXML", writeZip(t, map[string][]byte{}, "[Content_Types].xml", "word/document.xml"), Office{Container: OfficeOOXML}},
+		{"OOXML macros", writeZip(t, map[string][]byte{}, "[Content_Types].xml", "word/document.xml", "word/vbaProject.bin"),
+			Office{Container: OfficeOOXML, Macros: true, Evidence: "word/vbaProject.bin"}},
+		{"Excel 4 macros", writeZip(t, map[string][]byte{}, "[Content_Types].xml", "xl/macrosheets/sheet1.xml"),
+			Office{Container: OfficeOOXML, Macros: true, Evidence: "xl/macrosheets/sheet1.xml"}},
+		{"ODF", writeZip(t, map[string][]byte{}, "mimetype", "content.xml", "Basic/script-lc.xml"), Office{Container: OfficeODF}},
+		{"ODF macros", writeZip(t, map[string][]byte{}, "mimetype", "content.xml", "Basic/Standard/Module1.xml"),
+			Office{Container: OfficeODF, Macros: true, Evidence: "Basic/Standard/Module1.xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if o := tr.inspectOffice(tt.path, doc); o == nil || *o != tt.expected {
+				t.Errorf("inspectOffice() = %+v, want %+v", o, tt.expected)
+			}
+		})
+	}
+
+	if o := tr.inspectOffice(writeZip(t, map[string][]byte{}, "readme.txt"), doc); o != nil {
+		t.Errorf("inspectOffice() of a zip archive = %+v, want nil", o)
+	}
+
+	if o := tr.inspectOffice("testdata/sample.pdf", []FileType{{Extension: ".pdf"}}); o != nil {
+		t.Errorf("inspectOffice() of a PDF match = %+v, want nil", o)
+	}
+
+	if o := NewTrid(Options{Cmd: "trid"}).inspectOffice(writeOLE(t, "Macros"), doc); o != nil {
+		t.Errorf("inspectOffice() without Office = %+v, want nil", o)
+	}
+}
+
+const docOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: document.doc
+ 80.0% (.DOC) Microsoft Word document (32000/1/3)
+ 20.0% (.) Generic OLE2 / Multistream Compound (8000/1)
+`
+
+func TestScanOffice(t *testing.T) {
+	path := writeOLE(t, "WordDocument", "Macros")
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: docOutput}, nil
+	})
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, Office: true})
+
+	r, err := tr.ScanReport(context.Background(), path, 2)
+	if err != nil || r.Office == nil || !r.Office.Macros {
+		t.Errorf("ScanReport() = %+v, %v, want macros", r, err)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{path}, 2)
+	if err != nil || results[0].Office == nil || results[0].Office.Container != OfficeOLE {
+		t.Errorf("ScanBatch() = %+v, %v, want OLE container", results, err)
+	}
+}
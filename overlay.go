@@ -0,0 +1,261 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// Formats whose logical end ScanOverlay determines.
+const (
+	OverlayFormatZip = "zip"
+	OverlayFormatPNG = "png"
+	OverlayFormatPE  = "pe"
+	OverlayFormatPDF = "pdf"
+)
+
+// errNoEnd is returned by the end finders for files whose end cannot be
+// determined.
+var errNoEnd = errors.New("end of format not found")
+
+// Overlay is data appended to a file after the end of its format.
+type Overlay struct {
+	Offset    int64      // Offset of the overlay, the logical end of the file.
+	Size      int64      // Size of the overlay in bytes.
+	FileTypes []FileType // Identified file types of the overlay.
+	Err       error      // Error if the overlay could not be identified.
+}
+
+// OverlayReport is the result of ScanOverlay.
+type OverlayReport struct {
+	Path      string     // Path of the file scanned.
+	FileTypes []FileType // Identified file types of the file; nil if it could not be identified.
+	Err       error      // Error if the file could not be identified.
+	Format    string     // Format whose end was determined, such as OverlayFormatZip; empty if unsupported.
+	End       int64      // Logical end of the file, or -1 if not determined.
+	Overlay   *Overlay   // Data after End; nil if there is none or End was not determined.
+}
+
+// ScanOverlay identifies the file at filePath and determines where its format
+// logically ends, from the structure of zip archives, PNG images, PE
+// executables and PDF documents. Data appended after that end, a classic way
+// of smuggling a payload past scanners, is identified as by ScanAt and
+// reported as the overlay.
+//
+// The format is recognized from the signature of the file, whatever TrID
+// reports. The end of PE executables includes their signature; that of PDF
+// documents is the last end-of-file marker, so appended data holding one is
+// taken for an incremental update.
+//
+// Errors concerning the file or the overlay alone, such as ErrUnknownFileType,
+// are reported in the OverlayReport. The returned error is reserved for
+// invalid arguments, a missing file and failures affecting every file, as for
+// ScanArchive.
+func (t *Trid) ScanOverlay(ctx context.Context, filePath string, numberOfMatches int) (*OverlayReport, error) {
+	r := &OverlayReport{Path: filePath, End: -1}
+	if r.FileTypes, r.Err = t.ScanContext(ctx, filePath, numberOfMatches); isFatalScanError(ctx, r.Err) {
+		return nil, r.Err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	format, end := formatEnd(f, fi.Size())
+	if r.Format = format; end < 0 {
+		return r, nil
+	}
+	r.End = end
+
+	if end < fi.Size() {
+		r.Overlay = &Overlay{Offset: end, Size: fi.Size() - end}
+		r.Overlay.FileTypes, r.Overlay.Err = t.ScanAt(ctx, filePath, end, 0, numberOfMatches)
+
+		if isFatalScanError(ctx, r.Overlay.Err) {
+			return nil, r.Overlay.Err
+		}
+	}
+
+	return r, nil
+}
+
+// formatEnd returns the format of f, of the given size, and its logical end,
+// or -1 if it is not determined.
+func formatEnd(f io.ReaderAt, size int64) (string, int64) {
+	head := make([]byte, 8)
+	n, _ := f.ReadAt(head, 0)
+	head = head[:n]
+
+	var (
+		format string
+		end    int64
+		err    error
+	)
+
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		format = OverlayFormatPNG
+		end, err = pngEnd(f)
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		format = OverlayFormatZip
+		end, err = zipEnd(f, size)
+	case bytes.HasPrefix(head, []byte("MZ")):
+		format = OverlayFormatPE
+		end, err = peEnd(f)
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		format = OverlayFormatPDF
+		end, err = pdfEnd(f, size)
+	default:
+		return "", -1
+	}
+
+	if err != nil || end > size {
+		return format, -1
+	}
+
+	return format, end
+}
+
+// pngEnd returns the end of the IEND chunk of a PNG image.
+func pngEnd(f io.ReaderAt) (int64, error) {
+	hdr := make([]byte, 8)
+	for off := int64(8); ; {
+		if _, err := f.ReadAt(hdr, off); err != nil {
+			return 0, errNoEnd
+		}
+
+		// Length, type, data and CRC
+		off += 12 + int64(binary.BigEndian.Uint32(hdr))
+		if string(hdr[4:]) == "IEND" {
+			return off, nil
+		}
+	}
+}
+
+// zipEnd returns the end of the first valid end of central directory record
+// of a zip archive, one preceded by the central directory it describes.
+func zipEnd(f io.ReaderAt, size int64) (int64, error) {
+	end := int64(-1)
+	scanSignature(f, size, []byte("PK\x05\x06"), func(off int64) bool {
+		rec := make([]byte, 22)
+		if _, err := f.ReadAt(rec, off); err != nil {
+			return true
+		}
+
+		cdSize := int64(binary.LittleEndian.Uint32(rec[12:]))
+		cdOffset := int64(binary.LittleEndian.Uint32(rec[16:]))
+		if cdOffset+cdSize != off {
+			return true
+		}
+
+		end = off + 22 + int64(binary.LittleEndian.Uint16(rec[20:]))
+		return false
+	})
+
+	if end < 0 {
+		return 0, errNoEnd
+	}
+
+	return end, nil
+}
+
+// peEnd returns the end of the last section or of the signature of a PE
+// executable, whichever is further.
+func peEnd(f io.ReaderAt) (int64, error) {
+	pf, err := pe.NewFile(f)
+	if err != nil {
+		return 0, err
+	}
+	defer pf.Close()
+
+	var end int64
+	for _, s := range pf.Sections {
+		end = max(end, int64(s.Offset)+int64(s.Size))
+	}
+
+	// The security directory holds a file offset rather than an address
+	var dirs []pe.DataDirectory
+	switch oh := pf.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(int(oh.NumberOfRvaAndSizes), len(oh.DataDirectory))]
+	}
+
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		if sec := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]; sec.Size > 0 {
+			end = max(end, int64(sec.VirtualAddress)+int64(sec.Size))
+		}
+	}
+
+	if end == 0 {
+		return 0, errNoEnd
+	}
+
+	return end, nil
+}
+
+// pdfEnd returns the end of the last end-of-file marker of a PDF document,
+// with its end of line.
+func pdfEnd(f io.ReaderAt, size int64) (int64, error) {
+	marker := []byte("%%EOF")
+
+	last := int64(-1)
+	scanSignature(f, size, marker, func(off int64) bool {
+		last = off
+		return true
+	})
+
+	if last < 0 {
+		return 0, errNoEnd
+	}
+
+	end := last + int64(len(marker))
+	eol := make([]byte, 2)
+	n, _ := f.ReadAt(eol, end)
+	switch {
+	case n == 2 && string(eol) == "\r\n":
+		end += 2
+	case n > 0 && (eol[0] == '\n' || eol[0] == '\r'):
+		end++
+	}
+
+	return end, nil
+}
+
+// scanSignature calls visit with the offset of each occurrence of sig in f,
+// of the given size, in order, until visit returns false.
+func scanSignature(f io.ReaderAt, size int64, sig []byte, visit func(off int64) bool) {
+	const chunkSize = 64 << 10
+
+	buf := make([]byte, chunkSize+len(sig)-1)
+	for start := int64(0); start < size; start += chunkSize {
+		n, err := f.ReadAt(buf, start)
+		if err != nil && err != io.EOF {
+			return
+		}
+
+		for chunk, i := buf[:n], 0; ; {
+			j := bytes.Index(chunk[i:], sig)
+			if j < 0 || i+j >= chunkSize {
+				break
+			}
+
+			if !visit(start + int64(i+j)) {
+				return
+			}
+			i += j + 1
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanOverlay(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	zip := readFile(t, writeZip(t, map[string][]byte{"a.txt": []byte("text")}, "a.txt"))
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	files := map[string][]byte{
+		"image.png":  join(img.Bytes(), pdf),
+		"archive.zp": join(zip, pdf),
+		"doc.pdf":    join(pdf, zip),
+		"plain.pdf":  pdf,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		end    int64
+		ext    string // Extension of the overlay, if any.
+		err    error  // Error of the overlay.
+	}{
+		{"image.png", OverlayFormatPNG, int64(img.Len()), ".pdf", nil},
+		{"archive.zp", OverlayFormatZip, int64(len(zip)), ".pdf", nil},
+		{"doc.pdf", OverlayFormatPDF, int64(len(pdf)), "", ErrUnknownFileType},
+		{"plain.pdf", OverlayFormatPDF, int64(len(pdf)), "", nil},
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tr.ScanOverlay(context.Background(), filepath.Join(dir, tt.name), 1)
+			if err != nil {
+				t.Fatalf("ScanOverlay() error = %v", err)
+			}
+
+			if r.Format != tt.format || r.End != tt.end {
+				t.Errorf("ScanOverlay() = %s ending at %d, want %s ending at %d", r.Format, r.End, tt.format, tt.end)
+			}
+
+			if tt.ext == "" && tt.err == nil {
+				if r.Overlay != nil {
+					t.Errorf("ScanOverlay() overlay = %+v, want none", r.Overlay)
+				}
+				return
+			}
+
+			o := r.Overlay
+			if o == nil || o.Offset != tt.end || o.Size != int64(len(files[tt.name]))-tt.end || !errors.Is(o.Err, tt.err) {
+				t.Fatalf("ScanOverlay() overlay = %+v", o)
+			}
+
+			if tt.ext != "" && (len(o.FileTypes) != 1 || o.FileTypes[0].Extension != tt.ext) {
+				t.Errorf("overlay file types = %+v, want %s", o.FileTypes, tt.ext)
+			}
+		})
+	}
+
+	// Formats without a known structure have no end
+	r, err := tr.ScanOverlay(context.Background(), "testdata/sample.7z", 1)
+	if err != nil || r.Format != "" || r.End != -1 || r.Overlay != nil {
+		t.Errorf("ScanOverlay() = %+v, %v, want no end", r, err)
+	}
+
+	if _, err := tr.ScanOverlay(context.Background(), "missing.png", 1); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("ScanOverlay() error = %v, want %v", err, ErrFileNotFound)
+	}
+}
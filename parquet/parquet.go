@@ -0,0 +1,385 @@
+// Package parquet writes scan results as Apache Parquet files, so that the
+// identification results of large collections can be queried directly by
+// data engineering tools such as Spark, DuckDB, Trino or pandas.
+//
+// Files have one row per scanned file and the following schema, which is kept
+// stable: columns are only ever added.
+//
+//	path        string, required  Path of the file, as scanned.
+//	extension   string            Extension of the most likely match (e.g., ".pdf").
+//	name        string            Name of the most likely match.
+//	mime_type   string            MIME type of the most likely match, if known.
+//	category    string            Category of the most likely match (see trid.Category).
+//	probability double            Probability of the most likely match, as a percentage.
+//	definition  string            Definition file of the most likely match.
+//	matches     int32, required   Number of matches.
+//	file_types  string (JSON)     Every match, as a JSON array of trid.FileType.
+//	error       string            Why the file could not be identified.
+//
+// Columns of the most likely match are null for files without matches, and
+// string columns are null rather than empty. Strings that are not valid UTF-8,
+// such as some paths, have their invalid bytes replaced with U+FFFD.
+//
+// Values are stored uncompressed and PLAIN-encoded, in row groups of
+// Options.RowGroupSize rows, so memory use is bounded however many results
+// are written.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrClosed is returned when writing to a closed Writer.
+var ErrClosed = errors.New("parquet writer is closed")
+
+// magic starts and ends Parquet files.
+const magic = "PAR1"
+
+// createdBy identifies the writer in the file metadata.
+const createdBy = "github.com/attilabuti/trid/parquet"
+
+// Physical types of Parquet.
+const (
+	typeInt32     = 1
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Converted types of Parquet annotating byte arrays.
+const (
+	convertedNone = -1
+	convertedUTF8 = 0
+	convertedJSON = 19
+)
+
+// Encodings of Parquet.
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// Options configures a Writer.
+type Options struct {
+	RowGroupSize int // Number of rows buffered before they are written as a row group (default: 100000).
+}
+
+// column is a column of the current row group.
+type column struct {
+	name      string
+	typ       int32
+	converted int32
+	optional  bool
+	defined   []byte       // Definition level of each row: 1 if it has a value, 0 if null.
+	values    bytes.Buffer // PLAIN-encoded values of the rows that have one.
+}
+
+// chunk describes a column of a row group written to the file.
+type chunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// rowGroup describes a row group written to the file.
+type rowGroup struct {
+	chunks []chunk
+	rows   int64
+	size   int64
+}
+
+// Writer writes results as a Parquet file. Close must be called to write the
+// file metadata, without which the file cannot be read.
+type Writer struct {
+	w       io.Writer
+	opts    Options
+	offset  int64
+	columns []*column
+	rows    int
+	total   int64
+	groups  []rowGroup
+	closed  bool
+	err     error
+}
+
+// NewWriter returns a writer of results to w.
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.RowGroupSize < 1 {
+		opts.RowGroupSize = 100000
+	}
+
+	return &Writer{
+		w:    w,
+		opts: opts,
+		columns: []*column{
+			{name: "path", typ: typeByteArray, converted: convertedUTF8},
+			{name: "extension", typ: typeByteArray, converted: convertedUTF8, optional: true},
+			{name: "name", typ: typeByteArray, converted: convertedUTF8, optional: true},
+			{name: "mime_type", typ: typeByteArray, converted: convertedUTF8, optional: true},
+			{name: "category", typ: typeByteArray, converted: convertedUTF8, optional: true},
+			{name: "probability", typ: typeDouble, converted: convertedNone, optional: true},
+			{name: "definition", typ: typeByteArray, converted: convertedUTF8, optional: true},
+			{name: "matches", typ: typeInt32, converted: convertedNone},
+			{name: "file_types", typ: typeByteArray, converted: convertedJSON, optional: true},
+			{name: "error", typ: typeByteArray, converted: convertedUTF8, optional: true},
+		},
+	}
+}
+
+// Write adds a row for r, writing a row group once Options.RowGroupSize rows
+// are buffered.
+func (w *Writer) Write(r trid.BatchResult) error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+
+	best, ok := trid.Results(r.FileTypes).Best()
+
+	var fileTypes, errMsg string
+	if len(r.FileTypes) > 0 {
+		b, err := json.Marshal(r.FileTypes)
+		if err != nil {
+			return err
+		}
+		fileTypes = string(b)
+	}
+
+	if r.Err != nil {
+		errMsg = r.Err.Error()
+	}
+
+	c := w.columns
+	c[0].addString(r.Path)
+	if ok {
+		c[1].addString(best.Extension)
+		c[2].addString(best.Name)
+		c[3].addString(best.MimeType)
+		c[4].addString(string(best.Category()))
+		c[5].addDouble(best.Probability)
+		c[6].addString(best.Definition)
+	} else {
+		for _, col := range c[1:7] {
+			col.addNull()
+		}
+	}
+	c[7].addInt32(int32(len(r.FileTypes)))
+	c[8].addString(fileTypes)
+	c[9].addString(errMsg)
+
+	if w.rows++; w.rows >= w.opts.RowGroupSize {
+		w.err = w.flush()
+	}
+
+	return w.err
+}
+
+// Close writes the buffered rows and the file metadata. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+
+	if w.err == nil {
+		w.err = w.flush()
+	}
+
+	if w.err == nil {
+		w.err = w.writeFooter()
+	}
+
+	return w.err
+}
+
+// write writes b to the file, starting it with the magic number.
+func (w *Writer) write(b []byte) error {
+	if w.offset == 0 {
+		b = append([]byte(magic), b...)
+	}
+
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+
+	return err
+}
+
+// flush writes the buffered rows as a row group, with a single data page per
+// column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	group := rowGroup{rows: int64(w.rows)}
+	for _, col := range w.columns {
+		var page bytes.Buffer
+		if col.optional {
+			levels := encodeLevels(col.defined)
+			binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+			page.Write(levels)
+		}
+		page.Write(col.values.Bytes())
+
+		header := newCompact()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(page.Len()))
+		header.i32(3, int32(page.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.end()
+		header.end()
+
+		if err := w.write(header.Bytes()); err != nil {
+			return err
+		}
+
+		offset := w.offset - int64(header.Len())
+		if err := w.write(page.Bytes()); err != nil {
+			return err
+		}
+
+		size := int64(header.Len() + page.Len())
+		group.chunks = append(group.chunks, chunk{offset: offset, size: size, values: int64(w.rows)})
+		group.size += size
+
+		col.defined = col.defined[:0]
+		col.values.Reset()
+	}
+
+	w.groups = append(w.groups, group)
+	w.total += int64(w.rows)
+	w.rows = 0
+
+	return nil
+}
+
+// writeFooter writes the file metadata and the end of the file.
+func (w *Writer) writeFooter() error {
+	meta := newCompact()
+	meta.i32(1, 1)
+
+	meta.list(2, thriftStruct, len(w.columns)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.end()
+	for _, col := range w.columns {
+		repetition := int32(0) // REQUIRED
+		if col.optional {
+			repetition = 1 // OPTIONAL
+		}
+
+		meta.begin()
+		meta.i32(1, col.typ)
+		meta.i32(3, repetition)
+		meta.binary(4, col.name)
+		if col.converted != convertedNone {
+			meta.i32(6, col.converted)
+		}
+		meta.end()
+	}
+
+	meta.i64(3, w.total)
+
+	meta.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		meta.begin()
+		meta.list(1, thriftStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			col := w.columns[i]
+
+			meta.begin()
+			meta.i64(2, c.offset)
+			meta.beginStruct(3)
+			meta.i32(1, col.typ)
+			meta.list(2, thriftI32, 2)
+			meta.varint(encodingPlain)
+			meta.varint(encodingRLE)
+			meta.list(3, thriftBinary, 1)
+			meta.str(col.name)
+			meta.i32(4, 0) // UNCOMPRESSED
+			meta.i64(5, c.values)
+			meta.i64(6, c.size)
+			meta.i64(7, c.size)
+			meta.i64(9, c.offset)
+			meta.end()
+			meta.end()
+		}
+		meta.i64(2, g.size)
+		meta.i64(3, g.rows)
+		meta.end()
+	}
+
+	meta.binary(6, createdBy)
+	meta.end()
+
+	footer := meta.Bytes()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, magic...)
+
+	return w.write(footer)
+}
+
+// addString adds s to the column, or a null if s is empty and the column is
+// optional.
+func (c *column) addString(s string) {
+	if s == "" && c.optional {
+		c.addNull()
+		return
+	}
+
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	c.defined = append(c.defined, 1)
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+	c.values.WriteString(s)
+}
+
+// addDouble adds v to the column.
+func (c *column) addDouble(v float64) {
+	c.defined = append(c.defined, 1)
+	binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+}
+
+// addInt32 adds v to the column.
+func (c *column) addInt32(v int32) {
+	c.defined = append(c.defined, 1)
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+// addNull adds a null to the column.
+func (c *column) addNull() {
+	c.defined = append(c.defined, 0)
+}
+
+// encodeLevels encodes definition levels with the RLE/bit-packing hybrid
+// encoding, as runs of equal levels with a bit width of 1.
+func encodeLevels(levels []byte) []byte {
+	var b []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		b = binary.AppendUvarint(b, uint64(j-i)<<1)
+		b = append(b, levels[i])
+		i = j
+	}
+
+	return b
+}
@@ -0,0 +1,219 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+// reader decodes the Thrift compact protocol into maps of field identifiers
+// to values, enough to check the metadata written.
+type reader struct {
+	b []byte
+	i int
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.i:])
+	r.i += n
+	return v
+}
+
+func (r *reader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *reader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.i:]))
+		r.i += 8
+		return v
+	case 8:
+		n := int(r.uvarint())
+		s := string(r.b[r.i : r.i+n])
+		r.i += n
+		return s
+	case 9:
+		h := r.b[r.i]
+		r.i++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+
+		list := make([]any, n)
+		for j := range list {
+			list[j] = r.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return r.structure()
+	default:
+		panic("unsupported type")
+	}
+}
+
+func (r *reader) structure() map[int16]any {
+	m := make(map[int16]any)
+	var last int16
+	for {
+		h := r.b[r.i]
+		r.i++
+		if h == 0 {
+			return m
+		}
+
+		if delta := int16(h >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.zigzag())
+		}
+
+		m[last] = r.value(h & 0x0f)
+	}
+}
+
+// decode checks the framing of a Parquet file and returns its metadata.
+func decode(t *testing.T, data []byte) map[int16]any {
+	t.Helper()
+
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("file does not start and end with %q", magic)
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &reader{b: data[len(data)-8-size : len(data)-8]}
+	meta := r.structure()
+	if r.i != size {
+		t.Fatalf("metadata is %d bytes, footer says %d", r.i, size)
+	}
+
+	return meta
+}
+
+// readStrings returns the values of string column col, with nil for nulls.
+func readStrings(t *testing.T, data []byte, meta map[int16]any, col int, optional bool) []*string {
+	t.Helper()
+
+	var values []*string
+	for _, g := range meta[4].([]any) {
+		chunk := g.(map[int16]any)[1].([]any)[col].(map[int16]any)[3].(map[int16]any)
+		offset := int(chunk[9].(int64))
+
+		r := &reader{b: data, i: offset}
+		header := r.structure()
+		n := int(header[5].(map[int16]any)[1].(int64))
+		if got := int(header[3].(int64)) + r.i - offset; got != int(chunk[7].(int64)) {
+			t.Fatalf("column %d is %d bytes, metadata says %d", col, got, chunk[7])
+		}
+
+		defined := make([]byte, 0, n)
+		if optional {
+			end := r.i + 4 + int(binary.LittleEndian.Uint32(data[r.i:]))
+			r.i += 4
+			for r.i < end {
+				run := int(r.uvarint() >> 1)
+				level := data[r.i]
+				r.i++
+				defined = append(defined, bytes.Repeat([]byte{level}, run)...)
+			}
+		} else {
+			defined = append(defined, bytes.Repeat([]byte{1}, n)...)
+		}
+
+		for _, d := range defined {
+			if d == 0 {
+				values = append(values, nil)
+				continue
+			}
+
+			size := int(binary.LittleEndian.Uint32(data[r.i:]))
+			s := string(data[r.i+4 : r.i+4+size])
+			r.i += 4 + size
+			values = append(values, &s)
+		}
+	}
+
+	return values
+}
+
+func TestWriter(t *testing.T) {
+	pdf := []trid.FileType{{Name: "Adobe Portable Document Format", Extension: ".pdf", MimeType: "application/pdf", Probability: 100}}
+
+	var b bytes.Buffer
+	w := NewWriter(&b, Options{RowGroupSize: 2})
+	for _, r := range []trid.BatchResult{
+		{Path: "a.pdf", FileTypes: pdf},
+		{Path: "b.bin", Err: trid.ErrUnknownFileType},
+		{Path: "c\xff.pdf", FileTypes: pdf},
+	} {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Write(trid.BatchResult{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write() after Close() = %v, want ErrClosed", err)
+	}
+
+	meta := decode(t, b.Bytes())
+	if meta[3] != int64(3) {
+		t.Errorf("num_rows = %v, want 3", meta[3])
+	}
+
+	if groups := meta[4].([]any); len(groups) != 2 {
+		t.Errorf("%d row groups, want 2", len(groups))
+	}
+
+	schema := meta[2].([]any)
+	var names []string
+	for _, e := range schema[1:] {
+		names = append(names, e.(map[int16]any)[4].(string))
+	}
+	if len(names) != 10 || names[0] != "path" || names[1] != "extension" || names[9] != "error" {
+		t.Errorf("schema = %v", names)
+	}
+
+	paths := readStrings(t, b.Bytes(), meta, 0, false)
+	if len(paths) != 3 || *paths[0] != "a.pdf" || *paths[2] != "c�.pdf" {
+		t.Errorf("path column = %v", paths)
+	}
+
+	exts := readStrings(t, b.Bytes(), meta, 1, true)
+	if len(exts) != 3 || *exts[0] != ".pdf" || exts[1] != nil || *exts[2] != ".pdf" {
+		t.Errorf("extension column = %v", exts)
+	}
+
+	errs := readStrings(t, b.Bytes(), meta, 9, true)
+	if len(errs) != 3 || errs[0] != nil || *errs[1] != trid.ErrUnknownFileType.Error() {
+		t.Errorf("error column = %v", errs)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewWriter(&b, Options{}).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := decode(t, b.Bytes())
+	if meta[3] != int64(0) || len(meta[4].([]any)) != 0 {
+		t.Errorf("empty file has %v rows in %d row groups", meta[3], len(meta[4].([]any)))
+	}
+}
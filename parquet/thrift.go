@@ -0,0 +1,103 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Types of the Thrift compact protocol used by Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// compact encodes structures with the Thrift compact protocol, the encoding
+// of Parquet page headers and file metadata.
+type compact struct {
+	bytes.Buffer
+	last []int16 // Identifier of the last field written, for each open structure.
+}
+
+// newCompact returns an encoder with a structure open.
+func newCompact() *compact {
+	return &compact{last: []int16{0}}
+}
+
+// field writes the header of field id of type typ.
+func (c *compact) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.WriteByte(typ)
+		c.varint(int64(id))
+	}
+	*last = id
+}
+
+// varint writes v zigzag-encoded.
+func (c *compact) varint(v int64) {
+	c.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+// uvarint writes v as an unsigned variable-length integer.
+func (c *compact) uvarint(v uint64) {
+	c.Write(binary.AppendUvarint(nil, v))
+}
+
+// i32 writes field id as a 32-bit integer.
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, thriftI32)
+	c.varint(int64(v))
+}
+
+// i64 writes field id as a 64-bit integer.
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, thriftI64)
+	c.varint(v)
+}
+
+// binary writes field id as a string.
+func (c *compact) binary(id int16, s string) {
+	c.field(id, thriftBinary)
+	c.str(s)
+}
+
+// str writes s, without field header, as in lists.
+func (c *compact) str(s string) {
+	c.uvarint(uint64(len(s)))
+	c.WriteString(s)
+}
+
+// list writes the header of field id as a list of n elements of type elem,
+// which are written next without field headers.
+func (c *compact) list(id int16, elem byte, n int) {
+	c.field(id, thriftList)
+	if n < 15 {
+		c.WriteByte(byte(n)<<4 | elem)
+	} else {
+		c.WriteByte(0xf0 | elem)
+		c.uvarint(uint64(n))
+	}
+}
+
+// beginStruct writes the header of field id as a structure, whose fields are
+// written next, up to end.
+func (c *compact) beginStruct(id int16) {
+	c.field(id, thriftStruct)
+	c.begin()
+}
+
+// begin opens a structure without field header, as in lists.
+func (c *compact) begin() {
+	c.last = append(c.last, 0)
+}
+
+// end closes the open structure.
+func (c *compact) end() {
+	c.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
@@ -0,0 +1,208 @@
+package trid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes a line of TrID output that looks like a file type but
+// could not be parsed.
+type ParseError struct {
+	Line   int    // Line number, starting at 1.
+	Column int    // Column of the offending byte, starting at 1.
+	Text   string // Content of the line.
+	Msg    string // Description of the problem.
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("TrID output line %d, column %d: %s: %q", e.Line, e.Column, e.Msg, e.Text)
+}
+
+// lineKind classifies a line of TrID output.
+type lineKind int
+
+const (
+	lineBlank      lineKind = iota
+	lineBanner              // "TrID/32 - File Identifier v2.24 - ..."
+	lineFileHeader          // "Collecting data from file: ..." or "File: ..."
+	lineMatch               // " 87.5% (.EXT) Name (points)"
+	lineDetail              // "        Mime type       : value"
+	lineOther               // Anything else, such as progress messages.
+)
+
+// classifyLine returns the kind of a line of TrID output.
+func classifyLine(line string) lineKind {
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case trimmed == "":
+		return lineBlank
+	case reBanner.MatchString(line):
+		return lineBanner
+	case strings.HasPrefix(trimmed, "Collecting data from file:"), strings.HasPrefix(trimmed, "File:"):
+		return lineFileHeader
+	case isMatchLine(trimmed):
+		return lineMatch
+	case trimmed != line && strings.Contains(trimmed, ":"):
+		return lineDetail
+	default:
+		return lineOther
+	}
+}
+
+// isMatchLine reports whether a trimmed line starts with a percentage.
+func isMatchLine(trimmed string) bool {
+	end := strings.IndexByte(trimmed, '%')
+	if end < 1 || trimmed[0] < '0' || trimmed[0] > '9' {
+		return false
+	}
+
+	return strings.Trim(trimmed[:end], "0123456789.,") == ""
+}
+
+// infoPrefixes are the beginnings of informational lines that are neither
+// results nor warnings.
+var infoPrefixes = []string{"Definitions found:", "Analyzing...", "Unknown!"}
+
+// parse parses TrID output line by line and returns the file types it lists,
+// along with any warnings. Details are attached to the preceding file type;
+// detail lines with labels unknown to the profile are ignored unless they are
+// warnings. Unrecognized lines are reported as warnings, and so are match
+// lines that cannot be parsed, unless no match line can be, in which case the
+// *ParseError of the first one is returned. File types are returned in the
+// order of Results.SortByProbability, whatever order TrID printed them in.
+func (pr *profile) parse(out string) ([]FileType, []string, error) {
+	fileTypes := make([]FileType, 0)
+	current := -1 // Index of the file type receiving details.
+	var warnings []string
+	var parseErr *ParseError // First match line that could not be parsed.
+
+	for i, line := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n") {
+		switch classifyLine(line) {
+		case lineMatch:
+			f, err := parseMatchLine(line)
+			if err != nil {
+				// Keep the other matches; details of this one are dropped
+				err.Line = i + 1
+				if parseErr == nil {
+					parseErr = err
+				}
+
+				warnings = append(warnings, err.Error())
+				current = -1
+				break
+			}
+
+			fileTypes = append(fileTypes, f)
+			current = len(fileTypes) - 1
+
+		case lineDetail:
+			label, value, _ := strings.Cut(line, ":")
+			label = strings.ToLower(strings.TrimSpace(label))
+
+			if set, ok := pr.details[label]; ok && current >= 0 {
+				set(&fileTypes[current], strings.TrimSpace(value))
+			} else if strings.Contains(label, "warning") {
+				warnings = append(warnings, strings.TrimSpace(line))
+			}
+
+		case lineBanner, lineFileHeader:
+			current = -1
+
+		case lineOther:
+			if !isInfoLine(line) {
+				warnings = append(warnings, strings.TrimSpace(line))
+			}
+		}
+	}
+
+	if len(fileTypes) == 0 && parseErr != nil {
+		return nil, nil, parseErr
+	}
+
+	Results(fileTypes).SortByProbability()
+
+	return fileTypes, warnings, nil
+}
+
+// isInfoLine reports whether line is an informational message.
+func isInfoLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range infoPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stderrWarnings returns the non-empty lines of standard error.
+func stderrWarnings(stderr string) []string {
+	var warnings []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			warnings = append(warnings, line)
+		}
+	}
+
+	return warnings
+}
+
+// parseMatchLine parses a line describing a file type, such as
+// " 87.5% (.EXE) Win32 Executable (generic) (31206/45/13)". The trailing
+// points are optional; any other parentheses belong to the name.
+func parseMatchLine(line string) (FileType, *ParseError) {
+	fail := func(pos int, msg string) (FileType, *ParseError) {
+		return FileType{}, &ParseError{Column: pos + 1, Text: line, Msg: msg}
+	}
+
+	start := len(line) - len(strings.TrimLeft(line, " \t"))
+	end := strings.IndexByte(line, '%')
+
+	// Some builds format probabilities with a decimal comma
+	probability, err := strconv.ParseFloat(strings.Replace(line[start:end], ",", ".", 1), 64)
+	if err != nil || probability > 100 {
+		return fail(start, "invalid probability")
+	}
+
+	pos := end + 1
+	pos += len(line[pos:]) - len(strings.TrimLeft(line[pos:], " \t"))
+	if !strings.HasPrefix(line[pos:], "(.") {
+		return fail(pos, "expected extension")
+	}
+
+	extEnd := strings.IndexByte(line[pos:], ')')
+	if extEnd < 0 {
+		return fail(len(line), "unterminated extension")
+	}
+
+	f := FileType{
+		Probability: probability,
+		Extension:   strings.ToLower(line[pos+1 : pos+extEnd]),
+		Name:        trimPoints(strings.TrimSpace(line[pos+extEnd+1:])),
+	}
+
+	if f.Name == "" {
+		return fail(pos+extEnd+1, "missing name")
+	}
+
+	return f, nil
+}
+
+// trimPoints removes the trailing points, such as "(31206/45/13)", from a
+// file type name.
+func trimPoints(name string) string {
+	if !strings.HasSuffix(name, ")") {
+		return name
+	}
+
+	open := strings.LastIndexByte(name, '(')
+	if open < 0 || strings.Trim(name[open+1:len(name)-1], "0123456789/") != "" || open+2 == len(name) {
+		return name
+	}
+
+	return strings.TrimSpace(name[:open])
+}
@@ -0,0 +1,201 @@
+package trid
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseMatchLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected FileType
+	}{
+		{
+			name:     "Points",
+			line:     " 87.5% (.PDF) Adobe Portable Document Format (5000/1)",
+			expected: FileType{Probability: 87.5, Extension: ".pdf", Name: "Adobe Portable Document Format"},
+		},
+		{
+			name:     "Parenthesized name",
+			line:     "100.0% (.7Z) 7-Zip compressed archive (v0.4) (6000/1)",
+			expected: FileType{Probability: 100, Extension: ".7z", Name: "7-Zip compressed archive (v0.4)"},
+		},
+		{
+			name:     "Nested parentheses",
+			line:     " 40.1% (.BIN) Firmware image (ARM (Thumb) mode) (1000/2/3)",
+			expected: FileType{Probability: 40.1, Extension: ".bin", Name: "Firmware image (ARM (Thumb) mode)"},
+		},
+		{
+			name:     "Without points",
+			line:     " 10.0% (.DAT) Generic data (little endian)",
+			expected: FileType{Probability: 10, Extension: ".dat", Name: "Generic data (little endian)"},
+		},
+		{
+			name:     "Empty extension",
+			line:     " 12.5% (.) Text file (ASCII)",
+			expected: FileType{Probability: 12.5, Extension: ".", Name: "Text file (ASCII)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMatchLine(tt.line)
+			if err != nil {
+				t.Fatalf("parseMatchLine() error = %v", err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("parseMatchLine() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOutputErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		out            string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"Invalid probability", "Collecting data from file: a\n 1.2.3% (.PDF) PDF (1/1)\n", 2, 2},
+		{"Probability above 100", "Collecting data from file: a\n250.0% (.PDF) PDF (1/1)\n", 2, 1},
+		{"Missing extension", "\n\n 50.0% PDF document\n", 3, 8},
+		{"Unterminated extension", " 50.0% (.PDF PDF document\n", 1, 26},
+		{"Missing name", " 50.0% (.PDF)   \n", 1, 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOutput(tt.out)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("parseOutput() error = %v, want a *ParseError", err)
+			}
+
+			if parseErr.Line != tt.expectedLine || parseErr.Column != tt.expectedColumn {
+				t.Errorf("parseOutput() error at %d:%d, want %d:%d (%v)", parseErr.Line, parseErr.Column, tt.expectedLine, tt.expectedColumn, err)
+			}
+		})
+	}
+}
+
+func TestParseOutputMalformedLine(t *testing.T) {
+	out := "Collecting data from file: sample.pdf\n" +
+		" 60.0% (.PDF) Adobe Portable Document Format (5000/1)\n" +
+		" 30.0% PDF without extension (100/1)\n" +
+		"        Mime type       : application/x-broken\n" +
+		" 10.0% (.AI) Adobe Illustrator graphics (3000/1)\n"
+
+	fileTypes, warnings, err := detectProfile(out).parse(out)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	expected := []FileType{
+		{Probability: 60, Extension: ".pdf", Name: "Adobe Portable Document Format"},
+		{Probability: 10, Extension: ".ai", Name: "Adobe Illustrator graphics"},
+	}
+
+	if !slices.Equal(fileTypes, expected) {
+		t.Errorf("parse() = %+v, want %+v", fileTypes, expected)
+	}
+
+	expectedWarning := (&ParseError{Line: 3, Column: 8, Text: " 30.0% PDF without extension (100/1)", Msg: "expected extension"}).Error()
+	if !slices.Equal(warnings, []string{expectedWarning}) {
+		t.Errorf("parse() warnings = %q, want %q", warnings, expectedWarning)
+	}
+}
+
+func TestParseOutputDetails(t *testing.T) {
+	out := "TrID/32 - File Identifier v2.24\n" +
+		"Definitions found:  18452\n" +
+		"Analyzing...\n" +
+		"\n" +
+		"Collecting data from file: sample.pdf\n" +
+		" 60.0% (.PDF) Adobe Portable Document Format (5000/1)\n" +
+		"        Mime type       : application/pdf\n" +
+		"        Related URL     : http://www.adobe.com/\n" +
+		"        Unusual label   : ignored\n" +
+		" 40.0% (.AI) Adobe Illustrator graphics (3000/1)\n" +
+		"        Definition      : ai.trid.xml\n"
+
+	fileTypes, err := parseOutput(out)
+	if err != nil {
+		t.Fatalf("parseOutput() error = %v", err)
+	}
+
+	expected := []FileType{
+		{Probability: 60, Extension: ".pdf", Name: "Adobe Portable Document Format", MimeType: "application/pdf", RelatedURL: "http://www.adobe.com/"},
+		{Probability: 40, Extension: ".ai", Name: "Adobe Illustrator graphics", Definition: "ai.trid.xml"},
+	}
+
+	if len(fileTypes) != len(expected) {
+		t.Fatalf("parseOutput() = %+v, want %+v", fileTypes, expected)
+	}
+
+	for i := range expected {
+		if fileTypes[i] != expected[i] {
+			t.Errorf("parseOutput()[%d] = %+v, want %+v", i, fileTypes[i], expected[i])
+		}
+	}
+}
+
+func TestParseOutputWarnings(t *testing.T) {
+	out := "TrID/32 - File Identifier v2.24\n" +
+		"Definitions found:  18452\n" +
+		"Warning: definition foo.trid.xml skipped\n" +
+		"Analyzing...\n" +
+		"\n" +
+		"Collecting data from file: sample.pdf\n" +
+		"File truncated, only 4096 bytes read\n" +
+		" 60.0% (.PDF) Adobe Portable Document Format (5000/1)\n" +
+		"        Mime type       : application/pdf\n" +
+		"        Warning         : low confidence\n"
+
+	fileTypes, warnings, err := detectProfile(out).parse(out)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	if len(fileTypes) != 1 || fileTypes[0].MimeType != "application/pdf" {
+		t.Errorf("parse() = %+v", fileTypes)
+	}
+
+	expected := []string{
+		"Warning: definition foo.trid.xml skipped",
+		"File truncated, only 4096 bytes read",
+		"Warning         : low confidence",
+	}
+	if !slices.Equal(warnings, expected) {
+		t.Errorf("parse() warnings = %q, want %q", warnings, expected)
+	}
+}
+
+func TestParseOutputOrder(t *testing.T) {
+	out := "TrID/32 - File Identifier v2.24\n" +
+		"\n" +
+		"Collecting data from file: sample.bin\n" +
+		" 25.0% (.B) Second tie (1000/1)\n" +
+		"        Definition      : b.trid.xml\n" +
+		" 50.0% (.ZIP) Most likely (2000/1)\n" +
+		" 25.0% (.A) First tie (1000/1)\n" +
+		"        Definition      : a.trid.xml\n"
+
+	fileTypes, err := parseOutput(out)
+	if err != nil {
+		t.Fatalf("parseOutput() error = %v", err)
+	}
+
+	var extensions []string
+	for _, f := range fileTypes {
+		extensions = append(extensions, f.Extension)
+	}
+
+	if expected := []string{".zip", ".a", ".b"}; !slices.Equal(extensions, expected) {
+		t.Errorf("parseOutput() order = %q, want %q", extensions, expected)
+	}
+}
@@ -0,0 +1,193 @@
+package trid
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pdfReadLimit is the number of leading bytes of PDF documents searched for
+// their properties; the trailer of larger documents is read from their end.
+const pdfReadLimit = 64 << 20
+
+// pdfTailSize is the number of trailing bytes of PDF documents larger than
+// pdfReadLimit read for their trailer.
+const pdfTailSize = 1 << 20
+
+// maxObjectStreamSize bounds the decompressed size of each object stream.
+const maxObjectStreamSize = 16 << 20
+
+// PDF describes a PDF document, with Options.PDF.
+type PDF struct {
+	Version   string `json:"version"`   // PDF version, from the header or the catalog if later (e.g., "1.7").
+	Encrypted bool   `json:"encrypted"` // Whether the document is encrypted, even if only with an owner password.
+	Pages     int    `json:"pages"`     // Number of pages, or 0 if it cannot be determined, as for encrypted documents with compressed objects.
+}
+
+var (
+	rePDFHeader     = regexp.MustCompile(`%PDF-(\d\.\d)`)
+	rePDFVersion    = regexp.MustCompile(`/Version\s*/(\d\.\d)`)
+	rePDFEncrypt    = regexp.MustCompile(`/Encrypt\s*(?:\d+\s+\d+\s+R|<<)`)
+	rePDFPages      = regexp.MustCompile(`/Type\s*/Pages\b`)
+	rePDFCount      = regexp.MustCompile(`/Count\s+(\d+)`)
+	rePDFObjStm     = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	rePDFStreamHead = regexp.MustCompile(`>>\s*stream\r?\n`)
+)
+
+// isPDF reports whether ft is the PDF format.
+func isPDF(ft FileType) bool {
+	return strings.EqualFold(ft.Extension, ".pdf") || mediaType(ft.MimeType) == "application/pdf"
+}
+
+// inspectPDF extracts the properties of the document at path, if Options.PDF
+// is set and its most likely match is PDF. It returns nil if the file has no
+// PDF header.
+func (t *Trid) inspectPDF(path string, fileTypes []FileType) *PDF {
+	if !t.options.PDF || len(fileTypes) == 0 || !isPDF(fileTypes[0]) {
+		return nil
+	}
+
+	data, err := readPDF(path)
+	if err != nil {
+		return nil
+	}
+
+	// The header may follow up to 1 KiB of junk
+	m := rePDFHeader.FindSubmatch(data[:min(len(data), 1024+len("%PDF-1.7"))])
+	if m == nil {
+		return nil
+	}
+
+	p := &PDF{Version: string(m[1]), Encrypted: rePDFEncrypt.Match(data)}
+
+	// Objects, the page tree among them, may be compressed in object streams
+	// since PDF 1.5
+	texts := [][]byte{data}
+	if !p.Encrypted {
+		texts = append(texts, objectStreams(data)...)
+	}
+
+	for _, text := range texts {
+		if m := rePDFVersion.FindSubmatch(text); m != nil && string(m[1]) > p.Version {
+			p.Version = string(m[1])
+		}
+
+		// The root of the page tree counts every page
+		for _, loc := range rePDFPages.FindAllIndex(text, -1) {
+			if m := rePDFCount.FindSubmatch(enclosingDict(text, loc[0])); m != nil {
+				if n, err := strconv.Atoi(string(m[1])); err == nil && n > p.Pages {
+					p.Pages = n
+				}
+			}
+		}
+	}
+
+	return p
+}
+
+// readPDF reads the document at path, or its first pdfReadLimit bytes and
+// last pdfTailSize bytes if it is larger.
+func readPDF(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, pdfReadLimit))
+	if err != nil || len(data) < pdfReadLimit {
+		return data, err
+	}
+
+	tail := make([]byte, pdfTailSize)
+	if size, err := fileSize(f); err == nil && size > pdfReadLimit {
+		n, _ := f.ReadAt(tail, max(pdfReadLimit, size-pdfTailSize))
+		data = append(data, tail[:n]...)
+	}
+
+	return data, nil
+}
+
+// objectStreams returns the decompressed content of the Flate-encoded object
+// streams of data.
+func objectStreams(data []byte) [][]byte {
+	var streams [][]byte
+	for _, loc := range rePDFStreamHead.FindAllIndex(data, -1) {
+		dict := enclosingDict(data, loc[0])
+		if !rePDFObjStm.Match(dict) || !bytes.Contains(dict, []byte("/FlateDecode")) {
+			continue
+		}
+
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+
+		zr, err := zlib.NewReader(bytes.NewReader(data[start : start+end]))
+		if err != nil {
+			continue
+		}
+
+		// Keep what was decompressed of truncated or corrupt streams
+		content, _ := io.ReadAll(io.LimitReader(zr, maxObjectStreamSize))
+		streams = append(streams, content)
+	}
+
+	return streams
+}
+
+// enclosingDict returns the dictionary of data enclosing offset, without
+// nested dictionaries, or nil if there is none.
+func enclosingDict(data []byte, offset int) []byte {
+	start := -1
+	for i, depth := offset-1, 0; i > 0; i-- {
+		switch {
+		case data[i-1] == '>' && data[i] == '>':
+			depth++
+			i--
+		case data[i-1] == '<' && data[i] == '<':
+			if depth == 0 {
+				start = i - 1
+			} else {
+				depth--
+			}
+			i--
+		}
+		if start >= 0 {
+			break
+		}
+	}
+
+	if start < 0 {
+		return nil
+	}
+
+	// Nested dictionaries are cut out so their keys are not mistaken for
+	// those of the enclosing one
+	var dict []byte
+	for i, depth := start, 0; i+1 < len(data); i++ {
+		switch {
+		case data[i] == '<' && data[i+1] == '<':
+			depth++
+			i++
+			continue
+		case data[i] == '>' && data[i+1] == '>':
+			if depth--; depth == 0 {
+				return dict
+			}
+			i++
+			continue
+		}
+
+		if depth == 1 {
+			dict = append(dict, data[i])
+		}
+	}
+
+	return nil
+}
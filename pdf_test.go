@@ -0,0 +1,76 @@
+package trid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectPDF(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	plain := write("plain.pdf", "%PDF-1.4\n"+
+		"1 0 obj\n<< /Type /Catalog /Pages 2 0 R /Version /1.7 >>\nendobj\n"+
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 >>\nendobj\n"+
+		"3 0 obj\n<< /Type /Pages /Parent 2 0 R /Kids [5 0 R 6 0 R] /Count 2 >>\nendobj\n"+
+		"7 0 obj\n<< /Type /Outlines /Count 12 >>\nendobj\n"+
+		"trailer\n<< /Root 1 0 R /Size 8 >>\n%%EOF\n")
+	encrypted := write("encrypted.pdf", "%PDF-1.6\n"+
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n"+
+		"trailer\n<< /Root 1 0 R /Encrypt 9 0 R /Size 10 >>\n%%EOF\n")
+
+	tr := NewTrid(Options{Cmd: "trid", PDF: true})
+	pdf := []FileType{{Extension: ".pdf", Name: "Adobe Portable Document Format"}}
+
+	tests := []struct {
+		path     string
+		expected PDF
+	}{
+		{"testdata/sample.pdf", PDF{Version: "1.6", Pages: 1}}, // Page tree in object streams.
+		{plain, PDF{Version: "1.7", Pages: 3}},
+		{encrypted, PDF{Version: "1.6", Encrypted: true, Pages: 1}},
+	}
+
+	for _, tt := range tests {
+		if p := tr.inspectPDF(tt.path, pdf); p == nil || *p != tt.expected {
+			t.Errorf("inspectPDF(%s) = %+v, want %+v", filepath.Base(tt.path), p, tt.expected)
+		}
+	}
+
+	if p := tr.inspectPDF("testdata/sample.7z", pdf); p != nil {
+		t.Errorf("inspectPDF(sample.7z) = %+v, want nil", p)
+	}
+
+	if p := tr.inspectPDF(plain, []FileType{{Extension: ".txt"}}); p != nil {
+		t.Errorf("inspectPDF() of a text match = %+v, want nil", p)
+	}
+
+	if p := NewTrid(Options{Cmd: "trid"}).inspectPDF(plain, pdf); p != nil {
+		t.Errorf("inspectPDF() without PDF = %+v, want nil", p)
+	}
+}
+
+func TestScanPDF(t *testing.T) {
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: pdfOutput}, nil
+	})
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, PDF: true})
+
+	r, err := tr.ScanReport(context.Background(), "testdata/sample.pdf", 2)
+	if err != nil || r.PDF == nil || r.PDF.Pages != 1 {
+		t.Errorf("ScanReport() = %+v, %v, want PDF properties", r, err)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 2)
+	if err != nil || results[0].PDF == nil || results[0].PDF.Version != "1.6" {
+		t.Errorf("ScanBatch() = %+v, %v, want PDF properties", results, err)
+	}
+}
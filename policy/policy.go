@@ -0,0 +1,184 @@
+// Package policy decides whether a file identified by TrID is acceptable,
+// based on allowed and denied categories, extensions and MIME types.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/attilabuti/trid"
+)
+
+// Action is the outcome of a policy evaluation.
+type Action int
+
+const (
+	// Deny indicates that the file must be rejected.
+	Deny Action = iota
+
+	// Allow indicates that the file is acceptable.
+	Allow
+)
+
+// String returns the name of the action.
+func (a Action) String() string {
+	if a == Allow {
+		return "allow"
+	}
+
+	return "deny"
+}
+
+// Policy declares which file types are acceptable.
+//
+// Deny rules are checked against every match that meets MinProbability, so a
+// file is rejected if any plausible interpretation of it is denied. Allow rules
+// are checked against the best match only. An empty allow list places no
+// restriction on the corresponding attribute.
+type Policy struct {
+	AllowCategories []trid.Category // Categories that are accepted.
+	DenyCategories  []trid.Category // Categories that are rejected.
+	AllowExtensions []string        // Extensions that are accepted (e.g., ".pdf").
+	DenyExtensions  []string        // Extensions that are rejected (e.g., ".exe").
+	AllowMimeTypes  []string        // MIME types that are accepted; "image/*" matches any image type.
+	DenyMimeTypes   []string        // MIME types that are rejected; "image/*" matches any image type.
+	MinProbability  float64         // Matches below this probability (0-100) are ignored.
+}
+
+// Decision is the result of evaluating a policy against scan results.
+type Decision struct {
+	Action Action         // Whether the file is allowed or denied.
+	Reason string         // Human-readable explanation of the decision.
+	Match  *trid.FileType // The match that determined the decision, if any.
+}
+
+// Allowed reports whether the decision allows the file.
+func (d Decision) Allowed() bool {
+	return d.Action == Allow
+}
+
+// Evaluate applies the policy to the results of a scan and returns the
+// decision. Results are expected in the order returned by Trid.Scan, with the
+// best match first.
+func (p *Policy) Evaluate(results []trid.FileType) Decision {
+	candidates := make([]trid.FileType, 0, len(results))
+	for _, r := range results {
+		if r.Probability >= p.MinProbability {
+			candidates = append(candidates, r)
+		}
+	}
+
+	if len(candidates) == 0 {
+		if len(results) == 0 {
+			return Decision{Action: Deny, Reason: "file type could not be identified"}
+		}
+
+		return Decision{Action: Deny, Reason: fmt.Sprintf("no match with probability of at least %.1f%%", p.MinProbability)}
+	}
+
+	for i := range candidates {
+		if reason := p.denied(candidates[i]); reason != "" {
+			return Decision{Action: Deny, Reason: reason, Match: &candidates[i]}
+		}
+	}
+
+	best := &candidates[0]
+	if reason := p.notAllowed(*best); reason != "" {
+		return Decision{Action: Deny, Reason: reason, Match: best}
+	}
+
+	return Decision{Action: Allow, Reason: fmt.Sprintf("%s (%s) is allowed", best.Name, best.Extension), Match: best}
+}
+
+// denied returns the reason the file type is denied, or an empty string.
+func (p *Policy) denied(f trid.FileType) string {
+	if c := f.Category(); containsCategory(p.DenyCategories, c) {
+		return fmt.Sprintf("category %q is denied", c)
+	}
+
+	if ext, ok := matchExtension(p.DenyExtensions, f.Extension); ok {
+		return fmt.Sprintf("extension %q is denied", ext)
+	}
+
+	if matchMimeType(p.DenyMimeTypes, f.MimeType) {
+		return fmt.Sprintf("MIME type %q is denied", f.MimeType)
+	}
+
+	return ""
+}
+
+// notAllowed returns the reason the file type is not allowed, or an empty
+// string.
+func (p *Policy) notAllowed(f trid.FileType) string {
+	if c := f.Category(); len(p.AllowCategories) > 0 && !containsCategory(p.AllowCategories, c) {
+		return fmt.Sprintf("category %q is not allowed", c)
+	}
+
+	if _, ok := matchExtension(p.AllowExtensions, f.Extension); len(p.AllowExtensions) > 0 && !ok {
+		return fmt.Sprintf("extension %q is not allowed", f.Extension)
+	}
+
+	if len(p.AllowMimeTypes) > 0 && !matchMimeType(p.AllowMimeTypes, f.MimeType) {
+		return fmt.Sprintf("MIME type %q is not allowed", f.MimeType)
+	}
+
+	return ""
+}
+
+// containsCategory reports whether c is in list.
+func containsCategory(list []trid.Category, c trid.Category) bool {
+	for _, l := range list {
+		if l == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchExtension reports whether any of the slash-separated extensions
+// reported by TrID is in list, and returns the matching extension.
+func matchExtension(list []string, extension string) (string, bool) {
+	for _, ext := range strings.Split(extension, "/") {
+		ext = normalizeExtension(ext)
+		for _, l := range list {
+			if normalizeExtension(l) == ext {
+				return ext, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// normalizeExtension lower-cases an extension and ensures it has a leading dot.
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	return ext
+}
+
+// matchMimeType reports whether mimeType matches any pattern in list. A
+// pattern ending in "/*" matches every subtype.
+func matchMimeType(list []string, mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "" {
+		return false
+	}
+
+	for _, l := range list {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if prefix, ok := strings.CutSuffix(l, "/*"); ok {
+			if strings.HasPrefix(mimeType, prefix+"/") {
+				return true
+			}
+		} else if l == mimeType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+var (
+	pdf = trid.FileType{Extension: ".pdf", Probability: 100, Name: "Adobe Portable Document Format", MimeType: "application/pdf"}
+	png = trid.FileType{Extension: ".png", Probability: 90, Name: "Portable Network Graphics", MimeType: "image/png"}
+	exe = trid.FileType{Extension: ".exe", Probability: 30, Name: "Win32 Executable (generic)"}
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		results  []trid.FileType
+		expected Action
+	}{
+		{
+			name:     "No results",
+			policy:   Policy{},
+			expected: Deny,
+		},
+		{
+			name:     "Empty policy",
+			policy:   Policy{},
+			results:  []trid.FileType{pdf},
+			expected: Allow,
+		},
+		{
+			name:     "Allowed category",
+			policy:   Policy{AllowCategories: []trid.Category{trid.CategoryDocument}},
+			results:  []trid.FileType{pdf},
+			expected: Allow,
+		},
+		{
+			name:     "Category not allowed",
+			policy:   Policy{AllowCategories: []trid.Category{trid.CategoryImage}},
+			results:  []trid.FileType{pdf},
+			expected: Deny,
+		},
+		{
+			name:     "Allowed extension without dot",
+			policy:   Policy{AllowExtensions: []string{"PDF"}},
+			results:  []trid.FileType{pdf},
+			expected: Allow,
+		},
+		{
+			name:     "Allowed MIME type wildcard",
+			policy:   Policy{AllowMimeTypes: []string{"image/*"}},
+			results:  []trid.FileType{png},
+			expected: Allow,
+		},
+		{
+			name:     "Denied MIME type",
+			policy:   Policy{DenyMimeTypes: []string{"application/pdf"}},
+			results:  []trid.FileType{pdf},
+			expected: Deny,
+		},
+		{
+			name:     "Denied secondary match",
+			policy:   Policy{DenyCategories: []trid.Category{trid.CategoryExecutable}},
+			results:  []trid.FileType{png, exe},
+			expected: Deny,
+		},
+		{
+			name:     "Denied match below minimum probability",
+			policy:   Policy{DenyExtensions: []string{".exe"}, MinProbability: 50},
+			results:  []trid.FileType{png, exe},
+			expected: Allow,
+		},
+		{
+			name:     "Below minimum probability",
+			policy:   Policy{MinProbability: 95},
+			results:  []trid.FileType{png},
+			expected: Deny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.policy.Evaluate(tt.results)
+			if d.Action != tt.expected {
+				t.Errorf("Evaluate() = %v (%s), want %v", d.Action, d.Reason, tt.expected)
+			}
+
+			if d.Reason == "" {
+				t.Error("Evaluate() returned an empty reason")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultPolyglotMinProbability is the default
+// PolyglotOptions.MinProbability.
+const DefaultPolyglotMinProbability = 20
+
+// Sizes of the parts of a file read by ScanPolyglot: formats are recognized by
+// a signature near the beginning of the file or, for zip archives, by the end
+// of central directory record, within 64 KiB of the end.
+const (
+	polyglotHeadSize = 1024
+	polyglotTailSize = 22 + 65535
+)
+
+// PolyglotOptions configures ScanPolyglot.
+type PolyglotOptions struct {
+	// MinProbability is the percentage from which TrID matches count as a
+	// format of the file (default: DefaultPolyglotMinProbability).
+	MinProbability float64
+}
+
+// PolyglotFormat is a format a file is valid as.
+type PolyglotFormat struct {
+	Family   string    // Format family, such as "zip" for zip archives and the formats built on them.
+	Offset   int64     // Offset of the signature found, or -1 if only TrID reported the format.
+	FileType *FileType // TrID match of the family, if any.
+}
+
+// PolyglotReport is the result of ScanPolyglot.
+type PolyglotReport struct {
+	Path      string           // Path of the file scanned.
+	FileTypes []FileType       // TrID matches of the file; nil if TrID did not identify it.
+	Formats   []PolyglotFormat // Formats the file is valid as, one per family: those reported by TrID first, by probability.
+}
+
+// Polyglot reports whether the file is valid as several unrelated formats.
+func (r *PolyglotReport) Polyglot() bool {
+	return len(r.Formats) > 1
+}
+
+// polyglotSignature recognizes a format family from the head and tail of a
+// file, returning the offset of its signature or -1.
+type polyglotSignature struct {
+	family string
+	find   func(head, tail []byte, size int64) int64
+}
+
+// atStart returns a signature finder matching any of the prefixes.
+func atStart(prefixes ...string) func(head, tail []byte, size int64) int64 {
+	return func(head, _ []byte, _ int64) int64 {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(head, []byte(p)) {
+				return 0
+			}
+		}
+
+		return -1
+	}
+}
+
+// polyglotSignatures are the formats recognized by ScanPolyglot regardless
+// of TrID, because their readers tolerate leading or trailing data.
+var polyglotSignatures = []polyglotSignature{
+	{"pdf", func(head, _ []byte, _ int64) int64 {
+		// Readers accept the header anywhere in the first KiB
+		return int64(bytes.Index(head, []byte("%PDF-")))
+	}},
+	{"zip", func(_, tail []byte, size int64) int64 {
+		// Readers locate the central directory from the end of the file
+		if i := bytes.LastIndex(tail, []byte("PK\x05\x06")); i >= 0 {
+			return size - int64(len(tail)) + int64(i)
+		}
+
+		return -1
+	}},
+	{"html", func(head, _ []byte, _ int64) int64 {
+		// Browsers sniff markup in the first 512 bytes
+		lower := bytes.ToLower(head[:min(len(head), 512)])
+		for _, tag := range []string{"<html", "<script", "<svg"} {
+			if i := bytes.Index(lower, []byte(tag)); i >= 0 {
+				return int64(i)
+			}
+		}
+
+		return -1
+	}},
+	{"gif", atStart("GIF87a", "GIF89a")},
+	{"png", atStart("\x89PNG\r\n\x1a\n")},
+	{"jpeg", atStart("\xff\xd8\xff")},
+	{"pe", atStart("MZ")},
+	{"elf", atStart("\x7fELF")},
+	{"rar", atStart("Rar!\x1a\x07")},
+	{"7z", atStart("7z\xbc\xaf\x27\x1c")},
+}
+
+// polyglotFamilies maps the extensions of TrID matches to their format
+// family. Matches of other extensions are not considered.
+var polyglotFamilies = map[string]string{
+	".zip": "zip", ".jar": "zip", ".apk": "zip", ".epub": "zip", ".xpi": "zip",
+	".docx": "zip", ".docm": "zip", ".xlsx": "zip", ".xlsm": "zip", ".pptx": "zip", ".pptm": "zip",
+	".odt": "zip", ".ods": "zip", ".odp": "zip", ".odg": "zip",
+	".pdf": "pdf", ".html": "html", ".htm": "html", ".svg": "html",
+	".gif": "gif", ".png": "png", ".jpg": "jpeg", ".jpeg": "jpeg",
+	".exe": "pe", ".dll": "pe", ".sys": "pe", ".elf": "elf", ".so": "elf",
+	".rar": "rar", ".7z": "7z",
+}
+
+// ScanPolyglot identifies the file at filePath and reports the unrelated
+// formats it is valid as, to hunt for polyglots such as GIF/JAR or PDF/ZIP
+// files, which are handled as a harmless format by some readers and as a
+// dangerous one by others.
+//
+// Formats are recognized both from TrID matches of at least
+// opts.MinProbability percent and from signatures readers tolerate away from
+// the beginning of a file, such as the header of PDF documents or the end of
+// the central directory of zip archives, which TrID may not report. Formats
+// of the same family, such as zip archives and the JAR or Office Open XML
+// files built on them, count once.
+//
+// Files TrID cannot identify are not an error. The returned error is
+// reserved for invalid arguments, a missing file and failures affecting every
+// file, as for ScanArchive.
+func (t *Trid) ScanPolyglot(ctx context.Context, filePath string, numberOfMatches int, opts PolyglotOptions) (*PolyglotReport, error) {
+	if opts.MinProbability <= 0 {
+		opts.MinProbability = DefaultPolyglotMinProbability
+	}
+
+	fileTypes, err := t.ScanContext(ctx, filePath, numberOfMatches)
+	if isFatalScanError(ctx, err) {
+		return nil, err
+	}
+
+	head, tail, size, err := readHeadTail(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PolyglotReport{Path: filePath, FileTypes: fileTypes}
+	formats := make(map[string]int)
+	add := func(family string, offset int64, ft *FileType) {
+		if i, ok := formats[family]; ok {
+			if r.Formats[i].Offset < 0 {
+				r.Formats[i].Offset = offset
+			}
+			if r.Formats[i].FileType == nil {
+				r.Formats[i].FileType = ft
+			}
+			return
+		}
+
+		formats[family] = len(r.Formats)
+		r.Formats = append(r.Formats, PolyglotFormat{Family: family, Offset: offset, FileType: ft})
+	}
+
+	for i, ft := range fileTypes {
+		if family, ok := polyglotFamilies[strings.ToLower(ft.Extension)]; ok && ft.Probability >= opts.MinProbability {
+			add(family, -1, &fileTypes[i])
+		}
+	}
+
+	for _, sig := range polyglotSignatures {
+		if offset := sig.find(head, tail, size); offset >= 0 {
+			add(sig.family, offset, nil)
+		}
+	}
+
+	return r, nil
+}
+
+// readHeadTail returns the beginning and the end of the file at path, which
+// may overlap, and its size.
+func readHeadTail(path string) (head, tail []byte, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	size = fi.Size()
+
+	head = make([]byte, min(size, polyglotHeadSize))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return nil, nil, 0, err
+	}
+
+	tail = make([]byte, min(size, polyglotTailSize))
+	if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return nil, nil, 0, err
+	}
+
+	return head, tail, size, nil
+}
@@ -0,0 +1,76 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanPolyglot(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	jar := readFile(t, writeZip(t, map[string][]byte{"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n")}, "META-INF/MANIFEST.MF"))
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"pdfzip.pdf": append(append([]byte{}, pdf...), jar...),
+		"gifar.gif":  append([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), jar...),
+		"plain.pdf":  pdf,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		families []string
+		trid     bool // Whether TrID reported the first format.
+	}{
+		{"pdfzip.pdf", []string{"pdf", "zip"}, true},
+		{"gifar.gif", []string{"zip", "gif"}, false},
+		{"plain.pdf", []string{"pdf"}, true},
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tr.ScanPolyglot(context.Background(), filepath.Join(dir, tt.name), 5, PolyglotOptions{})
+			if err != nil {
+				t.Fatalf("ScanPolyglot() error = %v", err)
+			}
+
+			var families []string
+			for _, f := range r.Formats {
+				families = append(families, f.Family)
+			}
+
+			if len(families) != len(tt.families) || r.Polyglot() != (len(tt.families) > 1) {
+				t.Fatalf("ScanPolyglot() formats = %q, want %q", families, tt.families)
+			}
+
+			for i, f := range r.Formats {
+				if f.Family != tt.families[i] {
+					t.Errorf("format %d = %q, want %q", i, f.Family, tt.families[i])
+				}
+			}
+
+			if first := r.Formats[0]; (first.FileType != nil) != tt.trid || first.Offset != 0 && tt.trid {
+				t.Errorf("ScanPolyglot() first format = %+v", first)
+			}
+		})
+	}
+
+	// The zip archive is appended to the document
+	r, _ := tr.ScanPolyglot(context.Background(), filepath.Join(dir, "pdfzip.pdf"), 5, PolyglotOptions{})
+	if zip := r.Formats[1]; zip.Offset < int64(len(pdf)) {
+		t.Errorf("zip format offset = %d, want past %d", zip.Offset, len(pdf))
+	}
+
+	if _, err := tr.ScanPolyglot(context.Background(), "missing.pdf", 5, PolyglotOptions{}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("ScanPolyglot() error = %v, want %v", err, ErrFileNotFound)
+	}
+}
@@ -0,0 +1,167 @@
+package trid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// SourcePrecheck is the FileType.Source of matches found by Options.Precheck
+// without TrID.
+const SourcePrecheck = "precheck"
+
+// precheckSize is the number of leading bytes of a file Options.Precheck
+// classifies it from.
+const precheckSize = 8 << 10
+
+// specificRoots are the root elements of XML-based formats TrID tells apart,
+// which Options.Precheck leaves to it.
+var specificRoots = map[string]bool{
+	"svg": true, "html": true, "rss": true, "feed": true, "plist": true, "kml": true,
+	"gpx": true, "Project": true, "package": true, "xsl:stylesheet": true, "Envelope": true,
+}
+
+// textMarkers are the prefixes of textual formats TrID identifies more
+// precisely than as plain text, such as scripts, HTML and e-mail messages.
+var textMarkers = []string{
+	"#!", "%!", "%PDF", "{\\rtf", "<", "-----BEGIN", "BEGIN:", "MIME-Version:",
+	"Received:", "Return-Path:", "From ", "WEBVTT", "#EXTM3U",
+}
+
+// precheck classifies the file at path as JSON, XML, CSV or plain text from
+// its first bytes, if Options.Precheck is set, returning nil if it is not
+// obviously one of them.
+func (t *Trid) precheck(path string) []FileType {
+	if !t.options.Precheck {
+		return nil
+	}
+
+	head, err := readHead(path, precheckSize+1)
+	if err != nil || len(head) == 0 {
+		return nil
+	}
+
+	complete := len(head) <= precheckSize
+	head = head[:min(len(head), precheckSize)]
+
+	// Other encodings are rarely plain text formats
+	if charset := DetectCharset(head); charset != "US-ASCII" && charset != "UTF-8" {
+		return nil
+	}
+	head = bytes.TrimPrefix(head, []byte{0xef, 0xbb, 0xbf})
+
+	ft, ok := classifyText(head, complete)
+	if !ok {
+		return nil
+	}
+
+	ft.Probability, ft.Source = 100, SourcePrecheck
+	t.stats.precheckHit()
+
+	return []FileType{ft}
+}
+
+// classifyText classifies the text head, the whole file if complete, as JSON,
+// XML, CSV or plain text.
+func classifyText(head []byte, complete bool) (FileType, bool) {
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case len(trimmed) == 0:
+		return FileType{}, false
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		if isJSON(trimmed, complete) {
+			return FileType{Extension: ".json", Name: "JSON data", MimeType: "application/json"}, true
+		}
+		return FileType{}, false
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		if isGenericXML(trimmed) {
+			return FileType{Extension: ".xml", Name: "Extensible Markup Language", MimeType: "application/xml"}, true
+		}
+		return FileType{}, false
+	}
+
+	for _, marker := range textMarkers {
+		if strings.HasPrefix(string(trimmed), marker) {
+			return FileType{}, false
+		}
+	}
+
+	if comma, ok := csvDelimiter(head, complete); ok {
+		if comma == '\t' {
+			return FileType{Extension: ".tsv", Name: "Tab-separated values", MimeType: "text/tab-separated-values"}, true
+		}
+		return FileType{Extension: ".csv", Name: "Comma-separated values", MimeType: "text/csv"}, true
+	}
+
+	return FileType{Extension: ".txt", Name: "Plain text", MimeType: "text/plain"}, true
+}
+
+// isJSON reports whether data is a JSON document, or the start of one unless
+// complete.
+func isJSON(data []byte, complete bool) bool {
+	if complete {
+		return json.Valid(data)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err != nil {
+			return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// isGenericXML reports whether data starts a well-formed XML document whose
+// root element is not that of a specific XML-based format.
+func isGenericXML(data []byte) bool {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return false
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			if tok.Name.Space != "" {
+				name = tok.Name.Space + ":" + name
+			}
+			return !specificRoots[name] && !specificRoots[tok.Name.Local]
+		case xml.Directive:
+			// Documents with a DOCTYPE declare a specific format
+			return false
+		}
+	}
+}
+
+// csvDelimiter returns the delimiter of data if it is delimiter-separated
+// values: at least two lines with the same number of fields, more than one.
+// Unless complete, the last line is ignored as it may be cut.
+func csvDelimiter(data []byte, complete bool) (rune, bool) {
+	if !complete {
+		i := bytes.LastIndexByte(data, '\n')
+		if i < 0 {
+			return 0, false
+		}
+		data = data[:i+1]
+	}
+
+	for _, comma := range []rune{',', '\t', ';'} {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.Comma = comma
+
+		records, err := r.ReadAll()
+		if err == nil && len(records) >= 2 && len(records[0]) > 1 {
+			return comma, true
+		}
+	}
+
+	return 0, false
+}
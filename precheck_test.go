@@ -0,0 +1,105 @@
+package trid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyText(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		complete bool
+		expected string // Extension, or "" if left to TrID.
+	}{
+		{"JSON", `{"a": [1, 2, 3]}`, true, ".json"},
+		{"JSON cut", `[{"a": 1}, {"b": "tr`, false, ".json"},
+		{"Invalid JSON", `{"a": }`, true, ""},
+		{"XML", "<?xml version=\"1.0\"?>\n<config><item/></config>", true, ".xml"},
+		{"SVG", "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>", true, ""},
+		{"DOCTYPE", "<?xml version=\"1.0\"?>\n<!DOCTYPE note SYSTEM \"note.dtd\">\n<note/>", true, ""},
+		{"CSV", "id,name\n1,alpha\n2,beta\n", true, ".csv"},
+		{"CSV cut", "id,name\n1,alpha\n2,be", false, ".csv"},
+		{"TSV", "id\tname\n1\talpha\n", true, ".tsv"},
+		{"Text", "2024-01-01 12:00:00 INFO started\n2024-01-01 12:00:01 INFO done\n", true, ".txt"},
+		{"Script", "#!/bin/sh\necho hello\n", true, ""},
+		{"HTML", "<!DOCTYPE html><html></html>", true, ""},
+		{"Blank", " \n\t\n", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft, ok := classifyText([]byte(tt.data), tt.complete)
+			if !ok {
+				ft.Extension = ""
+			}
+
+			if ft.Extension != tt.expected {
+				t.Errorf("classifyText(%q) = %q, want %q", tt.data, ft.Extension, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanPrecheck(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.log":    "INFO started\nINFO done\n",
+		"data.json":  `{"ok": true}`,
+		"report.pdf": "%PDF-1.4\n",
+		"blob.bin":   "\x00\x01\x02\x03",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls []string
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		calls = append(calls, filepath.Base(c.Args[len(c.Args)-1]))
+		return CommandResult{Stdout: pdfOutput}, nil
+	})
+
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, Precheck: true})
+	ctx := context.Background()
+
+	fileTypes, err := tr.ScanContext(ctx, filepath.Join(dir, "app.log"), 5)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0].Extension != ".txt" || fileTypes[0].Source != SourcePrecheck {
+		t.Errorf("ScanContext(app.log) = %+v, %v, want precheck text", fileTypes, err)
+	}
+
+	if fileTypes, err = tr.ScanContext(ctx, filepath.Join(dir, "report.pdf"), 5); err != nil || fileTypes[0].Source != "" {
+		t.Errorf("ScanContext(report.pdf) = %+v, %v, want TrID match", fileTypes, err)
+	}
+
+	if strings.Join(calls, ",") != "report.pdf" {
+		t.Errorf("TrID runs = %v, want report.pdf only", calls)
+	}
+
+	paths := []string{filepath.Join(dir, "data.json"), filepath.Join(dir, "blob.bin")}
+	results, err := tr.ScanBatch(ctx, paths, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := results[0]; r.Err != nil || r.FileTypes[0].Extension != ".json" || r.FileTypes[0].Source != SourcePrecheck {
+		t.Errorf("ScanBatch() data.json = %+v, want precheck JSON", r)
+	}
+
+	if r := results[1]; r.Err != nil || r.FileTypes[0].Source != "" {
+		t.Errorf("ScanBatch() blob.bin = %+v, want TrID match", r)
+	}
+
+	if s := tr.Stats(); s.Prechecked != 2 {
+		t.Errorf("Stats().Prechecked = %d, want 2", s.Prechecked)
+	}
+
+	calls = nil
+	if _, err := NewTrid(Options{Cmd: "trid", Runner: runner}).ScanContext(ctx, filepath.Join(dir, "app.log"), 5); err != nil || len(calls) != 1 {
+		t.Errorf("ScanContext() without Precheck = %v, %d runs, want TrID run", err, len(calls))
+	}
+}
@@ -0,0 +1,116 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/attilabuti/trid/defs"
+)
+
+var (
+	// ErrBinaryNotFound is returned by Probe and FindBinary when the TrID
+	// executable cannot be found or is not executable.
+	ErrBinaryNotFound = errors.New("TrID executable not found")
+
+	// ErrDefinitionsUnreadable is returned by Probe when the TRiD definitions
+	// package exists but cannot be read.
+	ErrDefinitionsUnreadable = errors.New("TRiD definitions are unreadable")
+)
+
+// probeContent is the content of the file identified by Probe.
+const probeContent = "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<< >>\nendobj\ntrailer\n<< >>\n%%EOF\n"
+
+// Probe checks that TrID is usable: the executable can be found, the
+// definitions package exists, is not empty and is readable, and a small file
+// can be identified. With the native engine, which needs no executable, it
+// checks that the XML definitions were loaded and are still readable instead.
+// It returns nil if TrID is healthy, or an error matching ErrBinaryNotFound,
+// ErrNoDefinitions, ErrEmptyDefPackage, ErrDefinitionsUnreadable,
+// ErrDefinitionsMismatch or ErrUnavailable describing the first problem
+// found. Probe is suitable for readiness checks; it bypasses the cache.
+func (t *Trid) Probe(ctx context.Context) error {
+	if t.optsErr != nil {
+		return t.optsErr
+	}
+
+	if t.options.Engine == EngineNative {
+		if _, err := os.Stat(t.options.Definitions); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %s", ErrNoDefinitions, t.options.Definitions)
+			}
+
+			return fmt.Errorf("%w: %v", ErrDefinitionsUnreadable, err)
+		}
+	} else {
+		if _, err := exec.LookPath(t.options.Cmd); err != nil {
+			return fmt.Errorf("%w: %v", ErrBinaryNotFound, err)
+		}
+
+		if err := t.probeDefinitions(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.CreateTemp("", "trid-probe-*.pdf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(probeContent)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Definitions without a match for the probe file are still healthy
+	if _, err := t.run(ctx, f.Name(), 1); err != nil && !errors.Is(err, ErrUnknownFileType) {
+		return err
+	}
+
+	return nil
+}
+
+// probeDefinitions checks that the definitions package exists, is not empty
+// and can be read.
+func (t *Trid) probeDefinitions() error {
+	path, err := defs.Locate(t.options.Cmd, t.options.Definitions)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBinaryNotFound, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNoDefinitions, path)
+		}
+
+		return fmt.Errorf("%w: %v", ErrDefinitionsUnreadable, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDefinitionsUnreadable, err)
+	}
+
+	if fi.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrDefinitionsUnreadable, path)
+	}
+
+	if fi.Size() == 0 {
+		return fmt.Errorf("%w: %s", ErrEmptyDefPackage, path)
+	}
+
+	if _, err := f.Read(make([]byte, 1)); err != nil && err != io.EOF {
+		return fmt.Errorf("%w: %v", ErrDefinitionsUnreadable, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		setup       func(t *testing.T, cmd string) Options
+		expectedErr error
+	}{
+		{
+			name:   "Healthy",
+			output: pdfOutput,
+		},
+		{
+			name:   "Unknown probe file",
+			output: unknownOutput,
+		},
+		{
+			name:   "Missing executable",
+			output: pdfOutput,
+			setup: func(t *testing.T, cmd string) Options {
+				return Options{Cmd: filepath.Join(t.TempDir(), "missing")}
+			},
+			expectedErr: ErrBinaryNotFound,
+		},
+		{
+			name:   "Missing definitions",
+			output: pdfOutput,
+			setup: func(t *testing.T, cmd string) Options {
+				return Options{Cmd: cmd, Definitions: filepath.Join(t.TempDir(), "triddefs.trd")}
+			},
+			expectedErr: ErrNoDefinitions,
+		},
+		{
+			name:   "Empty definitions",
+			output: pdfOutput,
+			setup: func(t *testing.T, cmd string) Options {
+				path := filepath.Join(t.TempDir(), "triddefs.trd")
+				if err := os.WriteFile(path, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return Options{Cmd: cmd, Definitions: path}
+			},
+			expectedErr: ErrEmptyDefPackage,
+		},
+		{
+			name:   "Unreadable definitions",
+			output: pdfOutput,
+			setup: func(t *testing.T, cmd string) Options {
+				return Options{Cmd: cmd, Definitions: t.TempDir()}
+			},
+			expectedErr: ErrDefinitionsUnreadable,
+		},
+		{
+			name:        "Definitions rejected by TrID",
+			output:      "No definitions available!\n",
+			expectedErr: ErrNoDefinitions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, _ := newStub(t, tt.output)
+
+			opts := Options{Cmd: cmd}
+			if tt.setup != nil {
+				opts = tt.setup(t, cmd)
+			}
+
+			err := NewTrid(opts).Probe(context.Background())
+			if tt.expectedErr == nil && err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Probe() error = %v, want %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
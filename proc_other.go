@@ -0,0 +1,26 @@
+//go:build !unix && !windows
+
+package trid
+
+import (
+	"os/exec"
+	"time"
+)
+
+// processGroup is a no-op on platforms without process groups; only the
+// direct child is killed.
+type processGroup struct{}
+
+// newProcessGroup returns a no-op process group.
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	return &processGroup{}
+}
+
+// attach is called once the command has started.
+func (g *processGroup) attach() {}
+
+// wait returns immediately.
+func (g *processGroup) wait(timeout time.Duration) {}
+
+// close releases the resources of the group.
+func (g *processGroup) close() {}
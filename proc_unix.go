@@ -0,0 +1,56 @@
+//go:build unix
+
+package trid
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// processGroup kills TrID together with any processes it started.
+type processGroup struct {
+	cmd *exec.Cmd
+}
+
+// newProcessGroup configures cmd to run in a process group of its own, which
+// is killed as a whole when the command's context is done.
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	g := &processGroup{cmd: cmd}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = g.kill
+
+	return g
+}
+
+// attach is called once the command has started.
+func (g *processGroup) attach() {}
+
+// kill sends SIGKILL to every process in the group.
+func (g *processGroup) kill() error {
+	if err := syscall.Kill(-g.cmd.Process.Pid, syscall.SIGKILL); errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	} else if err != nil {
+		return g.cmd.Process.Kill()
+	}
+
+	return nil
+}
+
+// wait waits up to timeout for the processes of a killed group to exit.
+func (g *processGroup) wait(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(-g.cmd.Process.Pid, 0); errors.Is(err, syscall.ESRCH) {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// close releases the resources of the group.
+func (g *processGroup) close() {}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package trid
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTimeoutKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\n" +
+		"sleep 30 &\n" +
+		"echo $! > '" + pidFile + "'\n" +
+		"wait\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err := NewTrid(Options{Cmd: cmd, Timeout: 200 * time.Millisecond}).Scan(cmd, 1)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrTimeout)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Scan() returned after %v", elapsed)
+	}
+
+	b, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processAlive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("child process %d survived the timeout", pid)
+	}
+}
+
+// processAlive reports whether the process with the given pid is running.
+// Zombies waiting to be reaped are not considered running.
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+
+	// The state follows the parenthesized command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
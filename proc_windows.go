@@ -0,0 +1,119 @@
+//go:build windows
+
+package trid
+
+import (
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobAccounting mirrors JOBOBJECT_BASIC_ACCOUNTING_INFORMATION, which
+// golang.org/x/sys/windows does not define.
+type jobAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// processGroup kills TrID together with any processes it started, using a job
+// object.
+type processGroup struct {
+	cmd *exec.Cmd
+	job windows.Handle
+}
+
+// newProcessGroup prepares a job object for cmd, which is terminated as a
+// whole when the command's context is done. If the job object cannot be
+// created, only the direct child is killed.
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	g := &processGroup{cmd: cmd}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return g
+	}
+
+	// Terminate the processes of the job if the handle is closed early
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	_, err = windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+	if err != nil {
+		windows.CloseHandle(job)
+		return g
+	}
+
+	g.job = job
+	cmd.Cancel = g.kill
+
+	return g
+}
+
+// attach assigns the started command to the job object. If that fails, the
+// job object is released and only the direct child is killed.
+func (g *processGroup) attach() {
+	if g.job == 0 {
+		return
+	}
+
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(g.cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(g.job, process)
+		windows.CloseHandle(process)
+	}
+
+	if err != nil {
+		g.close()
+		g.job = 0
+	}
+}
+
+// kill terminates every process in the job.
+func (g *processGroup) kill() error {
+	if g.job == 0 {
+		return g.cmd.Process.Kill()
+	}
+
+	if err := windows.TerminateJobObject(g.job, 1); err != nil {
+		return g.cmd.Process.Kill()
+	}
+
+	return nil
+}
+
+// wait waits up to timeout for the processes of a terminated job to exit.
+func (g *processGroup) wait(timeout time.Duration) {
+	if g.job == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		var info jobAccounting
+		err := windows.QueryInformationJobObject(g.job, windows.JobObjectBasicAccountingInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), nil)
+		if err != nil || info.ActiveProcesses == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// close releases the job object.
+func (g *processGroup) close() {
+	if g.job != 0 {
+		windows.CloseHandle(g.job)
+	}
+}
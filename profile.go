@@ -0,0 +1,110 @@
+package trid
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// reBanner matches the TrID banner, capturing the version and its major and
+// minor numbers.
+var reBanner = regexp.MustCompile(`(?m)^[ \t]*TrID(?:/32)?[ \t]*-[ \t]*File Identifier v((\d+)\.(\d+)[0-9a-z.]*)`)
+
+// reDefinitionsFound matches the number of definitions TrID reports having
+// loaded.
+var reDefinitionsFound = regexp.MustCompile(`(?m)^[ \t]*Definitions found:[ \t]*(\d+)`)
+
+// banner holds the information found in the TrID banner.
+type banner struct {
+	found   bool
+	version string // Version as printed, such as "2.24".
+	major   int
+	minor   int
+}
+
+// before reports whether the banner version is older than major.minor.
+func (b banner) before(major, minor int) bool {
+	return b.major < major || b.major == major && b.minor < minor
+}
+
+// profile describes the output layout of a family of TrID versions. To support
+// a new layout, add a profile to profiles together with fixtures in
+// testdata/output.
+type profile struct {
+	name    string
+	match   func(b banner) bool     // Reports whether the profile applies to output with the banner.
+	details map[string]detailSetter // Setters of the detail lines printed under a file type, by lowercase label.
+}
+
+// detailSetter stores the value of a detail line in a file type.
+type detailSetter func(f *FileType, value string)
+
+// details222 are the detail lines printed by TrID 2.2x.
+var details222 = map[string]detailSetter{
+	"mime type":   func(f *FileType, v string) { f.MimeType = v },
+	"related url": func(f *FileType, v string) { f.RelatedURL = v },
+	"definition":  func(f *FileType, v string) { f.Definition = v },
+	"remarks":     func(f *FileType, v string) { f.Remarks = v },
+}
+
+// profiles lists the known output layouts, most specific first. The last one
+// is used when no other profile matches, including output without a banner.
+// Linux builds share the layout of the corresponding Win32 versions.
+var profiles = []*profile{
+	{
+		// TrID 2.1x does not report the definition of file types
+		name:  "2.1x",
+		match: func(b banner) bool { return b.found && b.before(2, 20) },
+		details: map[string]detailSetter{
+			"mime type":   details222["mime type"],
+			"related url": details222["related url"],
+			"remarks":     details222["remarks"],
+		},
+	},
+	{
+		name:    "2.2x",
+		match:   func(banner) bool { return true },
+		details: details222,
+	},
+}
+
+// parseBanner extracts the banner information from TrID output.
+func parseBanner(out string) banner {
+	m := reBanner.FindStringSubmatch(out)
+	if m == nil {
+		return banner{}
+	}
+
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+
+	// Versions are printed with two digits, so v2.1 means 2.10
+	if len(m[3]) == 1 {
+		minor *= 10
+	}
+
+	return banner{found: true, version: m[1], major: major, minor: minor}
+}
+
+// definitionsCount returns the number of definitions TrID reports in out, or
+// 0 if it is not found.
+func definitionsCount(out string) int {
+	m := reDefinitionsFound.FindStringSubmatch(out)
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// detectProfile returns the profile matching the banner of out.
+func detectProfile(out string) *profile {
+	b := parseBanner(out)
+	for _, p := range profiles {
+		if p.match(b) {
+			return p
+		}
+	}
+
+	return profiles[len(profiles)-1]
+}
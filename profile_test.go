@@ -0,0 +1,80 @@
+package trid
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// outputFixture is the expected result of parsing a file in testdata/output.
+type outputFixture struct {
+	Profile   string     `json:"profile"`
+	FileTypes []FileType `json:"file_types"`
+}
+
+func TestOutputFixtures(t *testing.T) {
+	files, err := filepath.Glob("testdata/output/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no output fixtures found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			out, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(strings.TrimSuffix(file, ".txt") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected outputFixture
+			if err := json.Unmarshal(b, &expected); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := detectProfile(string(out)).name; got != expected.Profile {
+				t.Errorf("detectProfile() = %q, want %q", got, expected.Profile)
+			}
+
+			fileTypes, err := parseOutput(string(out))
+			if err != nil {
+				t.Fatalf("parseOutput() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(fileTypes, expected.FileTypes) {
+				t.Errorf("parseOutput() = %+v, want %+v", fileTypes, expected.FileTypes)
+			}
+		})
+	}
+}
+
+func TestParseBanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		expected banner
+	}{
+		{"Win32", "TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello", banner{true, "2.24", 2, 24}},
+		{"Linux", "TrID - File Identifier v2.24 - (C) 2003-16 By M.Pontello", banner{true, "2.24", 2, 24}},
+		{"Single digit minor", "TrID/32 - File Identifier v2.1 - (C) 2003-11 By M.Pontello", banner{true, "2.1", 2, 10}},
+		{"Missing", "Collecting data from file: sample.pdf", banner{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBanner(tt.out); got != tt.expected {
+				t.Errorf("parseBanner() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
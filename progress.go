@@ -0,0 +1,82 @@
+package trid
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Progress describes how far ScanBatch has got, as reported to
+// Options.Progress.
+type Progress struct {
+	FilesDone  int           // Files identified, successfully or not.
+	FilesTotal int           // Files to identify.
+	BytesDone  int64         // Size of the files identified.
+	BytesTotal int64         // Size of the files to identify; files that cannot be read count as empty.
+	Current    string        // Path of the file identified last.
+	Elapsed    time.Duration // Time since the scan started.
+	ETA        time.Duration // Estimated time until the scan completes; 0 until it can be estimated.
+}
+
+// progress tracks the progress of a batch scan.
+type progress struct {
+	mu     sync.Mutex
+	report func(Progress)
+	start  time.Time
+	paths  []string
+	sizes  []int64
+	state  Progress
+}
+
+// newProgress returns a tracker for scanning paths, or nil if progress
+// reporting is disabled.
+func (t *Trid) newProgress(paths []string) *progress {
+	if t.options.Progress == nil {
+		return nil
+	}
+
+	p := &progress{
+		report: t.options.Progress,
+		start:  time.Now(),
+		paths:  paths,
+		sizes:  make([]int64, len(paths)),
+		state:  Progress{FilesTotal: len(paths)},
+	}
+
+	for i, path := range paths {
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			p.sizes[i] = fi.Size()
+			p.state.BytesTotal += fi.Size()
+		}
+	}
+
+	return p
+}
+
+// done reports the files at the given indexes as identified.
+func (p *progress) done(indexes ...int) {
+	if p == nil || len(indexes) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, i := range indexes {
+		p.state.FilesDone++
+		p.state.BytesDone += p.sizes[i]
+		p.state.Current = p.paths[i]
+	}
+
+	p.state.Elapsed = time.Since(p.start)
+
+	// Estimate from bytes if possible, as file sizes vary widely
+	switch {
+	case p.state.BytesDone > 0:
+		p.state.ETA = time.Duration(float64(p.state.Elapsed) * float64(p.state.BytesTotal-p.state.BytesDone) / float64(p.state.BytesDone))
+	case p.state.BytesTotal == 0:
+		p.state.ETA = time.Duration(float64(p.state.Elapsed) * float64(p.state.FilesTotal-p.state.FilesDone) / float64(p.state.FilesDone))
+	}
+
+	p.report(p.state)
+}
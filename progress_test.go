@@ -0,0 +1,66 @@
+package trid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	paths := writeFiles(t, "%PDF-1.4", "%PDF-1.5", "%PDF-1.7")
+	paths = append(paths, "missing.pdf")
+
+	var reports []Progress
+	trid := NewTrid(Options{Cmd: cmd, BatchSize: 2, Progress: func(p Progress) { reports = append(reports, p) }})
+
+	if _, err := trid.ScanBatch(context.Background(), paths, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("Progress called %d times, want 3: %+v", len(reports), reports)
+	}
+
+	// Files are reported a window of BatchSize files at a time
+	first, missing, last := reports[0], reports[1], reports[len(reports)-1]
+	if first.FilesDone != 2 || first.Current != paths[1] || first.BytesDone != 16 {
+		t.Errorf("first report = %+v, want the first window", first)
+	}
+
+	if missing.FilesDone != 3 || missing.Current != "missing.pdf" || missing.BytesDone != 16 {
+		t.Errorf("second report = %+v, want the missing file", missing)
+	}
+
+	if last.FilesDone != 4 || last.FilesTotal != 4 || last.BytesDone != last.BytesTotal || last.BytesTotal == 0 {
+		t.Errorf("last report = %+v, want all files done", last)
+	}
+
+	if last.Current != paths[2] || last.ETA != 0 {
+		t.Errorf("last report = %+v, want current %s and no ETA", last, paths[2])
+	}
+}
+
+func TestProgressFallback(t *testing.T) {
+	paths := writeFiles(t, "%PDF-1.4", "%PDF-1.5", "%PDF-1.7")
+
+	// TrID is unavailable, so every file is identified by the fallback
+	unavailable := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{}, ErrUnavailable
+	})
+
+	var reports []Progress
+	trid := NewTrid(Options{Cmd: "trid", Runner: unavailable, BatchSize: 2, Fallback: true, Progress: func(p Progress) { reports = append(reports, p) }})
+
+	results, err := trid.ScanBatch(context.Background(), paths, 1)
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("ScanBatch() = %+v, %v", results, err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("Progress called %d times, want 2: %+v", len(reports), reports)
+	}
+
+	if last := reports[len(reports)-1]; last.FilesDone != 3 || last.FilesTotal != 3 || last.BytesDone != last.BytesTotal {
+		t.Errorf("last report = %+v, want all files done", last)
+	}
+}
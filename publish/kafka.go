@@ -0,0 +1,572 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kafka API keys and versions used by the publisher.
+const (
+	kafkaProduce      = 0
+	kafkaMetadata     = 3
+	kafkaAPIVersions  = 18
+	kafkaProduceV     = 3
+	kafkaMetadataV    = 0
+	kafkaAPIVersionsV = 0
+	kafkaMaxFrameSize = 64 << 20
+)
+
+// kafkaAPIs names the APIs the publisher uses, with their version.
+var kafkaAPIs = []struct {
+	name   string
+	key, v int16
+}{
+	{"Produce", kafkaProduce, kafkaProduceV},
+	{"Metadata", kafkaMetadata, kafkaMetadataV},
+}
+
+// ErrKafkaVersion is returned when a broker does not support the versions of
+// the Kafka APIs the publisher uses.
+var ErrKafkaVersion = errors.New("unsupported Kafka API version")
+
+// Kafka error codes after which metadata is refreshed and the message is sent
+// again.
+var kafkaRetriable = map[int16]bool{
+	3: true, // UNKNOWN_TOPIC_OR_PARTITION
+	5: true, // LEADER_NOT_AVAILABLE
+	6: true, // NOT_LEADER_OR_FOLLOWER
+}
+
+// KafkaError is an error code returned by a Kafka broker.
+type KafkaError struct {
+	Code int16
+}
+
+// Error returns the error code and, if well-known, its name.
+func (e *KafkaError) Error() string {
+	names := map[int16]string{
+		2:  "CORRUPT_MESSAGE",
+		3:  "UNKNOWN_TOPIC_OR_PARTITION",
+		5:  "LEADER_NOT_AVAILABLE",
+		6:  "NOT_LEADER_OR_FOLLOWER",
+		7:  "REQUEST_TIMED_OUT",
+		10: "MESSAGE_TOO_LARGE",
+		17: "INVALID_TOPIC_EXCEPTION",
+		19: "NOT_ENOUGH_REPLICAS",
+		29: "TOPIC_AUTHORIZATION_FAILED",
+		35: "UNSUPPORTED_VERSION",
+	}
+
+	if name, ok := names[e.Code]; ok {
+		return fmt.Sprintf("kafka: error %d (%s)", e.Code, name)
+	}
+
+	return fmt.Sprintf("kafka: error %d", e.Code)
+}
+
+// KafkaOptions configures the Kafka publisher.
+type KafkaOptions struct {
+	Brokers      []string      // Addresses ("host:port") of brokers used to discover the cluster.
+	ClientID     string        // Client identifier sent to brokers (default: "trid").
+	RequiredAcks int16         // Acknowledgements required: 1 for the leader, -1 for all in-sync replicas (default: -1).
+	Timeout      time.Duration // Time brokers wait for the acknowledgements (default: 10s).
+	TLSConfig    *tls.Config   // TLS configuration; nil connects in plain text.
+	Dialer       *net.Dialer   // Dialer of connections (default: a dialer with a 10s timeout).
+}
+
+// Kafka publishes messages to a Kafka cluster, one record per message. Messages
+// with a key go to the partition chosen by the default partitioner of the Java
+// client (murmur2 of the key), and messages without one are spread
+// round-robin. It is safe for concurrent use, but sends one message at a time:
+// services publishing at high rates should implement Publisher with a batching
+// client.
+//
+// The publisher speaks Produce v3 and Metadata v0 only, which brokers from
+// version 0.11 up to 3.x support; Kafka 4.0 dropped Metadata v0. The versions
+// a broker supports are checked when connecting to it, and publishing fails
+// with ErrKafkaVersion if they do not include these. SASL authentication is
+// not supported: clusters requiring it need a Publisher built on an official
+// client.
+type Kafka struct {
+	opts       KafkaOptions
+	mu         sync.Mutex
+	correlator int32
+	next       uint32
+	brokers    map[int32]string      // Addresses of brokers by node identifier.
+	leaders    map[string][]int32    // Leader of each partition by topic.
+	conns      map[string]*kafkaConn // Connections by broker address.
+}
+
+// kafkaConn is a connection to a broker.
+type kafkaConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewKafka returns a publisher to the cluster of opts.Brokers. Brokers are
+// connected to when the first message is published.
+func NewKafka(opts KafkaOptions) (*Kafka, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers")
+	}
+
+	if opts.ClientID == "" {
+		opts.ClientID = "trid"
+	}
+
+	if opts.RequiredAcks == 0 {
+		opts.RequiredAcks = -1
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	if opts.Dialer == nil {
+		opts.Dialer = &net.Dialer{Timeout: 10 * time.Second}
+	}
+
+	return &Kafka{
+		opts:    opts,
+		brokers: make(map[int32]string),
+		leaders: make(map[string][]int32),
+		conns:   make(map[string]*kafkaConn),
+	}, nil
+}
+
+// Publish sends m as a record to its topic and waits for the acknowledgements
+// of KafkaOptions.RequiredAcks. Metadata is refreshed once if the partition
+// has moved.
+func (k *Kafka) Publish(ctx context.Context, m Message) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.conns == nil {
+		return ErrClosed
+	}
+
+	err := k.produce(ctx, m)
+
+	var kafkaErr *KafkaError
+	if errors.As(err, &kafkaErr) && kafkaRetriable[kafkaErr.Code] {
+		delete(k.leaders, m.Topic)
+		err = k.produce(ctx, m)
+	}
+
+	return err
+}
+
+// produce sends m to the leader of its partition.
+func (k *Kafka) produce(ctx context.Context, m Message) error {
+	leaders, err := k.metadata(ctx, m.Topic)
+	if err != nil {
+		return err
+	}
+
+	var partition int32
+	if m.Key != nil {
+		partition = (murmur2(m.Key) & 0x7fffffff) % int32(len(leaders))
+	} else {
+		partition = int32(k.next % uint32(len(leaders)))
+		k.next++
+	}
+
+	addr, ok := k.brokers[leaders[partition]]
+	if !ok {
+		return &KafkaError{Code: 5}
+	}
+
+	var body kafkaBuffer
+	body.int16(-1) // No transactional identifier
+	body.int16(k.opts.RequiredAcks)
+	body.int32(int32(k.opts.Timeout / time.Millisecond))
+	body.int32(1)
+	body.string(m.Topic)
+	body.int32(1)
+	body.int32(partition)
+	batch := recordBatch(m, time.Now())
+	body.int32(int32(len(batch)))
+	body = append(body, batch...)
+
+	resp, err := k.roundTrip(ctx, addr, kafkaProduce, kafkaProduceV, body)
+	if err != nil {
+		return err
+	}
+
+	r := kafkaReader{b: resp}
+	for topics := r.int32(); topics > 0; topics-- {
+		r.string()
+		for partitions := r.int32(); partitions > 0; partitions-- {
+			r.int32()
+			if code := r.int16(); code != 0 && r.err == nil {
+				return &KafkaError{Code: code}
+			}
+			r.int64()
+			r.int64()
+		}
+	}
+
+	return r.err
+}
+
+// metadata returns the leader of each partition of topic, requesting it from
+// the brokers if it is not known.
+func (k *Kafka) metadata(ctx context.Context, topic string) ([]int32, error) {
+	if leaders, ok := k.leaders[topic]; ok {
+		return leaders, nil
+	}
+
+	var body kafkaBuffer
+	body.int32(1)
+	body.string(topic)
+
+	var (
+		resp []byte
+		err  error
+	)
+	for _, addr := range k.opts.Brokers {
+		if resp, err = k.roundTrip(ctx, addr, kafkaMetadata, kafkaMetadataV, body); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := kafkaReader{b: resp}
+	for brokers := r.int32(); brokers > 0 && r.err == nil; brokers-- {
+		id, host, port := r.int32(), r.string(), r.int32()
+		k.brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+
+	var leaders []int32
+	for topics := r.int32(); topics > 0 && r.err == nil; topics-- {
+		code, name := r.int16(), r.string()
+		for partitions := r.int32(); partitions > 0 && r.err == nil; partitions-- {
+			r.int16()
+			id, leader := r.int32(), r.int32()
+			r.skipInt32s()
+			r.skipInt32s()
+
+			if name == topic && id >= 0 {
+				for int(id) >= len(leaders) {
+					leaders = append(leaders, -1)
+				}
+				leaders[id] = leader
+			}
+		}
+
+		if name == topic && code != 0 && r.err == nil {
+			return nil, &KafkaError{Code: code}
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if len(leaders) == 0 {
+		return nil, &KafkaError{Code: 3}
+	}
+
+	k.leaders[topic] = leaders
+
+	return leaders, nil
+}
+
+// roundTrip sends a request to the broker at addr and returns the body of its
+// response. The connection is dropped on errors, to be opened again by the
+// next request.
+func (k *Kafka) roundTrip(ctx context.Context, addr string, apiKey, version int16, body []byte) ([]byte, error) {
+	conn, err := k.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := conn.roundTrip(ctx, k.nextCorrelation(), k.opts.ClientID, apiKey, version, body)
+	if err != nil {
+		conn.Close()
+		delete(k.conns, addr)
+		return nil, fmt.Errorf("kafka: %s: %w", addr, err)
+	}
+
+	return resp, nil
+}
+
+// conn returns the connection to the broker at addr, opening it if needed.
+func (k *Kafka) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	if c, ok := k.conns[addr]; ok {
+		return c, nil
+	}
+
+	c, err := k.opts.Dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+
+	if k.opts.TLSConfig != nil {
+		config := k.opts.TLSConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		tlsConn := tls.Client(c, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("kafka: %w", err)
+		}
+		c = tlsConn
+	}
+
+	conn := &kafkaConn{Conn: c, r: bufio.NewReader(c)}
+	if err := k.checkVersions(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("kafka: %s: %w", addr, err)
+	}
+	k.conns[addr] = conn
+
+	return conn, nil
+}
+
+// checkVersions checks that the broker at the end of conn supports the
+// versions of the APIs the publisher uses.
+func (k *Kafka) checkVersions(ctx context.Context, conn *kafkaConn) error {
+	resp, err := conn.roundTrip(ctx, k.nextCorrelation(), k.opts.ClientID, kafkaAPIVersions, kafkaAPIVersionsV, nil)
+	if err != nil {
+		return err
+	}
+
+	r := kafkaReader{b: resp}
+	if code := r.int16(); code != 0 && r.err == nil {
+		return &KafkaError{Code: code}
+	}
+
+	supported := make(map[int16][2]int16)
+	for apis := r.int32(); apis > 0 && r.err == nil; apis-- {
+		key, minV, maxV := r.int16(), r.int16(), r.int16()
+		supported[key] = [2]int16{minV, maxV}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	for _, api := range kafkaAPIs {
+		versions, ok := supported[api.key]
+		if !ok {
+			return fmt.Errorf("%w: %s is not supported", ErrKafkaVersion, api.name)
+		}
+
+		if api.v < versions[0] || api.v > versions[1] {
+			return fmt.Errorf("%w: %s v%d is required, the broker supports v%d to v%d", ErrKafkaVersion, api.name, api.v, versions[0], versions[1])
+		}
+	}
+
+	return nil
+}
+
+// nextCorrelation returns the correlation identifier of the next request.
+func (k *Kafka) nextCorrelation() int32 {
+	k.correlator++
+	return k.correlator
+}
+
+// Close closes the connections to the brokers.
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var err error
+	for _, c := range k.conns {
+		err = errors.Join(err, c.Close())
+	}
+	k.conns = nil
+
+	return err
+}
+
+// roundTrip sends a request with the given header fields and body, and returns
+// the body of the response.
+func (c *kafkaConn) roundTrip(ctx context.Context, correlation int32, clientID string, apiKey, version int16, body []byte) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	c.SetDeadline(deadline)
+
+	// Interrupt the exchange if ctx is cancelled before its deadline
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+	defer stop()
+
+	var req kafkaBuffer
+	req.int32(0) // Size, set below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(correlation)
+	req.string(clientID)
+	req = append(req, body...)
+	binary.BigEndian.PutUint32(req, uint32(len(req)-4))
+
+	if _, err := c.Write(req); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > kafkaMaxFrameSize {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+
+	if got := int32(binary.BigEndian.Uint32(header[4:])); got != correlation {
+		return nil, fmt.Errorf("response to request %d, want %d", got, correlation)
+	}
+
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// recordBatch returns a record batch (message format v2) holding m as its
+// single record.
+func recordBatch(m Message, now time.Time) []byte {
+	var record kafkaBuffer
+	record = append(record, 0)              // Attributes
+	record = binary.AppendVarint(record, 0) // Timestamp delta
+	record = binary.AppendVarint(record, 0) // Offset delta
+	if m.Key == nil {
+		record = binary.AppendVarint(record, -1)
+	} else {
+		record = binary.AppendVarint(record, int64(len(m.Key)))
+		record = append(record, m.Key...)
+	}
+	record = binary.AppendVarint(record, int64(len(m.Value)))
+	record = append(record, m.Value...)
+	record = binary.AppendVarint(record, 0) // Headers
+
+	// Fields covered by the checksum
+	var tail kafkaBuffer
+	tail.int16(0) // Attributes: no compression, create time
+	tail.int32(0) // Last offset delta
+	tail.int64(now.UnixMilli())
+	tail.int64(now.UnixMilli())
+	tail.int64(-1) // Producer identifier
+	tail.int16(-1) // Producer epoch
+	tail.int32(-1) // Base sequence
+	tail.int32(1)  // Records
+	tail = binary.AppendVarint(tail, int64(len(record)))
+	tail = append(tail, record...)
+
+	var batch kafkaBuffer
+	batch.int64(0)                            // Base offset
+	batch.int32(int32(4 + 1 + 4 + len(tail))) // Length of the rest of the batch
+	batch.int32(-1)                           // Partition leader epoch
+	batch = append(batch, 2)                  // Magic
+	batch = binary.BigEndian.AppendUint32(batch, crc32.Checksum(tail, crc32.MakeTable(crc32.Castagnoli)))
+
+	return append(batch, tail...)
+}
+
+// murmur2 returns the hash of key used by the default partitioner of the Java
+// client.
+func murmur2(key []byte) int32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+
+	h := uint32(seed) ^ uint32(len(key))
+	n := len(key) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(key[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	switch len(key) & 3 {
+	case 3:
+		h ^= uint32(key[n+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(key[n+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(key[n])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return int32(h)
+}
+
+// kafkaBuffer encodes the primitive types of the Kafka protocol.
+type kafkaBuffer []byte
+
+func (b *kafkaBuffer) int16(v int16) { *b = binary.BigEndian.AppendUint16(*b, uint16(v)) }
+func (b *kafkaBuffer) int32(v int32) { *b = binary.BigEndian.AppendUint32(*b, uint32(v)) }
+func (b *kafkaBuffer) int64(v int64) { *b = binary.BigEndian.AppendUint64(*b, uint64(v)) }
+
+func (b *kafkaBuffer) string(s string) {
+	b.int16(int16(len(s)))
+	*b = append(*b, s...)
+}
+
+// kafkaReader decodes the primitive types of the Kafka protocol. Reading past
+// the end records an error and returns zero values.
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+// next returns the next n bytes.
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.b) {
+		if r.err == nil {
+			r.err = errors.New("kafka: truncated response")
+		}
+		return make([]byte, max(n, 0))
+	}
+
+	v := r.b[:n]
+	r.b = r.b[n:]
+
+	return v
+}
+
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+
+	return string(r.next(int(n)))
+}
+
+// skipInt32s skips an array of 32-bit integers.
+func (r *kafkaReader) skipInt32s() {
+	r.next(4 * int(r.int32()))
+}
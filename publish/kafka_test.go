@@ -0,0 +1,222 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// kafkaRecord is a record received by kafkaBroker.
+type kafkaRecord struct {
+	partition  int32
+	key, value string
+}
+
+// kafkaBroker serves metadata for a topic with two partitions led by itself,
+// fails the first produce request with NOT_LEADER_OR_FOLLOWER, and returns the
+// records of the following ones. It supports Metadata from version
+// minMetadata, as Kafka 4.0 does from version 4.
+func kafkaBroker(t *testing.T, minMetadata int16) (string, <-chan kafkaRecord) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+
+	records := make(chan kafkaRecord, 10)
+	go func() {
+		failed := false
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+
+				for {
+					var size int32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+
+					frame := make([]byte, size)
+					if _, err := io.ReadFull(r, frame); err != nil {
+						return
+					}
+
+					req := kafkaReader{b: frame}
+					apiKey, _, correlation := req.int16(), req.int16(), req.int32()
+					req.string()
+
+					var resp kafkaBuffer
+					resp.int32(correlation)
+
+					switch apiKey {
+					case kafkaAPIVersions:
+						resp.int16(0)
+						resp.int32(3)
+						for _, api := range [][3]int16{{kafkaProduce, 3, 11}, {kafkaMetadata, minMetadata, 12}, {kafkaAPIVersions, 0, 4}} {
+							resp.int16(api[0])
+							resp.int16(api[1])
+							resp.int16(api[2])
+						}
+					case kafkaMetadata:
+						req.int32()
+						topic := req.string()
+
+						resp.int32(1)
+						resp.int32(1)
+						resp.string(host)
+						resp.int32(int32(portNum))
+						resp.int32(1)
+						resp.int16(0)
+						resp.string(topic)
+						resp.int32(2)
+						for p := int32(0); p < 2; p++ {
+							resp.int16(0)
+							resp.int32(p)
+							resp.int32(1)
+							resp.int32(1)
+							resp.int32(1)
+							resp.int32(1)
+							resp.int32(1)
+						}
+					case kafkaProduce:
+						req.int16()
+						req.int16()
+						req.int32()
+						req.int32()
+						topic := req.string()
+						req.int32()
+						partition := req.int32()
+						batch := req.next(int(req.int32()))
+
+						code := int16(0)
+						if !failed {
+							failed, code = true, 6
+						} else if rec, ok := decodeBatch(batch); !ok {
+							code = 2
+						} else {
+							rec.partition = partition
+							records <- rec
+						}
+
+						resp.int32(1)
+						resp.string(topic)
+						resp.int32(1)
+						resp.int32(partition)
+						resp.int16(code)
+						resp.int64(0)
+						resp.int64(-1)
+						resp.int32(0)
+					}
+
+					binary.Write(conn, binary.BigEndian, int32(len(resp)))
+					conn.Write(resp)
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), records
+}
+
+// decodeBatch returns the single record of a record batch, and false if the
+// batch is invalid.
+func decodeBatch(batch []byte) (kafkaRecord, bool) {
+	if len(batch) < 61 || batch[16] != 2 {
+		return kafkaRecord{}, false
+	}
+
+	tail := batch[21:]
+	if crc32.Checksum(tail, crc32.MakeTable(crc32.Castagnoli)) != binary.BigEndian.Uint32(batch[17:]) {
+		return kafkaRecord{}, false
+	}
+
+	b := tail[40:]
+	varint := func() int64 {
+		v, n := binary.Varint(b)
+		b = b[n:]
+		return v
+	}
+
+	varint()      // Length
+	b = b[1:]     // Attributes
+	varint()      // Timestamp delta
+	varint()      // Offset delta
+	n := varint() // Key
+	key := string(b[:n])
+	b = b[n:]
+	n = varint() // Value
+
+	return kafkaRecord{key: key, value: string(b[:n])}, true
+}
+
+func TestKafka(t *testing.T) {
+	addr, records := kafkaBroker(t, 0)
+	ctx := context.Background()
+
+	k, err := NewKafka(KafkaOptions{Brokers: []string{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	if err := k.Publish(ctx, Message{Topic: "scans", Key: []byte("a.pdf"), Value: []byte(`{"path":"a.pdf"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := <-records
+	expected := (murmur2([]byte("a.pdf")) & 0x7fffffff) % 2
+	if rec.key != "a.pdf" || rec.value != `{"path":"a.pdf"}` || rec.partition != expected {
+		t.Errorf("record = %+v, want a.pdf in partition %d", rec, expected)
+	}
+}
+
+func TestKafkaUnsupportedVersion(t *testing.T) {
+	addr, _ := kafkaBroker(t, 4)
+
+	k, err := NewKafka(KafkaOptions{Brokers: []string{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	err = k.Publish(context.Background(), Message{Topic: "scans", Value: []byte("{}")})
+	if !errors.Is(err, ErrKafkaVersion) || !strings.Contains(err.Error(), "Metadata v0") {
+		t.Errorf("Publish() error = %v, want %v for Metadata v0", err, ErrKafkaVersion)
+	}
+}
+
+func TestMurmur2(t *testing.T) {
+	// Values of the Java client
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+
+	for key, expected := range tests {
+		if got := murmur2([]byte(key)); got != expected {
+			t.Errorf("murmur2(%q) = %d, want %d", key, got, expected)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when publishing with a closed publisher.
+var ErrClosed = errors.New("publisher is closed")
+
+// NATSOptions configures the NATS publisher.
+type NATSOptions struct {
+	Name      string      // Name of the connection, shown by the server (default: "trid").
+	User      string      // User name, if the server requires one.
+	Password  string      // Password of User.
+	Token     string      // Authentication token, if the server requires one instead of a user.
+	TLSConfig *tls.Config // TLS configuration; TLS is also used when the server requires it.
+	Dialer    *net.Dialer // Dialer of the connection (default: a dialer with a 10s timeout).
+}
+
+// NATS publishes messages to a NATS server. Keys are ignored, as NATS
+// subjects are not partitioned. It is safe for concurrent use.
+type NATS struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	w      *bufio.Writer
+	pongs  chan struct{}
+	err    error // First error reported by the server, or ErrClosed.
+	closed chan struct{}
+}
+
+// natsInfo is the part of the INFO message of the server that is used.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect is the CONNECT message sent to the server.
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// DialNATS connects to the NATS server at addr ("host:port"), authenticates
+// and returns a publisher once the server has accepted the connection.
+func DialNATS(ctx context.Context, addr string, opts NATSOptions) (*NATS, error) {
+	if opts.Name == "" {
+		opts.Name = "trid"
+	}
+
+	dialer := opts.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 10 * time.Second}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := handshake(ctx, conn, addr, opts)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+
+	return n, nil
+}
+
+// handshake reads the INFO message of the server on conn, upgrades it to TLS
+// if needed, sends CONNECT and waits for the server to answer a PING.
+func handshake(ctx context.Context, conn net.Conn, addr string, opts NATSOptions) (*NATS, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var info natsInfo
+	op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+	if op != "INFO" {
+		return nil, fmt.Errorf("unexpected %q from server", op)
+	}
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		return nil, err
+	}
+
+	if info.TLSRequired || opts.TLSConfig != nil {
+		config := opts.TLSConfig.Clone()
+		if config == nil {
+			config = &tls.Config{}
+		}
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect, err := json.Marshal(natsConnect{Name: opts.Name, Lang: "go", Version: "1", User: opts.User, Pass: opts.Password, Token: opts.Token})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return nil, err
+	}
+
+	// The server answers PING once CONNECT is processed, or reports an error
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		switch line = strings.TrimRight(line, "\r\n"); {
+		case line == "PONG":
+			n := &NATS{conn: conn, w: bufio.NewWriter(conn), pongs: make(chan struct{}, 1), closed: make(chan struct{})}
+			go n.read(r)
+			return n, nil
+		case strings.HasPrefix(line, "-ERR"):
+			return nil, natsError(line)
+		}
+	}
+}
+
+// read handles the messages of the server until the connection is closed.
+func (n *NATS) read(r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			n.fail(err)
+			return
+		}
+
+		switch line = strings.TrimRight(line, "\r\n"); {
+		case line == "PING":
+			n.mu.Lock()
+			n.w.WriteString("PONG\r\n")
+			n.w.Flush()
+			n.mu.Unlock()
+		case line == "PONG":
+			select {
+			case n.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			n.fail(natsError(line))
+		}
+	}
+}
+
+// fail records err as the error of the connection, if none is recorded yet.
+func (n *NATS) fail(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err == nil {
+		n.err = err
+		close(n.closed)
+	}
+}
+
+// Publish publishes m.Value to subject m.Topic. NATS does not acknowledge
+// messages: Publish returns once the message is written to the connection,
+// and Flush waits for the server to have processed it.
+func (n *NATS) Publish(ctx context.Context, m Message) error {
+	if m.Topic == "" || strings.ContainsAny(m.Topic, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", m.Topic)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return n.err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		n.conn.SetWriteDeadline(deadline)
+		defer n.conn.SetWriteDeadline(time.Time{})
+	}
+
+	fmt.Fprintf(n.w, "PUB %s %d\r\n", m.Topic, len(m.Value))
+	n.w.Write(m.Value)
+	n.w.WriteString("\r\n")
+
+	return n.w.Flush()
+}
+
+// Flush waits for the server to have processed the messages published so
+// far.
+func (n *NATS) Flush(ctx context.Context) error {
+	n.mu.Lock()
+	if n.err != nil {
+		n.mu.Unlock()
+		return n.err
+	}
+
+	// Drain a PONG left over by a flush that gave up
+	select {
+	case <-n.pongs:
+	default:
+	}
+
+	n.w.WriteString("PING\r\n")
+	err := n.w.Flush()
+	n.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-n.pongs:
+		return nil
+	case <-n.closed:
+		return n.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the connection to the server.
+func (n *NATS) Close() error {
+	n.fail(ErrClosed)
+
+	return n.conn.Close()
+}
+
+// natsError returns the error reported by an -ERR message.
+func natsError(line string) error {
+	return fmt.Errorf("nats: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+}
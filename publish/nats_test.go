@@ -0,0 +1,109 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// natsServer accepts a single connection, answering the handshake and PINGs,
+// and returns the payloads published by subject.
+func natsServer(t *testing.T, reject bool) (string, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	published := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"auth_required\":true}\r\n")
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			op, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+			switch op {
+			case "CONNECT":
+				if reject || !strings.Contains(args, `"auth_token":"secret"`) {
+					fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+					return
+				}
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "PUB":
+				var (
+					subject string
+					size    int
+				)
+				fmt.Sscanf(args, "%s %d", &subject, &size)
+
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				published <- subject + " " + string(payload[:size])
+			}
+		}
+	}()
+
+	return l.Addr().String(), published
+}
+
+func TestNATS(t *testing.T) {
+	addr, published := natsServer(t, false)
+	ctx := context.Background()
+
+	n, err := DialNATS(ctx, addr, NATSOptions{Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	if err := n.Publish(ctx, Message{Topic: "trid.scans", Value: []byte(`{"path":"a.pdf"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-published; got != `trid.scans {"path":"a.pdf"}` {
+		t.Errorf("published %q", got)
+	}
+
+	if err := n.Publish(ctx, Message{Topic: "bad subject"}); err == nil {
+		t.Error("Publish() with an invalid subject succeeded")
+	}
+
+	n.Close()
+	if err := n.Publish(ctx, Message{Topic: "trid.scans"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after Close() = %v, want ErrClosed", err)
+	}
+}
+
+func TestNATSAuthorization(t *testing.T) {
+	addr, _ := natsServer(t, true)
+
+	_, err := DialNATS(context.Background(), addr, NATSOptions{Token: "secret"})
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("DialNATS() = %v, want an authorization error", err)
+	}
+}
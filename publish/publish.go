@@ -0,0 +1,90 @@
+// Package publish emits a message for each scan result to a message bus, so
+// that identification workers can feed downstream processing without glue
+// code in every service.
+//
+// Publishers for NATS and Kafka are built in, speaking just enough of each
+// protocol to publish messages, with no dependencies. Other buses, or the
+// official clients of NATS and Kafka when their features are needed, are
+// supported by implementing Publisher.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// Message is a message published to a bus.
+type Message struct {
+	Topic string // Subject (NATS) or topic (Kafka) the message is published to.
+	Key   []byte // Key used to partition messages, if supported by the bus; nil if none.
+	Value []byte // Payload.
+}
+
+// Publisher publishes messages to a bus.
+type Publisher interface {
+	// Publish publishes m, returning once the bus has accepted it.
+	Publish(ctx context.Context, m Message) error
+
+	// Close releases the connections of the publisher.
+	Close() error
+}
+
+// Event is the payload of the message published for a scan result, encoded
+// as JSON.
+type Event struct {
+	Path      string          `json:"path"`                 // Path of the file scanned.
+	FileTypes []trid.FileType `json:"file_types,omitempty"` // Identified file types, most likely first.
+	Error     string          `json:"error,omitempty"`      // Why the scan failed, if it did.
+	Time      time.Time       `json:"time"`                 // When the scan completed.
+}
+
+// NewEvent returns the event of a scan of path, completed now.
+func NewEvent(path string, results []trid.FileType, err error) Event {
+	e := Event{Path: path, FileTypes: results, Time: time.Now().UTC()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+// Message returns e as a message for topic, keyed by the path of the file so
+// that the results of a file are kept in order on partitioned buses.
+func (e Event) Message(topic string) (Message, error) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Topic: topic, Key: []byte(e.Path), Value: value}, nil
+}
+
+// Publish publishes the event of r to topic.
+func Publish(ctx context.Context, p Publisher, topic string, r trid.BatchResult) error {
+	m, err := NewEvent(r.Path, r.FileTypes, r.Err).Message(topic)
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, m)
+}
+
+// Hook returns a function publishing the event of each scan to topic, for
+// Hooks.OnScanComplete. Since the hook has no caller to return errors to,
+// they are passed to onError, if not nil. Since scans wait for their hooks,
+// publishing adds a round trip to the bus to each scan.
+func Hook(p Publisher, topic string, onError func(path string, err error)) func(path string, results []trid.FileType, err error) {
+	return func(path string, results []trid.FileType, scanErr error) {
+		m, err := NewEvent(path, results, scanErr).Message(topic)
+		if err == nil {
+			err = p.Publish(context.Background(), m)
+		}
+
+		if err != nil && onError != nil {
+			onError(path, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+// memPublisher records the messages published.
+type memPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+func (p *memPublisher) Publish(ctx context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+func (p *memPublisher) Close() error {
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	p := &memPublisher{}
+	hook := Hook(p, "scans", nil)
+
+	hook("a.pdf", []trid.FileType{{Extension: ".pdf", Name: "PDF", Probability: 100}}, nil)
+	hook("b.bin", nil, trid.ErrUnknownFileType)
+
+	if len(p.messages) != 2 {
+		t.Fatalf("%d messages published, want 2", len(p.messages))
+	}
+
+	m := p.messages[0]
+	if m.Topic != "scans" || string(m.Key) != "a.pdf" {
+		t.Errorf("message = %q, %q, want scans, a.pdf", m.Topic, m.Key)
+	}
+
+	var e Event
+	if err := json.Unmarshal(m.Value, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Path != "a.pdf" || len(e.FileTypes) != 1 || e.FileTypes[0].Extension != ".pdf" || e.Time.IsZero() {
+		t.Errorf("event = %+v", e)
+	}
+
+	if err := json.Unmarshal(p.messages[1].Value, &e); err != nil || e.Error != trid.ErrUnknownFileType.Error() {
+		t.Errorf("event = %+v, %v, want error %q", e, err, trid.ErrUnknownFileType)
+	}
+}
+
+func TestHookError(t *testing.T) {
+	p := &memPublisher{err: errors.New("bus down")}
+
+	var failed []string
+	Hook(p, "scans", func(path string, err error) { failed = append(failed, path) })("a.pdf", nil, nil)
+
+	if len(failed) != 1 || failed[0] != "a.pdf" {
+		t.Errorf("onError called with %v, want [a.pdf]", failed)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	p := &memPublisher{}
+	if err := Publish(context.Background(), p, "scans", trid.BatchResult{Path: "a.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.messages) != 1 || string(p.messages[0].Key) != "a.pdf" {
+		t.Errorf("messages = %+v", p.messages)
+	}
+}
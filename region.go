@@ -0,0 +1,102 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidRange is returned by ScanAt for regions outside the file.
+var ErrInvalidRange = errors.New("invalid file region")
+
+// ScanAt identifies the region of length bytes starting at offset of the file
+// at filePath, for firmware images and forensic blobs holding formats at
+// known offsets. A length of 0, or one going past the end of the file,
+// selects the region up to the end of the file.
+//
+// With the native engine, the region is read and identified in memory.
+// Otherwise it is extracted to a temporary file in Options.LocalCopy.Dir and
+// identified as by ScanContext, so that caching, Options.Fallback and hooks
+// apply; the temporary file has no extension. Options.MaxFileSize applies to
+// the region. Block devices are supported as well as regular files.
+func (t *Trid) ScanAt(ctx context.Context, filePath string, offset, length int64, numberOfMatches int) ([]FileType, error) {
+	if filePath == "" {
+		return nil, ErrNoFileSpecified
+	}
+
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+
+		return nil, err
+	}
+
+	size, err := fileSize(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < 0 || length < 0 || offset >= size {
+		return nil, fmt.Errorf("%w: offset %d, length %d of %d bytes", ErrInvalidRange, offset, length, size)
+	}
+
+	if length == 0 || length > size-offset {
+		length = size - offset
+	}
+
+	if t.options.Engine == EngineNative {
+		return t.scanNativeAt(ctx, filePath, offset, length, numberOfMatches)
+	}
+
+	tmp, remove, err := copyRange(filePath, t.options.LocalCopy.Dir, "trid-region-*", offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer remove()
+
+	return t.ScanContext(ctx, tmp, numberOfMatches)
+}
+
+// scanNativeAt identifies a region of the file at filePath with the native
+// engine.
+func (t *Trid) scanNativeAt(ctx context.Context, filePath string, offset, length int64, numberOfMatches int) ([]FileType, error) {
+	if t.optsErr != nil {
+		return nil, t.optsErr
+	}
+
+	if limit := t.options.MaxFileSize; limit > 0 && length > limit {
+		if t.options.Oversize != OversizeTruncate {
+			return nil, fmt.Errorf("%w: region of %s is %d bytes, limit is %d", ErrFileTooLarge, filePath, length, limit)
+		}
+
+		length = limit
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, length)
+	n, err := f.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	r, err := t.identifyNative(ctx, filePath, data[:n], numberOfMatches)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.refine(ctx, r.FileTypes)
+}
@@ -0,0 +1,55 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanAt(t *testing.T) {
+	pdf := readFile(t, "testdata/sample.pdf")
+	blob := filepath.Join(t.TempDir(), "firmware.bin")
+	if err := os.WriteFile(blob, append(bytes.Repeat([]byte{0xff}, 100), pdf...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+	ctx := context.Background()
+
+	if fileTypes, err := tr.ScanAt(ctx, blob, 100, 0, 1); err != nil || fileTypes[0].Extension != ".pdf" {
+		t.Errorf("ScanAt() = %+v, %v, want the PDF", fileTypes, err)
+	}
+
+	if fileTypes, err := tr.ScanAt(ctx, blob, 100, int64(len(pdf))+100, 1); err != nil || fileTypes[0].Extension != ".pdf" {
+		t.Errorf("ScanAt() past the end = %+v, %v, want the PDF", fileTypes, err)
+	}
+
+	if _, err := tr.ScanAt(ctx, blob, 0, 100, 1); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("ScanAt() of the padding error = %v, want %v", err, ErrUnknownFileType)
+	}
+
+	for _, r := range [][2]int64{{-1, 0}, {0, -1}, {int64(len(pdf)) + 100, 0}} {
+		if _, err := tr.ScanAt(ctx, blob, r[0], r[1], 1); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("ScanAt(%d, %d) error = %v, want %v", r[0], r[1], err, ErrInvalidRange)
+		}
+	}
+
+	if _, err := tr.ScanAt(ctx, "missing.bin", 0, 0, 1); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("ScanAt() error = %v, want %v", err, ErrFileNotFound)
+	}
+
+	// TrID is given a temporary file holding the region
+	var region []byte
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		region, _ = os.ReadFile(c.Args[len(c.Args)-1])
+		return CommandResult{Stdout: pdfOutput}, nil
+	})
+
+	tr = NewTrid(Options{Cmd: "trid", Runner: runner})
+	if fileTypes, err := tr.ScanAt(ctx, blob, 100, 16, 1); err != nil || len(fileTypes) != 1 || !bytes.Equal(region, pdf[:16]) {
+		t.Errorf("ScanAt() = %+v, %v with region %q, want %q", fileTypes, err, region, pdf[:16])
+	}
+}
@@ -0,0 +1,182 @@
+// Package report renders the results of a scan, such as an archive audit, as a
+// self-contained HTML or Markdown document for readers who do not use the
+// command line: a summary of the file types found, followed by the matches of
+// every file.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// Report is the results of a scan with their summary.
+type Report struct {
+	Title   string             // Title of the document.
+	Time    time.Time          // When the scan was made.
+	Summary *trid.Summary      // Summary of Results.
+	Results []trid.BatchResult // Results of every file, in the order listed.
+}
+
+// TypeRow is a file type of the summary of a report.
+type TypeRow struct {
+	Name string
+	trid.TypeCount
+}
+
+// New returns a report of results titled title, made now.
+func New(title string, results []trid.BatchResult) *Report {
+	return &Report{
+		Title:   title,
+		Time:    time.Now(),
+		Summary: trid.Summarize(results),
+		Results: results,
+	}
+}
+
+// Types returns the file types of the summary by decreasing number of files,
+// then by name.
+func (r *Report) Types() []TypeRow {
+	rows := make([]TypeRow, 0, len(r.Summary.Types))
+	for name, tc := range r.Summary.Types {
+		rows = append(rows, TypeRow{Name: name, TypeCount: *tc})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Files != rows[j].Files {
+			return rows[i].Files > rows[j].Files
+		}
+
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows
+}
+
+// WriteHTML writes r to w as an HTML document with inline styles, needing no
+// other file to be displayed.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
+
+// WriteMarkdown writes r to w as a Markdown document, with tables as
+// supported by GitHub.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	return markdownReport.Execute(w, r)
+}
+
+// funcs are the functions available to the templates.
+var funcs = map[string]any{
+	"percent": func(rate float64) string {
+		return fmt.Sprintf("%.1f%%", rate*100)
+	},
+	"timestamp": func(t time.Time) string {
+		return t.Format(time.RFC1123)
+	},
+	"cell": markdownCell,
+}
+
+// htmlReport is the template of HTML reports.
+var htmlReport = template.Must(template.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+td.number { text-align: right; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Scanned {{timestamp .Time}}.</p>
+
+<h2>Summary</h2>
+{{with .Summary -}}
+<table>
+<tr><th>Files</th><td class="number">{{.Files}}</td></tr>
+<tr><th>Bytes</th><td class="number">{{.Bytes}}</td></tr>
+<tr><th>Unknown type</th><td class="number">{{.Unknown}} ({{percent .UnknownRate}})</td></tr>
+<tr><th>Failed</th><td class="number">{{.Errors}} ({{percent .ErrorRate}})</td></tr>
+</table>
+{{- end}}
+
+<table>
+<tr><th>File type</th><th>Extension</th><th>Files</th><th>Bytes</th></tr>
+{{- range .Types}}
+<tr><td>{{.Name}}</td><td>{{.Extension}}</td><td class="number">{{.Files}}</td><td class="number">{{.Bytes}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Files</h2>
+<table>
+<tr><th>Path</th><th>Matches</th></tr>
+{{- range .Results}}
+<tr><td>{{.Path}}</td><td>
+{{- if .Err}}<span class="error">{{.Err}}</span>
+{{- else}}{{range $i, $f := .FileTypes}}{{if $i}}<br>{{end}}{{printf "%.1f%%" $f.Probability}} ({{$f.Extension}}) {{$f.Name}}{{end}}
+{{- end}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// markdownReport is the template of Markdown reports.
+var markdownReport = textTemplate.Must(textTemplate.New("markdown").Funcs(funcs).Parse(`# {{cell .Title}}
+
+Scanned {{timestamp .Time}}.
+
+## Summary
+{{with .Summary}}
+| | |
+|---|---:|
+| Files | {{.Files}} |
+| Bytes | {{.Bytes}} |
+| Unknown type | {{.Unknown}} ({{percent .UnknownRate}}) |
+| Failed | {{.Errors}} ({{percent .ErrorRate}}) |
+{{end}}
+| File type | Extension | Files | Bytes |
+|---|---|---:|---:|
+{{- range .Types}}
+| {{cell .Name}} | {{cell .Extension}} | {{.Files}} | {{.Bytes}} |
+{{- end}}
+
+## Files
+
+| Path | Matches |
+|---|---|
+{{- range .Results}}
+| {{cell .Path}} | {{if .Err}}**{{cell .Err.Error}}**{{else}}{{range $i, $f := .FileTypes}}{{if $i}}<br>{{end}}{{printf "%.1f%%" $f.Probability}} ({{cell $f.Extension}}) {{cell $f.Name}}{{end}}{{end}} |
+{{- end}}
+`))
+
+// markdownCell escapes s for a cell of a Markdown table: characters with a
+// meaning in Markdown are escaped with backslashes, and line breaks are
+// replaced with spaces.
+func markdownCell(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '\\', '|', '*', '_', '`', '[', ']', '<', '>', '#':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case '\n', '\r':
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
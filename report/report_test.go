@@ -0,0 +1,68 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// results returns the results of a scan with an identified file, a file of an
+// unknown type and a file named to break the markup.
+func results() []trid.BatchResult {
+	pdf := []trid.FileType{{Name: "Adobe Portable Document Format", Extension: ".pdf", Probability: 100}}
+
+	return []trid.BatchResult{
+		{Path: "a.pdf", FileTypes: pdf},
+		{Path: "<b>|c.pdf", FileTypes: pdf},
+		{Path: "d.bin", Err: trid.ErrUnknownFileType},
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	r := New("Audit <2024>", results())
+
+	var b bytes.Buffer
+	if err := r.WriteHTML(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.String()
+	for _, expected := range []string{
+		"<title>Audit &lt;2024&gt;</title>",
+		`<td>Adobe Portable Document Format</td><td>.pdf</td><td class="number">2</td>`,
+		"<td>&lt;b&gt;|c.pdf</td><td>100.0% (.pdf) Adobe Portable Document Format</td>",
+		`<span class="error">unknown file type</span>`,
+		"1 (33.3%)",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("HTML report does not contain %q:\n%s", expected, out)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	r := New("Audit", results())
+	r.Time = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var b bytes.Buffer
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.String()
+	for _, expected := range []string{
+		"# Audit\n",
+		"Scanned Wed, 01 May 2024 12:00:00 UTC.",
+		"| Adobe Portable Document Format | .pdf | 2 | 0 |",
+		"| \\<b\\>\\|c.pdf | 100.0% (.pdf) Adobe Portable Document Format |",
+		"| d.bin | **unknown file type** |",
+		"| Unknown type | 1 (33.3%) |",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Markdown report does not contain %q:\n%s", expected, out)
+		}
+	}
+}
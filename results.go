@@ -0,0 +1,92 @@
+package trid
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Results is a list of file type matches, as returned by Scan, with helpers
+// for common selections. A []FileType converts to Results directly:
+//
+//	best, ok := trid.Results(fileTypes).Best()
+type Results []FileType
+
+// Best returns the match with the highest probability, and false if there are
+// no matches. Of matches with equal probability, the first is returned.
+func (r Results) Best() (FileType, bool) {
+	if len(r) == 0 {
+		return FileType{}, false
+	}
+
+	best := r[0]
+	for _, f := range r[1:] {
+		if f.Probability > best.Probability {
+			best = f
+		}
+	}
+
+	return best, true
+}
+
+// AboveProbability returns the matches with a probability of at least p
+// percent.
+func (r Results) AboveProbability(p float64) Results {
+	return r.filter(func(f FileType) bool { return f.Probability >= p })
+}
+
+// ByExtension returns the matches for the extension ext, such as ".pdf" or
+// "pdf". Extensions are compared case-insensitively, and matches listing
+// several extensions (e.g., ".jpg/.jpeg") are included if any of them is ext.
+func (r Results) ByExtension(ext string) Results {
+	ext = strings.TrimPrefix(ext, ".")
+
+	return r.filter(func(f FileType) bool {
+		for _, e := range strings.Split(f.Extension, "/") {
+			if strings.EqualFold(strings.TrimPrefix(e, "."), ext) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ByCategory returns the matches of category c.
+func (r Results) ByCategory(c Category) Results {
+	return r.filter(func(f FileType) bool { return f.Category() == c })
+}
+
+// SortByProbability sorts the matches in place by decreasing probability.
+// Matches with equal probability are ordered by definition name, then by name
+// and extension, so the order is reproducible. Scan results are always in this
+// order.
+func (r Results) SortByProbability() {
+	slices.SortStableFunc(r, func(a, b FileType) int {
+		if c := cmp.Compare(b.Probability, a.Probability); c != 0 {
+			return c
+		}
+
+		if c := strings.Compare(a.Definition, b.Definition); c != 0 {
+			return c
+		}
+
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Extension, b.Extension)
+	})
+}
+
+// filter returns the matches for which keep returns true, in a new slice.
+func (r Results) filter(keep func(FileType) bool) Results {
+	var matches Results
+	for _, f := range r {
+		if keep(f) {
+			matches = append(matches, f)
+		}
+	}
+
+	return matches
+}
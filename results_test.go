@@ -0,0 +1,58 @@
+package trid
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testResults = Results{
+	{Extension: ".zip", Probability: 40, MimeType: "application/zip"},
+	{Extension: ".jpg/.jpeg", Probability: 50, MimeType: "image/jpeg"},
+	{Extension: ".png", Probability: 10, MimeType: "image/png"},
+}
+
+func TestResultsBest(t *testing.T) {
+	if best, ok := testResults.Best(); !ok || best.Extension != ".jpg/.jpeg" {
+		t.Errorf("Best() = %+v, %v, want .jpg/.jpeg", best, ok)
+	}
+
+	if _, ok := (Results{}).Best(); ok {
+		t.Error("Best() of no results reported a match")
+	}
+}
+
+func TestResultsFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  Results
+		expected []string
+	}{
+		{"AboveProbability", testResults.AboveProbability(40), []string{".zip", ".jpg/.jpeg"}},
+		{"AboveProbability none", testResults.AboveProbability(90), nil},
+		{"ByExtension", testResults.ByExtension("JPEG"), []string{".jpg/.jpeg"}},
+		{"ByExtension with dot", testResults.ByExtension(".png"), []string{".png"}},
+		{"ByCategory", testResults.ByCategory(CategoryImage), []string{".jpg/.jpeg", ".png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var extensions []string
+			for _, f := range tt.results {
+				extensions = append(extensions, f.Extension)
+			}
+
+			if !reflect.DeepEqual(extensions, tt.expected) {
+				t.Errorf("got %q, want %q", extensions, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResultsSortByProbability(t *testing.T) {
+	r := Results{{Extension: ".a", Probability: 10}, {Extension: ".b", Probability: 50}, {Extension: ".c", Probability: 10}}
+	r.SortByProbability()
+
+	if r[0].Extension != ".b" || r[1].Extension != ".a" || r[2].Extension != ".c" {
+		t.Errorf("SortByProbability() = %+v", r)
+	}
+}
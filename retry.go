@@ -0,0 +1,82 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrEmptyOutput is passed to Retry.RetryIf when TrID exits without producing
+// any output.
+var ErrEmptyOutput = errors.New("TrID produced no output")
+
+// Retry configures retries of TrID processes that fail transiently, such as
+// processes that time out, are killed, exit without output or cannot be
+// spawned for lack of resources.
+type Retry struct {
+	Attempts int                  // Maximum number of attempts, including the first; values below 2 disable retries.
+	Backoff  time.Duration        // Delay before the first retry, doubled for each further retry (default: 100ms).
+	RetryIf  func(err error) bool // Reports whether a failed attempt is retried (default: IsTransient).
+}
+
+// IsTransient reports whether err is a failure that may not recur when TrID
+// is run again: a process that timed out, was killed by a signal, exited
+// without output or could not be spawned for lack of resources. Any other
+// error, such as a missing executable, invalid options, mismatching
+// definitions, a cancelled context or an open circuit breaker, is not
+// transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrUnavailable) {
+		return false
+	}
+
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrEmptyOutput) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Processes terminated by a signal report an exit code of -1
+		return exitErr.ExitCode() == -1
+	}
+
+	return isResourceErr(err)
+}
+
+// execRetry runs TrID like exec, retrying failed attempts according to
+// Options.Retry. The process slot is released while waiting between attempts.
+func (t *Trid) execRetry(ctx context.Context, timeout time.Duration, stdin string, args ...string) (*process, error) {
+	retry := t.options.Retry
+	backoff := retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		p, err := t.exec(ctx, timeout, stdin, args...)
+		if attempt >= retry.Attempts || ctx.Err() != nil {
+			return p, err
+		}
+
+		attemptErr := err
+		if err == nil && strings.TrimSpace(p.stdout) == "" {
+			attemptErr = ErrEmptyOutput
+		}
+
+		if attemptErr == nil || !retry.RetryIf(attemptErr) {
+			return p, err
+		}
+
+		t.log(ctx, slog.LevelInfo, "retrying TrID", "attempt", attempt, "backoff", backoff, "error", attemptErr, "class", errorClass(attemptErr))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return p, err
+		}
+
+		backoff *= 2
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix || windows
+
+package trid
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isResourceErr reports whether err is a failure to spawn a process for lack
+// of resources.
+func isResourceErr(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.ENOMEM)
+}
@@ -0,0 +1,18 @@
+//go:build unix || windows
+
+package trid
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsTransientSpawn(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.ENOMEM} {
+		err := &os.PathError{Op: "fork/exec", Path: "trid", Err: errno}
+		if !IsTransient(err) {
+			t.Errorf("IsTransient(%v) = false, want true", err)
+		}
+	}
+}
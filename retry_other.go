@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package trid
+
+// isResourceErr reports false on platforms whose errors do not tell a lack of
+// resources apart.
+func isResourceErr(err error) bool {
+	return false
+}
@@ -0,0 +1,162 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFlakyStub creates a stub TrID executable that runs the shell command
+// failure on its first failures invocations and prints pdfOutput afterwards.
+// It returns the command and a function reporting the number of invocations.
+func newFlakyStub(t *testing.T, failures int, failure string) (string, func() int) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	callsFile := filepath.Join(dir, "calls.txt")
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\n" +
+		"echo x >> '" + callsFile + "'\n" +
+		"if [ $(grep -c x '" + callsFile + "') -le " + strconv.Itoa(failures) + " ]; then\n" +
+		failure + "\n" +
+		"fi\n" +
+		"cat <<'EOF'\n" + pdfOutput + "EOF\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, func() int {
+		b, _ := os.ReadFile(callsFile)
+		return strings.Count(string(b), "x")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		failure       string
+		attempts      int
+		expectedErr   bool
+		expectedCalls int
+	}{
+		{"Killed process", 2, "kill -9 $$", 3, false, 3},
+		{"Empty output", 1, "exit 0", 3, false, 2},
+		{"Timeout", 1, "exec sleep 5", 2, false, 2},
+		{"Attempts exhausted", 3, "kill -9 $$", 3, true, 3},
+		{"Retries disabled", 1, "kill -9 $$", 0, true, 1},
+		{"Permanent failure", 1, "echo failed; exit 1", 3, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, calls := newFlakyStub(t, tt.failures, tt.failure)
+
+			trid := NewTrid(Options{
+				Cmd:     cmd,
+				Timeout: 500 * time.Millisecond,
+				Retry:   Retry{Attempts: tt.attempts, Backoff: time.Millisecond},
+			})
+
+			fileTypes, err := trid.Scan(cmd, 1)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Scan() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+
+			if err == nil && len(fileTypes) != 1 {
+				t.Errorf("Scan() returned %d results, want 1", len(fileTypes))
+			}
+
+			if got := calls(); got != tt.expectedCalls {
+				t.Errorf("TrID invoked %d times, want %d", got, tt.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestRetryIf(t *testing.T) {
+	cmd, calls := newFlakyStub(t, 1, "kill -9 $$")
+
+	var retried []error
+	trid := NewTrid(Options{
+		Cmd: cmd,
+		Retry: Retry{
+			Attempts: 3,
+			Backoff:  time.Millisecond,
+			RetryIf: func(err error) bool {
+				retried = append(retried, err)
+				return false
+			},
+		},
+	})
+
+	if _, err := trid.Scan(cmd, 1); err == nil {
+		t.Fatal("Scan() error = nil, want an error")
+	}
+
+	if len(retried) != 1 || calls() != 1 {
+		t.Errorf("RetryIf called with %v after %d invocations, want 1 call after 1 invocation", retried, calls())
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	cmd, calls := newFlakyStub(t, 5, "kill -9 $$")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	trid := NewTrid(Options{Cmd: cmd, Retry: Retry{Attempts: 5, Backoff: time.Hour}})
+
+	start := time.Now()
+	if _, err := trid.ScanContext(ctx, cmd, 1); err == nil {
+		t.Fatal("ScanContext() error = nil, want an error")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ScanContext() took %v after the context was done", elapsed)
+	}
+
+	if got := calls(); got != 1 {
+		t.Errorf("TrID invoked %d times, want 1", got)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Nil", nil, false},
+		{"Timeout", fmt.Errorf("%w: signal: killed", ErrTimeout), true},
+		{"Empty output", ErrEmptyOutput, true},
+		{"Cancelled", context.Canceled, false},
+		{"Executable not found", &exec.Error{Name: "trid", Err: exec.ErrNotFound}, false},
+		{"Permission denied", &os.PathError{Op: "fork/exec", Path: "trid", Err: os.ErrPermission}, false},
+		{"Reserved argument", fmt.Errorf("%w: -n:3", ErrReservedArg), false},
+		{"Binary not found", fmt.Errorf("%w: trid", ErrBinaryNotFound), false},
+		{"Definitions mismatch", ErrDefinitionsMismatch, false},
+		{"Unavailable", ErrUnavailable, false},
+		{"Other", errors.New("exit status 3"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.expected {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
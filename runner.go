@@ -0,0 +1,81 @@
+package trid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// Command is a TrID process to run.
+type Command struct {
+	Name  string    // Command to invoke, Options.Cmd.
+	Args  []string  // Arguments.
+	Stdin io.Reader // Standard input; nil if none.
+}
+
+// CommandResult is the outcome of running a Command.
+type CommandResult struct {
+	Stdout   string // Standard output.
+	Stderr   string // Standard error.
+	ExitCode int    // Exit code, or -1 if the process did not start or was killed.
+}
+
+// Runner runs TrID processes. Run must stop the process when ctx is done,
+// which happens when the timeout expires. It returns an error if the process
+// could not be run or failed, along with whatever output was captured.
+type Runner interface {
+	Run(ctx context.Context, c Command) (CommandResult, error)
+}
+
+// RunnerFunc adapts a function to the Runner interface.
+type RunnerFunc func(ctx context.Context, c Command) (CommandResult, error)
+
+// Run calls f(ctx, c).
+func (f RunnerFunc) Run(ctx context.Context, c Command) (CommandResult, error) {
+	return f(ctx, c)
+}
+
+// ExecRunner is the default Runner, starting local processes with os/exec.
+// Processes are killed together with any processes they started.
+type ExecRunner struct{}
+
+// killWaitDelay is how long ExecRunner waits for killed processes to exit.
+const killWaitDelay = 2 * time.Second
+
+// Run implements Runner.
+func (ExecRunner) Run(ctx context.Context, c Command) (CommandResult, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Stdin = c.Stdin
+
+	// Capture stdout and stderr separately, so warnings cannot end up in the
+	// middle of the results
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Run TrID in a process group of its own, so that it is killed together
+	// with any processes it started, and stop waiting for output held open
+	// by processes that escaped the group
+	group := newProcessGroup(cmd)
+	defer group.close()
+	cmd.WaitDelay = killWaitDelay
+
+	err := cmd.Start()
+	if err == nil {
+		group.attach()
+		err = cmd.Wait()
+
+		if ctx.Err() != nil {
+			group.wait(killWaitDelay)
+		}
+	}
+
+	res := CommandResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: -1}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	return res, err
+}
@@ -0,0 +1,75 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	var commands []Command
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		commands = append(commands, c)
+		return CommandResult{Stdout: pdfOutput}, nil
+	})
+
+	trid := NewTrid(Options{Cmd: "remote-trid", Runner: runner})
+
+	fileTypes, err := trid.Scan("testdata/sample.pdf", 2)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0].Extension != ".pdf" {
+		t.Fatalf("Scan() = %+v, %v", fileTypes, err)
+	}
+
+	expected := []string{"-v", "-n:2", "testdata/sample.pdf"}
+	if len(commands) != 1 || commands[0].Name != "remote-trid" || !slices.Equal(commands[0].Args, expected) {
+		t.Errorf("commands = %+v, want remote-trid %q", commands, expected)
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		runner   RunnerFunc
+		expected error
+	}{
+		{"Failure", func(ctx context.Context, c Command) (CommandResult, error) {
+			return CommandResult{Stderr: "boom", ExitCode: 3}, errors.New("exit status 3")
+		}, nil},
+		{"Timeout", func(ctx context.Context, c Command) (CommandResult, error) {
+			<-ctx.Done()
+			return CommandResult{ExitCode: -1}, ctx.Err()
+		}, ErrTimeout},
+		{"Timeout without error", func(ctx context.Context, c Command) (CommandResult, error) {
+			<-ctx.Done()
+			return CommandResult{}, nil
+		}, ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(Options{Cmd: "trid", Runner: tt.runner, Timeout: 10 * time.Millisecond})
+
+			_, err := trid.Scan("testdata/sample.pdf", 1)
+
+			var tridErr *TridError
+			if !errors.As(err, &tridErr) {
+				t.Fatalf("Scan() error = %v, want a *TridError", err)
+			}
+
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Scan() error = %v, want %v", err, tt.expected)
+			}
+
+			if strings.Contains(err.Error(), "%!") {
+				t.Errorf("Scan() error = %q, want a well-formed message", err)
+			}
+
+			if tt.expected == nil && (tridErr.ExitCode != 3 || tridErr.Stderr != "boom") {
+				t.Errorf("TridError = %+v, want exit code 3 and stderr", tridErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package s3scan
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 checksum of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// HTTPClientOptions configures the built-in S3 client.
+type HTTPClientOptions struct {
+	Endpoint        string       // Service endpoint (e.g., "https://s3.us-east-1.amazonaws.com").
+	Region          string       // Region used for request signing (default: "us-east-1").
+	AccessKeyID     string       // Access key; requests are sent unsigned if empty.
+	SecretAccessKey string       // Secret key.
+	SessionToken    string       // Optional session token for temporary credentials.
+	PathStyle       bool         // Address buckets as endpoint/bucket instead of bucket.endpoint.
+	HTTPClient      *http.Client // HTTP client to use (default: http.DefaultClient).
+}
+
+// HTTPClient is a minimal client for S3-compatible services, signing requests
+// with AWS Signature Version 4.
+type HTTPClient struct {
+	opts     HTTPClientOptions
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// NewHTTPClient returns a Client for the S3-compatible service described by
+// opts.
+func NewHTTPClient(opts HTTPClientOptions) (*HTTPClient, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", opts.Endpoint)
+	}
+
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return &HTTPClient{opts: opts, endpoint: endpoint, now: time.Now}, nil
+}
+
+// listBucketResult is the response of a ListObjectsV2 request.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects implements Client.
+func (c *HTTPClient) ListObjects(ctx context.Context, bucket, prefix string, fn func(Object) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, bucket, "", query, nil)
+		if err != nil {
+			return err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, o := range result.Contents {
+			obj := Object{
+				Key:          o.Key,
+				Size:         o.Size,
+				ETag:         strings.Trim(o.ETag, `"`),
+				LastModified: o.LastModified,
+			}
+
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+// GetObject implements Client.
+func (c *HTTPClient) GetObject(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	if length >= 0 {
+		if length == 0 {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.do(ctx, bucket, key, nil, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// do sends a signed GET request and checks the response status.
+func (c *HTTPClient) do(ctx context.Context, bucket, key string, query url.Values, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	if c.opts.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	c.sign(req)
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		defer resp.Body.Close()
+
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("s3: %s: %s: %s", resp.Status, s3Err.Code, s3Err.Message)
+		}
+
+		return nil, fmt.Errorf("s3: %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. Requests are left
+// unsigned when no credentials are configured.
+func (c *HTTPClient) sign(req *http.Request) {
+	if c.opts.AccessKeyID == "" {
+		return
+	}
+
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if c.opts.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.opts.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.opts.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.opts.SecretAccessKey), date)
+	key = hmacSHA256(key, c.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.opts.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes query parameters sorted by key, as required for
+// signing.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes s as specified for Signature Version 4. Slashes
+// are only encoded if encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+		}
+	}
+
+	return b.String()
+}
+
+// hashHex returns the hex-encoded SHA-256 checksum of b.
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,203 @@
+// Package s3scan identifies objects stored in S3-compatible object storage
+// with TrID, downloading only as many bytes as needed.
+package s3scan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrStop can be returned by a ScanPrefix callback to stop scanning without
+// reporting an error.
+var ErrStop = errors.New("stop scanning")
+
+// Object describes a stored object.
+type Object struct {
+	Key          string    // Key of the object.
+	Size         int64     // Size of the object in bytes.
+	ETag         string    // Entity tag of the object.
+	LastModified time.Time // Last modification time of the object.
+}
+
+// Client accesses an object store. NewHTTPClient returns an implementation for
+// S3-compatible services; other SDKs can be adapted by implementing it.
+type Client interface {
+	// ListObjects calls fn for every object in bucket whose key starts with
+	// prefix. Listing stops at the first error returned by fn.
+	ListObjects(ctx context.Context, bucket, prefix string, fn func(Object) error) error
+
+	// GetObject returns the bytes of an object starting at offset. If length
+	// is negative the rest of the object is returned.
+	GetObject(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// Scanner identifies files. *trid.Trid satisfies this interface.
+type Scanner interface {
+	ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error)
+}
+
+// Options configures an Adapter.
+type Options struct {
+	NumberOfMatches int    // Number of matches to request per object (default: 5).
+	HeadSize        int64  // Number of leading bytes downloaded from large objects (default: 1 MB).
+	MaxFullSize     int64  // Objects up to this size are downloaded completely (default: HeadSize).
+	MaxConcurrent   int    // Maximum number of objects identified concurrently by ScanPrefix (default: 4).
+	TempDir         string // Directory for temporary files (default: os.TempDir()).
+}
+
+// Result is the identification result of an object.
+type Result struct {
+	Bucket    string          // Bucket of the object.
+	Object    Object          // The identified object.
+	Partial   bool            // Whether only the head of the object was downloaded.
+	FileTypes []trid.FileType // Identified file types.
+	Err       error           // Error if the identification failed.
+}
+
+// Adapter identifies objects from an object store.
+type Adapter struct {
+	client  Client
+	scanner Scanner
+	opts    Options
+}
+
+// New returns an Adapter reading objects through client and identifying them
+// with s.
+func New(client Client, s Scanner, opts Options) *Adapter {
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	if opts.HeadSize <= 0 {
+		opts.HeadSize = 1 << 20
+	}
+
+	if opts.MaxFullSize < opts.HeadSize {
+		opts.MaxFullSize = opts.HeadSize
+	}
+
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 4
+	}
+
+	return &Adapter{client: client, scanner: s, opts: opts}
+}
+
+// ScanObject downloads obj, or its head if it is larger than MaxFullSize, to a
+// temporary file and identifies it.
+func (a *Adapter) ScanObject(ctx context.Context, bucket string, obj Object) Result {
+	r := Result{Bucket: bucket, Object: obj}
+
+	length := int64(-1)
+	if obj.Size > a.opts.MaxFullSize {
+		length = a.opts.HeadSize
+		r.Partial = true
+	}
+
+	path, err := a.download(ctx, bucket, obj.Key, length)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer os.Remove(path)
+
+	r.FileTypes, r.Err = a.scanner.ScanContext(ctx, path, a.opts.NumberOfMatches)
+
+	return r
+}
+
+// ScanPrefix identifies every object in bucket whose key starts with prefix,
+// calling fn with each result. Results are delivered in completion order and
+// fn is never called concurrently. Scanning stops at the first error returned
+// by fn; returning ErrStop stops without an error.
+func (a *Adapter) ScanPrefix(ctx context.Context, bucket, prefix string, fn func(Result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		fnErr error
+		sem   = make(chan struct{}, a.opts.MaxConcurrent)
+	)
+
+	listErr := a.client.ListObjects(ctx, bucket, prefix, func(obj Object) error {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r := a.ScanObject(ctx, bucket, obj)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if fnErr != nil {
+				return
+			}
+
+			if err := fn(r); err != nil {
+				fnErr = err
+				cancel()
+			}
+		}()
+
+		return nil
+	})
+
+	wg.Wait()
+
+	if fnErr != nil {
+		if errors.Is(fnErr, ErrStop) {
+			return nil
+		}
+
+		return fnErr
+	}
+
+	return listErr
+}
+
+// download copies length bytes of an object (all if negative) to a temporary
+// file and returns its path.
+func (a *Adapter) download(ctx context.Context, bucket, key string, length int64) (string, error) {
+	body, err := a.client.GetObject(ctx, bucket, key, 0, length)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(a.opts.TempDir, "trid-s3-*")
+	if err != nil {
+		return "", err
+	}
+
+	var src io.Reader = body
+	if length >= 0 {
+		src = io.LimitReader(body, length)
+	}
+
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
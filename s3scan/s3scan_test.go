@@ -0,0 +1,121 @@
+package s3scan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+// memClient serves objects from memory and records requested lengths.
+type memClient struct {
+	mu      sync.Mutex
+	objects map[string]string
+	lengths map[string]int64
+}
+
+func (m *memClient) ListObjects(ctx context.Context, bucket, prefix string, fn func(Object) error) error {
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			if err := fn(Object{Key: key, Size: int64(len(data))}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *memClient) GetObject(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.lengths[key] = length
+	m.mu.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+
+	return io.NopCloser(strings.NewReader(data[offset:])), nil
+}
+
+// sizeScanner reports the size of the scanned file as its name.
+type sizeScanner struct{}
+
+func (sizeScanner) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return []trid.FileType{{Name: strings.Repeat("x", int(fi.Size()))}}, nil
+}
+
+func TestScanObject(t *testing.T) {
+	client := &memClient{
+		objects: map[string]string{"small": "12345", "large": strings.Repeat("z", 100)},
+		lengths: make(map[string]int64),
+	}
+	a := New(client, sizeScanner{}, Options{HeadSize: 10, MaxFullSize: 50})
+
+	tests := []struct {
+		key             string
+		expectedPartial bool
+		expectedSize    int
+	}{
+		{"small", false, 5},
+		{"large", true, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			r := a.ScanObject(context.Background(), "bucket", Object{Key: tt.key, Size: int64(len(client.objects[tt.key]))})
+			if r.Err != nil {
+				t.Fatalf("ScanObject() error = %v", r.Err)
+			}
+
+			if r.Partial != tt.expectedPartial {
+				t.Errorf("ScanObject() partial = %v, want %v", r.Partial, tt.expectedPartial)
+			}
+
+			if len(r.FileTypes[0].Name) != tt.expectedSize {
+				t.Errorf("scanned %d bytes, want %d", len(r.FileTypes[0].Name), tt.expectedSize)
+			}
+		})
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	client := &memClient{
+		objects: map[string]string{"in/a": "a", "in/b": "bb", "in/c": "ccc", "out/d": "dddd"},
+		lengths: make(map[string]int64),
+	}
+	a := New(client, sizeScanner{}, Options{MaxConcurrent: 2})
+
+	var keys []string
+	err := a.ScanPrefix(context.Background(), "bucket", "in/", func(r Result) error {
+		keys = append(keys, r.Object.Key)
+		return r.Err
+	})
+	if err != nil {
+		t.Fatalf("ScanPrefix() error = %v", err)
+	}
+
+	if len(keys) != 3 {
+		t.Errorf("ScanPrefix() scanned %v, want 3 objects", keys)
+	}
+
+	calls := 0
+	err = a.ScanPrefix(context.Background(), "bucket", "", func(r Result) error {
+		calls++
+		return ErrStop
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("ScanPrefix() = %v after %d calls, want nil after 1", err, calls)
+	}
+}
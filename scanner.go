@@ -0,0 +1,112 @@
+package trid
+
+import (
+	"context"
+	"os"
+)
+
+// Scanner identifies files. It is implemented by *Trid, and by tridtest.Fake
+// for tests of code that identifies files without TrID installed.
+type Scanner interface {
+	Scan(filePath string, numberOfMatches int) ([]FileType, error)
+	ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]FileType, error)
+	ScanReport(ctx context.Context, filePath string, numberOfMatches int) (*Report, error)
+	ScanBatch(ctx context.Context, filePaths []string, numberOfMatches int) ([]BatchResult, error)
+}
+
+var _ Scanner = (*Trid)(nil)
+
+// ScannerFunc adapts a function identifying a single file to the Scanner
+// interface, for identification engines other than TrID. The function
+// returns the matches in any order, or an error. ScannerFunc validates the
+// arguments like Trid does, including ErrFileNotFound and ErrEmptyFile,
+// returns ErrUnknownFileType if there are no matches, and orders and limits
+// them as requested. Batches are identified
+// one file after another.
+type ScannerFunc func(ctx context.Context, filePath string) ([]FileType, error)
+
+var _ Scanner = ScannerFunc(nil)
+
+// Scan implements Scanner.
+func (f ScannerFunc) Scan(filePath string, numberOfMatches int) ([]FileType, error) {
+	return f.ScanContext(context.Background(), filePath, numberOfMatches)
+}
+
+// ScanContext implements Scanner.
+func (f ScannerFunc) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]FileType, error) {
+	r, err := f.ScanReport(ctx, filePath, numberOfMatches)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FileTypes, nil
+}
+
+// ScanReport implements Scanner. Reports have neither warnings nor raw
+// output.
+func (f ScannerFunc) ScanReport(ctx context.Context, filePath string, numberOfMatches int) (*Report, error) {
+	if filePath == "" {
+		return nil, ErrNoFileSpecified
+	}
+
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	return f.scan(ctx, filePath, numberOfMatches)
+}
+
+// ScanBatch implements Scanner.
+func (f ScannerFunc) ScanBatch(ctx context.Context, filePaths []string, numberOfMatches int) ([]BatchResult, error) {
+	if numberOfMatches < 1 {
+		return nil, ErrNumberOfMatches
+	}
+
+	results := make([]BatchResult, len(filePaths))
+	for i, path := range filePaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		results[i].Path = path
+
+		if path == "" {
+			results[i].Err = ErrNoFileSpecified
+			continue
+		}
+
+		r, err := f.scan(ctx, path, numberOfMatches)
+		if results[i].Err = err; err == nil {
+			results[i].FileTypes = r.FileTypes
+		}
+	}
+
+	return results, nil
+}
+
+// scan checks the file, calls f and orders and limits its matches.
+func (f ScannerFunc) scan(ctx context.Context, filePath string, numberOfMatches int) (*Report, error) {
+	fi, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if fi.Mode().IsRegular() && fi.Size() == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	fileTypes, err := f(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fileTypes) == 0 {
+		return nil, ErrUnknownFileType
+	}
+
+	Results(fileTypes).SortByProbability()
+
+	return &Report{FileTypes: fileTypes[:min(len(fileTypes), numberOfMatches)]}, nil
+}
@@ -0,0 +1,50 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScannerFunc(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, nil, 0o644)
+
+	var calls []string
+	scanner := ScannerFunc(func(ctx context.Context, path string) ([]FileType, error) {
+		calls = append(calls, path)
+
+		if path == "testdata/sample.unknown" {
+			return nil, nil
+		}
+
+		return []FileType{{Extension: ".b", Probability: 20}, {Extension: ".a", Probability: 80}, {Extension: ".c", Probability: 5}}, nil
+	})
+
+	fileTypes, err := scanner.Scan("testdata/sample.pdf", 2)
+	if err != nil || len(fileTypes) != 2 || fileTypes[0].Extension != ".a" || fileTypes[1].Extension != ".b" {
+		t.Errorf("Scan() = %+v, %v, want .a and .b", fileTypes, err)
+	}
+
+	results, err := scanner.ScanBatch(context.Background(), []string{"testdata/sample.unknown", "", "missing", empty}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []error{ErrUnknownFileType, ErrNoFileSpecified, ErrFileNotFound, ErrEmptyFile} {
+		if !errors.Is(results[i].Err, expected) {
+			t.Errorf("ScanBatch()[%d] error = %v, want %v", i, results[i].Err, expected)
+		}
+	}
+
+	if _, err := scanner.Scan("testdata/sample.pdf", 0); !errors.Is(err, ErrNumberOfMatches) {
+		t.Errorf("Scan() error = %v, want ErrNumberOfMatches", err)
+	}
+
+	if len(calls) != 2 {
+		t.Errorf("function called for %q, want only the existing files", calls)
+	}
+}
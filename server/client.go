@@ -0,0 +1,407 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/defs"
+)
+
+// Client calls an identification server over HTTP, following the operations
+// of the OpenAPI document.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	apiKey  string
+}
+
+// StatusError is an error response of the server. It matches the sentinel
+// error of the package with the same message, if any, so callers can use
+// errors.Is as with a local Trid.
+type StatusError struct {
+	StatusCode int    // HTTP status code of the response.
+	Message    string // Error reported by the server.
+	sentinel   error
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Unwrap returns the sentinel error the response corresponds to, if any.
+func (e *StatusError) Unwrap() error {
+	return e.sentinel
+}
+
+// NewClient returns a Client of the server at baseURL (e.g.,
+// "http://localhost:8080"). If httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server URL %q", baseURL)
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{baseURL: strings.TrimSuffix(u.String(), "/"), http: httpClient}, nil
+}
+
+// WithAPIKey returns a copy of c sending key as a bearer token, for servers
+// with Options.Auth.
+func (c *Client) WithAPIKey(key string) *Client {
+	clone := *c
+	clone.apiKey = key
+
+	return &clone
+}
+
+// Identify uploads content and returns its file types. A numberOfMatches of 0
+// uses the default of the server.
+func (c *Client) Identify(ctx context.Context, content io.Reader, numberOfMatches int) ([]trid.FileType, error) {
+	endpoint := c.baseURL + "/identify"
+	if numberOfMatches != 0 {
+		endpoint += "?matches=" + strconv.Itoa(numberOfMatches)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, content)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	var resp Response
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.FileTypes, nil
+}
+
+// IdentifyFile uploads the local file at path and returns its file types.
+func (c *Client) IdentifyFile(ctx context.Context, path string, numberOfMatches int) ([]trid.FileType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return c.Identify(ctx, f, numberOfMatches)
+}
+
+// IdentifyStream uploads content to /identify/stream and returns its file
+// types once the whole upload is identified. If provisional is not nil, it is
+// called with the result of the first bytes of the upload as soon as the
+// server sends it, while the rest is still being uploaded. An error of the
+// final result is returned as a *StatusError with the status of the response,
+// 200.
+func (c *Client) IdentifyStream(ctx context.Context, content io.Reader, numberOfMatches int, provisional func(StreamResult)) ([]trid.FileType, error) {
+	endpoint := c.baseURL + "/identify/stream"
+	if numberOfMatches != 0 {
+		endpoint += "?matches=" + strconv.Itoa(numberOfMatches)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, content)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, responseError(resp)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var r StreamResult
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if r.Stage == StageProvisional {
+			if provisional != nil {
+				provisional(r)
+			}
+			continue
+		}
+
+		if r.Error != "" {
+			return nil, statusError(resp.StatusCode, r.Error)
+		}
+
+		return r.FileTypes, nil
+	}
+}
+
+// IdentifyPath identifies the file at path on the file system of the server,
+// which must be below one of its Options.PathRoots.
+func (c *Client) IdentifyPath(ctx context.Context, path string, numberOfMatches int) ([]trid.FileType, error) {
+	body, err := json.Marshal(PathRequest{Path: path, Matches: numberOfMatches})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/identify/path", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp Response
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.FileTypes, nil
+}
+
+// DefsInfo describes the definitions package used by the server.
+func (c *Client) DefsInfo(ctx context.Context) (*defs.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/defs/info", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var info defs.Info
+	if err := c.do(req, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// Ready returns nil if TrID is usable by the server, and the reason it is not
+// otherwise.
+func (c *Client) Ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/readyz", nil)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, nil)
+}
+
+// ScanEvent is an event of a scan, as passed to the function of ScanEvents.
+type ScanEvent struct {
+	ID     int         // Sequence number of the event, from 0.
+	Type   string      // EventProgress, EventResult or EventDone.
+	Status *ScanStatus // Status of the scan, for progress and done events.
+	Result *ScanResult // Result of a file, for result events.
+}
+
+// StartScan starts scanning the directory or archive at path on the file
+// system of the server, which must be below one of its Options.PathRoots.
+func (c *Client) StartScan(ctx context.Context, path string, numberOfMatches int) (*ScanStatus, error) {
+	body, err := json.Marshal(ScanRequest{Path: path, Matches: numberOfMatches})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/scans", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var status ScanStatus
+	if err := c.do(req, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Scan returns the status of the scan with the given ID.
+func (c *Client) Scan(ctx context.Context, id string) (*ScanStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/scans/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ScanStatus
+	if err := c.do(req, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// CancelScan cancels the scan with the given ID.
+func (c *Client) CancelScan(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.baseURL+"/scans/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, nil)
+}
+
+// ScanEvents passes the events of the scan with the given ID to fn, from the
+// first one, until the done event. It stops with the error of fn, if any, or
+// with io.ErrUnexpectedEOF if the stream ends before the done event.
+func (c *Client) ScanEvents(ctx context.Context, id string, fn func(ScanEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/scans/"+url.PathEscape(id)+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	var e ScanEvent
+	var data []byte
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		field, value, _ := strings.Cut(sc.Text(), ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			e.ID, _ = strconv.Atoi(value)
+		case "event":
+			e.Type = value
+		case "data":
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		case "":
+			// A blank line ends an event; comments start with ":"
+			if sc.Text() != "" || data == nil {
+				continue
+			}
+
+			if e.Type == EventResult {
+				e.Result = new(ScanResult)
+				err = json.Unmarshal(data, e.Result)
+			} else {
+				e.Status = new(ScanStatus)
+				err = json.Unmarshal(data, e.Status)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := fn(e); err != nil {
+				return err
+			}
+
+			if e.Type == EventDone {
+				return nil
+			}
+
+			e, data = ScanEvent{}, nil
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	return io.ErrUnexpectedEOF
+}
+
+// do sends req and decodes the JSON response into v, if not nil, or returns
+// the error response as a *StatusError.
+func (c *Client) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// send sends req with the API key of c, if any.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return c.http.Do(req)
+}
+
+// responseError returns the error reported by the error response resp.
+func responseError(resp *http.Response) *StatusError {
+	var body ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil || body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+
+	return statusError(resp.StatusCode, body.Error)
+}
+
+// statusError returns the error of a response with the given status code and
+// message.
+func statusError(code int, message string) *StatusError {
+	err := &StatusError{StatusCode: code, Message: message}
+	switch code {
+	case http.StatusUnauthorized:
+		err.sentinel = auth.ErrUnauthenticated
+		return err
+	case http.StatusTooManyRequests:
+		err.sentinel = auth.ErrRateLimited
+		for _, sentinel := range []error{ErrQueueFull, ErrTooManyScans} {
+			if message == sentinel.Error() {
+				err.sentinel = sentinel
+			}
+		}
+		return err
+	}
+
+	for _, sentinel := range []error{trid.ErrNoFileSpecified, trid.ErrNumberOfMatches, trid.ErrFileNotFound, trid.ErrUnknownFileType, trid.ErrUnavailable, trid.ErrFileTooLarge, trid.ErrEmptyFile, trid.ErrNoDefinitions, ErrScanNotFound} {
+		if message == sentinel.Error() {
+			err.sentinel = sentinel
+			break
+		}
+	}
+
+	return err
+}
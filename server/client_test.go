@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+)
+
+func TestClient(t *testing.T) {
+	root := t.TempDir()
+	sample := filepath.Join(root, "sample.pdf")
+	if err := os.WriteFile(sample, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, dir := newTestServer(t, Options{PathRoots: []string{root}})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	fileTypes, err := c.Identify(ctx, strings.NewReader("%PDF-1.4"), 3)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0].Extension != ".pdf" {
+		t.Errorf("Identify() = %v, %v, want a PDF", fileTypes, err)
+	}
+
+	fileTypes, err = c.IdentifyFile(ctx, sample, 0)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0].MimeType != "application/pdf" {
+		t.Errorf("IdentifyFile() = %v, %v, want a PDF", fileTypes, err)
+	}
+
+	if fileTypes, err = c.IdentifyPath(ctx, sample, 0); err != nil || len(fileTypes) != 1 {
+		t.Errorf("IdentifyPath() = %v, %v, want a PDF", fileTypes, err)
+	}
+
+	_, err = c.IdentifyPath(ctx, filepath.Join(root, "missing.pdf"), 0)
+	var statusErr *StatusError
+	if !errors.Is(err, trid.ErrFileNotFound) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("IdentifyPath() of a missing file = %v, want ErrFileNotFound", err)
+	}
+
+	if _, err := c.Identify(ctx, strings.NewReader(""), -1); !errors.Is(err, trid.ErrNumberOfMatches) {
+		t.Errorf("Identify() with -1 matches = %v, want ErrNumberOfMatches", err)
+	}
+
+	info, err := c.DefsInfo(ctx)
+	if err != nil || info.Path != filepath.Join(dir, "triddefs.trd") {
+		t.Errorf("DefsInfo() = %+v, %v", info, err)
+	}
+
+	if err := c.Ready(ctx); err != nil {
+		t.Errorf("Ready() = %v", err)
+	}
+}
+
+func TestClientAPIKey(t *testing.T) {
+	s, _ := newTestServer(t, Options{Auth: auth.NewGuard(auth.StaticKeys{"secret": {Name: "tenant"}})})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.DefsInfo(context.Background()); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("DefsInfo() without key error = %v, want ErrUnauthenticated", err)
+	}
+
+	if _, err := c.WithAPIKey("secret").DefsInfo(context.Background()); err != nil {
+		t.Errorf("DefsInfo() with key error = %v", err)
+	}
+}
+
+func TestClientQueueFull(t *testing.T) {
+	s, _ := newTestServer(t, Options{MaxConcurrent: 1, MaxQueue: 1})
+	s.pending.Add(2)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Identify(context.Background(), strings.NewReader("%PDF"), 0); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Identify() error = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	if _, err := NewClient("localhost:8080", nil); err == nil {
+		t.Error("NewClient() accepted a URL without scheme")
+	}
+}
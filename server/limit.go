@@ -0,0 +1,131 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid/auth"
+)
+
+// ErrQueueFull is returned when Options.MaxQueue requests are already waiting
+// for a concurrency slot.
+var ErrQueueFull = errors.New("too many requests waiting for identification")
+
+// queueRetryAfter is the delay suggested to clients rejected by a full queue.
+const queueRetryAfter = time.Second
+
+// limiter enforces the request rate of each client with a token bucket per
+// client, forgetting the buckets of idle clients.
+type limiter struct {
+	rate    float64
+	burst   int
+	now     func() time.Time
+	mu      sync.Mutex
+	clients map[string]*client
+	pruned  time.Time
+}
+
+// client is the bucket of a client and the time of its last request.
+type client struct {
+	bucket *auth.Bucket
+	seen   time.Time
+}
+
+// newLimiter returns a limiter allowing rate requests per second with bursts
+// of burst requests, or nil if rate is not positive.
+func newLimiter(rate float64, burst int) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = max(1, int(math.Ceil(rate)))
+	}
+
+	return &limiter{rate: rate, burst: burst, now: time.Now, clients: make(map[string]*client)}
+}
+
+// allow counts a request of key, returning the time until the request would
+// be allowed, or 0 if it is.
+func (l *limiter) allow(key string) time.Duration {
+	now := l.now()
+
+	l.mu.Lock()
+	// A client idle for long enough has a full bucket, like a new one
+	idle := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	if now.Sub(l.pruned) > max(idle, time.Minute) {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > idle {
+				delete(l.clients, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{bucket: auth.NewBucket(l.rate, l.burst)}
+		l.clients[key] = c
+	}
+	c.seen = now
+	l.mu.Unlock()
+
+	return c.bucket.Take()
+}
+
+// limited returns a handler enforcing Options.RateLimit and Options.MaxQueue
+// before calling h. Rejected requests get 429 with Retry-After, before their
+// body is read.
+func (s *Server) limited(h http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil && s.opts.MaxQueue <= 0 {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter != nil {
+			if wait := s.limiter.allow(s.opts.ClientKey(r)); wait > 0 {
+				tooManyRequests(w, wait, auth.ErrRateLimited)
+				return
+			}
+		}
+
+		if s.opts.MaxQueue > 0 {
+			// Requests in flight either hold a slot or wait for one
+			if s.pending.Add(1) > int64(s.opts.MaxConcurrent+s.opts.MaxQueue) {
+				s.pending.Add(-1)
+				tooManyRequests(w, queueRetryAfter, ErrQueueFull)
+				return
+			}
+			defer s.pending.Add(-1)
+		}
+
+		h(w, r)
+	}
+}
+
+// clientKey identifies the client of r by its tenant, if authenticated, and
+// by its remote IP address otherwise.
+func clientKey(r *http.Request) string {
+	if tenant, ok := auth.FromContext(r.Context()); ok {
+		return "tenant:" + tenant.Name
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// tooManyRequests writes a 429 response asking the client to retry after
+// wait.
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+	writeError(w, http.StatusTooManyRequests, err)
+}
@@ -0,0 +1,461 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+)
+
+var (
+	// ErrScanNotFound is returned for a scan that does not exist, or no
+	// longer does.
+	ErrScanNotFound = errors.New("scan not found")
+
+	// ErrTooManyScans is returned when Options.MaxScans scans are already
+	// running.
+	ErrTooManyScans = errors.New("too many scans running")
+)
+
+// Scan states.
+const (
+	ScanRunning   = "running"
+	ScanDone      = "done"
+	ScanFailed    = "failed"
+	ScanCancelled = "cancelled"
+)
+
+// Scan event types, as sent by GET /scans/{id}/events.
+const (
+	EventProgress = "progress" // The ScanStatus of the scan, as files are identified.
+	EventResult   = "result"   // The ScanResult of a file.
+	EventDone     = "done"     // The final ScanStatus of the scan, last event of the stream.
+)
+
+const (
+	// scanChunkSize is the number of files of a directory scan identified by
+	// each TrID process, between progress events.
+	scanChunkSize = 100
+
+	// heartbeatInterval is the interval of comments sent on idle event
+	// streams, so proxies do not close them.
+	heartbeatInterval = 15 * time.Second
+
+	// scanEventBuffer is the maximum number of events of a scan kept for
+	// clients connecting late or reconnecting. Older events are dropped, half
+	// of the buffer at a time.
+	scanEventBuffer = 1024
+)
+
+// ScanRequest is the body of a POST /scans request.
+type ScanRequest struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches,omitempty"`
+}
+
+// ScanStatus describes a scan started with POST /scans.
+type ScanStatus struct {
+	ID         string     `json:"id"`
+	Path       string     `json:"path"`
+	State      string     `json:"state"`
+	FilesDone  int        `json:"files_done"`
+	FilesTotal int        `json:"files_total"`
+	Started    time.Time  `json:"started"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ScanResult is the result of a file of a scan. Entries of archives have the
+// path of ArchiveEntry.Path.
+type ScanResult struct {
+	Path      string          `json:"path"`
+	FileTypes []trid.FileType `json:"file_types"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// scan is a scan started with POST /scans, with the last events it has sent.
+type scan struct {
+	tenant  string // Name of the tenant that started the scan, with Options.Auth.
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	status  ScanStatus
+	events  []scanEvent   // Last events, up to scanEventBuffer.
+	first   int           // ID of events[0]; the events before were dropped.
+	changed chan struct{} // Closed when an event is added.
+}
+
+// scanEvent is an event of a scan, with its data encoded as JSON.
+type scanEvent struct {
+	typ  string
+	data []byte
+}
+
+// emit adds an event of type typ with data v, dropping the older half of the
+// events once scanEventBuffer are kept.
+func (sc *scan) emit(typ string, v any) {
+	if len(sc.events) == scanEventBuffer {
+		drop := scanEventBuffer / 2
+		sc.events = append(make([]scanEvent, 0, scanEventBuffer), sc.events[drop:]...)
+		sc.first += drop
+	}
+
+	data, _ := json.Marshal(v)
+	sc.events = append(sc.events, scanEvent{typ: typ, data: data})
+	close(sc.changed)
+	sc.changed = make(chan struct{})
+}
+
+// progress counts done more files as identified, out of total, and sends a
+// progress event.
+func (sc *scan) progress(done, total int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.status.FilesDone += done
+	sc.status.FilesTotal = total
+	sc.emit(EventProgress, sc.status)
+}
+
+// result sends the result of a file.
+func (sc *scan) result(path string, fileTypes []trid.FileType, err error) {
+	r := ScanResult{Path: path, FileTypes: fileTypes}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.emit(EventResult, r)
+}
+
+// finish records the end of the scan with err, and sends the done event.
+func (sc *scan) finish(ctx context.Context, err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	now := time.Now()
+	sc.status.Finished = &now
+
+	switch {
+	case ctx.Err() != nil:
+		sc.status.State = ScanCancelled
+	case err != nil:
+		sc.status.State, sc.status.Error = ScanFailed, err.Error()
+	default:
+		sc.status.State = ScanDone
+	}
+
+	sc.emit(EventDone, sc.status)
+}
+
+// since returns the events from ID i, or from the end if there are fewer,
+// with the ID of the first one, a channel closed when more are added, and
+// whether the scan is over, in which case the events include the last one. If
+// event i was dropped, the events kept are preceded by a progress event with
+// the current status, in place of the dropped ones.
+func (sc *scan) since(i int) ([]scanEvent, int, <-chan struct{}, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	finished := sc.status.State != ScanRunning
+
+	if i < sc.first {
+		data, _ := json.Marshal(sc.status)
+		events := append([]scanEvent{{typ: EventProgress, data: data}}, sc.events...)
+		return events, sc.first - 1, sc.changed, finished
+	}
+
+	i = min(i-sc.first, len(sc.events))
+	return sc.events[i:], sc.first + i, sc.changed, finished
+}
+
+// snapshot returns the current status of the scan.
+func (sc *scan) snapshot() ScanStatus {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.status
+}
+
+// handleStartScan starts scanning a directory, or an archive and its entries,
+// on the server's file system, and responds with the status of the scan.
+func (s *Server) handleStartScan(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Matches == 0 {
+		req.Matches = s.opts.NumberOfMatches
+	}
+
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, trid.ErrNoFileSpecified)
+		return
+	}
+
+	if req.Matches < 1 {
+		writeError(w, http.StatusBadRequest, trid.ErrNumberOfMatches)
+		return
+	}
+
+	if !trid.WithinRoots(req.Path, s.opts.PathRoots) {
+		writeError(w, http.StatusForbidden, errPathNotAllowed)
+		return
+	}
+
+	fi, err := os.Stat(req.Path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, trid.ErrFileNotFound)
+		return
+	}
+
+	id, err := newScanID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	sc := &scan{
+		cancel:  cancel,
+		status:  ScanStatus{ID: id, Path: req.Path, State: ScanRunning, Started: time.Now()},
+		changed: make(chan struct{}),
+	}
+	if tenant, ok := auth.FromContext(r.Context()); ok {
+		sc.tenant = tenant.Name
+	}
+
+	if !s.addScan(sc) {
+		cancel()
+		tooManyRequests(w, queueRetryAfter, ErrTooManyScans)
+		return
+	}
+
+	go func() {
+		defer cancel()
+
+		if fi.IsDir() {
+			sc.finish(ctx, s.scanDir(ctx, sc, req.Path, req.Matches))
+		} else {
+			sc.finish(ctx, s.scanArchive(ctx, sc, req.Path, req.Matches))
+		}
+	}()
+
+	w.Header().Set("Location", "/scans/"+id)
+	writeJSON(w, http.StatusAccepted, sc.snapshot())
+}
+
+// addScan registers sc, forgetting the scans finished more than
+// Options.ScanRetention ago. It reports false if Options.MaxScans scans are
+// running.
+func (s *Server) addScan(sc *scan) bool {
+	s.scansMu.Lock()
+	defer s.scansMu.Unlock()
+
+	running := 0
+	for id, other := range s.scans {
+		status := other.snapshot()
+		if status.Finished != nil && time.Since(*status.Finished) > s.opts.ScanRetention {
+			delete(s.scans, id)
+		} else if status.Finished == nil {
+			running++
+		}
+	}
+
+	if running >= s.opts.MaxScans {
+		return false
+	}
+
+	s.scans[sc.status.ID] = sc
+	return true
+}
+
+// scanDir identifies the regular files of the tree rooted at dir, one chunk
+// at a time, each holding a concurrency slot. Symbolic links are skipped, so
+// the scan stays within Options.PathRoots.
+func (s *Server) scanDir(ctx context.Context, sc *scan, dir string, matches int) error {
+	paths, err := trid.Walk(dir, trid.SymlinkRefuse)
+	if err != nil {
+		return err
+	}
+
+	sc.progress(0, len(paths))
+
+	for start := 0; start < len(paths); start += scanChunkSize {
+		chunk := paths[start:min(start+scanChunkSize, len(paths))]
+
+		results, err := s.scanChunk(ctx, chunk, matches)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range results {
+			sc.result(r.Path, r.FileTypes, r.Err)
+		}
+
+		sc.progress(len(chunk), len(paths))
+	}
+
+	return nil
+}
+
+// scanChunk identifies paths while holding a concurrency slot.
+func (s *Server) scanChunk(ctx context.Context, paths []string, matches int) ([]trid.BatchResult, error) {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return s.trid.ScanBatch(ctx, paths, matches)
+}
+
+// scanArchive identifies the file at path and, if it is an archive, its
+// entries, while holding a concurrency slot. Results are sent once the whole
+// archive has been scanned.
+func (s *Server) scanArchive(ctx context.Context, sc *scan, path string, matches int) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	root, err := s.trid.ScanArchive(ctx, path, matches, trid.ArchiveOptions{})
+	<-s.sem
+	if err != nil {
+		return err
+	}
+
+	var entries []*trid.ArchiveEntry
+	var flatten func(e *trid.ArchiveEntry)
+	flatten = func(e *trid.ArchiveEntry) {
+		entries = append(entries, e)
+		for _, child := range e.Entries {
+			flatten(child)
+		}
+	}
+	flatten(root)
+
+	for _, e := range entries {
+		sc.result(e.Path, e.FileTypes, e.Err)
+	}
+
+	sc.progress(len(entries), len(entries))
+
+	return nil
+}
+
+// scan returns the scan with the id of r, or writes a 404 response and
+// returns nil if there is none visible to the tenant of r.
+func (s *Server) scan(w http.ResponseWriter, r *http.Request) *scan {
+	s.scansMu.Lock()
+	sc := s.scans[r.PathValue("id")]
+	s.scansMu.Unlock()
+
+	if sc != nil {
+		if tenant, ok := auth.FromContext(r.Context()); !ok || tenant.Name == sc.tenant {
+			return sc
+		}
+	}
+
+	writeError(w, http.StatusNotFound, ErrScanNotFound)
+	return nil
+}
+
+// handleScanStatus responds with the status of a scan.
+func (s *Server) handleScanStatus(w http.ResponseWriter, r *http.Request) {
+	if sc := s.scan(w, r); sc != nil {
+		writeJSON(w, http.StatusOK, sc.snapshot())
+	}
+}
+
+// handleCancelScan cancels a scan. The scan ends with a done event once TrID
+// has stopped.
+func (s *Server) handleCancelScan(w http.ResponseWriter, r *http.Request) {
+	if sc := s.scan(w, r); sc != nil {
+		sc.cancel()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleScanEvents streams the events of a scan as Server-Sent Events, from
+// the first one or the one following the Last-Event-ID header of a client
+// reconnecting, until the done event. Clients asking for events no longer
+// kept get the current status instead.
+func (s *Server) handleScanEvents(w http.ResponseWriter, r *http.Request) {
+	sc := s.scan(w, r)
+	if sc == nil {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	next := 0
+	if id, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && id >= 0 {
+		next = id + 1
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		events, start, changed, finished := sc.since(next)
+		next = start
+		for _, e := range events {
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", next, e.typ, e.data)
+			next++
+		}
+		flusher.Flush()
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Close cancels the running scans.
+func (s *Server) Close() {
+	s.cancel()
+}
+
+// newScanID returns a random scan ID.
+func newScanID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
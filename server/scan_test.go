@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+)
+
+// newScanServer returns a server backed by a stub TrID executable that
+// identifies files starting with "%PDF" and reports other files as unknown,
+// in multi-file output format, and a directory below its PathRoots holding
+// two PDF files and a text file.
+func newScanServer(t *testing.T, opts Options) (*Server, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	cmd := filepath.Join(dir, "trid")
+	script := `#!/bin/sh
+files=$(for f in "$@"; do case "$f" in -*) ;; *) echo "$f" ;; esac; done)
+for f in "$@"; do
+  [ "$f" = "-@" ] && files=$(cat)
+done
+echo "TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello"
+echo "Definitions found:  18452"
+echo "Analyzing..."
+echo "$files" | while IFS= read -r f; do
+  echo
+  echo "File: $f"
+  if head -c 4 "$f" | grep -q '%PDF'; then
+    echo " 100.0% (.PDF) Adobe Portable Document Format (5000/1)"
+  else
+    echo "Unknown!"
+  fi
+done
+`
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "triddefs.trd"), []byte("defs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	for name, content := range map[string]string{"a.pdf": "%PDF-1.4", "b.txt": "hello", "sub/c.pdf": "%PDF-1.7"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts.PathRoots = []string{root}
+	s := New(trid.NewTrid(trid.Options{Cmd: cmd}), opts)
+	t.Cleanup(s.Close)
+
+	return s, root
+}
+
+func TestScanEvents(t *testing.T) {
+	s, root := newScanServer(t, Options{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	status, err := c.StartScan(ctx, root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.ID == "" || status.Path != root || status.State != ScanRunning {
+		t.Errorf("StartScan() = %+v", status)
+	}
+
+	var events []ScanEvent
+	if err := c.ScanEvents(ctx, status.ID, func(e ScanEvent) error {
+		events = append(events, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// progress, 3 results, progress, done
+	if len(events) != 6 {
+		t.Fatalf("got %d events, want 6: %+v", len(events), events)
+	}
+
+	if first := events[0]; first.Type != EventProgress || first.Status.FilesDone != 0 || first.Status.FilesTotal != 3 {
+		t.Errorf("first event = %s %+v, want progress of 0/3 files", first.Type, first.Status)
+	}
+
+	results := make(map[string]*ScanResult)
+	for i, e := range events {
+		if e.ID != i {
+			t.Errorf("event %d has ID %d", i, e.ID)
+		}
+
+		if e.Type == EventResult {
+			results[e.Result.Path] = e.Result
+		}
+	}
+
+	for name, expectedExt := range map[string]string{"a.pdf": ".pdf", "sub/c.pdf": ".pdf", "b.txt": ""} {
+		r := results[filepath.Join(root, name)]
+		switch {
+		case r == nil:
+			t.Errorf("no result for %s", name)
+		case expectedExt == "" && r.Error != trid.ErrUnknownFileType.Error():
+			t.Errorf("result of %s = %+v, want %v", name, r, trid.ErrUnknownFileType)
+		case expectedExt != "" && (len(r.FileTypes) != 1 || r.FileTypes[0].Extension != expectedExt):
+			t.Errorf("result of %s = %+v, want %s", name, r, expectedExt)
+		}
+	}
+
+	done := events[len(events)-1]
+	if done.Type != EventDone || done.Status.State != ScanDone || done.Status.FilesDone != 3 || done.Status.Finished == nil {
+		t.Errorf("last event = %s %+v, want done with 3 files", done.Type, done.Status)
+	}
+
+	if status, err := c.Scan(ctx, status.ID); err != nil || status.State != ScanDone {
+		t.Errorf("Scan() = %+v, %v", status, err)
+	}
+
+	// A client reconnecting gets the events following the last one it got
+	req := httptest.NewRequest(http.MethodGet, "/scans/"+status.ID+"/events", nil)
+	req.Header.Set("Last-Event-ID", "4")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if got := rec.Body.String(); !strings.HasPrefix(got, "id: 5\nevent: done\n") || strings.Count(got, "id: ") != 1 {
+		t.Errorf("events after reconnection = %q, want only the done event", got)
+	}
+}
+
+func TestScanEventsBounded(t *testing.T) {
+	sc := &scan{status: ScanStatus{State: ScanRunning}, changed: make(chan struct{})}
+	for i := 0; i < 2*scanEventBuffer; i++ {
+		sc.result("file", nil, nil)
+	}
+	sc.progress(2*scanEventBuffer, 2*scanEventBuffer)
+
+	if len(sc.events) > scanEventBuffer {
+		t.Fatalf("%d events kept, want at most %d", len(sc.events), scanEventBuffer)
+	}
+
+	// A client asking for dropped events gets the current status first
+	events, start, _, _ := sc.since(5)
+	if start != sc.first-1 || len(events) != len(sc.events)+1 {
+		t.Fatalf("since(5) = %d events from %d, want %d from %d", len(events), start, len(sc.events)+1, sc.first-1)
+	}
+
+	if first := events[0]; first.typ != EventProgress || !strings.Contains(string(first.data), fmt.Sprintf(`"files_done":%d`, 2*scanEventBuffer)) {
+		t.Errorf("first event = %s %s, want progress with the current status", first.typ, first.data)
+	}
+
+	// Events still kept are replayed from the requested one
+	last := sc.first + len(sc.events) - 1
+	if events, start, _, _ := sc.since(last); start != last || len(events) != 1 || events[0].typ != EventProgress {
+		t.Errorf("since(%d) = %d events from %d, want the last one", last, len(events), start)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	s, root := newScanServer(t, Options{})
+
+	link := filepath.Join(root, "outside")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Missing path", http.MethodPost, "/scans", `{}`, http.StatusBadRequest},
+		{"Outside the roots", http.MethodPost, "/scans", `{"path": "/etc"}`, http.StatusForbidden},
+		{"Symbolic link out of the roots", http.MethodPost, "/scans", `{"path": "` + link + `"}`, http.StatusForbidden},
+		{"Missing directory", http.MethodPost, "/scans", `{"path": "` + filepath.Join(root, "missing") + `"}`, http.StatusNotFound},
+		{"Unknown scan", http.MethodGet, "/scans/unknown", "", http.StatusNotFound},
+		{"Unknown scan events", http.MethodGet, "/scans/unknown/events", "", http.StatusNotFound},
+		{"Cancel unknown scan", http.MethodDelete, "/scans/unknown", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestScanMaxScans(t *testing.T) {
+	s, root := newScanServer(t, Options{MaxScans: 1})
+	s.scans["running"] = &scan{status: ScanStatus{ID: "running", State: ScanRunning}, changed: make(chan struct{})}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/scans", strings.NewReader(`{"path": "`+root+`"}`)))
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After = %q, want 429", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestScanTenants(t *testing.T) {
+	s, root := newScanServer(t, Options{Auth: auth.NewGuard(auth.StaticKeys{
+		"key-a": {Name: "a"},
+		"key-b": {Name: "b"},
+	})})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	status, err := c.WithAPIKey("key-a").StartScan(ctx, root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.WithAPIKey("key-a").Scan(ctx, status.ID); err != nil {
+		t.Errorf("Scan() by the same tenant error = %v", err)
+	}
+
+	if _, err := c.WithAPIKey("key-b").Scan(ctx, status.ID); !errors.Is(err, ErrScanNotFound) {
+		t.Errorf("Scan() by another tenant error = %v, want ErrScanNotFound", err)
+	}
+
+	err = c.WithAPIKey("key-b").ScanEvents(ctx, status.ID, func(ScanEvent) error { return nil })
+	if !errors.Is(err, ErrScanNotFound) {
+		t.Errorf("ScanEvents() by another tenant error = %v, want ErrScanNotFound", err)
+	}
+
+	// ScanEvents stops with the error of fn
+	err = c.WithAPIKey("key-a").ScanEvents(ctx, status.ID, func(e ScanEvent) error {
+		if e.Type == EventResult {
+			return io.EOF
+		}
+		return nil
+	})
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("ScanEvents() error = %v, want the error of fn", err)
+	}
+}
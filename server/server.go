@@ -0,0 +1,404 @@
+// Package server exposes TrID file identification over HTTP.
+//
+// The following endpoints are served:
+//
+//	POST   /identify           identify an uploaded file (multipart field "file" or raw body)
+//	POST   /identify/path      identify a local file: {"path": "...", "matches": 5}
+//	POST   /identify/stream    identify an upload from its first bytes, then once received
+//	GET    /defs/info          describe the definitions package in use
+//	GET    /readyz             report whether TrID is usable (see trid.Trid.Probe)
+//	GET    /openapi.json       describe the API as an OpenAPI 3 document
+//	POST   /scans              start scanning a local directory or archive: {"path": "..."}
+//	GET    /scans/{id}         report the status of a scan
+//	GET    /scans/{id}/events  stream the progress and results of a scan
+//	DELETE /scans/{id}         cancel a scan
+//
+// Scans of directories and archives run in the background; their events
+// stream progress and per-file results as Server-Sent Events, so web
+// interfaces can show them live.
+//
+// The OpenAPI document, also available as OpenAPI, lets clients in other
+// languages be generated; Go programs can use Client.
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/defs"
+)
+
+// Options configures the identification server.
+type Options struct {
+	MaxConcurrent   int      // Maximum number of concurrent identifications (default: 4).
+	MaxUploadSize   int64    // Maximum size of an uploaded file in bytes (default: 100 MB).
+	NumberOfMatches int      // Default number of matches when a request does not specify one (default: 5).
+	StreamHeadSize  int64    // Bytes of an upload to /identify/stream identified before the rest is received (default: 64 KB).
+	PathRoots       []string // Directories /identify/path and /scans may read from, symbolic links resolved; if empty, the endpoints are disabled.
+
+	// MaxQueue is the maximum number of identification requests waiting for
+	// one of the MaxConcurrent slots; further requests are rejected with 429
+	// (default: unlimited).
+	MaxQueue int
+
+	// RateLimit is the maximum number of identification requests per second
+	// of each client, with bursts of up to RateBurst requests (default:
+	// unlimited). Requests above it are rejected with 429.
+	RateLimit float64
+	RateBurst int // Default: RateLimit, at least 1.
+
+	// ClientKey identifies the client of a request for RateLimit (default:
+	// its tenant with Auth, or its remote IP address). Servers behind a proxy
+	// can use the address it forwards.
+	ClientKey func(r *http.Request) string
+
+	MaxScans      int           // Maximum number of scans started with POST /scans running at once (default: 4).
+	ScanRetention time.Duration // Time the status and events of a finished scan are kept (default: 1 hour).
+
+	// Auth, if set, requires an API key for identification, scan and
+	// definitions requests, sent as a bearer token or in the X-API-Key header,
+	// and enforces the quotas of its tenant. Scans are only visible to the
+	// tenant that started them. /readyz and /openapi.json remain open, for
+	// probes and client generators.
+	Auth *auth.Guard
+}
+
+// Server is an http.Handler serving the identification API.
+type Server struct {
+	trid    *trid.Trid
+	opts    Options
+	sem     chan struct{}
+	mux     *http.ServeMux
+	limiter *limiter
+	pending atomic.Int64 // Identification requests holding or waiting for a slot.
+	scansMu sync.Mutex
+	scans   map[string]*scan
+	ctx     context.Context // Context of the scans, cancelled by Close.
+	cancel  context.CancelFunc
+}
+
+// Response is the body of a successful identification response.
+type Response struct {
+	FileTypes []trid.FileType `json:"file_types"`
+}
+
+// ErrorResponse is the body of an error response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// PathRequest is the body of a POST /identify/path request.
+type PathRequest struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches,omitempty"`
+}
+
+// OpenAPI is the OpenAPI 3 document describing the API, as served at
+// /openapi.json.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// errPathNotAllowed is returned when a requested path is outside PathRoots.
+var errPathNotAllowed = errors.New("path is not allowed")
+
+// New returns a Server that identifies files with t.
+func New(t *trid.Trid, opts Options) *Server {
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 4
+	}
+
+	if opts.MaxUploadSize <= 0 {
+		opts.MaxUploadSize = 100 << 20
+	}
+
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	if opts.StreamHeadSize <= 0 {
+		opts.StreamHeadSize = 64 << 10
+	}
+
+	if opts.MaxScans < 1 {
+		opts.MaxScans = 4
+	}
+
+	if opts.ScanRetention <= 0 {
+		opts.ScanRetention = time.Hour
+	}
+
+	if opts.ClientKey == nil {
+		opts.ClientKey = clientKey
+	}
+
+	s := &Server{
+		trid:    t,
+		opts:    opts,
+		sem:     make(chan struct{}, opts.MaxConcurrent),
+		mux:     http.NewServeMux(),
+		limiter: newLimiter(opts.RateLimit, opts.RateBurst),
+		scans:   make(map[string]*scan),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.mux.HandleFunc("POST /identify", s.authenticated(s.limited(s.handleIdentify)))
+	s.mux.HandleFunc("POST /identify/path", s.authenticated(s.limited(s.handleIdentifyPath)))
+	s.mux.HandleFunc("POST /identify/stream", s.authenticated(s.limited(s.handleIdentifyStream)))
+	s.mux.HandleFunc("GET /defs/info", s.authenticated(s.handleDefsInfo))
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("POST /scans", s.authenticated(s.limited(s.handleStartScan)))
+	s.mux.HandleFunc("GET /scans/{id}", s.authenticated(s.handleScanStatus))
+	s.mux.HandleFunc("GET /scans/{id}/events", s.authenticated(s.handleScanEvents))
+	s.mux.HandleFunc("DELETE /scans/{id}", s.authenticated(s.handleCancelScan))
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleIdentify identifies an uploaded file.
+func (s *Server) handleIdentify(w http.ResponseWriter, r *http.Request) {
+	matches, err := s.matches(r.URL.Query().Get("matches"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize(r.Context()))
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, statusForBodyError(err), err)
+			return
+		}
+		defer f.Close()
+
+		body = f
+	}
+
+	path, err := spool(body)
+	if err != nil {
+		writeError(w, statusForBodyError(err), err)
+		return
+	}
+	defer os.Remove(path)
+
+	s.identify(w, r.Context(), path, matches)
+}
+
+// authenticated returns a handler admitting requests with Options.Auth before
+// calling h, with the tenant in the request context. Requests are rejected
+// with 401 without a valid key, and 429 above the rate quota of the tenant.
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	if s.opts.Auth == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := s.opts.Auth.Admit(r.Context(), auth.KeyFromRequest(r))
+
+		var quotaErr *auth.QuotaError
+		switch {
+		case errors.Is(err, auth.ErrUnauthenticated):
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		case errors.As(err, &quotaErr):
+			tooManyRequests(w, quotaErr.RetryAfter, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		h(w, r.WithContext(auth.NewContext(r.Context(), tenant)))
+	}
+}
+
+// maxUploadSize returns the maximum size of an upload by the tenant of ctx.
+func (s *Server) maxUploadSize(ctx context.Context) int64 {
+	if tenant, ok := auth.FromContext(ctx); ok && tenant.Quota.MaxUploadSize > 0 {
+		return min(s.opts.MaxUploadSize, tenant.Quota.MaxUploadSize)
+	}
+
+	return s.opts.MaxUploadSize
+}
+
+// handleIdentifyPath identifies a file on the server's file system.
+func (s *Server) handleIdentifyPath(w http.ResponseWriter, r *http.Request) {
+	var req PathRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Matches == 0 {
+		req.Matches = s.opts.NumberOfMatches
+	}
+
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, trid.ErrNoFileSpecified)
+		return
+	}
+
+	if !trid.WithinRoots(req.Path, s.opts.PathRoots) {
+		writeError(w, http.StatusForbidden, errPathNotAllowed)
+		return
+	}
+
+	s.identify(w, r.Context(), req.Path, req.Matches)
+}
+
+// handleDefsInfo describes the definitions package in use.
+func (s *Server) handleDefsInfo(w http.ResponseWriter, r *http.Request) {
+	opts := s.trid.Options()
+
+	path, err := defs.Locate(opts.Cmd, opts.Definitions)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	info, err := defs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeError(w, http.StatusNotFound, trid.ErrNoDefinitions)
+			return
+		}
+
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleReady responds with 204 if TrID is usable and 503 otherwise.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := s.trid.Probe(r.Context()); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleOpenAPI serves the OpenAPI document.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+// identify scans path while holding a concurrency slot and writes the result.
+func (s *Server) identify(w http.ResponseWriter, ctx context.Context, path string, matches int) {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		writeError(w, http.StatusServiceUnavailable, ctx.Err())
+		return
+	}
+
+	fileTypes, err := s.trid.ScanContext(ctx, path, matches)
+	if err != nil {
+		writeError(w, statusForScanError(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Response{FileTypes: fileTypes})
+}
+
+// matches parses the number of matches requested, applying the default.
+func (s *Server) matches(v string) (int, error) {
+	if v == "" {
+		return s.opts.NumberOfMatches, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, trid.ErrNumberOfMatches
+	}
+
+	return n, nil
+}
+
+// spool writes r to a temporary file and returns its path.
+func spool(r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp("", "trid-server-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// statusForBodyError maps errors reading a request body to a status code.
+func statusForBodyError(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// statusForScanError maps scan errors to a status code.
+func statusForScanError(err error) int {
+	switch {
+	case errors.Is(err, trid.ErrNoFileSpecified), errors.Is(err, trid.ErrNumberOfMatches):
+		return http.StatusBadRequest
+	case errors.Is(err, trid.ErrFileNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, trid.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, trid.ErrUnknownFileType), errors.Is(err, trid.ErrEmptyFile):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, trid.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, trid.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/defs"
+)
+
+const pdfOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: sample.pdf
+100.0% (.PDF) Adobe Portable Document Format (5000/1)
+        Mime type       : application/pdf
+        Definition      : pdf-generic.trid.xml
+`
+
+// newTestServer returns a server backed by a stub TrID executable that
+// prints pdfOutput.
+func newTestServer(t *testing.T, opts Options) (*Server, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "output.txt"), []byte(pdfOutput), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\ncat '" + filepath.Join(dir, "output.txt") + "'\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "triddefs.trd"), []byte("defs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(trid.NewTrid(trid.Options{Cmd: cmd}), opts), dir
+}
+
+func TestIdentify(t *testing.T) {
+	s, _ := newTestServer(t, Options{})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "sample.pdf")
+	fw.Write([]byte("%PDF-1.4"))
+	mw.Close()
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           []byte
+		query          string
+		expectedStatus int
+	}{
+		{"Multipart upload", mw.FormDataContentType(), body.Bytes(), "", http.StatusOK},
+		{"Raw body", "application/octet-stream", []byte("%PDF-1.4"), "?matches=2", http.StatusOK},
+		{"Invalid matches", "application/octet-stream", []byte("%PDF-1.4"), "?matches=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/identify"+tt.query, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp Response
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp.FileTypes) != 1 || resp.FileTypes[0].Extension != ".pdf" {
+				t.Errorf("unexpected file types: %+v", resp.FileTypes)
+			}
+		})
+	}
+}
+
+func TestIdentifyTooLarge(t *testing.T) {
+	s, _ := newTestServer(t, Options{MaxUploadSize: 4})
+
+	req := httptest.NewRequest(http.MethodPost, "/identify", bytes.NewReader([]byte("%PDF-1.4")))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestIdentifyPath(t *testing.T) {
+	root := t.TempDir()
+	sample := filepath.Join(root, "sample.pdf")
+	if err := os.WriteFile(sample, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A symbolic link inside the root to a file outside of it
+	outside := filepath.Join(t.TempDir(), "outside.pdf")
+	if err := os.WriteFile(outside, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link.pdf")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip(err)
+	}
+
+	s, _ := newTestServer(t, Options{PathRoots: []string{root}})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"Allowed path", sample, http.StatusOK},
+		{"Missing path", "", http.StatusBadRequest},
+		{"Missing file", filepath.Join(root, "missing.pdf"), http.StatusNotFound},
+		{"Path outside roots", filepath.Join(root, "..", "other.pdf"), http.StatusForbidden},
+		{"Symbolic link out of the roots", link, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(PathRequest{Path: tt.path})
+			req := httptest.NewRequest(http.MethodPost, "/identify/path", bytes.NewReader(body))
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDefsInfo(t *testing.T) {
+	s, dir := newTestServer(t, Options{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/defs/info", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var info struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Path != filepath.Join(dir, "triddefs.trd") || info.Size != 4 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
+
+func TestReady(t *testing.T) {
+	s, dir := newTestServer(t, Options{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+
+	if err := os.Remove(filepath.Join(dir, "triddefs.trd")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d (%s)", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	s, _ := newTestServer(t, Options{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var doc struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every documented operation is served
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(strings.ToUpper(method), path, nil))
+
+			if rec.Code == http.StatusNotFound && rec.Header().Get("Content-Type") != "application/json" || rec.Code == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s is documented but not served (%d)", strings.ToUpper(method), path, rec.Code)
+			}
+		}
+	}
+
+	// Schemas have the fields of the types they document
+	for name, v := range map[string]any{"FileType": trid.FileType{}, "Response": Response{}, "ErrorResponse": ErrorResponse{}, "PathRequest": PathRequest{}, "DefsInfo": defs.Info{}, "ScanRequest": ScanRequest{}, "ScanStatus": ScanStatus{}, "ScanResult": ScanResult{}, "StreamResult": StreamResult{}} {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			field, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if _, ok := doc.Components.Schemas[name].Properties[field]; !ok {
+				t.Errorf("schema %s does not document field %q", name, field)
+			}
+		}
+
+		if got, expected := len(doc.Components.Schemas[name].Properties), typ.NumField(); got != expected {
+			t.Errorf("schema %s has %d properties, want %d", name, got, expected)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	guard := auth.NewGuard(auth.StaticKeys{
+		"secret": {Name: "tenant", Quota: auth.Quota{RequestsPerSecond: 0.001, Burst: 2, MaxUploadSize: 4}},
+	})
+	s, _ := newTestServer(t, Options{Auth: guard})
+
+	tests := []struct {
+		name           string
+		path           string
+		key            string
+		body           string
+		expectedStatus int
+	}{
+		{"Open endpoint", "/readyz", "", "", http.StatusNoContent},
+		{"Missing key", "/identify", "", "%PDF", http.StatusUnauthorized},
+		{"Invalid key", "/identify", "other", "%PDF", http.StatusUnauthorized},
+		{"Valid key", "/identify", "secret", "%PDF", http.StatusOK},
+		{"Above the size quota", "/identify", "secret", "%PDF-1.4", http.StatusRequestEntityTooLarge},
+		{"Above the rate quota", "/identify", "secret", "%PDF", http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tt.body == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, tt.path, strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+
+			if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+				t.Error("429 response without Retry-After")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s, _ := newTestServer(t, Options{RateLimit: 0.001, RateBurst: 1})
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{"First request", "192.0.2.1:1234", http.StatusOK},
+		{"Same client", "192.0.2.1:5678", http.StatusTooManyRequests},
+		{"Other client", "192.0.2.2:1234", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/identify", strings.NewReader("%PDF"))
+			req.RemoteAddr = tt.remoteAddr
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+
+			if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+				t.Error("429 response without Retry-After")
+			}
+		})
+	}
+}
+
+func TestLimiterPrunesIdleClients(t *testing.T) {
+	l := newLimiter(1, 1)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.allow("a")
+	now = now.Add(2 * time.Minute)
+	l.allow("b")
+
+	if _, ok := l.clients["a"]; ok || len(l.clients) != 1 {
+		t.Errorf("clients = %v, want only b", l.clients)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	s, _ := newTestServer(t, Options{MaxConcurrent: 1, MaxQueue: 1})
+
+	// One request holds the slot and another waits for it
+	s.pending.Add(2)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/identify", strings.NewReader("%PDF")))
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("status = %d, Retry-After = %q, want 429 and 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	s.pending.Add(-1)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/identify", strings.NewReader("%PDF")))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if got := s.pending.Load(); got != 1 {
+		t.Errorf("pending = %d after the request, want 1", got)
+	}
+}
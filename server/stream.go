@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/attilabuti/trid"
+)
+
+// Stages of the results of POST /identify/stream.
+const (
+	StageProvisional = "provisional" // From the first Options.StreamHeadSize bytes of the upload.
+	StageFinal       = "final"       // From the whole upload.
+)
+
+// StreamResult is a line of a POST /identify/stream response.
+type StreamResult struct {
+	Stage     string          `json:"stage"`
+	Bytes     int64           `json:"bytes"`
+	FileTypes []trid.FileType `json:"file_types"`
+	Error     string          `json:"error,omitempty"`
+
+	// Confirmed reports, in a final result following a provisional one,
+	// whether the most likely file type is the provisional one.
+	Confirmed *bool `json:"confirmed,omitempty"`
+}
+
+// handleIdentifyStream identifies an upload from its first bytes while the
+// rest is received, then again once it is complete. The response is a stream
+// of JSON lines: a provisional result, unless the upload is smaller than
+// Options.StreamHeadSize, and a final one. Once the first bytes have been
+// received, the status is 200 and errors are reported in the final result.
+func (s *Server) handleIdentifyStream(w http.ResponseWriter, r *http.Request) {
+	matches, err := s.matches(r.URL.Query().Get("matches"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize(ctx))
+
+	// Results are written while the body is read; HTTP/2 always allows it
+	http.NewResponseController(w).EnableFullDuplex()
+
+	tmp, err := os.CreateTemp("", "trid-server-*")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	head, err := io.CopyN(tmp, r.Body, s.opts.StreamHeadSize)
+	if errors.Is(err, io.EOF) {
+		// The whole upload is received, so a provisional result is pointless
+		fileTypes, err := s.scanUpload(ctx, tmp.Name(), 0, matches)
+		if err != nil {
+			writeError(w, statusForScanError(err), err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		writeStreamResult(w, StreamResult{Stage: StageFinal, Bytes: head, FileTypes: fileTypes})
+		return
+	}
+
+	if err != nil {
+		writeError(w, statusForBodyError(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	var provisional []trid.FileType
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+
+		var err error
+		provisional, err = s.scanUpload(ctx, tmp.Name(), head, matches)
+		writeStreamResult(w, newStreamResult(StageProvisional, head, provisional, err))
+	}()
+
+	rest, err := io.Copy(tmp, r.Body)
+	if err == nil {
+		err = tmp.Close()
+	}
+	<-sent
+
+	var fileTypes []trid.FileType
+	if err == nil {
+		fileTypes, err = s.scanUpload(ctx, tmp.Name(), 0, matches)
+	}
+
+	final := newStreamResult(StageFinal, head+rest, fileTypes, err)
+	if err == nil {
+		confirmed := len(provisional) > 0 && len(fileTypes) > 0 &&
+			provisional[0].Extension == fileTypes[0].Extension && provisional[0].Name == fileTypes[0].Name
+		final.Confirmed = &confirmed
+	}
+
+	writeStreamResult(w, final)
+}
+
+// scanUpload identifies the file at path, or its first length bytes if length
+// is positive, while holding a concurrency slot.
+func (s *Server) scanUpload(ctx context.Context, path string, length int64, matches int) ([]trid.FileType, error) {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if length > 0 {
+		return s.trid.ScanAt(ctx, path, 0, length, matches)
+	}
+
+	return s.trid.ScanContext(ctx, path, matches)
+}
+
+// newStreamResult returns the result of a stage identifying size bytes.
+func newStreamResult(stage string, size int64, fileTypes []trid.FileType, err error) StreamResult {
+	r := StreamResult{Stage: stage, Bytes: size, FileTypes: fileTypes}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	return r
+}
+
+// writeStreamResult writes r as a JSON line and flushes it to the client.
+func writeStreamResult(w http.ResponseWriter, r StreamResult) {
+	json.NewEncoder(w).Encode(r)
+	http.NewResponseController(w).Flush()
+}
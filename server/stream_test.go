@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdentifyStream(t *testing.T) {
+	s, _ := newTestServer(t, Options{StreamHeadSize: 4})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, pw := io.Pipe()
+	provisional := make(chan StreamResult, 1)
+
+	type result struct {
+		exts []string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		fileTypes, err := c.IdentifyStream(context.Background(), pr, 0, func(r StreamResult) { provisional <- r })
+
+		var exts []string
+		for _, ft := range fileTypes {
+			exts = append(exts, ft.Extension)
+		}
+		done <- result{exts, err}
+	}()
+
+	if _, err := io.WriteString(pw, "%PDF"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The provisional result arrives while the upload is still open
+	select {
+	case r := <-provisional:
+		if r.Stage != StageProvisional || r.Bytes != 4 || len(r.FileTypes) != 1 || r.FileTypes[0].Extension != ".pdf" {
+			t.Errorf("provisional result = %+v", r)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no provisional result before the end of the upload")
+	}
+
+	io.WriteString(pw, "-1.4 rest of the document")
+	pw.Close()
+
+	select {
+	case r := <-done:
+		if r.err != nil || len(r.exts) != 1 || r.exts[0] != ".pdf" {
+			t.Errorf("IdentifyStream() = %v, %v", r.exts, r.err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no final result")
+	}
+}
+
+func TestIdentifyStreamResponses(t *testing.T) {
+	s, _ := newTestServer(t, Options{StreamHeadSize: 4, MaxUploadSize: 8})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedStages []string
+		expectedError  string
+	}{
+		{"Smaller than the head", "%PD", http.StatusOK, []string{StageFinal}, ""},
+		{"Larger than the head", "%PDF-1.4", http.StatusOK, []string{StageProvisional, StageFinal}, ""},
+		{"Too large after the head", "%PDF-1.4 and more", http.StatusOK, []string{StageProvisional, StageFinal}, "request body too large"},
+		{"Empty", "", http.StatusUnprocessableEntity, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/identify/stream", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var results []StreamResult
+			sc := bufio.NewScanner(rec.Body)
+			for sc.Scan() {
+				var r StreamResult
+				if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+					t.Fatal(err)
+				}
+				results = append(results, r)
+			}
+
+			var stages []string
+			for _, r := range results {
+				stages = append(stages, r.Stage)
+			}
+			if strings.Join(stages, ",") != strings.Join(tt.expectedStages, ",") {
+				t.Fatalf("stages = %v, want %v", stages, tt.expectedStages)
+			}
+
+			final := results[len(results)-1]
+			if !strings.Contains(final.Error, tt.expectedError) || tt.expectedError == "" && final.Error != "" {
+				t.Errorf("final error = %q, want %q", final.Error, tt.expectedError)
+			}
+
+			if expected := len(results) > 1 && final.Error == ""; (final.Confirmed != nil && *final.Confirmed) != expected {
+				t.Errorf("final confirmed = %v, want %v", final.Confirmed, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Sources of matches found without TrID, reported in FileType.Source.
+const (
+	SourceSniff     = "sniff"     // Content sniffing with http.DetectContentType.
+	SourceExtension = "extension" // The file extension, with mime.TypeByExtension.
+)
+
+// Probabilities of matches found without TrID, well below those of TrID
+// matches of any significance.
+const (
+	sniffProbability     = 10
+	extensionProbability = 5
+)
+
+// canFallback reports whether the standard library may identify a file TrID
+// failed to identify with err.
+func canFallback(err error) bool {
+	switch errorClass(err) {
+	case "unknown_type", "unavailable", "executable":
+		return true
+	default:
+		return false
+	}
+}
+
+// fallback identifies the file at path with the standard library after TrID
+// failed with err, if Options.Fallback is set. It returns err if the file
+// cannot be identified this way either.
+func (t *Trid) fallback(ctx context.Context, path string, err error) (*Report, error) {
+	if !t.options.Fallback || !canFallback(err) {
+		return nil, err
+	}
+
+	fileTypes := sniff(path)
+	if len(fileTypes) == 0 {
+		return nil, err
+	}
+
+	t.log(ctx, slog.LevelDebug, "identified file without TrID", "path", path, "error", err, "matches", len(fileTypes))
+
+	return &Report{FileTypes: fileTypes}, nil
+}
+
+// sniff identifies the file at path from its first 512 bytes and its
+// extension, returning low-confidence matches.
+func sniff(path string) []FileType {
+	var fileTypes []FileType
+
+	if head, err := readHead(path, 512); err == nil {
+		// application/octet-stream carries no information
+		if mimeType := mediaType(http.DetectContentType(head)); mimeType != "application/octet-stream" {
+			fileTypes = append(fileTypes, FileType{
+				Extension:   extensionForType(mimeType),
+				Probability: sniffProbability,
+				Name:        "Detected by content sniffing",
+				MimeType:    mimeType,
+				Source:      SourceSniff,
+			})
+		}
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext != "" {
+		if mimeType := mediaType(mime.TypeByExtension(ext)); mimeType != "" {
+			fileTypes = append(fileTypes, FileType{
+				Extension:   ext,
+				Probability: extensionProbability,
+				Name:        "Guessed from the file extension",
+				MimeType:    mimeType,
+				Source:      SourceExtension,
+			})
+		}
+	}
+
+	return fileTypes
+}
+
+// readHead returns up to n bytes from the beginning of the file at path.
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, n)
+	read, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return head[:read], nil
+}
+
+// mediaType returns the media type of a MIME type without parameters.
+func mediaType(mimeType string) string {
+	if t, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return t
+	}
+
+	return mimeType
+}
+
+// extensionForType returns the first extension, in lexical order, associated
+// with mimeType, or "" if there is none.
+func extensionForType(mimeType string) string {
+	exts, err := mime.ExtensionsByType(mimeType)
+	if err != nil || len(exts) == 0 {
+		return ""
+	}
+
+	return slices.Min(exts)
+}
@@ -0,0 +1,66 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	unknown := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: unknownOutput}, nil
+	})
+
+	expected := []FileType{
+		{Extension: ".pdf", Probability: sniffProbability, Name: "Detected by content sniffing", MimeType: "application/pdf", Source: SourceSniff},
+		{Extension: ".pdf", Probability: extensionProbability, Name: "Guessed from the file extension", MimeType: "application/pdf", Source: SourceExtension},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"Unknown file type", Options{Cmd: "trid", Runner: unknown, Fallback: true}},
+		{"Missing executable", Options{Cmd: filepath.Join(t.TempDir(), "trid"), Fallback: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(tt.opts)
+
+			fileTypes, err := trid.Scan("testdata/sample.pdf", 5)
+			if err != nil || len(fileTypes) != 2 || fileTypes[0] != expected[0] || fileTypes[1] != expected[1] {
+				t.Errorf("Scan() = %+v, %v, want %+v", fileTypes, err, expected)
+			}
+
+			results, err := trid.ScanBatch(context.Background(), []string{"testdata/sample.pdf"}, 5)
+			if err != nil || results[0].Err != nil || len(results[0].FileTypes) != 2 {
+				t.Errorf("ScanBatch() = %+v, %v", results, err)
+			}
+		})
+	}
+
+	trid := NewTrid(Options{Cmd: "trid", Runner: unknown})
+	if _, err := trid.Scan("testdata/sample.pdf", 5); !errors.Is(err, ErrUnknownFileType) {
+		t.Errorf("Scan() without Fallback error = %v, want ErrUnknownFileType", err)
+	}
+}
+
+func TestSniff(t *testing.T) {
+	dir := t.TempDir()
+	opaque := filepath.Join(dir, "data")
+	os.WriteFile(opaque, []byte{0x00, 0x01, 0x02, 0xff}, 0o644)
+
+	if fileTypes := sniff(opaque); len(fileTypes) != 0 {
+		t.Errorf("sniff() of opaque data = %+v, want no matches", fileTypes)
+	}
+
+	text := filepath.Join(dir, "notes.unknown-ext")
+	os.WriteFile(text, []byte("hello world\n"), 0o644)
+
+	if fileTypes := sniff(text); len(fileTypes) != 1 || fileTypes[0].MimeType != "text/plain" {
+		t.Errorf("sniff() of text = %+v, want text/plain", fileTypes)
+	}
+}
@@ -0,0 +1,97 @@
+package trid
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Summary aggregates the results of a batch or a walk: how many files of each
+// type, category and extension were found, the bytes of each type, and how
+// many files could not be identified. Files are counted by their most likely
+// match.
+type Summary struct {
+	Files      int                   `json:"files"`      // Number of files.
+	Bytes      int64                 `json:"bytes"`      // Total size of the files that could be read.
+	Unknown    int                   `json:"unknown"`    // Number of files of an unknown type.
+	Errors     int                   `json:"errors"`     // Number of files that could not be identified for other reasons.
+	Types      map[string]*TypeCount `json:"types"`      // Identified files by name of the file type.
+	Categories map[Category]int      `json:"categories"` // Number of identified files by category.
+	Extensions map[string]int        `json:"extensions"` // Number of identified files by lower case extension.
+}
+
+// TypeCount is the number and total size of the files of a file type.
+type TypeCount struct {
+	Extension string `json:"extension"` // File extension, as reported by TrID.
+	Files     int    `json:"files"`     // Number of files.
+	Bytes     int64  `json:"bytes"`     // Total size of the files.
+}
+
+// Summarize returns the summary of results, as returned by ScanBatch. The
+// size of each file is read from the file system.
+func Summarize(results []BatchResult) *Summary {
+	s := &Summary{}
+	for _, r := range results {
+		s.Add(r)
+	}
+
+	return s
+}
+
+// Add counts r in s, for results passed one at a time, as by ScanWalk.
+func (s *Summary) Add(r BatchResult) {
+	if s.Types == nil {
+		s.Types = make(map[string]*TypeCount)
+		s.Categories = make(map[Category]int)
+		s.Extensions = make(map[string]int)
+	}
+
+	var size int64
+	if info, err := os.Stat(r.Path); err == nil {
+		size = info.Size()
+	}
+
+	s.Files++
+	s.Bytes += size
+
+	best, ok := Results(r.FileTypes).Best()
+	switch {
+	case r.Err != nil && !errors.Is(r.Err, ErrUnknownFileType):
+		s.Errors++
+		return
+	case !ok:
+		s.Unknown++
+		return
+	}
+
+	tc := s.Types[best.Name]
+	if tc == nil {
+		tc = &TypeCount{Extension: best.Extension}
+		s.Types[best.Name] = tc
+	}
+	tc.Files++
+	tc.Bytes += size
+
+	s.Categories[best.Category()]++
+	s.Extensions[strings.ToLower(best.Extension)]++
+}
+
+// UnknownRate returns the share of files of an unknown type, from 0 to 1.
+func (s *Summary) UnknownRate() float64 {
+	return s.rate(s.Unknown)
+}
+
+// ErrorRate returns the share of files that could not be identified for
+// reasons other than an unknown type, from 0 to 1.
+func (s *Summary) ErrorRate() float64 {
+	return s.rate(s.Errors)
+}
+
+// rate returns n as a share of the files.
+func (s *Summary) rate(n int) float64 {
+	if s.Files == 0 {
+		return 0
+	}
+
+	return float64(n) / float64(s.Files)
+}
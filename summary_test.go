@@ -0,0 +1,56 @@
+package trid
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	paths := writeFiles(t, "%PDF-1", "%PDF-12", "GIF89a", "binary", "")
+
+	pdf := []FileType{{Name: "Adobe Portable Document Format", Extension: ".PDF", Probability: 100}}
+	s := Summarize([]BatchResult{
+		{Path: paths[0], FileTypes: pdf},
+		{Path: paths[1], FileTypes: pdf},
+		{Path: paths[2], FileTypes: []FileType{{Name: "GIF", Extension: ".gif", Probability: 100}}},
+		{Path: paths[3], Err: ErrUnknownFileType},
+		{Path: paths[4], Err: ErrEmptyFile},
+	})
+
+	if s.Files != 5 || s.Bytes != 25 || s.Unknown != 1 || s.Errors != 1 {
+		t.Errorf("Summarize() = %d files, %d bytes, %d unknown, %d errors, want 5, 25, 1, 1", s.Files, s.Bytes, s.Unknown, s.Errors)
+	}
+
+	if tc := s.Types["Adobe Portable Document Format"]; tc == nil || tc.Files != 2 || tc.Bytes != 13 || tc.Extension != ".PDF" {
+		t.Errorf("Types[pdf] = %+v, want 2 files of 13 bytes", tc)
+	}
+
+	if s.Categories[CategoryDocument] != 2 || s.Categories[CategoryImage] != 1 {
+		t.Errorf("Categories = %v", s.Categories)
+	}
+
+	if s.Extensions[".pdf"] != 2 || s.Extensions[".gif"] != 1 {
+		t.Errorf("Extensions = %v", s.Extensions)
+	}
+
+	if got := s.UnknownRate(); math.Abs(got-0.2) > 1e-9 {
+		t.Errorf("UnknownRate() = %v, want 0.2", got)
+	}
+
+	if got := s.ErrorRate(); math.Abs(got-0.2) > 1e-9 {
+		t.Errorf("ErrorRate() = %v, want 0.2", got)
+	}
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	var s Summary
+	if s.UnknownRate() != 0 || s.ErrorRate() != 0 {
+		t.Error("rates of an empty summary are not 0")
+	}
+
+	s.Add(BatchResult{Path: "missing", Err: errors.New("boom")})
+	if s.Files != 1 || s.Errors != 1 || s.Bytes != 0 {
+		t.Errorf("Add() = %+v, want 1 failed file", s)
+	}
+}
@@ -0,0 +1,142 @@
+package trid
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Reasons a file name is deceptive, reported in Suspicion.Reasons.
+const (
+	// SuspicionDoubleExtension flags a harmless-looking extension followed by
+	// a dangerous one, as in "invoice.pdf.exe".
+	SuspicionDoubleExtension = "double_extension"
+
+	// SuspicionPadding flags white space hiding the real extension, as in
+	// "invoice.pdf          .exe".
+	SuspicionPadding = "padding"
+
+	// SuspicionBidi flags Unicode bidirectional control characters reordering
+	// the displayed name, as in "invoice\u202efdp.exe", with a right-to-left
+	// override, displayed as "invoiceexe.pdf".
+	SuspicionBidi = "bidi_override"
+)
+
+// minPadding is the length of the run of white space before an extension
+// considered to hide it.
+const minPadding = 3
+
+// dangerousExts are the extensions Windows runs or opens with a script host.
+var dangerousExts = map[string]bool{
+	".exe": true, ".scr": true, ".com": true, ".pif": true, ".bat": true, ".cmd": true, ".cpl": true,
+	".msi": true, ".js": true, ".jse": true, ".vbs": true, ".vbe": true, ".wsf": true, ".wsh": true,
+	".hta": true, ".lnk": true, ".ps1": true, ".jar": true, ".reg": true, ".dll": true,
+}
+
+// bidiControls are the Unicode bidirectional formatting characters.
+var bidiControls = []rune{
+	'\u200e', '\u200f', '\u061c', // Marks
+	'\u202a', '\u202b', '\u202c', '\u202d', '\u202e', // Embeddings and overrides
+	'\u2066', '\u2067', '\u2068', '\u2069', // Isolates
+}
+
+// Suspicion describes a deceptive file name, with Options.SuspiciousNames.
+type Suspicion struct {
+	Reasons   []string `json:"reasons"`   // Why the name is deceptive, such as SuspicionDoubleExtension.
+	Displayed string   `json:"displayed"` // Name as displayed, with right-to-left overrides applied and other bidirectional controls removed.
+	Apparent  string   `json:"apparent"`  // Extension the displayed name suggests (e.g., ".pdf").
+	Actual    string   `json:"actual"`    // Actual extension of the name (e.g., ".exe").
+}
+
+// CheckName reports whether the file name, or base name of a path, is
+// deceptive given the identified file types of the file, most likely first,
+// and returns nil otherwise. Names holding bidirectional control characters
+// are always deceptive. Double extensions and extensions hidden by white
+// space are deceptive if the last extension is dangerous, such as ".exe" or
+// ".js", or the file is identified as an executable, and the extension before
+// it belongs to a harmless category, such as a document or image; names like
+// "backup.tar.gz" are not flagged.
+//
+// The Scan methods check the base name of the path they are given; uploads
+// stored under another name are checked by calling CheckName with the name
+// given by the client.
+func CheckName(name string, fileTypes []FileType) *Suspicion {
+	name = filepath.Base(name)
+	s := &Suspicion{Displayed: displayedName(name), Actual: strings.ToLower(filepath.Ext(name))}
+	s.Apparent = strings.ToLower(filepath.Ext(s.Displayed))
+
+	if strings.ContainsFunc(name, func(r rune) bool { return slices.Contains(bidiControls, r) }) {
+		s.Reasons = append(s.Reasons, SuspicionBidi)
+	}
+
+	executable := len(fileTypes) > 0 && fileTypes[0].Category() == CategoryExecutable
+	if dangerousExts[s.Actual] || executable {
+		stem := strings.TrimSuffix(s.Displayed, filepath.Ext(s.Displayed))
+		trimmed := strings.TrimRightFunc(stem, unicode.IsSpace)
+
+		if decoy := strings.ToLower(filepath.Ext(trimmed)); isDecoy(decoy) {
+			s.Reasons = append(s.Reasons, SuspicionDoubleExtension)
+			if len([]rune(stem))-len([]rune(trimmed)) >= minPadding {
+				s.Reasons = append(s.Reasons, SuspicionPadding)
+			}
+		}
+	}
+
+	if len(s.Reasons) == 0 {
+		return nil
+	}
+
+	return s
+}
+
+// isDecoy reports whether ext is the extension of a harmless category of
+// files, which a dangerous one may hide behind.
+func isDecoy(ext string) bool {
+	switch (FileType{Extension: ext}).Category() {
+	case CategoryDocument, CategoryImage, CategoryAudio, CategoryVideo, CategoryText, CategoryArchive:
+		return true
+	default:
+		return false
+	}
+}
+
+// displayedName returns name as displayed: the text following a right-to-left
+// override, up to the end of the override, is reversed, and bidirectional
+// controls are removed. Other reorderings are not simulated.
+func displayedName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\u202e' {
+			if !slices.Contains(bidiControls, runes[i]) {
+				b.WriteRune(runes[i])
+			}
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && runes[end] != '\u202c' {
+			end++
+		}
+
+		for j := end - 1; j > i; j-- {
+			if !slices.Contains(bidiControls, runes[j]) {
+				b.WriteRune(runes[j])
+			}
+		}
+		i = end
+	}
+
+	return b.String()
+}
+
+// checkName is CheckName for the Scan methods, if Options.SuspiciousNames is
+// set.
+func (t *Trid) checkName(path string, fileTypes []FileType) *Suspicion {
+	if !t.options.SuspiciousNames {
+		return nil
+	}
+
+	return CheckName(path, fileTypes)
+}
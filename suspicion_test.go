@@ -0,0 +1,77 @@
+package trid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckName(t *testing.T) {
+	exe := []FileType{{Extension: ".exe", Name: "Win32 Executable (generic)"}}
+	pdf := []FileType{{Extension: ".pdf", Name: "Adobe Portable Document Format"}}
+
+	tests := []struct {
+		name      string
+		fileTypes []FileType
+		reasons   []string
+		displayed string
+		apparent  string
+	}{
+		{"invoice.pdf.exe", exe, []string{SuspicionDoubleExtension}, "invoice.pdf.exe", ".exe"},
+		{"/uploads/Invoice.PDF.js", pdf, []string{SuspicionDoubleExtension}, "Invoice.PDF.js", ".js"},
+		{"invoice.pdf      .exe", exe, []string{SuspicionDoubleExtension, SuspicionPadding}, "invoice.pdf      .exe", ".exe"},
+		{"photo.jpg.bin", exe, []string{SuspicionDoubleExtension}, "photo.jpg.bin", ".bin"},
+		{"invoice\u202efdp.exe", exe, []string{SuspicionBidi}, "invoiceexe.pdf", ".pdf"},
+		{"report\u202egpj.scr\u202c", exe, []string{SuspicionBidi}, "reportrcs.jpg", ".jpg"},
+		{"invoice\u200e.pdf.exe", exe, []string{SuspicionBidi, SuspicionDoubleExtension}, "invoice.pdf.exe", ".exe"},
+
+		// Not deceptive
+		{"invoice.pdf", pdf, nil, "", ""},
+		{"setup.exe", exe, nil, "", ""},
+		{"backup.tar.gz", []FileType{{Extension: ".gz"}}, nil, "", ""},
+		{"report.v2.pdf", pdf, nil, "", ""},
+		{"notes.txt.bak", []FileType{{Extension: ".txt"}}, nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		s := CheckName(tt.name, tt.fileTypes)
+		if tt.reasons == nil {
+			if s != nil {
+				t.Errorf("CheckName(%q) = %+v, want nil", tt.name, s)
+			}
+			continue
+		}
+
+		if s == nil || !slices.Equal(s.Reasons, tt.reasons) || s.Displayed != tt.displayed || s.Apparent != tt.apparent {
+			t.Errorf("CheckName(%q) = %+v, want reasons %v, displayed %q, apparent %q", tt.name, s, tt.reasons, tt.displayed, tt.apparent)
+		}
+	}
+}
+
+func TestScanSuspiciousNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.pdf.exe")
+	if err := os.WriteFile(path, []byte("MZ"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := RunnerFunc(func(ctx context.Context, c Command) (CommandResult, error) {
+		return CommandResult{Stdout: exeOutput}, nil
+	})
+	tr := NewTrid(Options{Cmd: "trid", Runner: runner, SuspiciousNames: true})
+
+	r, err := tr.ScanReport(context.Background(), path, 1)
+	if err != nil || r.Suspicion == nil || r.Suspicion.Actual != ".exe" {
+		t.Errorf("ScanReport() = %+v, %v, want suspicion", r, err)
+	}
+
+	results, err := tr.ScanBatch(context.Background(), []string{path}, 1)
+	if err != nil || results[0].Suspicion == nil {
+		t.Errorf("ScanBatch() = %+v, %v, want suspicion", results, err)
+	}
+
+	if r, err := NewTrid(Options{Cmd: "trid", Runner: runner}).ScanReport(context.Background(), path, 1); err != nil || r.Suspicion != nil {
+		t.Errorf("ScanReport() without SuspiciousNames = %+v, %v", r, err)
+	}
+}
@@ -0,0 +1,191 @@
+package trid
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrIsSymlink is returned for symbolic links when Options.Symlinks is
+// SymlinkRefuse.
+var ErrIsSymlink = errors.New("file is a symbolic link")
+
+// SymlinkPolicy selects how symbolic links are handled.
+type SymlinkPolicy int
+
+const (
+	// SymlinkFollow passes symbolic links to TrID, which identifies the file
+	// they point to.
+	SymlinkFollow SymlinkPolicy = iota
+
+	// SymlinkRefuse rejects paths going through a symbolic link with
+	// ErrIsSymlink, so links placed in upload directories cannot expose other
+	// files. Every element of a path is checked, including the directories
+	// leading to the file, so paths must be given in their resolved form.
+	SymlinkRefuse
+
+	// SymlinkTarget resolves symbolic links and passes the target path to
+	// TrID, so error messages and command lines name the identified file.
+	SymlinkTarget
+)
+
+// WithinRoots reports whether path lies within one of roots once symbolic
+// links are resolved in both, so that links inside a root cannot expose files
+// outside of it. Trailing elements of path that do not exist are kept as
+// they are, so the paths of missing files can be checked too.
+func WithinRoots(path string, roots []string) bool {
+	real, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+
+	for _, root := range roots {
+		root, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+
+		if rel, err := filepath.Rel(root, real); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLinked reports whether path, made absolute, goes through a symbolic link
+// in any of its elements.
+func isLinked(path string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return false, err
+	}
+
+	return real != abs, nil
+}
+
+// resolvePath returns the absolute form of path with symbolic links resolved
+// in its longest existing prefix.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return real, err
+	}
+
+	dir := filepath.Dir(abs)
+	if dir == abs {
+		return "", err
+	}
+
+	parent, err := resolvePath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parent, filepath.Base(abs)), nil
+}
+
+// Walk returns the regular files in the tree rooted at root, in lexical order.
+// Symbolic links are skipped with SymlinkRefuse and followed otherwise;
+// directories are visited once, so symbolic link cycles are broken. Links that
+// cannot be resolved are skipped.
+func Walk(root string, symlinks SymlinkPolicy) ([]string, error) {
+	entries, err := walkTree(root, symlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.err != nil {
+			return nil, e.err
+		}
+
+		paths = append(paths, e.path)
+	}
+
+	return paths, nil
+}
+
+// walkEntry is a regular file listed by walkTree, or a directory that could
+// not be read, with the error.
+type walkEntry struct {
+	path string
+	err  error
+}
+
+// walkTree lists the tree rooted at root like Walk, keeping on walking past
+// directories that cannot be read, which are listed in place of their files.
+func walkTree(root string, symlinks SymlinkPolicy) ([]walkEntry, error) {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return []walkEntry{{path: root}}, nil
+	}
+
+	entries := make([]walkEntry, 0)
+	visited := make(map[string]bool)
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			entries = append(entries, walkEntry{path: dir, err: err})
+			return
+		}
+
+		if visited[real] {
+			return
+		}
+		visited[real] = true
+
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			entries = append(entries, walkEntry{path: dir, err: err})
+			return
+		}
+
+		for _, e := range dirEntries {
+			path := filepath.Join(dir, e.Name())
+
+			mode := e.Type()
+			if mode&fs.ModeSymlink != 0 {
+				if symlinks == SymlinkRefuse {
+					continue
+				}
+
+				fi, err := os.Stat(path)
+				if err != nil {
+					continue
+				}
+				mode = fi.Mode().Type()
+			}
+
+			switch {
+			case mode.IsDir():
+				walk(path)
+			case mode.IsRegular():
+				entries = append(entries, walkEntry{path: path})
+			}
+		}
+	}
+
+	walk(root)
+
+	return entries, nil
+}
@@ -0,0 +1,177 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestScanSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+
+	cmd, _ := newStub(t, pdfOutput)
+
+	// The temporary directory itself may lie below a symbolic link
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "secret.pdf")
+	if err := os.WriteFile(target, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	linkedDir := filepath.Join(dir, "uploads")
+	if err := os.Symlink(dir, linkedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "upload.pdf")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	dangling := filepath.Join(dir, "dangling.pdf")
+	if err := os.Symlink(filepath.Join(dir, "missing"), dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      SymlinkPolicy
+		path        string
+		expected    string
+		expectedErr error
+	}{
+		{"Follow", SymlinkFollow, link, link, nil},
+		{"Refuse", SymlinkRefuse, link, "", ErrIsSymlink},
+		{"Refuse regular file", SymlinkRefuse, target, target, nil},
+		{"Refuse linked directory", SymlinkRefuse, filepath.Join(linkedDir, "secret.pdf"), "", ErrIsSymlink},
+		{"Target", SymlinkTarget, link, target, nil},
+		{"Dangling", SymlinkTarget, dangling, "", ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trid := NewTrid(Options{Cmd: cmd, Symlinks: tt.policy})
+
+			got, _, err := trid.checkFile(tt.path)
+			if !errors.Is(err, tt.expectedErr) || got != tt.expected {
+				t.Fatalf("checkFile() = %q, %v, want %q, %v", got, err, tt.expected, tt.expectedErr)
+			}
+
+			results, err := trid.ScanBatch(context.Background(), []string{tt.path}, 1)
+			if err != nil {
+				t.Fatalf("ScanBatch() error = %v", err)
+			}
+
+			if !errors.Is(results[0].Err, tt.expectedErr) {
+				t.Errorf("ScanBatch() error = %v, want %v", results[0].Err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	for _, path := range []string{filepath.Join(root, "sub", "a.pdf"), filepath.Join(outside, "b.pdf")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A cycle back to the root and a link to a directory outside of it
+	if err := os.Symlink(root, filepath.Join(root, "sub", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		policy   SymlinkPolicy
+		expected []string
+	}{
+		{"Follow", SymlinkFollow, []string{filepath.Join(root, "outside", "b.pdf"), filepath.Join(root, "sub", "a.pdf")}},
+		{"Refuse", SymlinkRefuse, []string{filepath.Join(root, "sub", "a.pdf")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := Walk(root, tt.policy)
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+
+			if !slices.Equal(paths, tt.expected) {
+				t.Errorf("Walk() = %q, want %q", paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithinRoots(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "sub"), filepath.Join(outside, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A root given through a symbolic link
+	link := filepath.Join(t.TempDir(), "root")
+	if err := os.Symlink(root, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		roots    []string
+		expected bool
+	}{
+		{"Root", root, []string{root}, true},
+		{"Below the root", filepath.Join(root, "sub", "a.pdf"), []string{root}, true},
+		{"Missing file", filepath.Join(root, "sub", "missing", "a.pdf"), []string{root}, true},
+		{"Parent", filepath.Join(root, "..", "a.pdf"), []string{root}, false},
+		{"Link out of the root", filepath.Join(root, "outside", "a.pdf"), []string{root}, false},
+		{"Link into the root", filepath.Join(outside, "inside", "a.pdf"), []string{root}, true},
+		{"Linked root", filepath.Join(root, "sub"), []string{link}, true},
+		{"No roots", root, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithinRoots(tt.path, tt.roots); got != tt.expected {
+				t.Errorf("WithinRoots(%q, %q) = %v, want %v", tt.path, tt.roots, got, tt.expected)
+			}
+		})
+	}
+}
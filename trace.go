@@ -0,0 +1,46 @@
+package trid
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans created by Trid.
+const tracerName = "github.com/attilabuti/trid"
+
+// startSpan starts a span if Options.TracerProvider is set. The returned span
+// is never nil; it does nothing when tracing is disabled.
+func (t *Trid) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t.tracer == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+
+	attrs = append(attrs, attribute.String("trid.engine", "trid"))
+
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of an operation in span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("error.type", errorClass(err)))
+	}
+
+	span.End()
+}
+
+// fileSizeAttr returns the size of the file at path as a span attribute.
+func fileSizeAttr(path string) attribute.KeyValue {
+	var size int64
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+
+	return attribute.Int64("file.size", size)
+}
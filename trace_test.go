@@ -0,0 +1,85 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttr returns the value of the attribute named key of span.
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	trid := NewTrid(Options{Cmd: cmd, TracerProvider: provider})
+	if _, err := trid.Scan(filepath.Join("testdata", "sample.pdf"), 3); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "trid.exec" || spans[1].Name() != "trid.Scan" {
+		t.Fatalf("spans = %v, want trid.exec and trid.Scan", spans)
+	}
+
+	exec, scan := spans[0], spans[1]
+	if exec.Parent().SpanID() != scan.SpanContext().SpanID() {
+		t.Errorf("trid.exec is not a child of trid.Scan")
+	}
+
+	if v := spanAttr(scan, "file.size").AsInt64(); v != 4643 {
+		t.Errorf("file.size = %d, want 4643", v)
+	}
+
+	if v := spanAttr(scan, "trid.matches").AsInt64(); v != 1 {
+		t.Errorf("trid.matches = %d, want 1", v)
+	}
+
+	if v := spanAttr(scan, "trid.engine").AsString(); v != "trid" {
+		t.Errorf("trid.engine = %q, want trid", v)
+	}
+
+	if v := spanAttr(exec, "trid.version").AsString(); v != "2.24" {
+		t.Errorf("trid.version = %q, want 2.24", v)
+	}
+
+	if v := spanAttr(exec, "trid.definitions.count").AsInt64(); v != 18452 {
+		t.Errorf("trid.definitions.count = %d, want 18452", v)
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	trid := NewTrid(Options{Cmd: "trid", TracerProvider: provider})
+	if _, err := trid.ScanBatch(context.Background(), []string{"a"}, 0); !errors.Is(err, ErrNumberOfMatches) {
+		t.Fatalf("ScanBatch() error = %v, want ErrNumberOfMatches", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("spans = %v, want a failed trid.ScanBatch span", spans)
+	}
+
+	if v := spanAttr(spans[0], "error.type").AsString(); v != "invalid_argument" {
+		t.Errorf("error.type = %q, want invalid_argument", v)
+	}
+}
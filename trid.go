@@ -6,12 +6,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"regexp"
+	"io"
+	"log/slog"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/attilabuti/trid/native"
 )
 
 var (
@@ -27,83 +32,347 @@ var (
 	// ErrEmptyDefPackage is returned when a TRiD definition package is empty.
 	ErrEmptyDefPackage = errors.New("TRiD definition package is empty")
 
+	// ErrDefinitionsMismatch is returned when the checksum of the TRiD
+	// definition package differs from Options.DefsSHA256.
+	ErrDefinitionsMismatch = errors.New("TRiD definition package checksum mismatch")
+
 	// ErrFileNotFound is returned when the specified file cannot be located or accessed.
 	ErrFileNotFound = errors.New("file not found")
 
 	// ErrUnknownFileType is returned when TRiD fails to identify the file type.
 	ErrUnknownFileType = errors.New("unknown file type")
 
-	// Regular expressions for parsing TRiD output.
-	reFileInfo    = regexp.MustCompile(`(?mi)([0-9.]+%)\s+\((\..*?)\)\s+(.*?(?:\s+\([^()]+\))*?)(?:\s+\([^()]+\))?$`)
-	reFileDetails = regexp.MustCompile(`(?mi)(Mime type|Related URL|Definition|Remarks)\s*:\s*(.*?)$`)
+	// ErrEmptyFile is returned for zero-byte files, which TrID cannot identify.
+	ErrEmptyFile = errors.New("file is empty")
+
+	// ErrTimeout is returned when TrID does not finish within Options.Timeout.
+	// Results parsed from the output produced until then are available in
+	// TridError.Partial.
+	ErrTimeout = errors.New("command timed out")
+
+	// ErrReservedArg is returned when Options.ExtraArgs contains an argument
+	// managed by this package.
+	ErrReservedArg = errors.New("argument is managed by the trid package")
 )
 
 // Trid represents a TrID file identifier instance with specific options.
 type Trid struct {
-	options Options
+	options     Options
+	fingerprint fingerprint
+	defsAge     defsAgeCheck
+	circuit     circuit
+	version     version
+	optsErr     error        // Problem with the options, returned by every run of TrID.
+	tracer      trace.Tracer // Tracer of Options.TracerProvider, or nil if tracing is disabled.
+	stats       stats
+	native      *native.Set // Definitions of the native engine, if used.
 }
 
 // Options configures the TrID execution parameters.
 type Options struct {
-	Cmd         string        // Command to invoke the TrID file identifier.
-	Definitions string        // Path to the TrID definitions package.
-	Timeout     time.Duration // Maximum duration to wait for TrID execution.
+	Cmd              string              // Command to invoke the TrID file identifier; found with FindBinary if empty.
+	Definitions      string              // Path to the TrID definitions package.
+	DefsSHA256       string              // Expected SHA-256 checksum of the definitions package, hex-encoded, or SidecarChecksum; empty disables the check.
+	MaxDefsAge       time.Duration       // Age of the definitions above which scans warn they are stale; 0 disables the check.
+	Timeout          time.Duration       // Maximum duration to wait for TrID execution.
+	TimeoutPerMB     time.Duration       // Added to Timeout for each MiB of the files identified; 0 keeps the timeout flat.
+	Cache            Cache               // Cache for scan results, keyed by file content; nil disables caching.
+	CacheTTL         time.Duration       // Lifetime of cached results; 0 means they do not expire.
+	NegativeCacheTTL time.Duration       // Lifetime of cached ErrUnknownFileType results; 0 disables negative caching.
+	MaxConcurrent    int                 // Maximum number of concurrent TrID processes; 0 means unlimited.
+	Limiter          *Limiter            // Limiter shared with other instances; takes precedence over MaxConcurrent.
+	BatchSize        int                 // Maximum number of files passed to a single TrID process by ScanBatch.
+	FileList         FileListMode        // How ScanBatch passes file paths to TrID (default: FileListAuto).
+	Retry            Retry               // Retry policy for transient failures; retries are disabled by default.
+	Breaker          Breaker             // Circuit breaker for a broken TrID installation; disabled by default.
+	ExtraArgs        []string            // Additional TrID arguments, such as flags not modeled by this package.
+	MinProbability   float64             // Matches below this percentage are dropped; see WithMinProbability.
+	ResultFilter     func(FileType) bool // Reports whether a match is kept; nil keeps all matches.
+	Fallback         bool                // Identify files TrID cannot identify, or cannot be run for, with the standard library.
+	Containers       bool                // Refine zip matches by inspecting the structure of the archive, to tell OOXML, ODF, JAR and APK files apart.
+	Charset          bool                // Detect the character encoding of textual files, reported in Report.Charset.
+	Precheck         bool                // Classify obvious plain text, JSON, CSV and XML files from their first 8 KiB without running TrID.
+	Executables      bool                // Parse the headers of PE, ELF and Mach-O files, reported in Report.Executable.
+	PDF              bool                // Extract the version, encryption and page count of PDF documents, reported in Report.PDF.
+	Office           bool                // Tell OLE from OOXML Office documents and detect their macros, reported in Report.Office.
+	Images           bool                // Decode the dimensions, color depth and frame count of images, reported in Report.Image.
+	Enrichers        map[string]Enricher // Add details to identified files, reported in Report.Enrichments under the name of their enricher.
+	SuspiciousNames  bool                // Flag deceptive file names, such as double extensions, reported in Report.Suspicion.
+	Symlinks         SymlinkPolicy       // How symbolic links are handled (default: SymlinkFollow).
+	MaxFileSize      int64               // Size in bytes above which files are handled according to Oversize; 0 means no limit.
+	Oversize         OversizePolicy      // How files larger than MaxFileSize are handled (default: OversizeFail).
+	LocalCopy        LocalCopy           // Copying of files to local storage before identification; disabled by default.
+	Logger           *slog.Logger        // Logger for debugging information about TrID runs; nil disables logging.
+	ExpvarName       string              // Name under which basic counters are published with expvar; empty disables publishing.
+	Hooks            Hooks               // Callbacks invoked during scans.
+	Progress         func(Progress)      // Called by ScanBatch as files are identified; calls are not concurrent.
+	Runner           Runner              // Runs TrID processes (default: ExecRunner).
+	Engine           EngineMode          // How files are identified (default: EngineCLI).
+
+	// TracerProvider creates OpenTelemetry spans for scans and TrID
+	// processes; nil disables tracing.
+	TracerProvider trace.TracerProvider
 }
 
 // FileType represents detailed information about a file type as identified by TrID.
 type FileType struct {
-	Extension   string  // File extension (e.g., ".txt", ".pdf").
-	Probability float64 // Probability of the file type match, as a percentage (0-100).
-	Name        string  // Descriptive name of the file type.
-	MimeType    string  // Mime type of the file (e.g., "text/plain", "application/pdf").
-	RelatedURL  string  // URL for additional information about the file type.
-	Remarks     string  // Additional notes or comments about the file type from TRiD.
-	Definition  string  // Name of the TRiD definition XML file for this file type.
+	Extension   string  `json:"extension"`             // File extension (e.g., ".txt", ".pdf").
+	Probability float64 `json:"probability"`           // Probability of the file type match, as a percentage (0-100).
+	Name        string  `json:"name"`                  // Descriptive name of the file type.
+	MimeType    string  `json:"mime_type,omitempty"`   // Mime type of the file (e.g., "text/plain", "application/pdf").
+	RelatedURL  string  `json:"related_url,omitempty"` // URL for additional information about the file type.
+	Remarks     string  `json:"remarks,omitempty"`     // Additional notes or comments about the file type from TRiD.
+	Definition  string  `json:"definition,omitempty"`  // Name of the TRiD definition XML file for this file type.
+	Source      string  `json:"source,omitempty"`      // How the match was found if not by TrID, such as SourceSniff.
 }
 
-// NewTrid creates a new Trid instance with the given options.
+// Report is the result of identifying a file with ScanReport or ScanRaw.
+type Report struct {
+	FileTypes   []FileType     `json:"file_types"`            // Identified file types.
+	Warnings    []string       `json:"warnings,omitempty"`    // Non-fatal warnings printed by TrID, such as truncated reads.
+	Raw         string         `json:"raw,omitempty"`         // Unparsed standard output of TrID; empty for cached results.
+	Engine      string         `json:"engine,omitempty"`      // Engine that identified the file, "cli" or "native"; empty for cached results.
+	Charset     string         `json:"charset,omitempty"`     // Character encoding of textual files, with Options.Charset (e.g., "UTF-8", "Shift_JIS").
+	Executable  *Executable    `json:"executable,omitempty"`  // Header of executables, with Options.Executables.
+	PDF         *PDF           `json:"pdf,omitempty"`         // Properties of PDF documents, with Options.PDF.
+	Office      *Office        `json:"office,omitempty"`      // Container and macros of Office documents, with Options.Office.
+	Image       *Image         `json:"image,omitempty"`       // Header of images, with Options.Images.
+	Enrichments map[string]any `json:"enrichments,omitempty"` // Details added by Options.Enrichers, by name.
+	Suspicion   *Suspicion     `json:"suspicion,omitempty"`   // Why the file name is deceptive, with Options.SuspiciousNames; nil if it is not.
+}
+
+// NewTrid creates a new Trid instance with the given options. Like
+// expvar.Publish, it panics if Options.ExpvarName is already in use.
 func NewTrid(opts Options) *Trid {
+	engine, set, optsErr := resolveEngine(opts)
+	opts.Engine = engine
+
+	// The native engine does not need the executable
 	if opts.Cmd == "" {
-		opts.Cmd = "trid"
+		var err error
+		if opts.Cmd, err = FindBinary(); err != nil {
+			opts.Cmd = "trid"
+
+			if engine == EngineCLI {
+				optsErr = err
+			}
+		}
+	}
+
+	if err := checkExtraArgs(opts.ExtraArgs); err != nil && optsErr == nil {
+		optsErr = err
 	}
 
 	if opts.Timeout == 0 {
 		opts.Timeout = 30 * time.Second
 	}
 
-	return &Trid{opts}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 200
+	}
+
+	if opts.Retry.Backoff <= 0 {
+		opts.Retry.Backoff = 100 * time.Millisecond
+	}
+
+	if opts.Retry.RetryIf == nil {
+		opts.Retry.RetryIf = IsTransient
+	}
+
+	if opts.Breaker.Cooldown <= 0 {
+		opts.Breaker.Cooldown = 30 * time.Second
+	}
+
+	if opts.Runner == nil {
+		opts.Runner = ExecRunner{}
+	}
+
+	if opts.Limiter == nil && opts.MaxConcurrent > 0 {
+		opts.Limiter = NewLimiter(opts.MaxConcurrent)
+	}
+
+	t := &Trid{options: opts, optsErr: optsErr, native: set}
+	if opts.TracerProvider != nil {
+		t.tracer = opts.TracerProvider.Tracer(tracerName)
+	}
+
+	if opts.ExpvarName != "" {
+		t.publishExpvar(opts.ExpvarName)
+	}
+
+	return t
+}
+
+// Options returns the options of the Trid instance, with defaults applied.
+func (t *Trid) Options() Options {
+	return t.options
 }
 
 // Scan identifies the file type using TRiD, returning a slice of FileType
 // structs and an error. It takes a file path and the maximum number of potential
 // matches to return.
 func (t *Trid) Scan(filePath string, numberOfMatches int) ([]FileType, error) {
-	if filePath == "" {
-		return nil, ErrNoFileSpecified
+	return t.ScanContext(context.Background(), filePath, numberOfMatches)
+}
+
+// ScanContext is like Scan but stops the TrID process when the context is
+// cancelled. The configured timeout still applies.
+func (t *Trid) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]FileType, error) {
+	r, err := t.ScanReport(ctx, filePath, numberOfMatches)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := os.Stat(filePath); err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrFileNotFound
+	return r.FileTypes, nil
+}
+
+// ScanReport is like ScanContext but also returns the warnings TrID printed
+// while identifying the file and its raw output. Results served from the cache
+// have neither.
+func (t *Trid) ScanReport(ctx context.Context, filePath string, numberOfMatches int) (r *Report, err error) {
+	t.options.Hooks.scanStart(filePath)
+
+	ctx, span := t.startSpan(ctx, "trid.Scan", fileSizeAttr(filePath), attribute.Int("trid.matches.requested", numberOfMatches))
+	defer func() {
+		endSpan(span, err)
+		t.stats.scanDone(err)
+
+		if err != nil {
+			t.options.Hooks.scanComplete(filePath, nil, err)
+		} else {
+			t.options.Hooks.scanComplete(filePath, r.FileTypes, nil)
 		}
+	}()
+
+	target, release, err := t.checkScanArgs(filePath, numberOfMatches)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	t.checkDefsAge(ctx)
+
+	switch fileTypes := t.precheck(target); {
+	case fileTypes != nil:
+		r = &Report{FileTypes: fileTypes}
+	case t.options.Cache != nil:
+		r, err = t.scanCached(ctx, target, numberOfMatches)
+	default:
+		r, err = t.run(ctx, target, numberOfMatches)
+	}
+
+	if err != nil {
+		r, err = t.fallback(ctx, target, err)
+	}
+
+	if t.options.Charset {
+		r, err = t.reportCharset(target, r, err)
+	}
+
+	if err == nil {
+		r.FileTypes = t.refineContainer(ctx, target, r.FileTypes)
+
+		if r.FileTypes, err = t.refine(ctx, r.FileTypes); err != nil {
+			return nil, err
+		}
+
+		r.Executable = t.inspectExecutable(target, r.FileTypes)
+		r.PDF = t.inspectPDF(target, r.FileTypes)
+		r.Office = t.inspectOffice(target, r.FileTypes)
+		r.Image = t.inspectImage(target, r.FileTypes)
+		r.Enrichments = t.enrich(ctx, target, r.FileTypes)
+		r.Suspicion = t.checkName(filePath, r.FileTypes)
+		span.SetAttributes(attribute.Int("trid.matches", len(r.FileTypes)))
+	}
+
+	return r, err
+}
+
+// ScanRaw is like ScanReport but always runs TrID, bypassing the cache, so the
+// report includes the raw output. It can be used to archive the original
+// evidence or to debug parser discrepancies. If the scan fails, the raw output
+// is available in TridError.Output. The native engine has no raw output.
+func (t *Trid) ScanRaw(ctx context.Context, filePath string, numberOfMatches int) (r *Report, err error) {
+	t.options.Hooks.scanStart(filePath)
+
+	ctx, span := t.startSpan(ctx, "trid.ScanRaw", fileSizeAttr(filePath), attribute.Int("trid.matches.requested", numberOfMatches))
+	defer func() {
+		endSpan(span, err)
+		t.stats.scanDone(err)
+
+		if err != nil {
+			t.options.Hooks.scanComplete(filePath, nil, err)
+		} else {
+			t.options.Hooks.scanComplete(filePath, r.FileTypes, nil)
+		}
+	}()
+
+	target, release, err := t.checkScanArgs(filePath, numberOfMatches)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	t.checkDefsAge(ctx)
 
+	if r, err = t.run(ctx, target, numberOfMatches); err != nil {
 		return nil, err
 	}
 
+	r.FileTypes = t.refineContainer(ctx, target, r.FileTypes)
+
+	if r.FileTypes, err = t.refine(ctx, r.FileTypes); err != nil {
+		return nil, err
+	}
+
+	r.Executable = t.inspectExecutable(target, r.FileTypes)
+	r.PDF = t.inspectPDF(target, r.FileTypes)
+	r.Office = t.inspectOffice(target, r.FileTypes)
+	r.Image = t.inspectImage(target, r.FileTypes)
+	r.Enrichments = t.enrich(ctx, target, r.FileTypes)
+	r.Suspicion = t.checkName(filePath, r.FileTypes)
+	span.SetAttributes(attribute.Int("trid.matches", len(r.FileTypes)))
+
+	return r, nil
+}
+
+// checkScanArgs validates the arguments of a scan and returns the path to
+// pass to TrID and a function releasing it, as checkFile does.
+func (t *Trid) checkScanArgs(filePath string, numberOfMatches int) (string, func(), error) {
+	if filePath == "" {
+		return "", nil, ErrNoFileSpecified
+	}
+
+	target, release, err := t.checkFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
 	if numberOfMatches < 1 {
-		return nil, ErrNumberOfMatches
+		release()
+		return "", nil, ErrNumberOfMatches
 	}
 
-	args := []string{"-v", "-n:" + strconv.Itoa(numberOfMatches)}
-	if t.options.Definitions != "" {
-		args = append(args, "-d:"+t.options.Definitions)
+	return target, release, nil
+}
+
+// run executes TrID on a single file, with any extra arguments, and parses its
+// output. With the native engine, the file is identified in-process instead.
+func (t *Trid) run(ctx context.Context, filePath string, numberOfMatches int, extra ...string) (*Report, error) {
+	if t.options.Engine == EngineNative {
+		return t.runNative(ctx, filePath, numberOfMatches)
 	}
-	args = append(args, filePath)
 
-	// Execute TRiD command and capture output
-	out, err := execCmd(t.options.Cmd, t.options.Timeout, args...)
-	if tridErr := checkTridError(out); tridErr != nil {
-		return nil, tridErr
+	args := append(append(t.args(numberOfMatches), extra...), argPath(filePath))
+
+	p, err := t.execRetry(ctx, t.timeout(filePath), "", args...)
+	if tridErr := p.check(p.stdout); tridErr != nil {
+		return nil, p.error(tridErr)
+	}
+
+	var partialErr *TridError
+	if errors.Is(err, ErrTimeout) && errors.As(err, &partialErr) {
+		partialErr.Partial, _ = parseOutput(p.stdout)
 	}
 
 	if err != nil {
@@ -111,54 +380,128 @@ func (t *Trid) Scan(filePath string, numberOfMatches int) ([]FileType, error) {
 	}
 
 	// Parse the TRiD output
-	return parseOutput(out)
+	pr := detectProfile(p.stdout)
+	fileTypes, warnings, err := pr.parse(p.stdout)
+	if err != nil {
+		t.log(ctx, slog.LevelWarn, "unparsable TrID output", "path", filePath, "profile", pr.name, "error", err)
+		return nil, p.error(err)
+	}
+
+	t.log(ctx, slog.LevelDebug, "parsed TrID output", "path", filePath, "profile", pr.name, "matches", len(fileTypes), "warnings", len(warnings))
+
+	return &Report{
+		FileTypes: fileTypes,
+		Warnings:  append(stderrWarnings(p.stderr), warnings...),
+		Raw:       p.stdout,
+		Engine:    EngineCLI.String(),
+	}, nil
 }
 
-// parseOutput parses TRiD stdout and returns a slice of FileType structs.
-func parseOutput(out string) ([]FileType, error) {
-	fileTypes := make([]FileType, 0)
+// args returns the TrID arguments preceding the file paths.
+func (t *Trid) args(numberOfMatches int) []string {
+	args := []string{"-v", "-n:" + strconv.Itoa(numberOfMatches)}
+	if t.options.Definitions != "" {
+		args = append(args, "-d:"+t.options.Definitions)
+	}
 
-	results := strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n\n")
-	for _, result := range results {
-		fileInfo := reFileInfo.FindStringSubmatch(result)
-		if len(fileInfo) != 4 {
-			continue
-		}
+	return append(args, t.options.ExtraArgs...)
+}
 
-		fileInfo[1] = strings.TrimSpace(strings.Replace(fileInfo[1], "%", "", -1))
-		if len(fileInfo[1]) == 0 {
-			continue
-		}
+// argPath returns path in a form TrID cannot mistake for an option. Only
+// relative paths can start with a dash, so they are prefixed with the current
+// directory.
+func argPath(path string) string {
+	if strings.HasPrefix(path, "-") {
+		return "." + string(filepath.Separator) + path
+	}
 
-		probability, err := strconv.ParseFloat(fileInfo[1], 64)
-		if err != nil {
-			continue
-		}
+	return path
+}
 
-		f := FileType{
-			Probability: probability,
-			Extension:   strings.ToLower(fileInfo[2]),
-			Name:        fileInfo[3],
+// reservedArgs are the TrID arguments managed by this package, by lowercase
+// prefix.
+var reservedArgs = []string{"-v", "-n:", "-d:", "-@", "-ae", "-ce"}
+
+// checkExtraArgs checks that extra does not contain arguments managed by this
+// package.
+func checkExtraArgs(extra []string) error {
+	for _, arg := range extra {
+		lower := strings.ToLower(arg)
+		for _, reserved := range reservedArgs {
+			if lower == reserved || strings.HasSuffix(reserved, ":") && strings.HasPrefix(lower, reserved) {
+				return fmt.Errorf("%w: %s", ErrReservedArg, arg)
+			}
 		}
+	}
 
-		fileDetails := reFileDetails.FindAllStringSubmatch(result, -1)
-		for _, m := range fileDetails {
-			switch m[1] {
-			case "Mime type":
-				f.MimeType = m[2]
-			case "Related URL":
-				f.RelatedURL = m[2]
-			case "Definition":
-				f.Definition = m[2]
-			case "Remarks":
-				f.Remarks = m[2]
-			}
+	return nil
+}
+
+// exec runs TrID with the given arguments and standard input, if not empty,
+// for at most timeout once a process slot is available. It fails with ErrUnavailable while the
+// circuit breaker is open. The returned process is never nil; errors from the
+// process are returned as a *TridError.
+func (t *Trid) exec(ctx context.Context, timeout time.Duration, stdin string, args ...string) (*process, error) {
+	notStarted := &process{command: append([]string{t.options.Cmd}, args...), exitCode: -1}
+
+	if t.optsErr != nil {
+		return notStarted, t.optsErr
+	}
+
+	if err := t.verifyDefinitions(ctx); err != nil {
+		return notStarted, err
+	}
+
+	probe, err := t.breakerAllow()
+	if err != nil {
+		t.log(ctx, slog.LevelDebug, "TrID not run, circuit breaker is open", "command", notStarted.command)
+		return notStarted, err
+	}
+
+	if t.options.Limiter != nil {
+		if err := t.options.Limiter.Acquire(ctx); err != nil {
+			t.breakerRecord(ctx, probe, nil, err)
+			return notStarted, err
 		}
+		defer t.options.Limiter.Release()
+	}
 
-		fileTypes = append(fileTypes, f)
+	var r io.Reader
+	if stdin != "" {
+		r = strings.NewReader(stdin)
 	}
 
-	return fileTypes, nil
+	t.log(ctx, slog.LevelDebug, "running TrID", "command", notStarted.command, "timeout", timeout)
+
+	ctx, span := t.startSpan(ctx, "trid.exec", attribute.StringSlice("process.command_args", notStarted.command))
+	t.options.Hooks.processSpawn(notStarted.command)
+	t.stats.processStarted()
+	start := time.Now()
+	p, err := t.execCmd(ctx, timeout, r, args...)
+	t.stats.processDone(time.Since(start))
+	t.breakerRecord(ctx, probe, p, err)
+
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Int("process.exit.code", p.exitCode), attribute.String("trid.version", parseBanner(p.stdout).version),
+			attribute.Int("trid.definitions.count", definitionsCount(p.stdout)))
+	}
+	endSpan(span, err)
+
+	attrs := []any{"duration", time.Since(start), "exit_code", p.exitCode, "stdout_bytes", len(p.stdout), "stderr_bytes", len(p.stderr)}
+	if err != nil {
+		t.log(ctx, slog.LevelWarn, "TrID failed", append(attrs, "error", err, "class", errorClass(err))...)
+	} else {
+		t.log(ctx, slog.LevelDebug, "TrID finished", attrs...)
+	}
+
+	return p, err
+}
+
+// parseOutput parses TRiD stdout and returns a slice of FileType structs,
+// using the parsing profile matching the TrID version that produced it.
+func parseOutput(out string) ([]FileType, error) {
+	fileTypes, _, err := detectProfile(out).parse(out)
+	return fileTypes, err
 }
 
 // checkTridError checks the TrID output for known error messages and returns
@@ -187,24 +530,34 @@ func checkTridError(out string) error {
 	return nil
 }
 
-// execCmd executes a command with a timeout and returns its combined stdout and
-// stderr output.
-func execCmd(name string, timeout time.Duration, args ...string) (string, error) {
+// execCmd runs TrID with the configured Runner and a timeout, feeding it
+// stdin if not nil, and returns the process with its separately captured
+// stdout and stderr. Errors are returned as a *TridError.
+func (t *Trid) execCmd(ctx context.Context, timeout time.Duration, stdin io.Reader, args ...string) (*process, error) {
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel() // Ensure resources are cleaned up when the function returns
 
-	// Create the command with the timeout context
-	cmd := exec.CommandContext(ctx, name, args...)
-
-	// Execute the command and capture both stdout and stderr
-	out, err := cmd.CombinedOutput()
+	res, err := t.options.Runner.Run(ctx, Command{Name: t.options.Cmd, Args: args, Stdin: stdin})
+	p := &process{command: append([]string{t.options.Cmd}, args...), stdout: res.Stdout, stderr: res.Stderr, exitCode: res.ExitCode}
 
 	// Check if the command timed out
 	if ctx.Err() == context.DeadlineExceeded {
-		return string(out), fmt.Errorf("command timed out: %w", err)
+		if err == nil {
+			return p, p.error(ErrTimeout)
+		}
+
+		return p, p.error(fmt.Errorf("%w: %w", ErrTimeout, err))
+	}
+
+	// Check if the command was cancelled by the caller
+	if ctx.Err() == context.Canceled {
+		return p, p.error(ctx.Err())
+	}
+
+	if err != nil {
+		return p, p.error(err)
 	}
 
-	// Return the output and any execution error
-	return string(out), err
+	return p, nil
 }
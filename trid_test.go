@@ -1,7 +1,11 @@
 package trid
 
 import (
+	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -104,3 +108,153 @@ func TestScanErr(t *testing.T) {
 		}
 	})
 }
+
+func TestParseOutputProbability(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected float64
+	}{
+		{"Decimal point", " 87.5% (.PDF) Adobe Portable Document Format (5000/1)", 87.5},
+		{"Decimal comma", " 87,5% (.PDF) Adobe Portable Document Format (5000/1)", 87.5},
+		{"Whole number with comma", "100,0% (.PDF) Adobe Portable Document Format (5000/1)", 100},
+		{"No decimals", "  50% (.PDF) Adobe Portable Document Format (5000/1)", 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileTypes, err := parseOutput("Collecting data from file: sample.pdf\n" + tt.line + "\n")
+			if err != nil {
+				t.Fatalf("parseOutput() error = %v", err)
+			}
+
+			if len(fileTypes) != 1 {
+				t.Fatalf("parseOutput() returned %d results, want 1", len(fileTypes))
+			}
+
+			if fileTypes[0].Probability != tt.expected || fileTypes[0].Extension != ".pdf" {
+				t.Errorf("parseOutput() = %+v, want probability %v", fileTypes[0], tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtraArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args.txt")
+	cmd := filepath.Join(dir, "trid")
+	script := "#!/bin/sh\necho \"$*\" > '" + argsFile + "'\ncat <<'EOF'\n" + pdfOutput + "EOF\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "sample.pdf")
+	if err := os.WriteFile(file, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTrid(Options{Cmd: cmd, ExtraArgs: []string{"-w"}}).Scan(file, 2); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	if b, _ := os.ReadFile(argsFile); strings.TrimSpace(string(b)) != "-v -n:2 -w "+file {
+		t.Errorf("TrID arguments = %q, want extra arguments before the file", b)
+	}
+}
+
+func TestCheckExtraArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		reserved bool
+	}{
+		{[]string{"-w", "-r:3"}, false},
+		{[]string{"-V"}, true},
+		{[]string{"-n:10"}, true},
+		{[]string{"-d:other.trd"}, true},
+		{[]string{"-@"}, true},
+		{[]string{"-CE"}, true},
+		{[]string{"-vv"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if err := checkExtraArgs(tt.args); errors.Is(err, ErrReservedArg) != tt.reserved {
+				t.Errorf("checkExtraArgs() error = %v, want reserved %v", err, tt.reserved)
+			}
+		})
+	}
+
+	trid := NewTrid(Options{Cmd: "trid", ExtraArgs: []string{"-n:3"}})
+	if err := trid.Probe(context.Background()); !errors.Is(err, ErrReservedArg) {
+		t.Errorf("Probe() error = %v, want ErrReservedArg", err)
+	}
+}
+
+func TestScanHostileNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	// Like TrID, the stub treats arguments starting with a dash as options
+	// and fails if one of them is unknown
+	bin := t.TempDir()
+	cmd := filepath.Join(bin, "trid")
+	script := `#!/bin/sh
+echo "TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello"
+for f in "$@"; do
+  case "$f" in
+    -v|-n:*) ;;
+    -*) echo "Unknown option $f" >&2; exit 1 ;;
+    *)
+      [ -f "$f" ] || { echo "Error: found no file(s) to analyze!"; exit 1; }
+      echo
+      echo "File: $f"
+      echo " 100.0% (.PDF) Adobe Portable Document Format (5000/1)"
+      ;;
+  esac
+done
+`
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	names := []string{"-n:1", "--help", "-d:evil.trd", "with space.pdf", "ünïcødé.pdf", "line\nbreak.pdf"}
+	for _, name := range names {
+		if err := os.WriteFile(name, []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trid := NewTrid(Options{Cmd: cmd, FileList: FileListArgs})
+	for _, name := range names {
+		fileTypes, err := trid.Scan(name, 1)
+		if err != nil || len(fileTypes) != 1 {
+			t.Errorf("Scan(%q) = %+v, %v", name, fileTypes, err)
+		}
+	}
+
+	results, err := trid.ScanBatch(context.Background(), names, 1)
+	if err != nil {
+		t.Fatalf("ScanBatch() error = %v", err)
+	}
+
+	for _, r := range results {
+		if r.Err != nil || len(r.FileTypes) != 1 {
+			t.Errorf("ScanBatch() result for %q = %+v, %v", r.Path, r.FileTypes, r.Err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package tridgrpc
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/tridpb"
+)
+
+// Client calls a remote identification service.
+type Client struct {
+	client tridpb.IdentifierClient
+}
+
+// Result is the outcome of identifying one path in a bulk scan.
+type Result struct {
+	Path      string          // Path that was identified.
+	FileTypes []trid.FileType // Identified file types.
+	Err       error           // Error if the identification failed.
+}
+
+// NewClient returns a Client using the given connection.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{client: tridpb.NewIdentifierClient(cc)}
+}
+
+// Identify identifies a file on the server's file system.
+func (c *Client) Identify(ctx context.Context, path string, numberOfMatches int) ([]trid.FileType, error) {
+	return c.identify(ctx, &tridpb.IdentifyRequest{
+		Source:  &tridpb.IdentifyRequest_Path{Path: path},
+		Matches: int32(numberOfMatches),
+	})
+}
+
+// IdentifyContent identifies the given file contents.
+func (c *Client) IdentifyContent(ctx context.Context, content []byte, numberOfMatches int) ([]trid.FileType, error) {
+	return c.identify(ctx, &tridpb.IdentifyRequest{
+		Source:  &tridpb.IdentifyRequest_Content{Content: content},
+		Matches: int32(numberOfMatches),
+	})
+}
+
+// IdentifyAll streams paths to the server and returns a channel delivering a
+// Result per path as identifications complete, in completion order. The
+// channel is closed once every result has been received, the stream fails or
+// ctx is cancelled; a stream failure is delivered as a final Result with an
+// empty Path.
+func (c *Client) IdentifyAll(ctx context.Context, paths []string, numberOfMatches int) (<-chan Result, error) {
+	stream, err := c.client.IdentifyStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan Result)
+
+	go func() {
+		for i, p := range paths {
+			req := &tridpb.IdentifyRequest{
+				Id:      strconv.Itoa(i),
+				Source:  &tridpb.IdentifyRequest_Path{Path: p},
+				Matches: int32(numberOfMatches),
+			}
+
+			if err := stream.Send(req); err != nil {
+				break
+			}
+		}
+
+		stream.CloseSend()
+	}()
+
+	go func() {
+		defer close(results)
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				select {
+				case results <- Result{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+
+			r := Result{Path: resp.GetPath(), FileTypes: fromProto(resp.GetFileTypes())}
+			if resp.GetError() != "" {
+				r.Err = toError(status.New(codes.Code(resp.GetCode()), resp.GetError()).Err())
+			}
+
+			select {
+			case results <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// identify performs a unary identification call.
+func (c *Client) identify(ctx context.Context, req *tridpb.IdentifyRequest) ([]trid.FileType, error) {
+	resp, err := c.client.Identify(ctx, req)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return fromProto(resp.GetFileTypes()), nil
+}
+
+// toError maps gRPC status errors back to the package's sentinel errors where
+// possible, so callers can use errors.Is as with a local Trid.
+func toError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, sentinel := range []error{trid.ErrNoFileSpecified, trid.ErrNumberOfMatches, trid.ErrFileNotFound, trid.ErrUnknownFileType, trid.ErrUnavailable, trid.ErrFileTooLarge, trid.ErrEmptyFile} {
+		if st.Message() == sentinel.Error() {
+			return &remoteError{sentinel: sentinel, status: err}
+		}
+	}
+
+	return err
+}
+
+// remoteError is a gRPC status error that also matches a sentinel error.
+type remoteError struct {
+	sentinel error
+	status   error
+}
+
+// Error implements the error interface.
+func (e *remoteError) Error() string {
+	return e.status.Error()
+}
+
+// Unwrap returns both the sentinel and the status error.
+func (e *remoteError) Unwrap() []error {
+	return []error{e.sentinel, e.status}
+}
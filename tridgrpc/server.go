@@ -0,0 +1,302 @@
+// Package tridgrpc implements a gRPC identification service and client on top
+// of the tridpb protocol definitions.
+package tridgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/tridpb"
+)
+
+// ServerOptions configures the gRPC identification service.
+type ServerOptions struct {
+	MaxConcurrent   int      // Maximum number of concurrent identifications (default: 4).
+	NumberOfMatches int      // Default number of matches when a request does not specify one (default: 5).
+	PathRoots       []string // Directories path requests may read from, symbolic links resolved; if empty, path requests are rejected.
+
+	// Auth, if set, requires an API key in the "authorization" (as a bearer
+	// token) or "x-api-key" metadata of calls, and enforces the quotas of its
+	// tenant for each request, including each request of a stream.
+	Auth *auth.Guard
+}
+
+// Server implements tridpb.IdentifierServer.
+type Server struct {
+	tridpb.UnimplementedIdentifierServer
+
+	trid *trid.Trid
+	opts ServerOptions
+	sem  chan struct{}
+}
+
+// NewServer returns a Server that identifies files with t. Register it with
+// tridpb.RegisterIdentifierServer.
+func NewServer(t *trid.Trid, opts ServerOptions) *Server {
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 4
+	}
+
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	return &Server{
+		trid: t,
+		opts: opts,
+		sem:  make(chan struct{}, opts.MaxConcurrent),
+	}
+}
+
+// Identify implements tridpb.IdentifierServer.
+func (s *Server) Identify(ctx context.Context, req *tridpb.IdentifyRequest) (*tridpb.IdentifyResponse, error) {
+	tenant, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fileTypes, err := s.identify(ctx, tenant, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tridpb.IdentifyResponse{Id: req.GetId(), Path: req.GetPath(), FileTypes: fileTypes}, nil
+}
+
+// IdentifyStream implements tridpb.IdentifierServer. The requests of a
+// stream are identified by ServerOptions.MaxConcurrent workers; while all of
+// them are busy, no further request is received.
+func (s *Server) IdentifyStream(stream tridpb.Identifier_IdentifyStreamServer) error {
+	ctx := stream.Context()
+
+	tenant, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		sendMu  sync.Mutex
+		sendErr error
+	)
+
+	send := func(resp *tridpb.IdentifyResponse) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+
+		if sendErr == nil {
+			sendErr = stream.Send(resp)
+		}
+	}
+
+	reqs := make(chan *tridpb.IdentifyRequest)
+	for i := 0; i < s.opts.MaxConcurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for req := range reqs {
+				resp := &tridpb.IdentifyResponse{Id: req.GetId(), Path: req.GetPath()}
+
+				fileTypes, err := s.identify(ctx, tenant, req)
+				if err != nil {
+					st := status.Convert(err)
+					resp.Error = st.Message()
+					resp.Code = int32(st.Code())
+				}
+				resp.FileTypes = fileTypes
+
+				send(resp)
+			}
+		}()
+	}
+
+	var recvErr error
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				recvErr = err
+			}
+			break
+		}
+
+		reqs <- req
+	}
+
+	close(reqs)
+	wg.Wait()
+
+	if recvErr != nil {
+		return recvErr
+	}
+
+	return sendErr
+}
+
+// authenticate returns the tenant of the API key in the metadata of ctx, or
+// nil without ServerOptions.Auth.
+func (s *Server) authenticate(ctx context.Context) (*auth.Tenant, error) {
+	if s.opts.Auth == nil {
+		return nil, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := auth.KeyFromHeaders(first(md.Get("authorization")), first(md.Get("x-api-key")))
+
+	tenant, err := s.opts.Auth.Authenticate(ctx, key)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return tenant, nil
+}
+
+// identify scans the file selected by req while holding a concurrency slot,
+// once req is within the quotas of tenant, if not nil.
+func (s *Server) identify(ctx context.Context, tenant *auth.Tenant, req *tridpb.IdentifyRequest) ([]*tridpb.FileType, error) {
+	if tenant != nil {
+		if err := s.opts.Auth.Allow(tenant); err != nil {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		if limit := tenant.Quota.MaxUploadSize; limit > 0 && int64(len(req.GetContent())) > limit {
+			return nil, status.Error(codes.ResourceExhausted, auth.ErrUploadTooLarge.Error())
+		}
+	}
+
+	matches := int(req.GetMatches())
+	if matches == 0 {
+		matches = s.opts.NumberOfMatches
+	}
+
+	var path string
+	switch src := req.GetSource().(type) {
+	case *tridpb.IdentifyRequest_Path:
+		if !trid.WithinRoots(src.Path, s.opts.PathRoots) {
+			return nil, status.Error(codes.PermissionDenied, "path is not allowed")
+		}
+
+		path = src.Path
+	case *tridpb.IdentifyRequest_Content:
+		tmp, err := spool(src.Content)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		defer os.Remove(tmp)
+
+		path = tmp
+	default:
+		return nil, status.Error(codes.InvalidArgument, trid.ErrNoFileSpecified.Error())
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	fileTypes, err := s.trid.ScanContext(ctx, path, matches)
+	if err != nil {
+		return nil, status.Error(codeForScanError(err), err.Error())
+	}
+
+	return toProto(fileTypes), nil
+}
+
+// first returns the first of values, or an empty string if there are none.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// spool writes content to a temporary file and returns its path.
+func spool(content []byte) (string, error) {
+	tmp, err := os.CreateTemp("", "trid-grpc-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// codeForScanError maps scan errors to a gRPC status code.
+func codeForScanError(err error) codes.Code {
+	switch {
+	case errors.Is(err, trid.ErrNoFileSpecified), errors.Is(err, trid.ErrNumberOfMatches), errors.Is(err, trid.ErrFileTooLarge):
+		return codes.InvalidArgument
+	case errors.Is(err, trid.ErrFileNotFound):
+		return codes.NotFound
+	case errors.Is(err, trid.ErrUnknownFileType), errors.Is(err, trid.ErrEmptyFile):
+		return codes.FailedPrecondition
+	case errors.Is(err, trid.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, trid.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
+
+// toProto converts file types to their protobuf representation.
+func toProto(fileTypes []trid.FileType) []*tridpb.FileType {
+	out := make([]*tridpb.FileType, 0, len(fileTypes))
+	for _, f := range fileTypes {
+		out = append(out, &tridpb.FileType{
+			Extension:   f.Extension,
+			Probability: f.Probability,
+			Name:        f.Name,
+			MimeType:    f.MimeType,
+			RelatedUrl:  f.RelatedURL,
+			Remarks:     f.Remarks,
+			Definition:  f.Definition,
+		})
+	}
+
+	return out
+}
+
+// fromProto converts file types from their protobuf representation.
+func fromProto(fileTypes []*tridpb.FileType) []trid.FileType {
+	out := make([]trid.FileType, 0, len(fileTypes))
+	for _, f := range fileTypes {
+		out = append(out, trid.FileType{
+			Extension:   f.GetExtension(),
+			Probability: f.GetProbability(),
+			Name:        f.GetName(),
+			MimeType:    f.GetMimeType(),
+			RelatedURL:  f.GetRelatedUrl(),
+			Remarks:     f.GetRemarks(),
+			Definition:  f.GetDefinition(),
+		})
+	}
+
+	return out
+}
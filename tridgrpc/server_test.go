@@ -0,0 +1,214 @@
+package tridgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/auth"
+	"github.com/attilabuti/trid/tridpb"
+)
+
+const pdfOutput = `
+TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello
+Definitions found:  18452
+Analyzing...
+
+Collecting data from file: sample.pdf
+100.0% (.PDF) Adobe Portable Document Format (5000/1)
+        Mime type       : application/pdf
+        Definition      : pdf-generic.trid.xml
+`
+
+// newTestClient starts a server backed by a stub TrID executable that prints
+// pdfOutput and returns a client connected to it, along with a directory
+// path requests may read from.
+func newTestClient(t *testing.T, opts ServerOptions) (*Client, string) {
+	t.Helper()
+
+	return newScriptClient(t, opts, "")
+}
+
+// newScriptClient is like newTestClient, with a stub running script before
+// printing pdfOutput.
+func newScriptClient(t *testing.T, opts ServerOptions, script string) (*Client, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "output.txt"), []byte(pdfOutput), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := filepath.Join(dir, "trid")
+	script = "#!/bin/sh\n" + script + "cat '" + filepath.Join(dir, "output.txt") + "'\n"
+	if err := os.WriteFile(cmd, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "sample.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	opts.PathRoots = []string{root}
+	tridpb.RegisterIdentifierServer(srv, NewServer(trid.NewTrid(trid.Options{Cmd: cmd}), opts))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewClient(conn), root
+}
+
+func TestIdentify(t *testing.T) {
+	c, root := newTestClient(t, ServerOptions{})
+	ctx := context.Background()
+
+	fileTypes, err := c.Identify(ctx, filepath.Join(root, "sample.pdf"), 1)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+
+	if len(fileTypes) != 1 || fileTypes[0].Extension != ".pdf" {
+		t.Errorf("Identify() = %+v", fileTypes)
+	}
+
+	fileTypes, err = c.IdentifyContent(ctx, []byte("%PDF-1.4"), 1)
+	if err != nil || len(fileTypes) != 1 {
+		t.Errorf("IdentifyContent() = %+v, %v", fileTypes, err)
+	}
+
+	if _, err := c.Identify(ctx, filepath.Join(root, "missing.pdf"), 1); !errors.Is(err, trid.ErrFileNotFound) {
+		t.Errorf("Identify() error = %v, want ErrFileNotFound", err)
+	}
+
+	_, err = c.Identify(ctx, "/etc/passwd", 1)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Identify() error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestIdentifyAll(t *testing.T) {
+	c, root := newTestClient(t, ServerOptions{})
+
+	paths := []string{
+		filepath.Join(root, "sample.pdf"),
+		filepath.Join(root, "missing.pdf"),
+		filepath.Join(root, "sample.pdf"),
+	}
+
+	results, err := c.IdentifyAll(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("IdentifyAll() error = %v", err)
+	}
+
+	var ok, failed int
+	for r := range results {
+		switch {
+		case r.Err == nil && len(r.FileTypes) == 1:
+			ok++
+		case errors.Is(r.Err, trid.ErrFileNotFound):
+			failed++
+		default:
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+
+	if ok != 2 || failed != 1 {
+		t.Errorf("got %d successful and %d failed results, want 2 and 1", ok, failed)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	guard := auth.NewGuard(auth.StaticKeys{
+		"secret": {Name: "tenant", Quota: auth.Quota{RequestsPerSecond: 0.001, Burst: 2, MaxUploadSize: 4}},
+	})
+	c, root := newTestClient(t, ServerOptions{Auth: guard})
+	sample := filepath.Join(root, "sample.pdf")
+
+	if _, err := c.Identify(context.Background(), sample, 1); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Identify() without key error = %v, want Unauthenticated", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := c.Identify(ctx, sample, 1); err != nil {
+		t.Errorf("Identify() error = %v", err)
+	}
+
+	if _, err := c.IdentifyContent(ctx, []byte("%PDF-1.4"), 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("IdentifyContent() above the size quota error = %v, want ResourceExhausted", err)
+	}
+
+	if _, err := c.Identify(ctx, sample, 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Identify() above the rate quota error = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestIdentifyStreamBounded(t *testing.T) {
+	// The stub records the number of uploads spooled while it runs
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	counts := filepath.Join(t.TempDir(), "counts.txt")
+
+	c, _ := newScriptClient(t, ServerOptions{MaxConcurrent: 2},
+		"ls '"+tmp+"' | grep -c trid-grpc >> '"+counts+"'\nsleep 0.05\n")
+
+	stream, err := c.client.IdentifyStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 8
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := stream.Send(&tridpb.IdentifyRequest{Source: &tridpb.IdentifyRequest_Content{Content: []byte("%PDF-1.4")}}); err != nil {
+				break
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	for i := 0; i < n; i++ {
+		if resp, err := stream.Recv(); err != nil || resp.GetError() != "" {
+			t.Fatalf("Recv() = %v, %v", resp, err)
+		}
+	}
+
+	b, err := os.ReadFile(counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range strings.Fields(string(b)) {
+		if count, err := strconv.Atoi(field); err != nil || count > 2 {
+			t.Fatalf("%s uploads spooled at once, want at most 2", field)
+		}
+	}
+}
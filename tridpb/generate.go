@@ -0,0 +1,5 @@
+// Package tridpb contains the protocol buffer definitions of the gRPC
+// identification service.
+package tridpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative trid.proto
@@ -0,0 +1,168 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: trid.proto
+
+package tridpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Identifier_Identify_FullMethodName       = "/trid.v1.Identifier/Identify"
+	Identifier_IdentifyStream_FullMethodName = "/trid.v1.Identifier/IdentifyStream"
+)
+
+// IdentifierClient is the client API for Identifier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Identifier identifies file types with TrID.
+type IdentifierClient interface {
+	// Identify identifies a single file.
+	Identify(ctx context.Context, in *IdentifyRequest, opts ...grpc.CallOption) (*IdentifyResponse, error)
+	// IdentifyStream identifies files as requests are streamed in. Responses are
+	// sent as soon as each identification completes and may arrive out of order;
+	// use IdentifyRequest.id to correlate them. Per-file failures are reported in
+	// IdentifyResponse.error and do not terminate the stream.
+	IdentifyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IdentifyRequest, IdentifyResponse], error)
+}
+
+type identifierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIdentifierClient(cc grpc.ClientConnInterface) IdentifierClient {
+	return &identifierClient{cc}
+}
+
+func (c *identifierClient) Identify(ctx context.Context, in *IdentifyRequest, opts ...grpc.CallOption) (*IdentifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IdentifyResponse)
+	err := c.cc.Invoke(ctx, Identifier_Identify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identifierClient) IdentifyStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IdentifyRequest, IdentifyResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Identifier_ServiceDesc.Streams[0], Identifier_IdentifyStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IdentifyRequest, IdentifyResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Identifier_IdentifyStreamClient = grpc.BidiStreamingClient[IdentifyRequest, IdentifyResponse]
+
+// IdentifierServer is the server API for Identifier service.
+// All implementations must embed UnimplementedIdentifierServer
+// for forward compatibility.
+//
+// Identifier identifies file types with TrID.
+type IdentifierServer interface {
+	// Identify identifies a single file.
+	Identify(context.Context, *IdentifyRequest) (*IdentifyResponse, error)
+	// IdentifyStream identifies files as requests are streamed in. Responses are
+	// sent as soon as each identification completes and may arrive out of order;
+	// use IdentifyRequest.id to correlate them. Per-file failures are reported in
+	// IdentifyResponse.error and do not terminate the stream.
+	IdentifyStream(grpc.BidiStreamingServer[IdentifyRequest, IdentifyResponse]) error
+	mustEmbedUnimplementedIdentifierServer()
+}
+
+// UnimplementedIdentifierServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIdentifierServer struct{}
+
+func (UnimplementedIdentifierServer) Identify(context.Context, *IdentifyRequest) (*IdentifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Identify not implemented")
+}
+func (UnimplementedIdentifierServer) IdentifyStream(grpc.BidiStreamingServer[IdentifyRequest, IdentifyResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method IdentifyStream not implemented")
+}
+func (UnimplementedIdentifierServer) mustEmbedUnimplementedIdentifierServer() {}
+func (UnimplementedIdentifierServer) testEmbeddedByValue()                    {}
+
+// UnsafeIdentifierServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IdentifierServer will
+// result in compilation errors.
+type UnsafeIdentifierServer interface {
+	mustEmbedUnimplementedIdentifierServer()
+}
+
+func RegisterIdentifierServer(s grpc.ServiceRegistrar, srv IdentifierServer) {
+	// If the following call pancis, it indicates UnimplementedIdentifierServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Identifier_ServiceDesc, srv)
+}
+
+func _Identifier_Identify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IdentifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentifierServer).Identify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identifier_Identify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentifierServer).Identify(ctx, req.(*IdentifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identifier_IdentifyStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IdentifierServer).IdentifyStream(&grpc.GenericServerStream[IdentifyRequest, IdentifyResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Identifier_IdentifyStreamServer = grpc.BidiStreamingServer[IdentifyRequest, IdentifyResponse]
+
+// Identifier_ServiceDesc is the grpc.ServiceDesc for Identifier service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Identifier_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "trid.v1.Identifier",
+	HandlerType: (*IdentifierServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Identify",
+			Handler:    _Identifier_Identify_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "IdentifyStream",
+			Handler:       _Identifier_IdentifyStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "trid.proto",
+}
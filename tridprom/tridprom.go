@@ -0,0 +1,85 @@
+// Package tridprom exports the activity of a trid.Trid instance as Prometheus
+// metrics.
+package tridprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/attilabuti/trid"
+)
+
+// Collector is a prometheus.Collector reporting the Stats of a Trid instance.
+//
+// The following metrics are exported:
+//
+//	trid_scans_total                  files identified, successfully or not
+//	trid_scan_errors_total{class}     failed identifications by error class
+//	trid_cache_hits_total             scans answered from the cache
+//	trid_cache_misses_total           scans the cache could not answer
+//	trid_prechecked_total             scans answered without TrID by Options.Precheck
+//	trid_processes_running            TrID processes currently running
+//	trid_process_duration_seconds     histogram of TrID process durations
+type Collector struct {
+	trid *trid.Trid
+
+	scans       *prometheus.Desc
+	errors      *prometheus.Desc
+	cacheHits   *prometheus.Desc
+	cacheMisses *prometheus.Desc
+	prechecked  *prometheus.Desc
+	processes   *prometheus.Desc
+	duration    *prometheus.Desc
+}
+
+// NewCollector returns a Collector for t. Labels are added to every metric,
+// so that several instances can be registered with the same registry.
+func NewCollector(t *trid.Trid, labels prometheus.Labels) *Collector {
+	desc := func(name, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc("trid_"+name, help, variableLabels, labels)
+	}
+
+	return &Collector{
+		trid:        t,
+		scans:       desc("scans_total", "Number of files identified, successfully or not."),
+		errors:      desc("scan_errors_total", "Number of failed file identifications by error class.", "class"),
+		cacheHits:   desc("cache_hits_total", "Number of scans answered from the cache."),
+		cacheMisses: desc("cache_misses_total", "Number of scans the cache could not answer."),
+		prechecked:  desc("prechecked_total", "Number of scans answered without TrID by Options.Precheck."),
+		processes:   desc("processes_running", "Number of TrID processes currently running."),
+		duration:    desc("process_duration_seconds", "Duration of TrID processes."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.scans
+	ch <- c.errors
+	ch <- c.cacheHits
+	ch <- c.cacheMisses
+	ch <- c.prechecked
+	ch <- c.processes
+	ch <- c.duration
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.trid.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.scans, prometheus.CounterValue, float64(s.Scans))
+
+	for class, n := range s.Errors {
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(n), class)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.cacheHits, prometheus.CounterValue, float64(s.CacheHits))
+	ch <- prometheus.MustNewConstMetric(c.cacheMisses, prometheus.CounterValue, float64(s.CacheMisses))
+	ch <- prometheus.MustNewConstMetric(c.prechecked, prometheus.CounterValue, float64(s.Prechecked))
+	ch <- prometheus.MustNewConstMetric(c.processes, prometheus.GaugeValue, float64(s.Processes))
+
+	buckets := make(map[float64]uint64, len(s.Durations.Buckets))
+	for i, bound := range s.Durations.Buckets {
+		buckets[bound] = s.Durations.Counts[i]
+	}
+
+	ch <- prometheus.MustNewConstHistogram(c.duration, s.Durations.Count, s.Durations.Sum.Seconds(), buckets)
+}
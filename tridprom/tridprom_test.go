@@ -0,0 +1,61 @@
+package tridprom
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/attilabuti/trid"
+	"github.com/attilabuti/trid/tridtest"
+)
+
+func TestCollector(t *testing.T) {
+	stub := tridtest.NewStub(t, tridtest.StubOptions{
+		FileTypes: []trid.FileType{{Extension: ".pdf", Probability: 100, Name: "Adobe Portable Document Format"}},
+	})
+
+	dir := t.TempDir()
+	sample := filepath.Join(dir, "sample.pdf")
+	if err := os.WriteFile(sample, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := trid.NewTrid(trid.Options{Cmd: stub.Cmd})
+	if _, err := tr.ScanContext(context.Background(), sample, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.ScanContext(context.Background(), filepath.Join(dir, "missing"), 1); !errors.Is(err, trid.ErrFileNotFound) {
+		t.Fatalf("ScanContext() error = %v, want ErrFileNotFound", err)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(NewCollector(tr, prometheus.Labels{"instance": "test"})); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP trid_scan_errors_total Number of failed file identifications by error class.
+# TYPE trid_scan_errors_total counter
+trid_scan_errors_total{class="file",instance="test"} 1
+# HELP trid_scans_total Number of files identified, successfully or not.
+# TYPE trid_scans_total counter
+trid_scans_total{instance="test"} 2
+# HELP trid_processes_running Number of TrID processes currently running.
+# TYPE trid_processes_running gauge
+trid_processes_running{instance="test"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "trid_scans_total", "trid_scan_errors_total", "trid_processes_running"); err != nil {
+		t.Error(err)
+	}
+
+	if n, err := testutil.GatherAndCount(reg, "trid_process_duration_seconds"); err != nil || n != 1 {
+		t.Errorf("trid_process_duration_seconds count = %d, %v, want 1", n, err)
+	}
+}
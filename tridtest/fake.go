@@ -0,0 +1,149 @@
+// Package tridtest provides utilities for testing code that identifies files
+// with the trid package.
+package tridtest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/attilabuti/trid"
+)
+
+// Response is the programmed result of identifying a file with a Fake.
+type Response struct {
+	FileTypes []trid.FileType // Matches returned, limited to the number requested.
+	Warnings  []string        // Warnings included in reports.
+	Err       error           // Error returned instead of matches.
+}
+
+// Call records a file identified by a Fake.
+type Call struct {
+	Path    string // Path of the file.
+	Matches int    // Number of matches requested.
+}
+
+// Fake is an in-memory trid.Scanner returning programmed responses by path.
+// Paths without a response fail with trid.ErrFileNotFound, unless a default
+// is set. Arguments are validated like trid.Trid does. A Fake is safe for
+// concurrent use.
+type Fake struct {
+	mu         sync.Mutex
+	responses  map[string]Response
+	defaultRes *Response
+	calls      []Call
+}
+
+var _ trid.Scanner = (*Fake)(nil)
+
+// NewFake returns a Fake without responses.
+func NewFake() *Fake {
+	return &Fake{responses: make(map[string]Response)}
+}
+
+// Set programs the response for path.
+func (f *Fake) Set(path string, r Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[path] = r
+}
+
+// SetDefault programs the response for paths without their own response.
+func (f *Fake) SetDefault(r Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.defaultRes = &r
+}
+
+// Calls returns the files identified so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.calls)
+}
+
+// Scan implements trid.Scanner.
+func (f *Fake) Scan(filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	return f.ScanContext(context.Background(), filePath, numberOfMatches)
+}
+
+// ScanContext implements trid.Scanner.
+func (f *Fake) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	r, err := f.ScanReport(ctx, filePath, numberOfMatches)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FileTypes, nil
+}
+
+// ScanReport implements trid.Scanner.
+func (f *Fake) ScanReport(ctx context.Context, filePath string, numberOfMatches int) (*trid.Report, error) {
+	if numberOfMatches < 1 {
+		return nil, trid.ErrNumberOfMatches
+	}
+
+	return f.scan(ctx, filePath, numberOfMatches)
+}
+
+// ScanBatch implements trid.Scanner.
+func (f *Fake) ScanBatch(ctx context.Context, filePaths []string, numberOfMatches int) ([]trid.BatchResult, error) {
+	if numberOfMatches < 1 {
+		return nil, trid.ErrNumberOfMatches
+	}
+
+	results := make([]trid.BatchResult, len(filePaths))
+	for i, path := range filePaths {
+		results[i].Path = path
+
+		r, err := f.scan(ctx, path, numberOfMatches)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if results[i].Err = err; err == nil {
+			results[i].FileTypes, results[i].Warnings = r.FileTypes, r.Warnings
+		}
+	}
+
+	return results, nil
+}
+
+// scan returns the programmed response for filePath, recording the call.
+func (f *Fake) scan(ctx context.Context, filePath string, numberOfMatches int) (*trid.Report, error) {
+	if filePath == "" {
+		return nil, trid.ErrNoFileSpecified
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, Call{Path: filePath, Matches: numberOfMatches})
+
+	r, ok := f.responses[filePath]
+	if !ok {
+		if f.defaultRes == nil {
+			return nil, trid.ErrFileNotFound
+		}
+
+		r = *f.defaultRes
+	}
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	fileTypes := slices.Clone(r.FileTypes)
+	if len(fileTypes) > numberOfMatches {
+		fileTypes = fileTypes[:numberOfMatches]
+	}
+
+	return &trid.Report{FileTypes: fileTypes, Warnings: slices.Clone(r.Warnings)}, nil
+}
@@ -0,0 +1,55 @@
+package tridtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestFake(t *testing.T) {
+	fake := NewFake()
+	fake.Set("report.pdf", Response{FileTypes: []trid.FileType{{Extension: ".pdf"}, {Extension: ".ai"}}})
+	fake.Set("broken.bin", Response{Err: trid.ErrUnknownFileType})
+
+	var scanner trid.Scanner = fake
+
+	if fileTypes, err := scanner.Scan("report.pdf", 1); err != nil || len(fileTypes) != 1 || fileTypes[0].Extension != ".pdf" {
+		t.Errorf("Scan(report.pdf) = %+v, %v", fileTypes, err)
+	}
+
+	if _, err := scanner.Scan("broken.bin", 1); !errors.Is(err, trid.ErrUnknownFileType) {
+		t.Errorf("Scan(broken.bin) error = %v, want ErrUnknownFileType", err)
+	}
+
+	if _, err := scanner.Scan("other", 1); !errors.Is(err, trid.ErrFileNotFound) {
+		t.Errorf("Scan(other) error = %v, want ErrFileNotFound", err)
+	}
+
+	if _, err := scanner.Scan("report.pdf", 0); !errors.Is(err, trid.ErrNumberOfMatches) {
+		t.Errorf("Scan(report.pdf, 0) error = %v, want ErrNumberOfMatches", err)
+	}
+
+	fake.SetDefault(Response{FileTypes: []trid.FileType{{Extension: ".txt"}}})
+
+	results, err := scanner.ScanBatch(context.Background(), []string{"report.pdf", "other", ""}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results[0].FileTypes) != 2 || results[1].FileTypes[0].Extension != ".txt" || !errors.Is(results[2].Err, trid.ErrNoFileSpecified) {
+		t.Errorf("ScanBatch() = %+v", results)
+	}
+
+	expected := []Call{{"report.pdf", 1}, {"broken.bin", 1}, {"other", 1}, {"report.pdf", 5}, {"other", 5}}
+	if calls := fake.Calls(); len(calls) != len(expected) {
+		t.Errorf("Calls() = %+v, want %+v", calls, expected)
+	} else {
+		for i := range expected {
+			if calls[i] != expected[i] {
+				t.Errorf("Calls()[%d] = %+v, want %+v", i, calls[i], expected[i])
+			}
+		}
+	}
+}
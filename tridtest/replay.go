@@ -0,0 +1,129 @@
+package tridtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrNoRecording is returned by a replaying Runner for commands that were not
+// recorded.
+var ErrNoRecording = errors.New("no recording for command")
+
+// recording is a recorded TrID run, stored as JSON.
+type recording struct {
+	Args     []string `json:"args"`
+	Stdin    string   `json:"stdin,omitempty"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+	Err      string   `json:"error,omitempty"`
+}
+
+// Record returns a Runner that runs TrID with r and saves each run in dir,
+// to be replayed with Replay. Runs are keyed by their arguments and standard
+// input, not the command name, so recordings can be replayed on machines
+// with TrID installed elsewhere or not at all, as long as file paths match.
+func Record(dir string, r trid.Runner) trid.Runner {
+	return trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		stdin, err := readStdin(&c)
+		if err != nil {
+			return trid.CommandResult{ExitCode: -1}, err
+		}
+
+		res, runErr := r.Run(ctx, c)
+		if ctx.Err() != nil {
+			// Timeouts and cancellations depend on the circumstances; do not
+			// record them
+			return res, runErr
+		}
+
+		rec := recording{Args: c.Args, Stdin: stdin, Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode}
+		if runErr != nil {
+			rec.Err = runErr.Error()
+		}
+
+		b, err := json.MarshalIndent(rec, "", "  ")
+		if err != nil {
+			return res, err
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return res, err
+		}
+
+		if err := os.WriteFile(recordingPath(dir, c.Args, stdin), append(b, '\n'), 0o644); err != nil {
+			return res, err
+		}
+
+		return res, runErr
+	})
+}
+
+// Replay returns a Runner that serves the runs saved in dir by Record instead
+// of running TrID. Commands without a recording fail with ErrNoRecording.
+func Replay(dir string) trid.Runner {
+	return trid.RunnerFunc(func(ctx context.Context, c trid.Command) (trid.CommandResult, error) {
+		stdin, err := readStdin(&c)
+		if err != nil {
+			return trid.CommandResult{ExitCode: -1}, err
+		}
+
+		b, err := os.ReadFile(recordingPath(dir, c.Args, stdin))
+		if errors.Is(err, os.ErrNotExist) {
+			return trid.CommandResult{ExitCode: -1}, fmt.Errorf("%w: %s", ErrNoRecording, strings.Join(c.Args, " "))
+		} else if err != nil {
+			return trid.CommandResult{ExitCode: -1}, err
+		}
+
+		var rec recording
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return trid.CommandResult{ExitCode: -1}, err
+		}
+
+		res := trid.CommandResult{Stdout: rec.Stdout, Stderr: rec.Stderr, ExitCode: rec.ExitCode}
+		if rec.Err != "" {
+			return res, errors.New(rec.Err)
+		}
+
+		return res, nil
+	})
+}
+
+// readStdin reads the standard input of c and replaces it with a reader of
+// the same contents.
+func readStdin(c *trid.Command) (string, error) {
+	if c.Stdin == nil {
+		return "", nil
+	}
+
+	b, err := io.ReadAll(c.Stdin)
+	if err != nil {
+		return "", err
+	}
+
+	c.Stdin = strings.NewReader(string(b))
+
+	return string(b), nil
+}
+
+// recordingPath returns the path of the recording of a run.
+func recordingPath(dir string, args []string, stdin string) string {
+	h := sha256.New()
+	for _, arg := range args {
+		io.WriteString(h, arg)
+		h.Write([]byte{0})
+	}
+	io.WriteString(h, stdin)
+
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))[:16]+".json")
+}
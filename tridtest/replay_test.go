@@ -0,0 +1,48 @@
+package tridtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+func TestRecordReplay(t *testing.T) {
+	stub := NewStub(t, StubOptions{FileTypes: []trid.FileType{pdf}})
+	recordings := t.TempDir()
+
+	sample := filepath.Join(t.TempDir(), "sample.pdf")
+	if err := os.WriteFile(sample, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := trid.NewTrid(trid.Options{Cmd: stub.Cmd, Runner: Record(recordings, trid.ExecRunner{}), FileList: trid.FileListStdin})
+	if _, err := recorder.Scan(sample, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recorder.ScanBatch(context.Background(), []string{sample, sample}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replaying instance must not need the executable
+	os.Remove(stub.Cmd)
+	replayer := trid.NewTrid(trid.Options{Cmd: stub.Cmd, Runner: Replay(recordings), FileList: trid.FileListStdin})
+
+	fileTypes, err := replayer.Scan(sample, 1)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0] != pdf {
+		t.Errorf("replayed Scan() = %+v, %v", fileTypes, err)
+	}
+
+	results, err := replayer.ScanBatch(context.Background(), []string{sample, sample}, 1)
+	if err != nil || results[0].Err != nil || results[1].FileTypes[0] != pdf {
+		t.Errorf("replayed ScanBatch() = %+v, %v", results, err)
+	}
+
+	if _, err := replayer.Scan(sample, 2); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("Scan() of unrecorded command error = %v, want ErrNoRecording", err)
+	}
+}
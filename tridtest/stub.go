@@ -0,0 +1,160 @@
+package tridtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+// StubOptions configures the output of a stub TrID executable.
+type StubOptions struct {
+	FileTypes []trid.FileType            // Matches reported for files not listed in Files; none reports "Unknown!".
+	Files     map[string][]trid.FileType // Matches reported by path, as passed to TrID.
+	Output    string                     // Printed verbatim instead of a generated report, if not empty.
+	Stderr    string                     // Printed on standard error.
+	ExitCode  int                        // Exit code.
+}
+
+// Stub is a fake TrID executable emitting canned output, so that tests run
+// hermetically on machines without TrID installed. It prints a TrID 2.24
+// report with a section for each file passed as argument or, with -@, on
+// standard input, and records its invocations.
+type Stub struct {
+	Cmd         string // Path of the executable, for Options.Cmd.
+	Definitions string // Path of the definitions package next to it.
+
+	calls string
+}
+
+// sep terminates the arguments of each call in the call log. Arguments are
+// terminated by NUL bytes.
+const sep = "\x01"
+
+// NewStub creates a stub TrID executable in a temporary directory removed
+// when the test ends. The stub is a POSIX shell script; the test is skipped
+// on Windows.
+func NewStub(t testing.TB, opts StubOptions) *Stub {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("stub executable requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	s := &Stub{
+		Cmd:         filepath.Join(dir, "trid"),
+		Definitions: filepath.Join(dir, "triddefs.trd"),
+		calls:       filepath.Join(dir, "calls"),
+	}
+
+	files := map[string]string{
+		"triddefs.trd": "TRID-DEFS",
+		"stderr.txt":   opts.Stderr,
+		"default.txt":  Report(opts.FileTypes...),
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&script, "printf '%%s\\0' \"$@\" %s >> %s\n", quote(sep), quote(s.calls))
+	fmt.Fprintf(&script, "cat %s >&2\n", quote(filepath.Join(dir, "stderr.txt")))
+
+	if opts.Output != "" {
+		files["output.txt"] = opts.Output
+		fmt.Fprintf(&script, "cat %s\n", quote(filepath.Join(dir, "output.txt")))
+	} else {
+		script.WriteString("echo 'TrID/32 - File Identifier v2.24 - (C) 2003-16 By M.Pontello'\n")
+		script.WriteString("echo 'Definitions found:  18452'\n")
+		script.WriteString("echo 'Analyzing...'\n")
+		script.WriteString("report() {\n  echo\n  echo \"File: $1\"\n  case \"$1\" in\n")
+
+		paths := make([]string, 0, len(opts.Files))
+		for path := range opts.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for i, path := range paths {
+			name := fmt.Sprintf("file%d.txt", i)
+			files[name] = Report(opts.Files[path]...)
+			fmt.Fprintf(&script, "    %s) cat %s ;;\n", quote(path), quote(filepath.Join(dir, name)))
+		}
+
+		fmt.Fprintf(&script, "    *) cat %s ;;\n  esac\n}\n", quote(filepath.Join(dir, "default.txt")))
+		script.WriteString("for f in \"$@\"; do\n  case \"$f\" in\n")
+		script.WriteString("    -@) while IFS= read -r p; do report \"$p\"; done ;;\n")
+		script.WriteString("    -*) ;;\n    *) report \"$f\" ;;\n  esac\ndone\n")
+	}
+
+	fmt.Fprintf(&script, "exit %d\n", opts.ExitCode)
+	files["trid"] = script.String()
+
+	for name, content := range files {
+		mode := os.FileMode(0o644)
+		if name == "trid" {
+			mode = 0o755
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+// Calls returns the arguments of each invocation of the stub so far.
+func (s *Stub) Calls() [][]string {
+	b, err := os.ReadFile(s.calls)
+	if err != nil {
+		return nil
+	}
+
+	var calls [][]string
+	for _, call := range strings.SplitAfter(string(b), sep+"\x00") {
+		if call == "" {
+			continue
+		}
+
+		args := strings.Split(strings.TrimSuffix(call, sep+"\x00"), "\x00")
+		calls = append(calls, args[:len(args)-1])
+	}
+
+	return calls
+}
+
+// Report returns the lines TrID 2.24 prints for a file matching fileTypes,
+// without the file header, or "Unknown!" if there are none.
+func Report(fileTypes ...trid.FileType) string {
+	if len(fileTypes) == 0 {
+		return "Unknown!\n"
+	}
+
+	var b strings.Builder
+	for _, f := range fileTypes {
+		fmt.Fprintf(&b, "%5.1f%% (%s) %s (1/1)\n", f.Probability, strings.ToUpper(f.Extension), f.Name)
+
+		for _, detail := range [][2]string{
+			{"Mime type", f.MimeType},
+			{"Related URL", f.RelatedURL},
+			{"Remarks", f.Remarks},
+			{"Definition", f.Definition},
+		} {
+			if detail[1] != "" {
+				fmt.Fprintf(&b, "        %-16s: %s\n", detail[0], detail[1])
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// quote quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,78 @@
+package tridtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/attilabuti/trid"
+)
+
+var pdf = trid.FileType{
+	Extension:   ".pdf",
+	Probability: 100,
+	Name:        "Adobe Portable Document Format",
+	MimeType:    "application/pdf",
+	Definition:  "pdf-generic.trid.xml",
+}
+
+func TestStub(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.pdf"), filepath.Join(dir, "b.bin"), filepath.Join(dir, "it's.bin")}
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stub := NewStub(t, StubOptions{
+		FileTypes: []trid.FileType{pdf},
+		Files:     map[string][]trid.FileType{paths[1]: nil},
+	})
+
+	tr := trid.NewTrid(trid.Options{Cmd: stub.Cmd, Definitions: stub.Definitions})
+
+	fileTypes, err := tr.Scan(paths[0], 1)
+	if err != nil || len(fileTypes) != 1 || fileTypes[0] != pdf {
+		t.Errorf("Scan() = %+v, %v, want %+v", fileTypes, err, pdf)
+	}
+
+	if _, err := tr.Scan(paths[1], 1); !errors.Is(err, trid.ErrUnknownFileType) {
+		t.Errorf("Scan() error = %v, want ErrUnknownFileType", err)
+	}
+
+	for _, mode := range []trid.FileListMode{trid.FileListArgs, trid.FileListStdin} {
+		tr := trid.NewTrid(trid.Options{Cmd: stub.Cmd, FileList: mode})
+
+		results, err := tr.ScanBatch(context.Background(), paths, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if results[0].Err != nil || !errors.Is(results[1].Err, trid.ErrUnknownFileType) || results[2].Err != nil {
+			t.Errorf("ScanBatch() with mode %d = %+v", mode, results)
+		}
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 4 || !slices.Equal(calls[0], []string{"-v", "-n:1", "-d:" + stub.Definitions, paths[0]}) {
+		t.Errorf("Calls() = %q", calls)
+	}
+}
+
+func TestStubOutput(t *testing.T) {
+	stub := NewStub(t, StubOptions{Output: "No definitions available!\n", Stderr: "fatal\n", ExitCode: 1})
+
+	_, err := trid.NewTrid(trid.Options{Cmd: stub.Cmd}).Scan("stub_test.go", 1)
+	if !errors.Is(err, trid.ErrNoDefinitions) {
+		t.Errorf("Scan() error = %v, want ErrNoDefinitions", err)
+	}
+
+	var tridErr *trid.TridError
+	if errors.As(err, &tridErr) && (tridErr.ExitCode != 1 || tridErr.Stderr != "fatal\n") {
+		t.Errorf("TridError = %+v, want exit code 1 and stderr", tridErr)
+	}
+}
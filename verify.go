@@ -0,0 +1,113 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+)
+
+// Kinds of divergences between the native engine and TrID.
+const (
+	DivergenceTopMatch    = "top_match"   // The most likely matches differ.
+	DivergenceProbability = "probability" // The probabilities of a match differ by more than the allowed delta.
+	DivergenceError       = "error"       // Only one engine identified the file, or they failed differently.
+)
+
+// DefaultMaxDelta is the probability difference, in percentage points, above
+// which Verify reports a divergence if none is given.
+const DefaultMaxDelta = 5
+
+// Divergence is a difference between the results of the native engine and
+// TrID for a file.
+type Divergence struct {
+	Kind   string   // DivergenceTopMatch, DivergenceProbability or DivergenceError.
+	Native FileType // Match of the native engine, if any.
+	CLI    FileType // Match of TrID, if any.
+	Delta  float64  // Probability of the native match minus that of TrID, for DivergenceProbability.
+}
+
+// Verification is the outcome of identifying a file with both the native
+// engine and TrID.
+type Verification struct {
+	Path        string
+	Native      []FileType // Matches of the native engine.
+	NativeErr   error      // Error of the native engine.
+	CLI         []FileType // Matches of TrID.
+	CLIErr      error      // Error of TrID.
+	Divergences []Divergence
+}
+
+// Diverged reports whether the engines disagree.
+func (v *Verification) Diverged() bool {
+	return len(v.Divergences) > 0
+}
+
+// Verify identifies the file at filePath with native, typically a Trid using
+// EngineNative, and cli, typically one using EngineCLI, and compares their
+// results, to build trust in the native engine before switching to it. Both
+// engines reporting ErrUnknownFileType agree.
+//
+// The most likely matches must be the same definition, or have the same
+// extension and name if TrID did not report definitions, and every match
+// found by both must have probabilities at most maxDelta percentage points
+// apart; a maxDelta of 0 means DefaultMaxDelta.
+func Verify(ctx context.Context, native, cli Scanner, filePath string, numberOfMatches int, maxDelta float64) *Verification {
+	if maxDelta <= 0 {
+		maxDelta = DefaultMaxDelta
+	}
+
+	v := &Verification{Path: filePath}
+	v.Native, v.NativeErr = native.ScanContext(ctx, filePath, numberOfMatches)
+	v.CLI, v.CLIErr = cli.ScanContext(ctx, filePath, numberOfMatches)
+
+	if v.NativeErr != nil || v.CLIErr != nil {
+		if !bothUnknown(v.NativeErr, v.CLIErr) {
+			d := Divergence{Kind: DivergenceError}
+			if len(v.Native) > 0 {
+				d.Native = v.Native[0]
+			}
+			if len(v.CLI) > 0 {
+				d.CLI = v.CLI[0]
+			}
+
+			v.Divergences = append(v.Divergences, d)
+		}
+
+		return v
+	}
+
+	if !sameMatch(v.Native[0], v.CLI[0]) {
+		v.Divergences = append(v.Divergences, Divergence{Kind: DivergenceTopMatch, Native: v.Native[0], CLI: v.CLI[0]})
+	}
+
+	for _, c := range v.CLI {
+		for _, n := range v.Native {
+			if !sameMatch(n, c) {
+				continue
+			}
+
+			if delta := n.Probability - c.Probability; math.Abs(delta) > maxDelta {
+				v.Divergences = append(v.Divergences, Divergence{Kind: DivergenceProbability, Native: n, CLI: c, Delta: delta})
+			}
+			break
+		}
+	}
+
+	return v
+}
+
+// bothUnknown reports whether both engines failed to identify the file.
+func bothUnknown(nativeErr, cliErr error) bool {
+	return errors.Is(nativeErr, ErrUnknownFileType) && errors.Is(cliErr, ErrUnknownFileType)
+}
+
+// sameMatch reports whether a native match and a TrID match are the same file
+// type.
+func sameMatch(native, cli FileType) bool {
+	if native.Definition != "" && cli.Definition != "" {
+		return strings.EqualFold(native.Definition, cli.Definition)
+	}
+
+	return native.Extension == cli.Extension && native.Name == cli.Name
+}
@@ -0,0 +1,68 @@
+package trid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	pdf := FileType{Extension: ".pdf", Probability: 80, Name: "Adobe Portable Document Format", Definition: "pdf.trid.xml"}
+	ai := FileType{Extension: ".ai", Probability: 20, Name: "Adobe Illustrator graphics", Definition: "ai.trid.xml"}
+	shifted := func(ft FileType, delta float64) FileType {
+		ft.Probability += delta
+		return ft
+	}
+
+	tests := []struct {
+		name          string
+		native        Scanner
+		cli           Scanner
+		expectedKinds []string
+	}{
+		{"Agreement", engineFunc([]FileType{pdf, ai}, nil), engineFunc([]FileType{shifted(pdf, 4), shifted(ai, -4)}, nil), nil},
+		{"Top match", engineFunc([]FileType{ai}, nil), engineFunc([]FileType{pdf}, nil), []string{DivergenceTopMatch}},
+		{"Probability", engineFunc([]FileType{pdf, ai}, nil), engineFunc([]FileType{shifted(pdf, 10), shifted(ai, -10)}, nil),
+			[]string{DivergenceProbability, DivergenceProbability}},
+		{"Without definitions", engineFunc([]FileType{pdf}, nil), engineFunc([]FileType{{Extension: ".pdf", Probability: 80, Name: pdf.Name}}, nil), nil},
+		{"Both unknown", engineFunc(nil, nil), engineFunc(nil, ErrUnknownFileType), nil},
+		{"Only native", engineFunc([]FileType{pdf}, nil), engineFunc(nil, ErrUnknownFileType), []string{DivergenceError}},
+		{"Only TrID", engineFunc(nil, ErrNoDefinitions), engineFunc([]FileType{pdf}, nil), []string{DivergenceError}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Verify(context.Background(), tt.native, tt.cli, "testdata/sample.pdf", 5, 0)
+
+			var kinds []string
+			for _, d := range v.Divergences {
+				kinds = append(kinds, d.Kind)
+			}
+
+			if len(kinds) != len(tt.expectedKinds) || v.Diverged() != (len(tt.expectedKinds) > 0) {
+				t.Fatalf("Verify() divergences = %+v, want %q", v.Divergences, tt.expectedKinds)
+			}
+
+			for i := range kinds {
+				if kinds[i] != tt.expectedKinds[i] {
+					t.Errorf("Verify() divergences = %q, want %q", kinds, tt.expectedKinds)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyEngines(t *testing.T) {
+	cmd, _ := newStub(t, pdfOutput)
+	native := NewTrid(Options{Definitions: "testdata/defs", Engine: EngineNative})
+	cli := NewTrid(Options{Cmd: cmd})
+
+	v := Verify(context.Background(), native, cli, "testdata/sample.pdf", 5, 1)
+	if v.NativeErr != nil || v.CLIErr != nil {
+		t.Fatalf("Verify() errors = %v, %v", v.NativeErr, v.CLIErr)
+	}
+
+	// TrID reports the generic definition the test definitions lack
+	if len(v.Divergences) != 1 || v.Divergences[0].Kind != DivergenceTopMatch || v.Divergences[0].CLI.Definition != "pdf-generic.trid.xml" {
+		t.Errorf("Verify() divergences = %+v, want a different top match", v.Divergences)
+	}
+}
@@ -0,0 +1,46 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrUnknownVersion is returned when the TrID version cannot be found in its
+// output.
+var ErrUnknownVersion = errors.New("TrID version not found in output")
+
+// version memoizes the TrID version.
+type version struct {
+	mu    sync.Mutex
+	value string
+}
+
+// Version runs TrID without files and returns the version printed in its
+// banner, such as "2.24". The result is cached for the lifetime of the Trid
+// instance; failures are not cached.
+func (t *Trid) Version(ctx context.Context) (string, error) {
+	v := &t.version
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.value != "" {
+		return v.value, nil
+	}
+
+	// TrID prints its banner and usage, exiting with an error, when no file
+	// is given
+	p, err := t.exec(ctx, t.options.Timeout, "")
+	b := parseBanner(p.stdout)
+	if !b.found {
+		if err != nil {
+			return "", err
+		}
+
+		return "", p.error(ErrUnknownVersion)
+	}
+
+	v.value = b.version
+
+	return v.value, nil
+}
@@ -0,0 +1,42 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	cmd, calls := newStub(t, pdfOutput)
+
+	trid := NewTrid(Options{Cmd: cmd})
+	for i := 0; i < 2; i++ {
+		v, err := trid.Version(context.Background())
+		if err != nil {
+			t.Fatalf("Version() error = %v", err)
+		}
+
+		if v != "2.24" {
+			t.Errorf("Version() = %q, want %q", v, "2.24")
+		}
+	}
+
+	if got := calls(); got != 1 {
+		t.Errorf("TrID invoked %d times, want 1", got)
+	}
+}
+
+func TestVersionUnknown(t *testing.T) {
+	cmd, calls := newStub(t, "usage: trid [options] files\n")
+
+	trid := NewTrid(Options{Cmd: cmd})
+	for i := 0; i < 2; i++ {
+		if _, err := trid.Version(context.Background()); !errors.Is(err, ErrUnknownVersion) {
+			t.Fatalf("Version() error = %v, want %v", err, ErrUnknownVersion)
+		}
+	}
+
+	if got := calls(); got != 2 {
+		t.Errorf("TrID invoked %d times, want 2", got)
+	}
+}
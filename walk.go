@@ -0,0 +1,170 @@
+package trid
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// WalkOptions configures ScanWalk.
+type WalkOptions struct {
+	Symlinks        SymlinkPolicy // How symbolic links are handled while walking, as by Walk (default: SymlinkFollow).
+	Checkpoint      string        // File recording the files done, so an interrupted walk resumes where it stopped; empty disables checkpoints.
+	CheckpointEvery int           // Number of files identified between checkpoints (default: 1000).
+}
+
+// ScanWalk identifies the regular files in the trees rooted at roots, listed
+// by Walk, with ScanBatch, and passes each result to fn in walk order. Files
+// are identified in chunks of WalkOptions.CheckpointEvery files, and
+// Options.Progress reports the progress of the whole walk, across chunks; with
+// a checkpoint, the files it lists are left out of the totals.
+//
+// With WalkOptions.Checkpoint set, the paths of the files of each chunk are
+// appended to the checkpoint file once fn has returned for all of them, and
+// the files it lists are skipped, so a walk interrupted by a crash or a
+// cancelled context resumes after the last checkpoint instead of restarting.
+// Results between the last checkpoint and the interruption are passed to fn
+// again. The checkpoint file is removed once every file has been identified.
+//
+// Directories that cannot be read are passed to fn as results with the error,
+// in place of their files, and the walk goes on. ScanWalk stops with the
+// error of fn, if any, or the error of ScanBatch affecting a whole chunk;
+// errors of individual files are in their results.
+func (t *Trid) ScanWalk(ctx context.Context, roots []string, numberOfMatches int, opts WalkOptions, fn func(BatchResult) error) error {
+	if opts.CheckpointEvery < 1 {
+		opts.CheckpointEvery = 1000
+	}
+
+	var entries []walkEntry
+	for _, root := range roots {
+		rootEntries, err := walkTree(root, opts.Symlinks)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, rootEntries...)
+	}
+
+	var cp *os.File
+	if opts.Checkpoint != "" {
+		done, err := readCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return err
+		}
+
+		pending := entries[:0]
+		for _, e := range entries {
+			if e.err != nil || !done[e.path] {
+				pending = append(pending, e)
+			}
+		}
+		entries = pending
+
+		if cp, err = os.OpenFile(opts.Checkpoint, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+		defer cp.Close()
+	}
+
+	var all []string
+	for _, e := range entries {
+		if e.err == nil {
+			all = append(all, e.path)
+		}
+	}
+
+	// A single tracker reports the progress of the whole walk
+	tracker, base := t.newProgress(all), 0
+	for start := 0; start < len(entries); start += opts.CheckpointEvery {
+		chunk := entries[start:min(start+opts.CheckpointEvery, len(entries))]
+
+		paths := make([]string, 0, len(chunk))
+		for _, e := range chunk {
+			if e.err == nil {
+				paths = append(paths, e.path)
+			}
+		}
+
+		results, err := t.scanTracked(ctx, paths, numberOfMatches, tracker, base)
+		if err != nil {
+			return err
+		}
+		base += len(paths)
+
+		for _, e := range chunk {
+			r := BatchResult{Path: e.path, Err: e.err}
+			if e.err == nil {
+				r, results = results[0], results[1:]
+			}
+
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if cp != nil {
+			if err := writeCheckpoint(cp, paths); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cp == nil {
+		return nil
+	}
+
+	if err := cp.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(opts.Checkpoint)
+}
+
+// readCheckpoint returns the paths listed in the checkpoint file at path, one
+// JSON string per line. A missing file lists no paths, and a line cut short by
+// a crash is ignored.
+func readCheckpoint(path string) (map[string]bool, error) {
+	done := make(map[string]bool)
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var p string
+		if err := json.Unmarshal(sc.Bytes(), &p); err == nil {
+			done[p] = true
+		}
+	}
+
+	return done, sc.Err()
+}
+
+// writeCheckpoint appends paths to the checkpoint file f and flushes it to
+// stable storage.
+func writeCheckpoint(f *os.File, paths []string) error {
+	w := bufio.NewWriter(f)
+	for _, path := range paths {
+		b, err := json.Marshal(path)
+		if err != nil {
+			return err
+		}
+
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
@@ -0,0 +1,155 @@
+package trid
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestScanWalk(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+
+	paths := writeFiles(t, "%PDF-1", "%PDF-2", "%PDF-3", "binary", "%PDF-5")
+	root := filepath.Dir(paths[0])
+	checkpoint := filepath.Join(t.TempDir(), "walk.checkpoint")
+	opts := WalkOptions{Checkpoint: checkpoint, CheckpointEvery: 2}
+
+	trid := NewTrid(Options{Cmd: cmd})
+	errStop := errors.New("stop")
+
+	// Interrupt the walk in the second chunk
+	var seen []string
+	err := trid.ScanWalk(context.Background(), []string{root}, 1, opts, func(r BatchResult) error {
+		if len(seen) == 3 {
+			return errStop
+		}
+		seen = append(seen, r.Path)
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("ScanWalk() error = %v, want %v", err, errStop)
+	}
+
+	// Append a line cut short by a crash
+	f, err := os.OpenFile(checkpoint, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("checkpoint not written: %v", err)
+	}
+	f.WriteString(`"` + paths[2])
+	f.Close()
+
+	var resumed []string
+	var failed int
+	err = trid.ScanWalk(context.Background(), []string{root}, 1, opts, func(r BatchResult) error {
+		resumed = append(resumed, r.Path)
+		if r.Err != nil {
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanWalk() error = %v", err)
+	}
+
+	if !slices.Equal(resumed, paths[2:]) {
+		t.Errorf("ScanWalk() resumed with %q, want %q", resumed, paths[2:])
+	}
+
+	if failed != 1 {
+		t.Errorf("ScanWalk() reported %d failed files, want 1", failed)
+	}
+
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed after the walk: %v", err)
+	}
+}
+
+func TestScanWalkWithoutCheckpoint(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	paths := writeFiles(t, "%PDF-1", "%PDF-2", "%PDF-3")
+
+	var seen []string
+	err := NewTrid(Options{Cmd: cmd}).ScanWalk(context.Background(), []string{filepath.Dir(paths[0])}, 1, WalkOptions{}, func(r BatchResult) error {
+		seen = append(seen, r.Path)
+		return nil
+	})
+	if err != nil || !slices.Equal(seen, paths) {
+		t.Errorf("ScanWalk() = %q, %v, want %q", seen, err, paths)
+	}
+
+	if err := NewTrid(Options{Cmd: cmd}).ScanWalk(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, 1, WalkOptions{}, nil); !os.IsNotExist(err) {
+		t.Errorf("ScanWalk() error = %v, want not exist", err)
+	}
+}
+
+func TestScanWalkProgress(t *testing.T) {
+	cmd, _ := newBatchStub(t)
+	paths := writeFiles(t, "%PDF-1", "%PDF-2", "%PDF-3", "%PDF-4", "%PDF-5")
+
+	var reports []Progress
+	trid := NewTrid(Options{Cmd: cmd, Progress: func(p Progress) { reports = append(reports, p) }})
+
+	err := trid.ScanWalk(context.Background(), []string{filepath.Dir(paths[0])}, 1, WalkOptions{CheckpointEvery: 2}, func(BatchResult) error { return nil })
+	if err != nil {
+		t.Fatalf("ScanWalk() error = %v", err)
+	}
+
+	// One tracker counts the files of every chunk
+	for i, p := range reports {
+		if p.FilesTotal != len(paths) || p.BytesTotal != 30 || (i > 0 && p.FilesDone <= reports[i-1].FilesDone) {
+			t.Fatalf("report %d = %+v, want totals of the whole walk", i, p)
+		}
+	}
+
+	if last := reports[len(reports)-1]; last.FilesDone != len(paths) || last.BytesDone != last.BytesTotal || last.Current != paths[4] {
+		t.Errorf("last report = %+v, want every file done", last)
+	}
+}
+
+func TestScanWalkUnreadableDir(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced")
+	}
+
+	cmd, _ := newBatchStub(t)
+	paths := writeFiles(t, "%PDF-1", "%PDF-2")
+	root := filepath.Dir(paths[0])
+
+	locked := filepath.Join(root, "locked")
+	if err := os.Mkdir(locked, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(locked, "hidden.pdf"), []byte("%PDF-3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	var seen []string
+	var failed []BatchResult
+	err := NewTrid(Options{Cmd: cmd}).ScanWalk(context.Background(), []string{root}, 1, WalkOptions{}, func(r BatchResult) error {
+		seen = append(seen, r.Path)
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanWalk() error = %v", err)
+	}
+
+	if expected := append(slices.Clone(paths), locked); !slices.Equal(seen, expected) {
+		t.Errorf("ScanWalk() = %q, want %q", seen, expected)
+	}
+
+	if len(failed) != 1 || failed[0].Path != locked || !errors.Is(failed[0].Err, fs.ErrPermission) {
+		t.Errorf("failed results = %+v, want the locked directory with a permission error", failed)
+	}
+}
@@ -0,0 +1,345 @@
+// Package watch monitors directories and identifies files with TrID as they
+// are created or modified.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/attilabuti/trid"
+)
+
+// ErrClosed is returned when using a watcher that has been closed.
+var ErrClosed = errors.New("watcher closed")
+
+// Scanner identifies files. *trid.Trid satisfies this interface.
+type Scanner interface {
+	ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error)
+}
+
+// Options configures a Watcher.
+type Options struct {
+	Recursive       bool                   // Watch subdirectories, including ones created later.
+	Debounce        time.Duration          // Quiet period after the last change before a file is identified (default: 500ms).
+	NumberOfMatches int                    // Number of matches to request per file (default: 5).
+	MaxConcurrent   int                    // Maximum number of concurrent identifications (default: 1).
+	Filter          func(path string) bool // If set, only files for which Filter returns true are identified.
+	Handler         func(Event)            // If set, events are delivered to Handler instead of the Events channel.
+}
+
+// Event is the identification result of a created or modified file.
+type Event struct {
+	Path      string          // Path of the file.
+	FileTypes []trid.FileType // Identified file types.
+	Err       error           // Error if the identification failed.
+}
+
+// Watcher identifies files as they appear in watched directories.
+type Watcher struct {
+	scanner Scanner
+	opts    Options
+	fsw     *fsnotify.Watcher
+	events  chan Event
+	ready   chan string
+	done    chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+	closed  bool
+}
+
+// New returns a Watcher that identifies files with s. Directories are added
+// with Add, and events are produced once Run is called.
+func New(s Scanner, opts Options) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = 500 * time.Millisecond
+	}
+
+	if opts.NumberOfMatches < 1 {
+		opts.NumberOfMatches = 5
+	}
+
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 1
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		scanner: s,
+		opts:    opts,
+		fsw:     fsw,
+		events:  make(chan Event),
+		ready:   make(chan string),
+		done:    make(chan struct{}),
+		pending: make(map[string]*time.Timer),
+	}, nil
+}
+
+// Add starts watching dir, and its subdirectories if Options.Recursive is
+// set. Files already present are not identified.
+func (w *Watcher) Add(dir string) error {
+	if !w.opts.Recursive {
+		return w.fsw.Add(dir)
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return w.fsw.Add(path)
+		}
+
+		return nil
+	})
+}
+
+// Events returns the channel results are delivered on when no Handler is
+// configured. The channel is closed when Run returns.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Run processes file system notifications until ctx is cancelled or the
+// watcher is closed. Files are identified once they have not changed for the
+// debounce period, so files still being written are not scanned prematurely.
+// Watch errors, such as event queue overflows, are delivered as events with an
+// empty Path. Run may only be called once.
+func (w *Watcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan string)
+	errQueue := make(chan error)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.MaxConcurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range queue {
+				w.identify(ctx, path)
+			}
+		}()
+	}
+
+	// Watch errors are delivered by a goroutine of their own, so a slow
+	// consumer does not stop notifications from being read
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for err := range errQueue {
+			w.deliver(ctx, Event{Err: err})
+		}
+	}()
+
+	defer func() {
+		close(w.done)
+		w.stopTimers()
+		close(queue)
+		close(errQueue)
+		wg.Wait()
+		close(w.events)
+	}()
+
+	// Files ready while every worker is busy wait in backlog, in order, so
+	// notifications keep being read; a file readied again before a worker
+	// takes it is only identified once
+	var backlog []string
+	queued := make(map[string]bool)
+
+	// Watch errors wait likewise until they can be delivered
+	var errBacklog []error
+
+	for {
+		var next string
+		var dispatch chan<- string
+		if len(backlog) > 0 {
+			next, dispatch = backlog[0], queue
+		}
+
+		var nextErr error
+		var dispatchErr chan<- error
+		if len(errBacklog) > 0 {
+			nextErr, dispatchErr = errBacklog[0], errQueue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case dispatch <- next:
+			backlog = backlog[1:]
+			delete(queued, next)
+
+		case dispatchErr <- nextErr:
+			errBacklog = errBacklog[1:]
+
+		case path := <-w.ready:
+			if !queued[path] {
+				queued[path] = true
+				backlog = append(backlog, path)
+			}
+
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+
+			w.handle(ev)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+
+			// Errors such as event queue overflows are reported without a path
+			errBacklog = append(errBacklog, err)
+		}
+	}
+}
+
+// Close stops watching and makes Run return.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	return w.fsw.Close()
+}
+
+// handle reacts to a single file system notification.
+func (w *Watcher) handle(ev fsnotify.Event) {
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		w.cancel(ev.Name)
+		return
+	}
+
+	if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+		return
+	}
+
+	fi, err := os.Lstat(ev.Name)
+	if err != nil {
+		return
+	}
+
+	if fi.IsDir() {
+		if w.opts.Recursive && ev.Has(fsnotify.Create) {
+			w.addNew(ev.Name)
+		}
+		return
+	}
+
+	if fi.Mode().IsRegular() {
+		w.schedule(ev.Name)
+	}
+}
+
+// addNew watches a directory created after Run started and schedules the
+// files it already contains, which may have been moved in with it.
+func (w *Watcher) addNew(dir string) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			w.fsw.Add(path)
+		} else if d.Type().IsRegular() {
+			w.schedule(path)
+		}
+
+		return nil
+	})
+}
+
+// schedule (re)starts the debounce timer of path.
+func (w *Watcher) schedule(path string) {
+	if w.opts.Filter != nil && !w.opts.Filter(path) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[path]; ok {
+		t.Reset(w.opts.Debounce)
+		return
+	}
+
+	w.pending[path] = time.AfterFunc(w.opts.Debounce, func() {
+		w.mu.Lock()
+		_, ok := w.pending[path]
+		delete(w.pending, path)
+		w.mu.Unlock()
+
+		if ok {
+			select {
+			case w.ready <- path:
+			case <-w.done:
+			}
+		}
+	})
+}
+
+// cancel stops the debounce timer of path.
+func (w *Watcher) cancel(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[path]; ok {
+		t.Stop()
+		delete(w.pending, path)
+	}
+}
+
+// stopTimers stops every pending debounce timer.
+func (w *Watcher) stopTimers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for path, t := range w.pending {
+		t.Stop()
+		delete(w.pending, path)
+	}
+}
+
+// identify scans path and delivers the result.
+func (w *Watcher) identify(ctx context.Context, path string) {
+	fileTypes, err := w.scanner.ScanContext(ctx, path, w.opts.NumberOfMatches)
+	if ctx.Err() != nil {
+		return
+	}
+
+	w.deliver(ctx, Event{Path: path, FileTypes: fileTypes, Err: err})
+}
+
+// deliver passes ev to the handler or the events channel.
+func (w *Watcher) deliver(ctx context.Context, ev Event) {
+	if w.opts.Handler != nil {
+		w.opts.Handler(ev)
+		return
+	}
+
+	select {
+	case w.events <- ev:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,236 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/attilabuti/trid"
+)
+
+// countingScanner reports every file as a PDF and counts scans per path.
+type countingScanner struct {
+	mu    sync.Mutex
+	scans map[string]int
+}
+
+func (c *countingScanner) ScanContext(ctx context.Context, filePath string, numberOfMatches int) ([]trid.FileType, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scans[filePath]++
+
+	return []trid.FileType{{Extension: ".pdf", Probability: 100}}, nil
+}
+
+func newWatcher(t *testing.T, opts Options) (*Watcher, *countingScanner, string) {
+	t.Helper()
+
+	s := &countingScanner{scans: make(map[string]int)}
+	w, err := New(s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go w.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		w.Close()
+	})
+
+	return w, s, dir
+}
+
+func waitEvent(t *testing.T, w *Watcher) Event {
+	t.Helper()
+
+	select {
+	case ev := <-w.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	w, s, dir := newWatcher(t, Options{Debounce: 200 * time.Millisecond})
+
+	path := filepath.Join(dir, "upload.pdf")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write in several chunks, each within the debounce period
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("%PDF-1.4\n"))
+		time.Sleep(20 * time.Millisecond)
+	}
+	f.Close()
+
+	ev := waitEvent(t, w)
+	if ev.Path != path || ev.Err != nil || len(ev.FileTypes) != 1 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	select {
+	case ev := <-w.Events():
+		t.Errorf("unexpected second event: %+v", ev)
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scans[path] != 1 {
+		t.Errorf("file scanned %d times, want 1", s.scans[path])
+	}
+}
+
+func TestWatchBusyWorkers(t *testing.T) {
+	w, s, dir := newWatcher(t, Options{Debounce: 50 * time.Millisecond})
+
+	scans := func(path string) int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.scans[path]
+	}
+
+	// The only worker blocks delivering the event of a until it is read
+	a := filepath.Join(dir, "a.pdf")
+	if err := os.WriteFile(a, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); scans(a) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for scan")
+		}
+	}
+
+	// b is ready twice while the worker is busy
+	b := filepath.Join(dir, "b.pdf")
+	for i := 0; i < 2; i++ {
+		if err := os.WriteFile(b, []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	for _, expected := range []string{a, b} {
+		if ev := waitEvent(t, w); ev.Path != expected {
+			t.Errorf("event path = %s, want %s", ev.Path, expected)
+		}
+	}
+
+	select {
+	case ev := <-w.Events():
+		t.Errorf("unexpected event: %+v", ev)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if n := scans(b); n != 1 {
+		t.Errorf("b scanned %d times, want 1", n)
+	}
+}
+
+func TestWatchSlowConsumer(t *testing.T) {
+	w, s, dir := newWatcher(t, Options{Debounce: 50 * time.Millisecond})
+
+	// Nobody reads the events while watch errors arrive
+	errOverflow := errors.New("queue overflow")
+	go func() {
+		for i := 0; i < 2; i++ {
+			w.fsw.Errors <- errOverflow
+		}
+	}()
+
+	path := filepath.Join(dir, "a.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Notifications keep being read, so the file is identified all the same
+	scans := func() int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.scans[path]
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); scans() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for scan while watch errors were undelivered")
+		}
+	}
+
+	var errs, files int
+	for i := 0; i < 3; i++ {
+		if ev := waitEvent(t, w); ev.Path == path {
+			files++
+		} else if errors.Is(ev.Err, errOverflow) {
+			errs++
+		}
+	}
+
+	if errs != 2 || files != 1 {
+		t.Errorf("received %d errors and %d file events, want 2 and 1", errs, files)
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	w, _, dir := newWatcher(t, Options{Recursive: true, Debounce: 50 * time.Millisecond})
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the watcher time to add the new directory
+	time.Sleep(100 * time.Millisecond)
+
+	path := filepath.Join(sub, "nested.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev := waitEvent(t, w); ev.Path != path {
+		t.Errorf("event path = %s, want %s", ev.Path, path)
+	}
+}
+
+func TestWatchHandlerAndFilter(t *testing.T) {
+	events := make(chan Event, 2)
+	_, _, dir := newWatcher(t, Options{
+		Debounce: 50 * time.Millisecond,
+		Filter:   func(path string) bool { return filepath.Ext(path) == ".pdf" },
+		Handler:  func(ev Event) { events <- ev },
+	})
+
+	os.WriteFile(filepath.Join(dir, "ignored.tmp"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(dir, "doc.pdf"), []byte("%PDF-1.4"), 0o644)
+
+	select {
+	case ev := <-events:
+		if filepath.Base(ev.Path) != "doc.pdf" {
+			t.Errorf("handler received %s, want doc.pdf", ev.Path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for handler")
+	}
+
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event: %+v", ev)
+	case <-time.After(200 * time.Millisecond):
+	}
+}